@@ -0,0 +1,133 @@
+package app
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/archive"
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/export"
+	"github.com/dstdfx/twbridge/internal/log"
+	"go.uber.org/zap"
+)
+
+const exportFilePerm = 0o600
+
+var (
+	errExportChatRequired    = errors.New("-chat is required")
+	errExportContactRequired = errors.New("-contact is required")
+	errExportEmpty           = errors.New("there are no messages to export")
+)
+
+// Export runs the export subcommand, it writes a transcript of the conversation
+// from the message archive to a zip file with JSON, CSV and HTML formats.
+func Export(args []string) error {
+	dataDir, ok := os.LookupEnv(dataDirEnv)
+	if !ok {
+		dataDir = defaultDataDir
+	}
+
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.StringVar(&dataDir, "data", dataDir, "data directory of the bridge, "+dataDirEnv+" is used by default")
+	chatID := flags.Int64("chat", 0, "telegram chat identifier the conversation is bridged to, see /route")
+	contact := flags.String("contact", "", "jid or name of the contact")
+	from := flags.String("from", "", "first date of the messages, e.g. 2022-03-01")
+	to := flags.String("to", "", "last date of the messages, e.g. 2022-03-31")
+	timeZone := flags.String("tz", "UTC", "time zone of the dates and the transcript, e.g. Europe/Berlin")
+	out := flags.String("o", "", "path to the zip file, export-<contact>.zip by default")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	switch {
+	case *chatID == 0:
+		return errExportChatRequired
+	case *contact == "":
+		return errExportContactRequired
+	}
+
+	loc, err := time.LoadLocation(*timeZone)
+	if err != nil {
+		return fmt.Errorf("unknown time zone %q: %w", *timeZone, err)
+	}
+
+	var fromTime, toTime time.Time
+	if *from != "" {
+		if fromTime, err = export.ParseDate(*from, loc); err != nil {
+			return err
+		}
+	}
+	if *to != "" {
+		if toTime, err = export.ParseDate(*to, loc); err != nil {
+			return err
+		}
+		toTime = toTime.AddDate(0, 0, 1)
+	}
+
+	// Malformed lines of the archive are logged
+	logger, err := log.NewLogger(zap.WarnLevel, zap.String("service", "twbridge"))
+	if err != nil {
+		return err
+	}
+	messageArchive, err := archive.New(logger, &archive.Opts{
+		Path: filepath.Join(dataDir, archiveFileName),
+	})
+	if err != nil {
+		return err
+	}
+
+	messages := messageArchive.Messages(*chatID)
+	messages = export.Select(messages, fromTime, toTime, contactMatcher(messages, *contact))
+	if len(messages) == 0 {
+		return errExportEmpty
+	}
+
+	path := *out
+	if path == "" {
+		path = fmt.Sprintf("export-%s.zip", strings.NewReplacer("@", "_", "/", "_", " ", "_").Replace(*contact))
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, exportFilePerm)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	err = export.Write(file, &export.Opts{
+		Title:    *contact,
+		Location: loc,
+		Messages: messages,
+	})
+	if err != nil {
+		file.Close() // nolint
+
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", path, err)
+	}
+
+	fmt.Printf("%d messages are exported to %s\n", len(messages), path)
+
+	return nil
+}
+
+// contactMatcher returns a function that matches archived messages of the contact. The contact
+// is a jid or a name the contact has sent the messages with, so replies to it match too.
+func contactMatcher(messages []domain.ArchivedMessage, contact string) func(msg *domain.ArchivedMessage) bool {
+	jids := map[string]bool{contact: true}
+	name := strings.ToLower(contact)
+	for _, msg := range messages {
+		if msg.Direction == domain.MessageDirectionIn && strings.Contains(strings.ToLower(msg.SenderName), name) {
+			jids[msg.RemoteJid] = true
+		}
+	}
+
+	return func(msg *domain.ArchivedMessage) bool {
+		return jids[msg.RemoteJid]
+	}
+}
@@ -2,15 +2,39 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
+	_ "time/tzdata" // time zones of quiet hours don't depend on the system database
 
+	"github.com/dstdfx/twbridge/internal/archive"
+	"github.com/dstdfx/twbridge/internal/away"
+	"github.com/dstdfx/twbridge/internal/conversation"
+	"github.com/dstdfx/twbridge/internal/digest"
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/files"
 	"github.com/dstdfx/twbridge/internal/log"
 	"github.com/dstdfx/twbridge/internal/manager"
+	"github.com/dstdfx/twbridge/internal/outbox"
+	"github.com/dstdfx/twbridge/internal/quiet"
+	"github.com/dstdfx/twbridge/internal/route"
+	"github.com/dstdfx/twbridge/internal/rules"
+	"github.com/dstdfx/twbridge/internal/schedule"
+	"github.com/dstdfx/twbridge/internal/settings"
+	"github.com/dstdfx/twbridge/internal/team"
 	"github.com/dstdfx/twbridge/internal/telegram"
+	"github.com/dstdfx/twbridge/internal/topic"
+	"github.com/dstdfx/twbridge/internal/whatsapp"
+	"github.com/dstdfx/twbridge/internal/whatsapp/multidevice"
+	"github.com/dstdfx/twbridge/internal/whatsapp/simulator"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 	"go.uber.org/zap"
 )
@@ -25,8 +49,69 @@ var (
 
 const (
 	telegramAPITokenEnv = "TELEGRAM_API_TOKEN"
+	dataDirEnv          = "TWBRIDGE_DATA_DIR"
+	whatsappBackendEnv  = "WHATSAPP_BACKEND"
+	mdDBDialectEnv      = "WHATSAPP_MD_DB_DIALECT"
+	mdDBAddressEnv      = "WHATSAPP_MD_DB_ADDRESS"
+	rulesFileEnv        = "TWBRIDGE_RULES_FILE"
+	historyChatsEnv     = "TWBRIDGE_HISTORY_CHATS"
+	historyMessagesEnv  = "TWBRIDGE_HISTORY_MESSAGES"
+	presenceChatsEnv    = "TWBRIDGE_PRESENCE_CHATS"
+	filesURLEnv         = "TWBRIDGE_FILES_URL"
+	filesAddrEnv        = "TWBRIDGE_FILES_ADDR"
+	filesDirEnv         = "TWBRIDGE_FILES_DIR"
+	filesTTLEnv         = "TWBRIDGE_FILES_TTL"
+	archiveMaxAgeEnv    = "TWBRIDGE_ARCHIVE_MAX_AGE"
+	maxUploadSizeEnv    = "TWBRIDGE_MAX_UPLOAD_SIZE"
 
 	defaultTelegramReceiveTimeout = 60
+	defaultDataDir                = "data"
+	defaultHistoryMessages        = 20
+	defaultPresenceChats          = 20
+	defaultFilesAddr              = ":8080"
+	defaultFilesTTL               = 24 * time.Hour
+	defaultArchiveMaxAge          = 365 * 24 * time.Hour
+
+	webWhatsappBackend       = "web"
+	simulatorWhatsappBackend = "simulator"
+	mdWhatsappBackend        = "multidevice"
+
+	defaultMDDBDialect = "sqlite3"
+	mdDBFileName       = "whatsmeow.db"
+
+	outboxFileName        = "outbox.json"
+	teamsFileName         = "teams.json"
+	topicsFileName        = "topics.json"
+	routesFileName        = "routes.json"
+	conversationsFileName = "conversations.json"
+	settingsFileName      = "settings.json"
+	digestsFileName       = "digests.json"
+	rulesFileName         = "rules.json"
+	quietHoursFileName    = "quiet_hours.json"
+	scheduleFileName      = "schedule.json"
+	awayFileName          = "away.json"
+	archiveFileName       = "archive.jsonl"
+	filesDirName          = "files"
+)
+
+const (
+	// filesPath is a path the stored files are served at.
+	filesPath = "/files"
+
+	filesReadHeaderTimeout = 10 * time.Second
+	filesShutdownTimeout   = 5 * time.Second
+)
+
+const (
+	simulatorContactJid = "simulated-contact@s.whatsapp.net"
+	simulatorLoginDelay = 3 * time.Second
+	simulatorGreeting   = "Hi, this is a simulated WhatsApp contact, reply to this message and I'll echo it back"
+)
+
+var (
+	errUnknownWhatsappBackend = errors.New("unknown whatsapp backend")
+	errInvalidNumber          = errors.New("invalid number")
+	errInvalidDuration        = errors.New("invalid duration")
 )
 
 func Start() {
@@ -40,11 +125,27 @@ func Start() {
 		logger.Panic(fmt.Sprintf("%s is required", telegramAPITokenEnv))
 	}
 
+	dataDir, ok := os.LookupEnv(dataDirEnv)
+	if !ok {
+		dataDir = defaultDataDir
+	}
+
+	whatsappBackendName, ok := os.LookupEnv(whatsappBackendEnv)
+	if !ok {
+		whatsappBackendName = webWhatsappBackend
+	}
+
+	whatsappBackend, err := newWhatsappBackend(logger, whatsappBackendName, dataDir)
+	if err != nil {
+		logger.Panic("failed to create whatsapp backend", zap.Error(err))
+	}
+
 	logger.Info("twbridge is running...",
 		zap.String("build_commit", buildGitCommit),
 		zap.String("build_tag", buildGitTag),
 		zap.String("build_date", buildDate),
-		zap.String("go_version", buildCompiler))
+		zap.String("go_version", buildCompiler),
+		zap.String("whatsapp_backend", whatsappBackendName))
 
 	// Create telegram bot instance
 	bot, err := tgbotapi.NewBotAPI(apiToken)
@@ -58,26 +159,171 @@ func Start() {
 
 	// TODO: use webhook for receiving tg updates
 
-	// Create telegram config
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = defaultTelegramReceiveTimeout
+	// Create telegram updates poller, the library one can't receive reactions
+	updatesPoller := telegram.NewUpdatesPoller(logger, bot, defaultTelegramReceiveTimeout)
+	go func() {
+		if err := updatesPoller.Run(rootCtx); err != nil {
+			logger.Panic("failed to get telegram updates", zap.Error(err))
+		}
+	}()
 
-	// Create telegram updates channel
-	tgUpdatesCh, err := bot.GetUpdatesChan(u)
+	// Create telegram events provider instance
+	eventsProvider := telegram.NewEventsProvider(logger, &telegram.Opts{
+		TelegramUpdates: updatesPoller.Updates(),
+		Reactions:       updatesPoller.Reactions(),
+	})
+
+	// Create outbox for outgoing whatsapp messages
+	messagesOutbox, err := outbox.New(&outbox.Opts{
+		Path: filepath.Join(dataDir, outboxFileName),
+	})
 	if err != nil {
-		logger.Panic("failed to get updates chan", zap.Error(err))
+		logger.Panic("failed to create outbox", zap.Error(err))
 	}
-	defer bot.StopReceivingUpdates()
 
-	// Create telegram events provider instance
-	eventsProvider := telegram.NewEventsProvider(logger, &telegram.Opts{
-		TelegramUpdates: tgUpdatesCh,
+	// Create storage of the teams sharing whatsapp accounts
+	teams, err := team.New(&team.Opts{
+		Path: filepath.Join(dataDir, teamsFileName),
+	})
+	if err != nil {
+		logger.Panic("failed to create teams storage", zap.Error(err))
+	}
+
+	// Create storage of the forum topics whatsapp conversations are bridged to
+	topics, err := topic.New(&topic.Opts{
+		Path: filepath.Join(dataDir, topicsFileName),
+	})
+	if err != nil {
+		logger.Panic("failed to create topics storage", zap.Error(err))
+	}
+
+	// Create routing table of whatsapp conversations
+	routes, err := route.New(&route.Opts{
+		Path: filepath.Join(dataDir, routesFileName),
+	})
+	if err != nil {
+		logger.Panic("failed to create routes storage", zap.Error(err))
+	}
+
+	// Create storage of the active conversations
+	conversations, err := conversation.New(&conversation.Opts{
+		Path: filepath.Join(dataDir, conversationsFileName),
+	})
+	if err != nil {
+		logger.Panic("failed to create conversations storage", zap.Error(err))
+	}
+
+	// Create storage of the settings of whatsapp contacts
+	contactSettings, err := settings.New(&settings.Opts{
+		Path: filepath.Join(dataDir, settingsFileName),
+	})
+	if err != nil {
+		logger.Panic("failed to create settings storage", zap.Error(err))
+	}
+
+	// Create storage of the messages collected to the digests
+	digests, err := digest.New(&digest.Opts{
+		Path: filepath.Join(dataDir, digestsFileName),
 	})
+	if err != nil {
+		logger.Panic("failed to create digests storage", zap.Error(err))
+	}
+
+	// Create storage of the rules applied to incoming messages, the rules
+	// of the config file apply to every chat
+	messageRules, err := rules.New(&rules.Opts{
+		Path:       filepath.Join(dataDir, rulesFileName),
+		ConfigPath: os.Getenv(rulesFileEnv),
+	})
+	if err != nil {
+		logger.Panic("failed to create rules storage", zap.Error(err))
+	}
+
+	// Create storage of quiet hours and daily digests of the chats
+	quietHours, err := quiet.New(&quiet.Opts{
+		Path: filepath.Join(dataDir, quietHoursFileName),
+	})
+	if err != nil {
+		logger.Panic("failed to create quiet hours storage", zap.Error(err))
+	}
+
+	// Create storage of the messages scheduled to be sent later
+	schedules, err := schedule.New(&schedule.Opts{
+		Path: filepath.Join(dataDir, scheduleFileName),
+	})
+	if err != nil {
+		logger.Panic("failed to create schedule storage", zap.Error(err))
+	}
+
+	// Create storage of away modes of the chats
+	awayModes, err := away.New(&away.Opts{
+		Path: filepath.Join(dataDir, awayFileName),
+	})
+	if err != nil {
+		logger.Panic("failed to create away modes storage", zap.Error(err))
+	}
+
+	// Create storage of the bridged messages, older messages are removed
+	archiveMaxAge, err := durationEnv(archiveMaxAgeEnv, defaultArchiveMaxAge)
+	if err != nil {
+		logger.Panic("failed to parse archive max age", zap.Error(err))
+	}
+	messageArchive, err := archive.New(logger, &archive.Opts{
+		Path:   filepath.Join(dataDir, archiveFileName),
+		MaxAge: archiveMaxAge,
+	})
+	if err != nil {
+		logger.Panic("failed to create archive storage", zap.Error(err))
+	}
+
+	// History of the recent chats is loaded on login if it's enabled
+	historyChats, err := intEnv(historyChatsEnv, 0)
+	if err != nil {
+		logger.Panic("failed to parse history chats", zap.Error(err))
+	}
+	historyMessages, err := intEnv(historyMessagesEnv, defaultHistoryMessages)
+	if err != nil {
+		logger.Panic("failed to parse history messages", zap.Error(err))
+	}
+
+	// Contacts of the recent chats are subscribed to presence updates to show their typing
+	presenceChats, err := intEnv(presenceChatsEnv, defaultPresenceChats)
+	if err != nil {
+		logger.Panic("failed to parse presence chats", zap.Error(err))
+	}
+
+	// Files too large to upload to telegram are served by the bridge if its public URL is set
+	maxUploadSize, err := intEnv(maxUploadSizeEnv, 0)
+	if err != nil {
+		logger.Panic("failed to parse max upload size", zap.Error(err))
+	}
+	fileStore, err := newFileStore(rootCtx, logger, dataDir)
+	if err != nil {
+		logger.Panic("failed to create file storage", zap.Error(err))
+	}
 
 	// Create clients manager instance
 	clientManager := manager.NewManager(logger, &manager.Opts{
-		IncomingEvents: eventsProvider.EventsStream(),
-		TelegramAPI:    bot,
+		IncomingEvents:  eventsProvider.EventsStream(),
+		TelegramClient:  telegram.NewClient(bot),
+		WhatsappBackend: whatsappBackend,
+		Outbox:          messagesOutbox,
+		Teams:           teams,
+		Topics:          topics,
+		Routes:          routes,
+		Conversations:   conversations,
+		Settings:        contactSettings,
+		Digests:         digests,
+		Rules:           messageRules,
+		QuietHours:      quietHours,
+		Schedules:       schedules,
+		Away:            awayModes,
+		Archive:         messageArchive,
+		HistoryChats:    historyChats,
+		HistoryMessages: historyMessages,
+		PresenceChats:   presenceChats,
+		Files:           fileStore,
+		MaxUploadSize:   maxUploadSize,
 	})
 
 	go clientManager.Run(rootCtx)
@@ -91,3 +337,126 @@ func Start() {
 	<-rootCtx.Done()
 	stop()
 }
+
+func newWhatsappBackend(logger *zap.Logger, name, dataDir string) (domain.WhatsappBackend, error) {
+	switch name {
+	case webWhatsappBackend:
+		return whatsapp.NewBackend(logger), nil
+	case simulatorWhatsappBackend:
+		return simulator.NewBackend(logger, &simulator.Opts{
+			Contacts: []domain.WhatsappContact{
+				{
+					Jid:  simulatorContactJid,
+					Name: "Simulated Contact",
+				},
+			},
+			LoginDelay: simulatorLoginDelay,
+			Echo:       true,
+			Greeting:   simulatorGreeting,
+		}), nil
+	case mdWhatsappBackend:
+		dialect, ok := os.LookupEnv(mdDBDialectEnv)
+		if !ok {
+			dialect = defaultMDDBDialect
+		}
+		address, ok := os.LookupEnv(mdDBAddressEnv)
+		if !ok {
+			address = fmt.Sprintf("file:%s?_foreign_keys=on", filepath.Join(dataDir, mdDBFileName))
+		}
+
+		return multidevice.NewBackend(logger, &multidevice.Opts{
+			Dialect: dialect,
+			Address: address,
+		})
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnknownWhatsappBackend, name)
+	}
+}
+
+// newFileStore creates the storage of the files too large to upload to telegram and serves
+// them until the context is done, nil is returned if the public URL of the bridge isn't set.
+func newFileStore(ctx context.Context, logger *zap.Logger, dataDir string) (*files.Store, error) {
+	baseURL, ok := os.LookupEnv(filesURLEnv)
+	if !ok || baseURL == "" {
+		return nil, nil
+	}
+
+	dir, ok := os.LookupEnv(filesDirEnv)
+	if !ok {
+		dir = filepath.Join(dataDir, filesDirName)
+	}
+	addr, ok := os.LookupEnv(filesAddrEnv)
+	if !ok {
+		addr = defaultFilesAddr
+	}
+	ttl, err := durationEnv(filesTTLEnv, defaultFilesTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := files.New(&files.Opts{
+		Dir:     dir,
+		BaseURL: strings.TrimSuffix(baseURL, "/") + filesPath,
+		TTL:     ttl,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(filesPath+"/", http.StripPrefix(filesPath, store))
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: filesReadHeaderTimeout,
+	}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Panic("failed to serve files", zap.Error(err))
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), filesShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error("failed to stop files server", zap.Error(err))
+		}
+	}()
+
+	logger.Info("serving files", zap.String("addr", addr), zap.String("url", baseURL))
+
+	return store, nil
+}
+
+// durationEnv returns a positive duration from the environment variable, or the default if it isn't set.
+func durationEnv(name string, defaultValue time.Duration) (time.Duration, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return defaultValue, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%w: %s=%q", errInvalidDuration, name, value)
+	}
+
+	return d, nil
+}
+
+// intEnv returns a non-negative number from the environment variable, or the default if it isn't set.
+func intEnv(name string, defaultValue int) (int, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return defaultValue, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w: %s=%q", errInvalidNumber, name, value)
+	}
+
+	return n, nil
+}
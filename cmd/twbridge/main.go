@@ -1,7 +1,21 @@
 package main
 
-import "github.com/dstdfx/twbridge/cmd/twbridge/app"
+import (
+	"fmt"
+	"os"
+
+	"github.com/dstdfx/twbridge/cmd/twbridge/app"
+)
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		if err := app.Export(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	app.Start()
 }
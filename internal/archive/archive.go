@@ -0,0 +1,261 @@
+package archive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/storage"
+	"go.uber.org/zap"
+)
+
+// ErrNotFound is returned when the message is not in the archive.
+var ErrNotFound = errors.New("archived message not found")
+
+// Store represents a durable archive of the bridged messages.
+// Messages are appended to the file, a message appended again with the same
+// identifier replaces the previous version of it. Messages older than the maximum
+// age are removed, the file is rewritten without them and previous versions.
+type Store struct {
+	mu       sync.Mutex
+	log      *zap.Logger
+	file     *storage.JSONLinesFile
+	maxAge   time.Duration
+	now      func() time.Time
+	messages []domain.ArchivedMessage
+	index    map[int64]int
+	lastID   int64
+}
+
+// Opts represents options to create new instance of Store.
+type Opts struct {
+	// Path is a path to the file the messages are persisted to.
+	Path string
+
+	// MaxAge is a period the messages are kept for, they are kept forever if it's zero.
+	MaxAge time.Duration
+
+	// Clock returns the current time, time.Now is used if it's nil.
+	Clock func() time.Time
+}
+
+// Query represents a search query of the archive.
+type Query struct {
+	// ChatID is telegram bot chat identifier the messages belong to.
+	ChatID int64
+
+	// Text is a text the messages contain, case insensitive.
+	Text string
+
+	// Match reports whether the message matches the query, all messages match if it's nil.
+	Match func(msg *domain.ArchivedMessage) bool
+
+	// Limit is the maximum number of the messages, all of them are returned if it's zero.
+	Limit int
+}
+
+// New creates new instance of Store and loads previously archived messages.
+// Malformed lines, e.g. the last one is written partially, are skipped, so the rest
+// of the archive is still available.
+func New(log *zap.Logger, opts *Opts) (*Store, error) {
+	clock := opts.Clock
+	if clock == nil {
+		clock = time.Now
+	}
+
+	s := &Store{
+		log:      log,
+		file:     storage.NewJSONLinesFile(opts.Path),
+		maxAge:   opts.MaxAge,
+		now:      clock,
+		messages: make([]domain.ArchivedMessage, 0),
+		index:    make(map[int64]int),
+	}
+
+	lines := 0
+	err := s.file.Load(func(line []byte) error {
+		lines++
+
+		var msg domain.ArchivedMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			log.Warn("skip malformed archived message", zap.Error(err), zap.String("path", s.file.Path()))
+
+			return nil
+		}
+		s.put(msg)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load archive: %w", err)
+	}
+
+	// The file is compacted once something is skipped, replaced or expired,
+	// so it doesn't grow with the lines that aren't loaded anyway
+	if messages := s.unexpired(); lines != len(messages) {
+		if err := s.replace(messages); err != nil {
+			return nil, fmt.Errorf("failed to compact archive: %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+// Add method archives the message and returns it with a new identifier.
+func (s *Store) Add(msg domain.ArchivedMessage) (domain.ArchivedMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg.ID = s.lastID + 1
+	if err := s.file.Append(msg); err != nil {
+		return domain.ArchivedMessage{}, fmt.Errorf("failed to save archived message: %w", err)
+	}
+	s.put(msg)
+
+	return msg, nil
+}
+
+// Update method replaces the archived message with the same identifier, the new version
+// is appended to the file and replaces the previous one once it's loaded.
+func (s *Store) Update(msg domain.ArchivedMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.index[msg.ID]; !ok {
+		return fmt.Errorf("%w: %d", ErrNotFound, msg.ID)
+	}
+
+	if err := s.file.Append(msg); err != nil {
+		return fmt.Errorf("failed to save archived message: %w", err)
+	}
+	s.put(msg)
+
+	return nil
+}
+
+// Messages method returns archived messages of the chat in the order they have been archived.
+func (s *Store) Messages(chatID int64) []domain.ArchivedMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := make([]domain.ArchivedMessage, 0)
+	for _, msg := range s.messages {
+		if msg.ChatID == chatID {
+			messages = append(messages, msg)
+		}
+	}
+
+	return messages
+}
+
+// Search method returns archived messages that match the query, the most recent first.
+func (s *Store) Search(query *Query) []domain.ArchivedMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	text := strings.ToLower(query.Text)
+	found := make([]domain.ArchivedMessage, 0)
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		msg := s.messages[i]
+		if msg.ChatID != query.ChatID || !strings.Contains(strings.ToLower(msg.Text), text) {
+			continue
+		}
+		if query.Match != nil && !query.Match(&msg) {
+			continue
+		}
+
+		found = append(found, msg)
+		if query.Limit > 0 && len(found) == query.Limit {
+			break
+		}
+	}
+
+	return found
+}
+
+// Posted method returns the most recent archived message of any chat that has been posted
+// to telegram as the message, false is returned if there is no such message.
+func (s *Store) Posted(telegramChatID int64, telegramMessageID int) (domain.ArchivedMessage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		msg := s.messages[i]
+		if msg.TelegramChatID == telegramChatID && msg.TelegramMessageID == telegramMessageID {
+			return msg, true
+		}
+	}
+
+	return domain.ArchivedMessage{}, false
+}
+
+// RemoveExpired method removes the messages older than the maximum age of the archive.
+func (s *Store) RemoveExpired() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := s.unexpired()
+	if len(messages) == len(s.messages) {
+		return nil
+	}
+
+	if err := s.replace(messages); err != nil {
+		return fmt.Errorf("failed to remove expired messages: %w", err)
+	}
+
+	return nil
+}
+
+// unexpired returns the loaded messages that are not older than the maximum age.
+func (s *Store) unexpired() []domain.ArchivedMessage {
+	if s.maxAge == 0 {
+		return s.messages
+	}
+
+	since := s.now().Add(-s.maxAge)
+	messages := make([]domain.ArchivedMessage, 0, len(s.messages))
+	for _, msg := range s.messages {
+		if !msg.Timestamp.Before(since) {
+			messages = append(messages, msg)
+		}
+	}
+
+	return messages
+}
+
+// replace rewrites the file with the messages and makes them the loaded ones.
+func (s *Store) replace(messages []domain.ArchivedMessage) error {
+	if err := s.file.Replace(len(messages), func(i int) interface{} {
+		return messages[i]
+	}); err != nil {
+		return err
+	}
+
+	s.messages = messages
+	s.index = make(map[int64]int, len(messages))
+	for i, msg := range messages {
+		s.index[msg.ID] = i
+	}
+
+	return nil
+}
+
+// put adds the message to the loaded ones or replaces its previous version.
+func (s *Store) put(msg domain.ArchivedMessage) {
+	if msg.ID > s.lastID {
+		s.lastID = msg.ID
+	}
+
+	if i, ok := s.index[msg.ID]; ok {
+		s.messages[i] = msg
+
+		return
+	}
+
+	s.index[msg.ID] = len(s.messages)
+	s.messages = append(s.messages, msg)
+}
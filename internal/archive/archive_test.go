@@ -0,0 +1,205 @@
+package archive_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/archive"
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	testChatID    = int64(123)
+	testRemoteJid = "alice@s.whatsapp.net"
+)
+
+func testMessage(direction domain.MessageDirection, remoteJid, text string) domain.ArchivedMessage {
+	return domain.ArchivedMessage{
+		ChatID:    testChatID,
+		Direction: direction,
+		Account:   domain.DefaultWhatsappAccount,
+		RemoteJid: remoteJid,
+		Text:      text,
+		Timestamp: time.Date(2022, 3, 31, 10, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestStore(t *testing.T) {
+	t.Run("add and search", func(t *testing.T) {
+		store, err := archive.New(zap.NewNop(), &archive.Opts{Path: filepath.Join(t.TempDir(), "archive.jsonl")})
+		require.NoError(t, err)
+
+		first, err := store.Add(testMessage(domain.MessageDirectionIn, testRemoteJid, "Lunch tomorrow?"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), first.ID)
+
+		second, err := store.Add(testMessage(domain.MessageDirectionOut, testRemoteJid, "Sure, where do we have lunch?"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), second.ID)
+
+		_, err = store.Add(testMessage(domain.MessageDirectionIn, "bob@s.whatsapp.net", "lunch is ready"))
+		require.NoError(t, err)
+
+		other := testMessage(domain.MessageDirectionIn, testRemoteJid, "lunch")
+		other.ChatID = 456
+		_, err = store.Add(other)
+		require.NoError(t, err)
+
+		// The most recent messages come first
+		found := store.Search(&archive.Query{ChatID: testChatID, Text: "LUNCH"})
+		require.Len(t, found, 3)
+		assert.Equal(t, "lunch is ready", found[0].Text)
+		assert.Equal(t, second, found[1])
+		assert.Equal(t, first, found[2])
+
+		found = store.Search(&archive.Query{
+			ChatID: testChatID,
+			Text:   "lunch",
+			Match: func(msg *domain.ArchivedMessage) bool {
+				return msg.RemoteJid == testRemoteJid
+			},
+			Limit: 1,
+		})
+		assert.Equal(t, []domain.ArchivedMessage{second}, found)
+
+		assert.Empty(t, store.Search(&archive.Query{ChatID: testChatID, Text: "dinner"}))
+		assert.Len(t, store.Messages(testChatID), 3)
+	})
+
+	t.Run("messages are persisted", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "archive.jsonl")
+		store, err := archive.New(zap.NewNop(), &archive.Opts{Path: path})
+		require.NoError(t, err)
+
+		msg := testMessage(domain.MessageDirectionIn, testRemoteJid, "hi")
+		msg.Media = []domain.MediaReference{{Type: "image", FileName: "photo.jpg"}}
+		msg.TelegramChatID = testChatID
+		msg.TelegramMessageID = 42
+		msg, err = store.Add(msg)
+		require.NoError(t, err)
+
+		reloaded, err := archive.New(zap.NewNop(), &archive.Opts{Path: path})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.ArchivedMessage{msg}, reloaded.Messages(testChatID))
+
+		// Identifiers keep growing after the archive is reloaded
+		next, err := reloaded.Add(testMessage(domain.MessageDirectionOut, testRemoteJid, "hello"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), next.ID)
+	})
+
+	t.Run("posted message", func(t *testing.T) {
+		store, err := archive.New(zap.NewNop(), &archive.Opts{Path: filepath.Join(t.TempDir(), "archive.jsonl")})
+		require.NoError(t, err)
+
+		// Messages are found by the telegram message in any chat, e.g. the routed one
+		msg := testMessage(domain.MessageDirectionOut, testRemoteJid, "hi")
+		msg.TelegramChatID = -100456
+		msg.TelegramMessageID = 42
+		msg, err = store.Add(msg)
+		require.NoError(t, err)
+
+		found, ok := store.Posted(-100456, 42)
+		assert.True(t, ok)
+		assert.Equal(t, msg, found)
+
+		_, ok = store.Posted(testChatID, 42)
+		assert.False(t, ok)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "archive.jsonl")
+		store, err := archive.New(zap.NewNop(), &archive.Opts{Path: path})
+		require.NoError(t, err)
+
+		msg, err := store.Add(testMessage(domain.MessageDirectionOut, testRemoteJid, "See you at 5"))
+		require.NoError(t, err)
+		_, err = store.Add(testMessage(domain.MessageDirectionIn, testRemoteJid, "ok"))
+		require.NoError(t, err)
+
+		msg.Text = "See you at 6"
+		require.NoError(t, store.Update(msg))
+
+		unknown := msg
+		unknown.ID = 42
+		assert.ErrorIs(t, store.Update(unknown), archive.ErrNotFound)
+
+		// The updated version replaces the previous one in place once it's loaded
+		restored, err := archive.New(zap.NewNop(), &archive.Opts{Path: path})
+		require.NoError(t, err)
+		messages := restored.Messages(testChatID)
+		require.Len(t, messages, 2)
+		assert.Equal(t, msg, messages[0])
+
+		added, err := restored.Add(testMessage(domain.MessageDirectionIn, testRemoteJid, "great"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), added.ID)
+	})
+	t.Run("malformed lines are skipped", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "archive.jsonl")
+		store, err := archive.New(zap.NewNop(), &archive.Opts{Path: path})
+		require.NoError(t, err)
+		msg, err := store.Add(testMessage(domain.MessageDirectionIn, testRemoteJid, "hi"))
+		require.NoError(t, err)
+
+		// The last line is written partially, e.g. the bridge has crashed
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+		require.NoError(t, err)
+		_, err = file.WriteString(`{"id":2,"chat_id":123,"te`)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		restored, err := archive.New(zap.NewNop(), &archive.Opts{Path: path})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.ArchivedMessage{msg}, restored.Messages(testChatID))
+
+		// The file is compacted, so new messages are appended after the valid lines
+		next, err := restored.Add(testMessage(domain.MessageDirectionOut, testRemoteJid, "hello"))
+		require.NoError(t, err)
+		restored, err = archive.New(zap.NewNop(), &archive.Opts{Path: path})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.ArchivedMessage{msg, next}, restored.Messages(testChatID))
+	})
+
+	t.Run("remove expired", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "archive.jsonl")
+		now := time.Date(2022, 4, 30, 10, 0, 0, 0, time.UTC)
+		opts := &archive.Opts{
+			Path:   path,
+			MaxAge: 30 * 24 * time.Hour,
+			Clock:  func() time.Time { return now },
+		}
+		store, err := archive.New(zap.NewNop(), opts)
+		require.NoError(t, err)
+
+		old, err := store.Add(testMessage(domain.MessageDirectionIn, testRemoteJid, "old"))
+		require.NoError(t, err)
+		recent := testMessage(domain.MessageDirectionIn, testRemoteJid, "recent")
+		recent.Timestamp = now.Add(-time.Hour)
+		recent, err = store.Add(recent)
+		require.NoError(t, err)
+
+		require.NoError(t, store.RemoveExpired())
+		assert.Equal(t, []domain.ArchivedMessage{old, recent}, store.Messages(testChatID))
+
+		now = now.Add(24 * time.Hour)
+		require.NoError(t, store.RemoveExpired())
+		assert.Equal(t, []domain.ArchivedMessage{recent}, store.Messages(testChatID))
+		assert.ErrorIs(t, store.Update(old), archive.ErrNotFound)
+
+		reloaded, err := archive.New(zap.NewNop(), &archive.Opts{Path: path})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.ArchivedMessage{recent}, reloaded.Messages(testChatID))
+
+		// Messages that have expired while the bridge is stopped aren't loaded
+		now = now.Add(30 * 24 * time.Hour)
+		reloaded, err = archive.New(zap.NewNop(), opts)
+		require.NoError(t, err)
+		assert.Empty(t, reloaded.Messages(testChatID))
+	})
+}
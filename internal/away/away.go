@@ -0,0 +1,107 @@
+package away
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/storage"
+)
+
+// Store represents a durable storage of away modes of telegram chats.
+type Store struct {
+	mu    sync.Mutex
+	file  *storage.JSONFile
+	modes []domain.AwayMode
+}
+
+// Opts represents options to create new instance of Store.
+type Opts struct {
+	// Path is a path to the file the away modes are persisted to.
+	Path string
+}
+
+// New creates new instance of Store and loads previously saved away modes.
+func New(opts *Opts) (*Store, error) {
+	s := &Store{
+		file:  storage.NewJSONFile(opts.Path),
+		modes: make([]domain.AwayMode, 0),
+	}
+
+	if err := s.file.Load(&s.modes); err != nil {
+		return nil, fmt.Errorf("failed to load away modes: %w", err)
+	}
+
+	return s, nil
+}
+
+// Get method returns away mode of the chat, false is returned if it has never been set.
+func (s *Store) Get(chatID int64) (domain.AwayMode, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, mode := range s.modes {
+		if mode.ChatID == chatID {
+			mode.Replies = append([]domain.AwayReply(nil), mode.Replies...)
+
+			return mode, true
+		}
+	}
+
+	return domain.AwayMode{ChatID: chatID}, false
+}
+
+// Set method saves away mode of the chat.
+func (s *Store) Set(mode domain.AwayMode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.update(mode.ChatID, func(m *domain.AwayMode) {
+		*m = mode
+	})
+}
+
+// SetReplied method saves the time the auto-reply has been sent to the contact.
+func (s *Store) SetReplied(chatID int64, account, remoteJid string, repliedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.update(chatID, func(m *domain.AwayMode) {
+		replies := make([]domain.AwayReply, 0, len(m.Replies)+1)
+		for _, r := range m.Replies {
+			if r.Account != account || r.RemoteJid != remoteJid {
+				replies = append(replies, r)
+			}
+		}
+		m.Replies = append(replies, domain.AwayReply{
+			Account:   account,
+			RemoteJid: remoteJid,
+			RepliedAt: repliedAt,
+		})
+	})
+}
+
+// update applies the function to a copy of away mode of the chat and saves it.
+func (s *Store) update(chatID int64, fn func(m *domain.AwayMode)) error {
+	modes := make([]domain.AwayMode, 0, len(s.modes)+1)
+	mode := domain.AwayMode{ChatID: chatID}
+	for _, m := range s.modes {
+		if m.ChatID == chatID {
+			mode = m
+			mode.Replies = append([]domain.AwayReply(nil), m.Replies...)
+
+			continue
+		}
+		modes = append(modes, m)
+	}
+	fn(&mode)
+	modes = append(modes, mode)
+
+	if err := s.file.Save(modes); err != nil {
+		return fmt.Errorf("failed to save away modes: %w", err)
+	}
+	s.modes = modes
+
+	return nil
+}
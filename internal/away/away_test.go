@@ -0,0 +1,69 @@
+package away_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/away"
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testChatID    = int64(123)
+	testRemoteJid = "alice@s.whatsapp.net"
+)
+
+func TestStore(t *testing.T) {
+	repliedAt := time.Date(2022, 3, 31, 10, 0, 0, 0, time.UTC)
+
+	t.Run("set and reply", func(t *testing.T) {
+		store, err := away.New(&away.Opts{Path: filepath.Join(t.TempDir(), "away.json")})
+		require.NoError(t, err)
+
+		mode, ok := store.Get(testChatID)
+		assert.False(t, ok)
+		assert.Equal(t, domain.AwayMode{ChatID: testChatID}, mode)
+
+		mode.Enabled = true
+		mode.Text = "I'm away"
+		mode.Cooldown = time.Hour
+		require.NoError(t, store.Set(mode))
+
+		require.NoError(t, store.SetReplied(testChatID, domain.DefaultWhatsappAccount, testRemoteJid, repliedAt))
+		require.NoError(t, store.SetReplied(testChatID, domain.DefaultWhatsappAccount, testRemoteJid,
+			repliedAt.Add(time.Hour)))
+
+		got, ok := store.Get(testChatID)
+		assert.True(t, ok)
+		assert.Equal(t, "I'm away", got.Text)
+		assert.Equal(t, []domain.AwayReply{{
+			Account:   domain.DefaultWhatsappAccount,
+			RemoteJid: testRemoteJid,
+			RepliedAt: repliedAt.Add(time.Hour),
+		}}, got.Replies)
+
+		_, ok = store.Get(456)
+		assert.False(t, ok)
+	})
+
+	t.Run("away modes are persisted", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "away.json")
+		store, err := away.New(&away.Opts{Path: path})
+		require.NoError(t, err)
+
+		require.NoError(t, store.Set(domain.AwayMode{ChatID: testChatID, Enabled: true, Text: "I'm away"}))
+		require.NoError(t, store.SetReplied(testChatID, domain.DefaultWhatsappAccount, testRemoteJid, repliedAt))
+
+		reloaded, err := away.New(&away.Opts{Path: path})
+		require.NoError(t, err)
+
+		got, ok := reloaded.Get(testChatID)
+		assert.True(t, ok)
+		assert.True(t, got.Enabled)
+		require.Len(t, got.Replies, 1)
+		assert.True(t, repliedAt.Equal(got.Replies[0].RepliedAt))
+	})
+}
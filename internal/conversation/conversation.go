@@ -0,0 +1,93 @@
+package conversation
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/storage"
+)
+
+// Store represents a durable storage of active conversations of the chats.
+type Store struct {
+	mu            sync.Mutex
+	file          *storage.JSONFile
+	conversations []domain.ActiveConversation
+}
+
+// Opts represents options to create new instance of Store.
+type Opts struct {
+	// Path is a path to the file the conversations are persisted to.
+	Path string
+}
+
+// New creates new instance of Store and loads previously saved conversations.
+func New(opts *Opts) (*Store, error) {
+	s := &Store{
+		file:          storage.NewJSONFile(opts.Path),
+		conversations: make([]domain.ActiveConversation, 0),
+	}
+
+	if err := s.file.Load(&s.conversations); err != nil {
+		return nil, fmt.Errorf("failed to load conversations: %w", err)
+	}
+
+	return s, nil
+}
+
+// Get method returns the active conversation of the chat.
+func (s *Store) Get(chatID int64) (domain.ActiveConversation, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.conversations {
+		if c.ChatID == chatID {
+			return c, true
+		}
+	}
+
+	return domain.ActiveConversation{}, false
+}
+
+// Set method saves the active conversation of the chat, the previous one is replaced.
+func (s *Store) Set(conversation domain.ActiveConversation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conversations := make([]domain.ActiveConversation, 0, len(s.conversations)+1)
+	for _, c := range s.conversations {
+		if c.ChatID != conversation.ChatID {
+			conversations = append(conversations, c)
+		}
+	}
+	conversations = append(conversations, conversation)
+
+	return s.save(conversations)
+}
+
+// Delete method clears the active conversation of the chat.
+func (s *Store) Delete(chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conversations := make([]domain.ActiveConversation, 0, len(s.conversations))
+	for _, c := range s.conversations {
+		if c.ChatID != chatID {
+			conversations = append(conversations, c)
+		}
+	}
+	if len(conversations) == len(s.conversations) {
+		return nil
+	}
+
+	return s.save(conversations)
+}
+
+func (s *Store) save(conversations []domain.ActiveConversation) error {
+	if err := s.file.Save(conversations); err != nil {
+		return fmt.Errorf("failed to save conversations: %w", err)
+	}
+	s.conversations = conversations
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package conversation_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/conversation"
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	testConversation := domain.ActiveConversation{
+		ChatID:          123,
+		Account:         domain.DefaultWhatsappAccount,
+		RemoteJid:       "alice@s.whatsapp.net",
+		Name:            "Alice",
+		StatusMessageID: 42,
+	}
+
+	t.Run("set, get and delete", func(t *testing.T) {
+		store, err := conversation.New(&conversation.Opts{Path: filepath.Join(t.TempDir(), "conversations.json")})
+		require.NoError(t, err)
+
+		_, ok := store.Get(testConversation.ChatID)
+		assert.False(t, ok)
+
+		require.NoError(t, store.Set(testConversation))
+		got, ok := store.Get(testConversation.ChatID)
+		require.True(t, ok)
+		assert.Equal(t, testConversation, got)
+
+		bob := testConversation
+		bob.RemoteJid = "bob@s.whatsapp.net"
+		bob.Name = "Bob"
+		require.NoError(t, store.Set(bob))
+		got, ok = store.Get(testConversation.ChatID)
+		require.True(t, ok)
+		assert.Equal(t, bob, got)
+
+		require.NoError(t, store.Delete(testConversation.ChatID))
+		require.NoError(t, store.Delete(testConversation.ChatID))
+		_, ok = store.Get(testConversation.ChatID)
+		assert.False(t, ok)
+	})
+
+	t.Run("conversations are persisted", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "conversations.json")
+		store, err := conversation.New(&conversation.Opts{Path: path})
+		require.NoError(t, err)
+
+		require.NoError(t, store.Set(testConversation))
+
+		reloaded, err := conversation.New(&conversation.Opts{Path: path})
+		require.NoError(t, err)
+
+		got, ok := reloaded.Get(testConversation.ChatID)
+		require.True(t, ok)
+		assert.Equal(t, testConversation, got)
+	})
+}
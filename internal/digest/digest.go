@@ -0,0 +1,114 @@
+package digest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/storage"
+)
+
+// Store represents a durable storage of incoming whatsapp messages
+// collected to the digests of the chats.
+type Store struct {
+	mu      sync.Mutex
+	file    *storage.JSONFile
+	entries []domain.DigestEntry
+}
+
+// Opts represents options to create new instance of Store.
+type Opts struct {
+	// Path is a path to the file the digests are persisted to.
+	Path string
+}
+
+// New creates new instance of Store and loads previously saved digests.
+func New(opts *Opts) (*Store, error) {
+	s := &Store{
+		file:    storage.NewJSONFile(opts.Path),
+		entries: make([]domain.DigestEntry, 0),
+	}
+
+	if err := s.file.Load(&s.entries); err != nil {
+		return nil, fmt.Errorf("failed to load digests: %w", err)
+	}
+
+	return s, nil
+}
+
+// Add method adds the message to the digest of the chat.
+func (s *Store) Add(entry domain.DigestEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]domain.DigestEntry, 0, len(s.entries)+1)
+	entries = append(entries, s.entries...)
+	entries = append(entries, entry)
+
+	return s.save(entries)
+}
+
+// Len method returns the number of messages in the digest of the chat.
+func (s *Store) Len(chatID int64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, entry := range s.entries {
+		if entry.ChatID == chatID {
+			n++
+		}
+	}
+
+	return n
+}
+
+// HasQuietHours method returns true if the digest of the chat has messages
+// received during quiet hours.
+func (s *Store) HasQuietHours(chatID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range s.entries {
+		if entry.ChatID == chatID && entry.QuietHours {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Take method removes the digest of the chat and returns its messages
+// in the order they have been received.
+func (s *Store) Take(chatID int64) ([]domain.DigestEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	taken := make([]domain.DigestEntry, 0)
+	entries := make([]domain.DigestEntry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		if entry.ChatID == chatID {
+			taken = append(taken, entry)
+		} else {
+			entries = append(entries, entry)
+		}
+	}
+	if len(taken) == 0 {
+		return taken, nil
+	}
+
+	if err := s.save(entries); err != nil {
+		return nil, err
+	}
+
+	return taken, nil
+}
+
+func (s *Store) save(entries []domain.DigestEntry) error {
+	if err := s.file.Save(entries); err != nil {
+		return fmt.Errorf("failed to save digests: %w", err)
+	}
+	s.entries = entries
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package digest_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/digest"
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	receivedAt := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	testEntries := []domain.DigestEntry{
+		{ChatID: 123, Account: "default", RemoteJid: "alice-jid", SenderName: "Alice", Text: "hi", ReceivedAt: receivedAt},
+		{ChatID: 456, Account: "default", RemoteJid: "bob-jid", SenderName: "Bob", Text: "hey", ReceivedAt: receivedAt},
+		{ChatID: 123, Account: "work", RemoteJid: "carol-jid", SenderName: "Carol", Text: "hello", ReceivedAt: receivedAt},
+	}
+
+	t.Run("add and take", func(t *testing.T) {
+		store, err := digest.New(&digest.Opts{Path: filepath.Join(t.TempDir(), "digests.json")})
+		require.NoError(t, err)
+
+		for _, entry := range testEntries {
+			require.NoError(t, store.Add(entry))
+		}
+		assert.Equal(t, 2, store.Len(123))
+
+		taken, err := store.Take(123)
+		require.NoError(t, err)
+		assert.Equal(t, []domain.DigestEntry{testEntries[0], testEntries[2]}, taken)
+		assert.Zero(t, store.Len(123))
+		assert.Equal(t, 1, store.Len(456))
+
+		taken, err = store.Take(123)
+		require.NoError(t, err)
+		assert.Empty(t, taken)
+	})
+
+	t.Run("quiet hours", func(t *testing.T) {
+		store, err := digest.New(&digest.Opts{Path: filepath.Join(t.TempDir(), "digests.json")})
+		require.NoError(t, err)
+
+		require.NoError(t, store.Add(testEntries[0]))
+		assert.False(t, store.HasQuietHours(123))
+
+		quietEntry := testEntries[1]
+		quietEntry.QuietHours = true
+		require.NoError(t, store.Add(quietEntry))
+		assert.False(t, store.HasQuietHours(123))
+		assert.True(t, store.HasQuietHours(456))
+	})
+
+	t.Run("digests are persisted", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "digests.json")
+		store, err := digest.New(&digest.Opts{Path: path})
+		require.NoError(t, err)
+
+		require.NoError(t, store.Add(testEntries[0]))
+
+		reloaded, err := digest.New(&digest.Opts{Path: path})
+		require.NoError(t, err)
+
+		taken, err := reloaded.Take(123)
+		require.NoError(t, err)
+		assert.Equal(t, []domain.DigestEntry{testEntries[0]}, taken)
+	})
+}
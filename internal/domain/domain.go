@@ -1,8 +1,21 @@
 package domain
 
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"time"
+)
+
 // TextMessageFmt represents a message format that will be sent to a user in
-// case incoming text messages from whatsapp.
-const TextMessageFmt = "From: %s [jid: %s] \n= = = = = = = = = = = =\nMessage: %s"
+// case incoming text messages from whatsapp. The third argument is a list of
+// tags of the message, e.g. an account tag returned by AccountTag.
+const TextMessageFmt = "From: %s [jid: %s]%s \n= = = = = = = = = = = =\nMessage: %s"
+
+// DefaultWhatsappAccount is a name of the whatsapp account that is used
+// when no account is specified.
+const DefaultWhatsappAccount = "default"
 
 // EventType represents an event type.
 type EventType string
@@ -15,6 +28,38 @@ const (
 	TextMessageEventType EventType = "text_message" // whatsapp only
 	ReplyEventType       EventType = "reply"        // telegram only
 	DisconnectEventType  EventType = "disconnect_event"
+	RestoreEventType     EventType = "restore_event" // whatsapp only
+	RetryEventType       EventType = "retry"         // telegram only
+	CancelLoginEventType EventType = "cancel_login"  // telegram only
+	LoginResultEventType EventType = "login_result"
+	TeamEventType        EventType = "team"       // telegram only
+	AssignEventType      EventType = "assign"     // telegram only
+	TopicsEventType      EventType = "topics"     // telegram only
+	RouteEventType       EventType = "route"      // telegram only
+	ChatEventType        EventType = "chat"       // telegram only
+	CloseChatEventType   EventType = "close_chat" // telegram only
+	SettingsEventType    EventType = "settings"   // telegram only
+	DigestEventType      EventType = "digest"     // telegram only
+	RulesEventType       EventType = "rules"      // telegram only
+	QuietEventType       EventType = "quiet"      // telegram only
+	TickEventType        EventType = "tick"
+	ScheduleEventType    EventType = "schedule"     // telegram only
+	UnscheduleEventType  EventType = "unschedule"   // telegram only
+	AwayEventType        EventType = "away"         // telegram only
+	SearchEventType      EventType = "search"       // telegram only
+	ExportEventType      EventType = "export"       // telegram only
+	HistoryEventType     EventType = "history"      // telegram only
+	ChatsEventType       EventType = "chats"        // telegram only
+	PresenceEventType    EventType = "presence"     // whatsapp only
+	LastSeenEventType    EventType = "lastseen"     // telegram only
+	RevokeEventType      EventType = "revoke"       // whatsapp only
+	EditEventType        EventType = "edit"         // telegram only
+	DeleteEventType      EventType = "delete"       // telegram only
+	ReactionEventType    EventType = "reaction"     // whatsapp only
+	ReactEventType       EventType = "react"        // telegram only
+	LocationEventType    EventType = "location"     // whatsapp only
+	ContactCardEventType EventType = "contact_card" // whatsapp only
+	DocumentEventType    EventType = "document"     // whatsapp only
 )
 
 // Event represents a generic event API.
@@ -42,6 +87,9 @@ type LoginEvent struct {
 
 	// FromUser is a telegram username of the client that interacts with the bot.
 	FromUser string
+
+	// Account is a name of the whatsapp account to log in.
+	Account string
 }
 
 func (le *LoginEvent) Type() EventType {
@@ -55,6 +103,9 @@ type LogoutEvent struct {
 
 	// FromUser is a telegram username of the client that interacts with the bot.
 	FromUser string
+
+	// Account is a name of the whatsapp account to log out.
+	Account string
 }
 
 func (lo *LogoutEvent) Type() EventType {
@@ -87,6 +138,16 @@ type TextMessageEvent struct {
 
 	// Text is a text message body.
 	Text string
+
+	// Account is a name of the whatsapp account the message has been received by.
+	Account string
+
+	// WhatsappMessageID is an identifier of the whatsapp message, if it's known.
+	WhatsappMessageID string
+
+	// WhatsappSenderJid is a whatsapp identifier of the member of the group that sent
+	// the message, it's empty for other conversations.
+	WhatsappSenderJid string
 }
 
 func (te *TextMessageEvent) Type() EventType {
@@ -101,11 +162,37 @@ type ReplyEvent struct {
 	// FromUser is a telegram username of the client that interacts with the bot.
 	FromUser string
 
+	// FromName is a full name of the telegram user that has sent the reply.
+	FromName string
+
 	// Reply is a reply text message body.
 	Reply string
 
 	// RemoteJid is a whatsapp user identifier.
 	RemoteJid string
+
+	// Account is a name of the whatsapp account to reply from.
+	Account string
+
+	// ThreadID is an identifier of the telegram forum topic the reply is posted to,
+	// it's used to find the whatsapp conversation if RemoteJid is empty.
+	ThreadID int
+
+	// MessageID is an identifier of the telegram message with the reply.
+	MessageID int
+
+	// Location is a location shared as the reply, the reply text is empty then.
+	Location *WhatsappLocation
+
+	// ContactCard is a contact shared as the reply, the reply text is empty then.
+	ContactCard *WhatsappContactCard
+
+	// Media is a photo or a document sent as the reply, the reply text is its caption then.
+	Media *WhatsappMedia
+
+	// UnsupportedMedia is a name of the media that can't be sent to whatsapp,
+	// e.g. a sticker, it's empty for supported messages.
+	UnsupportedMedia string
 }
 
 func (re *ReplyEvent) Type() EventType {
@@ -116,64 +203,1870 @@ func (re *ReplyEvent) Type() EventType {
 type DisconnectEvent struct {
 	// ChatID is telegram bot chat identifier.
 	ChatID int64
+
+	// Account is a name of the whatsapp account that has been disconnected.
+	Account string
 }
 
 func (de *DisconnectEvent) Type() EventType {
 	return DisconnectEventType
 }
 
-// EventsHandler describes events handler API.
-type EventsHandler interface {
-	HandleStartEvent(*StartEvent) error
-	HandleLoginEvent(*LoginEvent) error
-	HandleLogoutEvent(*LogoutEvent) error
-	HandleHelpEvent(*HelpEvent) error
-	HandleRepeatedLoginEvent(*LoginEvent) error
-	HandleTextMessageEvent(*TextMessageEvent) error
-	HandleReplyEvent(*ReplyEvent) error
-	HandleDisconnectEvent(*DisconnectEvent) error
-	IsLoggedIn() bool
+// RestoreEvent represents an event of whatsapp session being restored
+// after a connection failure.
+type RestoreEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// Account is a name of the whatsapp account that has been restored.
+	Account string
 }
 
-/* Whatsapp related domain entities */
+func (re *RestoreEvent) Type() EventType {
+	return RestoreEventType
+}
 
-// WhatsappContact represents whatsapp contact.
-type WhatsappContact struct {
-	// Jid is a contact's identifier.
-	Jid string
+// RetryEvent represents a request to retry sending of a message that
+// couldn't be delivered to whatsapp.
+type RetryEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
 
-	// Name is a name of the contact.
-	Name string
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// CallbackID is an identifier of telegram callback query to answer.
+	CallbackID string
+
+	// MessageID is an identifier of the outbox message to retry.
+	MessageID string
 }
 
-// WhatsappMessageType represents whatsapp message type.
-type WhatsappMessageType string
+func (re *RetryEvent) Type() EventType {
+	return RetryEventType
+}
+
+// CancelLoginEvent represents a request to abort the login that is in progress.
+type CancelLoginEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
 
-const WhatsappTextMessageType = "text_message"
+	// CallbackID is an identifier of telegram callback query to answer.
+	CallbackID string
 
-// WhatsappMessage is an interface that represents whatsapp messages in general.
-type WhatsappMessage interface {
-	Type() WhatsappMessageType
+	// Account is a name of the whatsapp account to cancel login of.
+	Account string
 }
 
-// WhatsappTextMessage represents a whatsapp text message.
-type WhatsappTextMessage struct {
-	// RemoteJid is an identifier of a user the message is sent to.
+func (ce *CancelLoginEvent) Type() EventType {
+	return CancelLoginEventType
+}
+
+// LoginResultEvent represents a result of the login that has been running
+// in background.
+type LoginResultEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// QRMessageID is an identifier of telegram message with the QR-code,
+	// it's zero if no QR-code has been sent.
+	QRMessageID int
+
+	// WhatsappClient is a client of the established session.
+	WhatsappClient WhatsappClient
+
+	// Err is an error the login has failed with.
+	Err error
+
+	// Account is a name of the whatsapp account the login belongs to.
+	Account string
+}
+
+func (le *LoginResultEvent) Type() EventType {
+	return LoginResultEventType
+}
+
+// TeamEvent represents a command that manages the team sharing the chat.
+type TeamEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// Args is a list of the command arguments.
+	Args []string
+}
+
+func (te *TeamEvent) Type() EventType {
+	return TeamEventType
+}
+
+// AssignEvent represents a request to assign a whatsapp conversation to
+// a member of the team.
+type AssignEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// Assignee is a telegram username of the member the conversation is assigned to,
+	// the conversation is released if it's empty.
+	Assignee string
+
+	// RemoteJid is a whatsapp user identifier of the conversation.
+	RemoteJid string
+
+	// Account is a name of the whatsapp account of the conversation.
+	Account string
+}
+
+func (ae *AssignEvent) Type() EventType {
+	return AssignEventType
+}
+
+// TopicsEvent represents a command that turns bridging into telegram forum topics on and off.
+type TopicsEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// Args is a list of the command arguments.
+	Args []string
+}
+
+func (te *TopicsEvent) Type() EventType {
+	return TopicsEventType
+}
+
+// RouteEvent represents a command that manages routes of whatsapp conversations
+// to other telegram chats.
+type RouteEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// Args is a list of the command arguments.
+	Args []string
+
+	// RemoteJid is a whatsapp user identifier of the replied message, if any.
+	RemoteJid string
+
+	// Account is a name of the whatsapp account of the replied message, if any.
+	Account string
+}
+
+func (re *RouteEvent) Type() EventType {
+	return RouteEventType
+}
+
+// ChatEvent represents a request to select the active conversation plain
+// messages are sent to.
+type ChatEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// CallbackID is an identifier of telegram callback query to answer,
+	// it's empty if the event is not caused by a button.
+	CallbackID string
+
+	// Contact is a name, phone number or jid of the whatsapp contact to search for.
+	Contact string
+
+	// RemoteJid is a whatsapp user identifier of the conversation, it's used
+	// instead of Contact if it's known.
+	RemoteJid string
+
+	// Account is a name of the whatsapp account of the conversation.
+	Account string
+}
+
+func (ce *ChatEvent) Type() EventType {
+	return ChatEventType
+}
+
+// CloseChatEvent represents a request to clear the active conversation.
+type CloseChatEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// CallbackID is an identifier of telegram callback query to answer,
+	// it's empty if the event is not caused by a button.
+	CallbackID string
+}
+
+func (ce *CloseChatEvent) Type() EventType {
+	return CloseChatEventType
+}
+
+// SettingsEvent represents a command that shows or changes the way
+// messages of whatsapp contacts are delivered.
+type SettingsEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// CallbackID is an identifier of telegram callback query to answer,
+	// it's empty if the event is not caused by a button.
+	CallbackID string
+
+	// MessageID is an identifier of telegram message with the pressed button.
+	MessageID int
+
+	// Mode is a new mode of the contact, the settings are shown if it's empty.
+	Mode ContactMode
+
+	// Args is a list of the command arguments.
+	Args []string
+
+	// RemoteJid is a whatsapp user identifier of the replied message, if any.
 	RemoteJid string
 
+	// Account is a name of the whatsapp account of the replied message, if any.
+	Account string
+}
+
+func (se *SettingsEvent) Type() EventType {
+	return SettingsEventType
+}
+
+// DigestEvent represents a request to deliver messages collected to the digest
+// or to schedule the daily digest.
+type DigestEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// Args is a list of the command arguments.
+	Args []string
+}
+
+func (de *DigestEvent) Type() EventType {
+	return DigestEventType
+}
+
+// RulesEvent represents a command that manages rules applied to incoming messages.
+type RulesEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// Args is a list of the command arguments.
+	Args []string
+}
+
+func (re *RulesEvent) Type() EventType {
+	return RulesEventType
+}
+
+// QuietEvent represents a command that manages quiet hours of the chat.
+type QuietEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// Args is a list of the command arguments.
+	Args []string
+}
+
+func (qe *QuietEvent) Type() EventType {
+	return QuietEventType
+}
+
+// TickEvent is sent to every events handler periodically to run scheduled jobs.
+type TickEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// Time is the time of the tick.
+	Time time.Time
+}
+
+func (te *TickEvent) Type() EventType {
+	return TickEventType
+}
+
+// ScheduleEvent represents a request to send a whatsapp message at the specified time,
+// scheduled messages are listed if the time is empty.
+type ScheduleEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// Contact is a name, a phone number or a jid of the whatsapp contact.
+	Contact string
+
+	// Time is the time the message is sent at, e.g. "15:04", "2006-01-02T15:04" or "+1h30m".
+	Time string
+
 	// Text is a text of the message.
 	Text string
+
+	// RemoteJid is a whatsapp user identifier of the replied message, if any.
+	RemoteJid string
+
+	// Account is a name of the whatsapp account of the replied message, if any.
+	Account string
 }
 
-// Type method returns type of the message.
-func (msg *WhatsappTextMessage) Type() WhatsappMessageType {
-	return WhatsappTextMessageType
+func (se *ScheduleEvent) Type() EventType {
+	return ScheduleEventType
 }
 
-// WhatsappClient represents a common interface that describes whatsapp client behaviour.
-type WhatsappClient interface {
-	Restore() error
-	GetContacts() map[string]WhatsappContact
-	Send(msg WhatsappMessage) error
-	Logout() error
+// UnscheduleEvent represents a request to cancel a scheduled message.
+type UnscheduleEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// CallbackID is an identifier of telegram callback query, if the event is caused by one.
+	CallbackID string
+
+	// MessageID is an identifier of telegram message with the pressed button, if any.
+	MessageID int
+
+	// ID is an identifier of the scheduled message.
+	ID string
+}
+
+func (ue *UnscheduleEvent) Type() EventType {
+	return UnscheduleEventType
+}
+
+// AwayEvent represents a command that manages the auto-reply of the chat.
+type AwayEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// Args is the text of the command arguments as is.
+	Args string
+}
+
+func (ae *AwayEvent) Type() EventType {
+	return AwayEventType
+}
+
+// SearchEvent represents a command that searches the message archive.
+type SearchEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// Query is a text to search for.
+	Query string
+
+	// Contact is a name, phone number or jid of the contact to search messages of, optional.
+	Contact string
+}
+
+func (se *SearchEvent) Type() EventType {
+	return SearchEventType
+}
+
+// ExportEvent represents a command that exports the conversation from the message archive.
+type ExportEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// Contact is a name, phone number or jid of the contact to export messages of.
+	Contact string
+
+	// From is the first date of the exported messages, e.g. 2006-01-02, optional.
+	From string
+
+	// To is the last date of the exported messages, optional.
+	To string
+}
+
+func (ee *ExportEvent) Type() EventType {
+	return ExportEventType
+}
+
+// HistoryEvent represents a request to load recent messages of the conversation from whatsapp history.
+type HistoryEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// Contact is a name, a phone number or a jid of the whatsapp contact.
+	Contact string
+
+	// Count is a number of the loaded messages, optional.
+	Count string
+
+	// RemoteJid is a whatsapp user identifier of the replied message, if any.
+	RemoteJid string
+
+	// Account is a name of the whatsapp account of the replied message, if any.
+	Account string
+}
+
+func (he *HistoryEvent) Type() EventType {
+	return HistoryEventType
+}
+
+// ChatsEvent represents a request to show the recent whatsapp conversations
+// or to apply an action of the overview to one of them.
+type ChatsEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// Action is an action applied to the conversation, the overview is shown if it's empty.
+	Action ChatsAction
+
+	// RemoteJid is a whatsapp user identifier of the conversation the action is applied to.
+	RemoteJid string
+
+	// Account is a name of the whatsapp account of the conversation.
+	Account string
+
+	// CallbackID is an identifier of the callback query the event is sent by, if any.
+	CallbackID string
+
+	// MessageID is an identifier of the overview message the button belongs to.
+	MessageID int
+}
+
+func (ce *ChatsEvent) Type() EventType {
+	return ChatsEventType
+}
+
+// ChatsAction represents an action of the conversations overview.
+type ChatsAction string
+
+const (
+	ChatsActionRead   ChatsAction = "read"
+	ChatsActionMute   ChatsAction = "mute"
+	ChatsActionUnmute ChatsAction = "unmute"
+)
+
+// PresenceEvent represents a presence update of a whatsapp contact.
+type PresenceEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// WhatsappRemoteJid is a whatsapp identifier of the conversation, it's a group
+	// if a member of the group is typing.
+	WhatsappRemoteJid string
+
+	// Presence is the current presence of the contact.
+	Presence WhatsappPresence
+
+	// LastSeen is the time the contact has been online last time, if it's known.
+	LastSeen time.Time
+
+	// Account is a name of the whatsapp account the update has been received by.
+	Account string
+}
+
+func (pe *PresenceEvent) Type() EventType {
+	return PresenceEventType
+}
+
+// LastSeenEvent represents a request to show availability of a whatsapp contact.
+type LastSeenEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// Contact is a name, a phone number or a jid of the whatsapp contact.
+	Contact string
+
+	// RemoteJid is a whatsapp user identifier of the replied message, if any.
+	RemoteJid string
+
+	// Account is a name of the whatsapp account of the replied message, if any.
+	Account string
+}
+
+func (le *LastSeenEvent) Type() EventType {
+	return LastSeenEventType
+}
+
+// RevokeEvent represents a whatsapp message deleted for everyone by its sender.
+type RevokeEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// WhatsappRemoteJid is a whatsapp identifier of the conversation the message belongs to.
+	WhatsappRemoteJid string
+
+	// WhatsappMessageID is an identifier of the deleted whatsapp message.
+	WhatsappMessageID string
+
+	// Account is a name of the whatsapp account the message has been received by.
+	Account string
+}
+
+func (re *RevokeEvent) Type() EventType {
+	return RevokeEventType
+}
+
+// EditEvent represents an edit of a telegram message sent by the user.
+type EditEvent struct {
+	// ChatID is telegram chat identifier the message belongs to.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// FromName is a full name of the telegram user.
+	FromName string
+
+	// MessageID is an identifier of the edited telegram message.
+	MessageID int
+
+	// Text is a new text of the message.
+	Text string
+}
+
+func (ee *EditEvent) Type() EventType {
+	return EditEventType
+}
+
+// DeleteEvent represents a request to delete a whatsapp message sent from telegram for everyone.
+type DeleteEvent struct {
+	// ChatID is telegram chat identifier the command has been sent to.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// MessageID is an identifier of the replied telegram message, it's zero
+	// if the command doesn't reply to a message.
+	MessageID int
+}
+
+func (de *DeleteEvent) Type() EventType {
+	return DeleteEventType
+}
+
+// ReactionEvent represents a reaction of a whatsapp contact to a message.
+type ReactionEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// WhatsappRemoteJid is a whatsapp identifier of the conversation the message belongs to.
+	WhatsappRemoteJid string
+
+	// WhatsappSenderName is a name of the contact that has reacted.
+	WhatsappSenderName string
+
+	// WhatsappSenderJid is a whatsapp identifier of the contact that has reacted,
+	// every member of a group has its own reaction.
+	WhatsappSenderJid string
+
+	// WhatsappMessageID is an identifier of the whatsapp message the contact has reacted to.
+	WhatsappMessageID string
+
+	// Emoji is the reaction, it's empty if the reaction is removed.
+	Emoji string
+
+	// Account is a name of the whatsapp account the reaction has been received by.
+	Account string
+}
+
+func (re *ReactionEvent) Type() EventType {
+	return ReactionEventType
+}
+
+// ReactEvent represents a reaction of the telegram user to a message.
+type ReactEvent struct {
+	// ChatID is telegram chat identifier the message belongs to.
+	ChatID int64
+
+	// FromUser is a telegram username of the client that interacts with the bot.
+	FromUser string
+
+	// MessageID is an identifier of the telegram message the user has reacted to.
+	MessageID int
+
+	// Emoji is the reaction, it's empty if the reaction is removed.
+	Emoji string
+}
+
+func (re *ReactEvent) Type() EventType {
+	return ReactEventType
+}
+
+// LocationEvent represents a location shared by a whatsapp contact. Live locations
+// are sent again every time the contact moves.
+type LocationEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// WhatsappRemoteJid is a whatsapp identifier of the conversation.
+	WhatsappRemoteJid string
+
+	// WhatsappSenderName is a name of the contact that has shared the location.
+	WhatsappSenderName string
+
+	// WhatsappSenderJid is a whatsapp identifier of the member of the group that has
+	// shared the location, it's empty for other conversations.
+	WhatsappSenderJid string
+
+	// WhatsappMessageID is an identifier of the whatsapp message, if it's known.
+	WhatsappMessageID string
+
+	// Location is the shared location.
+	Location WhatsappLocation
+
+	// Live indicates that the location is updated while the contact is moving.
+	Live bool
+
+	// LiveSequence is a sequence number of the live location update, it grows while
+	// the contact shares the location and starts over once the location is shared again.
+	LiveSequence int64
+
+	// Account is a name of the whatsapp account the location has been received by.
+	Account string
+}
+
+func (le *LocationEvent) Type() EventType {
+	return LocationEventType
+}
+
+// ContactCardEvent represents a contact card shared by a whatsapp contact.
+type ContactCardEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// WhatsappRemoteJid is a whatsapp identifier of the conversation.
+	WhatsappRemoteJid string
+
+	// WhatsappSenderName is a name of the contact that has shared the card.
+	WhatsappSenderName string
+
+	// WhatsappSenderJid is a whatsapp identifier of the member of the group that has
+	// shared the card, it's empty for other conversations.
+	WhatsappSenderJid string
+
+	// WhatsappMessageID is an identifier of the whatsapp message, if it's known.
+	WhatsappMessageID string
+
+	// ContactCard is the shared contact card.
+	ContactCard WhatsappContactCard
+
+	// Account is a name of the whatsapp account the card has been received by.
+	Account string
+}
+
+func (ce *ContactCardEvent) Type() EventType {
+	return ContactCardEventType
+}
+
+// DocumentEvent represents a document, e.g. a PDF, sent by a whatsapp contact.
+type DocumentEvent struct {
+	// ChatID is telegram bot chat identifier.
+	ChatID int64
+
+	// WhatsappRemoteJid is a whatsapp identifier of the conversation.
+	WhatsappRemoteJid string
+
+	// WhatsappSenderName is a name of the contact that has sent the document.
+	WhatsappSenderName string
+
+	// WhatsappSenderJid is a whatsapp identifier of the member of the group that has
+	// sent the document, it's empty for other conversations.
+	WhatsappSenderJid string
+
+	// WhatsappMessageID is an identifier of the whatsapp message, if it's known.
+	WhatsappMessageID string
+
+	// Document is the received document.
+	Document WhatsappDocument
+
+	// Account is a name of the whatsapp account the document has been received by.
+	Account string
+}
+
+func (de *DocumentEvent) Type() EventType {
+	return DocumentEventType
+}
+
+// EventsHandler describes events handler API.
+type EventsHandler interface {
+	HandleStartEvent(*StartEvent) error
+	HandleLoginEvent(*LoginEvent) error
+	HandleLogoutEvent(*LogoutEvent) error
+	HandleHelpEvent(*HelpEvent) error
+	HandleRepeatedLoginEvent(*LoginEvent) error
+	HandleTextMessageEvent(*TextMessageEvent) error
+	HandleReplyEvent(*ReplyEvent) error
+	HandleDisconnectEvent(*DisconnectEvent) error
+	HandleRestoreEvent(*RestoreEvent) error
+	HandleRetryEvent(*RetryEvent) error
+	HandleCancelLoginEvent(*CancelLoginEvent) error
+	HandleLoginResultEvent(*LoginResultEvent) error
+	HandleTeamEvent(*TeamEvent) error
+	HandleAssignEvent(*AssignEvent) error
+	HandleTopicsEvent(*TopicsEvent) error
+	HandleRouteEvent(*RouteEvent) error
+	HandleChatEvent(*ChatEvent) error
+	HandleCloseChatEvent(*CloseChatEvent) error
+	HandleSettingsEvent(*SettingsEvent) error
+	HandleDigestEvent(*DigestEvent) error
+	HandleRulesEvent(*RulesEvent) error
+	HandleQuietEvent(*QuietEvent) error
+	HandleTickEvent(*TickEvent) error
+	HandleScheduleEvent(*ScheduleEvent) error
+	HandleUnscheduleEvent(*UnscheduleEvent) error
+	HandleAwayEvent(*AwayEvent) error
+	HandleSearchEvent(*SearchEvent) error
+	HandleExportEvent(*ExportEvent) error
+	HandleHistoryEvent(*HistoryEvent) error
+	HandleChatsEvent(*ChatsEvent) error
+	HandlePresenceEvent(*PresenceEvent) error
+	HandleLastSeenEvent(*LastSeenEvent) error
+	HandleRevokeEvent(*RevokeEvent) error
+	HandleEditEvent(*EditEvent) error
+	HandleDeleteEvent(*DeleteEvent) error
+	HandleReactionEvent(*ReactionEvent) error
+	HandleReactEvent(*ReactEvent) error
+	HandleLocationEvent(*LocationEvent) error
+	HandleContactCardEvent(*ContactCardEvent) error
+	HandleDocumentEvent(*DocumentEvent) error
+	IsLoggedIn(account string) bool
+}
+
+/* Whatsapp related domain entities */
+
+// WhatsappContact represents whatsapp contact.
+type WhatsappContact struct {
+	// Jid is a contact's identifier.
+	Jid string
+
+	// Name is a name of the contact.
+	Name string
+}
+
+// WhatsappChat represents a whatsapp conversation.
+type WhatsappChat struct {
+	// Jid is an identifier of the conversation.
+	Jid string
+
+	// Name is a name of the conversation.
+	Name string
+
+	// LastMessageAt is the time of the last message of the conversation.
+	LastMessageAt time.Time
+
+	// Unread is a number of the unread messages of the conversation.
+	Unread int
+
+	// Preview is a text of the last message of the conversation, if it's known.
+	Preview string
+}
+
+// WhatsappPresence represents a presence of a whatsapp contact.
+type WhatsappPresence string
+
+const (
+	WhatsappPresenceAvailable   WhatsappPresence = "available"
+	WhatsappPresenceUnavailable WhatsappPresence = "unavailable"
+	WhatsappPresenceComposing   WhatsappPresence = "composing"
+	WhatsappPresencePaused      WhatsappPresence = "paused"
+)
+
+// WhatsappHistoryMessage represents a message loaded from whatsapp history.
+type WhatsappHistoryMessage struct {
+	// ID is an identifier of the message.
+	ID string
+
+	// RemoteJid is an identifier of the conversation.
+	RemoteJid string
+
+	// SenderName is a name of the sender, it's empty for own messages.
+	SenderName string
+
+	// FromMe is true if the message has been sent from the account.
+	FromMe bool
+
+	// Text is a text of the message.
+	Text string
+
+	// Timestamp is the time the message has been sent.
+	Timestamp time.Time
+}
+
+// WhatsappMessageType represents whatsapp message type.
+type WhatsappMessageType string
+
+const (
+	WhatsappTextMessageType        = "text_message"
+	WhatsappReactionMessageType    = "reaction_message"
+	WhatsappLocationMessageType    = "location_message"
+	WhatsappContactCardMessageType = "contact_card_message"
+	WhatsappMediaMessageType       = "media_message"
+)
+
+// WhatsappMessage is an interface that represents whatsapp messages in general.
+type WhatsappMessage interface {
+	Type() WhatsappMessageType
+}
+
+// WhatsappTextMessage represents a whatsapp text message.
+type WhatsappTextMessage struct {
+	// ID is an identifier of the message, the client generates it if it's empty.
+	ID string
+
+	// RemoteJid is an identifier of a user the message is sent to.
+	RemoteJid string
+
+	// Text is a text of the message.
+	Text string
+}
+
+// Type method returns type of the message.
+func (msg *WhatsappTextMessage) Type() WhatsappMessageType {
+	return WhatsappTextMessageType
+}
+
+// WhatsappReactionMessage represents a reaction to a whatsapp message.
+type WhatsappReactionMessage struct {
+	// RemoteJid is an identifier of the conversation the message belongs to.
+	RemoteJid string
+
+	// MessageID is an identifier of the message the reaction is sent to.
+	MessageID string
+
+	// SenderJid is an identifier of the member of the group that has sent the message,
+	// it's empty for other conversations.
+	SenderJid string
+
+	// FromMe indicates that the message has been sent by the account.
+	FromMe bool
+
+	// Emoji is the reaction, the previous reaction is removed if it's empty.
+	Emoji string
+}
+
+// Type method returns type of the message.
+func (msg *WhatsappReactionMessage) Type() WhatsappMessageType {
+	return WhatsappReactionMessageType
+}
+
+// WhatsappLocation represents a geographic location shared in whatsapp.
+type WhatsappLocation struct {
+	// Latitude is a latitude of the location in degrees.
+	Latitude float64 `json:"latitude"`
+
+	// Longitude is a longitude of the location in degrees.
+	Longitude float64 `json:"longitude"`
+
+	// Name is a name of the place, optional.
+	Name string `json:"name,omitempty"`
+
+	// Address is an address of the place, optional.
+	Address string `json:"address,omitempty"`
+}
+
+// WhatsappLocationMessage represents an outgoing whatsapp location message.
+type WhatsappLocationMessage struct {
+	// ID is an identifier the message is sent with, it's generated if it's empty.
+	ID string
+
+	// RemoteJid is an identifier of the conversation the message is sent to.
+	RemoteJid string
+
+	// Location is the location to share.
+	Location WhatsappLocation
+}
+
+// Type method returns type of the message.
+func (msg *WhatsappLocationMessage) Type() WhatsappMessageType {
+	return WhatsappLocationMessageType
+}
+
+// WhatsappContactCard represents a contact card shared in whatsapp.
+type WhatsappContactCard struct {
+	// DisplayName is a name of the contact shown in the conversation.
+	DisplayName string `json:"display_name"`
+
+	// Vcard is the contact card in vCard format.
+	Vcard string `json:"vcard"`
+}
+
+// WhatsappDocument represents a document received from whatsapp.
+type WhatsappDocument struct {
+	// FileName is an original name of the file, if any.
+	FileName string
+
+	// MimeType is a mime type of the file, if known.
+	MimeType string
+
+	// Caption is a caption of the document, if any.
+	Caption string
+
+	// Size is a size of the file in bytes.
+	Size int64
+
+	// Data is content of the file, it's nil if the file couldn't be downloaded.
+	Data []byte
+}
+
+// WhatsappMediaKind represents a kind of the media sent to whatsapp.
+type WhatsappMediaKind string
+
+const (
+	WhatsappMediaImage    WhatsappMediaKind = "image"
+	WhatsappMediaDocument WhatsappMediaKind = "document"
+)
+
+// WhatsappMedia represents a photo or a document sent to whatsapp from telegram.
+// The content isn't kept, it's downloaded from telegram when the message is sent.
+type WhatsappMedia struct {
+	// Kind is a kind of the media.
+	Kind WhatsappMediaKind `json:"kind"`
+
+	// TelegramFileID is an identifier of the telegram file with the content.
+	TelegramFileID string `json:"telegram_file_id"`
+
+	// FileName is an original name of the file, if any.
+	FileName string `json:"file_name,omitempty"`
+
+	// MimeType is a mime type of the file, if known.
+	MimeType string `json:"mime_type,omitempty"`
+
+	// Caption is a caption of the media, if any.
+	Caption string `json:"caption,omitempty"`
+
+	// Size is a size of the file in bytes, if known.
+	Size int64 `json:"size,omitempty"`
+}
+
+// WhatsappMediaMessage represents an outgoing whatsapp photo or document message.
+type WhatsappMediaMessage struct {
+	// ID is an identifier the message is sent with, it's generated if it's empty.
+	ID string
+
+	// RemoteJid is an identifier of the conversation the message is sent to.
+	RemoteJid string
+
+	// Media describes the media to send.
+	Media WhatsappMedia
+
+	// Data is content of the file, it has to be downloaded before the message is sent.
+	Data []byte
+}
+
+// Type method returns type of the message.
+func (msg *WhatsappMediaMessage) Type() WhatsappMessageType {
+	return WhatsappMediaMessageType
+}
+
+// WhatsappContactCardMessage represents an outgoing whatsapp contact message.
+type WhatsappContactCardMessage struct {
+	// ID is an identifier the message is sent with, it's generated if it's empty.
+	ID string
+
+	// RemoteJid is an identifier of the conversation the message is sent to.
+	RemoteJid string
+
+	// ContactCard is the contact card to share.
+	ContactCard WhatsappContactCard
+}
+
+// Type method returns type of the message.
+func (msg *WhatsappContactCardMessage) Type() WhatsappMessageType {
+	return WhatsappContactCardMessageType
+}
+
+// OutboxMessage represents an outgoing whatsapp message that is waiting
+// to be delivered.
+type OutboxMessage struct {
+	// ID is a unique identifier of the message.
+	ID string `json:"id"`
+
+	// ChatID is telegram bot chat identifier the message was sent from.
+	ChatID int64 `json:"chat_id"`
+
+	// Account is a name of the whatsapp account the message is sent from.
+	Account string `json:"account"`
+
+	// RemoteJid is an identifier of a user the message is sent to.
+	RemoteJid string `json:"remote_jid"`
+
+	// Text is a text of the message.
+	Text string `json:"text"`
+
+	// Location is the location to share, the text describes it then.
+	Location *WhatsappLocation `json:"location,omitempty"`
+
+	// ContactCard is the contact card to share, the text describes it then.
+	ContactCard *WhatsappContactCard `json:"contact_card,omitempty"`
+
+	// Media is the photo or the document to send, the text describes it then.
+	Media *WhatsappMedia `json:"media,omitempty"`
+
+	// WhatsappMessageID is an identifier the message is sent to whatsapp with,
+	// so it can be edited or deleted later.
+	WhatsappMessageID string `json:"whatsapp_message_id,omitempty"`
+
+	// Attempts is a number of failed delivery attempts.
+	Attempts int `json:"attempts"`
+
+	// LastError is an error of the last failed delivery attempt.
+	LastError string `json:"last_error,omitempty"`
+
+	// Failed indicates that the message won't be retried until it's
+	// explicitly requested.
+	Failed bool `json:"failed"`
+
+	// CreatedAt is a time when the message has been queued.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WhatsappMessage returns whatsapp message that is represented by the outbox message.
+func (msg *OutboxMessage) WhatsappMessage() WhatsappMessage {
+	if msg.Location != nil {
+		return &WhatsappLocationMessage{
+			ID:        msg.WhatsappMessageID,
+			RemoteJid: msg.RemoteJid,
+			Location:  *msg.Location,
+		}
+	}
+
+	if msg.ContactCard != nil {
+		return &WhatsappContactCardMessage{
+			ID:          msg.WhatsappMessageID,
+			RemoteJid:   msg.RemoteJid,
+			ContactCard: *msg.ContactCard,
+		}
+	}
+
+	if msg.Media != nil {
+		return &WhatsappMediaMessage{
+			ID:        msg.WhatsappMessageID,
+			RemoteJid: msg.RemoteJid,
+			Media:     *msg.Media,
+		}
+	}
+
+	return &WhatsappTextMessage{
+		ID:        msg.WhatsappMessageID,
+		RemoteJid: msg.RemoteJid,
+		Text:      msg.Text,
+	}
+}
+
+// Team represents telegram users that share whatsapp accounts bridged to
+// a telegram group.
+type Team struct {
+	// ChatID is telegram bot chat identifier the team works in.
+	ChatID int64 `json:"chat_id"`
+
+	// Owner is a telegram username of the user who manages the team.
+	Owner string `json:"owner"`
+
+	// Members is a list of telegram usernames of the team members except the owner.
+	Members []string `json:"members"`
+
+	// SignReplies indicates that replies are signed with names of the members.
+	SignReplies bool `json:"sign_replies"`
+
+	// Assignments is a list of conversations claimed by the members.
+	Assignments []Assignment `json:"assignments"`
+}
+
+// Assignment represents a whatsapp conversation claimed by a team member.
+type Assignment struct {
+	// Account is a name of the whatsapp account of the conversation.
+	Account string `json:"account"`
+
+	// RemoteJid is a whatsapp user identifier of the conversation.
+	RemoteJid string `json:"remote_jid"`
+
+	// Assignee is a telegram username of the member the conversation is assigned to.
+	Assignee string `json:"assignee"`
+}
+
+// IsOwner method returns true if the user owns the team.
+func (t *Team) IsOwner(user string) bool {
+	return user != "" && strings.EqualFold(t.Owner, user)
+}
+
+// IsMember method returns true if the user is the owner or a member of the team.
+func (t *Team) IsMember(user string) bool {
+	if t.IsOwner(user) {
+		return true
+	}
+
+	for _, member := range t.Members {
+		if user != "" && strings.EqualFold(member, user) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Assignee method returns a member the conversation is assigned to,
+// empty string is returned if it's not assigned.
+func (t *Team) Assignee(account, remoteJid string) string {
+	for _, assignment := range t.Assignments {
+		if assignment.Account == account && assignment.RemoteJid == remoteJid {
+			return assignment.Assignee
+		}
+	}
+
+	return ""
+}
+
+// Route represents a rule that delivers messages of whatsapp conversations
+// to a telegram chat other than the one the account is logged in from.
+type Route struct {
+	// OwnerChatID is telegram chat identifier the whatsapp account is logged in from.
+	OwnerChatID int64 `json:"owner_chat_id"`
+
+	// Account is a name of the whatsapp account of the conversations.
+	Account string `json:"account"`
+
+	// Jid is a whatsapp user or group identifier, it may be a pattern
+	// like "*@g.us" to route several conversations.
+	Jid string `json:"jid"`
+
+	// ChatID is telegram chat identifier the messages are delivered to.
+	ChatID int64 `json:"chat_id"`
+}
+
+// Matches method returns true if the route applies to the whatsapp conversation.
+func (r *Route) Matches(account, remoteJid string) bool {
+	if r.Account != account {
+		return false
+	}
+	if r.Jid == remoteJid {
+		return true
+	}
+
+	matched, err := path.Match(r.Jid, remoteJid)
+
+	return err == nil && matched
+}
+
+// IsPattern method returns true if the route applies to several conversations.
+func (r *Route) IsPattern() bool {
+	return strings.ContainsAny(r.Jid, `*?[\`)
+}
+
+// ActiveConversation represents a whatsapp conversation plain telegram
+// messages of the chat are sent to.
+type ActiveConversation struct {
+	// ChatID is telegram chat identifier.
+	ChatID int64 `json:"chat_id"`
+
+	// Account is a name of the whatsapp account of the conversation.
+	Account string `json:"account"`
+
+	// RemoteJid is a whatsapp user identifier of the conversation.
+	RemoteJid string `json:"remote_jid"`
+
+	// Name is a name of the whatsapp contact.
+	Name string `json:"name"`
+
+	// StatusMessageID is an identifier of the pinned telegram message
+	// that shows the active conversation.
+	StatusMessageID int `json:"status_message_id"`
+}
+
+// ContactMode represents the way messages of a whatsapp contact are delivered.
+type ContactMode string
+
+const (
+	ContactModeNormal ContactMode = "normal" // messages are delivered as usual
+	ContactModeSilent ContactMode = "silent" // messages are delivered without notification
+	ContactModeDigest ContactMode = "digest" // messages are collected to the digest
+	ContactModeMute   ContactMode = "mute"   // messages are dropped
+	ContactModeBlock  ContactMode = "block"  // messages are dropped and replies are not sent
+)
+
+// ContactModes is a list of all contact modes.
+var ContactModes = []ContactMode{
+	ContactModeNormal,
+	ContactModeSilent,
+	ContactModeDigest,
+	ContactModeMute,
+	ContactModeBlock,
+}
+
+// IsValid method returns true if the mode is known.
+func (m ContactMode) IsValid() bool {
+	for _, mode := range ContactModes {
+		if m == mode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ContactSettings represents settings of a whatsapp contact in telegram chat.
+type ContactSettings struct {
+	// ChatID is telegram chat identifier the whatsapp account is logged in from.
+	ChatID int64 `json:"chat_id"`
+
+	// Account is a name of the whatsapp account of the contact.
+	Account string `json:"account"`
+
+	// RemoteJid is a whatsapp user or group identifier.
+	RemoteJid string `json:"remote_jid"`
+
+	// Mode is the way messages of the contact are delivered.
+	Mode ContactMode `json:"mode"`
+}
+
+// DigestEntry represents an incoming whatsapp message collected to the digest.
+type DigestEntry struct {
+	// ChatID is telegram chat identifier the digest is delivered to.
+	ChatID int64 `json:"chat_id"`
+
+	// Account is a name of the whatsapp account the message came to.
+	Account string `json:"account"`
+
+	// RemoteJid is a whatsapp user or group identifier of the conversation.
+	RemoteJid string `json:"remote_jid"`
+
+	// SenderName is a name of the sender.
+	SenderName string `json:"sender_name"`
+
+	// Text is a text of the message.
+	Text string `json:"text"`
+
+	// ReceivedAt is the time the message has been received.
+	ReceivedAt time.Time `json:"received_at"`
+
+	// QuietHours means that the message has been received during quiet hours,
+	// the digest is delivered once they are over.
+	QuietHours bool `json:"quiet_hours,omitempty"`
+}
+
+// QuietHours represents the time telegram chat is not disturbed by every incoming
+// message, the messages are collected to the digest instead.
+type QuietHours struct {
+	// ChatID is telegram chat identifier.
+	ChatID int64 `json:"chat_id"`
+
+	// Hours is a time of day range of quiet hours, e.g. "23:00-07:00", empty if they are off.
+	Hours string `json:"hours,omitempty"`
+
+	// TimeZone is a name of the time zone of the chat, e.g. "Europe/Berlin",
+	// the local time zone is used if it's empty.
+	TimeZone string `json:"time_zone,omitempty"`
+
+	// DailyDigestAt is a time of day the digest is delivered every day, e.g. "20:00",
+	// empty if the daily digest is off.
+	DailyDigestAt string `json:"daily_digest_at,omitempty"`
+
+	// LastDailyDigest is a date the daily digest has been delivered last time, e.g. "2022-03-31".
+	LastDailyDigest string `json:"last_daily_digest,omitempty"`
+}
+
+// ScheduledMessage represents a whatsapp message that is sent at the specified time.
+type ScheduledMessage struct {
+	// ID is an identifier of the message unique within telegram chat.
+	ID int `json:"id"`
+
+	// ChatID is telegram chat identifier the message is scheduled from.
+	ChatID int64 `json:"chat_id"`
+
+	// Account is a name of the whatsapp account the message is sent from.
+	Account string `json:"account"`
+
+	// RemoteJid is a whatsapp user or group identifier the message is sent to.
+	RemoteJid string `json:"remote_jid"`
+
+	// Name is a name of the whatsapp contact.
+	Name string `json:"name"`
+
+	// Text is a text of the message.
+	Text string `json:"text"`
+
+	// SendAt is the time the message is sent at.
+	SendAt time.Time `json:"send_at"`
+}
+
+// AwayMode represents the auto-reply sent to whatsapp contacts while nobody is in telegram chat.
+type AwayMode struct {
+	// ChatID is telegram chat identifier.
+	ChatID int64 `json:"chat_id"`
+
+	// Enabled means that the auto-reply is sent.
+	Enabled bool `json:"enabled"`
+
+	// Text is a text of the auto-reply.
+	Text string `json:"text"`
+
+	// Cooldown is a period a contact gets the auto-reply once per.
+	Cooldown time.Duration `json:"cooldown"`
+
+	// Replies contains the last auto-replies sent to the contacts since away mode is enabled.
+	Replies []AwayReply `json:"replies,omitempty"`
+}
+
+// AwayReply represents the last auto-reply sent to a whatsapp contact.
+type AwayReply struct {
+	// Account is a name of the whatsapp account the auto-reply is sent from.
+	Account string `json:"account"`
+
+	// RemoteJid is a whatsapp user identifier the auto-reply is sent to.
+	RemoteJid string `json:"remote_jid"`
+
+	// RepliedAt is the time the auto-reply has been sent.
+	RepliedAt time.Time `json:"replied_at"`
+}
+
+// MessageDirection represents a direction of a bridged message.
+type MessageDirection string
+
+const (
+	MessageDirectionIn  MessageDirection = "in"  // the message is received from whatsapp
+	MessageDirectionOut MessageDirection = "out" // the message is sent to whatsapp
+)
+
+// MediaReference represents a media file attached to an archived message.
+type MediaReference struct {
+	// Type is a type of the media, e.g. image or document.
+	Type string `json:"type"`
+
+	// FileName is a name of the file, if any.
+	FileName string `json:"file_name,omitempty"`
+
+	// MimeType is a mime type of the file, if known.
+	MimeType string `json:"mime_type,omitempty"`
+
+	// Path is a path to the local copy of the file, it's empty if the file isn't stored.
+	Path string `json:"path,omitempty"`
+}
+
+// StoredFile represents a file kept by the bridge to be downloaded with a time-limited link,
+// e.g. a whatsapp document that is too large to upload to telegram.
+type StoredFile struct {
+	// ID is a unique unguessable identifier of the file, it's a part of the link.
+	ID string `json:"id"`
+
+	// Name is an original name of the file.
+	Name string `json:"name"`
+
+	// MimeType is a mime type of the file, if known.
+	MimeType string `json:"mime_type,omitempty"`
+
+	// Size is a size of the file in bytes.
+	Size int64 `json:"size"`
+
+	// Path is a path to the file in the local storage.
+	Path string `json:"path"`
+
+	// ExpiresAt is the time the link expires and the file is removed at.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ArchivedMessage represents a bridged message kept in the local archive.
+type ArchivedMessage struct {
+	// ID is a unique identifier of the message in the archive.
+	ID int64 `json:"id"`
+
+	// ChatID is telegram bot chat identifier the message belongs to.
+	ChatID int64 `json:"chat_id"`
+
+	// Direction is a direction the message has been bridged in.
+	Direction MessageDirection `json:"direction"`
+
+	// Account is a name of the whatsapp account of the conversation.
+	Account string `json:"account,omitempty"`
+
+	// RemoteJid is a whatsapp user identifier of the conversation.
+	RemoteJid string `json:"remote_jid"`
+
+	// SenderName is a name of the sender, a whatsapp contact or a telegram user.
+	SenderName string `json:"sender_name,omitempty"`
+
+	// Text is a text of the message.
+	Text string `json:"text"`
+
+	// Media is a list of media files attached to the message.
+	Media []MediaReference `json:"media,omitempty"`
+
+	// WhatsappMessageID is an identifier of the whatsapp message, if it's known.
+	WhatsappMessageID string `json:"whatsapp_message_id,omitempty"`
+
+	// WhatsappSenderJid is a whatsapp identifier of the member of the group that has sent
+	// the incoming message, it's empty for other conversations.
+	WhatsappSenderJid string `json:"whatsapp_sender_jid,omitempty"`
+
+	// Deleted indicates that the message has been deleted for everyone, its text is replaced.
+	Deleted bool `json:"deleted,omitempty"`
+
+	// Reactions is a list of the reactions of whatsapp contacts to the message,
+	// the latest one comes last.
+	Reactions []MessageReaction `json:"reactions,omitempty"`
+
+	// Timestamp is the time the message has been bridged, messages loaded
+	// from whatsapp history have the time they have been sent.
+	Timestamp time.Time `json:"timestamp"`
+
+	// TelegramChatID is telegram chat identifier the message has been posted to, if any.
+	TelegramChatID int64 `json:"telegram_chat_id,omitempty"`
+
+	// TelegramMessageID is an identifier of the telegram message, it's zero if the message
+	// hasn't been posted to telegram, e.g. it's collected to the digest.
+	TelegramMessageID int `json:"telegram_message_id,omitempty"`
+}
+
+// MessageReaction represents a reaction of a whatsapp contact to an archived message.
+type MessageReaction struct {
+	// SenderJid is a whatsapp identifier of the contact that has reacted.
+	SenderJid string `json:"sender_jid,omitempty"`
+
+	// Emoji is the reaction.
+	Emoji string `json:"emoji"`
+}
+
+// MessageType represents a type of incoming whatsapp message.
+type MessageType string
+
+const (
+	MessageTypeText     MessageType = "text"
+	MessageTypeLocation MessageType = "location"
+	MessageTypeContact  MessageType = "contact"
+	MessageTypeDocument MessageType = "document"
+)
+
+// RuleAction represents an action applied to incoming messages matched by a rule.
+type RuleAction string
+
+const (
+	RuleActionForward   RuleAction = "forward"   // the message is sent to another chat as well
+	RuleActionDrop      RuleAction = "drop"      // the message is dropped
+	RuleActionImportant RuleAction = "important" // the message is marked and delivered with notification
+	RuleActionTag       RuleAction = "tag"       // the message is tagged with a hashtag
+)
+
+// Rule represents a rule applied to incoming whatsapp messages. The action
+// is applied if all conditions of the rule match, empty conditions match any message.
+type Rule struct {
+	// ID is an identifier of the rule.
+	ID string `json:"id"`
+
+	// Keyword is a text the message must contain, it's case-insensitive.
+	Keyword string `json:"keyword,omitempty"`
+
+	// Regex is a regular expression the text of the message must match.
+	Regex string `json:"regex,omitempty"`
+
+	// Sender is a pattern of jid or name of the contact that has sent the message,
+	// it doesn't match messages of groups.
+	Sender string `json:"sender,omitempty"`
+
+	// Group is a pattern of jid or name of the group the message has been sent to.
+	Group string `json:"group,omitempty"`
+
+	// Hours is a time of day range the message must be received in, e.g. "22:00-07:00".
+	Hours string `json:"hours,omitempty"`
+
+	// MessageType is a type of the message.
+	MessageType MessageType `json:"type,omitempty"`
+
+	// Account is a name of the whatsapp account that has received the message.
+	Account string `json:"account,omitempty"`
+
+	// Action is an action applied to the matched messages.
+	Action RuleAction `json:"action"`
+
+	// ForwardTo is telegram chat identifier the messages are forwarded to.
+	ForwardTo int64 `json:"forward_to,omitempty"`
+
+	// Tag is a hashtag the messages are tagged with, without leading "#".
+	Tag string `json:"tag,omitempty"`
+}
+
+// RuleSet represents the rules of telegram chat.
+type RuleSet struct {
+	// ChatID is telegram chat identifier.
+	ChatID int64 `json:"chat_id"`
+
+	// DryRun means that the rules are only logged, not applied.
+	DryRun bool `json:"dry_run"`
+
+	// Rules is a list of the rules in the order they are evaluated.
+	Rules []Rule `json:"rules"`
+}
+
+// WhatsappClient represents a common interface that describes whatsapp client behaviour.
+type WhatsappClient interface {
+	Restore() error
+	GetContacts() map[string]WhatsappContact
+	Send(msg WhatsappMessage) error
+	Logout() error
+
+	// RecentChats returns up to count conversations, the most recent first.
+	RecentChats(count int) ([]WhatsappChat, error)
+
+	// History returns up to count last text messages of the conversation in chronological order.
+	History(remoteJid string, count int) ([]WhatsappHistoryMessage, error)
+
+	// MarkRead marks messages of the conversation as read.
+	MarkRead(remoteJid string) error
+
+	// SubscribePresence subscribes to presence updates of the contact.
+	SubscribePresence(remoteJid string) error
+
+	// SendPresence lets the conversation know whether the user is composing a message,
+	// the presence is either WhatsappPresenceComposing or WhatsappPresencePaused.
+	SendPresence(remoteJid string, presence WhatsappPresence) error
+
+	// Revoke deletes the message sent to the conversation for everyone.
+	Revoke(remoteJid, messageID string) error
+
+	// Edit replaces the text of the message sent to the conversation,
+	// ErrWhatsappNotSupported is returned if the client can't do it.
+	Edit(remoteJid, messageID, text string) error
+}
+
+// ErrWhatsappNotSupported is returned when the whatsapp client doesn't support the action.
+var ErrWhatsappNotSupported = errors.New("not supported by whatsapp client")
+
+// WhatsappSessionOpts represents options of a new whatsapp session.
+type WhatsappSessionOpts struct {
+	// ChatID is telegram bot chat identifier the session belongs to.
+	ChatID int64
+
+	// Account is a name of the whatsapp account the session belongs to.
+	Account string
+
+	// Events is a channel to send events of the session to.
+	Events chan Event
+}
+
+// WhatsappQRCode represents a QR-code challenge of the login.
+type WhatsappQRCode struct {
+	// Content is a content of the QR-code.
+	Content string
+
+	// Timeout is a time the QR-code is valid for.
+	Timeout time.Duration
+}
+
+// WhatsappBackend represents a common interface that describes a backend that
+// establishes whatsapp sessions.
+type WhatsappBackend interface {
+	// Login authenticates a new session via QR-code challenge. QR-codes are
+	// sent to the provided channel, a new one is sent once the previous expires.
+	// The login is aborted once ctx is done.
+	Login(ctx context.Context, opts *WhatsappSessionOpts, qrCodes chan<- WhatsappQRCode) (WhatsappClient, error)
+}
+
+/* Telegram related domain entities */
+
+// ErrTelegramTopicNotFound is returned when the forum topic a message is sent to
+// doesn't exist anymore.
+var ErrTelegramTopicNotFound = errors.New("telegram topic not found")
+
+// TelegramTopic represents a telegram forum topic a whatsapp conversation is bridged to.
+type TelegramTopic struct {
+	// ChatID is telegram chat identifier the topic belongs to.
+	ChatID int64 `json:"chat_id"`
+
+	// ThreadID is an identifier of the topic.
+	ThreadID int `json:"thread_id"`
+
+	// Account is a name of the whatsapp account of the conversation.
+	Account string `json:"account"`
+
+	// RemoteJid is a whatsapp user identifier of the conversation.
+	RemoteJid string `json:"remote_jid"`
+}
+
+// TelegramButton represents an inline keyboard button attached to a telegram message.
+type TelegramButton struct {
+	// Text is a label of the button.
+	Text string
+
+	// CallbackData is data that is sent back to the bot when the button is pressed.
+	CallbackData string
+}
+
+// TelegramFile represents a file uploaded to telegram.
+type TelegramFile struct {
+	// Name is a name of the file.
+	Name string
+
+	// Bytes is content of the file.
+	Bytes []byte
+}
+
+// TelegramTextMessage represents a telegram text message.
+type TelegramTextMessage struct {
+	// ChatID is telegram chat identifier the message is sent to.
+	ChatID int64
+
+	// Text is a text of the message.
+	Text string
+
+	// ThreadID is an identifier of the forum topic the message is sent to,
+	// the message is sent to the general topic if it's zero.
+	ThreadID int
+
+	// Buttons is an inline keyboard attached to the message, row by row.
+	Buttons [][]TelegramButton
+
+	// DisableNotification sends the message silently.
+	DisableNotification bool
+
+	// ReplyToMessageID is an identifier of the message it replies to, optional.
+	ReplyToMessageID int
+}
+
+// TelegramPhotoMessage represents a telegram photo message.
+type TelegramPhotoMessage struct {
+	// ChatID is telegram chat identifier the message is sent to.
+	ChatID int64
+
+	// Photo is a photo file to upload.
+	Photo TelegramFile
+
+	// Caption is a caption of the photo.
+	Caption string
+
+	// Buttons is an inline keyboard attached to the message, row by row.
+	Buttons [][]TelegramButton
+}
+
+// TelegramDocumentMessage represents a telegram document message.
+type TelegramDocumentMessage struct {
+	// ChatID is telegram chat identifier the message is sent to.
+	ChatID int64
+
+	// Document is a file to upload.
+	Document TelegramFile
+
+	// MimeType is a mime type of the file, telegram guesses it by the name if it's empty.
+	MimeType string
+
+	// Caption is a caption of the document.
+	Caption string
+
+	// DisableNotification sends the message silently.
+	DisableNotification bool
+
+	// ReplyToMessageID is an identifier of the message it replies to, optional.
+	ReplyToMessageID int
+
+	// Buttons is an inline keyboard attached to the message, row by row.
+	Buttons [][]TelegramButton
+}
+
+// TelegramEditMessage represents an update of a previously sent telegram text message.
+type TelegramEditMessage struct {
+	// ChatID is telegram chat identifier the message belongs to.
+	ChatID int64
+
+	// MessageID is an identifier of the message to edit.
+	MessageID int
+
+	// Text is a new text of the message.
+	Text string
+
+	// Buttons is a new inline keyboard of the message, row by row.
+	Buttons [][]TelegramButton
+}
+
+// TelegramEditPhotoMessage represents a replacement of a previously sent telegram photo.
+type TelegramEditPhotoMessage struct {
+	// ChatID is telegram chat identifier the message belongs to.
+	ChatID int64
+
+	// MessageID is an identifier of the message to edit.
+	MessageID int
+
+	// Photo is a new photo file to upload.
+	Photo TelegramFile
+
+	// Caption is a new caption of the photo.
+	Caption string
+
+	// Buttons is a new inline keyboard of the message, row by row.
+	Buttons [][]TelegramButton
+}
+
+// TelegramEditCaptionMessage represents an update of a caption of a previously
+// sent telegram media message.
+type TelegramEditCaptionMessage struct {
+	// ChatID is telegram chat identifier the message belongs to.
+	ChatID int64
+
+	// MessageID is an identifier of the message to edit.
+	MessageID int
+
+	// Caption is a new caption of the message.
+	Caption string
+
+	// Buttons is a new inline keyboard of the message, row by row.
+	Buttons [][]TelegramButton
+}
+
+// TelegramCallbackAnswer represents an answer to a telegram callback query.
+type TelegramCallbackAnswer struct {
+	// CallbackID is an identifier of the callback query to answer.
+	CallbackID string
+
+	// Text is a notification shown to the user.
+	Text string
+}
+
+// TelegramChatActionTyping is a chat action that shows that somebody is typing.
+const TelegramChatActionTyping = "typing"
+
+// TelegramChatAction represents a telegram chat action, e.g. typing, shown
+// to the users of the chat for a few seconds.
+type TelegramChatAction struct {
+	// ChatID is telegram chat identifier.
+	ChatID int64
+
+	// ThreadID is an identifier of the forum topic the action is shown in, optional.
+	ThreadID int
+
+	// Action is a type of the action, e.g. TelegramChatActionTyping.
+	Action string
+}
+
+// TelegramLocationMessage represents a telegram location message, it's sent as a venue
+// if the place has a title and an address.
+type TelegramLocationMessage struct {
+	// ChatID is telegram chat identifier the message is sent to.
+	ChatID int64
+
+	// Latitude is a latitude of the location in degrees.
+	Latitude float64
+
+	// Longitude is a longitude of the location in degrees.
+	Longitude float64
+
+	// Title is a name of the place, optional.
+	Title string
+
+	// Address is an address of the place, optional.
+	Address string
+
+	// LivePeriod is a period in seconds the location can be updated for, it's zero
+	// for static locations.
+	LivePeriod int
+
+	// DisableNotification sends the message silently.
+	DisableNotification bool
+
+	// ReplyToMessageID is an identifier of the message it replies to, optional.
+	ReplyToMessageID int
+}
+
+// TelegramEditLocationMessage represents an update of a telegram live location.
+type TelegramEditLocationMessage struct {
+	// ChatID is telegram chat identifier the message belongs to.
+	ChatID int64
+
+	// MessageID is an identifier of the live location message.
+	MessageID int
+
+	// Latitude is a new latitude of the location in degrees.
+	Latitude float64
+
+	// Longitude is a new longitude of the location in degrees.
+	Longitude float64
+}
+
+// TelegramContactMessage represents a telegram contact message.
+type TelegramContactMessage struct {
+	// ChatID is telegram chat identifier the message is sent to.
+	ChatID int64
+
+	// PhoneNumber is a phone number of the contact.
+	PhoneNumber string
+
+	// FirstName is a first name of the contact.
+	FirstName string
+
+	// LastName is a last name of the contact, optional.
+	LastName string
+
+	// Vcard is additional data about the contact in vCard format, optional.
+	Vcard string
+
+	// DisableNotification sends the message silently.
+	DisableNotification bool
+
+	// ReplyToMessageID is an identifier of the message it replies to, optional.
+	ReplyToMessageID int
+}
+
+// TelegramReaction represents a reaction of the bot to a telegram message.
+type TelegramReaction struct {
+	// ChatID is telegram chat identifier the message belongs to.
+	ChatID int64
+
+	// MessageID is an identifier of the message.
+	MessageID int
+
+	// Emoji is the reaction, the previous reaction is removed if it's empty.
+	Emoji string
+}
+
+// TelegramClient represents a common interface that describes telegram client behaviour.
+type TelegramClient interface {
+	SendText(msg *TelegramTextMessage) (int, error)
+	SendPhoto(msg *TelegramPhotoMessage) (int, error)
+	SendDocument(msg *TelegramDocumentMessage) (int, error)
+	EditMessage(msg *TelegramEditMessage) error
+	EditPhoto(msg *TelegramEditPhotoMessage) error
+	EditCaption(msg *TelegramEditCaptionMessage) error
+	AnswerCallback(answer *TelegramCallbackAnswer) error
+	CreateTopic(chatID int64, name string) (int, error)
+	PinMessage(chatID int64, messageID int) error
+	UnpinMessage(chatID int64, messageID int) error
+	SendChatAction(action *TelegramChatAction) error
+	SetReaction(reaction *TelegramReaction) error
+	SendLocation(msg *TelegramLocationMessage) (int, error)
+	EditLocation(msg *TelegramEditLocationMessage) error
+	StopLocation(chatID int64, messageID int) error
+	SendContact(msg *TelegramContactMessage) (int, error)
+	DownloadFile(fileID string) ([]byte, error)
 }
@@ -1,15 +1,348 @@
 package domain
 
-import "strings"
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
 
-// ExtractMsgJid returns remote jid from the message if it has one,
-// otherwise - empty string.
+	"github.com/dstdfx/twbridge/internal/vcard"
+)
+
+// ErrInvalidHours is returned when the time of day range can't be parsed.
+var ErrInvalidHours = errors.New("invalid hours")
+
+// locationTextFmt represents a text of the location with a link to the map.
+const locationTextFmt = "📍 %s\nhttps://maps.google.com/?q=%.6f,%.6f"
+
+// contactCardTextFmt represents a text of the contact card.
+const contactCardTextFmt = "👤 Contact: %s"
+
+// documentTextFmt represents a text of the document with its type and size.
+const documentTextFmt = "📎 %s (%s)"
+
+// photoText is a text of the photo sent to whatsapp.
+const photoText = "🖼 Photo"
+
+// defaultDocumentName is a name of the document that has no file name.
+const defaultDocumentName = "Document"
+
+// RetryCallbackAction is a telegram callback action to retry sending of
+// an outbox message.
+const RetryCallbackAction = "retry"
+
+// CancelLoginCallbackAction is a telegram callback action to abort the login
+// that is in progress.
+const CancelLoginCallbackAction = "cancel_login"
+
+// ChatCallbackAction is a telegram callback action to select the active conversation.
+const ChatCallbackAction = "chat"
+
+// CloseChatCallbackAction is a telegram callback action to clear the active conversation.
+const CloseChatCallbackAction = "close_chat"
+
+// SettingsCallbackAction is a telegram callback action to show settings of the contact.
+const SettingsCallbackAction = "settings"
+
+// ContactModeCallbackAction is a telegram callback action to change the mode of the contact.
+const ContactModeCallbackAction = "mode"
+
+// UnscheduleCallbackAction is a telegram callback action to cancel a scheduled message.
+const UnscheduleCallbackAction = "unschedule"
+
+// ChatsCallbackAction is a telegram callback action of the conversations overview.
+const ChatsCallbackAction = "chats"
+
+const (
+	callbackDataSeparator    = ":"
+	conversationRefSeparator = "/"
+)
+
+const (
+	hoursSeparator = "-"
+	hoursLayout    = "15:04"
+	minutesInDay   = 24 * 60
+)
+
+// supergroupChatIDOffset is subtracted from identifiers of telegram supergroups and channels
+// in the bot API, e.g. -1001234567890 is 1234567890 in message links.
+const supergroupChatIDOffset = -1000000000000
+
+const telegramMessageLinkFmt = "https://t.me/c/%d/%d"
+
+const (
+	accountTagFmt    = " [account: %s]"
+	accountTagPrefix = " [account: "
+	jidTagPrefix     = "[jid: "
+
+	// headerSeparator separates the header of the bridged message from its text.
+	headerSeparator = "\n= = ="
+)
+
+// accountNameRe describes allowed names of whatsapp accounts, names are kept short
+// since they are passed in telegram callback data.
+var accountNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// ExtractMsgJid returns remote jid from the header of the message if it has one,
+// otherwise - empty string. The last tag of the header is trusted like in ExtractMsgAccount.
 func ExtractMsgJid(message string) string {
-	jidStart := strings.Index(message, "jid:")
-	jidEnd := strings.Index(message, "]")
-	if jidStart == -1 || jidEnd == -1 || jidStart+5 >= jidEnd {
+	jid, _, ok := msgJidTag(message)
+	if !ok {
 		return ""
 	}
 
-	return message[jidStart+5 : jidEnd] // jid: + space
+	return jid
+}
+
+// AccountTag returns a tag that marks telegram messages related to the whatsapp
+// account. The default account is not tagged.
+func AccountTag(account string) string {
+	if account == "" || account == DefaultWhatsappAccount {
+		return ""
+	}
+
+	return fmt.Sprintf(accountTagFmt, account)
+}
+
+// ExtractMsgAccount returns whatsapp account from the message tagged by AccountTag,
+// otherwise - the default account. Only the tag that follows the jid in the header
+// of the message is trusted, the text of the message and the name of the contact
+// are written by the contact and may contain a fake tag.
+func ExtractMsgAccount(message string) string {
+	_, rest, ok := msgJidTag(message)
+	if !ok || !strings.HasPrefix(rest, accountTagPrefix) {
+		return DefaultWhatsappAccount
+	}
+
+	account := rest[len(accountTagPrefix):]
+	tagEnd := strings.Index(account, "]")
+	if tagEnd == -1 || !IsValidAccountName(account[:tagEnd]) {
+		return DefaultWhatsappAccount
+	}
+
+	return account[:tagEnd]
+}
+
+// msgJidTag returns the jid of the last jid tag in the header of the message and the rest
+// of the header that follows the tag, false is returned if the header has no such tag.
+func msgJidTag(message string) (jid, rest string, ok bool) {
+	header := msgHeader(message)
+	jidStart := strings.LastIndex(header, jidTagPrefix)
+	if jidStart == -1 {
+		return "", "", false
+	}
+
+	tag := header[jidStart+len(jidTagPrefix):]
+	jidEnd := strings.Index(tag, "]")
+	if jidEnd == -1 {
+		return "", "", false
+	}
+
+	return tag[:jidEnd], tag[jidEnd+1:], true
+}
+
+// msgHeader returns the header of the bridged message, it's the part before the separator
+// or the first line if the message has no separator.
+func msgHeader(message string) string {
+	if i := strings.Index(message, headerSeparator); i != -1 {
+		return message[:i]
+	}
+	if i := strings.Index(message, "\n"); i != -1 {
+		return message[:i]
+	}
+
+	return message
+}
+
+// IsValidAccountName returns true if the name can be used as a whatsapp account name.
+func IsValidAccountName(name string) bool {
+	return accountNameRe.MatchString(name)
+}
+
+// NewCallbackData returns telegram callback data for the provided action
+// and its argument.
+func NewCallbackData(action, arg string) string {
+	return action + callbackDataSeparator + arg
+}
+
+// ParseCallbackData returns action and its argument from telegram callback data.
+func ParseCallbackData(data string) (action, arg string) {
+	sepIdx := strings.Index(data, callbackDataSeparator)
+	if sepIdx == -1 {
+		return data, ""
+	}
+
+	return data[:sepIdx], data[sepIdx+1:]
+}
+
+// NewConversationRef returns a reference to the whatsapp conversation
+// that fits into telegram callback data.
+func NewConversationRef(account, remoteJid string) string {
+	return account + conversationRefSeparator + remoteJid
+}
+
+// ParseConversationRef returns the whatsapp account and the jid of
+// the conversation reference made by NewConversationRef.
+func ParseConversationRef(ref string) (account, remoteJid string) {
+	parts := strings.SplitN(ref, conversationRefSeparator, 2)
+	if len(parts) != 2 {
+		return DefaultWhatsappAccount, ref
+	}
+
+	return parts[0], parts[1]
+}
+
+// TelegramMessageLink returns a link that opens the message of the telegram chat.
+// Only messages of supergroups and channels have links, false is returned otherwise.
+func TelegramMessageLink(chatID int64, messageID int) (string, bool) {
+	if chatID >= supergroupChatIDOffset || messageID == 0 {
+		return "", false
+	}
+
+	return fmt.Sprintf(telegramMessageLinkFmt, supergroupChatIDOffset-chatID, messageID), true
+}
+
+// InHours returns true if the time of day is in the range, the range may
+// span midnight, e.g. "22:00-07:00".
+func InHours(hours string, t time.Time) bool {
+	from, to, err := ParseHours(hours)
+	if err != nil {
+		return false
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	if from <= to {
+		return minute >= from && minute < to
+	}
+
+	return minute >= from || minute < to
+}
+
+// ParseHours returns the range of the time of day in minutes since midnight.
+func ParseHours(hours string) (from, to int, err error) {
+	parts := strings.Split(hours, hoursSeparator)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("%w, they must look like 22:00-07:00", ErrInvalidHours)
+	}
+
+	minutes := make([]int, 0, len(parts))
+	for _, part := range parts {
+		minute, err := ParseTimeOfDay(part)
+		if err != nil {
+			return 0, 0, err
+		}
+		minutes = append(minutes, minute)
+	}
+
+	return minutes[0], minutes[1], nil
+}
+
+// ParseTimeOfDay returns the time of day, e.g. "07:30", in minutes since midnight.
+func ParseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse(hoursLayout, s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid time %q", ErrInvalidHours, s)
+	}
+
+	return (t.Hour()*60 + t.Minute()) % minutesInDay, nil
+}
+
+// LocationText returns a text that describes the location with a link to the map,
+// it's used where the location can't be shown as is, e.g. in the archive.
+func LocationText(location WhatsappLocation, live bool) string {
+	title := "Location"
+	if live {
+		title = "Live location"
+	}
+
+	var place []string
+	for _, part := range []string{location.Name, location.Address} {
+		if part != "" {
+			place = append(place, part)
+		}
+	}
+	if len(place) != 0 {
+		title += ": " + strings.Join(place, ", ")
+	}
+
+	return fmt.Sprintf(locationTextFmt, title, location.Latitude, location.Longitude)
+}
+
+// ContactCardText returns a text that describes the contact card with its phone numbers,
+// it's used where the card can't be shown as is, e.g. in the archive.
+func ContactCardText(contactCard WhatsappContactCard) string {
+	card, err := vcard.Decode(contactCard.Vcard)
+	if err != nil {
+		return fmt.Sprintf(contactCardTextFmt, contactCard.DisplayName)
+	}
+
+	name := contactCard.DisplayName
+	if name == "" {
+		name = card.Name()
+	}
+
+	parts := []string{name}
+	for _, phone := range card.Phones {
+		parts = append(parts, phone.Number)
+	}
+
+	return fmt.Sprintf(contactCardTextFmt, strings.Join(parts, ", "))
+}
+
+// DocumentText returns a text that describes the document with its caption, it's used
+// where the document can't be shown as is, e.g. in the archive.
+func DocumentText(document WhatsappDocument) string {
+	name := document.FileName
+	if name == "" {
+		name = defaultDocumentName
+	}
+
+	details := []string{FormatSize(document.Size)}
+	if document.MimeType != "" {
+		details = append([]string{document.MimeType}, details...)
+	}
+
+	text := fmt.Sprintf(documentTextFmt, name, strings.Join(details, ", "))
+	if document.Caption != "" {
+		text += "\n" + document.Caption
+	}
+
+	return text
+}
+
+// MediaText returns a text that describes the photo or the document sent to whatsapp.
+func MediaText(media WhatsappMedia) string {
+	if media.Kind == WhatsappMediaImage {
+		if media.Caption == "" {
+			return photoText
+		}
+
+		return photoText + "\n" + media.Caption
+	}
+
+	return DocumentText(WhatsappDocument{
+		FileName: media.FileName,
+		MimeType: media.MimeType,
+		Caption:  media.Caption,
+		Size:     media.Size,
+	})
+}
+
+// FormatSize returns human-readable size of a file, e.g. 1.5 MB.
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	value := float64(size) / unit
+	units := []string{"KB", "MB", "GB", "TB"}
+	i := 0
+	for value >= unit && i < len(units)-1 {
+		value /= unit
+		i++
+	}
+
+	return fmt.Sprintf("%.1f %s", value, units[i])
 }
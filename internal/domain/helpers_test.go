@@ -3,9 +3,11 @@ package domain_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/dstdfx/twbridge/internal/domain"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestExtractMsgJid(t *testing.T) {
@@ -18,7 +20,12 @@ func TestExtractMsgJid(t *testing.T) {
 			expected: "",
 		},
 		{
-			input:    fmt.Sprintf(domain.TextMessageFmt, "test user", "test@jid.net", "hello, world!"),
+			input:    fmt.Sprintf(domain.TextMessageFmt, "test user", "test@jid.net", "", "hello, world!"),
+			expected: "test@jid.net",
+		},
+		{
+			input: fmt.Sprintf(domain.TextMessageFmt,
+				"test user", "test@jid.net", domain.AccountTag("work"), "hello, world!"),
 			expected: "test@jid.net",
 		},
 		{
@@ -41,9 +48,251 @@ func TestExtractMsgJid(t *testing.T) {
 			input:    "ewfwefwef",
 			expected: "",
 		},
+		{
+			input:    fmt.Sprintf(domain.TextMessageFmt, "Bob ]", "test@jid.net", "", "hello, world!"),
+			expected: "test@jid.net",
+		},
+		{
+			input: fmt.Sprintf(domain.TextMessageFmt,
+				"jid: fake@s.whatsapp.net]", "test@jid.net", domain.AccountTag("work"), "hello, world!"),
+			expected: "test@jid.net",
+		},
+		{
+			input: fmt.Sprintf(domain.TextMessageFmt,
+				"Bob [jid: fake@s.whatsapp.net]", "test@jid.net", "", "hello, world!"),
+			expected: "test@jid.net",
+		},
+		{
+			input:    fmt.Sprintf(domain.TextMessageFmt, "Bob", "test@jid.net", "", "[jid: fake@s.whatsapp.net]"),
+			expected: "test@jid.net",
+		},
+		{
+			input:    "The message #1 to Bob ] [jid: test@jid.net] is scheduled at Apr 1 09:00 (UTC)",
+			expected: "test@jid.net",
+		},
 	}
 
 	for _, test := range tableTest {
 		assert.Equal(t, test.expected, domain.ExtractMsgJid(test.input))
 	}
 }
+
+func TestExtractMsgAccount(t *testing.T) {
+	tableTest := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    "",
+			expected: domain.DefaultWhatsappAccount,
+		},
+		{
+			input:    fmt.Sprintf(domain.TextMessageFmt, "test user", "test@jid.net", "", "hello, world!"),
+			expected: domain.DefaultWhatsappAccount,
+		},
+		{
+			input: fmt.Sprintf(domain.TextMessageFmt,
+				"test user", "test@jid.net", domain.AccountTag("work"), "hello, world!"),
+			expected: "work",
+		},
+		{
+			input:    "[jid: test@jid.net] [account: work",
+			expected: domain.DefaultWhatsappAccount,
+		},
+		{
+			input:    "[jid: test@jid.net] [account: not valid]",
+			expected: domain.DefaultWhatsappAccount,
+		},
+		{
+			input: fmt.Sprintf(domain.TextMessageFmt,
+				"test user", "test@jid.net", "", "hello [jid: test@jid.net] [account: work]"),
+			expected: domain.DefaultWhatsappAccount,
+		},
+		{
+			input: fmt.Sprintf(domain.TextMessageFmt,
+				"test user", "test@jid.net", domain.AccountTag("home"), "[jid: test@jid.net] [account: work]"),
+			expected: "home",
+		},
+		{
+			input: fmt.Sprintf(domain.TextMessageFmt,
+				"user [jid: x] [account: work]", "test@jid.net", "", "hello, world!"),
+			expected: domain.DefaultWhatsappAccount,
+		},
+		{
+			input: fmt.Sprintf(domain.TextMessageFmt,
+				"jid: fake@s.whatsapp.net] [account: work]", "test@jid.net", domain.AccountTag("home"), "hello"),
+			expected: "home",
+		},
+		{
+			input:    "The conversation [jid: test@jid.net] [account: work] is assigned to @bob",
+			expected: "work",
+		},
+	}
+
+	for _, test := range tableTest {
+		assert.Equal(t, test.expected, domain.ExtractMsgAccount(test.input))
+	}
+}
+
+func TestAccountTag(t *testing.T) {
+	assert.Empty(t, domain.AccountTag(domain.DefaultWhatsappAccount))
+	assert.Empty(t, domain.AccountTag(""))
+	assert.Equal(t, " [account: work]", domain.AccountTag("work"))
+}
+
+func TestLocationText(t *testing.T) {
+	assert.Equal(t, "📍 Location\nhttps://maps.google.com/?q=55.751244,37.618423",
+		domain.LocationText(domain.WhatsappLocation{Latitude: 55.751244, Longitude: 37.618423}, false))
+	assert.Equal(t, "📍 Live location\nhttps://maps.google.com/?q=-33.856700,151.215000",
+		domain.LocationText(domain.WhatsappLocation{Latitude: -33.8567, Longitude: 151.215}, true))
+	assert.Equal(t, "📍 Location: Cafe, 1 Main St\nhttps://maps.google.com/?q=1.000000,2.000000",
+		domain.LocationText(domain.WhatsappLocation{
+			Latitude:  1,
+			Longitude: 2,
+			Name:      "Cafe",
+			Address:   "1 Main St",
+		}, false))
+}
+
+func TestContactCardText(t *testing.T) {
+	assert.Equal(t, "👤 Contact: Alice, +1 555-123-4567, +1 555 000 0000",
+		domain.ContactCardText(domain.WhatsappContactCard{
+			DisplayName: "Alice",
+			Vcard:       "BEGIN:VCARD\nFN:Alice Smith\nTEL:+1 555-123-4567\nTEL:+1 555 000 0000\nEND:VCARD",
+		}))
+	assert.Equal(t, "👤 Contact: Alice Smith", domain.ContactCardText(domain.WhatsappContactCard{
+		Vcard: "BEGIN:VCARD\nN:Smith;Alice\nEND:VCARD",
+	}))
+	assert.Equal(t, "👤 Contact: Bob", domain.ContactCardText(domain.WhatsappContactCard{DisplayName: "Bob"}))
+}
+
+func TestDocumentText(t *testing.T) {
+	assert.Equal(t, "📎 report.pdf (application/pdf, 1.5 MB)\nQ1 numbers", domain.DocumentText(domain.WhatsappDocument{
+		FileName: "report.pdf",
+		MimeType: "application/pdf",
+		Caption:  "Q1 numbers",
+		Size:     3 << 19,
+	}))
+	assert.Equal(t, "📎 Document (512 B)", domain.DocumentText(domain.WhatsappDocument{Size: 512}))
+}
+
+func TestMediaText(t *testing.T) {
+	assert.Equal(t, "🖼 Photo", domain.MediaText(domain.WhatsappMedia{Kind: domain.WhatsappMediaImage}))
+	assert.Equal(t, "🖼 Photo\nLook", domain.MediaText(domain.WhatsappMedia{
+		Kind:    domain.WhatsappMediaImage,
+		Caption: "Look",
+	}))
+	assert.Equal(t, "📎 report.pdf (application/pdf, 2.0 KB)", domain.MediaText(domain.WhatsappMedia{
+		Kind:     domain.WhatsappMediaDocument,
+		FileName: "report.pdf",
+		MimeType: "application/pdf",
+		Size:     2048,
+	}))
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "0 B", domain.FormatSize(0))
+	assert.Equal(t, "1023 B", domain.FormatSize(1023))
+	assert.Equal(t, "1.0 KB", domain.FormatSize(1024))
+	assert.Equal(t, "50.0 MB", domain.FormatSize(50<<20))
+	assert.Equal(t, "2.5 GB", domain.FormatSize(5<<29))
+}
+
+func TestCallbackData(t *testing.T) {
+	tableTest := []struct {
+		input          string
+		expectedAction string
+		expectedArg    string
+	}{
+		{
+			input:          domain.NewCallbackData(domain.RetryCallbackAction, "42"),
+			expectedAction: domain.RetryCallbackAction,
+			expectedArg:    "42",
+		},
+		{
+			input:          domain.NewCallbackData("action", "with:separator"),
+			expectedAction: "action",
+			expectedArg:    "with:separator",
+		},
+		{
+			input:          "action",
+			expectedAction: "action",
+			expectedArg:    "",
+		},
+		{
+			input:          "",
+			expectedAction: "",
+			expectedArg:    "",
+		},
+	}
+
+	for _, test := range tableTest {
+		gotAction, gotArg := domain.ParseCallbackData(test.input)
+		assert.Equal(t, test.expectedAction, gotAction)
+		assert.Equal(t, test.expectedArg, gotArg)
+	}
+}
+
+func TestConversationRef(t *testing.T) {
+	tableTest := []struct {
+		input             string
+		expectedAccount   string
+		expectedRemoteJid string
+	}{
+		{
+			input:             domain.NewConversationRef("work", "alice@s.whatsapp.net"),
+			expectedAccount:   "work",
+			expectedRemoteJid: "alice@s.whatsapp.net",
+		},
+		{
+			input:             "alice@s.whatsapp.net",
+			expectedAccount:   domain.DefaultWhatsappAccount,
+			expectedRemoteJid: "alice@s.whatsapp.net",
+		},
+	}
+
+	for _, test := range tableTest {
+		gotAccount, gotRemoteJid := domain.ParseConversationRef(test.input)
+		assert.Equal(t, test.expectedAccount, gotAccount)
+		assert.Equal(t, test.expectedRemoteJid, gotRemoteJid)
+	}
+}
+
+func TestTelegramMessageLink(t *testing.T) {
+	link, ok := domain.TelegramMessageLink(-1001234567890, 42)
+	assert.True(t, ok)
+	assert.Equal(t, "https://t.me/c/1234567890/42", link)
+
+	_, ok = domain.TelegramMessageLink(123, 42)
+	assert.False(t, ok)
+
+	_, ok = domain.TelegramMessageLink(-123, 42)
+	assert.False(t, ok)
+
+	_, ok = domain.TelegramMessageLink(-1001234567890, 0)
+	assert.False(t, ok)
+}
+
+func TestInHours(t *testing.T) {
+	tableTest := []struct {
+		hours    string
+		time     string
+		expected bool
+	}{
+		{hours: "09:00-18:00", time: "09:00", expected: true},
+		{hours: "09:00-18:00", time: "18:00", expected: false},
+		{hours: "22:00-07:00", time: "23:30", expected: true},
+		{hours: "22:00-07:00", time: "06:59", expected: true},
+		{hours: "22:00-07:00", time: "12:00", expected: false},
+		{hours: "22:00", time: "22:00", expected: false},
+	}
+
+	for _, test := range tableTest {
+		tm, err := time.Parse("15:04", test.time)
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, domain.InHours(test.hours, tm), "%s at %s", test.hours, test.time)
+	}
+
+	_, _, err := domain.ParseHours("25:00-07:00")
+	assert.ErrorIs(t, err, domain.ErrInvalidHours)
+}
@@ -0,0 +1,758 @@
+package e2e_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/archive"
+	"github.com/dstdfx/twbridge/internal/away"
+	"github.com/dstdfx/twbridge/internal/conversation"
+	"github.com/dstdfx/twbridge/internal/digest"
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/manager"
+	"github.com/dstdfx/twbridge/internal/outbox"
+	"github.com/dstdfx/twbridge/internal/quiet"
+	"github.com/dstdfx/twbridge/internal/route"
+	"github.com/dstdfx/twbridge/internal/rules"
+	"github.com/dstdfx/twbridge/internal/schedule"
+	"github.com/dstdfx/twbridge/internal/settings"
+	"github.com/dstdfx/twbridge/internal/team"
+	"github.com/dstdfx/twbridge/internal/telegram"
+	"github.com/dstdfx/twbridge/internal/telegram/fake"
+	"github.com/dstdfx/twbridge/internal/topic"
+	"github.com/dstdfx/twbridge/internal/whatsapp/simulator"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	testChatID   = int64(42)
+	testUserName = "testuser"
+
+	aliceJid = "alice@s.whatsapp.net"
+	bobJid   = "bob@s.whatsapp.net"
+
+	waitTimeout  = 5 * time.Second
+	waitInterval = 10 * time.Millisecond
+)
+
+var errTestNetwork = errors.New("simulated network failure")
+
+// bridge represents the whole bridge running against the whatsapp simulator
+// and the fake telegram client.
+type bridge struct {
+	t              *testing.T
+	updates        chan telegram.Update
+	reactions      chan telegram.MessageReaction
+	telegramClient *fake.Client
+	backend        *simulator.Backend
+	outbox         *outbox.Outbox
+	lastUpdateID   int
+	lastMessageID  int
+}
+
+func startBridge(t *testing.T) *bridge {
+	t.Helper()
+
+	return startBridgeWithBackend(t, &simulator.Opts{})
+}
+
+// startBridgeWithBackend starts the bridge with the simulator configured
+// by the provided options, the test contacts are added to it.
+func startBridgeWithBackend(t *testing.T, opts *simulator.Opts) *bridge {
+	t.Helper()
+
+	opts.Contacts = []domain.WhatsappContact{
+		{Jid: aliceJid, Name: "Alice"},
+		{Jid: bobJid, Name: "Bob"},
+	}
+	b := &bridge{
+		t:              t,
+		updates:        make(chan telegram.Update),
+		reactions:      make(chan telegram.MessageReaction),
+		telegramClient: fake.NewClient(),
+		backend:        simulator.NewBackend(zap.NewNop(), opts),
+	}
+
+	var err error
+	b.outbox, err = outbox.New(&outbox.Opts{
+		Path:        filepath.Join(t.TempDir(), "outbox.json"),
+		MaxAttempts: 2,
+	})
+	require.NoError(t, err)
+	teams, err := team.New(&team.Opts{Path: filepath.Join(t.TempDir(), "teams.json")})
+	require.NoError(t, err)
+	topics, err := topic.New(&topic.Opts{Path: filepath.Join(t.TempDir(), "topics.json")})
+	require.NoError(t, err)
+	routes, err := route.New(&route.Opts{Path: filepath.Join(t.TempDir(), "routes.json")})
+	require.NoError(t, err)
+	conversations, err := conversation.New(&conversation.Opts{Path: filepath.Join(t.TempDir(), "conversations.json")})
+	require.NoError(t, err)
+	contactSettings, err := settings.New(&settings.Opts{Path: filepath.Join(t.TempDir(), "settings.json")})
+	require.NoError(t, err)
+	digests, err := digest.New(&digest.Opts{Path: filepath.Join(t.TempDir(), "digests.json")})
+	require.NoError(t, err)
+	messageRules, err := rules.New(&rules.Opts{Path: filepath.Join(t.TempDir(), "rules.json")})
+	require.NoError(t, err)
+	quietHours, err := quiet.New(&quiet.Opts{Path: filepath.Join(t.TempDir(), "quiet_hours.json")})
+	require.NoError(t, err)
+	schedules, err := schedule.New(&schedule.Opts{Path: filepath.Join(t.TempDir(), "schedule.json")})
+	require.NoError(t, err)
+	awayModes, err := away.New(&away.Opts{Path: filepath.Join(t.TempDir(), "away.json")})
+	require.NoError(t, err)
+	messageArchive, err := archive.New(zap.NewNop(), &archive.Opts{Path: filepath.Join(t.TempDir(), "archive.jsonl")})
+	require.NoError(t, err)
+
+	eventsProvider := telegram.NewEventsProvider(zap.NewNop(), &telegram.Opts{
+		TelegramUpdates: b.updates,
+		Reactions:       b.reactions,
+	})
+	clientManager := manager.NewManager(zap.NewNop(), &manager.Opts{
+		IncomingEvents:  eventsProvider.EventsStream(),
+		TelegramClient:  b.telegramClient,
+		WhatsappBackend: b.backend,
+		Outbox:          b.outbox,
+		Teams:           teams,
+		Topics:          topics,
+		Routes:          routes,
+		Conversations:   conversations,
+		Settings:        contactSettings,
+		Digests:         digests,
+		Rules:           messageRules,
+		QuietHours:      quietHours,
+		Schedules:       schedules,
+		Away:            awayModes,
+		Archive:         messageArchive,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		clientManager.Run(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		if err := eventsProvider.Run(ctx); err != nil {
+			t.Errorf("failed to run telegram events provider: %s", err)
+		}
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		wg.Wait()
+	})
+
+	return b
+}
+
+// sendText emulates a text message written by the user in telegram chat.
+func (b *bridge) sendText(text string) {
+	b.sendMessage(&tgbotapi.Message{Text: text})
+}
+
+// reply emulates a reply to the telegram message.
+func (b *bridge) reply(to domain.TelegramTextMessage, text string) {
+	b.sendMessage(&tgbotapi.Message{
+		Text: text,
+		ReplyToMessage: &tgbotapi.Message{
+			Chat: &tgbotapi.Chat{ID: to.ChatID},
+			Text: to.Text,
+		},
+	})
+}
+
+// replyAs emulates a reply to the telegram message written by another user.
+func (b *bridge) replyAs(from *tgbotapi.User, to domain.TelegramTextMessage, text string) {
+	b.sendMessage(&tgbotapi.Message{
+		From: from,
+		Text: text,
+		ReplyToMessage: &tgbotapi.Message{
+			Chat: &tgbotapi.Chat{ID: to.ChatID},
+			Text: to.Text,
+		},
+	})
+}
+
+// replyIn emulates a reply to the telegram message written in another chat.
+func (b *bridge) replyIn(chatID int64, to domain.TelegramTextMessage, text string) {
+	b.sendMessage(&tgbotapi.Message{
+		Chat: &tgbotapi.Chat{ID: chatID},
+		Text: text,
+		ReplyToMessage: &tgbotapi.Message{
+			Chat: &tgbotapi.Chat{ID: to.ChatID},
+			Text: to.Text,
+		},
+	})
+}
+
+// postToTopic emulates a message posted to the forum topic, telegram sends it
+// as a reply to the service message that has created the topic.
+func (b *bridge) postToTopic(threadID int, text string) {
+	b.sendTopicMessage(threadID, &tgbotapi.Message{
+		Text: text,
+		ReplyToMessage: &tgbotapi.Message{
+			MessageID: threadID,
+			Chat:      &tgbotapi.Chat{ID: testChatID},
+		},
+	})
+}
+
+// edit emulates an edit of the telegram message written by the user.
+func (b *bridge) edit(messageID int, text string) {
+	b.lastUpdateID++
+	b.updates <- telegram.Update{Update: tgbotapi.Update{
+		UpdateID: b.lastUpdateID,
+		EditedMessage: &tgbotapi.Message{
+			MessageID: messageID,
+			From:      &tgbotapi.User{UserName: testUserName},
+			Chat:      &tgbotapi.Chat{ID: testChatID},
+			Text:      text,
+		},
+	}}
+}
+
+// react emulates a reaction of the user to the telegram message.
+func (b *bridge) react(messageID int, emoji string) {
+	b.reactions <- telegram.MessageReaction{
+		Chat:        &tgbotapi.Chat{ID: testChatID},
+		MessageID:   messageID,
+		User:        &tgbotapi.User{UserName: testUserName},
+		NewReaction: []telegram.ReactionType{{Type: "emoji", Emoji: emoji}},
+	}
+}
+
+// press emulates a press of the inline keyboard button.
+func (b *bridge) press(button domain.TelegramButton) {
+	b.lastUpdateID++
+	b.updates <- telegram.Update{Update: tgbotapi.Update{
+		UpdateID: b.lastUpdateID,
+		CallbackQuery: &tgbotapi.CallbackQuery{
+			ID:   "callback-" + button.CallbackData,
+			From: &tgbotapi.User{UserName: testUserName},
+			Message: &tgbotapi.Message{
+				Chat: &tgbotapi.Chat{ID: testChatID},
+			},
+			Data: button.CallbackData,
+		},
+	}}
+}
+
+func (b *bridge) sendMessage(msg *tgbotapi.Message) {
+	b.sendTopicMessage(0, msg)
+}
+
+// sendTopicMessage emulates the message posted to the forum topic, it's posted
+// to the chat itself if the thread identifier is zero.
+func (b *bridge) sendTopicMessage(threadID int, msg *tgbotapi.Message) {
+	b.lastUpdateID++
+	b.lastMessageID++
+
+	msg.MessageID = b.lastMessageID
+	if msg.From == nil {
+		msg.From = &tgbotapi.User{UserName: testUserName}
+	}
+	if msg.Chat == nil {
+		msg.Chat = &tgbotapi.Chat{ID: testChatID}
+	}
+	b.updates <- telegram.Update{
+		Update: tgbotapi.Update{
+			UpdateID: b.lastUpdateID,
+			Message:  msg,
+		},
+		MessageThreadID: threadID,
+	}
+}
+
+// waitForText waits for a telegram text message that contains substr.
+func (b *bridge) waitForText(substr string) domain.TelegramTextMessage {
+	b.t.Helper()
+
+	var found domain.TelegramTextMessage
+	require.Eventually(b.t, func() bool {
+		for _, msg := range b.telegramClient.TextMessages() {
+			if strings.Contains(msg.Text, substr) {
+				found = msg
+
+				return true
+			}
+		}
+
+		return false
+	}, waitTimeout, waitInterval, "no telegram message containing %q", substr)
+
+	return found
+}
+
+// waitForSent waits for n whatsapp messages sent through the session. Identifiers of the text
+// messages are random, so they are cleared, use session.Sent to get them.
+func (b *bridge) waitForSent(session *simulator.Session, n int) []domain.WhatsappMessage {
+	b.t.Helper()
+
+	require.Eventually(b.t, func() bool {
+		return len(session.Sent()) >= n
+	}, waitTimeout, waitInterval, "expected %d sent whatsapp messages", n)
+
+	sent := session.Sent()
+	for i, msg := range sent {
+		if textMessage, ok := msg.(*domain.WhatsappTextMessage); ok {
+			withoutID := *textMessage
+			withoutID.ID = ""
+			sent[i] = &withoutID
+		}
+	}
+
+	return sent
+}
+
+// login goes through the login flow and returns the simulated session.
+func (b *bridge) login() *simulator.Session {
+	b.t.Helper()
+
+	b.sendText("/start")
+	b.waitForText("Telegram<->WhatsApp bridge")
+
+	return b.relogin()
+}
+
+func (b *bridge) relogin() *simulator.Session {
+	b.t.Helper()
+
+	return b.loginAccount(domain.DefaultWhatsappAccount)
+}
+
+// loginAccount logs the whatsapp account in and returns its simulated session.
+func (b *bridge) loginAccount(account string) *simulator.Session {
+	b.t.Helper()
+
+	loggedInMsg := "Successfully logged in" + domain.AccountTag(account) + "\n"
+	loginsBefore := strings.Count(strings.Join(b.telegramClient.Texts(), "\n")+"\n", loggedInMsg)
+
+	if account == domain.DefaultWhatsappAccount {
+		b.sendText("/login")
+	} else {
+		b.sendText("/login " + account)
+	}
+	require.Eventually(b.t, func() bool {
+		texts := strings.Join(b.telegramClient.Texts(), "\n") + "\n"
+
+		return strings.Count(texts, loggedInMsg) > loginsBefore
+	}, waitTimeout, waitInterval, "login hasn't completed")
+
+	session, ok := b.backend.Session(testChatID, account)
+	require.True(b.t, ok)
+
+	return session
+}
+
+func TestBridge(t *testing.T) {
+	t.Run("login, receive message and reply", func(t *testing.T) {
+		b := startBridge(t)
+		session := b.login()
+
+		// The QR-code is sent to the chat
+		require.Eventually(t, func() bool {
+			return len(b.telegramClient.PhotoMessages()) == 1
+		}, waitTimeout, waitInterval)
+
+		// Incoming whatsapp message is bridged to telegram
+		session.ReceiveText(aliceJid, "hi there")
+		incoming := b.waitForText("hi there")
+		assert.Equal(t, testChatID, incoming.ChatID)
+		assert.Contains(t, incoming.Text, "Alice")
+		assert.Equal(t, aliceJid, domain.ExtractMsgJid(incoming.Text))
+
+		// Reply in telegram is sent to the contact
+		b.reply(incoming, "hello, Alice")
+		sent := b.waitForSent(session, 1)
+		assert.Equal(t, &domain.WhatsappTextMessage{
+			RemoteJid: aliceJid,
+			Text:      "hello, Alice",
+		}, sent[0])
+	})
+
+	t.Run("repeated login", func(t *testing.T) {
+		b := startBridge(t)
+		b.login()
+
+		b.sendText("/login")
+		b.waitForText("Already logged in")
+	})
+
+	t.Run("failed login", func(t *testing.T) {
+		b := startBridge(t)
+		b.backend.FailNextLogin(errTestNetwork)
+
+		b.sendText("/start")
+		b.sendText("/login")
+		b.waitForText("QR-code scanning timed out")
+
+		// The next attempt succeeds
+		b.relogin()
+	})
+
+	t.Run("logout", func(t *testing.T) {
+		b := startBridge(t)
+		session := b.login()
+
+		b.sendText("/logout")
+		b.waitForText("Successfully logged out")
+		assert.False(t, session.LoggedIn())
+
+		b.sendText("/logout")
+		b.waitForText("Already logged out")
+	})
+
+	t.Run("replies are kept in order while the session is being restored", func(t *testing.T) {
+		b := startBridge(t)
+		session := b.login()
+
+		session.ReceiveText(aliceJid, "are you there?")
+		incoming := b.waitForText("are you there?")
+
+		// Sending fails while the connection is unstable
+		session.SetSendError(errTestNetwork)
+		b.reply(incoming, "first")
+		b.waitForText("once the WhatsApp session is restored")
+		b.reply(incoming, "second")
+
+		// Once the session is restored, queued messages are delivered in order
+		session.SetSendError(nil)
+		session.Disconnect(nil)
+
+		sent := b.waitForSent(session, 2)
+		assert.Equal(t, []domain.WhatsappMessage{
+			&domain.WhatsappTextMessage{RemoteJid: aliceJid, Text: "first"},
+			&domain.WhatsappTextMessage{RemoteJid: aliceJid, Text: "second"},
+		}, sent)
+	})
+
+	t.Run("failed message is retried by button", func(t *testing.T) {
+		b := startBridge(t)
+		session := b.login()
+
+		session.ReceiveText(bobJid, "ping")
+		incoming := b.waitForText("ping")
+
+		// Run out of delivery attempts
+		session.SetSendError(errTestNetwork)
+		b.reply(incoming, "pong")
+		b.waitForText("once the WhatsApp session is restored")
+		session.Disconnect(nil)
+		failed := b.waitForText("Failed to deliver message")
+		assert.Equal(t, bobJid, domain.ExtractMsgJid(failed.Text))
+		require.Len(t, failed.Buttons, 1)
+		require.Len(t, failed.Buttons[0], 1)
+
+		session.SetSendError(nil)
+		b.press(failed.Buttons[0][0])
+
+		sent := b.waitForSent(session, 1)
+		assert.Equal(t, &domain.WhatsappTextMessage{RemoteJid: bobJid, Text: "pong"}, sent[0])
+		require.Eventually(t, func() bool {
+			return len(b.telegramClient.CallbackAnswers()) == 1
+		}, waitTimeout, waitInterval)
+	})
+
+	t.Run("session is lost and replies are delivered after the next login", func(t *testing.T) {
+		b := startBridge(t)
+		session := b.login()
+
+		session.ReceiveText(aliceJid, "call me")
+		incoming := b.waitForText("call me")
+
+		// The session can't be restored
+		session.Disconnect(errTestNetwork)
+		b.waitForText("The session is invalidated")
+		assert.False(t, session.LoggedIn())
+
+		b.reply(incoming, "calling you later")
+		b.waitForText("will be sent after /login")
+
+		newSession := b.relogin()
+		sent := b.waitForSent(newSession, 1)
+		assert.Equal(t, &domain.WhatsappTextMessage{RemoteJid: aliceJid, Text: "calling you later"}, sent[0])
+		assert.Empty(t, b.outbox.Messages(testChatID))
+	})
+	t.Run("QR-code is refreshed and login is cancelled", func(t *testing.T) {
+		b := startBridgeWithBackend(t, &simulator.Opts{
+			LoginDelay:    time.Minute,
+			QRCodeTimeout: 50 * time.Millisecond,
+		})
+
+		b.sendText("/start")
+		b.sendText("/login")
+
+		// The same photo is updated with new QR-codes
+		require.Eventually(t, func() bool {
+			return len(b.telegramClient.PhotoEdits()) >= 2
+		}, waitTimeout, waitInterval)
+		photos := b.telegramClient.PhotoMessages()
+		require.Len(t, photos, 1)
+		require.Len(t, photos[0].Buttons, 1)
+		require.Len(t, photos[0].Buttons[0], 1)
+
+		b.press(photos[0].Buttons[0][0])
+		b.waitForText("Login is cancelled")
+		_, ok := b.backend.Session(testChatID, domain.DefaultWhatsappAccount)
+		assert.False(t, ok)
+
+		// No QR-codes are sent once the login is cancelled
+		photoEdits := len(b.telegramClient.PhotoEdits())
+		time.Sleep(100 * time.Millisecond)
+		assert.Len(t, b.telegramClient.PhotoEdits(), photoEdits)
+	})
+	t.Run("multiple accounts", func(t *testing.T) {
+		b := startBridge(t)
+		personal := b.login()
+		work := b.loginAccount("work")
+
+		work.ReceiveText(aliceJid, "meeting at 5?")
+		incoming := b.waitForText("meeting at 5?")
+		assert.Contains(t, incoming.Text, "[account: work]")
+
+		// The reply is sent from the account the message came to
+		b.reply(incoming, "sure")
+		sent := b.waitForSent(work, 1)
+		assert.Equal(t, &domain.WhatsappTextMessage{RemoteJid: aliceJid, Text: "sure"}, sent[0])
+		assert.Empty(t, personal.Sent())
+
+		personal.ReceiveText(bobJid, "dinner?")
+		incoming = b.waitForText("dinner?")
+		assert.NotContains(t, incoming.Text, "[account:")
+
+		b.reply(incoming, "yes")
+		sent = b.waitForSent(personal, 1)
+		assert.Equal(t, &domain.WhatsappTextMessage{RemoteJid: bobJid, Text: "yes"}, sent[0])
+		assert.Len(t, work.Sent(), 1)
+	})
+
+	t.Run("team inbox", func(t *testing.T) {
+		b := startBridge(t)
+		session := b.login()
+
+		agent := &tgbotapi.User{UserName: "agent", FirstName: "Support", LastName: "Agent"}
+		stranger := &tgbotapi.User{UserName: "stranger"}
+
+		b.sendText("/team on")
+		b.waitForText("you're the owner")
+		b.sendText("/team add @agent")
+		b.waitForText("@agent is a member of the team now")
+		b.sendText("/team sign on")
+		b.waitForText("Signed replies are on")
+
+		// Members reply on behalf of the account, their replies are signed
+		session.ReceiveText(aliceJid, "is anybody there?")
+		incoming := b.waitForText("is anybody there?")
+		b.replyAs(agent, incoming, "yes, how can I help?")
+		sent := b.waitForSent(session, 1)
+		assert.Equal(t, &domain.WhatsappTextMessage{
+			RemoteJid: aliceJid,
+			Text:      "yes, how can I help?\n\n— Support Agent",
+		}, sent[0])
+
+		// Strangers can't reply
+		b.replyAs(stranger, incoming, "hi, Alice")
+		b.waitForText("You're not a member of the team")
+
+		// The agent claims the conversation
+		b.replyAs(agent, incoming, "/assign")
+		b.waitForText("The conversation [jid: alice@s.whatsapp.net] is assigned to @agent")
+
+		session.ReceiveText(aliceJid, "thanks")
+		assigned := b.waitForText("thanks")
+		assert.Contains(t, assigned.Text, "[assigned: @agent]")
+		assert.Equal(t, aliceJid, domain.ExtractMsgJid(assigned.Text))
+	})
+
+	t.Run("forum topics", func(t *testing.T) {
+		b := startBridge(t)
+		session := b.login()
+
+		b.sendText("/topics on")
+		b.waitForText("bridged into topics now")
+
+		// Every conversation gets its own topic
+		session.ReceiveText(aliceJid, "hi from Alice")
+		fromAlice := b.waitForText("hi from Alice")
+		session.ReceiveText(bobJid, "hi from Bob")
+		fromBob := b.waitForText("hi from Bob")
+		assert.NotZero(t, fromAlice.ThreadID)
+		assert.NotZero(t, fromBob.ThreadID)
+		assert.NotEqual(t, fromAlice.ThreadID, fromBob.ThreadID)
+		assert.Equal(t, "Alice", b.telegramClient.Topics()[fromAlice.ThreadID])
+
+		// Any message posted to the topic is sent to its contact
+		b.postToTopic(fromBob.ThreadID, "hello, Bob")
+		sent := b.waitForSent(session, 1)
+		assert.Equal(t, &domain.WhatsappTextMessage{
+			RemoteJid: bobJid,
+			Text:      "hello, Bob",
+		}, sent[0])
+
+		// Replies to messages in the topic keep working
+		b.reply(fromAlice, "hello, Alice")
+		sent = b.waitForSent(session, 2)
+		assert.Equal(t, &domain.WhatsappTextMessage{
+			RemoteJid: aliceJid,
+			Text:      "hello, Alice",
+		}, sent[1])
+	})
+
+	t.Run("routes", func(t *testing.T) {
+		b := startBridge(t)
+		session := b.login()
+
+		routedChatID := int64(-100456)
+		b.sendText("/route add bob@s.whatsapp.net -100456")
+		b.waitForText("Messages of bob@s.whatsapp.net are delivered to chat -100456 now")
+
+		// Messages of the routed contact are delivered to the routed chat
+		session.ReceiveText(bobJid, "hi from Bob")
+		fromBob := b.waitForText("hi from Bob")
+		assert.Equal(t, routedChatID, fromBob.ChatID)
+
+		// Other contacts fall back to the chat the account is logged in from
+		session.ReceiveText(aliceJid, "hi from Alice")
+		fromAlice := b.waitForText("hi from Alice")
+		assert.Equal(t, testChatID, fromAlice.ChatID)
+
+		// Replies from the routed chat go back to the contact
+		b.replyIn(routedChatID, fromBob, "hello, Bob")
+		sent := b.waitForSent(session, 1)
+		assert.Equal(t, &domain.WhatsappTextMessage{
+			RemoteJid: bobJid,
+			Text:      "hello, Bob",
+		}, sent[0])
+	})
+
+	t.Run("active conversation", func(t *testing.T) {
+		b := startBridge(t)
+		session := b.login()
+
+		b.sendText("/chat bob")
+		status := b.waitForText("Active conversation: Bob [jid: bob@s.whatsapp.net]")
+		require.Eventually(t, func() bool {
+			return len(b.telegramClient.Pinned(testChatID)) == 1
+		}, waitTimeout, waitInterval)
+
+		// Plain messages are sent to the active conversation
+		b.sendText("hello, Bob")
+		sent := b.waitForSent(session, 1)
+		assert.Equal(t, &domain.WhatsappTextMessage{
+			RemoteJid: bobJid,
+			Text:      "hello, Bob",
+		}, sent[0])
+
+		// The status message is unpinned once the conversation is closed
+		b.press(status.Buttons[0][0])
+		require.Eventually(t, func() bool {
+			return len(b.telegramClient.Pinned(testChatID)) == 0
+		}, waitTimeout, waitInterval)
+	})
+
+	t.Run("contact settings", func(t *testing.T) {
+		b := startBridge(t)
+		session := b.login()
+
+		session.ReceiveText(bobJid, "first message from Bob")
+		incoming := b.waitForText("first message from Bob")
+
+		// Mute the contact with the buttons of its message
+		b.press(incoming.Buttons[0][0])
+		settings := b.waitForText("Messages of [jid: bob@s.whatsapp.net] are delivered as usual")
+		b.press(settings.Buttons[1][0])
+		b.waitForText("Messages of [jid: bob@s.whatsapp.net] are dropped")
+
+		session.ReceiveText(bobJid, "second message from Bob")
+		session.ReceiveText(aliceJid, "hi from Alice")
+		b.waitForText("hi from Alice")
+		for _, text := range b.telegramClient.Texts() {
+			assert.NotContains(t, text, "second message from Bob")
+		}
+	})
+
+	t.Run("edit and delete", func(t *testing.T) {
+		b := startBridge(t)
+		session := b.login()
+
+		session.ReceiveText(aliceJid, "lunch at 12?")
+		incoming := b.waitForText("lunch at 12?")
+		b.reply(incoming, "see you at 12")
+		b.waitForSent(session, 1)
+		replyID := b.lastMessageID
+
+		// The contact sees the edited text
+		b.edit(replyID, "see you at 1")
+		require.Eventually(t, func() bool {
+			history, err := session.History(aliceJid, 10)
+
+			return err == nil && len(history) == 2 && history[1].Text == "see you at 1"
+		}, waitTimeout, waitInterval)
+
+		b.sendMessage(&tgbotapi.Message{
+			Text: "/delete",
+			ReplyToMessage: &tgbotapi.Message{
+				MessageID: replyID,
+				Chat:      &tgbotapi.Chat{ID: testChatID},
+				Text:      "see you at 1",
+			},
+		})
+		b.waitForText("The message to Alice has been deleted for everyone")
+		history, err := session.History(aliceJid, 10)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+
+		// The copy of the message deleted by the contact tells it's deleted
+		session.ReceiveRevoke(aliceJid, history[0].ID)
+		require.Eventually(t, func() bool {
+			for _, edit := range b.telegramClient.Edits() {
+				if strings.HasSuffix(edit.Text, "Message: message deleted") {
+					return true
+				}
+			}
+
+			return false
+		}, waitTimeout, waitInterval)
+	})
+
+	t.Run("reactions", func(t *testing.T) {
+		b := startBridge(t)
+		session := b.login()
+
+		session.ReceiveText(aliceJid, "lunch at 12?")
+		incoming := b.waitForText("lunch at 12?")
+		history, err := session.History(aliceJid, 10)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+
+		// The reaction of the contact is set on the telegram copy of the message
+		session.ReceiveReaction(aliceJid, history[0].ID, "❤️")
+		require.Eventually(t, func() bool {
+			return len(b.telegramClient.Reactions()) == 1
+		}, waitTimeout, waitInterval)
+		assert.Equal(t, "❤️", b.telegramClient.Reactions()[0].Emoji)
+
+		// The reaction of the user is sent to the contact
+		b.reply(incoming, "see you at 12")
+		b.waitForSent(session, 1)
+		b.react(b.lastMessageID, "👍")
+		sent := b.waitForSent(session, 2)
+		reply, ok := session.Sent()[0].(*domain.WhatsappTextMessage)
+		require.True(t, ok)
+		assert.Equal(t, &domain.WhatsappReactionMessage{
+			RemoteJid: aliceJid,
+			MessageID: reply.ID,
+			FromMe:    true,
+			Emoji:     "👍",
+		}, sent[1])
+	})
+}
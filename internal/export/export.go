@@ -0,0 +1,327 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+)
+
+const (
+	jsonFileName = "transcript.json"
+	csvFileName  = "transcript.csv"
+	htmlFileName = "transcript.html"
+	mediaDir     = "media"
+	dateLayout   = "2006-01-02"
+)
+
+// ErrInvalidDate is returned when the date of the export range can't be parsed.
+var ErrInvalidDate = errors.New("invalid date")
+
+// Opts represents options of the export.
+type Opts struct {
+	// Title is a title of the transcript, e.g. a name of the contact.
+	Title string
+
+	// Location is a time zone the messages are shown in, UTC is used if it's nil.
+	Location *time.Location
+
+	// Messages is a list of the exported messages in chronological order.
+	Messages []domain.ArchivedMessage
+}
+
+// Select returns the messages archived in the time range that match the function,
+// the range is open if its bound is zero and all messages match if the function is nil.
+func Select(messages []domain.ArchivedMessage, from, to time.Time,
+	match func(msg *domain.ArchivedMessage) bool) []domain.ArchivedMessage {
+	selected := make([]domain.ArchivedMessage, 0)
+	for i := range messages {
+		msg := &messages[i]
+		if !from.IsZero() && msg.Timestamp.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !msg.Timestamp.Before(to) {
+			continue
+		}
+		if match != nil && !match(msg) {
+			continue
+		}
+
+		selected = append(selected, *msg)
+	}
+
+	return selected
+}
+
+// ParseDate returns the beginning of the day in the location, the date looks like 2006-01-02.
+func ParseDate(s string, loc *time.Location) (time.Time, error) {
+	date, err := time.ParseInLocation(dateLayout, s, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w %q, use 2006-01-02", ErrInvalidDate, s)
+	}
+
+	return date, nil
+}
+
+// Write writes a zip archive with the transcript of the messages in JSON, CSV and HTML formats
+// to w. Media files stored locally are put to the archive, so the transcript is self-contained.
+func Write(w io.Writer, opts *Opts) error {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	zw := zip.NewWriter(w)
+	messages, err := writeMedia(zw, opts.Messages)
+	if err != nil {
+		return err
+	}
+
+	writers := []struct {
+		name  string
+		write func(w io.Writer) error
+	}{
+		{name: jsonFileName, write: func(w io.Writer) error { return writeJSON(w, messages) }},
+		{name: csvFileName, write: func(w io.Writer) error { return writeCSV(w, messages, loc) }},
+		{name: htmlFileName, write: func(w io.Writer) error { return writeHTML(w, opts.Title, messages, loc) }},
+	}
+	for _, file := range writers {
+		fw, err := createFile(zw, file.name)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", file.name, err)
+		}
+		if err := file.write(fw); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to close archive: %w", err)
+	}
+
+	return nil
+}
+
+// writeMedia copies media files of the messages to the archive and returns the messages
+// with paths of the files in the archive. Paths of the files that aren't stored are cleared.
+func writeMedia(zw *zip.Writer, messages []domain.ArchivedMessage) ([]domain.ArchivedMessage, error) {
+	exported := make([]domain.ArchivedMessage, 0, len(messages))
+	for _, msg := range messages {
+		media := make([]domain.MediaReference, 0, len(msg.Media))
+		for i, ref := range msg.Media {
+			if ref.Path == "" {
+				media = append(media, ref)
+
+				continue
+			}
+
+			name := ref.FileName
+			if name == "" {
+				name = ref.Path
+			}
+			name = path.Join(mediaDir, fmt.Sprintf("%d-%d-%s", msg.ID, i+1, filepath.Base(name)))
+
+			copied, err := copyFile(zw, ref.Path, name)
+			if err != nil {
+				return nil, err
+			}
+
+			ref.Path = ""
+			if copied {
+				ref.Path = name
+			}
+			media = append(media, ref)
+		}
+
+		msg.Media = media
+		exported = append(exported, msg)
+	}
+
+	return exported, nil
+}
+
+// copyFile copies the file to the archive, false is returned if the file doesn't exist anymore.
+func copyFile(zw *zip.Writer, src, name string) (bool, error) {
+	file, err := os.Open(src)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer file.Close() // nolint
+
+	fw, err := createFile(zw, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	if _, err := io.Copy(fw, file); err != nil {
+		return false, fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+
+	return true, nil
+}
+
+// createFile adds a new compressed file to the archive.
+func createFile(zw *zip.Writer, name string) (io.Writer, error) {
+	return zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+}
+
+// writeJSON writes the messages as a JSON array.
+func writeJSON(w io.Writer, messages []domain.ArchivedMessage) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(messages)
+}
+
+// writeCSV writes the messages as CSV records with a header.
+func writeCSV(w io.Writer, messages []domain.ArchivedMessage, loc *time.Location) error {
+	cw := csv.NewWriter(w)
+	header := []string{
+		"id", "time", "direction", "account", "remote_jid", "sender",
+		"text", "media", "telegram_chat_id", "telegram_message_id",
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, msg := range messages {
+		media := make([]string, 0, len(msg.Media))
+		for _, ref := range msg.Media {
+			media = append(media, mediaName(ref))
+		}
+
+		record := []string{
+			strconv.FormatInt(msg.ID, 10),
+			msg.Timestamp.In(loc).Format(time.RFC3339),
+			string(msg.Direction),
+			msg.Account,
+			msg.RemoteJid,
+			msg.SenderName,
+			msg.Text,
+			strings.Join(media, ";"),
+			strconv.FormatInt(msg.TelegramChatID, 10),
+			strconv.Itoa(msg.TelegramMessageID),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// mediaName returns a path of the media file in the archive, or its name if it isn't stored.
+func mediaName(ref domain.MediaReference) string {
+	if ref.Path != "" {
+		return ref.Path
+	}
+	if ref.FileName != "" {
+		return ref.FileName
+	}
+
+	return ref.Type
+}
+
+// htmlMessage represents a message of the HTML transcript.
+type htmlMessage struct {
+	Time     string
+	Incoming bool
+	Sender   string
+	Text     string
+	Media    []htmlMedia
+}
+
+// htmlMedia represents a media file of the HTML transcript.
+type htmlMedia struct {
+	Name  string
+	Path  string
+	Image bool
+}
+
+// writeHTML writes the messages as an HTML page that looks like a chat.
+func writeHTML(w io.Writer, title string, messages []domain.ArchivedMessage, loc *time.Location) error {
+	page := struct {
+		Title    string
+		TimeZone string
+		Messages []htmlMessage
+	}{
+		Title:    title,
+		TimeZone: loc.String(),
+		Messages: make([]htmlMessage, 0, len(messages)),
+	}
+
+	for _, msg := range messages {
+		sender := msg.SenderName
+		if sender == "" {
+			sender = msg.RemoteJid
+			if msg.Direction == domain.MessageDirectionOut {
+				sender = "You"
+			}
+		}
+
+		m := htmlMessage{
+			Time:     msg.Timestamp.In(loc).Format("2006-01-02 15:04:05"),
+			Incoming: msg.Direction == domain.MessageDirectionIn,
+			Sender:   sender,
+			Text:     msg.Text,
+		}
+		for _, ref := range msg.Media {
+			m.Media = append(m.Media, htmlMedia{
+				Name:  mediaName(ref),
+				Path:  ref.Path,
+				Image: ref.Path != "" && (ref.Type == "image" || strings.HasPrefix(ref.MimeType, "image/")),
+			})
+		}
+		page.Messages = append(page.Messages, m)
+	}
+
+	return htmlTemplate.Execute(w, page)
+}
+
+var htmlTemplate = template.Must(template.New(htmlFileName).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 800px; margin: 0 auto; padding: 16px; background: #f5f5f5; }
+.message { margin: 8px 0; padding: 8px 12px; border-radius: 8px; max-width: 80%; }
+.in { background: #fff; }
+.out { background: #dcf8c6; margin-left: auto; }
+.meta { color: #888; font-size: 12px; }
+.text { white-space: pre-wrap; }
+img { max-width: 100%; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">{{len .Messages}} messages, time zone {{.TimeZone}}</p>
+{{range .Messages}}<div class="message {{if .Incoming}}in{{else}}out{{end}}">
+<div class="meta">{{.Sender}}, {{.Time}}</div>
+{{if .Text}}<div class="text">{{.Text}}</div>
+{{end}}{{range .Media}}{{if .Image}}<img src="{{.Path}}" alt="{{.Name}}">
+{{else if .Path}}<a href="{{.Path}}">{{.Name}}</a>
+{{else}}<div class="meta">{{.Name}} (not stored)</div>
+{{end}}{{end}}</div>
+{{end}}</body>
+</html>
+`))
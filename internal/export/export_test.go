@@ -0,0 +1,134 @@
+package export_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/export"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTime = time.Date(2022, 3, 31, 10, 0, 0, 0, time.UTC)
+
+func testMessages(t *testing.T) []domain.ArchivedMessage {
+	t.Helper()
+
+	photo := filepath.Join(t.TempDir(), "photo.jpg")
+	require.NoError(t, os.WriteFile(photo, []byte("jpeg"), 0o600))
+
+	return []domain.ArchivedMessage{
+		{
+			ID:                1,
+			ChatID:            123,
+			Direction:         domain.MessageDirectionIn,
+			RemoteJid:         "alice@s.whatsapp.net",
+			SenderName:        "Alice",
+			Text:              "Lunch <tomorrow>?",
+			Timestamp:         testTime,
+			TelegramChatID:    123,
+			TelegramMessageID: 42,
+		},
+		{
+			ID:         2,
+			ChatID:     123,
+			Direction:  domain.MessageDirectionOut,
+			RemoteJid:  "alice@s.whatsapp.net",
+			SenderName: "Test User",
+			Text:       "Sure, see the place",
+			Media: []domain.MediaReference{
+				{Type: "image", FileName: "place.jpg", Path: photo},
+				{Type: "document", FileName: "menu.pdf", Path: filepath.Join(t.TempDir(), "deleted.pdf")},
+			},
+			Timestamp: testTime.Add(time.Hour),
+		},
+	}
+}
+
+// readZip returns content of the files of the zip archive.
+func readZip(t *testing.T, raw []byte) map[string]string {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	require.NoError(t, err)
+
+	files := make(map[string]string)
+	for _, file := range zr.File {
+		r, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+		files[file.Name] = string(content)
+	}
+
+	return files
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, export.Write(&buf, &export.Opts{
+		Title:    "Alice",
+		Messages: testMessages(t),
+	}))
+
+	files := readZip(t, buf.Bytes())
+	require.Len(t, files, 4)
+	assert.Equal(t, "jpeg", files["media/2-1-place.jpg"])
+
+	var messages []domain.ArchivedMessage
+	require.NoError(t, json.Unmarshal([]byte(files["transcript.json"]), &messages))
+	require.Len(t, messages, 2)
+	assert.Equal(t, "Lunch <tomorrow>?", messages[0].Text)
+	assert.Equal(t, []domain.MediaReference{
+		{Type: "image", FileName: "place.jpg", Path: "media/2-1-place.jpg"},
+		{Type: "document", FileName: "menu.pdf"},
+	}, messages[1].Media)
+
+	records, err := csv.NewReader(bytes.NewBufferString(files["transcript.csv"])).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "time", records[0][1])
+	assert.Equal(t, []string{
+		"2", "2022-03-31T11:00:00Z", "out", "", "alice@s.whatsapp.net", "Test User",
+		"Sure, see the place", "media/2-1-place.jpg;menu.pdf", "0", "0",
+	}, records[2])
+
+	html := files["transcript.html"]
+	assert.Contains(t, html, "<title>Alice</title>")
+	assert.Contains(t, html, "Lunch &lt;tomorrow&gt;?")
+	assert.Contains(t, html, `<img src="media/2-1-place.jpg" alt="media/2-1-place.jpg">`)
+	assert.Contains(t, html, "menu.pdf (not stored)")
+	assert.Contains(t, html, "Alice, 2022-03-31 10:00:00")
+}
+
+func TestSelect(t *testing.T) {
+	messages := testMessages(t)
+
+	assert.Len(t, export.Select(messages, time.Time{}, time.Time{}, nil), 2)
+	assert.Equal(t, messages[1:], export.Select(messages, testTime.Add(time.Minute), time.Time{}, nil))
+	assert.Equal(t, messages[:1], export.Select(messages, time.Time{}, testTime.Add(time.Hour), nil))
+	assert.Empty(t, export.Select(messages, time.Time{}, time.Time{}, func(msg *domain.ArchivedMessage) bool {
+		return msg.RemoteJid == "bob@s.whatsapp.net"
+	}))
+}
+
+func TestParseDate(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	date, err := export.ParseDate("2022-03-31", loc)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 3, 31, 0, 0, 0, 0, loc), date)
+
+	_, err = export.ParseDate("31.03.2022", loc)
+	assert.ErrorIs(t, err, export.ErrInvalidDate)
+}
@@ -0,0 +1,236 @@
+package files
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/storage"
+)
+
+const (
+	indexFileName = "files.json"
+	dataDirPerm   = 0o700
+	dataFilePerm  = 0o600
+	fileIDSize    = 16
+
+	// defaultTTL is a period the links are valid for by default.
+	defaultTTL = 24 * time.Hour
+
+	defaultMimeType = "application/octet-stream"
+)
+
+// ErrNotFound is returned when the file doesn't exist or its link has expired.
+var ErrNotFound = errors.New("file not found")
+
+// Store represents a local storage of the files that are downloaded with time-limited
+// links served by the bridge. The files are removed once their links expire.
+type Store struct {
+	mu      sync.Mutex
+	dir     string
+	baseURL string
+	ttl     time.Duration
+	now     func() time.Time
+	file    *storage.JSONFile
+	files   []domain.StoredFile
+}
+
+// Opts represents options to create new instance of Store.
+type Opts struct {
+	// Dir is a directory the files and their index are kept in.
+	Dir string
+
+	// BaseURL is a public URL the files are served at, e.g. https://bridge.example.com/files.
+	BaseURL string
+
+	// TTL is a period the links are valid for, a day is used if it's zero.
+	TTL time.Duration
+
+	// Clock returns the current time, time.Now is used if it's nil.
+	Clock func() time.Time
+}
+
+// New creates new instance of Store and loads the index of previously stored files.
+func New(opts *Opts) (*Store, error) {
+	ttl := opts.TTL
+	if ttl == 0 {
+		ttl = defaultTTL
+	}
+	clock := opts.Clock
+	if clock == nil {
+		clock = time.Now
+	}
+
+	s := &Store{
+		dir:     opts.Dir,
+		baseURL: strings.TrimSuffix(opts.BaseURL, "/"),
+		ttl:     ttl,
+		now:     clock,
+		file:    storage.NewJSONFile(filepath.Join(opts.Dir, indexFileName)),
+		files:   make([]domain.StoredFile, 0),
+	}
+
+	if err := s.file.Load(&s.files); err != nil {
+		return nil, fmt.Errorf("failed to load files: %w", err)
+	}
+
+	return s, nil
+}
+
+// Put method saves the file and returns it, the file can be downloaded by its link until it expires.
+func (s *Store) Put(name, mimeType string, data []byte) (domain.StoredFile, error) {
+	id, err := newFileID()
+	if err != nil {
+		return domain.StoredFile{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, dataDirPerm); err != nil {
+		return domain.StoredFile{}, fmt.Errorf("failed to create %s: %w", s.dir, err)
+	}
+
+	// Files are stored by their identifiers, so the original names can't escape the directory
+	file := domain.StoredFile{
+		ID:        id,
+		Name:      name,
+		MimeType:  mimeType,
+		Size:      int64(len(data)),
+		Path:      filepath.Join(s.dir, id),
+		ExpiresAt: s.now().Add(s.ttl),
+	}
+	if err := os.WriteFile(file.Path, data, dataFilePerm); err != nil {
+		return domain.StoredFile{}, fmt.Errorf("failed to write %s: %w", file.Path, err)
+	}
+
+	files := append(append(make([]domain.StoredFile, 0, len(s.files)+1), s.files...), file)
+	if err := s.file.Save(files); err != nil {
+		os.Remove(file.Path) // nolint
+
+		return domain.StoredFile{}, err
+	}
+	s.files = files
+
+	return file, nil
+}
+
+// Link method returns the link the file is downloaded by.
+func (s *Store) Link(file domain.StoredFile) string {
+	return s.baseURL + "/" + file.ID + "/" + url.PathEscape(file.Name)
+}
+
+// Get method returns the file by its identifier, ErrNotFound is returned if its link has expired.
+func (s *Store) Get(id string) (domain.StoredFile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for _, file := range s.files {
+		if file.ID == id && now.Before(file.ExpiresAt) {
+			return file, nil
+		}
+	}
+
+	return domain.StoredFile{}, ErrNotFound
+}
+
+// RemoveExpired method removes the files whose links have expired.
+func (s *Store) RemoveExpired() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	files := make([]domain.StoredFile, 0, len(s.files))
+	var expired []domain.StoredFile
+	for _, file := range s.files {
+		if now.Before(file.ExpiresAt) {
+			files = append(files, file)
+		} else {
+			expired = append(expired, file)
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	for _, file := range expired {
+		if err := os.Remove(file.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", file.Path, err)
+		}
+	}
+
+	if err := s.file.Save(files); err != nil {
+		return err
+	}
+	s.files = files
+
+	return nil
+}
+
+// ServeHTTP method serves the file by its link, the path is relative to the base URL.
+func (s *Store) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	// The name in the link is informational, the file is found by its identifier
+	id := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)[0]
+	file, err := s.Get(id)
+	if err != nil {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	f, err := os.Open(file.Path)
+	if err != nil {
+		http.NotFound(w, r)
+
+		return
+	}
+	defer f.Close() // nolint
+
+	stat, err := f.Stat()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
+
+	// The type is chosen by the sender, so browsers are told not to guess
+	// another one and to download the file instead of opening it
+	mimeType := file.MimeType
+	if _, _, err := mime.ParseMediaType(mimeType); err != nil {
+		mimeType = defaultMimeType
+	}
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": file.Name,
+	}))
+	http.ServeContent(w, r, file.Name, stat.ModTime(), f)
+}
+
+func newFileID() (string, error) {
+	raw := make([]byte, fileIDSize)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate file id: %w", err)
+	}
+
+	return hex.EncodeToString(raw), nil
+}
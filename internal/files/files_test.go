@@ -0,0 +1,165 @@
+package files_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTime = time.Date(2022, 3, 31, 10, 0, 0, 0, time.UTC)
+
+// testClock returns the time that can be moved forward by the tests.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func newTestStore(t *testing.T, clock *testClock) *files.Store {
+	t.Helper()
+
+	store, err := files.New(&files.Opts{
+		Dir:     t.TempDir(),
+		BaseURL: "https://bridge.example.com/files/",
+		TTL:     time.Hour,
+		Clock:   clock.Now,
+	})
+	require.NoError(t, err)
+
+	return store
+}
+
+func TestStore(t *testing.T) {
+	t.Run("put file", func(t *testing.T) {
+		clock := &testClock{now: testTime}
+		store := newTestStore(t, clock)
+
+		file, err := store.Put("Q1 report.pdf", "application/pdf", []byte("%PDF"))
+		require.NoError(t, err)
+		assert.Len(t, file.ID, 32)
+		assert.Equal(t, "Q1 report.pdf", file.Name)
+		assert.Equal(t, int64(4), file.Size)
+		assert.Equal(t, testTime.Add(time.Hour), file.ExpiresAt)
+		assert.Equal(t, "https://bridge.example.com/files/"+file.ID+"/Q1%20report.pdf", store.Link(file))
+
+		data, err := os.ReadFile(file.Path)
+		require.NoError(t, err)
+		assert.Equal(t, "%PDF", string(data))
+
+		got, err := store.Get(file.ID)
+		require.NoError(t, err)
+		assert.Equal(t, file, got)
+	})
+
+	t.Run("files survive restart", func(t *testing.T) {
+		clock := &testClock{now: testTime}
+		dir := t.TempDir()
+		store, err := files.New(&files.Opts{Dir: dir, Clock: clock.Now})
+		require.NoError(t, err)
+
+		file, err := store.Put("report.pdf", "application/pdf", []byte("%PDF"))
+		require.NoError(t, err)
+		assert.Equal(t, testTime.Add(24*time.Hour), file.ExpiresAt)
+
+		restored, err := files.New(&files.Opts{Dir: dir, Clock: clock.Now})
+		require.NoError(t, err)
+		got, err := restored.Get(file.ID)
+		require.NoError(t, err)
+		assert.Equal(t, file, got)
+	})
+
+	t.Run("expired files", func(t *testing.T) {
+		clock := &testClock{now: testTime}
+		store := newTestStore(t, clock)
+
+		expired, err := store.Put("old.pdf", "", []byte("old"))
+		require.NoError(t, err)
+		clock.now = testTime.Add(30 * time.Minute)
+		fresh, err := store.Put("new.pdf", "", []byte("new"))
+		require.NoError(t, err)
+
+		// The link expires before the file is removed
+		clock.now = testTime.Add(time.Hour)
+		_, err = store.Get(expired.ID)
+		assert.ErrorIs(t, err, files.ErrNotFound)
+
+		require.NoError(t, store.RemoveExpired())
+		_, err = os.Stat(expired.Path)
+		assert.True(t, os.IsNotExist(err))
+		_, err = store.Get(fresh.ID)
+		assert.NoError(t, err)
+		_, err = os.Stat(fresh.Path)
+		assert.NoError(t, err)
+	})
+}
+
+func TestStoreServeHTTP(t *testing.T) {
+	clock := &testClock{now: testTime}
+	store := newTestStore(t, clock)
+
+	file, err := store.Put("Отчёт.pdf", "application/pdf", []byte("%PDF"))
+	require.NoError(t, err)
+	path := strings.TrimPrefix(store.Link(file), "https://bridge.example.com/files")
+
+	t.Run("download file", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		store.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "%PDF", recorder.Body.String())
+		assert.Equal(t, "application/pdf", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, "attachment; filename*=utf-8''%D0%9E%D1%82%D1%87%D1%91%D1%82.pdf",
+			recorder.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("type chosen by the sender isn't sniffed", func(t *testing.T) {
+		for mimeType, expected := range map[string]string{
+			"text/html":  "text/html",
+			"not a type": "application/octet-stream",
+		} {
+			page, err := store.Put("page.html", mimeType, []byte("<script>alert(1)</script>"))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			pagePath := strings.TrimPrefix(store.Link(page), "https://bridge.example.com/files")
+			store.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, pagePath, nil))
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, expected, recorder.Header().Get("Content-Type"))
+			assert.Equal(t, "nosniff", recorder.Header().Get("X-Content-Type-Options"))
+			assert.Equal(t, `attachment; filename=page.html`, recorder.Header().Get("Content-Disposition"))
+		}
+	})
+
+	t.Run("unknown file", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		store.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/unknown/report.pdf", nil))
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		store.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, path, nil))
+
+		assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	})
+
+	t.Run("expired link", func(t *testing.T) {
+		clock.now = testTime.Add(time.Hour)
+
+		recorder := httptest.NewRecorder()
+		store.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dstdfx/twbridge/internal/archive"
+	"github.com/dstdfx/twbridge/internal/domain"
+	"go.uber.org/zap"
+)
+
+const (
+	searchUnsupportedMsg = "Search is not supported"
+	searchUsageMsg       = "Usage: /search <query> [contact], put the query in quotes to search a phrase, " +
+		`e.g. /search "lunch plans" Alice`
+	searchNotFoundFmt  = "No messages match %q%s"
+	searchResultsFmt   = "Messages matching %q%s (%s):"
+	searchContactFmt   = " with %s"
+	searchMoreMsg      = "... and more, refine the query to find older messages"
+	searchOutgoingFmt  = "%s to %s"
+	searchYouName      = "You"
+	searchMediaFmt     = " [%s]"
+	searchTimeLayout   = "Jan 2 15:04"
+	maxSearchResults   = 20
+	searchPreviewLimit = 100
+)
+
+// postedMessage represents a message posted to telegram.
+type postedMessage struct {
+	chatID    int64
+	messageID int
+
+	// collected means that the message is collected to the digest instead of being posted,
+	// quietHours means that the digest is delivered once quiet hours are over.
+	collected  bool
+	quietHours bool
+}
+
+// HandleSearchEvent method handles search event.
+func (eh *EventsHandler) HandleSearchEvent(event *domain.SearchEvent) error {
+	eh.log.Debug("handle search event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.String("query", event.Query),
+		zap.String("contact", event.Contact))
+
+	if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+		return err
+	}
+
+	if err := eh.notifyTelegram(eh.applySearchCommand(event)); err != nil {
+		return fmt.Errorf("failed to notify telegram: %w", err)
+	}
+
+	return nil
+}
+
+// applySearchCommand searches the archive and returns a message with the found messages.
+func (eh *EventsHandler) applySearchCommand(event *domain.SearchEvent) string {
+	if eh.archive == nil {
+		return searchUnsupportedMsg
+	}
+	if event.Query == "" {
+		return searchUsageMsg
+	}
+
+	var contactTag string
+	if event.Contact != "" {
+		contactTag = fmt.Sprintf(searchContactFmt, event.Contact)
+	}
+
+	// One more message is requested to find out whether there are older ones
+	found := eh.archive.Search(&archive.Query{
+		ChatID: eh.chatID,
+		Text:   event.Query,
+		Match:  eh.archiveContactMatcher(event.Contact),
+		Limit:  maxSearchResults + 1,
+	})
+	if len(found) == 0 {
+		return fmt.Sprintf(searchNotFoundFmt, event.Query, contactTag)
+	}
+
+	loc, timeZone := eh.chatTimeZone()
+	lines := make([]string, 0, len(found)+1)
+	lines = append(lines, fmt.Sprintf(searchResultsFmt, event.Query, contactTag, timeZone))
+	for i := range found {
+		if i == maxSearchResults {
+			lines = append(lines, searchMoreMsg)
+
+			break
+		}
+
+		msg := &found[i]
+		line := fmt.Sprintf("[%s] %s: %s",
+			msg.Timestamp.In(loc).Format(searchTimeLayout),
+			eh.archivedMessageAuthor(msg),
+			archivedMessagePreview(msg))
+		if link, ok := domain.TelegramMessageLink(msg.TelegramChatID, msg.TelegramMessageID); ok {
+			line += " " + link
+		}
+		lines = append(lines, line)
+	}
+
+	text := strings.Join(lines, "\n")
+	if runes := []rune(text); len(runes) > maxTelegramMessageLength {
+		text = string(runes[:maxTelegramMessageLength])
+	}
+
+	return text
+}
+
+// archiveContactMatcher returns a function that matches archived messages of the contact,
+// nil is returned if the contact is empty, so all messages match.
+func (eh *EventsHandler) archiveContactMatcher(contact string) func(msg *domain.ArchivedMessage) bool {
+	if contact == "" {
+		return nil
+	}
+
+	if jid := queryJid(contact); jid != "" {
+		return func(msg *domain.ArchivedMessage) bool {
+			return msg.RemoteJid == jid
+		}
+	}
+
+	// Names are looked up in the contacts of the logged in accounts and in the names
+	// the messages have been received with, so messages of other accounts are found too
+	jids := make(map[string]bool)
+	for _, match := range eh.findContacts(eh.loggedInAccounts(), contact) {
+		jids[domain.NewConversationRef(match.account, match.jid)] = true
+	}
+	name := strings.ToLower(contact)
+
+	return func(msg *domain.ArchivedMessage) bool {
+		if jids[domain.NewConversationRef(msg.Account, msg.RemoteJid)] {
+			return true
+		}
+
+		return msg.Direction == domain.MessageDirectionIn && strings.Contains(strings.ToLower(msg.SenderName), name)
+	}
+}
+
+// archivedMessageAuthor returns a description of the author of the archived message.
+func (eh *EventsHandler) archivedMessageAuthor(msg *domain.ArchivedMessage) string {
+	if msg.Direction == domain.MessageDirectionIn {
+		if msg.SenderName != "" {
+			return msg.SenderName
+		}
+
+		return msg.RemoteJid
+	}
+
+	sender := msg.SenderName
+	if sender == "" {
+		sender = searchYouName
+	}
+
+	return fmt.Sprintf(searchOutgoingFmt, sender, eh.contactName(msg.Account, msg.RemoteJid))
+}
+
+// archivedMessagePreview returns the text of the archived message as a single short line.
+func archivedMessagePreview(msg *domain.ArchivedMessage) string {
+	text := strings.Join(strings.Fields(msg.Text), " ")
+	if runes := []rune(text); len(runes) > searchPreviewLimit {
+		text = string(runes[:searchPreviewLimit]) + "..."
+	}
+	for _, media := range msg.Media {
+		text += fmt.Sprintf(searchMediaFmt, strings.TrimSpace(media.Type+" "+media.FileName))
+	}
+
+	return strings.TrimSpace(text)
+}
+
+// archiveTextMessage saves the incoming message with its media files to the archive.
+func (eh *EventsHandler) archiveTextMessage(event *domain.TextMessageEvent, media []domain.MediaReference,
+	posted postedMessage) {
+	eh.archiveMessage(domain.ArchivedMessage{
+		Direction:         domain.MessageDirectionIn,
+		Account:           event.Account,
+		RemoteJid:         event.WhatsappRemoteJid,
+		SenderName:        event.WhatsappSenderName,
+		Text:              event.Text,
+		Media:             media,
+		WhatsappMessageID: event.WhatsappMessageID,
+		WhatsappSenderJid: event.WhatsappSenderJid,
+	}, posted)
+}
+
+// archiveMessage saves the bridged message to the archive, the message posted to telegram
+// is referenced by it. Errors are only logged, so the message is bridged anyway.
+func (eh *EventsHandler) archiveMessage(msg domain.ArchivedMessage, posted postedMessage) {
+	if eh.archive == nil {
+		return
+	}
+
+	msg.ChatID = eh.chatID
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = eh.now()
+	}
+	msg.TelegramChatID = posted.chatID
+	msg.TelegramMessageID = posted.messageID
+	if _, err := eh.archive.Add(msg); err != nil {
+		eh.log.Error("failed to archive message",
+			zap.String("direction", string(msg.Direction)),
+			zap.String("remote_jid", msg.RemoteJid),
+			zap.String("account", msg.Account),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,144 @@
+package handler_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// search handles the search command.
+func (env *testEnv) search(t *testing.T, query, contact string) {
+	t.Helper()
+
+	require.NoError(t, env.eventsHandler.HandleSearchEvent(&domain.SearchEvent{
+		ChatID:   testChatID,
+		FromUser: testUserName,
+		Query:    query,
+		Contact:  contact,
+	}))
+}
+
+func TestEventsHandlerArchive(t *testing.T) {
+	t.Run("messages are archived", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+		env.clock.now = time.Date(2022, 3, 31, 10, 0, 0, 0, time.UTC)
+
+		env.receive(t, "alice-jid", "Alice", "lunch?")
+		require.NoError(t, env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+			ChatID:    testChatID,
+			FromUser:  testUserName,
+			FromName:  "Test User",
+			Reply:     "sure",
+			RemoteJid: "alice-jid",
+			Account:   testAccount,
+			MessageID: 77,
+		}))
+
+		// Messages of muted contacts are archived as well, though they aren't posted to telegram
+		require.NoError(t, env.settings.SetMode(testChatID, testAccount, "bob-jid", domain.ContactModeMute))
+		env.receive(t, "bob-jid", "Bob", "spam")
+
+		messages := env.archive.Messages(testChatID)
+		require.Len(t, messages, 3)
+
+		incoming := env.telegramClient.TextMessages()
+		assert.Equal(t, domain.ArchivedMessage{
+			ID:                1,
+			ChatID:            testChatID,
+			Direction:         domain.MessageDirectionIn,
+			Account:           testAccount,
+			RemoteJid:         "alice-jid",
+			SenderName:        "Alice",
+			Text:              "lunch?",
+			Timestamp:         env.clock.now,
+			TelegramChatID:    testChatID,
+			TelegramMessageID: len(env.telegramClient.Texts()),
+		}, messages[0])
+		assert.Contains(t, incoming[len(incoming)-1].Text, "Message: lunch?")
+
+		// The reply is sent with the identifier generated by the outbox
+		assert.NotEmpty(t, messages[1].WhatsappMessageID)
+		assert.Equal(t, domain.ArchivedMessage{
+			ID:                2,
+			ChatID:            testChatID,
+			Direction:         domain.MessageDirectionOut,
+			Account:           testAccount,
+			RemoteJid:         "alice-jid",
+			SenderName:        "Test User",
+			Text:              "sure",
+			WhatsappMessageID: messages[1].WhatsappMessageID,
+			Timestamp:         env.clock.now,
+			TelegramChatID:    testChatID,
+			TelegramMessageID: 77,
+		}, messages[1])
+
+		assert.Equal(t, "spam", messages[2].Text)
+		assert.Zero(t, messages[2].TelegramMessageID)
+	})
+
+	t.Run("search", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+		require.NoError(t, env.quietHours.Set(domain.QuietHours{ChatID: testChatID, TimeZone: "UTC"}))
+		env.clock.now = time.Date(2022, 3, 31, 10, 0, 0, 0, time.UTC)
+
+		env.search(t, "", "")
+		assert.Equal(t, `Usage: /search <query> [contact], put the query in quotes to search a phrase, `+
+			`e.g. /search "lunch plans" Alice`, env.lastText(t))
+
+		// Messages of the chat routed to a supergroup can be opened by links
+		env.route(t, &domain.RouteEvent{Args: []string{"add", "alice-jid", "-1001234567890"}})
+		env.receive(t, "alice-jid", "Alice", "Lunch\ntomorrow?")
+		env.clock.now = env.clock.now.Add(5 * time.Minute)
+		env.receive(t, "bob-jid", "Bob", "lunch is ready")
+		env.clock.now = env.clock.now.Add(5 * time.Minute)
+		require.NoError(t, env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+			ChatID:    testChatID,
+			FromUser:  testUserName,
+			Reply:     "no lunch today",
+			RemoteJid: "alice-jid",
+			Account:   testAccount,
+		}))
+
+		aliceMessageID := env.archive.Messages(testChatID)[0].TelegramMessageID
+		env.search(t, "LUNCH", "")
+		assert.Equal(t, "Messages matching \"LUNCH\" (UTC):\n"+
+			"[Mar 31 10:10] You to Alice: no lunch today\n"+
+			"[Mar 31 10:05] Bob: lunch is ready\n"+
+			fmt.Sprintf("[Mar 31 10:00] Alice: Lunch tomorrow? https://t.me/c/1234567890/%d", aliceMessageID),
+			env.lastText(t))
+
+		env.search(t, "lunch", "Alice")
+		assert.Equal(t, "Messages matching \"lunch\" with Alice (UTC):\n"+
+			"[Mar 31 10:10] You to Alice: no lunch today\n"+
+			fmt.Sprintf("[Mar 31 10:00] Alice: Lunch tomorrow? https://t.me/c/1234567890/%d", aliceMessageID),
+			env.lastText(t))
+
+		env.search(t, "lunch", "bob")
+		assert.Equal(t, "Messages matching \"lunch\" with bob (UTC):\n"+
+			"[Mar 31 10:05] Bob: lunch is ready", env.lastText(t))
+
+		env.search(t, "dinner", "")
+		assert.Equal(t, `No messages match "dinner"`, env.lastText(t))
+	})
+
+	t.Run("search results are limited", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		for i := 0; i < 25; i++ {
+			env.receive(t, "alice-jid", "Alice", fmt.Sprintf("message %d", i))
+		}
+
+		env.search(t, "message", "")
+		text := env.lastText(t)
+		assert.Contains(t, text, "Alice: message 24")
+		assert.Contains(t, text, "Alice: message 5")
+		assert.NotContains(t, text, "Alice: message 4")
+		assert.Contains(t, text, "\n... and more, refine the query to find older messages")
+	})
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"go.uber.org/zap"
+)
+
+const (
+	awayUnsupportedMsg  = "Away mode is not supported"
+	awayOnFmt           = "Away mode is on, every contact gets the auto-reply once per %s:\n%s"
+	awayOffMsg          = "Away mode is off, type /away <text> to turn it on"
+	awayCooldownFmt     = "Contacts get the auto-reply once per %s"
+	invalidCooldownFmt  = "Invalid period %q, use e.g. 4h or 30m"
+	awayUsageMsg        = "Usage: /away [<text>|on|off|cooldown <period>]"
+	awayReplyFmt        = "Auto-reply to %s [jid: %s]%s\n= = = = = = = = = = = =\nMessage: %s"
+	awayReplySender     = "Auto-reply"
+	awayCooldownArg     = "cooldown"
+	defaultAwayText     = "Hi, I'm away at the moment and will get back to you as soon as possible"
+	defaultAwayCooldown = 24 * time.Hour
+	groupJidSuffix      = "@g.us"
+)
+
+// HandleAwayEvent method handles away event.
+func (eh *EventsHandler) HandleAwayEvent(event *domain.AwayEvent) error {
+	eh.log.Debug("handle away event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.String("args", event.Args))
+
+	if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+		return err
+	}
+
+	msg, err := eh.applyAwayCommand(event)
+	if err != nil {
+		return err
+	}
+
+	if err := eh.notifyTelegram(msg); err != nil {
+		return fmt.Errorf("failed to notify telegram: %w", err)
+	}
+
+	return nil
+}
+
+// applyAwayCommand applies the away command and returns a message to reply with.
+func (eh *EventsHandler) applyAwayCommand(event *domain.AwayEvent) (string, error) {
+	if eh.away == nil {
+		return awayUnsupportedMsg, nil
+	}
+
+	mode, _ := eh.away.Get(eh.chatID)
+	if mode.Text == "" {
+		mode.Text = defaultAwayText
+	}
+	if mode.Cooldown == 0 {
+		mode.Cooldown = defaultAwayCooldown
+	}
+
+	args := strings.TrimSpace(event.Args)
+	fields := strings.Fields(args)
+	switch {
+	case args == "":
+		if !mode.Enabled {
+			return awayOffMsg, nil
+		}
+
+		return fmt.Sprintf(awayOnFmt, formatPeriod(mode.Cooldown), mode.Text), nil
+	case args == teamOffArg:
+		mode.Enabled = false
+	case args == teamOnArg:
+		mode.Enabled = true
+		mode.Replies = nil
+	case fields[0] == awayCooldownArg:
+		if len(fields) != 2 {
+			return awayUsageMsg, nil
+		}
+
+		cooldown, err := time.ParseDuration(fields[1])
+		if err != nil || cooldown <= 0 {
+			return fmt.Sprintf(invalidCooldownFmt, fields[1]), nil
+		}
+		mode.Cooldown = cooldown
+		if err := eh.away.Set(mode); err != nil {
+			return "", fmt.Errorf("failed to update away mode: %w", err)
+		}
+
+		return fmt.Sprintf(awayCooldownFmt, formatPeriod(mode.Cooldown)), nil
+	default:
+		// Every contact gets the new text, even if it has got the previous one
+		mode.Enabled = true
+		mode.Text = args
+		mode.Replies = nil
+	}
+
+	if err := eh.away.Set(mode); err != nil {
+		return "", fmt.Errorf("failed to update away mode: %w", err)
+	}
+
+	if !mode.Enabled {
+		return awayOffMsg, nil
+	}
+
+	return fmt.Sprintf(awayOnFmt, formatPeriod(mode.Cooldown), mode.Text), nil
+}
+
+// sendAutoReply answers the contact with the auto-reply if away mode is on and the contact
+// hasn't got it during the cooldown period, the auto-reply is logged to the conversation.
+// Groups are never answered. Errors are only logged, so they don't affect the delivery.
+func (eh *EventsHandler) sendAutoReply(event *domain.TextMessageEvent) {
+	if eh.away == nil || strings.HasSuffix(event.WhatsappRemoteJid, groupJidSuffix) {
+		return
+	}
+
+	mode, ok := eh.away.Get(eh.chatID)
+	if !ok || !mode.Enabled {
+		return
+	}
+
+	now := eh.now()
+	for _, reply := range mode.Replies {
+		if reply.Account == event.Account && reply.RemoteJid == event.WhatsappRemoteJid &&
+			now.Sub(reply.RepliedAt) < mode.Cooldown {
+			return
+		}
+	}
+
+	whatsappClient, ok := eh.whatsappClient(event.Account)
+	if !ok {
+		return
+	}
+
+	logger := eh.log.With(
+		zap.String("remote_jid", event.WhatsappRemoteJid),
+		zap.String("account", event.Account))
+
+	err := whatsappClient.Send(&domain.WhatsappTextMessage{
+		RemoteJid: event.WhatsappRemoteJid,
+		Text:      mode.Text,
+	})
+	if err != nil {
+		logger.Error("failed to send auto-reply", zap.Error(err))
+
+		return
+	}
+
+	if err := eh.away.SetReplied(eh.chatID, event.Account, event.WhatsappRemoteJid, now); err != nil {
+		logger.Error("failed to save auto-reply", zap.Error(err))
+	}
+
+	textMessage := domain.TelegramTextMessage{
+		Text: fmt.Sprintf(awayReplyFmt,
+			event.WhatsappSenderName,
+			event.WhatsappRemoteJid,
+			domain.AccountTag(event.Account),
+			mode.Text),
+		DisableNotification: true,
+	}
+	posted, err := eh.deliverConversationMessage(event.Account,
+		event.WhatsappRemoteJid,
+		event.WhatsappSenderName,
+		textMessage)
+	if err != nil {
+		logger.Error("failed to log auto-reply", zap.Error(err))
+	}
+
+	eh.archiveMessage(domain.ArchivedMessage{
+		Direction:  domain.MessageDirectionOut,
+		Account:    event.Account,
+		RemoteJid:  event.WhatsappRemoteJid,
+		SenderName: awayReplySender,
+		Text:       mode.Text,
+	}, posted)
+}
+
+// formatPeriod returns the period without trailing zero units, e.g. "24h" instead of "24h0m0s".
+func formatPeriod(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+
+	return s
+}
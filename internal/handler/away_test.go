@@ -0,0 +1,100 @@
+package handler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// awayCommand handles the away command.
+func (env *testEnv) awayCommand(t *testing.T, args string) {
+	t.Helper()
+
+	require.NoError(t, env.eventsHandler.HandleAwayEvent(&domain.AwayEvent{
+		ChatID:   testChatID,
+		FromUser: testUserName,
+		Args:     args,
+	}))
+}
+
+func TestEventsHandlerAway(t *testing.T) {
+	t.Run("away command", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		env.awayCommand(t, "")
+		assert.Equal(t, "Away mode is off, type /away <text> to turn it on", env.lastText(t))
+
+		env.awayCommand(t, "on")
+		assert.Equal(t, "Away mode is on, every contact gets the auto-reply once per 24h:\n"+
+			"Hi, I'm away at the moment and will get back to you as soon as possible", env.lastText(t))
+
+		env.awayCommand(t, "cooldown 1h30m")
+		assert.Equal(t, "Contacts get the auto-reply once per 1h30m", env.lastText(t))
+
+		env.awayCommand(t, "cooldown soon")
+		assert.Equal(t, `Invalid period "soon", use e.g. 4h or 30m`, env.lastText(t))
+
+		env.awayCommand(t, "cooldown")
+		assert.Equal(t, "Usage: /away [<text>|on|off|cooldown <period>]", env.lastText(t))
+
+		env.awayCommand(t, "I'm on vacation\nuntil Monday")
+		assert.Equal(t, "Away mode is on, every contact gets the auto-reply once per 1h30m:\n"+
+			"I'm on vacation\nuntil Monday", env.lastText(t))
+
+		env.awayCommand(t, "off")
+		assert.Equal(t, "Away mode is off, type /away <text> to turn it on", env.lastText(t))
+
+		// The text is kept
+		env.awayCommand(t, "on")
+		assert.Contains(t, env.lastText(t), "I'm on vacation\nuntil Monday")
+	})
+
+	t.Run("auto-reply once per cooldown", func(t *testing.T) {
+		env := newTestEnv(t)
+		whatsappClientMock := newContactsClient()
+		env.login(t, whatsappClientMock)
+		env.clock.now = time.Date(2022, 3, 31, 10, 0, 0, 0, time.UTC)
+		env.awayCommand(t, "I'm away")
+		env.awayCommand(t, "cooldown 1h")
+
+		env.receive(t, "alice-jid", "Alice", "hi")
+		autoReply := sentText("alice-jid", "I'm away")
+		whatsappClientMock.AssertCalled(t, "Send", autoReply)
+
+		// The auto-reply is logged after the message silently
+		messages := env.telegramClient.TextMessages()
+		require.GreaterOrEqual(t, len(messages), 2)
+		assert.Contains(t, messages[len(messages)-2].Text, "Message: hi")
+		logged := messages[len(messages)-1]
+		assert.Equal(t, "Auto-reply to Alice [jid: alice-jid]\n= = = = = = = = = = = =\nMessage: I'm away", logged.Text)
+		assert.True(t, logged.DisableNotification)
+
+		env.clock.now = env.clock.now.Add(30 * time.Minute)
+		env.receive(t, "alice-jid", "Alice", "are you there?")
+		whatsappClientMock.AssertNumberOfCalls(t, "Send", 1)
+
+		env.clock.now = env.clock.now.Add(time.Hour)
+		env.receive(t, "alice-jid", "Alice", "hello?")
+		whatsappClientMock.AssertNumberOfCalls(t, "Send", 2)
+	})
+
+	t.Run("no auto-reply to groups and muted contacts", func(t *testing.T) {
+		env := newTestEnv(t)
+		whatsappClientMock := newContactsClient()
+		env.login(t, whatsappClientMock)
+		env.awayCommand(t, "I'm away")
+		require.NoError(t, env.settings.SetMode(testChatID, testAccount, "bob-jid", domain.ContactModeMute))
+
+		env.receive(t, "1234567890-1600000000@g.us", "Family", "dinner?")
+		env.receive(t, "bob-jid", "Bob", "hey")
+		whatsappClientMock.AssertNotCalled(t, "Send", mock.Anything)
+
+		env.awayCommand(t, "off")
+		env.receive(t, "alice-jid", "Alice", "hi")
+		whatsappClientMock.AssertNotCalled(t, "Send", mock.Anything)
+	})
+}
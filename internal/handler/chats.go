@@ -0,0 +1,266 @@
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dstdfx/twbridge/internal/archive"
+	"github.com/dstdfx/twbridge/internal/domain"
+	"go.uber.org/zap"
+)
+
+const (
+	noChatsMsg            = "There are no recent conversations"
+	chatsTitle            = "Recent conversations:"
+	chatsLineFmt          = "\n\n%d. %s%s"
+	chatsUnreadFmt        = ", %d unread"
+	chatsPreviewFmt       = "[%s] %s"
+	chatsTimeLayout       = "Jan 2 15:04"
+	chatsOpenButtonFmt    = "%d. Open"
+	chatsReadButton       = "Mark read"
+	chatsMuteButton       = "Mute"
+	chatsUnmuteButton     = "Unmute"
+	chatsReadAnswer       = "The conversation is marked as read"
+	chatsReadFailedAnswer = "Failed to mark the conversation as read"
+	chatsMutedAnswer      = "The conversation is muted"
+	chatsUnmutedAnswer    = "The conversation is unmuted"
+	maxChats              = 10
+)
+
+// recentChat represents a recent whatsapp conversation of the account.
+type recentChat struct {
+	domain.WhatsappChat
+	account string
+}
+
+// HandleChatsEvent method handles chats event.
+func (eh *EventsHandler) HandleChatsEvent(event *domain.ChatsEvent) error {
+	eh.log.Debug("handle chats event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.String("action", string(event.Action)),
+		zap.String("remote_jid", event.RemoteJid),
+		zap.String("account", event.Account))
+
+	if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+		return err
+	}
+
+	if event.CallbackID == "" {
+		msg := eh.chatsOverview()
+		if _, err := eh.telegramClient.SendText(&msg); err != nil {
+			return fmt.Errorf("failed to send message to telegram: %w", err)
+		}
+
+		return nil
+	}
+
+	answer, err := eh.applyChatsAction(event)
+	if err != nil {
+		return err
+	}
+
+	// Buttons belong to the overview, it's updated in place
+	if event.MessageID != 0 {
+		overview := eh.chatsOverview()
+		err := eh.telegramClient.EditMessage(&domain.TelegramEditMessage{
+			ChatID:    eh.chatID,
+			MessageID: event.MessageID,
+			Text:      overview.Text,
+			Buttons:   overview.Buttons,
+		})
+		if err != nil {
+			eh.log.Error("failed to update conversations overview", zap.Error(err))
+		}
+	}
+
+	callbackAnswer := &domain.TelegramCallbackAnswer{
+		CallbackID: event.CallbackID,
+		Text:       answer,
+	}
+	if err := eh.telegramClient.AnswerCallback(callbackAnswer); err != nil {
+		return fmt.Errorf("failed to answer callback query: %w", err)
+	}
+
+	return nil
+}
+
+// applyChatsAction applies the action of the overview to the conversation and returns
+// an answer to the callback query.
+func (eh *EventsHandler) applyChatsAction(event *domain.ChatsEvent) (string, error) {
+	switch event.Action {
+	case domain.ChatsActionRead:
+		whatsappClient, ok := eh.whatsappClient(event.Account)
+		if !ok {
+			return fmt.Sprintf(chatNotLoggedInFmt, loginCommand(event.Account)), nil
+		}
+
+		if err := whatsappClient.MarkRead(event.RemoteJid); err != nil {
+			eh.log.Error("failed to mark conversation as read",
+				zap.String("remote_jid", event.RemoteJid),
+				zap.String("account", event.Account),
+				zap.Error(err))
+
+			return chatsReadFailedAnswer, nil
+		}
+
+		return chatsReadAnswer, nil
+	case domain.ChatsActionMute, domain.ChatsActionUnmute:
+		if eh.settings == nil {
+			return settingsUnsupportedMsg, nil
+		}
+
+		mode, answer := domain.ContactModeMute, chatsMutedAnswer
+		if event.Action == domain.ChatsActionUnmute {
+			mode, answer = domain.ContactModeNormal, chatsUnmutedAnswer
+		}
+		if err := eh.settings.SetMode(eh.chatID, event.Account, event.RemoteJid, mode); err != nil {
+			return "", fmt.Errorf("failed to update settings: %w", err)
+		}
+
+		return answer, nil
+	default:
+		return "", nil
+	}
+}
+
+// chatsOverview returns the list of the recent conversations of the logged in accounts
+// with buttons to open them, mark them as read or mute them.
+func (eh *EventsHandler) chatsOverview() domain.TelegramTextMessage {
+	accounts := eh.loggedInAccounts()
+	if len(accounts) == 0 {
+		return domain.TelegramTextMessage{
+			ChatID: eh.chatID,
+			Text:   fmt.Sprintf(chatNotLoggedInFmt, loginCommand(domain.DefaultWhatsappAccount)),
+		}
+	}
+
+	chats := eh.recentChats(accounts)
+	if len(chats) == 0 {
+		return domain.TelegramTextMessage{ChatID: eh.chatID, Text: noChatsMsg}
+	}
+
+	loc, _ := eh.chatTimeZone()
+	var b strings.Builder
+	b.WriteString(chatsTitle)
+	buttons := make([][]domain.TelegramButton, 0, len(chats))
+	for i, chat := range chats {
+		name := chat.Name
+		if name == "" {
+			name = eh.contactName(chat.account, chat.Jid)
+		}
+
+		unread := ""
+		if chat.Unread > 0 {
+			unread = fmt.Sprintf(chatsUnreadFmt, chat.Unread)
+		}
+		fmt.Fprintf(&b, chatsLineFmt, i+1, name+domain.AccountTag(chat.account), unread)
+		preview := eh.chatPreview(&chat)
+		if !chat.LastMessageAt.IsZero() {
+			preview = fmt.Sprintf(chatsPreviewFmt, chat.LastMessageAt.In(loc).Format(chatsTimeLayout), preview)
+		}
+		if preview = strings.TrimSpace(preview); preview != "" {
+			b.WriteString("\n" + preview)
+		}
+
+		buttons = append(buttons, eh.chatButtons(i+1, &chat))
+	}
+
+	return domain.TelegramTextMessage{
+		ChatID:  eh.chatID,
+		Text:    strings.TrimSpace(b.String()),
+		Buttons: buttons,
+	}
+}
+
+// recentChats returns the most recent conversations of the accounts.
+func (eh *EventsHandler) recentChats(accounts []string) []recentChat {
+	chats := make([]recentChat, 0)
+	for _, account := range accounts {
+		whatsappClient, ok := eh.whatsappClient(account)
+		if !ok {
+			continue
+		}
+
+		accountChats, err := whatsappClient.RecentChats(maxChats)
+		if err != nil {
+			eh.log.Error("failed to load recent whatsapp chats", zap.String("account", account), zap.Error(err))
+
+			continue
+		}
+		for _, chat := range accountChats {
+			chats = append(chats, recentChat{WhatsappChat: chat, account: account})
+		}
+	}
+
+	sort.SliceStable(chats, func(i, j int) bool {
+		return chats[i].LastMessageAt.After(chats[j].LastMessageAt)
+	})
+	if len(chats) > maxChats {
+		chats = chats[:maxChats]
+	}
+
+	return chats
+}
+
+// chatPreview returns a preview of the last message of the conversation, the last archived
+// message is used if whatsapp doesn't provide it.
+func (eh *EventsHandler) chatPreview(chat *recentChat) string {
+	if chat.Preview != "" {
+		return archivedMessagePreview(&domain.ArchivedMessage{Text: chat.Preview})
+	}
+	if eh.archive == nil {
+		return ""
+	}
+
+	found := eh.archive.Search(&archive.Query{
+		ChatID: eh.chatID,
+		Match: func(msg *domain.ArchivedMessage) bool {
+			return msg.Account == chat.account && msg.RemoteJid == chat.Jid
+		},
+		Limit: 1,
+	})
+	if len(found) == 0 {
+		return ""
+	}
+
+	return archivedMessagePreview(&found[0])
+}
+
+// chatButtons returns buttons of the conversation of the overview,
+// buttons with too long callback data are not sent.
+func (eh *EventsHandler) chatButtons(n int, chat *recentChat) []domain.TelegramButton {
+	ref := domain.NewConversationRef(chat.account, chat.Jid)
+	candidates := []domain.TelegramButton{{
+		Text:         fmt.Sprintf(chatsOpenButtonFmt, n),
+		CallbackData: domain.NewCallbackData(domain.ChatCallbackAction, ref),
+	}}
+	if chat.Unread > 0 {
+		candidates = append(candidates, chatsActionButton(chatsReadButton, domain.ChatsActionRead, ref))
+	}
+	if eh.settings != nil {
+		if eh.contactMode(chat.account, chat.Jid) == domain.ContactModeMute {
+			candidates = append(candidates, chatsActionButton(chatsUnmuteButton, domain.ChatsActionUnmute, ref))
+		} else {
+			candidates = append(candidates, chatsActionButton(chatsMuteButton, domain.ChatsActionMute, ref))
+		}
+	}
+
+	buttons := make([]domain.TelegramButton, 0, len(candidates))
+	for _, button := range candidates {
+		if len(button.CallbackData) <= maxCallbackDataLength {
+			buttons = append(buttons, button)
+		}
+	}
+
+	return buttons
+}
+
+// chatsActionButton returns a button that applies the action of the overview to the conversation.
+func chatsActionButton(text string, action domain.ChatsAction, ref string) domain.TelegramButton {
+	return domain.TelegramButton{
+		Text:         text,
+		CallbackData: domain.NewCallbackData(domain.ChatsCallbackAction, domain.NewCallbackData(string(action), ref)),
+	}
+}
@@ -0,0 +1,114 @@
+package handler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// chats handles the chats command or the action of the overview.
+func (env *testEnv) chats(t *testing.T, event *domain.ChatsEvent) {
+	t.Helper()
+
+	event.ChatID = testChatID
+	event.FromUser = testUserName
+	require.NoError(t, env.eventsHandler.HandleChatsEvent(event))
+}
+
+func TestEventsHandlerChats(t *testing.T) {
+	t.Run("chats overview", func(t *testing.T) {
+		env := newTestEnv(t)
+		require.NoError(t, env.quietHours.Set(domain.QuietHours{ChatID: testChatID, TimeZone: "UTC"}))
+
+		env.chats(t, &domain.ChatsEvent{})
+		assert.Equal(t, "You're not logged in to WhatsApp, type /login first", env.lastText(t))
+
+		whatsappClientMock := newContactsClient()
+		whatsappClientMock.On("RecentChats", 10).Return([]domain.WhatsappChat{
+			{Jid: "alice-jid", LastMessageAt: testHistoryTime, Unread: 2},
+			{Jid: "bob-jid", Name: "Bob", LastMessageAt: testHistoryTime.Add(-time.Hour), Preview: "see  you\ntomorrow"},
+		}, nil).Once()
+		env.login(t, whatsappClientMock)
+		env.receive(t, "alice-jid", "Alice", "lunch?")
+
+		env.chats(t, &domain.ChatsEvent{})
+		overview := env.telegramClient.TextMessages()
+		msg := overview[len(overview)-1]
+		assert.Equal(t, "Recent conversations:\n\n"+
+			"1. Alice, 2 unread\n[Mar 31 10:00] lunch?\n\n"+
+			"2. Bob\n[Mar 31 09:00] see you tomorrow", msg.Text)
+		require.Len(t, msg.Buttons, 2)
+		assert.Equal(t, []domain.TelegramButton{
+			{Text: "1. Open", CallbackData: "chat:default/alice-jid"},
+			{Text: "Mark read", CallbackData: "chats:read:default/alice-jid"},
+			{Text: "Mute", CallbackData: "chats:mute:default/alice-jid"},
+		}, msg.Buttons[0])
+		assert.Len(t, msg.Buttons[1], 2)
+	})
+
+	t.Run("chats actions", func(t *testing.T) {
+		env := newTestEnv(t)
+		whatsappClientMock := newContactsClient()
+		whatsappClientMock.On("RecentChats", 10).Return([]domain.WhatsappChat{
+			{Jid: "alice-jid", Name: "Alice"},
+		}, nil)
+		whatsappClientMock.On("MarkRead", "alice-jid").Return(nil).Once()
+		env.login(t, whatsappClientMock)
+
+		env.chats(t, &domain.ChatsEvent{
+			Action:     domain.ChatsActionRead,
+			Account:    testAccount,
+			RemoteJid:  "alice-jid",
+			CallbackID: "read-callback",
+			MessageID:  42,
+		})
+		whatsappClientMock.AssertCalled(t, "MarkRead", "alice-jid")
+		answers := env.telegramClient.CallbackAnswers()
+		require.Len(t, answers, 1)
+		assert.Equal(t, "The conversation is marked as read", answers[0].Text)
+
+		// The overview is updated in place
+		edits := env.telegramClient.Edits()
+		require.Len(t, edits, 1)
+		assert.Equal(t, 42, edits[0].MessageID)
+		assert.Equal(t, "Recent conversations:\n\n1. Alice", edits[0].Text)
+
+		env.chats(t, &domain.ChatsEvent{
+			Action:     domain.ChatsActionMute,
+			Account:    testAccount,
+			RemoteJid:  "alice-jid",
+			CallbackID: "mute-callback",
+			MessageID:  42,
+		})
+		assert.Equal(t, domain.ContactModeMute, env.settings.Mode(testChatID, testAccount, "alice-jid"))
+		edits = env.telegramClient.Edits()
+		require.Len(t, edits, 2)
+		assert.Equal(t, "Unmute", edits[1].Buttons[0][1].Text)
+
+		env.chats(t, &domain.ChatsEvent{
+			Action:     domain.ChatsActionUnmute,
+			Account:    testAccount,
+			RemoteJid:  "alice-jid",
+			CallbackID: "unmute-callback",
+			MessageID:  42,
+		})
+		assert.Equal(t, domain.ContactModeNormal, env.settings.Mode(testChatID, testAccount, "alice-jid"))
+		answers = env.telegramClient.CallbackAnswers()
+		require.Len(t, answers, 3)
+		assert.Equal(t, "The conversation is unmuted", answers[2].Text)
+	})
+
+	t.Run("no recent chats", func(t *testing.T) {
+		env := newTestEnv(t)
+		whatsappClientMock := newContactsClient()
+		whatsappClientMock.On("RecentChats", mock.Anything).Return([]domain.WhatsappChat{}, nil)
+		env.login(t, whatsappClientMock)
+
+		env.chats(t, &domain.ChatsEvent{})
+		assert.Equal(t, "There are no recent conversations", env.lastText(t))
+	})
+}
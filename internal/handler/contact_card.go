@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/vcard"
+	"go.uber.org/zap"
+)
+
+// HandleContactCardEvent method handles contact card event. The card is bridged like a text
+// message with the phone numbers, so it's archived and can be replied to, and the contact
+// itself is posted as a reply to it with the full vCard attached.
+func (eh *EventsHandler) HandleContactCardEvent(event *domain.ContactCardEvent) error {
+	eh.log.Debug("handle contact card event",
+		zap.String("remote_jid", event.WhatsappRemoteJid),
+		zap.String("account", event.Account))
+
+	posted, err := eh.handleIncomingMessage(&domain.TextMessageEvent{
+		ChatID:             event.ChatID,
+		WhatsappRemoteJid:  event.WhatsappRemoteJid,
+		WhatsappSenderName: event.WhatsappSenderName,
+		WhatsappSenderJid:  event.WhatsappSenderJid,
+		WhatsappMessageID:  event.WhatsappMessageID,
+		Text:               domain.ContactCardText(event.ContactCard),
+		Account:            event.Account,
+	}, domain.MessageTypeContact, nil)
+	// Cards collected to the digest or dropped are left as texts
+	if err != nil || posted.messageID == 0 {
+		return err
+	}
+
+	contact, ok := telegramContact(event.ContactCard)
+	if !ok {
+		eh.log.Debug("contact card has no phone number")
+
+		return nil
+	}
+	contact.ChatID = posted.chatID
+	contact.DisableNotification = true
+	contact.ReplyToMessageID = posted.messageID
+
+	if _, err := eh.telegramClient.SendContact(contact); err != nil {
+		return fmt.Errorf("failed to send contact to telegram: %w", err)
+	}
+
+	return nil
+}
+
+// telegramContact returns telegram contact of the card, false is returned if the card
+// can't be shared as a contact, e.g. it has no phone number.
+func telegramContact(contactCard domain.WhatsappContactCard) (*domain.TelegramContactMessage, bool) {
+	card, err := vcard.Decode(contactCard.Vcard)
+	if err != nil || len(card.Phones) == 0 {
+		return nil, false
+	}
+
+	contact := &domain.TelegramContactMessage{
+		PhoneNumber: card.Phones[0].Number,
+		FirstName:   card.FirstName,
+		LastName:    card.LastName,
+		Vcard:       contactCard.Vcard,
+	}
+	// Telegram requires the first name, the whole name is used if the card has no parts of it
+	if contact.FirstName == "" {
+		contact.FirstName = card.Name()
+		contact.LastName = ""
+	}
+	if contact.FirstName == "" {
+		contact.FirstName = contactCard.DisplayName
+	}
+	if contact.FirstName == "" {
+		contact.FirstName = contact.PhoneNumber
+	}
+
+	return contact, true
+}
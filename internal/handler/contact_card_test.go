@@ -0,0 +1,114 @@
+package handler_test
+
+import (
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testContactCard is a contact card shared in the tests.
+var testContactCard = domain.WhatsappContactCard{
+	DisplayName: "Bob",
+	Vcard: "BEGIN:VCARD\nVERSION:3.0\nN:Smith;Bob;;;\nFN:Bob Smith\n" +
+		"item1.TEL;waid=15551234567:+1 555-123-4567\nEND:VCARD",
+}
+
+// contactCard handles the contact card shared by Alice.
+func (env *testEnv) contactCard(t *testing.T, contactCard domain.WhatsappContactCard) {
+	t.Helper()
+
+	require.NoError(t, env.eventsHandler.HandleContactCardEvent(&domain.ContactCardEvent{
+		ChatID:             testChatID,
+		WhatsappRemoteJid:  "alice-jid",
+		WhatsappSenderName: "Alice",
+		WhatsappMessageID:  "alice-contact-id",
+		ContactCard:        contactCard,
+		Account:            testAccount,
+	}))
+}
+
+func TestEventsHandlerContactCard(t *testing.T) {
+	t.Run("contact card", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+
+		env.contactCard(t, testContactCard)
+		assert.Equal(t, "From: Alice [jid: alice-jid] \n= = = = = = = = = = = =\nMessage: "+
+			"👤 Contact: Bob, +1 555-123-4567", env.lastText(t))
+
+		// The contact replies to the message, so it's clear who has shared it
+		assert.Equal(t, []domain.TelegramContactMessage{{
+			ChatID:              testChatID,
+			PhoneNumber:         "+1 555-123-4567",
+			FirstName:           "Bob",
+			LastName:            "Smith",
+			Vcard:               testContactCard.Vcard,
+			DisableNotification: true,
+			ReplyToMessageID:    len(env.telegramClient.Texts()),
+		}}, env.telegramClient.Contacts())
+
+		archived := env.lastArchived(t)
+		assert.Equal(t, "alice-contact-id", archived.WhatsappMessageID)
+		assert.Contains(t, archived.Text, "👤 Contact: Bob, +1 555-123-4567")
+	})
+
+	t.Run("contact card with formatted name only", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+
+		env.contactCard(t, domain.WhatsappContactCard{
+			Vcard: "BEGIN:VCARD\nFN:Bob Smith\nTEL:+1 555-123-4567\nEND:VCARD",
+		})
+		assert.Contains(t, env.lastText(t), "👤 Contact: Bob Smith, +1 555-123-4567")
+
+		contacts := env.telegramClient.Contacts()
+		require.Len(t, contacts, 1)
+		assert.Equal(t, "Bob Smith", contacts[0].FirstName)
+		assert.Empty(t, contacts[0].LastName)
+	})
+
+	t.Run("contact card without phone number", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+
+		env.contactCard(t, domain.WhatsappContactCard{DisplayName: "Bob", Vcard: "BEGIN:VCARD\nFN:Bob\nEND:VCARD"})
+		assert.Contains(t, env.lastText(t), "👤 Contact: Bob")
+		assert.Empty(t, env.telegramClient.Contacts())
+	})
+
+	t.Run("contact card of muted contact", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+		require.NoError(t, env.settings.SetMode(testChatID, testAccount, "alice-jid", domain.ContactModeMute))
+
+		env.contactCard(t, testContactCard)
+		assert.Empty(t, env.telegramClient.Contacts())
+		assert.Contains(t, env.lastArchived(t).Text, "👤 Contact: Bob, +1 555-123-4567")
+	})
+
+	t.Run("contact card reply", func(t *testing.T) {
+		env := newTestEnv(t)
+		whatsappClientMock := newContactsClient()
+		env.login(t, whatsappClientMock)
+
+		require.NoError(t, env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+			ChatID:      testChatID,
+			FromUser:    testUserName,
+			RemoteJid:   "alice-jid",
+			Account:     testAccount,
+			MessageID:   replyMessageID,
+			ContactCard: &testContactCard,
+		}))
+
+		sent := env.lastArchived(t)
+		assert.Equal(t, domain.MessageDirectionOut, sent.Direction)
+		assert.Equal(t, "👤 Contact: Bob, +1 555-123-4567", sent.Text)
+		whatsappClientMock.AssertCalled(t, "Send", &domain.WhatsappContactCardMessage{
+			ID:          sent.WhatsappMessageID,
+			RemoteJid:   "alice-jid",
+			ContactCard: testContactCard,
+		})
+	})
+}
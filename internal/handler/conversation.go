@@ -0,0 +1,430 @@
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"go.uber.org/zap"
+)
+
+const (
+	conversationsUnsupportedMsg = "Active conversations are not supported"
+	chatUsageMsg                = "Usage: /chat <name, phone number or jid>, or reply to a message with /chat"
+	activeConversationFmt       = "Active conversation: %s [jid: %s]%s\n" +
+		"Plain messages are sent to this contact, type /close to stop"
+	closedConversationFmt  = "The conversation with %s [jid: %s]%s is closed"
+	noActiveConversation   = "There is no active conversation"
+	noContactsFmt          = "No contacts match %q"
+	severalContactsFmt     = "Several contacts match %q, choose one:"
+	contactChoiceFmt       = "%s [jid: %s]%s\nReply to this message with /chat to choose it"
+	chatNotLoggedInFmt     = "You're not logged in to WhatsApp, type %s first"
+	selectedContactAnswer  = "The conversation is selected"
+	closedChatAnswer       = "The conversation is closed"
+	maxContactButtons      = 10
+	minPhoneNumberDigits   = 7
+	whatsappUserJidDomain  = "@s.whatsapp.net"
+	phoneNumberPunctuation = "+-() "
+)
+
+// contactMatch represents a whatsapp contact found by the query.
+type contactMatch struct {
+	account string
+	jid     string
+	name    string
+}
+
+// HandleChatEvent method handles chat event.
+func (eh *EventsHandler) HandleChatEvent(event *domain.ChatEvent) error {
+	eh.log.Debug("handle chat event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.String("contact", event.Contact),
+		zap.String("remote_jid", event.RemoteJid),
+		zap.String("account", event.Account))
+
+	if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+		return err
+	}
+
+	if event.CallbackID != "" {
+		callbackAnswer := &domain.TelegramCallbackAnswer{
+			CallbackID: event.CallbackID,
+			Text:       selectedContactAnswer,
+		}
+		if err := eh.telegramClient.AnswerCallback(callbackAnswer); err != nil {
+			return fmt.Errorf("failed to answer callback query: %w", err)
+		}
+	}
+
+	msg, err := eh.applyChatCommand(event)
+	if err != nil || msg == "" {
+		return err
+	}
+
+	if err := eh.notifyTelegram(msg); err != nil {
+		return fmt.Errorf("failed to notify telegram: %w", err)
+	}
+
+	return nil
+}
+
+// HandleCloseChatEvent method handles close chat event.
+func (eh *EventsHandler) HandleCloseChatEvent(event *domain.CloseChatEvent) error {
+	eh.log.Debug("handle close chat event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID))
+
+	if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+		return err
+	}
+
+	var (
+		conversation domain.ActiveConversation
+		ok           bool
+	)
+	if eh.conversations != nil {
+		conversation, ok = eh.conversations.Get(eh.chatID)
+	}
+
+	answer := noActiveConversation
+	if ok {
+		if err := eh.conversations.Delete(eh.chatID); err != nil {
+			return fmt.Errorf("failed to close conversation: %w", err)
+		}
+
+		answer = fmt.Sprintf(closedConversationFmt,
+			conversation.Name,
+			conversation.RemoteJid,
+			domain.AccountTag(conversation.Account))
+		eh.closeConversationStatus(&conversation, answer)
+		eh.sendComposing(conversation.Account, conversation.RemoteJid, false)
+	}
+
+	if event.CallbackID == "" {
+		if err := eh.notifyTelegram(answer); err != nil {
+			return fmt.Errorf("failed to notify telegram: %w", err)
+		}
+
+		return nil
+	}
+
+	if ok {
+		answer = closedChatAnswer
+	}
+	callbackAnswer := &domain.TelegramCallbackAnswer{
+		CallbackID: event.CallbackID,
+		Text:       answer,
+	}
+	if err := eh.telegramClient.AnswerCallback(callbackAnswer); err != nil {
+		return fmt.Errorf("failed to answer callback query: %w", err)
+	}
+
+	return nil
+}
+
+// applyChatCommand selects the conversation and returns a message to reply with,
+// empty message is returned if the reply is already sent.
+func (eh *EventsHandler) applyChatCommand(event *domain.ChatEvent) (string, error) {
+	if eh.conversations == nil {
+		return conversationsUnsupportedMsg, nil
+	}
+
+	if event.RemoteJid != "" {
+		return "", eh.activateConversation(event.Account,
+			event.RemoteJid,
+			eh.contactName(event.Account, event.RemoteJid))
+	}
+
+	if event.Contact == "" {
+		conversation, ok := eh.conversations.Get(eh.chatID)
+		if !ok {
+			return chatUsageMsg, nil
+		}
+
+		return fmt.Sprintf(activeConversationFmt,
+			conversation.Name,
+			conversation.RemoteJid,
+			domain.AccountTag(conversation.Account)), nil
+	}
+
+	accounts := eh.loggedInAccounts()
+	if len(accounts) == 0 {
+		return fmt.Sprintf(chatNotLoggedInFmt, loginCommand(domain.DefaultWhatsappAccount)), nil
+	}
+
+	matches := eh.findContacts(accounts, event.Contact)
+	switch len(matches) {
+	case 0:
+		return fmt.Sprintf(noContactsFmt, event.Contact), nil
+	case 1:
+		return "", eh.activateConversation(matches[0].account, matches[0].jid, matches[0].name)
+	default:
+		return "", eh.chooseContact(event.Contact, matches)
+	}
+}
+
+// activateConversation makes the conversation active and shows it
+// in the pinned status message.
+func (eh *EventsHandler) activateConversation(account, remoteJid, name string) error {
+	status := &domain.TelegramTextMessage{
+		ChatID:  eh.chatID,
+		Text:    fmt.Sprintf(activeConversationFmt, name, remoteJid, domain.AccountTag(account)),
+		Buttons: closeChatButtons(),
+	}
+
+	// The status message of the previous conversation is reused, so it stays pinned
+	statusMessageID := 0
+	previous, ok := eh.conversations.Get(eh.chatID)
+	if ok && (previous.Account != account || previous.RemoteJid != remoteJid) {
+		eh.sendComposing(previous.Account, previous.RemoteJid, false)
+	}
+	if ok && previous.StatusMessageID != 0 {
+		err := eh.telegramClient.EditMessage(&domain.TelegramEditMessage{
+			ChatID:    eh.chatID,
+			MessageID: previous.StatusMessageID,
+			Text:      status.Text,
+			Buttons:   status.Buttons,
+		})
+		if err == nil {
+			statusMessageID = previous.StatusMessageID
+		} else {
+			eh.log.Error("failed to update conversation status", zap.Error(err))
+		}
+	}
+
+	if statusMessageID == 0 {
+		sentID, err := eh.telegramClient.SendText(status)
+		if err != nil {
+			return fmt.Errorf("failed to send message to telegram: %w", err)
+		}
+		statusMessageID = sentID
+
+		if err := eh.telegramClient.PinMessage(eh.chatID, statusMessageID); err != nil {
+			eh.log.Error("failed to pin conversation status", zap.Error(err))
+		}
+	}
+
+	err := eh.conversations.Set(domain.ActiveConversation{
+		ChatID:          eh.chatID,
+		Account:         account,
+		RemoteJid:       remoteJid,
+		Name:            name,
+		StatusMessageID: statusMessageID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save conversation: %w", err)
+	}
+
+	// Telegram doesn't tell the bot when the user is typing, the user is about to write
+	// once the conversation is active, so the contact sees it
+	eh.sendComposing(account, remoteJid, true)
+
+	return nil
+}
+
+// closeConversationStatus replaces the status message of the closed conversation
+// and unpins it.
+func (eh *EventsHandler) closeConversationStatus(conversation *domain.ActiveConversation, text string) {
+	if conversation.StatusMessageID == 0 {
+		return
+	}
+
+	err := eh.telegramClient.EditMessage(&domain.TelegramEditMessage{
+		ChatID:    eh.chatID,
+		MessageID: conversation.StatusMessageID,
+		Text:      text,
+	})
+	if err != nil {
+		eh.log.Error("failed to update conversation status", zap.Error(err))
+	}
+
+	if err := eh.telegramClient.UnpinMessage(eh.chatID, conversation.StatusMessageID); err != nil {
+		eh.log.Error("failed to unpin conversation status", zap.Error(err))
+	}
+}
+
+// chooseContact offers buttons to choose one of the contacts found by the query,
+// contacts whose buttons are too long are chosen by a reply to them instead.
+func (eh *EventsHandler) chooseContact(query string, matches []contactMatch) error {
+	if len(matches) > maxContactButtons {
+		matches = matches[:maxContactButtons]
+	}
+
+	buttons := make([][]domain.TelegramButton, 0, len(matches))
+	var withoutButtons []contactMatch
+	for _, match := range matches {
+		data := domain.NewCallbackData(domain.ChatCallbackAction, domain.NewConversationRef(match.account, match.jid))
+		if len(data) > maxCallbackDataLength {
+			withoutButtons = append(withoutButtons, match)
+
+			continue
+		}
+
+		buttons = append(buttons, []domain.TelegramButton{{
+			Text:         match.name + domain.AccountTag(match.account),
+			CallbackData: data,
+		}})
+	}
+
+	textMessage := &domain.TelegramTextMessage{
+		ChatID:  eh.chatID,
+		Text:    fmt.Sprintf(severalContactsFmt, query),
+		Buttons: buttons,
+	}
+	if _, err := eh.telegramClient.SendText(textMessage); err != nil {
+		return fmt.Errorf("failed to send message to telegram: %w", err)
+	}
+
+	// Telegram refuses too long callback data, e.g. of groups of long named accounts,
+	// such contacts are posted one by one to be chosen by a reply
+	for _, match := range withoutButtons {
+		choice := fmt.Sprintf(contactChoiceFmt, match.name, match.jid, domain.AccountTag(match.account))
+		if err := eh.notifyTelegram(choice); err != nil {
+			return fmt.Errorf("failed to notify telegram: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// findContacts returns contacts of the logged in accounts that match the query.
+// The query may be a jid, a phone number or a name, contacts with exactly
+// the same name take precedence over the ones that contain it.
+func (eh *EventsHandler) findContacts(accounts []string, query string) []contactMatch {
+	if jid := queryJid(query); jid != "" {
+		matches := make([]contactMatch, 0, len(accounts))
+		for _, account := range accounts {
+			matches = append(matches, contactMatch{
+				account: account,
+				jid:     jid,
+				name:    eh.contactName(account, jid),
+			})
+		}
+
+		return matches
+	}
+
+	var exact, partial []contactMatch
+	for _, account := range accounts {
+		whatsappClient, ok := eh.whatsappClient(account)
+		if !ok {
+			continue
+		}
+
+		for _, contact := range whatsappClient.GetContacts() {
+			match := contactMatch{account: account, jid: contact.Jid, name: contact.Name}
+			switch {
+			case contact.Name == "":
+				continue
+			case strings.EqualFold(contact.Name, query):
+				exact = append(exact, match)
+			case strings.Contains(strings.ToLower(contact.Name), strings.ToLower(query)):
+				partial = append(partial, match)
+			}
+		}
+	}
+
+	matches := exact
+	if len(matches) == 0 {
+		matches = partial
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].name != matches[j].name {
+			return matches[i].name < matches[j].name
+		}
+
+		return matches[i].account < matches[j].account
+	})
+
+	return matches
+}
+
+// contactName returns a name of the whatsapp contact, jid is returned
+// if the contact is unknown.
+func (eh *EventsHandler) contactName(account, remoteJid string) string {
+	if whatsappClient, ok := eh.whatsappClient(account); ok {
+		if contact, ok := whatsappClient.GetContacts()[remoteJid]; ok && contact.Name != "" {
+			return contact.Name
+		}
+	}
+
+	return remoteJid
+}
+
+// loggedInAccounts returns sorted names of the logged in whatsapp accounts.
+func (eh *EventsHandler) loggedInAccounts() []string {
+	eh.mu.RLock()
+	defer eh.mu.RUnlock()
+
+	accounts := make([]string, 0, len(eh.whatsappClients))
+	for account := range eh.whatsappClients {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+
+	return accounts
+}
+
+// resolveActiveConversation sends the plain message to the active conversation,
+// false is returned if there is no active conversation.
+func (eh *EventsHandler) resolveActiveConversation(event *domain.ReplyEvent) bool {
+	if eh.conversations == nil || event.ChatID != eh.chatID {
+		return false
+	}
+
+	conversation, ok := eh.conversations.Get(eh.chatID)
+	if !ok {
+		return false
+	}
+	event.RemoteJid = conversation.RemoteJid
+	event.Account = conversation.Account
+
+	return true
+}
+
+func closeChatButtons() [][]domain.TelegramButton {
+	return [][]domain.TelegramButton{{
+		{
+			Text:         "Close",
+			CallbackData: domain.NewCallbackData(domain.CloseChatCallbackAction, ""),
+		},
+	}}
+}
+
+// queryJid returns a jid of the contact if the query is a jid or a phone number,
+// otherwise - empty string.
+func queryJid(query string) string {
+	switch {
+	case strings.Contains(query, "@"):
+		return query
+	case isPhoneNumber(query):
+		return strings.Map(func(r rune) rune {
+			if strings.ContainsRune(phoneNumberPunctuation, r) {
+				return -1
+			}
+
+			return r
+		}, query) + whatsappUserJidDomain
+	default:
+		return ""
+	}
+}
+
+// isPhoneNumber returns true if the query looks like a phone number.
+func isPhoneNumber(query string) bool {
+	digits := 0
+	for _, r := range query {
+		switch {
+		case unicode.IsDigit(r):
+			digits++
+		case strings.ContainsRune(phoneNumberPunctuation, r):
+		default:
+			return false
+		}
+	}
+
+	return digits >= minPhoneNumberDigits
+}
@@ -0,0 +1,191 @@
+package handler_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/whatsapp/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// chat handles the chat command.
+func (env *testEnv) chat(t *testing.T, event *domain.ChatEvent) {
+	t.Helper()
+
+	event.ChatID = testChatID
+	event.FromUser = testUserName
+	require.NoError(t, env.eventsHandler.HandleChatEvent(event))
+}
+
+// plainMessage handles a message that doesn't reply to anything.
+func (env *testEnv) plainMessage(t *testing.T, text string) {
+	t.Helper()
+
+	require.NoError(t, env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+		ChatID:   testChatID,
+		FromUser: testUserName,
+		Reply:    text,
+	}))
+}
+
+func newContactsClient() *mocks.WhatsappClient {
+	whatsappClientMock := &mocks.WhatsappClient{}
+	whatsappClientMock.On("Send", mock.Anything).Return(nil)
+	whatsappClientMock.On("GetContacts").Return(map[string]domain.WhatsappContact{
+		"alice-jid":     {Jid: "alice-jid", Name: "Alice"},
+		"alice-bob-jid": {Jid: "alice-bob-jid", Name: "Alice Bob"},
+		"bob-jid":       {Jid: "bob-jid", Name: "Bob"},
+		"carol-jid":     {Jid: "carol-jid", Name: "Carol Smith"},
+		"dave-jid":      {Jid: "dave-jid", Name: "Dave Smith"},
+	})
+
+	return whatsappClientMock
+}
+
+func TestEventsHandlerConversation(t *testing.T) {
+	t.Run("chat and close", func(t *testing.T) {
+		env := newTestEnv(t)
+		whatsappClientMock := newContactsClient()
+		env.login(t, whatsappClientMock)
+
+		// Plain messages are ignored without an active conversation
+		env.plainMessage(t, "is anybody there?")
+		whatsappClientMock.AssertNotCalled(t, "Send", mock.Anything)
+
+		// Exact name takes precedence over the partial matches
+		env.chat(t, &domain.ChatEvent{Contact: "alice"})
+		status := env.telegramClient.TextMessages()[len(env.telegramClient.TextMessages())-1]
+		assert.Equal(t, "Active conversation: Alice [jid: alice-jid]\n"+
+			"Plain messages are sent to this contact, type /close to stop", status.Text)
+		require.Len(t, status.Buttons, 1)
+		assert.Equal(t, "Close", status.Buttons[0][0].Text)
+
+		conversation, ok := env.conversations.Get(testChatID)
+		require.True(t, ok)
+		assert.Equal(t, []int{conversation.StatusMessageID}, env.telegramClient.Pinned(testChatID))
+
+		env.plainMessage(t, "hi, Alice")
+		whatsappClientMock.AssertCalled(t, "Send", sentText("alice-jid", "hi, Alice"))
+
+		// Switching the conversation updates the pinned status message
+		env.chat(t, &domain.ChatEvent{Contact: "bob"})
+		edits := env.telegramClient.Edits()
+		require.NotEmpty(t, edits)
+		assert.Equal(t, conversation.StatusMessageID, edits[len(edits)-1].MessageID)
+		assert.Contains(t, edits[len(edits)-1].Text, "Active conversation: Bob [jid: bob-jid]")
+
+		env.chat(t, &domain.ChatEvent{})
+		assert.Contains(t, env.lastText(t), "Active conversation: Bob [jid: bob-jid]")
+
+		require.NoError(t, env.eventsHandler.HandleCloseChatEvent(&domain.CloseChatEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+		}))
+		assert.Equal(t, "The conversation with Bob [jid: bob-jid] is closed", env.lastText(t))
+		assert.Empty(t, env.telegramClient.Pinned(testChatID))
+		_, ok = env.conversations.Get(testChatID)
+		assert.False(t, ok)
+
+		env.plainMessage(t, "hi, Bob")
+		whatsappClientMock.AssertNotCalled(t, "Send", sentText("bob-jid", "hi, Bob"))
+
+		require.NoError(t, env.eventsHandler.HandleCloseChatEvent(&domain.CloseChatEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+		}))
+		assert.Equal(t, "There is no active conversation", env.lastText(t))
+	})
+
+	t.Run("choose one of several contacts", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+
+		env.chat(t, &domain.ChatEvent{Contact: "smith"})
+		sent := env.telegramClient.TextMessages()
+		choice := sent[len(sent)-1]
+		assert.Equal(t, `Several contacts match "smith", choose one:`, choice.Text)
+		require.Len(t, choice.Buttons, 2)
+		assert.Equal(t, "Carol Smith", choice.Buttons[0][0].Text)
+		assert.Equal(t, domain.NewCallbackData(domain.ChatCallbackAction,
+			domain.NewConversationRef(testAccount, "carol-jid")), choice.Buttons[0][0].CallbackData)
+
+		env.chat(t, &domain.ChatEvent{
+			CallbackID: "test-callback-id",
+			RemoteJid:  "dave-jid",
+			Account:    testAccount,
+		})
+		assert.Equal(t, "The conversation is selected", env.telegramClient.CallbackAnswers()[0].Text)
+		assert.Contains(t, env.lastText(t), "Active conversation: Dave Smith [jid: dave-jid]")
+
+		// Close button
+		require.NoError(t, env.eventsHandler.HandleCloseChatEvent(&domain.CloseChatEvent{
+			ChatID:     testChatID,
+			FromUser:   testUserName,
+			CallbackID: "test-callback-id",
+		}))
+		assert.Equal(t, "The conversation is closed", env.telegramClient.CallbackAnswers()[1].Text)
+		edits := env.telegramClient.Edits()
+		assert.Equal(t, "The conversation with Dave Smith [jid: dave-jid] is closed", edits[len(edits)-1].Text)
+	})
+
+	t.Run("choose a contact of the account with a long name", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+		longAccount := strings.Repeat("a", 32)
+		groupJid := "120363025246125888-1648812345@g.us"
+		groupClient := &mocks.WhatsappClient{}
+		groupClient.On("GetContacts").Return(map[string]domain.WhatsappContact{
+			groupJid: {Jid: groupJid, Name: "Carol Smith"},
+		})
+		env.loginAccount(t, longAccount, groupClient)
+
+		env.chat(t, &domain.ChatEvent{Contact: "carol smith"})
+		sent := env.telegramClient.TextMessages()
+		choice := sent[len(sent)-2]
+		assert.Equal(t, `Several contacts match "carol smith", choose one:`, choice.Text)
+		require.Len(t, choice.Buttons, 1)
+		assert.Equal(t, "Carol Smith", choice.Buttons[0][0].Text)
+
+		// The contact without a button is chosen by a reply
+		reply := sent[len(sent)-1]
+		assert.Empty(t, reply.Buttons)
+		assert.Equal(t, groupJid, domain.ExtractMsgJid(reply.Text))
+		assert.Equal(t, longAccount, domain.ExtractMsgAccount(reply.Text))
+		env.chat(t, &domain.ChatEvent{
+			RemoteJid: domain.ExtractMsgJid(reply.Text),
+			Account:   domain.ExtractMsgAccount(reply.Text),
+		})
+		conversation, ok := env.conversations.Get(testChatID)
+		require.True(t, ok)
+		assert.Equal(t, groupJid, conversation.RemoteJid)
+		assert.Equal(t, longAccount, conversation.Account)
+	})
+
+	t.Run("chat by phone number", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+
+		env.chat(t, &domain.ChatEvent{Contact: "+1 (234) 567-890"})
+		conversation, ok := env.conversations.Get(testChatID)
+		require.True(t, ok)
+		assert.Equal(t, "1234567890@s.whatsapp.net", conversation.RemoteJid)
+		assert.Equal(t, "1234567890@s.whatsapp.net", conversation.Name)
+	})
+
+	t.Run("contact is not found", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		env.chat(t, &domain.ChatEvent{})
+		assert.Contains(t, env.lastText(t), "Usage: /chat")
+
+		env.chat(t, &domain.ChatEvent{Contact: "alice"})
+		assert.Equal(t, "You're not logged in to WhatsApp, type /login first", env.lastText(t))
+
+		env.login(t, newContactsClient())
+		env.chat(t, &domain.ChatEvent{Contact: "eve"})
+		assert.Equal(t, `No contacts match "eve"`, env.lastText(t))
+	})
+}
@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"go.uber.org/zap"
+)
+
+const (
+	digestsUnsupportedMsg = "Digests are not supported"
+	emptyDigestMsg        = "The digest is empty"
+	digestTimeLayout      = "Jan 2 15:04"
+	digestUsageMsg        = "Usage: /digest [daily <time> [time zone]|daily off], e.g. /digest daily 20:00"
+	dailyDigestOnFmt      = "The digest is delivered every day at %s (%s)"
+	dailyDigestOffMsg     = "The daily digest is off"
+	invalidDailyDigestFmt = "Invalid time %q, it must look like 20:00"
+	digestDailyArg        = "daily"
+)
+
+const (
+	quietDigestTitle    = "Quiet hours are over"
+	dailyDigestTitle    = "Daily digest"
+	digestSummaryFmt    = "%s, %d new messages in %d conversations"
+	digestContactFmt    = "%s [jid: %s]%s - %d messages"
+	digestMoreFmt       = "... and %d more"
+	digestChatButtonFmt = "Chat with %s"
+)
+
+const (
+	// maxDigestSummaryLines is a maximum number of the latest messages
+	// of a conversation shown in the digest summary.
+	maxDigestSummaryLines = 5
+
+	// maxTelegramMessageLength is a maximum length of a telegram text message.
+	maxTelegramMessageLength = 4096
+)
+
+// HandleDigestEvent method handles digest event.
+func (eh *EventsHandler) HandleDigestEvent(event *domain.DigestEvent) error {
+	eh.log.Debug("handle digest event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.Strings("args", event.Args))
+
+	if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+		return err
+	}
+
+	var msg string
+	switch {
+	case eh.digests == nil:
+		msg = digestsUnsupportedMsg
+	case len(event.Args) > 0 && event.Args[0] == digestDailyArg:
+		var err error
+		if msg, err = eh.applyDailyDigestCommand(event.Args[1:]); err != nil {
+			return err
+		}
+	case len(event.Args) > 0:
+		msg = digestUsageMsg
+	default:
+		entries, err := eh.digests.Take(eh.chatID)
+		if err != nil {
+			return fmt.Errorf("failed to take digest: %w", err)
+		}
+		if len(entries) > 0 {
+			return eh.deliverDigest(entries)
+		}
+		msg = emptyDigestMsg
+	}
+
+	if err := eh.notifyTelegram(msg); err != nil {
+		return fmt.Errorf("failed to notify telegram: %w", err)
+	}
+
+	return nil
+}
+
+// applyDailyDigestCommand applies the daily digest command and returns a message to reply with.
+func (eh *EventsHandler) applyDailyDigestCommand(args []string) (string, error) {
+	if eh.quietHours == nil {
+		return quietUnsupportedMsg, nil
+	}
+
+	qh, _ := eh.quietHours.Get(eh.chatID)
+	switch {
+	case len(args) == 0:
+		if qh.DailyDigestAt == "" {
+			return dailyDigestOffMsg, nil
+		}
+
+		return fmt.Sprintf(dailyDigestOnFmt, qh.DailyDigestAt, timeZoneName(qh.TimeZone)), nil
+	case len(args) == 1 && args[0] == teamOffArg:
+		qh.DailyDigestAt = ""
+		qh.LastDailyDigest = ""
+		if err := eh.quietHours.Set(qh); err != nil {
+			return "", fmt.Errorf("failed to update quiet hours: %w", err)
+		}
+
+		return dailyDigestOffMsg, nil
+	case len(args) <= 2:
+		if _, err := domain.ParseTimeOfDay(args[0]); err != nil {
+			return fmt.Sprintf(invalidDailyDigestFmt, args[0]), nil
+		}
+		if len(args) == 2 {
+			if !isValidTimeZone(args[1]) {
+				return fmt.Sprintf(unknownTimeZoneFmt, args[1]), nil
+			}
+			qh.TimeZone = args[1]
+		}
+		qh.DailyDigestAt = args[0]
+
+		// The digest isn't delivered today if the time has already passed
+		qh.LastDailyDigest = ""
+		if dailyDigestDue(qh, eh.now()) {
+			qh.LastDailyDigest = eh.now().In(timeZoneLocation(qh.TimeZone)).Format(dateLayout)
+		}
+		if err := eh.quietHours.Set(qh); err != nil {
+			return "", fmt.Errorf("failed to update quiet hours: %w", err)
+		}
+
+		return fmt.Sprintf(dailyDigestOnFmt, qh.DailyDigestAt, timeZoneName(qh.TimeZone)), nil
+	default:
+		return digestUsageMsg, nil
+	}
+}
+
+// addToDigest collects the incoming message to the digest of the chat.
+func (eh *EventsHandler) addToDigest(event *domain.TextMessageEvent, quietHours bool) error {
+	err := eh.digests.Add(domain.DigestEntry{
+		ChatID:     eh.chatID,
+		Account:    event.Account,
+		RemoteJid:  event.WhatsappRemoteJid,
+		SenderName: event.WhatsappSenderName,
+		Text:       event.Text,
+		ReceivedAt: eh.now(),
+		QuietHours: quietHours,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add message to digest: %w", err)
+	}
+
+	return nil
+}
+
+// deliverDigest sends one message per conversation of the digest, so it's possible
+// to reply to them as to usual messages.
+func (eh *EventsHandler) deliverDigest(entries []domain.DigestEntry) error {
+	for _, conversation := range groupDigest(entries) {
+		lines := make([]string, 0, len(conversation))
+		for _, entry := range conversation {
+			lines = append(lines, fmt.Sprintf("[%s] %s", entry.ReceivedAt.Format(digestTimeLayout), entry.Text))
+		}
+
+		last := conversation[len(conversation)-1]
+		textMessage := domain.TelegramTextMessage{
+			Text: fmt.Sprintf(domain.TextMessageFmt,
+				last.SenderName,
+				last.RemoteJid,
+				domain.AccountTag(last.Account)+eh.assignedTag(last.Account, last.RemoteJid),
+				strings.Join(lines, "\n")),
+			Buttons: eh.contactButtons(last.Account, last.RemoteJid),
+		}
+		if _, err := eh.deliverConversationMessage(last.Account, last.RemoteJid, last.SenderName, textMessage); err != nil {
+			return fmt.Errorf("failed to deliver digest: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// deliverDigestSummary sends the whole digest of the chat as a single message that
+// summarizes messages per conversation, nothing is sent if the digest is empty.
+func (eh *EventsHandler) deliverDigestSummary(title string, loc *time.Location) error {
+	entries, err := eh.digests.Take(eh.chatID)
+	if err != nil {
+		return fmt.Errorf("failed to take digest: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	conversations := groupDigest(entries)
+	sections := make([]string, 0, len(conversations)+1)
+	sections = append(sections, fmt.Sprintf(digestSummaryFmt, title, len(entries), len(conversations)))
+	buttons := make([][]domain.TelegramButton, 0, len(conversations))
+	for _, conversation := range conversations {
+		last := conversation[len(conversation)-1]
+		lines := []string{fmt.Sprintf(digestContactFmt,
+			last.SenderName,
+			last.RemoteJid,
+			domain.AccountTag(last.Account),
+			len(conversation))}
+		if len(conversation) > maxDigestSummaryLines {
+			lines = append(lines, fmt.Sprintf(digestMoreFmt, len(conversation)-maxDigestSummaryLines))
+			conversation = conversation[len(conversation)-maxDigestSummaryLines:]
+		}
+		for _, entry := range conversation {
+			lines = append(lines, fmt.Sprintf("[%s] %s", entry.ReceivedAt.In(loc).Format(digestTimeLayout), entry.Text))
+		}
+		sections = append(sections, strings.Join(lines, "\n"))
+
+		data := domain.NewCallbackData(domain.ChatCallbackAction, domain.NewConversationRef(last.Account, last.RemoteJid))
+		if eh.conversations != nil && len(buttons) < maxContactButtons && len(data) <= maxCallbackDataLength {
+			name := last.SenderName
+			if name == "" {
+				name = last.RemoteJid
+			}
+			buttons = append(buttons, []domain.TelegramButton{{
+				Text:         fmt.Sprintf(digestChatButtonFmt, name),
+				CallbackData: data,
+			}})
+		}
+	}
+
+	text := strings.Join(sections, "\n\n")
+	if runes := []rune(text); len(runes) > maxTelegramMessageLength {
+		text = string(runes[:maxTelegramMessageLength])
+	}
+
+	_, err = eh.sendTopicMessage(0, domain.TelegramTextMessage{
+		Text:    text,
+		Buttons: buttons,
+	})
+
+	return err
+}
+
+// groupDigest groups messages of the digest by conversations in order of their first message.
+func groupDigest(entries []domain.DigestEntry) [][]domain.DigestEntry {
+	type conversationKey struct {
+		account   string
+		remoteJid string
+	}
+
+	var (
+		order         []conversationKey
+		conversations = make(map[conversationKey][]domain.DigestEntry)
+	)
+	for _, entry := range entries {
+		key := conversationKey{account: entry.Account, remoteJid: entry.RemoteJid}
+		if _, ok := conversations[key]; !ok {
+			order = append(order, key)
+		}
+		conversations[key] = append(conversations[key], entry)
+	}
+
+	grouped := make([][]domain.DigestEntry, 0, len(order))
+	for _, key := range order {
+		grouped = append(grouped, conversations[key])
+	}
+
+	return grouped
+}
@@ -0,0 +1,48 @@
+package handler_test
+
+import (
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// digestCommand handles the digest command.
+func (env *testEnv) digestCommand(t *testing.T, args ...string) {
+	t.Helper()
+
+	require.NoError(t, env.eventsHandler.HandleDigestEvent(&domain.DigestEvent{
+		ChatID:   testChatID,
+		FromUser: testUserName,
+		Args:     args,
+	}))
+}
+
+func TestEventsHandlerDigest(t *testing.T) {
+	t.Run("digest only contacts", func(t *testing.T) {
+		env := newTestEnv(t)
+		require.NoError(t, env.settings.SetMode(testChatID, testAccount, "alice-jid", domain.ContactModeDigest))
+		require.NoError(t, env.settings.SetMode(testChatID, testAccount, "bob-jid", domain.ContactModeDigest))
+
+		env.digestCommand(t)
+		assert.Equal(t, "The digest is empty", env.lastText(t))
+
+		env.receive(t, "alice-jid", "Alice", "hi")
+		env.receive(t, "bob-jid", "Bob", "hey")
+		env.receive(t, "alice-jid", "Alice", "are you there?")
+		assert.Len(t, env.telegramClient.TextMessages(), 1)
+		assert.Equal(t, 3, env.digests.Len(testChatID))
+
+		// Messages are delivered one per conversation, so it's possible to reply to them
+		env.digestCommand(t)
+		sent := env.telegramClient.TextMessages()
+		require.Len(t, sent, 3)
+		assert.Equal(t, "alice-jid", domain.ExtractMsgJid(sent[1].Text))
+		assert.Contains(t, sent[1].Text, "] hi\n[")
+		assert.Contains(t, sent[1].Text, "] are you there?")
+		assert.Equal(t, "bob-jid", domain.ExtractMsgJid(sent[2].Text))
+		assert.Contains(t, sent[2].Text, "] hey")
+		assert.Zero(t, env.digests.Len(testChatID))
+	})
+}
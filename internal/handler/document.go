@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"fmt"
+	"mime"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultMaxUploadSize is the largest file in bytes the bot can upload to telegram.
+	defaultMaxUploadSize = 50 << 20
+
+	// maxCaptionLength is the longest caption of telegram media in characters.
+	maxCaptionLength = 1024
+
+	defaultDocumentName = "document"
+	documentTimeLayout  = "Jan 2 15:04"
+
+	documentLinkFmt          = "The file is too large for Telegram, download it before %s:\n%s"
+	documentTooLargeMsg      = "The file is too large for Telegram, open it in WhatsApp"
+	documentNotDownloadedMsg = "The file couldn't be downloaded from WhatsApp, open it in WhatsApp"
+	documentDigestLinkFmt    = "Download the file before %s:\n%s"
+	documentNotKeptMsg       = "The file isn't kept until the digest, open it in WhatsApp"
+)
+
+// HandleDocumentEvent method handles document event. The document is bridged like a text
+// message with its name, type and caption, so it's archived and can be replied to, and the
+// file itself is uploaded as a reply to it. Files too large for telegram are kept locally
+// and a time-limited link to download them is posted instead.
+func (eh *EventsHandler) HandleDocumentEvent(event *domain.DocumentEvent) error {
+	eh.log.Debug("handle document event",
+		zap.String("remote_jid", event.WhatsappRemoteJid),
+		zap.String("file_name", event.Document.FileName),
+		zap.Int64("size", event.Document.Size),
+		zap.String("account", event.Account))
+
+	// Files too large for telegram are kept before the message is delivered,
+	// so the archived message refers to the local copy
+	document := event.Document
+	var stored *domain.StoredFile
+	if document.Data != nil && len(document.Data) > eh.maxUploadSize {
+		stored = eh.storeDocument(document)
+	}
+	media := domain.MediaReference{
+		Type:     string(domain.WhatsappMediaDocument),
+		FileName: documentFileName(document),
+		MimeType: document.MimeType,
+	}
+	if stored != nil {
+		media.Path = stored.Path
+	}
+
+	posted, err := eh.handleIncomingMessage(&domain.TextMessageEvent{
+		ChatID:             event.ChatID,
+		WhatsappRemoteJid:  event.WhatsappRemoteJid,
+		WhatsappSenderName: event.WhatsappSenderName,
+		WhatsappSenderJid:  event.WhatsappSenderJid,
+		WhatsappMessageID:  event.WhatsappMessageID,
+		Text:               domain.DocumentText(event.Document),
+		Account:            event.Account,
+	}, domain.MessageTypeDocument, []domain.MediaReference{media})
+	switch {
+	case err != nil:
+		return err
+	case posted.collected:
+		return eh.collectDocument(event, stored, posted.quietHours)
+	case posted.messageID == 0:
+		// Dropped documents are left as texts
+		return nil
+	}
+
+	switch {
+	case document.Data == nil:
+		return eh.sendDocumentNote(posted, documentNotDownloadedMsg)
+	case len(document.Data) > eh.maxUploadSize:
+		return eh.sendDocumentNote(posted, eh.documentLinkNote(stored))
+	}
+
+	caption := document.Caption
+	if runes := []rune(caption); len(runes) > maxCaptionLength {
+		caption = string(runes[:maxCaptionLength])
+	}
+
+	if _, err := eh.telegramClient.SendDocument(&domain.TelegramDocumentMessage{
+		ChatID: posted.chatID,
+		Document: domain.TelegramFile{
+			Name:  documentFileName(document),
+			Bytes: document.Data,
+		},
+		MimeType:            document.MimeType,
+		Caption:             caption,
+		DisableNotification: true,
+		ReplyToMessageID:    posted.messageID,
+	}); err != nil {
+		return fmt.Errorf("failed to send document to telegram: %w", err)
+	}
+
+	return nil
+}
+
+// storeDocument keeps the document in the local file storage, nil is returned
+// if the document can't be kept.
+func (eh *EventsHandler) storeDocument(document domain.WhatsappDocument) *domain.StoredFile {
+	if eh.files == nil {
+		return nil
+	}
+
+	file, err := eh.files.Put(documentFileName(document), document.MimeType, document.Data)
+	if err != nil {
+		eh.log.Error("failed to store document", zap.Error(err))
+
+		return nil
+	}
+
+	return &file
+}
+
+// documentLinkNote returns a note with the link to download the stored document.
+func (eh *EventsHandler) documentLinkNote(file *domain.StoredFile) string {
+	if file == nil {
+		return documentTooLargeMsg
+	}
+
+	loc, _ := eh.chatTimeZone()
+
+	return fmt.Sprintf(documentLinkFmt, file.ExpiresAt.In(loc).Format(documentTimeLayout), eh.files.Link(*file))
+}
+
+// collectDocument adds the link to download the document to the digest the document
+// is collected to, the file is kept until the link expires.
+func (eh *EventsHandler) collectDocument(event *domain.DocumentEvent, stored *domain.StoredFile,
+	quietHours bool) error {
+	if event.Document.Data == nil {
+		return nil
+	}
+	if stored == nil {
+		stored = eh.storeDocument(event.Document)
+		if stored != nil {
+			eh.archiveDocumentPath(event, stored.Path)
+		}
+	}
+
+	note := documentNotKeptMsg
+	if stored != nil {
+		loc, _ := eh.chatTimeZone()
+		note = fmt.Sprintf(documentDigestLinkFmt,
+			stored.ExpiresAt.In(loc).Format(documentTimeLayout),
+			eh.files.Link(*stored))
+	}
+
+	return eh.addToDigest(&domain.TextMessageEvent{
+		ChatID:             event.ChatID,
+		WhatsappRemoteJid:  event.WhatsappRemoteJid,
+		WhatsappSenderName: event.WhatsappSenderName,
+		Text:               note,
+		Account:            event.Account,
+	}, quietHours)
+}
+
+// archiveDocumentPath refers the archived message of the document to its local copy,
+// the document is kept after the message is archived when it's collected to the digest.
+func (eh *EventsHandler) archiveDocumentPath(event *domain.DocumentEvent, path string) {
+	msg, ok := eh.findArchivedMessage(func(msg *domain.ArchivedMessage) bool {
+		return msg.Direction == domain.MessageDirectionIn &&
+			msg.Account == event.Account &&
+			msg.RemoteJid == event.WhatsappRemoteJid &&
+			msg.WhatsappMessageID == event.WhatsappMessageID
+	})
+	if !ok {
+		return
+	}
+
+	for i := range msg.Media {
+		if msg.Media[i].Type == string(domain.WhatsappMediaDocument) && msg.Media[i].Path == "" {
+			msg.Media[i].Path = path
+		}
+	}
+	if err := eh.archive.Update(msg); err != nil {
+		eh.log.Error("failed to update archived document", zap.Error(err))
+	}
+}
+
+// sendDocumentNote posts the note about the document as a reply to its message.
+func (eh *EventsHandler) sendDocumentNote(posted postedMessage, note string) error {
+	if _, err := eh.telegramClient.SendText(&domain.TelegramTextMessage{
+		ChatID:              posted.chatID,
+		Text:                note,
+		DisableNotification: true,
+		ReplyToMessageID:    posted.messageID,
+	}); err != nil {
+		return fmt.Errorf("failed to send message to telegram: %w", err)
+	}
+
+	return nil
+}
+
+// documentFileName returns a name the document is uploaded with, a name with the extension
+// of its type is made up if the document has no name.
+func documentFileName(document domain.WhatsappDocument) string {
+	if document.FileName != "" {
+		return document.FileName
+	}
+
+	if extensions, err := mime.ExtensionsByType(document.MimeType); err == nil && len(extensions) != 0 {
+		return defaultDocumentName + extensions[0]
+	}
+
+	return defaultDocumentName
+}
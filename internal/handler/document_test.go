@@ -0,0 +1,197 @@
+package handler_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/files"
+	"github.com/dstdfx/twbridge/internal/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDocument is a document sent in the tests.
+var testDocument = domain.WhatsappDocument{
+	FileName: "report.pdf",
+	MimeType: "application/pdf",
+	Caption:  "Q1 numbers",
+	Size:     4,
+	Data:     []byte("%PDF"),
+}
+
+// document handles the document sent by Alice.
+func (env *testEnv) document(t *testing.T, document domain.WhatsappDocument) {
+	t.Helper()
+
+	require.NoError(t, env.eventsHandler.HandleDocumentEvent(&domain.DocumentEvent{
+		ChatID:             testChatID,
+		WhatsappRemoteJid:  "alice-jid",
+		WhatsappSenderName: "Alice",
+		WhatsappMessageID:  "alice-document-id",
+		Document:           document,
+		Account:            testAccount,
+	}))
+}
+
+func TestEventsHandlerDocument(t *testing.T) {
+	t.Run("document", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+
+		env.document(t, testDocument)
+		assert.Equal(t, "From: Alice [jid: alice-jid] \n= = = = = = = = = = = =\nMessage: "+
+			"📎 report.pdf (application/pdf, 4 B)\nQ1 numbers", env.lastText(t))
+
+		// The document replies to the message, so it's clear who has sent it
+		assert.Equal(t, []domain.TelegramDocumentMessage{{
+			ChatID:              testChatID,
+			Document:            domain.TelegramFile{Name: "report.pdf", Bytes: []byte("%PDF")},
+			MimeType:            "application/pdf",
+			Caption:             "Q1 numbers",
+			DisableNotification: true,
+			ReplyToMessageID:    len(env.telegramClient.Texts()),
+		}}, env.telegramClient.Documents())
+
+		archived := env.lastArchived(t)
+		assert.Equal(t, "alice-document-id", archived.WhatsappMessageID)
+		assert.Contains(t, archived.Text, "📎 report.pdf (application/pdf, 4 B)\nQ1 numbers")
+
+		// Documents uploaded to telegram aren't kept by the bridge
+		assert.Equal(t, []domain.MediaReference{
+			{Type: "document", FileName: "report.pdf", MimeType: "application/pdf"},
+		}, archived.Media)
+	})
+
+	t.Run("document without name", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+
+		env.document(t, domain.WhatsappDocument{MimeType: "application/pdf", Size: 4, Data: []byte("%PDF")})
+		assert.Contains(t, env.lastText(t), "📎 Document (application/pdf, 4 B)")
+
+		documents := env.telegramClient.Documents()
+		require.Len(t, documents, 1)
+		assert.Equal(t, "document.pdf", documents[0].Document.Name)
+	})
+
+	t.Run("document that is not downloaded", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+
+		env.document(t, domain.WhatsappDocument{FileName: "report.pdf", Size: 1 << 20})
+		assert.Empty(t, env.telegramClient.Documents())
+		assert.Equal(t, domain.TelegramTextMessage{
+			ChatID:              testChatID,
+			Text:                "The file couldn't be downloaded from WhatsApp, open it in WhatsApp",
+			DisableNotification: true,
+			ReplyToMessageID:    len(env.telegramClient.Texts()) - 1,
+		}, env.lastMessage(t))
+	})
+
+	t.Run("large document", func(t *testing.T) {
+		dir := t.TempDir()
+		var store *files.Store
+		env := newTestEnv(t, func(opts *handler.Opts) {
+			var err error
+			store, err = files.New(&files.Opts{
+				Dir:     dir,
+				BaseURL: "https://bridge.example.com/files",
+				Clock:   opts.Clock,
+			})
+			require.NoError(t, err)
+			opts.Files = store
+			opts.MaxUploadSize = 3
+		})
+		env.login(t, newContactsClient())
+		env.clock.now = testHistoryTime
+
+		env.document(t, testDocument)
+		assert.Empty(t, env.telegramClient.Documents())
+
+		// The file is kept until the link expires
+		note := env.lastMessage(t)
+		assert.Equal(t, len(env.telegramClient.Texts())-1, note.ReplyToMessageID)
+		assert.True(t, note.DisableNotification)
+
+		link := note.Text[strings.LastIndex(note.Text, "\n")+1:]
+		id := strings.Split(strings.TrimPrefix(link, "https://bridge.example.com/files/"), "/")[0]
+		file, err := store.Get(id)
+		require.NoError(t, err)
+		assert.Equal(t, store.Link(file), link)
+		assert.Equal(t, fmt.Sprintf("The file is too large for Telegram, download it before %s:\n%s",
+			testHistoryTime.Add(24*time.Hour).In(time.Local).Format("Jan 2 15:04"), link), note.Text)
+
+		data, err := os.ReadFile(file.Path)
+		require.NoError(t, err)
+		assert.Equal(t, "%PDF", string(data))
+		assert.Equal(t, "application/pdf", file.MimeType)
+	})
+
+	t.Run("large document without file storage", func(t *testing.T) {
+		env := newTestEnv(t, func(opts *handler.Opts) {
+			opts.MaxUploadSize = 3
+		})
+		env.login(t, newContactsClient())
+
+		env.document(t, testDocument)
+		assert.Empty(t, env.telegramClient.Documents())
+		assert.Equal(t, "The file is too large for Telegram, open it in WhatsApp", env.lastText(t))
+	})
+
+	t.Run("document of muted contact", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+		require.NoError(t, env.settings.SetMode(testChatID, testAccount, "alice-jid", domain.ContactModeMute))
+
+		env.document(t, testDocument)
+		assert.Empty(t, env.telegramClient.Documents())
+		assert.Contains(t, env.lastArchived(t).Text, "📎 report.pdf")
+	})
+	t.Run("document collected to the digest", func(t *testing.T) {
+		env := newTestEnv(t, func(opts *handler.Opts) {
+			store, err := files.New(&files.Opts{
+				Dir:     t.TempDir(),
+				BaseURL: "https://bridge.example.com/files",
+				Clock:   opts.Clock,
+			})
+			require.NoError(t, err)
+			opts.Files = store
+		})
+		env.login(t, newContactsClient())
+		env.clock.now = testHistoryTime
+		require.NoError(t, env.settings.SetMode(testChatID, testAccount, "alice-jid", domain.ContactModeDigest))
+
+		env.document(t, testDocument)
+		assert.Empty(t, env.telegramClient.Documents())
+
+		// The archived message refers to the kept file, so it's exported with it
+		archived := env.lastArchived(t)
+		require.Len(t, archived.Media, 1)
+		require.NotEmpty(t, archived.Media[0].Path)
+		data, err := os.ReadFile(archived.Media[0].Path)
+		require.NoError(t, err)
+		assert.Equal(t, testDocument.Data, data)
+
+		// The file is kept, so it's downloaded by the link from the digest
+		env.digestCommand(t)
+		digest := env.lastText(t)
+		assert.Contains(t, digest, "📎 report.pdf (application/pdf, 4 B)\nQ1 numbers\n")
+		assert.Contains(t, digest, fmt.Sprintf("Download the file before %s:\nhttps://bridge.example.com/files/",
+			testHistoryTime.Add(24*time.Hour).In(time.Local).Format("Jan 2 15:04")))
+		assert.True(t, strings.HasSuffix(digest, "/report.pdf"))
+	})
+
+	t.Run("document collected to the digest without file storage", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+		require.NoError(t, env.settings.SetMode(testChatID, testAccount, "alice-jid", domain.ContactModeDigest))
+
+		env.document(t, testDocument)
+		env.digestCommand(t)
+		assert.Contains(t, env.lastText(t), "The file isn't kept until the digest, open it in WhatsApp")
+	})
+}
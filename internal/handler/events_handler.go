@@ -1,26 +1,53 @@
 package handler
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/Rhymen/go-whatsapp"
+	"github.com/dstdfx/twbridge/internal/archive"
+	"github.com/dstdfx/twbridge/internal/away"
+	"github.com/dstdfx/twbridge/internal/conversation"
+	"github.com/dstdfx/twbridge/internal/digest"
 	"github.com/dstdfx/twbridge/internal/domain"
-	whatsappevents "github.com/dstdfx/twbridge/internal/whatsapp"
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/dstdfx/twbridge/internal/files"
+	"github.com/dstdfx/twbridge/internal/outbox"
+	"github.com/dstdfx/twbridge/internal/quiet"
+	"github.com/dstdfx/twbridge/internal/route"
+	"github.com/dstdfx/twbridge/internal/rules"
+	"github.com/dstdfx/twbridge/internal/schedule"
+	"github.com/dstdfx/twbridge/internal/settings"
+	"github.com/dstdfx/twbridge/internal/team"
+	"github.com/dstdfx/twbridge/internal/topic"
 	"github.com/skip2/go-qrcode"
 	"go.uber.org/zap"
 )
 
 const (
-	defaultWhatsappClientMajorVersion = 2
-	defaultWhatsappClientMinorVersion = 2134
-	defaultWhatsappClientPatchVersion = 10
-	defaultWhatsappConnTimeout        = 20 * time.Second
+	defaultQRCodePNGSize    = 256
+	qrCodeCountdownInterval = 5 * time.Second
+)
 
-	defaultQRCodePNGSize = 256
+const (
+	qrCodeCaptionFmt        = "Scan the QR-code%s with WhatsApp on your phone, it expires in %s"
+	qrCodeExpiredCaption    = "The QR-code has expired, waiting for a new one..."
+	qrCodeLoggedInCaption   = "The QR-code has been scanned"
+	qrCodeCancelledCaption  = "Login is cancelled"
+	qrCodeTimedOutCaption   = "The QR-code has expired"
+	loginInProgressFmt      = "Login is already in progress%s, scan the QR-code or cancel it"
+	loginTimedOutFmt        = "QR-code scanning timed out%s, let's try again, type %s"
+	loginCancelledFmt       = "Login is cancelled%s, type %s to try again"
+	loggedInFmt             = "Successfully logged in%s"
+	alreadyLoggedInFmt      = "Already logged in%s"
+	loggedOutFmt            = "Successfully logged out%s"
+	alreadyLoggedOutFmt     = "Already logged out%s"
+	noLoginInProgressAnswer = "There is no login in progress"
+	cancellingLoginAnswer   = "Cancelling login..."
+	invalidAccountMsg       = "Account name may contain only latin letters, digits, '-' and '_' " +
+		"and must be up to 32 characters long"
 )
 
 const startMsg = `
@@ -32,26 +59,88 @@ This step is needed in order to authenticate you in WhatsApp.
 
 So let's get it started.`
 
-const disconnectMsg = `The session is invalidated due to internal error, please repeat login process again.`
+const disconnectFmt = `The session is invalidated%s due to internal error, please repeat login process again.`
+
+const (
+	notLoggedInQueuedFmt = `You're not logged in to WhatsApp%s, the message will be sent after %s`
+	postponedMsg         = `The message couldn't be sent right now, it will be sent once the WhatsApp session is restored`
+	blockedMsg           = `The message waits for an earlier message to the contact that has failed, ` +
+		`press "Retry" under the failure notice or reply to that message with /delete`
+)
+
+// failedMessageFmt represents a format of a message that notifies a user
+// about a message that couldn't be delivered to whatsapp.
+const failedMessageFmt = "Failed to deliver message [jid: %s]%s\n= = = = = = = = = = = =\nMessage: %s\nError: %s"
 
 const helpMsg = `
 Supported commands:
 /start - prints starting message
-/login - establishes a session with WhatsApp via QR-code challenge
-/logout - invalidates your current session with WhatsApp
+/login [account] - establishes a session with WhatsApp via QR-code challenge,
+name the account to link several WhatsApp accounts, e.g. /login work
+/logout [account] - invalidates your current session with WhatsApp
+/team [on|off|add|remove|sign] - shares the chat with a team, e.g. /team add @username
+/assign [@username] - assigns the conversation of the replied message to you or a member of the team
+/unassign - releases the conversation of the replied message
+/topics [on|off] - bridges every WhatsApp conversation into its own topic of the group
+/route [add <jid> <chat id>|remove <jid>] - delivers messages of the conversation to another chat,
+reply to a message with /route <chat id> to route its conversation
+/chats - lists recent WhatsApp conversations with unread messages
+/lastseen <contact> - shows whether the contact is online or when it was last seen
+/delete - deletes your replied message in WhatsApp for everyone, edits of your messages are sent to WhatsApp too
+/chat <contact> - sends plain messages of the chat to the contact, contact is a name, phone number or jid
+/close - stops sending plain messages to the active conversation
+/mute [jid] - drops messages of the contact, reply to a message with /mute to mute its conversation
+/unmute [jid] - delivers messages of the contact as usual
+/settings [jid] - shows the way messages of the contacts are delivered
+/digest [daily <time>|daily off] - delivers messages collected to the digest, e.g. /digest daily 20:00
+/quiet [<from>-<to> [time zone]|off] - collects messages to the digest at night, e.g. /quiet 23:00-07:00 Europe/Berlin
+/schedule <contact> <time> <text> - sends the message at the time, e.g. /schedule Alice 09:00 call me,
+reply to a message with /schedule <time> <text> to schedule a message to its conversation
+/schedule - lists scheduled messages
+/unschedule <id> - cancels the scheduled message
+/away [<text>|on|off|cooldown <period>] - answers WhatsApp contacts with the auto-reply, e.g. /away I'm on vacation
+/search <query> [contact] - finds bridged messages, e.g. /search "lunch plans" Alice
+/export <contact> [from] [to] - exports the conversation to a zip file, e.g. /export Alice 2022-03-01 2022-03-31
+/history <contact> [n] - loads the last messages of the conversation from WhatsApp, e.g. /history Alice 50
+/rules [add|remove|dryrun] - manages rules applied to incoming messages, e.g. /rules add drop keyword=lottery
 /help - prints this message
 `
 
 // EventsHandler represents entity that handles events from telegram and whatsapp
 // event providers.
 type EventsHandler struct {
-	log                *zap.Logger
-	chatID             int64
-	eventsCh           chan domain.Event
-	telegramAPI        *tgbotapi.BotAPI
-	whatsappClient     domain.WhatsappClient
-	mu                 sync.RWMutex
-	isWhatsAppLoggedIn bool
+	log             *zap.Logger
+	chatID          int64
+	eventsCh        chan domain.Event
+	telegramClient  domain.TelegramClient
+	whatsappBackend domain.WhatsappBackend
+	outbox          *outbox.Outbox
+	teams           *team.Store
+	topics          *topic.Store
+	routes          *route.Store
+	conversations   *conversation.Store
+	settings        *settings.Store
+	digests         *digest.Store
+	rules           *rules.Store
+	quietHours      *quiet.Store
+	schedules       *schedule.Store
+	away            *away.Store
+	archive         *archive.Store
+	files           *files.Store
+	maxUploadSize   int
+	historyChats    int
+	historyMessages int
+	presenceChats   int
+	now             func() time.Time
+	mu              sync.RWMutex
+	whatsappClients map[string]domain.WhatsappClient
+	logins          map[string]context.CancelFunc
+
+	presences             map[presenceKey]contactPresence
+	presenceSubscriptions map[presenceKey]bool
+	pendingLastSeen       map[presenceKey]bool
+
+	liveLocations map[liveLocationKey]liveLocation
 }
 
 // Opts represents options to create new instance of EventsHandler.
@@ -62,27 +151,123 @@ type Opts struct {
 	// WhatsappProviderEvents is a channel to send events from whatsapp provider.
 	WhatsappProviderEvents chan domain.Event
 
-	// TelegramAPI is a client to interact with telegram API.
-	TelegramAPI *tgbotapi.BotAPI
+	// TelegramClient is a client to interact with telegram API.
+	TelegramClient domain.TelegramClient
+
+	// WhatsappBackend is a backend that establishes whatsapp sessions.
+	WhatsappBackend domain.WhatsappBackend
+
+	// Outbox is a queue of outgoing whatsapp messages.
+	Outbox *outbox.Outbox
+
+	// Teams is a storage of the teams, team mode is disabled if it's nil.
+	Teams *team.Store
+
+	// Topics is a storage of the forum topics, topics are not supported if it's nil.
+	Topics *topic.Store
+
+	// Routes is a routing table of whatsapp conversations, routes are not supported if it's nil.
+	Routes *route.Store
+
+	// Conversations is a storage of the active conversations, they are not supported if it's nil.
+	Conversations *conversation.Store
+
+	// Settings is a storage of the settings of whatsapp contacts, they are not supported if it's nil.
+	Settings *settings.Store
+
+	// Digests is a storage of the messages collected to the digests, digests are not supported if it's nil.
+	Digests *digest.Store
+
+	// Rules is a storage of the rules applied to incoming messages, rules are not supported if it's nil.
+	Rules *rules.Store
+
+	// QuietHours is a storage of quiet hours of the chats, they are not supported if it's nil.
+	QuietHours *quiet.Store
+
+	// Schedules is a storage of the scheduled messages, they are not supported if it's nil.
+	Schedules *schedule.Store
+
+	// Away is a storage of away modes of the chats, away mode is not supported if it's nil.
+	Away *away.Store
+
+	// Archive is a storage of the bridged messages, search is not supported if it's nil.
+	Archive *archive.Store
+
+	// Files is a local storage of the files too large to upload to telegram, such files
+	// are not bridged if it's nil.
+	Files *files.Store
+
+	// MaxUploadSize is the largest file in bytes uploaded to telegram, the limit of the bot API
+	// is used if it's zero. It's higher if the bot works with a local bot API server.
+	MaxUploadSize int
+
+	// HistoryChats is a number of the recent whatsapp chats whose history is loaded
+	// once an account is logged in, history isn't loaded on login if it's zero.
+	HistoryChats int
+
+	// HistoryMessages is a number of the last messages loaded per chat on login.
+	HistoryMessages int
+
+	// PresenceChats is a number of the recent whatsapp chats whose contacts are subscribed
+	// to presence updates once an account is logged in, presence is not supported if it's zero.
+	PresenceChats int
+
+	// Clock returns the current time, time.Now is used if it's nil.
+	Clock func() time.Time
 }
 
 // NewEventsHandler creates new instance of EventsHandler.
 func NewEventsHandler(log *zap.Logger, opts *Opts) *EventsHandler {
+	clock := opts.Clock
+	if clock == nil {
+		clock = time.Now
+	}
+	maxUploadSize := opts.MaxUploadSize
+	if maxUploadSize == 0 {
+		maxUploadSize = defaultMaxUploadSize
+	}
+
 	return &EventsHandler{
-		log:         log,
-		chatID:      opts.ChatID,
-		eventsCh:    opts.WhatsappProviderEvents,
-		telegramAPI: opts.TelegramAPI,
+		log:             log,
+		chatID:          opts.ChatID,
+		eventsCh:        opts.WhatsappProviderEvents,
+		telegramClient:  opts.TelegramClient,
+		whatsappBackend: opts.WhatsappBackend,
+		outbox:          opts.Outbox,
+		teams:           opts.Teams,
+		topics:          opts.Topics,
+		routes:          opts.Routes,
+		conversations:   opts.Conversations,
+		settings:        opts.Settings,
+		digests:         opts.Digests,
+		rules:           opts.Rules,
+		quietHours:      opts.QuietHours,
+		schedules:       opts.Schedules,
+		away:            opts.Away,
+		archive:         opts.Archive,
+		files:           opts.Files,
+		maxUploadSize:   maxUploadSize,
+		historyChats:    opts.HistoryChats,
+		historyMessages: opts.HistoryMessages,
+		presenceChats:   opts.PresenceChats,
+		now:             clock,
+		whatsappClients: make(map[string]domain.WhatsappClient),
+		logins:          make(map[string]context.CancelFunc),
+
+		presences:             make(map[presenceKey]contactPresence),
+		presenceSubscriptions: make(map[presenceKey]bool),
+		pendingLastSeen:       make(map[presenceKey]bool),
+
+		liveLocations: make(map[liveLocationKey]liveLocation),
 	}
 }
 
-// IsLoggedIn method returns `true` if client is authenticated in WhatsApp, otherwise
-// returns `false`.
-func (eh *EventsHandler) IsLoggedIn() bool {
-	eh.mu.RLock()
-	defer eh.mu.RUnlock()
+// IsLoggedIn method returns `true` if the whatsapp account is authenticated
+// in WhatsApp, otherwise returns `false`.
+func (eh *EventsHandler) IsLoggedIn(account string) bool {
+	_, ok := eh.whatsappClient(account)
 
-	return eh.isWhatsAppLoggedIn
+	return ok
 }
 
 // HandleStartEvent method handles start event.
@@ -99,87 +284,135 @@ func (eh *EventsHandler) HandleStartEvent(event *domain.StartEvent) error {
 }
 
 // HandleLoginEvent method handles login event.
+// The login runs in background, its result is handled by HandleLoginResultEvent.
 func (eh *EventsHandler) HandleLoginEvent(event *domain.LoginEvent) error {
 	eh.log.Debug("handle whatsapp login",
 		zap.String("username", event.FromUser),
-		zap.Int64("chat_id", event.ChatID))
+		zap.Int64("chat_id", event.ChatID),
+		zap.String("account", event.Account))
 
-	wac, err := whatsapp.NewConnWithOptions(&whatsapp.Options{
-		Timeout: defaultWhatsappConnTimeout,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to establish new whatsapp connection: %w", err)
+	if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+		return err
 	}
 
-	// Initialize new whatsapp client
-	eh.whatsappClient = whatsappevents.NewClient(wac)
-
-	// Initialize whatsapp events provider
-	waHandler := whatsappevents.NewEventsProvider(eh.log, &whatsappevents.Opts{
-		ChatID:         eh.chatID,
-		OutgoingEvents: eh.eventsCh,
-		WhatsappClient: eh.whatsappClient,
-	})
-	wac.AddHandler(waHandler)
-	wac.SetClientVersion(
-		defaultWhatsappClientMajorVersion,
-		defaultWhatsappClientMinorVersion,
-		defaultWhatsappClientPatchVersion)
+	if !domain.IsValidAccountName(event.Account) {
+		if err := eh.notifyTelegram(invalidAccountMsg); err != nil {
+			return fmt.Errorf("failed to notify telegram: %w", err)
+		}
 
-	qr := make(chan string)
-	go func() {
-		qrCode, err := qrcode.New(<-qr, qrcode.Low)
-		if err != nil {
-			eh.log.Error("failed to receive QR-code", zap.Error(err))
+		return nil
+	}
 
-			return
+	if _, ok := eh.logins[event.Account]; ok {
+		if err := eh.notifyTelegram(fmt.Sprintf(loginInProgressFmt, domain.AccountTag(event.Account))); err != nil {
+			return fmt.Errorf("failed to notify telegram: %w", err)
 		}
 
-		rawCode, err := qrCode.PNG(defaultQRCodePNGSize)
-		if err != nil {
-			eh.log.Error("failed to parse QR-code", zap.Error(err))
+		return nil
+	}
 
-			return
-		}
+	ctx, cancel := context.WithCancel(context.Background())
+	eh.logins[event.Account] = cancel
 
-		qrCodeReader := tgbotapi.FileReader{
-			Name:   "QrCode",
-			Reader: bytes.NewReader(rawCode),
-			Size:   int64(len(rawCode)),
-		}
+	go eh.login(ctx, event.Account)
+
+	return nil
+}
+
+// HandleCancelLoginEvent method handles cancel login event.
+func (eh *EventsHandler) HandleCancelLoginEvent(event *domain.CancelLoginEvent) error {
+	eh.log.Debug("handle cancel login event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.String("account", event.Account))
 
-		photo := tgbotapi.NewPhotoUpload(eh.chatID, qrCodeReader)
-		if _, err := eh.telegramAPI.Send(photo); err != nil {
-			eh.log.Error("failed to send QR-code", zap.Error(err))
+	if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+		return err
+	}
+
+	answer := noLoginInProgressAnswer
+	if cancel, ok := eh.logins[event.Account]; ok {
+		cancel()
+		answer = cancellingLoginAnswer
+	}
+
+	callbackAnswer := &domain.TelegramCallbackAnswer{
+		CallbackID: event.CallbackID,
+		Text:       answer,
+	}
+	if err := eh.telegramClient.AnswerCallback(callbackAnswer); err != nil {
+		return fmt.Errorf("failed to answer callback query: %w", err)
+	}
+
+	return nil
+}
 
-			return
+// HandleLoginResultEvent method handles login result event.
+func (eh *EventsHandler) HandleLoginResultEvent(event *domain.LoginResultEvent) error {
+	eh.log.Debug("handle login result event",
+		zap.Int64("chat_id", event.ChatID),
+		zap.String("account", event.Account),
+		zap.Error(event.Err))
+
+	if cancel, ok := eh.logins[event.Account]; ok {
+		cancel()
+		delete(eh.logins, event.Account)
+	}
+
+	cancelled := errors.Is(event.Err, context.Canceled)
+
+	// The QR-code is not needed anymore, so remove the countdown and the cancel button
+	if event.QRMessageID != 0 {
+		caption := qrCodeLoggedInCaption
+		switch {
+		case cancelled:
+			caption = qrCodeCancelledCaption
+		case event.Err != nil:
+			caption = qrCodeTimedOutCaption
 		}
 
-		eh.log.Debug("QR-code has been sent")
-	}()
+		if err := eh.editQRCodeCaption(event.QRMessageID, caption, nil); err != nil {
+			eh.log.Error("failed to update QR-code caption", zap.Error(err))
+		}
+	}
 
-	// TODO: save and restore sessions
+	accountTag := domain.AccountTag(event.Account)
+	if cancelled {
+		if err := eh.notifyTelegram(fmt.Sprintf(loginCancelledFmt, accountTag, loginCommand(event.Account))); err != nil {
+			return fmt.Errorf("failed to notify telegram: %w", err)
+		}
 
-	session, err := wac.Login(qr)
-	if err != nil {
-		err := eh.notifyTelegram("QR-code scanning timed out, let's try again, type /login")
-		if err != nil {
+		return nil
+	}
+
+	if event.Err != nil {
+		if err := eh.notifyTelegram(fmt.Sprintf(loginTimedOutFmt, accountTag, loginCommand(event.Account))); err != nil {
 			return fmt.Errorf("failed to notify telegram: %w", err)
 		}
 
-		return fmt.Errorf("failed to login to whatsapp: %w", err)
+		return fmt.Errorf("failed to login to whatsapp: %w", event.Err)
 	}
 
 	eh.mu.Lock()
-	eh.isWhatsAppLoggedIn = true
+	eh.whatsappClients[event.Account] = event.WhatsappClient
 	eh.mu.Unlock()
 
-	eh.log.Debug("login successful", zap.String("client_id", session.ClientId))
+	eh.log.Debug("login successful",
+		zap.Int64("chat_id", eh.chatID),
+		zap.String("account", event.Account))
 
-	if err := eh.notifyTelegram("Successfully logged in"); err != nil {
+	if err := eh.notifyTelegram(fmt.Sprintf(loggedInFmt, accountTag)); err != nil {
 		return fmt.Errorf("failed to notify telegram: %w", err)
 	}
 
+	// Send messages that have been queued while the account was logged out
+	if _, err := eh.flushOutbox(event.Account); err != nil {
+		return err
+	}
+
+	eh.backfillHistory(event.Account, event.WhatsappClient)
+	eh.subscribeRecentPresence(event.Account, event.WhatsappClient)
+
 	return nil
 }
 
@@ -187,26 +420,34 @@ func (eh *EventsHandler) HandleLoginEvent(event *domain.LoginEvent) error {
 func (eh *EventsHandler) HandleLogoutEvent(event *domain.LogoutEvent) error {
 	eh.log.Debug("handle whatsapp logout",
 		zap.String("username", event.FromUser),
-		zap.Int64("chat_id", event.ChatID))
+		zap.Int64("chat_id", event.ChatID),
+		zap.String("account", event.Account))
+
+	if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+		return err
+	}
+
+	accountTag := domain.AccountTag(event.Account)
 
-	// Check if the client is already logged out
-	if !eh.IsLoggedIn() {
-		if err := eh.notifyTelegram("Already logged out"); err != nil {
+	// Check if the account is already logged out
+	whatsappClient, ok := eh.whatsappClient(event.Account)
+	if !ok {
+		if err := eh.notifyTelegram(fmt.Sprintf(alreadyLoggedOutFmt, accountTag)); err != nil {
 			return fmt.Errorf("failed to notify telegram: %w", err)
 		}
 
 		return nil
 	}
 
-	if err := eh.whatsappClient.Logout(); err != nil {
+	if err := whatsappClient.Logout(); err != nil {
 		return fmt.Errorf("failed to logout: %w", err)
 	}
 
 	eh.mu.Lock()
-	eh.isWhatsAppLoggedIn = false
+	delete(eh.whatsappClients, event.Account)
 	eh.mu.Unlock()
 
-	if err := eh.notifyTelegram("Successfully logged out"); err != nil {
+	if err := eh.notifyTelegram(fmt.Sprintf(loggedOutFmt, accountTag)); err != nil {
 		return fmt.Errorf("failed to notify telegram: %w", err)
 	}
 
@@ -217,9 +458,10 @@ func (eh *EventsHandler) HandleLogoutEvent(event *domain.LogoutEvent) error {
 func (eh *EventsHandler) HandleRepeatedLoginEvent(event *domain.LoginEvent) error {
 	eh.log.Debug("handle repeated login event",
 		zap.String("username", event.FromUser),
-		zap.Int64("chat_id", event.ChatID))
+		zap.Int64("chat_id", event.ChatID),
+		zap.String("account", event.Account))
 
-	if err := eh.notifyTelegram("Already logged in"); err != nil {
+	if err := eh.notifyTelegram(fmt.Sprintf(alreadyLoggedInFmt, domain.AccountTag(event.Account))); err != nil {
 		return fmt.Errorf("failed to notify telegram: %w", err)
 	}
 
@@ -242,36 +484,244 @@ func (eh *EventsHandler) HandleHelpEvent(event *domain.HelpEvent) error {
 // HandleTextMessageEvent method handles text message event.
 func (eh *EventsHandler) HandleTextMessageEvent(event *domain.TextMessageEvent) error {
 	eh.log.Debug("handle text message event",
-		zap.String("remote_jid", event.WhatsappRemoteJid))
+		zap.String("remote_jid", event.WhatsappRemoteJid),
+		zap.String("account", event.Account))
+
+	_, err := eh.handleIncomingMessage(event, domain.MessageTypeText, nil)
+
+	return err
+}
+
+// handleIncomingMessage delivers the incoming message to telegram and returns the posted
+// message, it's empty if the message hasn't been posted, e.g. it's dropped by the rules.
+// The type of the message is matched by the rules, the text describes other messages
+// and the media files they have are archived with them.
+func (eh *EventsHandler) handleIncomingMessage(
+	event *domain.TextMessageEvent,
+	messageType domain.MessageType,
+	media []domain.MediaReference,
+) (postedMessage, error) {
+	result := eh.evaluateRules(event, messageType)
+	if result.Drop {
+		eh.log.Debug("drop message by the rules")
+
+		return postedMessage{}, nil
+	}
 
-	textMessageTemplate := fmt.Sprintf(domain.TextMessageFmt,
+	// The message is tagged with its account, so replies are sent from the same account
+	text := fmt.Sprintf(domain.TextMessageFmt,
 		event.WhatsappSenderName,
 		event.WhatsappRemoteJid,
+		domain.AccountTag(event.Account)+eh.assignedTag(event.Account, event.WhatsappRemoteJid)+ruleTags(&result),
 		event.Text)
+	eh.forwardMessage(text, result.Forward)
 
-	if err := eh.notifyTelegram(textMessageTemplate); err != nil {
-		return fmt.Errorf("failed to notify telegram: %w", err)
+	// Important messages are delivered with notification unless the contact is muted
+	mode := eh.contactMode(event.Account, event.WhatsappRemoteJid)
+	if result.Important && (mode == domain.ContactModeSilent || mode == domain.ContactModeDigest) {
+		mode = domain.ContactModeNormal
 	}
 
-	return nil
+	if mode == domain.ContactModeMute || mode == domain.ContactModeBlock {
+		eh.log.Debug("drop message of the contact", zap.String("mode", string(mode)))
+		eh.archiveTextMessage(event, media, postedMessage{})
+
+		return postedMessage{}, nil
+	}
+
+	posted, err := eh.deliverTextMessage(event, text, mode, result.Important)
+	if err != nil {
+		return postedMessage{}, err
+	}
+	eh.archiveTextMessage(event, media, posted)
+
+	// The contact has a recent conversation now, so its typing is shown from now on
+	if whatsappClient, ok := eh.whatsappClient(event.Account); ok {
+		if err := eh.subscribePresence(event.Account, whatsappClient, event.WhatsappRemoteJid); err != nil {
+			eh.log.Error("failed to subscribe to presence", zap.Error(err))
+		}
+	}
+
+	// The auto-reply is logged after the message it answers
+	eh.sendAutoReply(event)
+
+	return posted, nil
+}
+
+// deliverTextMessage delivers the incoming message to telegram in the mode of its contact,
+// it's collected to the digest instead if the contact is digest only or it's quiet hours now.
+// The posted message has no identifier if the message is collected to the digest.
+func (eh *EventsHandler) deliverTextMessage(event *domain.TextMessageEvent, text string,
+	mode domain.ContactMode, important bool) (postedMessage, error) {
+	switch {
+	case mode == domain.ContactModeDigest && eh.digests != nil:
+		return postedMessage{collected: true}, eh.addToDigest(event, false)
+	case mode != domain.ContactModeDigest && !important && eh.inQuietHours():
+		// Messages received during quiet hours are delivered once they are over
+		return postedMessage{collected: true, quietHours: true}, eh.addToDigest(event, true)
+	}
+
+	textMessage := domain.TelegramTextMessage{
+		Text:                text,
+		Buttons:             eh.contactButtons(event.Account, event.WhatsappRemoteJid),
+		DisableNotification: mode == domain.ContactModeSilent,
+	}
+
+	posted, err := eh.deliverConversationMessage(event.Account,
+		event.WhatsappRemoteJid,
+		event.WhatsappSenderName,
+		textMessage)
+	if err != nil {
+		return postedMessage{}, fmt.Errorf("failed to notify telegram: %w", err)
+	}
+
+	return posted, nil
 }
 
 // HandleReplyEvent method handles reply event.
 func (eh *EventsHandler) HandleReplyEvent(event *domain.ReplyEvent) error {
 	eh.log.Debug("reply to a message",
 		zap.Int64("chat_id", event.ChatID),
-		zap.String("remote_jid", event.RemoteJid))
+		zap.String("remote_jid", event.RemoteJid),
+		zap.String("account", event.Account),
+		zap.Int("thread_id", event.ThreadID))
+
+	// Messages posted to a topic are sent to the conversation of the topic,
+	// other plain messages are sent to the active conversation
+	if !eh.resolveTopicReply(event) && !eh.resolveActiveConversation(event) {
+		return nil
+	}
+
+	if eh.contactMode(event.Account, event.RemoteJid) == domain.ContactModeBlock {
+		msg := fmt.Sprintf(blockedReplyFmt, event.RemoteJid, domain.AccountTag(event.Account))
+		if err := eh.notifyTelegram(msg); err != nil {
+			return fmt.Errorf("failed to notify telegram: %w", err)
+		}
 
-	msg := &domain.WhatsappTextMessage{
-		RemoteJid: event.RemoteJid,
-		Text:      event.Reply,
+		return nil
 	}
 
-	if err := eh.whatsappClient.Send(msg); err != nil {
-		return fmt.Errorf("failed to send message chat_id=%d remote_jid=%s: %w",
-			event.ChatID,
-			event.RemoteJid,
-			err)
+	// Replies from the chats the conversation is routed to are sent as is,
+	// the team manages only this chat
+	reply := event.Reply
+	if event.ChatID == eh.chatID {
+		if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+			return err
+		}
+
+		teamReply, ok, err := eh.teamReply(event)
+		if !ok || err != nil {
+			return err
+		}
+		reply = teamReply
+	}
+
+	if msg := unsupportedReplyMsg(event); msg != "" {
+		if err := eh.notifyTelegram(msg); err != nil {
+			return fmt.Errorf("failed to notify telegram: %w", err)
+		}
+
+		return nil
+	}
+
+	var queued domain.OutboxMessage
+	var archivedMedia []domain.MediaReference
+	var err error
+	switch {
+	case event.Location != nil:
+		// Locations and contacts can't be signed, they are sent as is
+		queued, _, err = eh.queueWhatsappLocation(event.Account, event.RemoteJid, *event.Location)
+	case event.ContactCard != nil:
+		queued, _, err = eh.queueWhatsappContactCard(event.Account, event.RemoteJid, *event.ContactCard)
+	case event.Media != nil:
+		// The caption is the reply, so it's signed like a text
+		media := *event.Media
+		media.Caption = strings.TrimSpace(reply)
+		queued, _, err = eh.queueWhatsappMedia(event.Account, event.RemoteJid, media)
+		archivedMedia = []domain.MediaReference{mediaReference(&media)}
+	default:
+		queued, _, err = eh.queueWhatsappMessage(event.Account, event.RemoteJid, reply)
+	}
+	if err != nil {
+		return err
+	}
+
+	// The telegram message is referenced, so it's possible to edit or delete the whatsapp one
+	eh.archiveMessage(domain.ArchivedMessage{
+		Direction:         domain.MessageDirectionOut,
+		Account:           event.Account,
+		RemoteJid:         event.RemoteJid,
+		SenderName:        event.FromName,
+		Text:              queued.Text,
+		Media:             archivedMedia,
+		WhatsappMessageID: queued.WhatsappMessageID,
+	}, postedMessage{chatID: event.ChatID, messageID: event.MessageID})
+
+	return nil
+}
+
+// HandleRestoreEvent method handles restore event.
+func (eh *EventsHandler) HandleRestoreEvent(event *domain.RestoreEvent) error {
+	eh.log.Debug("handle restore event",
+		zap.Int64("chat_id", event.ChatID),
+		zap.String("account", event.Account))
+
+	if !eh.IsLoggedIn(event.Account) {
+		return nil
+	}
+
+	// Send messages that have been queued while the session was broken
+	if _, err := eh.flushOutbox(event.Account); err != nil {
+		return err
+	}
+
+	if whatsappClient, ok := eh.whatsappClient(event.Account); ok {
+		eh.subscribeRecentPresence(event.Account, whatsappClient)
+	}
+
+	return nil
+}
+
+// HandleRetryEvent method handles retry event.
+func (eh *EventsHandler) HandleRetryEvent(event *domain.RetryEvent) error {
+	eh.log.Debug("handle retry event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.String("message_id", event.MessageID))
+
+	if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+		return err
+	}
+
+	msg, retried, err := eh.outbox.Retry(eh.chatID, event.MessageID)
+	if err != nil {
+		return fmt.Errorf("failed to retry message %s: %w", event.MessageID, err)
+	}
+
+	var answer string
+	switch {
+	case !retried:
+		answer = "The message has already been handled"
+	case !eh.IsLoggedIn(msg.Account):
+		answer = fmt.Sprintf("The message will be sent after %s", loginCommand(msg.Account))
+	default:
+		answer = "Sending the message..."
+	}
+
+	callbackAnswer := &domain.TelegramCallbackAnswer{
+		CallbackID: event.CallbackID,
+		Text:       answer,
+	}
+	if err := eh.telegramClient.AnswerCallback(callbackAnswer); err != nil {
+		return fmt.Errorf("failed to answer callback query: %w", err)
+	}
+
+	if !retried || !eh.IsLoggedIn(msg.Account) {
+		return nil
+	}
+
+	if _, err := eh.flushOutbox(msg.Account); err != nil {
+		return err
 	}
 
 	return nil
@@ -280,26 +730,361 @@ func (eh *EventsHandler) HandleReplyEvent(event *domain.ReplyEvent) error {
 // HandleDisconnectEvent method handles disconnect event.
 func (eh *EventsHandler) HandleDisconnectEvent(event *domain.DisconnectEvent) error {
 	eh.log.Debug("handle disconnect event",
-		zap.Int64("chat_id", event.ChatID))
+		zap.Int64("chat_id", event.ChatID),
+		zap.String("account", event.Account))
 
 	// Attempt to logout the client and stop whatsapp handler
-	if err := eh.whatsappClient.Logout(); err != nil {
-		eh.log.Error("failed to logout disconnected client", zap.Error(err))
+	if whatsappClient, ok := eh.whatsappClient(event.Account); ok {
+		if err := whatsappClient.Logout(); err != nil {
+			eh.log.Error("failed to logout disconnected client", zap.Error(err))
+		}
 	}
 
 	eh.mu.Lock()
-	eh.isWhatsAppLoggedIn = false
+	delete(eh.whatsappClients, event.Account)
 	eh.mu.Unlock()
 
-	if err := eh.notifyTelegram(disconnectMsg); err != nil {
+	if err := eh.notifyTelegram(fmt.Sprintf(disconnectFmt, domain.AccountTag(event.Account))); err != nil {
 		return fmt.Errorf("failed to notify telegram: %w", err)
 	}
 
 	return nil
 }
 
+// HandleTickEvent method handles tick event, it runs scheduled jobs of the chat.
+func (eh *EventsHandler) HandleTickEvent(event *domain.TickEvent) error {
+	if err := eh.sendScheduledMessages(event.Time); err != nil {
+		eh.log.Error("failed to send scheduled messages", zap.Error(err))
+	}
+
+	return eh.deliverScheduledDigest(event.Time)
+}
+
+// whatsappClient returns a client of the logged in whatsapp account.
+func (eh *EventsHandler) whatsappClient(account string) (domain.WhatsappClient, bool) {
+	eh.mu.RLock()
+	defer eh.mu.RUnlock()
+
+	whatsappClient, ok := eh.whatsappClients[account]
+
+	return whatsappClient, ok
+}
+
+// queueWhatsappMessage puts the message to the outbox and sends it if the account
+// is logged in, the queued message is returned with true if it has been delivered.
+func (eh *EventsHandler) queueWhatsappMessage(account, remoteJid, text string) (domain.OutboxMessage, bool, error) {
+	// Put the message to the outbox first, so it's not lost if whatsapp
+	// is not reachable at the moment
+	queued, err := eh.outbox.Enqueue(eh.chatID, account, remoteJid, text)
+	if err != nil {
+		return domain.OutboxMessage{}, false, fmt.Errorf("failed to queue message chat_id=%d remote_jid=%s: %w",
+			eh.chatID,
+			remoteJid,
+			err)
+	}
+
+	return eh.sendQueued(queued)
+}
+
+// queueWhatsappLocation puts the location to the outbox and sends it like queueWhatsappMessage.
+func (eh *EventsHandler) queueWhatsappLocation(account, remoteJid string,
+	location domain.WhatsappLocation) (domain.OutboxMessage, bool, error) {
+	queued, err := eh.outbox.EnqueueLocation(eh.chatID, account, remoteJid, location)
+	if err != nil {
+		return domain.OutboxMessage{}, false, fmt.Errorf("failed to queue location chat_id=%d remote_jid=%s: %w",
+			eh.chatID,
+			remoteJid,
+			err)
+	}
+
+	return eh.sendQueued(queued)
+}
+
+// queueWhatsappContactCard puts the contact card to the outbox and sends it like queueWhatsappMessage.
+func (eh *EventsHandler) queueWhatsappContactCard(account, remoteJid string,
+	contactCard domain.WhatsappContactCard) (domain.OutboxMessage, bool, error) {
+	queued, err := eh.outbox.EnqueueContactCard(eh.chatID, account, remoteJid, contactCard)
+	if err != nil {
+		return domain.OutboxMessage{}, false, fmt.Errorf("failed to queue contact card chat_id=%d remote_jid=%s: %w",
+			eh.chatID,
+			remoteJid,
+			err)
+	}
+
+	return eh.sendQueued(queued)
+}
+
+// sendQueued sends the queued message if the account is logged in,
+// true is returned if it has been delivered.
+func (eh *EventsHandler) sendQueued(queued domain.OutboxMessage) (domain.OutboxMessage, bool, error) {
+	account := queued.Account
+	if !eh.IsLoggedIn(account) {
+		notLoggedInMsg := fmt.Sprintf(notLoggedInQueuedFmt,
+			domain.AccountTag(account),
+			loginCommand(account))
+		if err := eh.notifyTelegram(notLoggedInMsg); err != nil {
+			return queued, false, fmt.Errorf("failed to notify telegram: %w", err)
+		}
+
+		return queued, false, nil
+	}
+
+	report, err := eh.flushOutbox(account)
+	if err != nil {
+		return queued, false, err
+	}
+
+	for _, msg := range report.Postponed {
+		if msg.ID != queued.ID {
+			continue
+		}
+
+		if err := eh.notifyTelegram(postponedMsg); err != nil {
+			return queued, false, fmt.Errorf("failed to notify telegram: %w", err)
+		}
+	}
+
+	// The session is fine, the message is kept in order behind the failed one
+	for _, msg := range report.Blocked {
+		if msg.ID != queued.ID {
+			continue
+		}
+
+		if err := eh.notifyTelegram(blockedMsg); err != nil {
+			return queued, false, fmt.Errorf("failed to notify telegram: %w", err)
+		}
+	}
+
+	for _, msg := range report.Delivered {
+		if msg.ID == queued.ID {
+			return queued, true, nil
+		}
+	}
+
+	return queued, false, nil
+}
+
+// flushOutbox sends queued messages of the logged in whatsapp account and
+// notifies telegram about messages that couldn't be delivered.
+func (eh *EventsHandler) flushOutbox(account string) (*outbox.Report, error) {
+	whatsappClient, ok := eh.whatsappClient(account)
+	if !ok {
+		return &outbox.Report{}, nil
+	}
+
+	report, err := eh.outbox.Flush(eh.chatID, account, eh.sendWhatsappMessage(whatsappClient))
+	if err != nil {
+		return nil, fmt.Errorf("failed to flush outbox: %w", err)
+	}
+
+	for _, msg := range report.Postponed {
+		eh.log.Debug("message has been postponed",
+			zap.String("message_id", msg.ID),
+			zap.String("remote_jid", msg.RemoteJid),
+			zap.String("account", msg.Account),
+			zap.String("error", msg.LastError))
+	}
+
+	for _, msg := range report.Blocked {
+		eh.log.Debug("message is blocked by a failed message",
+			zap.String("message_id", msg.ID),
+			zap.String("remote_jid", msg.RemoteJid),
+			zap.String("account", msg.Account))
+	}
+
+	for _, msg := range report.Failed {
+		eh.log.Error("failed to deliver message",
+			zap.String("message_id", msg.ID),
+			zap.String("remote_jid", msg.RemoteJid),
+			zap.String("account", msg.Account),
+			zap.String("error", msg.LastError))
+
+		notification := &domain.TelegramTextMessage{
+			ChatID: eh.chatID,
+			Text: fmt.Sprintf(failedMessageFmt,
+				msg.RemoteJid,
+				domain.AccountTag(msg.Account),
+				msg.Text,
+				msg.LastError),
+			Buttons: [][]domain.TelegramButton{{
+				{
+					Text:         "Retry",
+					CallbackData: domain.NewCallbackData(domain.RetryCallbackAction, msg.ID),
+				},
+			}},
+		}
+		if _, err := eh.telegramClient.SendText(notification); err != nil {
+			return nil, fmt.Errorf("failed to send message to telegram: %w", err)
+		}
+	}
+
+	return report, nil
+}
+
+// login runs the login via whatsapp backend and reports its result
+// by LoginResultEvent.
+func (eh *EventsHandler) login(ctx context.Context, account string) {
+	qr := make(chan domain.WhatsappQRCode)
+	qrMessageID := make(chan int, 1)
+	go func() {
+		qrMessageID <- eh.showQRCodes(account, qr)
+	}()
+
+	whatsappClient, err := eh.whatsappBackend.Login(ctx, &domain.WhatsappSessionOpts{
+		ChatID:  eh.chatID,
+		Account: account,
+		Events:  eh.eventsCh,
+	}, qr)
+	close(qr)
+
+	eh.eventsCh <- &domain.LoginResultEvent{
+		ChatID:         eh.chatID,
+		Account:        account,
+		QRMessageID:    <-qrMessageID,
+		WhatsappClient: whatsappClient,
+		Err:            err,
+	}
+}
+
+// showQRCodes keeps a single telegram photo with the latest QR-code and
+// the time left to scan it until qr channel is closed.
+// It returns an identifier of the photo message.
+func (eh *EventsHandler) showQRCodes(account string, qr <-chan domain.WhatsappQRCode) int {
+	var (
+		messageID int
+		expiresAt time.Time
+		caption   string
+	)
+
+	ticker := time.NewTicker(qrCodeCountdownInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case qrCode, ok := <-qr:
+			if !ok {
+				return messageID
+			}
+
+			expiresAt = time.Now().Add(qrCode.Timeout)
+			caption = qrCodeCaption(account, expiresAt)
+			sentID, err := eh.sendQRCode(messageID, account, qrCode.Content, caption)
+			if err != nil {
+				eh.log.Error("failed to send QR-code", zap.Error(err))
+
+				continue
+			}
+			messageID = sentID
+
+			eh.log.Debug("QR-code has been sent")
+		case <-ticker.C:
+			newCaption := qrCodeCaption(account, expiresAt)
+			if messageID == 0 || newCaption == caption {
+				continue
+			}
+
+			caption = newCaption
+			if err := eh.editQRCodeCaption(messageID, caption, cancelLoginButtons(account)); err != nil {
+				eh.log.Error("failed to update QR-code caption", zap.Error(err))
+			}
+		}
+	}
+}
+
+// sendQRCode sends a new QR-code photo or replaces the photo of the message
+// if messageID is provided. It returns an identifier of the photo message.
+func (eh *EventsHandler) sendQRCode(messageID int, account, content, caption string) (int, error) {
+	qrCode, err := qrcode.New(content, qrcode.Low)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create QR-code: %w", err)
+	}
+
+	rawCode, err := qrCode.PNG(defaultQRCodePNGSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode QR-code: %w", err)
+	}
+
+	photo := domain.TelegramFile{
+		Name:  "QrCode",
+		Bytes: rawCode,
+	}
+
+	if messageID != 0 {
+		err := eh.telegramClient.EditPhoto(&domain.TelegramEditPhotoMessage{
+			ChatID:    eh.chatID,
+			MessageID: messageID,
+			Photo:     photo,
+			Caption:   caption,
+			Buttons:   cancelLoginButtons(account),
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to edit message in telegram: %w", err)
+		}
+
+		return messageID, nil
+	}
+
+	messageID, err = eh.telegramClient.SendPhoto(&domain.TelegramPhotoMessage{
+		ChatID:  eh.chatID,
+		Photo:   photo,
+		Caption: caption,
+		Buttons: cancelLoginButtons(account),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to send message to telegram: %w", err)
+	}
+
+	return messageID, nil
+}
+
+func (eh *EventsHandler) editQRCodeCaption(messageID int, caption string, buttons [][]domain.TelegramButton) error {
+	err := eh.telegramClient.EditCaption(&domain.TelegramEditCaptionMessage{
+		ChatID:    eh.chatID,
+		MessageID: messageID,
+		Caption:   caption,
+		Buttons:   buttons,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to edit message in telegram: %w", err)
+	}
+
+	return nil
+}
+
+// qrCodeCaption returns a caption of the QR-code photo with the time left to scan it.
+func qrCodeCaption(account string, expiresAt time.Time) string {
+	left := time.Until(expiresAt).Round(time.Second)
+	if left <= 0 {
+		return qrCodeExpiredCaption
+	}
+
+	return fmt.Sprintf(qrCodeCaptionFmt, domain.AccountTag(account), left)
+}
+
+func cancelLoginButtons(account string) [][]domain.TelegramButton {
+	return [][]domain.TelegramButton{{
+		{
+			Text:         "Cancel login",
+			CallbackData: domain.NewCallbackData(domain.CancelLoginCallbackAction, account),
+		},
+	}}
+}
+
+// loginCommand returns a command to log in the whatsapp account.
+func loginCommand(account string) string {
+	if domain.AccountTag(account) == "" {
+		return "/login"
+	}
+
+	return "/login " + account
+}
+
 func (eh *EventsHandler) notifyTelegram(msg string) error {
-	if _, err := eh.telegramAPI.Send(tgbotapi.NewMessage(eh.chatID, msg)); err != nil {
+	textMessage := &domain.TelegramTextMessage{
+		ChatID: eh.chatID,
+		Text:   msg,
+	}
+	if _, err := eh.telegramClient.SendText(textMessage); err != nil {
 		return fmt.Errorf("failed to send message to telegram: %w", err)
 	}
 
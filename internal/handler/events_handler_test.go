@@ -1,21 +1,762 @@
 package handler_test
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/archive"
+	"github.com/dstdfx/twbridge/internal/away"
+	"github.com/dstdfx/twbridge/internal/conversation"
+	"github.com/dstdfx/twbridge/internal/digest"
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/handler"
+	"github.com/dstdfx/twbridge/internal/outbox"
+	"github.com/dstdfx/twbridge/internal/quiet"
+	"github.com/dstdfx/twbridge/internal/route"
+	"github.com/dstdfx/twbridge/internal/rules"
+	"github.com/dstdfx/twbridge/internal/schedule"
+	"github.com/dstdfx/twbridge/internal/settings"
+	"github.com/dstdfx/twbridge/internal/team"
+	"github.com/dstdfx/twbridge/internal/telegram/fake"
+	"github.com/dstdfx/twbridge/internal/topic"
+	"github.com/dstdfx/twbridge/internal/whatsapp/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	testChatID    = int64(123)
+	testUserName  = "test-user"
+	testRemoteJid = "test-remote-jid"
+	testAccount   = domain.DefaultWhatsappAccount
+)
+
+var errTestSend = errors.New("failed to send message")
+
+type testEnv struct {
+	eventsHandler   *handler.EventsHandler
+	telegramClient  *fake.Client
+	whatsappBackend *mocks.WhatsappBackend
+	outbox          *outbox.Outbox
+	teams           *team.Store
+	topics          *topic.Store
+	routes          *route.Store
+	conversations   *conversation.Store
+	settings        *settings.Store
+	digests         *digest.Store
+	rules           *rules.Store
+	quietHours      *quiet.Store
+	schedules       *schedule.Store
+	away            *away.Store
+	archive         *archive.Store
+	clock           *testClock
+	events          chan domain.Event
+}
+
+// newTestEnv returns new test environment, the options modify options of the events handler.
+func newTestEnv(t *testing.T, options ...func(opts *handler.Opts)) *testEnv {
+	t.Helper()
+
+	telegramClient := fake.NewClient()
+	whatsappBackend := &mocks.WhatsappBackend{}
+	testOutbox, err := outbox.New(&outbox.Opts{
+		Path:        filepath.Join(t.TempDir(), "outbox.json"),
+		MaxAttempts: 2,
+	})
+	require.NoError(t, err)
+	teams, err := team.New(&team.Opts{Path: filepath.Join(t.TempDir(), "teams.json")})
+	require.NoError(t, err)
+	topics, err := topic.New(&topic.Opts{Path: filepath.Join(t.TempDir(), "topics.json")})
+	require.NoError(t, err)
+	routes, err := route.New(&route.Opts{Path: filepath.Join(t.TempDir(), "routes.json")})
+	require.NoError(t, err)
+	conversations, err := conversation.New(&conversation.Opts{Path: filepath.Join(t.TempDir(), "conversations.json")})
+	require.NoError(t, err)
+	contactSettings, err := settings.New(&settings.Opts{Path: filepath.Join(t.TempDir(), "settings.json")})
+	require.NoError(t, err)
+	digests, err := digest.New(&digest.Opts{Path: filepath.Join(t.TempDir(), "digests.json")})
+	require.NoError(t, err)
+	messageRules, err := rules.New(&rules.Opts{Path: filepath.Join(t.TempDir(), "rules.json")})
+	require.NoError(t, err)
+	quietHours, err := quiet.New(&quiet.Opts{Path: filepath.Join(t.TempDir(), "quiet_hours.json")})
+	require.NoError(t, err)
+	schedules, err := schedule.New(&schedule.Opts{Path: filepath.Join(t.TempDir(), "schedule.json")})
+	require.NoError(t, err)
+	awayModes, err := away.New(&away.Opts{Path: filepath.Join(t.TempDir(), "away.json")})
+	require.NoError(t, err)
+	messageArchive, err := archive.New(zap.NewNop(), &archive.Opts{Path: filepath.Join(t.TempDir(), "archive.jsonl")})
+	require.NoError(t, err)
+	clock := &testClock{}
+
+	events := make(chan domain.Event, 1)
+	opts := &handler.Opts{
+		ChatID:                 testChatID,
+		WhatsappProviderEvents: events,
+		TelegramClient:         telegramClient,
+		WhatsappBackend:        whatsappBackend,
+		Outbox:                 testOutbox,
+		Teams:                  teams,
+		Topics:                 topics,
+		Routes:                 routes,
+		Conversations:          conversations,
+		Settings:               contactSettings,
+		Digests:                digests,
+		Rules:                  messageRules,
+		QuietHours:             quietHours,
+		Schedules:              schedules,
+		Away:                   awayModes,
+		Archive:                messageArchive,
+		Clock:                  clock.Now,
+	}
+	for _, option := range options {
+		option(opts)
+	}
+	eventsHandler := handler.NewEventsHandler(zap.NewNop(), opts)
+
+	return &testEnv{
+		eventsHandler:   eventsHandler,
+		telegramClient:  telegramClient,
+		whatsappBackend: whatsappBackend,
+		outbox:          testOutbox,
+		teams:           teams,
+		topics:          topics,
+		routes:          routes,
+		conversations:   conversations,
+		settings:        contactSettings,
+		digests:         digests,
+		rules:           messageRules,
+		quietHours:      quietHours,
+		schedules:       schedules,
+		away:            awayModes,
+		archive:         messageArchive,
+		clock:           clock,
+		events:          events,
+	}
+}
+
+// testClock returns the time it's set to, or the current time if it isn't set.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	if c.now.IsZero() {
+		return time.Now()
+	}
+
+	return c.now
+}
+
+// login logs the events handler in with the provided whatsapp client.
+func (env *testEnv) login(t *testing.T, client domain.WhatsappClient) {
+	t.Helper()
+
+	env.loginAccount(t, testAccount, client)
+}
+
+// loginAccount logs the whatsapp account in with the provided whatsapp client.
+func (env *testEnv) loginAccount(t *testing.T, account string, client domain.WhatsappClient) {
+	t.Helper()
+
+	env.whatsappBackend.On("Login", mock.Anything, mock.MatchedBy(func(opts *domain.WhatsappSessionOpts) bool {
+		return opts.Account == account
+	}), mock.Anything).Return(client, nil).Once()
+	require.NoError(t, env.eventsHandler.HandleLoginEvent(&domain.LoginEvent{
+		ChatID:   testChatID,
+		FromUser: testUserName,
+		Account:  account,
+	}))
+	require.NoError(t, env.eventsHandler.HandleLoginResultEvent(env.loginResult(t)))
+	require.True(t, env.eventsHandler.IsLoggedIn(account))
+}
+
+// loginResult waits for the result of the login running in background.
+func (env *testEnv) loginResult(t *testing.T) *domain.LoginResultEvent {
+	t.Helper()
+
+	select {
+	case event := <-env.events:
+		result, ok := event.(*domain.LoginResultEvent)
+		require.True(t, ok, "unexpected event %T", event)
+
+		return result
+	case <-time.After(time.Second):
+		require.FailNow(t, "login hasn't completed")
+	}
+
+	return nil
+}
+
+// texts returns texts sent to telegram after the login message.
+func (env *testEnv) texts() []string {
+	texts := env.telegramClient.Texts()
+	for i, text := range texts {
+		if text == "Successfully logged in" {
+			return texts[i+1:]
+		}
+	}
+
+	return texts
+}
+
+// sentText returns an argument that matches the whatsapp text message,
+// its identifier is generated by the outbox, so it's not compared.
+func sentText(remoteJid, text string) interface{} {
+	return mock.MatchedBy(func(msg *domain.WhatsappTextMessage) bool {
+		return msg.RemoteJid == remoteJid && msg.Text == text
+	})
+}
 
 func TestEventsHandler(t *testing.T) {
 	t.Run("handle start event", func(t *testing.T) {
+		env := newTestEnv(t)
 
+		err := env.eventsHandler.HandleStartEvent(&domain.StartEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+		})
+		require.NoError(t, err)
+
+		sent := env.telegramClient.TextMessages()
+		require.Len(t, sent, 1)
+		assert.Equal(t, testChatID, sent[0].ChatID)
+		assert.Contains(t, sent[0].Text, "/login")
+	})
+
+	t.Run("handle help event", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		err := env.eventsHandler.HandleHelpEvent(&domain.HelpEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+		})
+		require.NoError(t, err)
+
+		texts := env.telegramClient.Texts()
+		require.Len(t, texts, 1)
+		assert.Contains(t, texts[0], "Supported commands")
 	})
 
 	t.Run("handle login event", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		whatsappClientMock := &mocks.WhatsappClient{}
+		env.whatsappBackend.On("Login", mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				opts := args.Get(1).(*domain.WhatsappSessionOpts)
+				assert.Equal(t, testChatID, opts.ChatID)
+
+				// Emulate QR-code challenge
+				qrCodes := args.Get(2).(chan<- domain.WhatsappQRCode)
+				qrCodes <- domain.WhatsappQRCode{Content: "test-qr-code", Timeout: time.Minute}
+			}).
+			Return(whatsappClientMock, nil)
+
+		err := env.eventsHandler.HandleLoginEvent(&domain.LoginEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+			Account:  testAccount,
+		})
+		require.NoError(t, err)
+
+		result := env.loginResult(t)
+		assert.Equal(t, testChatID, result.ChatID)
+		assert.Equal(t, whatsappClientMock, result.WhatsappClient)
+		require.NoError(t, env.eventsHandler.HandleLoginResultEvent(result))
 
+		assert.True(t, env.eventsHandler.IsLoggedIn(testAccount))
+		assert.Equal(t, []string{"Successfully logged in"}, env.telegramClient.Texts())
+
+		photos := env.telegramClient.PhotoMessages()
+		require.Len(t, photos, 1)
+		assert.Equal(t, "Scan the QR-code with WhatsApp on your phone, it expires in 1m0s", photos[0].Caption)
+		require.Len(t, photos[0].Buttons, 1)
+		assert.Equal(t, "Cancel login", photos[0].Buttons[0][0].Text)
+
+		// The cancel button is removed once the QR-code is scanned
+		captionEdits := env.telegramClient.CaptionEdits()
+		require.Len(t, captionEdits, 1)
+		assert.Equal(t, result.QRMessageID, captionEdits[0].MessageID)
+		assert.Equal(t, "The QR-code has been scanned", captionEdits[0].Caption)
+		assert.Empty(t, captionEdits[0].Buttons)
 	})
 
-	t.Run("handle reply event", func(t *testing.T) {
+	t.Run("handle login event, QR-code is refreshed", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		env.whatsappBackend.On("Login", mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				qrCodes := args.Get(2).(chan<- domain.WhatsappQRCode)
+				qrCodes <- domain.WhatsappQRCode{Content: "first-qr-code", Timeout: time.Minute}
+				qrCodes <- domain.WhatsappQRCode{Content: "second-qr-code", Timeout: time.Minute}
+			}).
+			Return(&mocks.WhatsappClient{}, nil)
+
+		require.NoError(t, env.eventsHandler.HandleLoginEvent(&domain.LoginEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+			Account:  testAccount,
+		}))
+		result := env.loginResult(t)
+
+		// The same photo is replaced with the new QR-code
+		photos := env.telegramClient.PhotoMessages()
+		require.Len(t, photos, 1)
+		photoEdits := env.telegramClient.PhotoEdits()
+		require.Len(t, photoEdits, 1)
+		assert.Equal(t, result.QRMessageID, photoEdits[0].MessageID)
+		assert.NotEqual(t, photos[0].Photo.Bytes, photoEdits[0].Photo.Bytes)
+		assert.Equal(t, photos[0].Buttons, photoEdits[0].Buttons)
+	})
+
+	t.Run("handle login event, login failed", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		env.whatsappBackend.On("Login", mock.Anything, mock.Anything, mock.Anything).
+			Return(nil, errTestSend)
+
+		err := env.eventsHandler.HandleLoginEvent(&domain.LoginEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+			Account:  testAccount,
+		})
+		require.NoError(t, err)
+
+		err = env.eventsHandler.HandleLoginResultEvent(env.loginResult(t))
+		assert.ErrorIs(t, err, errTestSend)
+
+		assert.False(t, env.eventsHandler.IsLoggedIn(testAccount))
+		texts := env.telegramClient.Texts()
+		require.Len(t, texts, 1)
+		assert.Contains(t, texts[0], "/login")
+	})
 
+	t.Run("handle login event, login is in progress", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		loginStarted := make(chan struct{})
+		env.whatsappBackend.On("Login", mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				close(loginStarted)
+				<-args.Get(0).(context.Context).Done()
+			}).
+			Return(nil, context.Canceled).Once()
+
+		loginEvent := &domain.LoginEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+			Account:  testAccount,
+		}
+		require.NoError(t, env.eventsHandler.HandleLoginEvent(loginEvent))
+		<-loginStarted
+
+		require.NoError(t, env.eventsHandler.HandleLoginEvent(loginEvent))
+		assert.Equal(t, []string{"Login is already in progress, scan the QR-code or cancel it"},
+			env.telegramClient.Texts())
+		env.whatsappBackend.AssertNumberOfCalls(t, "Login", 1)
+
+		// Let the login finish
+		require.NoError(t, env.eventsHandler.HandleCancelLoginEvent(&domain.CancelLoginEvent{
+			ChatID:     testChatID,
+			FromUser:   testUserName,
+			CallbackID: "test-callback-id",
+			Account:    testAccount,
+		}))
+		require.NoError(t, env.eventsHandler.HandleLoginResultEvent(env.loginResult(t)))
+	})
+
+	t.Run("handle cancel login event", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		env.whatsappBackend.On("Login", mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				qrCodes := args.Get(2).(chan<- domain.WhatsappQRCode)
+				qrCodes <- domain.WhatsappQRCode{Content: "test-qr-code", Timeout: time.Minute}
+
+				// Block until the login is cancelled
+				<-args.Get(0).(context.Context).Done()
+			}).
+			Return(nil, context.Canceled).Once()
+
+		require.NoError(t, env.eventsHandler.HandleLoginEvent(&domain.LoginEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+			Account:  testAccount,
+		}))
+		require.Eventually(t, func() bool {
+			return len(env.telegramClient.PhotoMessages()) == 1
+		}, time.Second, 10*time.Millisecond)
+
+		err := env.eventsHandler.HandleCancelLoginEvent(&domain.CancelLoginEvent{
+			ChatID:     testChatID,
+			FromUser:   testUserName,
+			CallbackID: "test-callback-id",
+			Account:    testAccount,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.TelegramCallbackAnswer{
+			{CallbackID: "test-callback-id", Text: "Cancelling login..."},
+		}, env.telegramClient.CallbackAnswers())
+
+		require.NoError(t, env.eventsHandler.HandleLoginResultEvent(env.loginResult(t)))
+		assert.False(t, env.eventsHandler.IsLoggedIn(testAccount))
+		assert.Equal(t, []string{"Login is cancelled, type /login to try again"}, env.telegramClient.Texts())
+
+		captionEdits := env.telegramClient.CaptionEdits()
+		require.Len(t, captionEdits, 1)
+		assert.Equal(t, "Login is cancelled", captionEdits[0].Caption)
+		assert.Empty(t, captionEdits[0].Buttons)
+
+		// The next login can be started
+		env.login(t, &mocks.WhatsappClient{})
+	})
+
+	t.Run("handle cancel login event, no login in progress", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		err := env.eventsHandler.HandleCancelLoginEvent(&domain.CancelLoginEvent{
+			ChatID:     testChatID,
+			FromUser:   testUserName,
+			CallbackID: "test-callback-id",
+			Account:    testAccount,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.TelegramCallbackAnswer{
+			{CallbackID: "test-callback-id", Text: "There is no login in progress"},
+		}, env.telegramClient.CallbackAnswers())
+	})
+
+	t.Run("handle login event, queued messages are sent", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		_, err := env.outbox.Enqueue(testChatID, testAccount, testRemoteJid, "queued message")
+		require.NoError(t, err)
+
+		whatsappClientMock := &mocks.WhatsappClient{}
+		whatsappClientMock.On("Send", mock.Anything).Return(nil)
+		env.login(t, whatsappClientMock)
+
+		whatsappClientMock.AssertCalled(t, "Send", sentText(testRemoteJid, "queued message"))
+		assert.Empty(t, env.outbox.Messages(testChatID))
+	})
+
+	t.Run("handle login event, invalid account name", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		err := env.eventsHandler.HandleLoginEvent(&domain.LoginEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+			Account:  "not valid",
+		})
+		require.NoError(t, err)
+
+		env.whatsappBackend.AssertNotCalled(t, "Login", mock.Anything, mock.Anything, mock.Anything)
+		texts := env.telegramClient.Texts()
+		require.Len(t, texts, 1)
+		assert.Contains(t, texts[0], "Account name may contain only")
+	})
+
+	t.Run("multiple accounts", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		personalClient := &mocks.WhatsappClient{}
+		workClient := &mocks.WhatsappClient{}
+		workClient.On("Send", mock.Anything).Return(nil)
+		env.login(t, personalClient)
+		env.loginAccount(t, "work", workClient)
+		assert.Contains(t, env.telegramClient.Texts(), "Successfully logged in [account: work]")
+
+		// Incoming messages are tagged with their account
+		err := env.eventsHandler.HandleTextMessageEvent(&domain.TextMessageEvent{
+			ChatID:             testChatID,
+			WhatsappRemoteJid:  testRemoteJid,
+			WhatsappSenderName: "test-sender",
+			Text:               "hello from work",
+			Account:            "work",
+		})
+		require.NoError(t, err)
+
+		texts := env.telegramClient.Texts()
+		incoming := texts[len(texts)-1]
+		assert.Contains(t, incoming, "[account: work]")
+		assert.Equal(t, "work", domain.ExtractMsgAccount(incoming))
+
+		// Replies are sent from the account the message came to
+		err = env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+			ChatID:    testChatID,
+			FromUser:  testUserName,
+			Reply:     "test reply",
+			RemoteJid: testRemoteJid,
+			Account:   domain.ExtractMsgAccount(incoming),
+		})
+		require.NoError(t, err)
+
+		workClient.AssertCalled(t, "Send", sentText(testRemoteJid, "test reply"))
+		personalClient.AssertNotCalled(t, "Send", mock.Anything)
+
+		// Logging out of one account keeps the other one
+		workClient.On("Logout").Return(nil)
+		require.NoError(t, env.eventsHandler.HandleLogoutEvent(&domain.LogoutEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+			Account:  "work",
+		}))
+		assert.False(t, env.eventsHandler.IsLoggedIn("work"))
+		assert.True(t, env.eventsHandler.IsLoggedIn(testAccount))
+	})
+
+	t.Run("handle reply event, account is not logged in", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, &mocks.WhatsappClient{})
+
+		err := env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+			ChatID:    testChatID,
+			FromUser:  testUserName,
+			Reply:     "test reply",
+			RemoteJid: testRemoteJid,
+			Account:   "work",
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{
+			"You're not logged in to WhatsApp [account: work], the message will be sent after /login work",
+		}, env.texts())
+		messages := env.outbox.Messages(testChatID)
+		require.Len(t, messages, 1)
+		assert.Equal(t, "work", messages[0].Account)
+	})
+
+	t.Run("handle repeated login event", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		err := env.eventsHandler.HandleRepeatedLoginEvent(&domain.LoginEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+			Account:  testAccount,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Already logged in"}, env.telegramClient.Texts())
+	})
+
+	t.Run("handle logout event", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		whatsappClientMock := &mocks.WhatsappClient{}
+		whatsappClientMock.On("Logout").Return(nil)
+		env.login(t, whatsappClientMock)
+
+		err := env.eventsHandler.HandleLogoutEvent(&domain.LogoutEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+			Account:  testAccount,
+		})
+		require.NoError(t, err)
+
+		whatsappClientMock.AssertCalled(t, "Logout")
+		assert.False(t, env.eventsHandler.IsLoggedIn(testAccount))
+		assert.Equal(t, []string{"Successfully logged out"}, env.texts())
+	})
+
+	t.Run("handle logout event, not logged in", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		err := env.eventsHandler.HandleLogoutEvent(&domain.LogoutEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+			Account:  testAccount,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Already logged out"}, env.telegramClient.Texts())
 	})
 
 	t.Run("handle text message event", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		err := env.eventsHandler.HandleTextMessageEvent(&domain.TextMessageEvent{
+			ChatID:             testChatID,
+			WhatsappRemoteJid:  testRemoteJid,
+			WhatsappSenderName: "test-sender",
+			Text:               "hello, world!",
+		})
+		require.NoError(t, err)
+
+		texts := env.telegramClient.Texts()
+		require.Len(t, texts, 1)
+		assert.Equal(t, testRemoteJid, domain.ExtractMsgJid(texts[0]))
+		assert.Contains(t, texts[0], "test-sender")
+		assert.Contains(t, texts[0], "hello, world!")
+	})
+
+	t.Run("handle reply event", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		whatsappClientMock := &mocks.WhatsappClient{}
+		whatsappClientMock.On("Send", mock.Anything).Return(nil)
+		env.login(t, whatsappClientMock)
+
+		err := env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+			ChatID:    testChatID,
+			FromUser:  testUserName,
+			Reply:     "test reply",
+			RemoteJid: testRemoteJid,
+			Account:   testAccount,
+		})
+		require.NoError(t, err)
+
+		whatsappClientMock.AssertCalled(t, "Send", sentText(testRemoteJid, "test reply"))
+		assert.Empty(t, env.outbox.Messages(testChatID))
+		assert.Empty(t, env.texts())
+	})
+
+	t.Run("handle reply event, not logged in", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		err := env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+			ChatID:    testChatID,
+			FromUser:  testUserName,
+			Reply:     "test reply",
+			RemoteJid: testRemoteJid,
+			Account:   testAccount,
+		})
+		require.NoError(t, err)
+
+		assert.Len(t, env.outbox.Messages(testChatID), 1)
+		texts := env.telegramClient.Texts()
+		require.Len(t, texts, 1)
+		assert.Contains(t, texts[0], "/login")
+	})
+
+	t.Run("handle reply event, message is postponed and then failed", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		whatsappClientMock := &mocks.WhatsappClient{}
+		whatsappClientMock.On("Send", mock.Anything).Return(errTestSend)
+		env.login(t, whatsappClientMock)
+
+		err := env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+			ChatID:    testChatID,
+			FromUser:  testUserName,
+			Reply:     "test reply",
+			RemoteJid: testRemoteJid,
+			Account:   testAccount,
+		})
+		require.NoError(t, err)
+
+		texts := env.texts()
+		require.Len(t, texts, 1)
+		assert.Contains(t, texts[0], "restored")
+
+		// The second attempt happens once the session is restored
+		err = env.eventsHandler.HandleRestoreEvent(&domain.RestoreEvent{ChatID: testChatID, Account: testAccount})
+		require.NoError(t, err)
+
+		sent := env.telegramClient.TextMessages()
+		failedNotification := sent[len(sent)-1]
+		assert.Equal(t, testRemoteJid, domain.ExtractMsgJid(failedNotification.Text))
+		assert.Contains(t, failedNotification.Text, errTestSend.Error())
+
+		messages := env.outbox.Messages(testChatID)
+		require.Len(t, messages, 1)
+		assert.True(t, messages[0].Failed)
+
+		// The notification offers to retry the message
+		require.Len(t, failedNotification.Buttons, 1)
+		require.Len(t, failedNotification.Buttons[0], 1)
+		assert.Equal(t,
+			domain.NewCallbackData(domain.RetryCallbackAction, messages[0].ID),
+			failedNotification.Buttons[0][0].CallbackData)
+	})
+
+	t.Run("handle retry event", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		var sendErr error = errTestSend
+		whatsappClientMock := &mocks.WhatsappClient{}
+		whatsappClientMock.On("Send", mock.Anything).Return(func(domain.WhatsappMessage) error {
+			return sendErr
+		})
+		env.login(t, whatsappClientMock)
+
+		for i := 0; i < 2; i++ {
+			err := env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+				ChatID:    testChatID,
+				FromUser:  testUserName,
+				Reply:     "test reply",
+				RemoteJid: testRemoteJid,
+				Account:   testAccount,
+			})
+			require.NoError(t, err)
+		}
+
+		messages := env.outbox.Messages(testChatID)
+		require.Len(t, messages, 2)
+		require.True(t, messages[0].Failed)
+
+		// WhatsApp is reachable again
+		sendErr = nil
+
+		err := env.eventsHandler.HandleRetryEvent(&domain.RetryEvent{
+			ChatID:     testChatID,
+			FromUser:   testUserName,
+			CallbackID: "test-callback-id",
+			MessageID:  messages[0].ID,
+		})
+		require.NoError(t, err)
+
+		assert.Empty(t, env.outbox.Messages(testChatID))
+
+		answers := env.telegramClient.CallbackAnswers()
+		require.Len(t, answers, 1)
+		assert.Equal(t, "test-callback-id", answers[0].CallbackID)
+	})
+
+	t.Run("handle retry event, unknown message", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		err := env.eventsHandler.HandleRetryEvent(&domain.RetryEvent{
+			ChatID:     testChatID,
+			FromUser:   testUserName,
+			CallbackID: "test-callback-id",
+			MessageID:  "unknown",
+		})
+		require.NoError(t, err)
+
+		answers := env.telegramClient.CallbackAnswers()
+		require.Len(t, answers, 1)
+		assert.Contains(t, answers[0].Text, "already")
+	})
+
+	t.Run("handle disconnect event", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		whatsappClientMock := &mocks.WhatsappClient{}
+		whatsappClientMock.On("Logout").Return(nil)
+		env.login(t, whatsappClientMock)
+
+		err := env.eventsHandler.HandleDisconnectEvent(&domain.DisconnectEvent{ChatID: testChatID, Account: testAccount})
+		require.NoError(t, err)
+
+		whatsappClientMock.AssertCalled(t, "Logout")
+		assert.False(t, env.eventsHandler.IsLoggedIn(testAccount))
+
+		texts := env.texts()
+		require.Len(t, texts, 1)
+		assert.Contains(t, texts[0], "login")
+	})
+
+	t.Run("telegram is not available", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.telegramClient.SetError(errTestSend)
 
+		err := env.eventsHandler.HandleStartEvent(&domain.StartEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+		})
+		assert.ErrorIs(t, err, errTestSend)
 	})
 }
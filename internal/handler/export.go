@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/export"
+	"go.uber.org/zap"
+)
+
+const (
+	exportUnsupportedMsg = "Export is not supported"
+	exportUsageMsg       = "Usage: /export <contact> [from] [to], or reply to a message with /export [from] [to] " +
+		"to export its conversation. Dates look like 2006-01-02"
+	exportNotFoundFmt    = "There are no messages with %s to export"
+	invalidExportDateFmt = "Invalid date %q, use 2006-01-02"
+	exportRangeFmt       = "The last date %s is before the first one %s"
+	exportTooLargeFmt    = "The export is %d MB, it's too large to upload to Telegram, use twbridge export on the server"
+	exportCaptionFmt     = "Conversation with %s, %d messages (%s)"
+	exportFileNameFmt    = "export-%s-%s.zip"
+	exportFileDateLayout = "20060102"
+
+	// maxTelegramDocumentSize is a maximum size of a document uploaded by a bot.
+	maxTelegramDocumentSize = 50 << 20
+)
+
+// HandleExportEvent method handles export event.
+func (eh *EventsHandler) HandleExportEvent(event *domain.ExportEvent) error {
+	eh.log.Debug("handle export event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.String("contact", event.Contact),
+		zap.String("from", event.From),
+		zap.String("to", event.To))
+
+	if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+		return err
+	}
+
+	doc, msg, err := eh.exportDocument(event)
+	if err != nil {
+		return err
+	}
+
+	if doc == nil {
+		if err := eh.notifyTelegram(msg); err != nil {
+			return fmt.Errorf("failed to notify telegram: %w", err)
+		}
+
+		return nil
+	}
+
+	if _, err := eh.telegramClient.SendDocument(doc); err != nil {
+		return fmt.Errorf("failed to send document to telegram: %w", err)
+	}
+
+	return nil
+}
+
+// exportDocument exports the conversation and returns a document to send,
+// a message to reply with is returned instead if there is nothing to export.
+func (eh *EventsHandler) exportDocument(event *domain.ExportEvent) (*domain.TelegramDocumentMessage, string, error) {
+	if eh.archive == nil {
+		return nil, exportUnsupportedMsg, nil
+	}
+	if event.Contact == "" {
+		return nil, exportUsageMsg, nil
+	}
+
+	loc, timeZone := eh.chatTimeZone()
+	from, to, msg := parseExportRange(event, loc)
+	if msg != "" {
+		return nil, msg, nil
+	}
+
+	messages := export.Select(eh.archive.Messages(eh.chatID), from, to, eh.archiveContactMatcher(event.Contact))
+	if len(messages) == 0 {
+		return nil, fmt.Sprintf(exportNotFoundFmt, event.Contact), nil
+	}
+
+	title := exportTitle(event.Contact, messages)
+	var buf bytes.Buffer
+	err := export.Write(&buf, &export.Opts{
+		Title:    title,
+		Location: loc,
+		Messages: messages,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to export messages: %w", err)
+	}
+	if buf.Len() > maxTelegramDocumentSize {
+		return nil, fmt.Sprintf(exportTooLargeFmt, buf.Len()>>20), nil
+	}
+
+	return &domain.TelegramDocumentMessage{
+		ChatID: eh.chatID,
+		Document: domain.TelegramFile{
+			Name:  fmt.Sprintf(exportFileNameFmt, exportFileSlug(title), eh.now().In(loc).Format(exportFileDateLayout)),
+			Bytes: buf.Bytes(),
+		},
+		Caption: fmt.Sprintf(exportCaptionFmt, title, len(messages), timeZone),
+	}, "", nil
+}
+
+// parseExportRange returns the time range of the export, the last date is included.
+// A message to reply with is returned if the range is invalid.
+func parseExportRange(event *domain.ExportEvent, loc *time.Location) (from, to time.Time, msg string) {
+	var err error
+	if event.From != "" {
+		if from, err = export.ParseDate(event.From, loc); err != nil {
+			return time.Time{}, time.Time{}, fmt.Sprintf(invalidExportDateFmt, event.From)
+		}
+	}
+	if event.To != "" {
+		if to, err = export.ParseDate(event.To, loc); err != nil {
+			return time.Time{}, time.Time{}, fmt.Sprintf(invalidExportDateFmt, event.To)
+		}
+		if to.Before(from) {
+			return time.Time{}, time.Time{}, fmt.Sprintf(exportRangeFmt, event.To, event.From)
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+
+	return from, to, ""
+}
+
+// exportTitle returns a title of the exported conversation, the name the contact
+// has sent the messages with is used if the contact is a jid or a phone number.
+func exportTitle(contact string, messages []domain.ArchivedMessage) string {
+	if queryJid(contact) == "" {
+		return contact
+	}
+
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Direction == domain.MessageDirectionIn && messages[i].SenderName != "" {
+			return fmt.Sprintf("%s (%s)", messages[i].SenderName, messages[i].RemoteJid)
+		}
+	}
+
+	return contact
+}
+
+// exportFileSlug returns the title reduced to characters that are safe in file names.
+func exportFileSlug(title string) string {
+	words := strings.FieldsFunc(title, func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9')
+	})
+
+	return strings.Join(words, "_")
+}
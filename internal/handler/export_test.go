@@ -0,0 +1,117 @@
+package handler_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/files"
+	"github.com/dstdfx/twbridge/internal/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exportCommand handles the export command.
+func (env *testEnv) exportCommand(t *testing.T, contact, from, to string) {
+	t.Helper()
+
+	require.NoError(t, env.eventsHandler.HandleExportEvent(&domain.ExportEvent{
+		ChatID:   testChatID,
+		FromUser: testUserName,
+		Contact:  contact,
+		From:     from,
+		To:       to,
+	}))
+}
+
+func TestEventsHandlerExport(t *testing.T) {
+	t.Run("export command", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+		require.NoError(t, env.quietHours.Set(domain.QuietHours{ChatID: testChatID, TimeZone: "UTC"}))
+
+		env.clock.now = time.Date(2022, 3, 30, 10, 0, 0, 0, time.UTC)
+		env.receive(t, "alice-jid", "Alice", "hi")
+		env.clock.now = time.Date(2022, 3, 31, 10, 0, 0, 0, time.UTC)
+		env.receive(t, "alice-jid", "Alice", "lunch?")
+		env.receive(t, "bob-jid", "Bob", "hey")
+
+		env.exportCommand(t, "", "", "")
+		assert.Contains(t, env.lastText(t), "Usage: /export <contact> [from] [to]")
+
+		env.exportCommand(t, "Carol", "", "")
+		assert.Equal(t, "There are no messages with Carol to export", env.lastText(t))
+
+		env.exportCommand(t, "Alice", "31.03.2022", "")
+		assert.Equal(t, `Invalid date "31.03.2022", use 2006-01-02`, env.lastText(t))
+
+		env.exportCommand(t, "Alice", "2022-03-31", "2022-03-30")
+		assert.Equal(t, "The last date 2022-03-30 is before the first one 2022-03-31", env.lastText(t))
+
+		env.exportCommand(t, "Alice", "2022-03-31", "2022-03-31")
+		documents := env.telegramClient.Documents()
+		require.Len(t, documents, 1)
+		assert.Equal(t, testChatID, documents[0].ChatID)
+		assert.Equal(t, "export-Alice-20220331.zip", documents[0].Document.Name)
+		assert.Equal(t, "Conversation with Alice, 1 messages (UTC)", documents[0].Caption)
+
+		raw := documents[0].Document.Bytes
+		zr, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+		require.NoError(t, err)
+		names := make([]string, 0, len(zr.File))
+		for _, file := range zr.File {
+			names = append(names, file.Name)
+		}
+		assert.ElementsMatch(t, []string{"transcript.json", "transcript.csv", "transcript.html"}, names)
+
+		// The whole conversation is exported without dates
+		env.exportCommand(t, "alice", "", "")
+		documents = env.telegramClient.Documents()
+		require.Len(t, documents, 2)
+		assert.Equal(t, "Conversation with alice, 2 messages (UTC)", documents[1].Caption)
+
+		// The conversation of the jid is named after the contact
+		env.receive(t, "carol@s.whatsapp.net", "Carol", "hello")
+		env.exportCommand(t, "carol@s.whatsapp.net", "", "")
+		documents = env.telegramClient.Documents()
+		require.Len(t, documents, 3)
+		assert.Equal(t, "export-Carol_carol_s_whatsapp_net-20220331.zip", documents[2].Document.Name)
+		assert.Equal(t, "Conversation with Carol (carol@s.whatsapp.net), 1 messages (UTC)", documents[2].Caption)
+	})
+	t.Run("stored documents are exported", func(t *testing.T) {
+		env := newTestEnv(t, func(opts *handler.Opts) {
+			store, err := files.New(&files.Opts{
+				Dir:     t.TempDir(),
+				BaseURL: "https://bridge.example.com/files",
+				Clock:   opts.Clock,
+			})
+			require.NoError(t, err)
+			opts.Files = store
+			opts.MaxUploadSize = 3
+		})
+		env.login(t, newContactsClient())
+
+		env.document(t, testDocument)
+		archived := env.lastArchived(t)
+		require.Len(t, archived.Media, 1)
+		assert.NotEmpty(t, archived.Media[0].Path)
+
+		env.exportCommand(t, "Alice", "", "")
+		documents := env.telegramClient.Documents()
+		require.Len(t, documents, 1)
+
+		raw := documents[0].Document.Bytes
+		zr, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+		require.NoError(t, err)
+		file, err := zr.Open(fmt.Sprintf("media/%d-1-report.pdf", archived.ID))
+		require.NoError(t, err)
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		require.NoError(t, err)
+		assert.Equal(t, "%PDF", string(data))
+	})
+}
@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/dstdfx/twbridge/internal/archive"
+	"github.com/dstdfx/twbridge/internal/domain"
+	"go.uber.org/zap"
+)
+
+const (
+	historyUsageMsg = "Usage: /history <contact> [n], or reply to a message with /history [n] " +
+		"to load the last messages of its conversation"
+	invalidHistoryCountFmt = "Invalid number of messages %q, use a number from 1 to %d"
+	severalHistoryFmt      = "Several contacts match %q, use a phone number or a jid"
+	historyLoadedFmt       = "Loaded %d messages of %s from WhatsApp history"
+	historyEmptyFmt        = "There are no new messages of %s in WhatsApp history"
+	historyTagFmt          = " [history %s]"
+	historyTimeLayout      = "Jan 2 15:04"
+	historyYouName         = "You"
+
+	defaultHistoryMessages = 20
+	maxHistoryMessages     = 100
+)
+
+// HandleHistoryEvent method handles history event.
+func (eh *EventsHandler) HandleHistoryEvent(event *domain.HistoryEvent) error {
+	eh.log.Debug("handle history event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.String("contact", event.Contact),
+		zap.String("remote_jid", event.RemoteJid),
+		zap.String("account", event.Account))
+
+	if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+		return err
+	}
+
+	msg, err := eh.applyHistoryCommand(event)
+	if err != nil {
+		return err
+	}
+
+	if err := eh.notifyTelegram(msg); err != nil {
+		return fmt.Errorf("failed to notify telegram: %w", err)
+	}
+
+	return nil
+}
+
+// applyHistoryCommand posts the last messages of the conversation and returns a message to reply with.
+func (eh *EventsHandler) applyHistoryCommand(event *domain.HistoryEvent) (string, error) {
+	if event.RemoteJid == "" && event.Contact == "" {
+		return historyUsageMsg, nil
+	}
+
+	count := defaultHistoryMessages
+	if event.Count != "" {
+		n, err := strconv.Atoi(event.Count)
+		if err != nil || n < 1 || n > maxHistoryMessages {
+			return fmt.Sprintf(invalidHistoryCountFmt, event.Count, maxHistoryMessages), nil
+		}
+		count = n
+	}
+
+	account, remoteJid := event.Account, event.RemoteJid
+	if remoteJid == "" {
+		accounts := eh.loggedInAccounts()
+		if len(accounts) == 0 {
+			return fmt.Sprintf(chatNotLoggedInFmt, loginCommand(domain.DefaultWhatsappAccount)), nil
+		}
+
+		matches := eh.findContacts(accounts, event.Contact)
+		switch len(matches) {
+		case 0:
+			return fmt.Sprintf(noContactsFmt, event.Contact), nil
+		case 1:
+			account, remoteJid = matches[0].account, matches[0].jid
+		default:
+			return fmt.Sprintf(severalHistoryFmt, event.Contact), nil
+		}
+	}
+
+	whatsappClient, ok := eh.whatsappClient(account)
+	if !ok {
+		return fmt.Sprintf(chatNotLoggedInFmt, loginCommand(account)), nil
+	}
+
+	name := eh.contactName(account, remoteJid)
+	posted, err := eh.postHistory(account, whatsappClient, remoteJid, name, count)
+	if err != nil {
+		return "", err
+	}
+	if posted == 0 {
+		return fmt.Sprintf(historyEmptyFmt, name), nil
+	}
+
+	return fmt.Sprintf(historyLoadedFmt, posted, name), nil
+}
+
+// backfillHistory posts the last messages of the recent chats of the account once it's logged in,
+// so the conversations don't start empty. Errors are only logged, the login has succeeded anyway.
+func (eh *EventsHandler) backfillHistory(account string, whatsappClient domain.WhatsappClient) {
+	if eh.historyChats == 0 || eh.historyMessages == 0 {
+		return
+	}
+
+	chats, err := whatsappClient.RecentChats(eh.historyChats)
+	if err != nil {
+		eh.log.Error("failed to load recent whatsapp chats", zap.String("account", account), zap.Error(err))
+
+		return
+	}
+
+	// The most recent chat is posted last, so it ends up at the bottom of the chat
+	for i := len(chats) - 1; i >= 0; i-- {
+		mode := eh.contactMode(account, chats[i].Jid)
+		if mode == domain.ContactModeMute || mode == domain.ContactModeBlock {
+			continue
+		}
+
+		name := chats[i].Name
+		if name == "" {
+			name = eh.contactName(account, chats[i].Jid)
+		}
+
+		if _, err := eh.postHistory(account, whatsappClient, chats[i].Jid, name, eh.historyMessages); err != nil {
+			eh.log.Error("failed to backfill whatsapp history",
+				zap.String("remote_jid", chats[i].Jid),
+				zap.String("account", account),
+				zap.Error(err))
+		}
+	}
+}
+
+// postHistory loads the last messages of the conversation from whatsapp history and posts them
+// to the conversation marked as history, so replies to them are sent to the conversation.
+// Messages that have been bridged already are skipped. The number of posted messages is returned.
+func (eh *EventsHandler) postHistory(account string, whatsappClient domain.WhatsappClient,
+	remoteJid, name string, count int) (int, error) {
+	messages, err := whatsappClient.History(remoteJid, count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load whatsapp history: %w", err)
+	}
+
+	loc, _ := eh.chatTimeZone()
+	posted := 0
+	for _, msg := range messages {
+		if eh.historyArchived(account, remoteJid, msg.ID) {
+			continue
+		}
+
+		direction, sender := domain.MessageDirectionIn, msg.SenderName
+		switch {
+		case msg.FromMe:
+			direction, sender = domain.MessageDirectionOut, historyYouName
+		case sender == "":
+			sender = name
+		}
+
+		text := fmt.Sprintf(domain.TextMessageFmt,
+			sender,
+			remoteJid,
+			domain.AccountTag(account)+fmt.Sprintf(historyTagFmt, msg.Timestamp.In(loc).Format(historyTimeLayout)),
+			msg.Text)
+		postedMsg, err := eh.deliverConversationMessage(account, remoteJid, name, domain.TelegramTextMessage{
+			Text:                text,
+			DisableNotification: true,
+		})
+		if err != nil {
+			return posted, fmt.Errorf("failed to notify telegram: %w", err)
+		}
+		posted++
+
+		archived := domain.ArchivedMessage{
+			Direction:         direction,
+			Account:           account,
+			RemoteJid:         remoteJid,
+			SenderName:        msg.SenderName,
+			Text:              msg.Text,
+			WhatsappMessageID: msg.ID,
+			Timestamp:         msg.Timestamp,
+		}
+		if direction == domain.MessageDirectionIn && archived.SenderName == "" {
+			archived.SenderName = name
+		}
+		eh.archiveMessage(archived, postedMsg)
+	}
+
+	return posted, nil
+}
+
+// historyArchived returns true if the whatsapp message has been archived already.
+func (eh *EventsHandler) historyArchived(account, remoteJid, messageID string) bool {
+	if eh.archive == nil || messageID == "" {
+		return false
+	}
+
+	found := eh.archive.Search(&archive.Query{
+		ChatID: eh.chatID,
+		Match: func(msg *domain.ArchivedMessage) bool {
+			return msg.WhatsappMessageID == messageID && msg.Account == account && msg.RemoteJid == remoteJid
+		},
+		Limit: 1,
+	})
+
+	return len(found) > 0
+}
@@ -0,0 +1,138 @@
+package handler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/archive"
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testHistoryTime = time.Date(2022, 3, 31, 10, 0, 0, 0, time.UTC)
+
+// history handles the history command.
+func (env *testEnv) history(t *testing.T, event *domain.HistoryEvent) {
+	t.Helper()
+
+	event.ChatID = testChatID
+	event.FromUser = testUserName
+	require.NoError(t, env.eventsHandler.HandleHistoryEvent(event))
+}
+
+// historyMessages returns messages of the conversation as they are loaded from whatsapp history.
+func historyMessages(remoteJid, senderName string, texts ...string) []domain.WhatsappHistoryMessage {
+	messages := make([]domain.WhatsappHistoryMessage, 0, len(texts))
+	for i, text := range texts {
+		messages = append(messages, domain.WhatsappHistoryMessage{
+			ID:         remoteJid + "-" + text,
+			RemoteJid:  remoteJid,
+			SenderName: senderName,
+			Text:       text,
+			Timestamp:  testHistoryTime.Add(time.Duration(i) * time.Minute),
+		})
+	}
+
+	return messages
+}
+
+func TestEventsHandlerHistory(t *testing.T) {
+	t.Run("history command", func(t *testing.T) {
+		env := newTestEnv(t)
+		require.NoError(t, env.quietHours.Set(domain.QuietHours{ChatID: testChatID, TimeZone: "UTC"}))
+
+		env.history(t, &domain.HistoryEvent{Contact: "Alice"})
+		assert.Equal(t, "You're not logged in to WhatsApp, type /login first", env.lastText(t))
+
+		whatsappClientMock := newContactsClient()
+		messages := historyMessages("alice-jid", "Alice", "hi", "lunch?")
+		messages[1].FromMe = true
+		whatsappClientMock.On("History", "alice-jid", 20).Return(messages, nil)
+		whatsappClientMock.On("History", "bob-jid", 5).Return([]domain.WhatsappHistoryMessage{}, nil)
+		env.login(t, whatsappClientMock)
+
+		env.history(t, &domain.HistoryEvent{})
+		assert.Contains(t, env.lastText(t), "Usage: /history <contact> [n]")
+
+		env.history(t, &domain.HistoryEvent{Contact: "Alice", Count: "1000"})
+		assert.Equal(t, `Invalid number of messages "1000", use a number from 1 to 100`, env.lastText(t))
+
+		env.history(t, &domain.HistoryEvent{Contact: "Smith"})
+		assert.Equal(t, `Several contacts match "Smith", use a phone number or a jid`, env.lastText(t))
+
+		env.history(t, &domain.HistoryEvent{Contact: "Alice"})
+		posted := env.telegramClient.TextMessages()
+		require.GreaterOrEqual(t, len(posted), 3)
+		posted = posted[len(posted)-3:]
+		assert.Equal(t, "From: Alice [jid: alice-jid] [history Mar 31 10:00] \n= = = = = = = = = = = =\nMessage: hi",
+			posted[0].Text)
+		assert.True(t, posted[0].DisableNotification)
+		assert.Equal(t, "From: You [jid: alice-jid] [history Mar 31 10:01] \n= = = = = = = = = = = =\nMessage: lunch?",
+			posted[1].Text)
+		assert.Equal(t, "Loaded 2 messages of Alice from WhatsApp history", posted[2].Text)
+
+		// Replies to the history are sent to the conversation
+		assert.Equal(t, "alice-jid", domain.ExtractMsgJid(posted[1].Text))
+
+		archived := env.archive.Search(&archive.Query{ChatID: testChatID})
+		require.Len(t, archived, 2)
+		assert.Equal(t, domain.MessageDirectionOut, archived[0].Direction)
+		assert.Equal(t, "alice-jid-lunch?", archived[0].WhatsappMessageID)
+		assert.Equal(t, testHistoryTime.Add(time.Minute), archived[0].Timestamp)
+		assert.Equal(t, "Alice", archived[1].SenderName)
+
+		// Messages that have been bridged already are skipped
+		env.history(t, &domain.HistoryEvent{Contact: "Alice"})
+		assert.Equal(t, "There are no new messages of Alice in WhatsApp history", env.lastText(t))
+
+		env.history(t, &domain.HistoryEvent{RemoteJid: "bob-jid", Account: testAccount, Count: "5"})
+		assert.Equal(t, "There are no new messages of Bob in WhatsApp history", env.lastText(t))
+	})
+
+	t.Run("received messages are not repeated", func(t *testing.T) {
+		env := newTestEnv(t)
+		whatsappClientMock := newContactsClient()
+		whatsappClientMock.On("History", "alice-jid", 20).
+			Return(historyMessages("alice-jid", "Alice", "hi", "lunch?"), nil)
+		env.login(t, whatsappClientMock)
+
+		require.NoError(t, env.eventsHandler.HandleTextMessageEvent(&domain.TextMessageEvent{
+			ChatID:             testChatID,
+			WhatsappRemoteJid:  "alice-jid",
+			WhatsappSenderName: "Alice",
+			Text:               "lunch?",
+			Account:            testAccount,
+			WhatsappMessageID:  "alice-jid-lunch?",
+		}))
+
+		env.history(t, &domain.HistoryEvent{Contact: "Alice"})
+		assert.Equal(t, "Loaded 1 messages of Alice from WhatsApp history", env.lastText(t))
+	})
+
+	t.Run("history is loaded on login", func(t *testing.T) {
+		env := newTestEnv(t, func(opts *handler.Opts) {
+			opts.HistoryChats = 3
+			opts.HistoryMessages = 5
+		})
+		require.NoError(t, env.settings.SetMode(testChatID, testAccount, "carol-jid", domain.ContactModeMute))
+
+		whatsappClientMock := newContactsClient()
+		whatsappClientMock.On("RecentChats", 3).Return([]domain.WhatsappChat{
+			{Jid: "bob-jid", Name: "Bob"},
+			{Jid: "carol-jid", Name: "Carol Smith"},
+			{Jid: "alice-jid"},
+		}, nil)
+		whatsappClientMock.On("History", "alice-jid", 5).Return(historyMessages("alice-jid", "", "hi"), nil)
+		whatsappClientMock.On("History", "bob-jid", 5).Return(historyMessages("bob-jid", "Bob", "hey"), nil)
+		env.login(t, whatsappClientMock)
+
+		// The most recent chat is the last one, muted chats are skipped
+		texts := env.texts()
+		require.Len(t, texts, 2)
+		assert.Contains(t, texts[0], "From: Alice [jid: alice-jid] [history")
+		assert.Contains(t, texts[1], "From: Bob [jid: bob-jid] [history")
+		whatsappClientMock.AssertNotCalled(t, "History", "carol-jid", 5)
+	})
+}
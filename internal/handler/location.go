@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"go.uber.org/zap"
+)
+
+// liveLocationPeriod is a period a live location is shown for in telegram, whatsapp
+// shares live locations for 8 hours at most.
+const liveLocationPeriod = 8 * time.Hour
+
+// liveLocationKey identifies a live location shared by a whatsapp contact, a contact
+// shares one live location to a conversation at a time.
+type liveLocationKey struct {
+	account   string
+	remoteJid string
+	senderJid string
+}
+
+// liveLocation represents a telegram live location that is updated by the contact.
+type liveLocation struct {
+	chatID    int64
+	messageID int
+	expiresAt time.Time
+
+	// whatsappMessageID and sequence identify the latest update of the share,
+	// older updates are ignored and the sequence starts over once it's shared again.
+	whatsappMessageID string
+	sequence          int64
+}
+
+// HandleLocationEvent method handles location event. The location is bridged like a text
+// message with a link to the map, so it's archived and can be replied to, and the location
+// itself is posted as a reply to it. Live locations are moved once the contact moves,
+// the previous live location of the contact is stopped once the location is shared again.
+func (eh *EventsHandler) HandleLocationEvent(event *domain.LocationEvent) error {
+	eh.log.Debug("handle location event",
+		zap.String("remote_jid", event.WhatsappRemoteJid),
+		zap.Bool("live", event.Live),
+		zap.String("account", event.Account))
+
+	key := liveLocationKey{
+		account:   event.Account,
+		remoteJid: event.WhatsappRemoteJid,
+		senderJid: event.WhatsappSenderJid,
+	}
+	if event.Live && eh.moveLiveLocation(key, event) {
+		return nil
+	}
+
+	posted, err := eh.handleIncomingMessage(&domain.TextMessageEvent{
+		ChatID:             event.ChatID,
+		WhatsappRemoteJid:  event.WhatsappRemoteJid,
+		WhatsappSenderName: event.WhatsappSenderName,
+		WhatsappSenderJid:  event.WhatsappSenderJid,
+		WhatsappMessageID:  event.WhatsappMessageID,
+		Text:               domain.LocationText(event.Location, event.Live),
+		Account:            event.Account,
+	}, domain.MessageTypeLocation, nil)
+	// Locations collected to the digest or dropped are left as links
+	if err != nil || posted.messageID == 0 {
+		return err
+	}
+
+	location := &domain.TelegramLocationMessage{
+		ChatID:              posted.chatID,
+		Latitude:            event.Location.Latitude,
+		Longitude:           event.Location.Longitude,
+		Title:               event.Location.Name,
+		Address:             event.Location.Address,
+		DisableNotification: true,
+		ReplyToMessageID:    posted.messageID,
+	}
+	if event.Live {
+		location.LivePeriod = int(liveLocationPeriod.Seconds())
+	}
+
+	messageID, err := eh.telegramClient.SendLocation(location)
+	if err != nil {
+		return fmt.Errorf("failed to send location to telegram: %w", err)
+	}
+
+	if event.Live {
+		eh.mu.Lock()
+		eh.liveLocations[key] = liveLocation{
+			chatID:            posted.chatID,
+			messageID:         messageID,
+			expiresAt:         eh.now().Add(liveLocationPeriod),
+			whatsappMessageID: event.WhatsappMessageID,
+			sequence:          event.LiveSequence,
+		}
+		eh.mu.Unlock()
+	}
+
+	return nil
+}
+
+// moveLiveLocation moves the telegram live location of the contact, false is returned
+// if there is no live location to move, e.g. it has expired or the location is shared again.
+// Outdated updates are ignored, true is returned for them too.
+func (eh *EventsHandler) moveLiveLocation(key liveLocationKey, event *domain.LocationEvent) bool {
+	eh.mu.Lock()
+	live, ok := eh.liveLocations[key]
+	if !ok {
+		eh.mu.Unlock()
+
+		return false
+	}
+
+	switch {
+	case !eh.now().Before(live.expiresAt):
+		// Telegram has stopped the live location by itself
+		delete(eh.liveLocations, key)
+		eh.mu.Unlock()
+
+		return false
+	case event.LiveSequence <= live.sequence && event.WhatsappMessageID != live.whatsappMessageID:
+		// The sequence starts over, so it's a new share
+		delete(eh.liveLocations, key)
+		eh.mu.Unlock()
+		eh.stopLiveLocation(key, live)
+
+		return false
+	case event.LiveSequence <= live.sequence:
+		eh.mu.Unlock()
+		eh.log.Debug("skip outdated live location",
+			zap.String("remote_jid", key.remoteJid),
+			zap.Int64("sequence", event.LiveSequence))
+
+		return true
+	}
+
+	live.whatsappMessageID = event.WhatsappMessageID
+	live.sequence = event.LiveSequence
+	eh.liveLocations[key] = live
+	eh.mu.Unlock()
+
+	// Errors are only logged, e.g. telegram refuses updates to the same place
+	if err := eh.telegramClient.EditLocation(&domain.TelegramEditLocationMessage{
+		ChatID:    live.chatID,
+		MessageID: live.messageID,
+		Latitude:  event.Location.Latitude,
+		Longitude: event.Location.Longitude,
+	}); err != nil {
+		eh.log.Debug("failed to move live location",
+			zap.String("remote_jid", key.remoteJid),
+			zap.String("account", key.account),
+			zap.Error(err))
+	}
+
+	return true
+}
+
+// endLiveLocation stops the telegram live location shared by the whatsapp message,
+// e.g. the contact has deleted it, nothing is done if the location isn't live.
+func (eh *EventsHandler) endLiveLocation(account, remoteJid, whatsappMessageID string) {
+	eh.mu.Lock()
+	var (
+		key   liveLocationKey
+		live  liveLocation
+		found bool
+	)
+	for k, l := range eh.liveLocations {
+		if k.account == account && k.remoteJid == remoteJid && l.whatsappMessageID == whatsappMessageID {
+			key, live, found = k, l, true
+			delete(eh.liveLocations, k)
+
+			break
+		}
+	}
+	eh.mu.Unlock()
+
+	if found {
+		eh.stopLiveLocation(key, live)
+	}
+}
+
+// stopLiveLocation stops updating the telegram live location, so it stays at the last place
+// instead of looking live until it expires.
+func (eh *EventsHandler) stopLiveLocation(key liveLocationKey, live liveLocation) {
+	// Errors are only logged, e.g. telegram has already stopped the location
+	if err := eh.telegramClient.StopLocation(live.chatID, live.messageID); err != nil {
+		eh.log.Debug("failed to stop live location",
+			zap.String("remote_jid", key.remoteJid),
+			zap.String("account", key.account),
+			zap.Error(err))
+	}
+}
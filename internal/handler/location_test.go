@@ -0,0 +1,185 @@
+package handler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testLocation is a location shared in the tests.
+var testLocation = domain.WhatsappLocation{
+	Latitude:  55.751244,
+	Longitude: 37.618423,
+	Name:      "Cafe",
+	Address:   "1 Main St",
+}
+
+// location handles the location shared by Alice.
+func (env *testEnv) location(t *testing.T, location domain.WhatsappLocation, live bool) {
+	t.Helper()
+
+	require.NoError(t, env.eventsHandler.HandleLocationEvent(&domain.LocationEvent{
+		ChatID:             testChatID,
+		WhatsappRemoteJid:  "alice-jid",
+		WhatsappSenderName: "Alice",
+		WhatsappMessageID:  "alice-location-id",
+		Location:           location,
+		Live:               live,
+		Account:            testAccount,
+	}))
+}
+
+// liveLocation handles the update of the live location shared by Alice with the message.
+func (env *testEnv) liveLocation(t *testing.T, location domain.WhatsappLocation, messageID string, sequence int64) {
+	t.Helper()
+
+	require.NoError(t, env.eventsHandler.HandleLocationEvent(&domain.LocationEvent{
+		ChatID:             testChatID,
+		WhatsappRemoteJid:  "alice-jid",
+		WhatsappSenderName: "Alice",
+		WhatsappMessageID:  messageID,
+		Location:           location,
+		Live:               true,
+		LiveSequence:       sequence,
+		Account:            testAccount,
+	}))
+}
+
+func TestEventsHandlerLocation(t *testing.T) {
+	t.Run("location", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+
+		env.location(t, testLocation, false)
+		assert.Equal(t, "From: Alice [jid: alice-jid] \n= = = = = = = = = = = =\nMessage: "+
+			"📍 Location: Cafe, 1 Main St\nhttps://maps.google.com/?q=55.751244,37.618423", env.lastText(t))
+
+		// The location replies to the message, so it's clear who has shared it
+		assert.Equal(t, []domain.TelegramLocationMessage{{
+			ChatID:              testChatID,
+			Latitude:            55.751244,
+			Longitude:           37.618423,
+			Title:               "Cafe",
+			Address:             "1 Main St",
+			DisableNotification: true,
+			ReplyToMessageID:    len(env.telegramClient.Texts()),
+		}}, env.telegramClient.Locations())
+
+		archived := env.lastArchived(t)
+		assert.Equal(t, "alice-location-id", archived.WhatsappMessageID)
+		assert.Contains(t, archived.Text, "📍 Location: Cafe, 1 Main St")
+	})
+
+	t.Run("live location", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+		env.clock.now = testHistoryTime
+
+		live := domain.WhatsappLocation{Latitude: 55.75, Longitude: 37.62}
+		env.liveLocation(t, live, "alice-live-id", 1)
+		assert.Contains(t, env.lastText(t), "📍 Live location\nhttps://maps.google.com/?q=55.750000,37.620000")
+		locations := env.telegramClient.Locations()
+		require.Len(t, locations, 1)
+		assert.Equal(t, 8*60*60, locations[0].LivePeriod)
+		liveMessageID := locations[0].ReplyToMessageID + 1
+
+		// The live location is moved, nothing is posted
+		texts := len(env.telegramClient.Texts())
+		env.liveLocation(t, domain.WhatsappLocation{Latitude: 55.76, Longitude: 37.63}, "alice-live-id", 3)
+		assert.Equal(t, []domain.TelegramEditLocationMessage{
+			{ChatID: testChatID, MessageID: liveMessageID, Latitude: 55.76, Longitude: 37.63},
+		}, env.telegramClient.LocationEdits())
+		assert.Len(t, env.telegramClient.Texts(), texts)
+		assert.Len(t, env.telegramClient.Locations(), 1)
+
+		// The update delivered late doesn't move it back
+		env.liveLocation(t, domain.WhatsappLocation{Latitude: 55.755, Longitude: 37.625}, "alice-live-id", 2)
+		assert.Len(t, env.telegramClient.LocationEdits(), 1)
+		assert.Len(t, env.telegramClient.Texts(), texts)
+		assert.Empty(t, env.telegramClient.StoppedLocations())
+
+		// Expired live location is shared again
+		env.clock.now = testHistoryTime.Add(9 * time.Hour)
+		env.liveLocation(t, live, "alice-live-id", 4)
+		assert.Len(t, env.telegramClient.Texts(), texts+1)
+		assert.Len(t, env.telegramClient.Locations(), 2)
+		assert.Len(t, env.telegramClient.LocationEdits(), 1)
+		assert.Empty(t, env.telegramClient.StoppedLocations())
+	})
+
+	t.Run("live location is shared again", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+
+		live := domain.WhatsappLocation{Latitude: 55.75, Longitude: 37.62}
+		env.liveLocation(t, live, "alice-live-id", 5)
+		liveMessageID := env.telegramClient.Locations()[0].ReplyToMessageID + 1
+
+		// The sequence starts over with the new share, the previous live location is stopped
+		env.liveLocation(t, live, "alice-new-live-id", 1)
+		assert.Equal(t, []int{liveMessageID}, env.telegramClient.StoppedLocations())
+		locations := env.telegramClient.Locations()
+		require.Len(t, locations, 2)
+		assert.Empty(t, env.telegramClient.LocationEdits())
+
+		// The new live location is moved
+		env.liveLocation(t, domain.WhatsappLocation{Latitude: 55.76, Longitude: 37.63}, "alice-new-live-id", 2)
+		edits := env.telegramClient.LocationEdits()
+		require.Len(t, edits, 1)
+		assert.Equal(t, locations[1].ReplyToMessageID+1, edits[0].MessageID)
+	})
+
+	t.Run("deleted live location is stopped", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+
+		env.liveLocation(t, domain.WhatsappLocation{Latitude: 55.75, Longitude: 37.62}, "alice-live-id", 1)
+		liveMessageID := env.telegramClient.Locations()[0].ReplyToMessageID + 1
+
+		require.NoError(t, env.eventsHandler.HandleRevokeEvent(&domain.RevokeEvent{
+			ChatID:            testChatID,
+			WhatsappRemoteJid: "alice-jid",
+			WhatsappMessageID: "alice-live-id",
+			Account:           testAccount,
+		}))
+		assert.Equal(t, []int{liveMessageID}, env.telegramClient.StoppedLocations())
+		assert.Contains(t, env.telegramClient.Edits()[len(env.telegramClient.Edits())-1].Text, "message deleted")
+	})
+
+	t.Run("location of muted contact", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+		require.NoError(t, env.settings.SetMode(testChatID, testAccount, "alice-jid", domain.ContactModeMute))
+
+		env.location(t, testLocation, false)
+		assert.Empty(t, env.telegramClient.Locations())
+		assert.Contains(t, env.lastArchived(t).Text, "📍 Location: Cafe, 1 Main St")
+	})
+
+	t.Run("location reply", func(t *testing.T) {
+		env := newTestEnv(t)
+		whatsappClientMock := newContactsClient()
+		env.login(t, whatsappClientMock)
+
+		require.NoError(t, env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+			ChatID:    testChatID,
+			FromUser:  testUserName,
+			RemoteJid: "alice-jid",
+			Account:   testAccount,
+			MessageID: replyMessageID,
+			Location:  &testLocation,
+		}))
+
+		sent := env.lastArchived(t)
+		assert.Equal(t, domain.MessageDirectionOut, sent.Direction)
+		assert.Equal(t, "📍 Location: Cafe, 1 Main St\nhttps://maps.google.com/?q=55.751244,37.618423", sent.Text)
+		whatsappClientMock.AssertCalled(t, "Send", &domain.WhatsappLocationMessage{
+			ID:        sent.WhatsappMessageID,
+			RemoteJid: "alice-jid",
+			Location:  testLocation,
+		})
+	})
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/outbox"
+)
+
+const (
+	unsupportedMediaFmt = "%s can't be sent to WhatsApp, send a photo or a file instead"
+	mediaTooLargeMsg    = "The file is too large, bots can download files up to 20 MB from Telegram"
+
+	// maxDownloadSize is the largest file in bytes bots can download from telegram.
+	maxDownloadSize = 20 << 20
+)
+
+// queueWhatsappMedia puts the photo or the document to the outbox and sends it like queueWhatsappMessage.
+func (eh *EventsHandler) queueWhatsappMedia(account, remoteJid string,
+	media domain.WhatsappMedia) (domain.OutboxMessage, bool, error) {
+	queued, err := eh.outbox.EnqueueMedia(eh.chatID, account, remoteJid, media)
+	if err != nil {
+		return domain.OutboxMessage{}, false, fmt.Errorf("failed to queue media chat_id=%d remote_jid=%s: %w",
+			eh.chatID,
+			remoteJid,
+			err)
+	}
+
+	return eh.sendQueued(queued)
+}
+
+// unsupportedReplyMsg returns a message that explains why the reply can't be sent
+// to whatsapp, it's empty if the reply can be sent.
+func unsupportedReplyMsg(event *domain.ReplyEvent) string {
+	switch {
+	case event.UnsupportedMedia != "":
+		return fmt.Sprintf(unsupportedMediaFmt, event.UnsupportedMedia)
+	case event.Media != nil && event.Media.Size > maxDownloadSize:
+		return mediaTooLargeMsg
+	default:
+		return ""
+	}
+}
+
+// mediaReference returns a reference to the media sent to whatsapp for the archive, the file
+// isn't kept by the bridge, so it has no local path.
+func mediaReference(media *domain.WhatsappMedia) domain.MediaReference {
+	return domain.MediaReference{
+		Type:     string(media.Kind),
+		FileName: media.FileName,
+		MimeType: media.MimeType,
+	}
+}
+
+// sendWhatsappMessage returns a function that sends the outbox messages via the client,
+// content of the media is downloaded from telegram right before it's sent.
+func (eh *EventsHandler) sendWhatsappMessage(whatsappClient domain.WhatsappClient) outbox.SendFunc {
+	return func(msg domain.WhatsappMessage) error {
+		if mediaMessage, ok := msg.(*domain.WhatsappMediaMessage); ok && mediaMessage.Data == nil {
+			data, err := eh.telegramClient.DownloadFile(mediaMessage.Media.TelegramFileID)
+			if err != nil {
+				return fmt.Errorf("failed to download file from telegram: %w", err)
+			}
+			mediaMessage.Data = data
+		}
+
+		return whatsappClient.Send(msg)
+	}
+}
@@ -0,0 +1,123 @@
+package handler_test
+
+import (
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// testPhoto is a photo sent to whatsapp in the tests.
+var testPhoto = domain.WhatsappMedia{
+	Kind:           domain.WhatsappMediaImage,
+	TelegramFileID: "photo-file-id",
+	MimeType:       "image/jpeg",
+	Size:           4,
+}
+
+// sendMedia handles the media sent to the active conversation, the reply is its caption.
+func (env *testEnv) sendMedia(t *testing.T, media *domain.WhatsappMedia, unsupported string) {
+	t.Helper()
+
+	require.NoError(t, env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+		ChatID:           testChatID,
+		FromUser:         testUserName,
+		Reply:            "look",
+		MessageID:        replyMessageID,
+		Media:            media,
+		UnsupportedMedia: unsupported,
+	}))
+}
+
+func TestEventsHandlerMedia(t *testing.T) {
+	t.Run("photo to the active conversation", func(t *testing.T) {
+		env := newTestEnv(t)
+		whatsappClientMock := newContactsClient()
+		env.login(t, whatsappClientMock)
+		env.chat(t, &domain.ChatEvent{Contact: "alice"})
+		env.telegramClient.SetFile("photo-file-id", []byte("jpeg"))
+
+		photo := testPhoto
+		env.sendMedia(t, &photo, "")
+
+		sent := env.lastArchived(t)
+		assert.Equal(t, "🖼 Photo\nlook", sent.Text)
+		assert.Equal(t, []domain.MediaReference{{Type: "image", MimeType: "image/jpeg"}}, sent.Media)
+		photo.Caption = "look"
+		whatsappClientMock.AssertCalled(t, "Send", &domain.WhatsappMediaMessage{
+			ID:        sent.WhatsappMessageID,
+			RemoteJid: "alice-jid",
+			Media:     photo,
+			Data:      []byte("jpeg"),
+		})
+		assert.Empty(t, env.outbox.Messages(testChatID))
+	})
+
+	t.Run("document reply", func(t *testing.T) {
+		env := newTestEnv(t)
+		whatsappClientMock := newContactsClient()
+		env.login(t, whatsappClientMock)
+		env.telegramClient.SetFile("document-file-id", []byte("%PDF"))
+
+		document := domain.WhatsappMedia{
+			Kind:           domain.WhatsappMediaDocument,
+			TelegramFileID: "document-file-id",
+			FileName:       "report.pdf",
+			MimeType:       "application/pdf",
+			Size:           4,
+		}
+		require.NoError(t, env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+			ChatID:    testChatID,
+			FromUser:  testUserName,
+			RemoteJid: "alice-jid",
+			Account:   testAccount,
+			MessageID: replyMessageID,
+			Media:     &document,
+		}))
+
+		sent := env.lastArchived(t)
+		assert.Equal(t, "📎 report.pdf (application/pdf, 4 B)", sent.Text)
+		whatsappClientMock.AssertCalled(t, "Send", &domain.WhatsappMediaMessage{
+			ID:        sent.WhatsappMessageID,
+			RemoteJid: "alice-jid",
+			Media:     document,
+			Data:      []byte("%PDF"),
+		})
+	})
+
+	t.Run("file isn't downloaded from telegram", func(t *testing.T) {
+		env := newTestEnv(t)
+		whatsappClientMock := newContactsClient()
+		env.login(t, whatsappClientMock)
+		env.chat(t, &domain.ChatEvent{Contact: "alice"})
+
+		photo := testPhoto
+		env.sendMedia(t, &photo, "")
+
+		// The message stays in the outbox to be retried
+		whatsappClientMock.AssertNotCalled(t, "Send", mock.Anything)
+		messages := env.outbox.Messages(testChatID)
+		require.Len(t, messages, 1)
+		assert.Contains(t, messages[0].LastError, "failed to download file from telegram")
+	})
+
+	t.Run("unsupported media", func(t *testing.T) {
+		env := newTestEnv(t)
+		whatsappClientMock := newContactsClient()
+		env.login(t, whatsappClientMock)
+		env.chat(t, &domain.ChatEvent{Contact: "alice"})
+
+		env.sendMedia(t, nil, "Stickers")
+		assert.Equal(t, "Stickers can't be sent to WhatsApp, send a photo or a file instead", env.lastText(t))
+
+		photo := testPhoto
+		photo.Size = 21 << 20
+		env.sendMedia(t, &photo, "")
+		assert.Equal(t, "The file is too large, bots can download files up to 20 MB from Telegram", env.lastText(t))
+
+		whatsappClientMock.AssertNotCalled(t, "Send", mock.Anything)
+		assert.Empty(t, env.outbox.Messages(testChatID))
+	})
+}
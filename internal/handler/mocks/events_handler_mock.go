@@ -12,6 +12,132 @@ type EventsHandler struct {
 	mock.Mock
 }
 
+// HandleAssignEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleAssignEvent(_a0 *domain.AssignEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.AssignEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleAwayEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleAwayEvent(_a0 *domain.AwayEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.AwayEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleCancelLoginEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleCancelLoginEvent(_a0 *domain.CancelLoginEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.CancelLoginEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleChatEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleChatEvent(_a0 *domain.ChatEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.ChatEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleChatsEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleChatsEvent(_a0 *domain.ChatsEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.ChatsEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleCloseChatEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleCloseChatEvent(_a0 *domain.CloseChatEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.CloseChatEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleContactCardEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleContactCardEvent(_a0 *domain.ContactCardEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.ContactCardEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleDeleteEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleDeleteEvent(_a0 *domain.DeleteEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.DeleteEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleDigestEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleDigestEvent(_a0 *domain.DigestEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.DigestEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // HandleDisconnectEvent provides a mock function with given fields: _a0
 func (_m *EventsHandler) HandleDisconnectEvent(_a0 *domain.DisconnectEvent) error {
 	ret := _m.Called(_a0)
@@ -26,6 +152,48 @@ func (_m *EventsHandler) HandleDisconnectEvent(_a0 *domain.DisconnectEvent) erro
 	return r0
 }
 
+// HandleDocumentEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleDocumentEvent(_a0 *domain.DocumentEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.DocumentEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleEditEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleEditEvent(_a0 *domain.EditEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.EditEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleExportEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleExportEvent(_a0 *domain.ExportEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.ExportEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // HandleHelpEvent provides a mock function with given fields: _a0
 func (_m *EventsHandler) HandleHelpEvent(_a0 *domain.HelpEvent) error {
 	ret := _m.Called(_a0)
@@ -40,6 +208,48 @@ func (_m *EventsHandler) HandleHelpEvent(_a0 *domain.HelpEvent) error {
 	return r0
 }
 
+// HandleHistoryEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleHistoryEvent(_a0 *domain.HistoryEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.HistoryEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleLastSeenEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleLastSeenEvent(_a0 *domain.LastSeenEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.LastSeenEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleLocationEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleLocationEvent(_a0 *domain.LocationEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.LocationEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // HandleLoginEvent provides a mock function with given fields: _a0
 func (_m *EventsHandler) HandleLoginEvent(_a0 *domain.LoginEvent) error {
 	ret := _m.Called(_a0)
@@ -54,6 +264,20 @@ func (_m *EventsHandler) HandleLoginEvent(_a0 *domain.LoginEvent) error {
 	return r0
 }
 
+// HandleLoginResultEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleLoginResultEvent(_a0 *domain.LoginResultEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.LoginResultEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // HandleLogoutEvent provides a mock function with given fields: _a0
 func (_m *EventsHandler) HandleLogoutEvent(_a0 *domain.LogoutEvent) error {
 	ret := _m.Called(_a0)
@@ -68,6 +292,62 @@ func (_m *EventsHandler) HandleLogoutEvent(_a0 *domain.LogoutEvent) error {
 	return r0
 }
 
+// HandlePresenceEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandlePresenceEvent(_a0 *domain.PresenceEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.PresenceEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleQuietEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleQuietEvent(_a0 *domain.QuietEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.QuietEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleReactEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleReactEvent(_a0 *domain.ReactEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.ReactEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleReactionEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleReactionEvent(_a0 *domain.ReactionEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.ReactionEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // HandleRepeatedLoginEvent provides a mock function with given fields: _a0
 func (_m *EventsHandler) HandleRepeatedLoginEvent(_a0 *domain.LoginEvent) error {
 	ret := _m.Called(_a0)
@@ -96,6 +376,118 @@ func (_m *EventsHandler) HandleReplyEvent(_a0 *domain.ReplyEvent) error {
 	return r0
 }
 
+// HandleRestoreEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleRestoreEvent(_a0 *domain.RestoreEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.RestoreEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleRetryEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleRetryEvent(_a0 *domain.RetryEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.RetryEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleRevokeEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleRevokeEvent(_a0 *domain.RevokeEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.RevokeEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleRouteEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleRouteEvent(_a0 *domain.RouteEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.RouteEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleRulesEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleRulesEvent(_a0 *domain.RulesEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.RulesEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleScheduleEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleScheduleEvent(_a0 *domain.ScheduleEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.ScheduleEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleSearchEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleSearchEvent(_a0 *domain.SearchEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.SearchEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleSettingsEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleSettingsEvent(_a0 *domain.SettingsEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.SettingsEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // HandleStartEvent provides a mock function with given fields: _a0
 func (_m *EventsHandler) HandleStartEvent(_a0 *domain.StartEvent) error {
 	ret := _m.Called(_a0)
@@ -110,6 +502,20 @@ func (_m *EventsHandler) HandleStartEvent(_a0 *domain.StartEvent) error {
 	return r0
 }
 
+// HandleTeamEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleTeamEvent(_a0 *domain.TeamEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.TeamEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // HandleTextMessageEvent provides a mock function with given fields: _a0
 func (_m *EventsHandler) HandleTextMessageEvent(_a0 *domain.TextMessageEvent) error {
 	ret := _m.Called(_a0)
@@ -124,13 +530,55 @@ func (_m *EventsHandler) HandleTextMessageEvent(_a0 *domain.TextMessageEvent) er
 	return r0
 }
 
-// IsLoggedIn provides a mock function with given fields:
-func (_m *EventsHandler) IsLoggedIn() bool {
-	ret := _m.Called()
+// HandleTickEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleTickEvent(_a0 *domain.TickEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.TickEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleTopicsEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleTopicsEvent(_a0 *domain.TopicsEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.TopicsEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HandleUnscheduleEvent provides a mock function with given fields: _a0
+func (_m *EventsHandler) HandleUnscheduleEvent(_a0 *domain.UnscheduleEvent) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*domain.UnscheduleEvent) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// IsLoggedIn provides a mock function with given fields: account
+func (_m *EventsHandler) IsLoggedIn(account string) bool {
+	ret := _m.Called(account)
 
 	var r0 bool
-	if rf, ok := ret.Get(0).(func() bool); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(account)
 	} else {
 		r0 = ret.Get(0).(bool)
 	}
@@ -0,0 +1,310 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"go.uber.org/zap"
+)
+
+const (
+	lastSeenUsageMsg = "Usage: /lastseen <contact>, or reply to a message with /lastseen " +
+		"to find out whether the contact is online"
+	severalLastSeenFmt  = "Several contacts match %q, use a phone number or a jid"
+	groupLastSeenMsg    = "Groups don't have last seen time, use a contact instead"
+	onlineFmt           = "%s is online"
+	lastSeenFmt         = "%s was last seen %s"
+	offlineFmt          = "%s is offline, the last seen time is hidden"
+	lastSeenPendingFmt  = "Waiting for WhatsApp to report whether %s is online, the bot will let you know"
+	lastSeenTimeLayout  = "Jan 2 15:04"
+	presenceUnsupported = "Presence is not supported"
+)
+
+// presenceKey identifies a whatsapp contact of the account.
+type presenceKey struct {
+	account   string
+	remoteJid string
+}
+
+// contactPresence represents the last known availability of a whatsapp contact.
+type contactPresence struct {
+	online   bool
+	lastSeen time.Time
+}
+
+// HandlePresenceEvent method handles presence event. The availability of the contact
+// is kept to answer /lastseen and typing of the contact is shown in the chat of its conversation.
+func (eh *EventsHandler) HandlePresenceEvent(event *domain.PresenceEvent) error {
+	eh.log.Debug("handle presence event",
+		zap.String("remote_jid", event.WhatsappRemoteJid),
+		zap.String("presence", string(event.Presence)),
+		zap.String("account", event.Account))
+
+	key := presenceKey{account: event.Account, remoteJid: event.WhatsappRemoteJid}
+	presence, known := eh.updatePresence(key, event)
+	if known && eh.takePendingLastSeen(key) {
+		if err := eh.notifyTelegram(eh.presenceStatus(key, presence)); err != nil {
+			return fmt.Errorf("failed to notify telegram: %w", err)
+		}
+	}
+
+	if event.Presence == domain.WhatsappPresenceComposing {
+		eh.showTyping(event.Account, event.WhatsappRemoteJid)
+	}
+
+	return nil
+}
+
+// HandleLastSeenEvent method handles last seen event.
+func (eh *EventsHandler) HandleLastSeenEvent(event *domain.LastSeenEvent) error {
+	eh.log.Debug("handle last seen event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.String("contact", event.Contact),
+		zap.String("remote_jid", event.RemoteJid),
+		zap.String("account", event.Account))
+
+	if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+		return err
+	}
+
+	msg, err := eh.applyLastSeenCommand(event)
+	if err != nil {
+		return err
+	}
+
+	if err := eh.notifyTelegram(msg); err != nil {
+		return fmt.Errorf("failed to notify telegram: %w", err)
+	}
+
+	return nil
+}
+
+// applyLastSeenCommand returns a message about availability of the contact. The contact is
+// subscribed to if its availability is unknown yet, the chat is notified once it's reported.
+func (eh *EventsHandler) applyLastSeenCommand(event *domain.LastSeenEvent) (string, error) {
+	if eh.presenceChats == 0 {
+		return presenceUnsupported, nil
+	}
+	if event.RemoteJid == "" && event.Contact == "" {
+		return lastSeenUsageMsg, nil
+	}
+
+	account, remoteJid := event.Account, event.RemoteJid
+	if remoteJid == "" {
+		accounts := eh.loggedInAccounts()
+		if len(accounts) == 0 {
+			return fmt.Sprintf(chatNotLoggedInFmt, loginCommand(domain.DefaultWhatsappAccount)), nil
+		}
+
+		matches := eh.findContacts(accounts, event.Contact)
+		switch len(matches) {
+		case 0:
+			return fmt.Sprintf(noContactsFmt, event.Contact), nil
+		case 1:
+			account, remoteJid = matches[0].account, matches[0].jid
+		default:
+			return fmt.Sprintf(severalLastSeenFmt, event.Contact), nil
+		}
+	}
+
+	if strings.HasSuffix(remoteJid, groupJidSuffix) {
+		return groupLastSeenMsg, nil
+	}
+
+	whatsappClient, ok := eh.whatsappClient(account)
+	if !ok {
+		return fmt.Sprintf(chatNotLoggedInFmt, loginCommand(account)), nil
+	}
+
+	key := presenceKey{account: account, remoteJid: remoteJid}
+	eh.mu.RLock()
+	presence, known := eh.presences[key]
+	eh.mu.RUnlock()
+	if known {
+		return eh.presenceStatus(key, presence), nil
+	}
+
+	// Whatsapp reports the current presence once the contact is subscribed to
+	eh.mu.Lock()
+	eh.pendingLastSeen[key] = true
+	delete(eh.presenceSubscriptions, key)
+	eh.mu.Unlock()
+
+	if err := eh.subscribePresence(account, whatsappClient, remoteJid); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(lastSeenPendingFmt, eh.contactName(account, remoteJid)), nil
+}
+
+// presenceStatus returns a message about availability of the contact.
+func (eh *EventsHandler) presenceStatus(key presenceKey, presence contactPresence) string {
+	name := eh.contactName(key.account, key.remoteJid) + domain.AccountTag(key.account)
+	switch {
+	case presence.online:
+		return fmt.Sprintf(onlineFmt, name)
+	case presence.lastSeen.IsZero():
+		return fmt.Sprintf(offlineFmt, name)
+	}
+
+	loc, _ := eh.chatTimeZone()
+
+	return fmt.Sprintf(lastSeenFmt, name, presence.lastSeen.In(loc).Format(lastSeenTimeLayout))
+}
+
+// updatePresence keeps availability of the contact reported by the event and returns it,
+// false is returned if the event doesn't tell it, e.g. a member of a group is typing.
+func (eh *EventsHandler) updatePresence(key presenceKey, event *domain.PresenceEvent) (contactPresence, bool) {
+	if strings.HasSuffix(key.remoteJid, groupJidSuffix) {
+		return contactPresence{}, false
+	}
+
+	eh.mu.Lock()
+	defer eh.mu.Unlock()
+
+	presence := eh.presences[key]
+	switch event.Presence {
+	case domain.WhatsappPresenceUnavailable:
+		presence.online = false
+		if !event.LastSeen.IsZero() {
+			presence.lastSeen = event.LastSeen
+		}
+	default:
+		// The contact is typing or has stopped typing, so it's online
+		presence.online = true
+		presence.lastSeen = eh.now()
+	}
+	eh.presences[key] = presence
+
+	return presence, true
+}
+
+// takePendingLastSeen returns true if availability of the contact has been requested
+// by /lastseen and hasn't been reported yet, the request is forgotten.
+func (eh *EventsHandler) takePendingLastSeen(key presenceKey) bool {
+	eh.mu.Lock()
+	defer eh.mu.Unlock()
+
+	pending := eh.pendingLastSeen[key]
+	delete(eh.pendingLastSeen, key)
+
+	return pending
+}
+
+// showTyping shows that the contact is typing in the chat its messages are delivered to.
+// Nothing is shown if the messages of the contact are not delivered right away.
+func (eh *EventsHandler) showTyping(account, remoteJid string) {
+	if mode := eh.contactMode(account, remoteJid); mode != domain.ContactModeNormal &&
+		mode != domain.ContactModeSilent {
+		return
+	}
+	if eh.inQuietHours() {
+		return
+	}
+
+	action := &domain.TelegramChatAction{ChatID: eh.chatID, Action: domain.TelegramChatActionTyping}
+	if eh.routes != nil {
+		if r, ok := eh.routes.Target(eh.chatID, account, remoteJid); ok {
+			action.ChatID = r.ChatID
+		}
+	}
+	if action.ChatID == eh.chatID && eh.topics != nil && eh.topics.Enabled(eh.chatID) {
+		// The topic is created by the first message, there is no place to show typing until then
+		topic, ok := eh.topics.Find(eh.chatID, account, remoteJid)
+		if !ok {
+			return
+		}
+		action.ThreadID = topic.ThreadID
+	}
+
+	if err := eh.telegramClient.SendChatAction(action); err != nil {
+		eh.log.Error("failed to show typing",
+			zap.String("remote_jid", remoteJid),
+			zap.String("account", account),
+			zap.Error(err))
+	}
+}
+
+// subscribeRecentPresence subscribes to presence updates of the contacts of the recent chats
+// once the account is logged in or its session is restored, since whatsapp forgets subscriptions
+// of a broken session. Errors are only logged, presence updates are optional.
+func (eh *EventsHandler) subscribeRecentPresence(account string, whatsappClient domain.WhatsappClient) {
+	if eh.presenceChats == 0 {
+		return
+	}
+
+	eh.mu.Lock()
+	for key := range eh.presenceSubscriptions {
+		if key.account == account {
+			delete(eh.presenceSubscriptions, key)
+		}
+	}
+	eh.mu.Unlock()
+
+	chats, err := whatsappClient.RecentChats(eh.presenceChats)
+	if err != nil {
+		eh.log.Error("failed to load recent whatsapp chats", zap.String("account", account), zap.Error(err))
+
+		return
+	}
+
+	for _, chat := range chats {
+		if err := eh.subscribePresence(account, whatsappClient, chat.Jid); err != nil {
+			eh.log.Error("failed to subscribe to presence", zap.Error(err))
+		}
+	}
+}
+
+// subscribePresence subscribes to presence updates of the contact unless it's done already.
+// Groups are skipped, typing of their members is reported without subscription.
+func (eh *EventsHandler) subscribePresence(account string, whatsappClient domain.WhatsappClient,
+	remoteJid string) error {
+	if eh.presenceChats == 0 || strings.HasSuffix(remoteJid, groupJidSuffix) {
+		return nil
+	}
+
+	key := presenceKey{account: account, remoteJid: remoteJid}
+	eh.mu.Lock()
+	subscribed := eh.presenceSubscriptions[key]
+	eh.presenceSubscriptions[key] = true
+	eh.mu.Unlock()
+
+	if subscribed {
+		return nil
+	}
+
+	if err := whatsappClient.SubscribePresence(remoteJid); err != nil {
+		eh.mu.Lock()
+		delete(eh.presenceSubscriptions, key)
+		eh.mu.Unlock()
+
+		return fmt.Errorf("failed to subscribe to presence of %s: %w", remoteJid, err)
+	}
+
+	return nil
+}
+
+// sendComposing lets the contact know whether the user is composing a message. The bot isn't
+// told when the user is typing in telegram, so the user is considered composing while
+// the conversation is active. Errors are only logged, the presence is optional.
+func (eh *EventsHandler) sendComposing(account, remoteJid string, composing bool) {
+	whatsappClient, ok := eh.whatsappClient(account)
+	if !ok || eh.presenceChats == 0 {
+		return
+	}
+
+	presence := domain.WhatsappPresencePaused
+	if composing {
+		presence = domain.WhatsappPresenceComposing
+	}
+
+	if err := whatsappClient.SendPresence(remoteJid, presence); err != nil {
+		eh.log.Error("failed to send presence",
+			zap.String("remote_jid", remoteJid),
+			zap.String("account", account),
+			zap.Error(err))
+	}
+}
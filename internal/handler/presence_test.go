@@ -0,0 +1,180 @@
+package handler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/handler"
+	"github.com/dstdfx/twbridge/internal/whatsapp/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// lastSeen handles the last seen command.
+func (env *testEnv) lastSeen(t *testing.T, event *domain.LastSeenEvent) {
+	t.Helper()
+
+	event.ChatID = testChatID
+	event.FromUser = testUserName
+	require.NoError(t, env.eventsHandler.HandleLastSeenEvent(event))
+}
+
+// presence handles the presence update of the contact.
+func (env *testEnv) presence(t *testing.T, remoteJid string, presence domain.WhatsappPresence) {
+	t.Helper()
+
+	require.NoError(t, env.eventsHandler.HandlePresenceEvent(&domain.PresenceEvent{
+		ChatID:            testChatID,
+		WhatsappRemoteJid: remoteJid,
+		Presence:          presence,
+		LastSeen:          testHistoryTime,
+		Account:           testAccount,
+	}))
+}
+
+// newPresenceEnv returns a test env with presence enabled and logged in with a client
+// whose recent chats are the conversations with Alice and a group.
+func newPresenceEnv(t *testing.T) (*testEnv, *mocks.WhatsappClient) {
+	t.Helper()
+
+	env := newTestEnv(t, func(opts *handler.Opts) {
+		opts.PresenceChats = 5
+	})
+	require.NoError(t, env.quietHours.Set(domain.QuietHours{ChatID: testChatID, TimeZone: "UTC"}))
+
+	whatsappClientMock := newContactsClient()
+	whatsappClientMock.On("RecentChats", 5).Return([]domain.WhatsappChat{
+		{Jid: "alice-jid", Name: "Alice"},
+		{Jid: "family@g.us", Name: "Family"},
+	}, nil)
+	whatsappClientMock.On("SubscribePresence", mock.Anything).Return(nil)
+	whatsappClientMock.On("SendPresence", mock.Anything, mock.Anything).Return(nil)
+	env.login(t, whatsappClientMock)
+
+	return env, whatsappClientMock
+}
+
+func TestEventsHandlerPresence(t *testing.T) {
+	t.Run("typing", func(t *testing.T) {
+		env, whatsappClientMock := newPresenceEnv(t)
+
+		// Contacts of the recent chats are subscribed to on login, groups are skipped
+		whatsappClientMock.AssertCalled(t, "SubscribePresence", "alice-jid")
+		whatsappClientMock.AssertNotCalled(t, "SubscribePresence", "family@g.us")
+
+		// Contacts are subscribed to once they send a message
+		env.receive(t, "bob-jid", "Bob", "hi")
+		env.receive(t, "bob-jid", "Bob", "are you there?")
+		whatsappClientMock.AssertNumberOfCalls(t, "SubscribePresence", 2)
+
+		env.presence(t, "alice-jid", domain.WhatsappPresenceComposing)
+		env.presence(t, "alice-jid", domain.WhatsappPresencePaused)
+		assert.Equal(t, []domain.TelegramChatAction{
+			{ChatID: testChatID, Action: domain.TelegramChatActionTyping},
+		}, env.telegramClient.ChatActions())
+
+		// Typing of muted contacts is not shown
+		require.NoError(t, env.settings.SetMode(testChatID, testAccount, "bob-jid", domain.ContactModeMute))
+		env.presence(t, "bob-jid", domain.WhatsappPresenceComposing)
+		assert.Len(t, env.telegramClient.ChatActions(), 1)
+
+		// Typing of the routed conversations is shown in the routed chat
+		require.NoError(t, env.routes.Add(domain.Route{
+			OwnerChatID: testChatID,
+			Account:     testAccount,
+			Jid:         "family@g.us",
+			ChatID:      -100456,
+		}))
+		env.presence(t, "family@g.us", domain.WhatsappPresenceComposing)
+		actions := env.telegramClient.ChatActions()
+		require.Len(t, actions, 2)
+		assert.Equal(t, int64(-100456), actions[1].ChatID)
+	})
+
+	t.Run("typing in topics", func(t *testing.T) {
+		env, _ := newPresenceEnv(t)
+		env.topicsCommand(t, "on")
+
+		// There is no topic to show typing in until the first message
+		env.presence(t, "alice-jid", domain.WhatsappPresenceComposing)
+		assert.Empty(t, env.telegramClient.ChatActions())
+
+		env.receive(t, "alice-jid", "Alice", "hi")
+		topic, ok := env.topics.Find(testChatID, testAccount, "alice-jid")
+		require.True(t, ok)
+
+		env.presence(t, "alice-jid", domain.WhatsappPresenceComposing)
+		assert.Equal(t, []domain.TelegramChatAction{
+			{ChatID: testChatID, ThreadID: topic.ThreadID, Action: domain.TelegramChatActionTyping},
+		}, env.telegramClient.ChatActions())
+	})
+
+	t.Run("last seen", func(t *testing.T) {
+		env, whatsappClientMock := newPresenceEnv(t)
+
+		env.lastSeen(t, &domain.LastSeenEvent{})
+		assert.Contains(t, env.lastText(t), "Usage: /lastseen <contact>")
+
+		env.lastSeen(t, &domain.LastSeenEvent{Contact: "Smith"})
+		assert.Equal(t, `Several contacts match "Smith", use a phone number or a jid`, env.lastText(t))
+
+		env.lastSeen(t, &domain.LastSeenEvent{RemoteJid: "family@g.us", Account: testAccount})
+		assert.Equal(t, "Groups don't have last seen time, use a contact instead", env.lastText(t))
+
+		// Availability is reported once whatsapp sends it
+		env.lastSeen(t, &domain.LastSeenEvent{Contact: "Bob"})
+		assert.Equal(t, "Waiting for WhatsApp to report whether Bob is online, the bot will let you know",
+			env.lastText(t))
+		whatsappClientMock.AssertCalled(t, "SubscribePresence", "bob-jid")
+
+		env.presence(t, "bob-jid", domain.WhatsappPresenceUnavailable)
+		assert.Equal(t, "Bob was last seen Mar 31 10:00", env.lastText(t))
+
+		// The chat is notified only once
+		env.clock.now = testHistoryTime.Add(time.Hour)
+		sent := len(env.telegramClient.Texts())
+		env.presence(t, "bob-jid", domain.WhatsappPresenceAvailable)
+		assert.Len(t, env.telegramClient.Texts(), sent)
+
+		env.lastSeen(t, &domain.LastSeenEvent{Contact: "Bob"})
+		assert.Equal(t, "Bob is online", env.lastText(t))
+
+		// The contact has been online until the update without the last seen time
+		require.NoError(t, env.eventsHandler.HandlePresenceEvent(&domain.PresenceEvent{
+			ChatID:            testChatID,
+			WhatsappRemoteJid: "bob-jid",
+			Presence:          domain.WhatsappPresenceUnavailable,
+			Account:           testAccount,
+		}))
+		env.lastSeen(t, &domain.LastSeenEvent{Contact: "Bob"})
+		assert.Equal(t, "Bob was last seen Mar 31 11:00", env.lastText(t))
+	})
+
+	t.Run("last seen, presence is not supported", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+
+		env.lastSeen(t, &domain.LastSeenEvent{Contact: "Alice"})
+		assert.Equal(t, "Presence is not supported", env.lastText(t))
+	})
+
+	t.Run("composing in active conversation", func(t *testing.T) {
+		env, whatsappClientMock := newPresenceEnv(t)
+
+		env.chat(t, &domain.ChatEvent{Contact: "Alice"})
+		whatsappClientMock.AssertCalled(t, "SendPresence", "alice-jid", domain.WhatsappPresenceComposing)
+
+		// The previous conversation is paused once another one is active
+		env.chat(t, &domain.ChatEvent{Contact: "Bob"})
+		whatsappClientMock.AssertCalled(t, "SendPresence", "alice-jid", domain.WhatsappPresencePaused)
+		whatsappClientMock.AssertCalled(t, "SendPresence", "bob-jid", domain.WhatsappPresenceComposing)
+
+		require.NoError(t, env.eventsHandler.HandleCloseChatEvent(&domain.CloseChatEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+		}))
+		whatsappClientMock.AssertCalled(t, "SendPresence", "bob-jid", domain.WhatsappPresencePaused)
+	})
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"go.uber.org/zap"
+)
+
+const (
+	quietUnsupportedMsg = "Quiet hours are not supported"
+	quietOnFmt          = "Quiet hours are %s (%s), messages received during them are delivered " +
+		"as a single digest once they are over"
+	quietOffMsg        = "Quiet hours are off"
+	quietUsageMsg      = "Usage: /quiet [<from>-<to> [time zone]|off], e.g. /quiet 23:00-07:00 Europe/Berlin"
+	invalidQuietFmt    = "Invalid quiet hours %q, they must look like 23:00-07:00"
+	unknownTimeZoneFmt = "Unknown time zone %q, use a name like Europe/Berlin"
+	localTimeZoneName  = "server time zone"
+	dateLayout         = "2006-01-02"
+)
+
+// HandleQuietEvent method handles quiet event.
+func (eh *EventsHandler) HandleQuietEvent(event *domain.QuietEvent) error {
+	eh.log.Debug("handle quiet event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.Strings("args", event.Args))
+
+	if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+		return err
+	}
+
+	msg, err := eh.applyQuietCommand(event)
+	if err != nil {
+		return err
+	}
+
+	if err := eh.notifyTelegram(msg); err != nil {
+		return fmt.Errorf("failed to notify telegram: %w", err)
+	}
+
+	return nil
+}
+
+// applyQuietCommand applies the quiet command and returns a message to reply with.
+func (eh *EventsHandler) applyQuietCommand(event *domain.QuietEvent) (string, error) {
+	if eh.quietHours == nil || eh.digests == nil {
+		return quietUnsupportedMsg, nil
+	}
+
+	qh, _ := eh.quietHours.Get(eh.chatID)
+	switch {
+	case len(event.Args) == 0:
+		if qh.Hours == "" {
+			return quietOffMsg, nil
+		}
+
+		return fmt.Sprintf(quietOnFmt, qh.Hours, timeZoneName(qh.TimeZone)), nil
+	case len(event.Args) == 1 && event.Args[0] == teamOffArg:
+		qh.Hours = ""
+		if err := eh.quietHours.Set(qh); err != nil {
+			return "", fmt.Errorf("failed to update quiet hours: %w", err)
+		}
+
+		return quietOffMsg, nil
+	case len(event.Args) <= 2:
+		if _, _, err := domain.ParseHours(event.Args[0]); err != nil {
+			return fmt.Sprintf(invalidQuietFmt, event.Args[0]), nil
+		}
+		if len(event.Args) == 2 {
+			if !isValidTimeZone(event.Args[1]) {
+				return fmt.Sprintf(unknownTimeZoneFmt, event.Args[1]), nil
+			}
+			qh.TimeZone = event.Args[1]
+		}
+		qh.Hours = event.Args[0]
+		if err := eh.quietHours.Set(qh); err != nil {
+			return "", fmt.Errorf("failed to update quiet hours: %w", err)
+		}
+
+		return fmt.Sprintf(quietOnFmt, qh.Hours, timeZoneName(qh.TimeZone)), nil
+	default:
+		return quietUsageMsg, nil
+	}
+}
+
+// deliverScheduledDigest delivers the digest once quiet hours are over
+// and at the time of the daily digest.
+func (eh *EventsHandler) deliverScheduledDigest(tickTime time.Time) error {
+	if eh.quietHours == nil || eh.digests == nil {
+		return nil
+	}
+
+	qh, ok := eh.quietHours.Get(eh.chatID)
+	if !ok {
+		return nil
+	}
+
+	loc := timeZoneLocation(qh.TimeZone)
+	now := tickTime.In(loc)
+	switch {
+	case dailyDigestDue(qh, now):
+		eh.log.Debug("deliver daily digest", zap.Int64("chat_id", eh.chatID))
+
+		qh.LastDailyDigest = now.Format(dateLayout)
+		if err := eh.quietHours.Set(qh); err != nil {
+			return fmt.Errorf("failed to update quiet hours: %w", err)
+		}
+
+		return eh.deliverDigestSummary(dailyDigestTitle, loc)
+	case qh.Hours != "" && !domain.InHours(qh.Hours, now) && eh.digests.HasQuietHours(eh.chatID):
+		eh.log.Debug("deliver digest of quiet hours", zap.Int64("chat_id", eh.chatID))
+
+		return eh.deliverDigestSummary(quietDigestTitle, loc)
+	}
+
+	return nil
+}
+
+// inQuietHours returns true if messages of the chat are collected to the digest now.
+func (eh *EventsHandler) inQuietHours() bool {
+	if eh.quietHours == nil || eh.digests == nil {
+		return false
+	}
+
+	qh, ok := eh.quietHours.Get(eh.chatID)
+	if !ok || qh.Hours == "" {
+		return false
+	}
+
+	return domain.InHours(qh.Hours, eh.now().In(timeZoneLocation(qh.TimeZone)))
+}
+
+// chatTimeZone returns the time zone of the chat set along with quiet hours
+// and its name.
+func (eh *EventsHandler) chatTimeZone() (*time.Location, string) {
+	var name string
+	if eh.quietHours != nil {
+		qh, _ := eh.quietHours.Get(eh.chatID)
+		name = qh.TimeZone
+	}
+
+	return timeZoneLocation(name), timeZoneName(name)
+}
+
+// dailyDigestDue returns true if the time of the daily digest has come
+// and it hasn't been delivered today yet.
+func dailyDigestDue(qh domain.QuietHours, now time.Time) bool {
+	if qh.DailyDigestAt == "" {
+		return false
+	}
+
+	at, err := domain.ParseTimeOfDay(qh.DailyDigestAt)
+	if err != nil {
+		return false
+	}
+	now = now.In(timeZoneLocation(qh.TimeZone))
+
+	return now.Hour()*60+now.Minute() >= at && qh.LastDailyDigest != now.Format(dateLayout)
+}
+
+// timeZoneLocation returns the location of the time zone, the local
+// time zone is used if it's empty or unknown.
+func timeZoneLocation(name string) *time.Location {
+	if name == "" {
+		return time.Local
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local
+	}
+
+	return loc
+}
+
+func timeZoneName(name string) string {
+	if name == "" {
+		return localTimeZoneName
+	}
+
+	return name
+}
+
+func isValidTimeZone(name string) bool {
+	_, err := time.LoadLocation(name)
+
+	return err == nil
+}
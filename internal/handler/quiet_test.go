@@ -0,0 +1,189 @@
+package handler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// quiet handles the quiet command.
+func (env *testEnv) quiet(t *testing.T, args ...string) {
+	t.Helper()
+
+	require.NoError(t, env.eventsHandler.HandleQuietEvent(&domain.QuietEvent{
+		ChatID:   testChatID,
+		FromUser: testUserName,
+		Args:     args,
+	}))
+}
+
+// tick handles the tick event at the provided time.
+func (env *testEnv) tick(t *testing.T, now time.Time) {
+	t.Helper()
+
+	env.clock.now = now
+	require.NoError(t, env.eventsHandler.HandleTickEvent(&domain.TickEvent{
+		ChatID: testChatID,
+		Time:   now,
+	}))
+}
+
+func TestEventsHandlerQuietHours(t *testing.T) {
+	t.Run("quiet command", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		env.quiet(t)
+		assert.Equal(t, "Quiet hours are off", env.lastText(t))
+
+		env.quiet(t, "23:00-07:00", "Europe/Berlin")
+		assert.Equal(t, "Quiet hours are 23:00-07:00 (Europe/Berlin), messages received during them "+
+			"are delivered as a single digest once they are over", env.lastText(t))
+
+		// The time zone is kept if it's omitted
+		env.quiet(t, "22:00-06:00")
+		qh, ok := env.quietHours.Get(testChatID)
+		require.True(t, ok)
+		assert.Equal(t, "22:00-06:00", qh.Hours)
+		assert.Equal(t, "Europe/Berlin", qh.TimeZone)
+
+		env.quiet(t, "22:00")
+		assert.Equal(t, `Invalid quiet hours "22:00", they must look like 23:00-07:00`, env.lastText(t))
+
+		env.quiet(t, "23:00-07:00", "Mars/Olympus")
+		assert.Equal(t, `Unknown time zone "Mars/Olympus", use a name like Europe/Berlin`, env.lastText(t))
+
+		env.quiet(t, "23:00-07:00", "UTC", "now")
+		assert.Contains(t, env.lastText(t), "Usage: /quiet")
+
+		env.quiet(t, "off")
+		assert.Equal(t, "Quiet hours are off", env.lastText(t))
+		qh, _ = env.quietHours.Get(testChatID)
+		assert.Empty(t, qh.Hours)
+		assert.Equal(t, "Europe/Berlin", qh.TimeZone)
+	})
+
+	t.Run("messages are delivered once quiet hours are over", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.quiet(t, "23:00-07:00", "Europe/Berlin")
+		env.rulesCommand(t, "add", "important", "keyword=urgent")
+		sent := len(env.telegramClient.TextMessages())
+
+		berlin, err := time.LoadLocation("Europe/Berlin")
+		require.NoError(t, err)
+		env.clock.now = time.Date(2022, 3, 31, 23, 30, 0, 0, berlin)
+
+		env.receive(t, "alice-jid", "Alice", "are you asleep?")
+		env.receive(t, "bob-jid", "Bob", "hey")
+		env.receive(t, "alice-jid", "Alice", "good night")
+		assert.Len(t, env.telegramClient.TextMessages(), sent)
+		assert.Equal(t, 3, env.digests.Len(testChatID))
+
+		// Important messages are delivered right away
+		env.receive(t, "carol-jid", "Carol", "urgent: call me")
+		assert.Len(t, env.telegramClient.TextMessages(), sent+1)
+
+		env.tick(t, time.Date(2022, 4, 1, 3, 0, 0, 0, berlin))
+		assert.Len(t, env.telegramClient.TextMessages(), sent+1)
+
+		// The tick time is converted to the time zone of quiet hours
+		env.tick(t, time.Date(2022, 4, 1, 5, 1, 0, 0, time.UTC))
+		messages := env.telegramClient.TextMessages()
+		require.Len(t, messages, sent+2)
+		summary := messages[len(messages)-1]
+		assert.Equal(t, "Quiet hours are over, 3 new messages in 2 conversations\n\n"+
+			"Alice [jid: alice-jid] - 2 messages\n"+
+			"[Mar 31 23:30] are you asleep?\n"+
+			"[Mar 31 23:30] good night\n\n"+
+			"Bob [jid: bob-jid] - 1 messages\n"+
+			"[Mar 31 23:30] hey", summary.Text)
+		require.Len(t, summary.Buttons, 2)
+		assert.Equal(t, "Chat with Alice", summary.Buttons[0][0].Text)
+		assert.Equal(t, domain.NewCallbackData(domain.ChatCallbackAction,
+			domain.NewConversationRef(testAccount, "alice-jid")), summary.Buttons[0][0].CallbackData)
+		assert.Zero(t, env.digests.Len(testChatID))
+
+		// Messages are delivered as usual after quiet hours
+		env.receive(t, "alice-jid", "Alice", "good morning")
+		assert.Len(t, env.telegramClient.TextMessages(), sent+3)
+		env.tick(t, time.Date(2022, 4, 1, 7, 2, 0, 0, berlin))
+		assert.Len(t, env.telegramClient.TextMessages(), sent+3)
+	})
+
+	t.Run("digest summary is shortened", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.quiet(t, "23:00-07:00", "UTC")
+		sent := len(env.telegramClient.TextMessages())
+
+		env.clock.now = time.Date(2022, 3, 31, 23, 30, 0, 0, time.UTC)
+		for _, text := range []string{"1", "2", "3", "4", "5", "6", "7"} {
+			env.receive(t, "alice-jid", "Alice", text)
+		}
+
+		env.tick(t, time.Date(2022, 4, 1, 7, 0, 0, 0, time.UTC))
+		messages := env.telegramClient.TextMessages()
+		require.Len(t, messages, sent+1)
+		assert.Equal(t, "Quiet hours are over, 7 new messages in 1 conversations\n\n"+
+			"Alice [jid: alice-jid] - 7 messages\n"+
+			"... and 2 more\n"+
+			"[Mar 31 23:30] 3\n"+
+			"[Mar 31 23:30] 4\n"+
+			"[Mar 31 23:30] 5\n"+
+			"[Mar 31 23:30] 6\n"+
+			"[Mar 31 23:30] 7", messages[len(messages)-1].Text)
+	})
+
+	t.Run("daily digest", func(t *testing.T) {
+		env := newTestEnv(t)
+		require.NoError(t, env.settings.SetMode(testChatID, testAccount, "alice-jid", domain.ContactModeDigest))
+
+		env.clock.now = time.Date(2022, 3, 31, 10, 0, 0, 0, time.UTC)
+		env.digestCommand(t, "daily")
+		assert.Equal(t, "The daily digest is off", env.lastText(t))
+
+		env.digestCommand(t, "daily", "8pm")
+		assert.Equal(t, `Invalid time "8pm", it must look like 20:00`, env.lastText(t))
+
+		env.digestCommand(t, "daily", "20:00", "UTC")
+		assert.Equal(t, "The digest is delivered every day at 20:00 (UTC)", env.lastText(t))
+		sent := len(env.telegramClient.TextMessages())
+
+		env.receive(t, "alice-jid", "Alice", "hi")
+		env.tick(t, time.Date(2022, 3, 31, 19, 59, 0, 0, time.UTC))
+		assert.Len(t, env.telegramClient.TextMessages(), sent)
+
+		env.tick(t, time.Date(2022, 3, 31, 20, 0, 0, 0, time.UTC))
+		messages := env.telegramClient.TextMessages()
+		require.Len(t, messages, sent+1)
+		assert.Equal(t, "Daily digest, 1 new messages in 1 conversations\n\n"+
+			"Alice [jid: alice-jid] - 1 messages\n"+
+			"[Mar 31 10:00] hi", messages[len(messages)-1].Text)
+
+		// The digest is delivered once a day
+		env.receive(t, "alice-jid", "Alice", "hi again")
+		env.tick(t, time.Date(2022, 3, 31, 21, 0, 0, 0, time.UTC))
+		assert.Len(t, env.telegramClient.TextMessages(), sent+1)
+
+		env.tick(t, time.Date(2022, 4, 1, 20, 0, 0, 0, time.UTC))
+		assert.Len(t, env.telegramClient.TextMessages(), sent+2)
+
+		env.digestCommand(t, "daily", "off")
+		assert.Equal(t, "The daily digest is off", env.lastText(t))
+	})
+
+	t.Run("daily digest isn't delivered right after it's scheduled", func(t *testing.T) {
+		env := newTestEnv(t)
+		require.NoError(t, env.settings.SetMode(testChatID, testAccount, "alice-jid", domain.ContactModeDigest))
+
+		env.clock.now = time.Date(2022, 3, 31, 21, 0, 0, 0, time.UTC)
+		env.receive(t, "alice-jid", "Alice", "hi")
+		env.digestCommand(t, "daily", "20:00", "UTC")
+		sent := len(env.telegramClient.TextMessages())
+
+		env.tick(t, time.Date(2022, 3, 31, 21, 1, 0, 0, time.UTC))
+		assert.Len(t, env.telegramClient.TextMessages(), sent)
+		assert.Equal(t, 1, env.digests.Len(testChatID))
+	})
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"go.uber.org/zap"
+)
+
+const (
+	reactionNoteFmt     = "%s from %s"
+	reactNotLoggedInFmt = "You're not logged in to WhatsApp%s, the reaction isn't sent"
+	reactUnsupportedFmt = "WhatsApp%s doesn't support reactions, the reaction isn't sent"
+	reactFailedFmt      = "Failed to send the reaction to WhatsApp: %s"
+	reactUnknownMsg     = "The message has been bridged before reactions were supported, the reaction isn't sent"
+	reactDeletedMsg     = "The message has been deleted, the reaction isn't sent"
+)
+
+// HandleReactionEvent method handles reaction event. The reaction of the contact is set
+// on the telegram copy of the message, the message is found in the archive. A short note
+// replying to the message is posted instead if the reaction can't be set, e.g. the emoji
+// isn't available in telegram. The bot sets one reaction per message, so the latest
+// reaction of the members of a group is set, the previous one is set again once it's removed.
+func (eh *EventsHandler) HandleReactionEvent(event *domain.ReactionEvent) error {
+	eh.log.Debug("handle reaction event",
+		zap.String("remote_jid", event.WhatsappRemoteJid),
+		zap.String("message_id", event.WhatsappMessageID),
+		zap.String("emoji", event.Emoji),
+		zap.String("account", event.Account))
+
+	msg, ok := eh.findArchivedMessage(func(msg *domain.ArchivedMessage) bool {
+		return msg.Account == event.Account &&
+			msg.RemoteJid == event.WhatsappRemoteJid &&
+			msg.WhatsappMessageID == event.WhatsappMessageID
+	})
+	// Messages collected to the digest or dropped haven't been posted
+	if !ok || msg.TelegramMessageID == 0 {
+		eh.log.Debug("reacted message is not archived")
+
+		return nil
+	}
+
+	msg.Reactions = updateReactions(msg.Reactions, event.WhatsappSenderJid, event.Emoji)
+	if err := eh.archive.Update(msg); err != nil {
+		return fmt.Errorf("failed to update archived message: %w", err)
+	}
+
+	var emoji string
+	if len(msg.Reactions) != 0 {
+		emoji = msg.Reactions[len(msg.Reactions)-1].Emoji
+	}
+	err := eh.telegramClient.SetReaction(&domain.TelegramReaction{
+		ChatID:    msg.TelegramChatID,
+		MessageID: msg.TelegramMessageID,
+		Emoji:     emoji,
+	})
+	if err == nil {
+		return nil
+	}
+
+	// A removed reaction has no note to remove
+	eh.log.Debug("failed to set reaction", zap.Error(err))
+	if event.Emoji == "" {
+		return nil
+	}
+
+	name := event.WhatsappSenderName
+	if name == "" {
+		name = eh.contactName(event.Account, event.WhatsappRemoteJid)
+	}
+
+	note := &domain.TelegramTextMessage{
+		ChatID:              msg.TelegramChatID,
+		Text:                fmt.Sprintf(reactionNoteFmt, event.Emoji, name),
+		DisableNotification: true,
+		ReplyToMessageID:    msg.TelegramMessageID,
+	}
+	if _, err := eh.telegramClient.SendText(note); err != nil {
+		return fmt.Errorf("failed to send message to telegram: %w", err)
+	}
+
+	return nil
+}
+
+// updateReactions replaces the reaction of the contact with the new one that becomes the latest,
+// the reaction is removed if the emoji is empty.
+func updateReactions(reactions []domain.MessageReaction, senderJid, emoji string) []domain.MessageReaction {
+	updated := make([]domain.MessageReaction, 0, len(reactions)+1)
+	for _, reaction := range reactions {
+		if reaction.SenderJid != senderJid {
+			updated = append(updated, reaction)
+		}
+	}
+	if emoji != "" {
+		updated = append(updated, domain.MessageReaction{SenderJid: senderJid, Emoji: emoji})
+	}
+
+	return updated
+}
+
+// HandleReactEvent method handles react event. The reaction of the user to the bridged
+// message is sent to whatsapp, reactions to other telegram messages are ignored.
+func (eh *EventsHandler) HandleReactEvent(event *domain.ReactEvent) error {
+	eh.log.Debug("handle react event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.Int("message_id", event.MessageID),
+		zap.String("emoji", event.Emoji))
+
+	msg, ok := eh.findArchivedMessage(func(msg *domain.ArchivedMessage) bool {
+		return msg.TelegramChatID == event.ChatID &&
+			msg.TelegramMessageID == event.MessageID
+	})
+	if !ok {
+		return nil
+	}
+
+	if event.ChatID == eh.chatID {
+		if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+			return err
+		}
+	}
+
+	reply := eh.applyReact(&msg, event)
+	if reply == "" {
+		return nil
+	}
+
+	if err := eh.notifyTelegram(reply); err != nil {
+		return fmt.Errorf("failed to notify telegram: %w", err)
+	}
+
+	return nil
+}
+
+// applyReact sends the reaction to the whatsapp message. A message about the reaction
+// that couldn't be sent is returned, it's empty once it's sent.
+func (eh *EventsHandler) applyReact(msg *domain.ArchivedMessage, event *domain.ReactEvent) string {
+	switch {
+	case msg.Deleted:
+		return reactDeletedMsg
+	case msg.WhatsappMessageID == "":
+		return reactUnknownMsg
+	}
+
+	whatsappClient, ok := eh.whatsappClient(msg.Account)
+	if !ok {
+		return fmt.Sprintf(reactNotLoggedInFmt, domain.AccountTag(msg.Account))
+	}
+
+	err := whatsappClient.Send(&domain.WhatsappReactionMessage{
+		RemoteJid: msg.RemoteJid,
+		MessageID: msg.WhatsappMessageID,
+		SenderJid: msg.WhatsappSenderJid,
+		FromMe:    msg.Direction == domain.MessageDirectionOut,
+		Emoji:     event.Emoji,
+	})
+	switch {
+	case errors.Is(err, domain.ErrWhatsappNotSupported):
+		return fmt.Sprintf(reactUnsupportedFmt, domain.AccountTag(msg.Account))
+	case err != nil:
+		return fmt.Sprintf(reactFailedFmt, err)
+	}
+
+	return ""
+}
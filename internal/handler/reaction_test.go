@@ -0,0 +1,221 @@
+package handler_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/whatsapp/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var errTestReaction = errors.New("reaction is not available")
+
+// receiveMessage handles the whatsapp message with the identifier and returns
+// the identifier of its telegram copy.
+func (env *testEnv) receiveMessage(t *testing.T, event *domain.TextMessageEvent) int {
+	t.Helper()
+
+	event.ChatID = testChatID
+	event.Account = testAccount
+	require.NoError(t, env.eventsHandler.HandleTextMessageEvent(event))
+
+	return len(env.telegramClient.Texts())
+}
+
+// reaction handles the reaction of the contact to the whatsapp message.
+func (env *testEnv) reaction(t *testing.T, remoteJid, messageID, emoji string) {
+	t.Helper()
+
+	require.NoError(t, env.eventsHandler.HandleReactionEvent(&domain.ReactionEvent{
+		ChatID:             testChatID,
+		WhatsappRemoteJid:  remoteJid,
+		WhatsappSenderName: "Alice",
+		WhatsappMessageID:  messageID,
+		Emoji:              emoji,
+		Account:            testAccount,
+	}))
+}
+
+// react handles the reaction of the user to the telegram message.
+func (env *testEnv) react(t *testing.T, messageID int, emoji string) {
+	t.Helper()
+
+	require.NoError(t, env.eventsHandler.HandleReactEvent(&domain.ReactEvent{
+		ChatID:    testChatID,
+		FromUser:  testUserName,
+		MessageID: messageID,
+		Emoji:     emoji,
+	}))
+}
+
+func TestEventsHandlerReaction(t *testing.T) {
+	t.Run("reaction", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+
+		postedID := env.receiveMessage(t, &domain.TextMessageEvent{
+			WhatsappRemoteJid:  "alice-jid",
+			WhatsappSenderName: "Alice",
+			WhatsappMessageID:  "alice-message-id",
+			Text:               "lunch?",
+		})
+
+		env.reaction(t, "alice-jid", "alice-message-id", "❤️")
+		env.reaction(t, "alice-jid", "alice-message-id", "")
+		assert.Equal(t, []domain.TelegramReaction{
+			{ChatID: testChatID, MessageID: postedID, Emoji: "❤️"},
+			{ChatID: testChatID, MessageID: postedID},
+		}, env.telegramClient.Reactions())
+
+		// Reactions to the messages the bot doesn't know are ignored
+		env.reaction(t, "alice-jid", "unknown-message-id", "👍")
+		assert.Len(t, env.telegramClient.Reactions(), 2)
+		assert.Len(t, env.telegramClient.Texts(), postedID)
+
+		// Reactions of the contact to the replies are set too
+		env.sendReply(t, "sure")
+		env.reaction(t, "alice-jid", env.lastArchived(t).WhatsappMessageID, "👍")
+		reactions := env.telegramClient.Reactions()
+		require.Len(t, reactions, 3)
+		assert.Equal(t, domain.TelegramReaction{ChatID: testChatID, MessageID: replyMessageID, Emoji: "👍"},
+			reactions[2])
+	})
+
+	t.Run("reactions of the group members", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+
+		postedID := env.receiveMessage(t, &domain.TextMessageEvent{
+			WhatsappRemoteJid:  "family@g.us",
+			WhatsappSenderName: "Family",
+			WhatsappSenderJid:  "alice-jid",
+			WhatsappMessageID:  "alice-message-id",
+			Text:               "lunch?",
+		})
+		groupReaction := func(senderJid, emoji string) {
+			require.NoError(t, env.eventsHandler.HandleReactionEvent(&domain.ReactionEvent{
+				ChatID:            testChatID,
+				WhatsappRemoteJid: "family@g.us",
+				WhatsappSenderJid: senderJid,
+				WhatsappMessageID: "alice-message-id",
+				Emoji:             emoji,
+				Account:           testAccount,
+			}))
+		}
+
+		// The latest reaction is set, the previous one is set again once it's removed
+		groupReaction("bob-jid", "👍")
+		groupReaction("carol-jid", "❤️")
+		groupReaction("bob-jid", "🔥")
+		groupReaction("bob-jid", "")
+		assert.Equal(t, []domain.TelegramReaction{
+			{ChatID: testChatID, MessageID: postedID, Emoji: "👍"},
+			{ChatID: testChatID, MessageID: postedID, Emoji: "❤️"},
+			{ChatID: testChatID, MessageID: postedID, Emoji: "🔥"},
+			{ChatID: testChatID, MessageID: postedID, Emoji: "❤️"},
+		}, env.telegramClient.Reactions())
+		assert.Equal(t, []domain.MessageReaction{{SenderJid: "carol-jid", Emoji: "❤️"}},
+			env.lastArchived(t).Reactions)
+
+		groupReaction("carol-jid", "")
+		reactions := env.telegramClient.Reactions()
+		assert.Equal(t, domain.TelegramReaction{ChatID: testChatID, MessageID: postedID}, reactions[len(reactions)-1])
+	})
+
+	t.Run("reaction is not available", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+		env.telegramClient.SetReactionError(errTestReaction)
+
+		postedID := env.receiveMessage(t, &domain.TextMessageEvent{
+			WhatsappRemoteJid:  "alice-jid",
+			WhatsappSenderName: "Alice",
+			WhatsappMessageID:  "alice-message-id",
+			Text:               "lunch?",
+		})
+
+		env.reaction(t, "alice-jid", "alice-message-id", "🫡")
+		messages := env.telegramClient.TextMessages()
+		require.Len(t, messages, postedID+1)
+		assert.Equal(t, domain.TelegramTextMessage{
+			ChatID:              testChatID,
+			Text:                "🫡 from Alice",
+			DisableNotification: true,
+			ReplyToMessageID:    postedID,
+		}, messages[postedID])
+
+		// There is no note about the removed reaction
+		env.reaction(t, "alice-jid", "alice-message-id", "")
+		assert.Len(t, env.telegramClient.Texts(), postedID+1)
+	})
+
+	t.Run("react", func(t *testing.T) {
+		env := newTestEnv(t)
+		whatsappClientMock := newContactsClient()
+		env.login(t, whatsappClientMock)
+
+		postedID := env.receiveMessage(t, &domain.TextMessageEvent{
+			WhatsappRemoteJid:  "family@g.us",
+			WhatsappSenderName: "Bob",
+			WhatsappSenderJid:  "bob-jid",
+			WhatsappMessageID:  "bob-message-id",
+			Text:               "dinner at 7",
+		})
+
+		env.react(t, postedID, "👍")
+		whatsappClientMock.AssertCalled(t, "Send", &domain.WhatsappReactionMessage{
+			RemoteJid: "family@g.us",
+			MessageID: "bob-message-id",
+			SenderJid: "bob-jid",
+			Emoji:     "👍",
+		})
+
+		env.sendReply(t, "see you at 5")
+		sent := env.lastArchived(t)
+		env.react(t, replyMessageID, "")
+		whatsappClientMock.AssertCalled(t, "Send", &domain.WhatsappReactionMessage{
+			RemoteJid: "alice-jid",
+			MessageID: sent.WhatsappMessageID,
+			FromMe:    true,
+		})
+		assert.Len(t, env.telegramClient.Texts(), postedID)
+
+		// Reactions to the other telegram messages are ignored
+		env.react(t, replyMessageID+1, "👍")
+		whatsappClientMock.AssertNumberOfCalls(t, "Send", 3)
+		assert.Len(t, env.telegramClient.Texts(), postedID)
+	})
+
+	t.Run("react, not sent", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		postedID := env.receiveMessage(t, &domain.TextMessageEvent{
+			WhatsappRemoteJid:  "alice-jid",
+			WhatsappSenderName: "Alice",
+			WhatsappMessageID:  "alice-message-id",
+			Text:               "lunch?",
+		})
+		env.react(t, postedID, "👍")
+		assert.Equal(t, "You're not logged in to WhatsApp, the reaction isn't sent", env.lastText(t))
+
+		whatsappClientMock := &mocks.WhatsappClient{}
+		whatsappClientMock.On("Send", mock.Anything).Return(domain.ErrWhatsappNotSupported)
+		whatsappClientMock.On("GetContacts").Return(map[string]domain.WhatsappContact{})
+		env.login(t, whatsappClientMock)
+
+		env.react(t, postedID, "👍")
+		assert.Equal(t, "WhatsApp doesn't support reactions, the reaction isn't sent", env.lastText(t))
+
+		postedID = env.receiveMessage(t, &domain.TextMessageEvent{
+			WhatsappRemoteJid:  "alice-jid",
+			WhatsappSenderName: "Alice",
+			Text:               "are you there?",
+		})
+		env.react(t, postedID, "👍")
+		assert.Equal(t, "The message has been bridged before reactions were supported, the reaction isn't sent",
+			env.lastText(t))
+	})
+}
@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dstdfx/twbridge/internal/archive"
+	"github.com/dstdfx/twbridge/internal/domain"
+	"go.uber.org/zap"
+)
+
+const (
+	deletedText          = "message deleted"
+	deleteUnsupportedMsg = "Deleting messages is not supported"
+	deleteUsageMsg       = "Reply to your message with /delete to delete it in WhatsApp for everyone"
+	deleteNotFoundMsg    = "The message hasn't been sent to WhatsApp, reply to your message with /delete"
+	deleteIncomingMsg    = "Only your messages can be deleted for everyone"
+	deleteUnknownMsg     = "The message has been sent before deleting was supported, it can't be deleted"
+	alreadyDeletedMsg    = "The message has already been deleted"
+	deletedQueuedFmt     = "The message to %s has been removed from the outbox, it won't be sent"
+	deletedFmt           = "The message to %s has been deleted for everyone"
+	deleteFailedFmt      = "Failed to delete the message in WhatsApp: %s"
+	editDeletedMsg       = "The message has been deleted, the edit isn't sent to WhatsApp"
+	editUnknownMsg       = "The message has been sent before editing was supported, the contact still sees the original text"
+	editNotLoggedInFmt   = "You're not logged in to WhatsApp%s, the edit isn't sent, the contact still sees the original text"
+	editUnsupportedFmt   = "WhatsApp%s doesn't support editing messages, the contact still sees the original text"
+	editFailedFmt        = "Failed to edit the message in WhatsApp: %s"
+)
+
+// HandleRevokeEvent method handles revoke event. The telegram copy of the message deleted
+// by the contact is edited to tell it's deleted, the message is found in the archive.
+// A deleted live location is no longer updated.
+func (eh *EventsHandler) HandleRevokeEvent(event *domain.RevokeEvent) error {
+	eh.log.Debug("handle revoke event",
+		zap.String("remote_jid", event.WhatsappRemoteJid),
+		zap.String("message_id", event.WhatsappMessageID),
+		zap.String("account", event.Account))
+
+	eh.endLiveLocation(event.Account, event.WhatsappRemoteJid, event.WhatsappMessageID)
+
+	msg, ok := eh.findArchivedMessage(func(msg *domain.ArchivedMessage) bool {
+		return msg.Direction == domain.MessageDirectionIn &&
+			msg.Account == event.Account &&
+			msg.RemoteJid == event.WhatsappRemoteJid &&
+			msg.WhatsappMessageID == event.WhatsappMessageID
+	})
+	if !ok || msg.Deleted {
+		eh.log.Debug("revoked message is not archived")
+
+		return nil
+	}
+
+	if err := eh.markDeleted(&msg); err != nil {
+		return err
+	}
+
+	// Messages collected to the digest or dropped haven't been posted
+	if msg.TelegramMessageID == 0 {
+		return nil
+	}
+
+	edit := &domain.TelegramEditMessage{
+		ChatID:    msg.TelegramChatID,
+		MessageID: msg.TelegramMessageID,
+		Text: fmt.Sprintf(domain.TextMessageFmt,
+			msg.SenderName,
+			msg.RemoteJid,
+			domain.AccountTag(msg.Account),
+			deletedText),
+	}
+	if msg.TelegramChatID == eh.chatID {
+		edit.Buttons = eh.contactButtons(msg.Account, msg.RemoteJid)
+	}
+	if err := eh.telegramClient.EditMessage(edit); err != nil {
+		return fmt.Errorf("failed to edit telegram message: %w", err)
+	}
+
+	return nil
+}
+
+// HandleEditEvent method handles edit event. The whatsapp message sent from the edited
+// telegram message is edited too, other telegram messages are ignored.
+func (eh *EventsHandler) HandleEditEvent(event *domain.EditEvent) error {
+	eh.log.Debug("handle edit event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.Int("message_id", event.MessageID))
+
+	msg, ok := eh.findArchivedMessage(func(msg *domain.ArchivedMessage) bool {
+		return msg.Direction == domain.MessageDirectionOut &&
+			msg.TelegramChatID == event.ChatID &&
+			msg.TelegramMessageID == event.MessageID
+	})
+	if !ok {
+		return nil
+	}
+
+	if event.ChatID == eh.chatID {
+		if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+			return err
+		}
+	}
+
+	reply, err := eh.applyEdit(&msg, event)
+	if err != nil || reply == "" {
+		return err
+	}
+
+	if err := eh.notifyTelegram(reply); err != nil {
+		return fmt.Errorf("failed to notify telegram: %w", err)
+	}
+
+	return nil
+}
+
+// applyEdit edits the whatsapp message, it's edited in the outbox if it hasn't been sent yet.
+// A message about the edit that couldn't be applied is returned, it's empty once it's applied.
+func (eh *EventsHandler) applyEdit(msg *domain.ArchivedMessage, event *domain.EditEvent) (string, error) {
+	switch {
+	case msg.Deleted:
+		return editDeletedMsg, nil
+	case msg.WhatsappMessageID == "":
+		return editUnknownMsg, nil
+	}
+
+	// Replies from the chats the conversation is routed to are sent as is
+	text := event.Text
+	if event.ChatID == eh.chatID {
+		text = eh.signedReply(text, event.FromName, event.FromUser)
+	}
+
+	edited, err := eh.outbox.Edit(eh.chatID, msg.WhatsappMessageID, text)
+	if err != nil {
+		return "", fmt.Errorf("failed to edit queued message: %w", err)
+	}
+
+	if !edited {
+		whatsappClient, ok := eh.whatsappClient(msg.Account)
+		if !ok {
+			return fmt.Sprintf(editNotLoggedInFmt, domain.AccountTag(msg.Account)), nil
+		}
+
+		err := whatsappClient.Edit(msg.RemoteJid, msg.WhatsappMessageID, text)
+		switch {
+		case errors.Is(err, domain.ErrWhatsappNotSupported):
+			return fmt.Sprintf(editUnsupportedFmt, domain.AccountTag(msg.Account)), nil
+		case err != nil:
+			return fmt.Sprintf(editFailedFmt, err), nil
+		}
+	}
+
+	msg.Text = text
+	if err := eh.archive.Update(*msg); err != nil {
+		return "", fmt.Errorf("failed to update archived message: %w", err)
+	}
+
+	return "", nil
+}
+
+// HandleDeleteEvent method handles delete event.
+func (eh *EventsHandler) HandleDeleteEvent(event *domain.DeleteEvent) error {
+	eh.log.Debug("handle delete event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.Int("message_id", event.MessageID))
+
+	// Messages of the chats the conversation is routed to are deleted as is,
+	// the team manages only this chat
+	if event.ChatID == eh.chatID {
+		if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+			return err
+		}
+	}
+
+	msg, err := eh.applyDeleteCommand(event)
+	if err != nil {
+		return err
+	}
+
+	if err := eh.notifyTelegram(msg); err != nil {
+		return fmt.Errorf("failed to notify telegram: %w", err)
+	}
+
+	return nil
+}
+
+// applyDeleteCommand deletes the whatsapp message sent from the replied telegram message
+// for everyone, it's removed from the outbox if it hasn't been sent yet.
+func (eh *EventsHandler) applyDeleteCommand(event *domain.DeleteEvent) (string, error) {
+	if eh.archive == nil {
+		return deleteUnsupportedMsg, nil
+	}
+	if event.MessageID == 0 {
+		return deleteUsageMsg, nil
+	}
+
+	msg, ok := eh.findArchivedMessage(func(msg *domain.ArchivedMessage) bool {
+		return msg.TelegramChatID == event.ChatID && msg.TelegramMessageID == event.MessageID
+	})
+	switch {
+	case !ok:
+		return deleteNotFoundMsg, nil
+	case msg.Direction == domain.MessageDirectionIn:
+		return deleteIncomingMsg, nil
+	case msg.Deleted:
+		return alreadyDeletedMsg, nil
+	case msg.WhatsappMessageID == "":
+		return deleteUnknownMsg, nil
+	}
+
+	name := eh.contactName(msg.Account, msg.RemoteJid) + domain.AccountTag(msg.Account)
+	removed, err := eh.outbox.Remove(eh.chatID, msg.WhatsappMessageID)
+	if err != nil {
+		return "", fmt.Errorf("failed to remove queued message: %w", err)
+	}
+
+	reply := fmt.Sprintf(deletedQueuedFmt, name)
+	if !removed {
+		whatsappClient, ok := eh.whatsappClient(msg.Account)
+		if !ok {
+			return fmt.Sprintf(chatNotLoggedInFmt, loginCommand(msg.Account)), nil
+		}
+
+		if err := whatsappClient.Revoke(msg.RemoteJid, msg.WhatsappMessageID); err != nil {
+			return fmt.Sprintf(deleteFailedFmt, err), nil
+		}
+		reply = fmt.Sprintf(deletedFmt, name)
+	}
+
+	if err := eh.markDeleted(&msg); err != nil {
+		return "", err
+	}
+
+	// Messages to the contact blocked by the removed message are sent now
+	if removed {
+		if _, err := eh.flushOutbox(msg.Account); err != nil {
+			return "", err
+		}
+	}
+
+	return reply, nil
+}
+
+// findArchivedMessage returns the most recent archived message of the chat that matches,
+// false is returned if there is no such message or the archive is disabled.
+func (eh *EventsHandler) findArchivedMessage(match func(msg *domain.ArchivedMessage) bool) (
+	domain.ArchivedMessage, bool) {
+	if eh.archive == nil {
+		return domain.ArchivedMessage{}, false
+	}
+
+	found := eh.archive.Search(&archive.Query{ChatID: eh.chatID, Match: match, Limit: 1})
+	if len(found) == 0 {
+		return domain.ArchivedMessage{}, false
+	}
+
+	return found[0], true
+}
+
+// markDeleted replaces the text of the archived message, since it's deleted for everyone.
+func (eh *EventsHandler) markDeleted(msg *domain.ArchivedMessage) error {
+	msg.Text = deletedText
+	msg.Deleted = true
+	if err := eh.archive.Update(*msg); err != nil {
+		return fmt.Errorf("failed to update archived message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,226 @@
+package handler_test
+
+import (
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/whatsapp/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// replyMessageID is an identifier of the telegram message sent to whatsapp by sendReply.
+const replyMessageID = 77
+
+// sendReply sends the reply to Alice from the telegram message with replyMessageID.
+func (env *testEnv) sendReply(t *testing.T, text string) {
+	t.Helper()
+
+	require.NoError(t, env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+		ChatID:    testChatID,
+		FromUser:  testUserName,
+		Reply:     text,
+		RemoteJid: "alice-jid",
+		Account:   testAccount,
+		MessageID: replyMessageID,
+	}))
+}
+
+// edit edits the telegram message.
+func (env *testEnv) edit(t *testing.T, messageID int, text string) {
+	t.Helper()
+
+	require.NoError(t, env.eventsHandler.HandleEditEvent(&domain.EditEvent{
+		ChatID:    testChatID,
+		FromUser:  testUserName,
+		MessageID: messageID,
+		Text:      text,
+	}))
+}
+
+// deleteCommand handles the delete command replying to the telegram message.
+func (env *testEnv) deleteCommand(t *testing.T, messageID int) {
+	t.Helper()
+
+	require.NoError(t, env.eventsHandler.HandleDeleteEvent(&domain.DeleteEvent{
+		ChatID:    testChatID,
+		FromUser:  testUserName,
+		MessageID: messageID,
+	}))
+}
+
+// lastArchived returns the last archived message of the chat.
+func (env *testEnv) lastArchived(t *testing.T) domain.ArchivedMessage {
+	t.Helper()
+
+	messages := env.archive.Messages(testChatID)
+	require.NotEmpty(t, messages)
+
+	return messages[len(messages)-1]
+}
+
+func TestEventsHandlerRevoke(t *testing.T) {
+	t.Run("revoked message", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.login(t, newContactsClient())
+
+		require.NoError(t, env.eventsHandler.HandleTextMessageEvent(&domain.TextMessageEvent{
+			ChatID:             testChatID,
+			WhatsappRemoteJid:  "alice-jid",
+			WhatsappSenderName: "Alice",
+			WhatsappMessageID:  "alice-message-id",
+			Text:               "lunch?",
+			Account:            testAccount,
+		}))
+		postedID := len(env.telegramClient.Texts())
+
+		revoke := &domain.RevokeEvent{
+			ChatID:            testChatID,
+			WhatsappRemoteJid: "alice-jid",
+			WhatsappMessageID: "alice-message-id",
+			Account:           testAccount,
+		}
+		require.NoError(t, env.eventsHandler.HandleRevokeEvent(revoke))
+
+		edits := env.telegramClient.Edits()
+		require.Len(t, edits, 1)
+		assert.Equal(t, testChatID, edits[0].ChatID)
+		assert.Equal(t, postedID, edits[0].MessageID)
+		assert.Equal(t, "From: Alice [jid: alice-jid] \n= = = = = = = = = = = =\nMessage: message deleted", edits[0].Text)
+		assert.NotEmpty(t, edits[0].Buttons)
+
+		archived := env.lastArchived(t)
+		assert.True(t, archived.Deleted)
+		assert.Equal(t, "message deleted", archived.Text)
+
+		// Repeated and unknown revokes are ignored
+		require.NoError(t, env.eventsHandler.HandleRevokeEvent(revoke))
+		revoke.WhatsappMessageID = "unknown-message-id"
+		require.NoError(t, env.eventsHandler.HandleRevokeEvent(revoke))
+		assert.Len(t, env.telegramClient.Edits(), 1)
+	})
+
+	t.Run("edit", func(t *testing.T) {
+		env := newTestEnv(t)
+		whatsappClientMock := newContactsClient()
+		whatsappClientMock.On("Edit", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		env.login(t, whatsappClientMock)
+
+		env.sendReply(t, "see you at 5")
+		sent := env.lastArchived(t)
+		require.NotEmpty(t, sent.WhatsappMessageID)
+		whatsappClientMock.AssertCalled(t, "Send", &domain.WhatsappTextMessage{
+			ID:        sent.WhatsappMessageID,
+			RemoteJid: "alice-jid",
+			Text:      "see you at 5",
+		})
+
+		texts := len(env.telegramClient.Texts())
+		env.edit(t, replyMessageID, "see you at 6")
+		whatsappClientMock.AssertCalled(t, "Edit", "alice-jid", sent.WhatsappMessageID, "see you at 6")
+		assert.Equal(t, "see you at 6", env.lastArchived(t).Text)
+		assert.Len(t, env.telegramClient.Texts(), texts)
+
+		// Edits of the messages that haven't been sent to whatsapp are ignored
+		env.edit(t, replyMessageID+1, "/chat Alice")
+		whatsappClientMock.AssertNumberOfCalls(t, "Edit", 1)
+		assert.Len(t, env.telegramClient.Texts(), texts)
+	})
+
+	t.Run("edit, not supported", func(t *testing.T) {
+		env := newTestEnv(t)
+		whatsappClientMock := newContactsClient()
+		whatsappClientMock.On("Edit", mock.Anything, mock.Anything, mock.Anything).
+			Return(domain.ErrWhatsappNotSupported)
+		env.login(t, whatsappClientMock)
+
+		env.sendReply(t, "see you at 5")
+		env.edit(t, replyMessageID, "see you at 6")
+		assert.Equal(t, "WhatsApp doesn't support editing messages, the contact still sees the original text",
+			env.lastText(t))
+		assert.Equal(t, "see you at 5", env.lastArchived(t).Text)
+	})
+
+	t.Run("delete failed message that blocks later ones", func(t *testing.T) {
+		env := newTestEnv(t)
+		var sendErr error = errTestSend
+		whatsappClientMock := &mocks.WhatsappClient{}
+		whatsappClientMock.On("Send", mock.Anything).Return(func(domain.WhatsappMessage) error {
+			return sendErr
+		})
+		whatsappClientMock.On("GetContacts").Return(map[string]domain.WhatsappContact{})
+		env.login(t, whatsappClientMock)
+
+		// The message fails on the second attempt made with the next one, which waits for it
+		env.sendReply(t, "see you at 5")
+		assert.Contains(t, env.lastText(t), "restored")
+		require.NoError(t, env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+			ChatID:    testChatID,
+			FromUser:  testUserName,
+			Reply:     "are you there?",
+			RemoteJid: "alice-jid",
+			Account:   testAccount,
+			MessageID: replyMessageID + 1,
+		}))
+		assert.Equal(t, `The message waits for an earlier message to the contact that has failed, `+
+			`press "Retry" under the failure notice or reply to that message with /delete`, env.lastText(t))
+		queued := env.outbox.Messages(testChatID)
+		require.Len(t, queued, 2)
+		assert.True(t, queued[0].Failed)
+
+		sendErr = nil
+		env.deleteCommand(t, replyMessageID)
+		assert.Equal(t, "The message to alice-jid has been removed from the outbox, it won't be sent", env.lastText(t))
+		assert.Empty(t, env.outbox.Messages(testChatID))
+		whatsappClientMock.AssertCalled(t, "Send", sentText("alice-jid", "are you there?"))
+	})
+
+	t.Run("edit and delete queued message", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		env.sendReply(t, "see you at 5")
+		env.edit(t, replyMessageID, "see you at 6")
+
+		queued := env.outbox.Messages(testChatID)
+		require.Len(t, queued, 1)
+		assert.Equal(t, "see you at 6", queued[0].Text)
+		assert.Equal(t, "see you at 6", env.lastArchived(t).Text)
+
+		env.deleteCommand(t, replyMessageID)
+		assert.Equal(t, "The message to alice-jid has been removed from the outbox, it won't be sent", env.lastText(t))
+		assert.Empty(t, env.outbox.Messages(testChatID))
+		assert.True(t, env.lastArchived(t).Deleted)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		env := newTestEnv(t)
+		whatsappClientMock := newContactsClient()
+		whatsappClientMock.On("Revoke", mock.Anything, mock.Anything).Return(nil)
+		env.login(t, whatsappClientMock)
+
+		env.deleteCommand(t, 0)
+		assert.Equal(t, "Reply to your message with /delete to delete it in WhatsApp for everyone", env.lastText(t))
+
+		env.deleteCommand(t, replyMessageID)
+		assert.Equal(t, "The message hasn't been sent to WhatsApp, reply to your message with /delete", env.lastText(t))
+
+		env.receive(t, "alice-jid", "Alice", "lunch?")
+		env.deleteCommand(t, len(env.telegramClient.Texts()))
+		assert.Equal(t, "Only your messages can be deleted for everyone", env.lastText(t))
+
+		env.sendReply(t, "sure")
+		sent := env.lastArchived(t)
+		env.deleteCommand(t, replyMessageID)
+		assert.Equal(t, "The message to Alice has been deleted for everyone", env.lastText(t))
+		whatsappClientMock.AssertCalled(t, "Revoke", "alice-jid", sent.WhatsappMessageID)
+		assert.True(t, env.lastArchived(t).Deleted)
+
+		env.deleteCommand(t, replyMessageID)
+		assert.Equal(t, "The message has already been deleted", env.lastText(t))
+		whatsappClientMock.AssertNumberOfCalls(t, "Revoke", 1)
+
+		env.edit(t, replyMessageID, "sure, at 12")
+		assert.Equal(t, "The message has been deleted, the edit isn't sent to WhatsApp", env.lastText(t))
+	})
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"go.uber.org/zap"
+)
+
+const (
+	routesUnsupportedMsg = "Routes are not supported"
+	routeUsageMsg        = "Usage: /route [add <jid> <chat id> [account]|remove <jid> [account]], " +
+		"or reply to a message with /route <chat id> or /route remove"
+	routeAddedFmt       = "Messages of %s%s are delivered to chat %d now"
+	routeRemovedFmt     = "Messages of %s%s are delivered to this chat now"
+	routeNotFoundFmt    = "There is no route of %s%s"
+	routeInvalidChatFmt = "Invalid chat ID %q, type /route in the chat to find out its ID"
+	routeUnreachableFmt = "Can't send messages to chat %d, make sure the bot is a member of it: %s"
+	routeWelcomeFmt     = "Messages of %s%s are delivered to this chat, reply to them to answer"
+)
+
+const (
+	routeAddArg    = "add"
+	routeRemoveArg = "remove"
+)
+
+// HandleRouteEvent method handles route event.
+func (eh *EventsHandler) HandleRouteEvent(event *domain.RouteEvent) error {
+	eh.log.Debug("handle route event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.Strings("args", event.Args),
+		zap.String("remote_jid", event.RemoteJid),
+		zap.String("account", event.Account))
+
+	if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+		return err
+	}
+
+	msg, err := eh.applyRouteCommand(event)
+	if err != nil {
+		return err
+	}
+
+	if err := eh.notifyTelegram(msg); err != nil {
+		return fmt.Errorf("failed to notify telegram: %w", err)
+	}
+
+	return nil
+}
+
+// applyRouteCommand applies the route command and returns a message to reply with.
+func (eh *EventsHandler) applyRouteCommand(event *domain.RouteEvent) (string, error) {
+	if eh.routes == nil {
+		return routesUnsupportedMsg, nil
+	}
+
+	args := event.Args
+	switch {
+	case len(args) == 0:
+		return eh.routesStatus(), nil
+	case event.RemoteJid != "" && len(args) == 1 && args[0] != routeRemoveArg:
+		// Route the conversation of the replied message
+		return eh.addRoute(event.Account, event.RemoteJid, args[0])
+	case event.RemoteJid != "" && len(args) == 1:
+		return eh.removeRoute(event.Account, event.RemoteJid)
+	case args[0] == routeAddArg && (len(args) == 3 || len(args) == 4):
+		return eh.addRoute(routeAccount(args[3:]), args[1], args[2])
+	case args[0] == routeRemoveArg && (len(args) == 2 || len(args) == 3):
+		return eh.removeRoute(routeAccount(args[2:]), args[1])
+	default:
+		return routeUsageMsg, nil
+	}
+}
+
+func (eh *EventsHandler) addRoute(account, jid, rawChatID string) (string, error) {
+	chatID, err := strconv.ParseInt(rawChatID, 10, 64)
+	if err != nil {
+		return fmt.Sprintf(routeInvalidChatFmt, rawChatID), nil
+	}
+	if !domain.IsValidAccountName(account) {
+		return invalidAccountMsg, nil
+	}
+
+	accountTag := domain.AccountTag(account)
+
+	// Make sure the bot can deliver messages to the chat before routing them there
+	welcome := &domain.TelegramTextMessage{
+		ChatID: chatID,
+		Text:   fmt.Sprintf(routeWelcomeFmt, jid, accountTag),
+	}
+	if _, err := eh.telegramClient.SendText(welcome); err != nil {
+		return fmt.Sprintf(routeUnreachableFmt, chatID, err), nil
+	}
+
+	err = eh.routes.Add(domain.Route{
+		OwnerChatID: eh.chatID,
+		Account:     account,
+		Jid:         jid,
+		ChatID:      chatID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to add route: %w", err)
+	}
+
+	return fmt.Sprintf(routeAddedFmt, jid, accountTag, chatID), nil
+}
+
+func (eh *EventsHandler) removeRoute(account, jid string) (string, error) {
+	removed, err := eh.routes.Remove(eh.chatID, account, jid)
+	if err != nil {
+		return "", fmt.Errorf("failed to remove route: %w", err)
+	}
+
+	if !removed {
+		return fmt.Sprintf(routeNotFoundFmt, jid, domain.AccountTag(account)), nil
+	}
+
+	return fmt.Sprintf(routeRemovedFmt, jid, domain.AccountTag(account)), nil
+}
+
+// routesStatus returns a description of the routes of the chat.
+func (eh *EventsHandler) routesStatus() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "This chat ID: %d\n", eh.chatID)
+
+	routes := eh.routes.Routes(eh.chatID)
+	if len(routes) == 0 {
+		b.WriteString("There are no routes, all messages are delivered to this chat")
+
+		return b.String()
+	}
+
+	b.WriteString("Routes:")
+	for _, r := range routes {
+		fmt.Fprintf(&b, "\n%s%s - %d", r.Jid, domain.AccountTag(r.Account), r.ChatID)
+	}
+	b.WriteString("\nOther messages are delivered to this chat")
+
+	return b.String()
+}
+
+// deliverConversationMessage sends the message that belongs to the whatsapp conversation
+// to the chat it's routed to. The message is delivered to this chat if there is no
+// route or the routed chat is not reachable.
+func (eh *EventsHandler) deliverConversationMessage(account, remoteJid, name string,
+	msg domain.TelegramTextMessage) (postedMessage, error) {
+	if eh.routes != nil {
+		if r, ok := eh.routes.Target(eh.chatID, account, remoteJid); ok {
+			// Buttons manage the conversation in this chat, they're not sent to the routed one
+			routed := msg
+			routed.ChatID = r.ChatID
+			routed.Buttons = nil
+			messageID, err := eh.telegramClient.SendText(&routed)
+			if err == nil {
+				return postedMessage{chatID: r.ChatID, messageID: messageID}, nil
+			}
+
+			eh.log.Error("failed to deliver message to the routed chat",
+				zap.Int64("routed_chat_id", r.ChatID),
+				zap.String("remote_jid", remoteJid),
+				zap.String("account", account),
+				zap.Error(err))
+		}
+	}
+
+	return eh.sendConversationMessage(account, remoteJid, name, msg)
+}
+
+// routeAccount returns a name of the whatsapp account from the optional argument.
+func routeAccount(args []string) string {
+	if len(args) == 0 {
+		return domain.DefaultWhatsappAccount
+	}
+
+	return args[0]
+}
@@ -0,0 +1,201 @@
+package handler_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/whatsapp/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testRoutedChatID = int64(-100456)
+
+var errTestChatNotFound = errors.New("chat not found")
+
+// route handles the route command.
+func (env *testEnv) route(t *testing.T, event *domain.RouteEvent) {
+	t.Helper()
+
+	event.ChatID = testChatID
+	event.FromUser = testUserName
+	require.NoError(t, env.eventsHandler.HandleRouteEvent(event))
+}
+
+func TestEventsHandlerRoutes(t *testing.T) {
+	t.Run("route command", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		env.route(t, &domain.RouteEvent{})
+		assert.Equal(t, "This chat ID: 123\nThere are no routes, all messages are delivered to this chat",
+			env.lastText(t))
+
+		env.route(t, &domain.RouteEvent{Args: []string{"add", "*@g.us", "-100456"}})
+		assert.Equal(t, "Messages of *@g.us are delivered to chat -100456 now", env.lastText(t))
+
+		// The routed chat is notified
+		sent := env.telegramClient.TextMessages()
+		assert.Equal(t, testRoutedChatID, sent[len(sent)-2].ChatID)
+		assert.Contains(t, sent[len(sent)-2].Text, "are delivered to this chat")
+
+		// Route the conversation of the replied message
+		env.route(t, &domain.RouteEvent{
+			Args:      []string{"-100789"},
+			RemoteJid: testRemoteJid,
+			Account:   "work",
+		})
+		assert.Equal(t, "Messages of test-remote-jid [account: work] are delivered to chat -100789 now",
+			env.lastText(t))
+
+		env.route(t, &domain.RouteEvent{})
+		assert.Equal(t, "This chat ID: 123\nRoutes:\n*@g.us - -100456\n"+
+			"test-remote-jid [account: work] - -100789\nOther messages are delivered to this chat",
+			env.lastText(t))
+
+		env.route(t, &domain.RouteEvent{Args: []string{"remove", "*@g.us"}})
+		assert.Equal(t, "Messages of *@g.us are delivered to this chat now", env.lastText(t))
+
+		env.route(t, &domain.RouteEvent{
+			Args:      []string{"remove"},
+			RemoteJid: testRemoteJid,
+			Account:   "work",
+		})
+		assert.Equal(t, "Messages of test-remote-jid [account: work] are delivered to this chat now",
+			env.lastText(t))
+		assert.Empty(t, env.routes.Routes(testChatID))
+
+		env.route(t, &domain.RouteEvent{Args: []string{"remove", "*@g.us"}})
+		assert.Equal(t, "There is no route of *@g.us", env.lastText(t))
+
+		env.route(t, &domain.RouteEvent{Args: []string{"add", testRemoteJid, "chat"}})
+		assert.Contains(t, env.lastText(t), `Invalid chat ID "chat"`)
+
+		env.route(t, &domain.RouteEvent{Args: []string{"move"}})
+		assert.Contains(t, env.lastText(t), "Usage: /route")
+	})
+
+	t.Run("route to unreachable chat", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.telegramClient.SetChatError(testRoutedChatID, errTestChatNotFound)
+
+		env.route(t, &domain.RouteEvent{Args: []string{"add", testRemoteJid, "-100456"}})
+		assert.Contains(t, env.lastText(t), "Can't send messages to chat -100456")
+		assert.Empty(t, env.routes.Routes(testChatID))
+	})
+
+	t.Run("messages are delivered to the routed chat", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.route(t, &domain.RouteEvent{Args: []string{"add", "alice-jid", "-100456"}})
+
+		env.receive(t, "alice-jid", "Alice", "hi from Alice")
+		env.receive(t, "bob-jid", "Bob", "hi from Bob")
+
+		sent := env.telegramClient.TextMessages()
+		require.Len(t, sent, 4)
+		assert.Equal(t, testRoutedChatID, sent[2].ChatID)
+		assert.Contains(t, sent[2].Text, "hi from Alice")
+		assert.Equal(t, testChatID, sent[3].ChatID)
+		assert.Contains(t, sent[3].Text, "hi from Bob")
+
+		// Messages are delivered to this chat if the routed one is unreachable
+		env.telegramClient.SetChatError(testRoutedChatID, errTestChatNotFound)
+		env.receive(t, "alice-jid", "Alice", "are you there?")
+
+		sent = env.telegramClient.TextMessages()
+		assert.Equal(t, testChatID, sent[len(sent)-1].ChatID)
+		assert.Contains(t, sent[len(sent)-1].Text, "are you there?")
+	})
+
+	t.Run("reply from the routed chat", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		whatsappClientMock := &mocks.WhatsappClient{}
+		whatsappClientMock.On("Send", mock.Anything).Return(nil)
+		env.login(t, whatsappClientMock)
+		env.route(t, &domain.RouteEvent{Args: []string{"add", "alice-jid", "-100456"}})
+		env.team(t, testUserName, "on")
+
+		// Users of the routed chat are not members of the team of this chat
+		require.NoError(t, env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+			ChatID:    testRoutedChatID,
+			FromUser:  "routed-chat-user",
+			Reply:     "hi, Alice",
+			RemoteJid: "alice-jid",
+			Account:   testAccount,
+		}))
+
+		whatsappClientMock.AssertCalled(t, "Send", sentText("alice-jid", "hi, Alice"))
+	})
+	t.Run("edit and delete from the routed chat", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		whatsappClientMock := newContactsClient()
+		whatsappClientMock.On("Edit", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		whatsappClientMock.On("Revoke", mock.Anything, mock.Anything).Return(nil)
+		env.login(t, whatsappClientMock)
+		env.route(t, &domain.RouteEvent{Args: []string{"add", "alice-jid", "-100456"}})
+		env.team(t, testUserName, "on")
+		env.team(t, testUserName, "sign", "on")
+
+		require.NoError(t, env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+			ChatID:    testRoutedChatID,
+			FromUser:  "routed-chat-user",
+			Reply:     "see you at 5",
+			RemoteJid: "alice-jid",
+			Account:   testAccount,
+			MessageID: replyMessageID,
+		}))
+		sent := env.lastArchived(t)
+		texts := len(env.telegramClient.Texts())
+
+		// Messages of the routed chat are edited and deleted as is, like its replies
+		require.NoError(t, env.eventsHandler.HandleEditEvent(&domain.EditEvent{
+			ChatID:    testRoutedChatID,
+			FromUser:  "routed-chat-user",
+			MessageID: replyMessageID,
+			Text:      "see you at 6",
+		}))
+		whatsappClientMock.AssertCalled(t, "Edit", "alice-jid", sent.WhatsappMessageID, "see you at 6")
+		assert.Len(t, env.telegramClient.Texts(), texts)
+
+		require.NoError(t, env.eventsHandler.HandleDeleteEvent(&domain.DeleteEvent{
+			ChatID:    testRoutedChatID,
+			FromUser:  "routed-chat-user",
+			MessageID: replyMessageID,
+		}))
+		whatsappClientMock.AssertCalled(t, "Revoke", "alice-jid", sent.WhatsappMessageID)
+		assert.Contains(t, env.lastText(t), "has been deleted for everyone")
+	})
+	t.Run("react from the routed chat", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		whatsappClientMock := newContactsClient()
+		env.login(t, whatsappClientMock)
+		env.route(t, &domain.RouteEvent{Args: []string{"add", "alice-jid", "-100456"}})
+		env.team(t, testUserName, "on")
+
+		env.receiveMessage(t, &domain.TextMessageEvent{
+			WhatsappRemoteJid:  "alice-jid",
+			WhatsappSenderName: "Alice",
+			WhatsappMessageID:  "alice-message-id",
+			Text:               "lunch?",
+		})
+		received := env.lastArchived(t)
+		require.Equal(t, testRoutedChatID, received.TelegramChatID)
+
+		// Users of the routed chat are not members of the team of this chat
+		require.NoError(t, env.eventsHandler.HandleReactEvent(&domain.ReactEvent{
+			ChatID:    testRoutedChatID,
+			FromUser:  "routed-chat-user",
+			MessageID: received.TelegramMessageID,
+			Emoji:     "👍",
+		}))
+		whatsappClientMock.AssertCalled(t, "Send", &domain.WhatsappReactionMessage{
+			RemoteJid: "alice-jid",
+			MessageID: received.WhatsappMessageID,
+			Emoji:     "👍",
+		})
+	})
+}
@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/rules"
+	"go.uber.org/zap"
+)
+
+const (
+	rulesUnsupportedMsg = "Rules are not supported"
+	noRulesMsg          = "There are no rules, add one with /rules add <action> [conditions]"
+	rulesUsageMsg       = "Usage: /rules [add <action> [key=value...]|remove <id>|dryrun on|off]"
+	ruleAddedFmt        = "Rule %s is added"
+	ruleRemovedFmt      = "Rule #%s is removed"
+	noRuleFmt           = "There is no rule #%s"
+	configRuleFmt       = "Rule #%s comes from the config file, it can't be removed"
+	invalidRuleFmt      = "The rule is not added, %s"
+	dryRunOnMsg         = "Dry-run mode is on, the rules are only logged"
+	dryRunOffMsg        = "Dry-run mode is off, the rules are applied"
+)
+
+const (
+	dryRunArg    = "dryrun"
+	dryRunOnArg  = "on"
+	dryRunOffArg = "off"
+)
+
+// importantTag represents a tag of incoming messages marked as important by the rules.
+const importantTag = " [important]"
+
+// HandleRulesEvent method handles rules event.
+func (eh *EventsHandler) HandleRulesEvent(event *domain.RulesEvent) error {
+	eh.log.Debug("handle rules event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.Strings("args", event.Args))
+
+	if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+		return err
+	}
+
+	msg, err := eh.applyRulesCommand(event)
+	if err != nil {
+		return err
+	}
+
+	if err := eh.notifyTelegram(msg); err != nil {
+		return fmt.Errorf("failed to notify telegram: %w", err)
+	}
+
+	return nil
+}
+
+// applyRulesCommand applies the rules command and returns a message to reply with.
+func (eh *EventsHandler) applyRulesCommand(event *domain.RulesEvent) (string, error) {
+	if eh.rules == nil {
+		return rulesUnsupportedMsg, nil
+	}
+
+	switch {
+	case len(event.Args) == 0:
+		return eh.rulesStatus(), nil
+	case event.Args[0] == "add" && len(event.Args) > 1:
+		rule, err := rules.Parse(event.Args[1:])
+		if err == nil {
+			rule, err = eh.rules.Add(eh.chatID, rule)
+		}
+		if errors.Is(err, rules.ErrInvalidRule) {
+			return fmt.Sprintf(invalidRuleFmt, err), nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to add rule: %w", err)
+		}
+
+		return fmt.Sprintf(ruleAddedFmt, rules.Describe(&rule)), nil
+	case event.Args[0] == "remove" && len(event.Args) == 2:
+		id := strings.TrimPrefix(event.Args[1], "#")
+		if eh.rules.IsConfigRule(id) {
+			return fmt.Sprintf(configRuleFmt, id), nil
+		}
+
+		removed, err := eh.rules.Remove(eh.chatID, id)
+		if err != nil {
+			return "", fmt.Errorf("failed to remove rule: %w", err)
+		}
+		if !removed {
+			return fmt.Sprintf(noRuleFmt, id), nil
+		}
+
+		return fmt.Sprintf(ruleRemovedFmt, id), nil
+	case event.Args[0] == dryRunArg && len(event.Args) == 2 &&
+		(event.Args[1] == dryRunOnArg || event.Args[1] == dryRunOffArg):
+		dryRun := event.Args[1] == dryRunOnArg
+		if err := eh.rules.SetDryRun(eh.chatID, dryRun); err != nil {
+			return "", fmt.Errorf("failed to update rules: %w", err)
+		}
+
+		if eh.rules.DryRun(eh.chatID) {
+			return dryRunOnMsg, nil
+		}
+
+		return dryRunOffMsg, nil
+	default:
+		return rulesUsageMsg, nil
+	}
+}
+
+// rulesStatus returns a description of the rules of the chat.
+func (eh *EventsHandler) rulesStatus() string {
+	chatRules := eh.rules.Rules(eh.chatID)
+	if len(chatRules) == 0 {
+		return noRulesMsg
+	}
+
+	var b strings.Builder
+
+	b.WriteString("Rules:")
+	for i := range chatRules {
+		b.WriteString("\n" + rules.Describe(&chatRules[i]))
+		if eh.rules.IsConfigRule(chatRules[i].ID) {
+			b.WriteString(" (config file)")
+		}
+	}
+
+	if eh.rules.DryRun(eh.chatID) {
+		b.WriteString("\n" + dryRunOnMsg)
+	}
+
+	return b.String()
+}
+
+// evaluateRules returns actions of the rules that match the incoming message.
+// The actions are only logged in dry-run mode, so nothing is applied.
+func (eh *EventsHandler) evaluateRules(event *domain.TextMessageEvent, messageType domain.MessageType) rules.Result {
+	if eh.rules == nil {
+		return rules.Result{}
+	}
+
+	result := rules.Evaluate(eh.rules.Rules(eh.chatID), &rules.Message{
+		Account:    event.Account,
+		RemoteJid:  event.WhatsappRemoteJid,
+		SenderName: event.WhatsappSenderName,
+		Text:       event.Text,
+		Type:       messageType,
+		ReceivedAt: eh.now(),
+	})
+	if len(result.Matched) == 0 {
+		return result
+	}
+
+	matched := make([]string, 0, len(result.Matched))
+	for i := range result.Matched {
+		matched = append(matched, rules.Describe(&result.Matched[i]))
+	}
+
+	if eh.rules.DryRun(eh.chatID) {
+		eh.log.Info("dry-run: rules match the message",
+			zap.Int64("chat_id", eh.chatID),
+			zap.String("remote_jid", event.WhatsappRemoteJid),
+			zap.String("account", event.Account),
+			zap.Strings("rules", matched),
+			zap.Bool("drop", result.Drop),
+			zap.Bool("important", result.Important),
+			zap.Int64s("forward", result.Forward),
+			zap.Strings("tags", result.Tags))
+
+		return rules.Result{}
+	}
+
+	eh.log.Debug("rules match the message",
+		zap.String("remote_jid", event.WhatsappRemoteJid),
+		zap.String("account", event.Account),
+		zap.Strings("rules", matched))
+
+	return result
+}
+
+// forwardMessage sends the incoming message to the chats the rules forward it to,
+// errors are only logged so the message is still delivered to this chat.
+func (eh *EventsHandler) forwardMessage(text string, chatIDs []int64) {
+	for _, chatID := range chatIDs {
+		textMessage := &domain.TelegramTextMessage{
+			ChatID: chatID,
+			Text:   text,
+		}
+		if _, err := eh.telegramClient.SendText(textMessage); err != nil {
+			eh.log.Error("failed to forward message",
+				zap.Int64("forward_chat_id", chatID),
+				zap.Error(err))
+		}
+	}
+}
+
+// ruleTags returns tags of the incoming message added by the rules.
+func ruleTags(result *rules.Result) string {
+	var b strings.Builder
+
+	if result.Important {
+		b.WriteString(importantTag)
+	}
+	for _, tag := range result.Tags {
+		b.WriteString(" #" + tag)
+	}
+
+	return b.String()
+}
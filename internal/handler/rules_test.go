@@ -0,0 +1,105 @@
+package handler_test
+
+import (
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rulesCommand handles the rules command with the arguments.
+func (env *testEnv) rulesCommand(t *testing.T, args ...string) {
+	t.Helper()
+
+	require.NoError(t, env.eventsHandler.HandleRulesEvent(&domain.RulesEvent{
+		ChatID:   testChatID,
+		FromUser: testUserName,
+		Args:     args,
+	}))
+}
+
+func TestEventsHandlerRules(t *testing.T) {
+	t.Run("rules command", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		env.rulesCommand(t)
+		assert.Contains(t, env.lastText(t), "There are no rules")
+
+		env.rulesCommand(t, "add", "drop", "keyword=lottery")
+		assert.Equal(t, "Rule #1 action=drop keyword=lottery is added", env.lastText(t))
+
+		env.rulesCommand(t, "add", "tag", "tag=groups", "group=*@g.us")
+		assert.Equal(t, "Rule #2 action=tag tag=groups group=*@g.us is added", env.lastText(t))
+
+		env.rulesCommand(t, "add", "forward")
+		assert.Equal(t, "The rule is not added, invalid rule: forward requires a chat to forward to", env.lastText(t))
+
+		env.rulesCommand(t, "dryrun", "on")
+		assert.Equal(t, "Dry-run mode is on, the rules are only logged", env.lastText(t))
+
+		env.rulesCommand(t)
+		assert.Equal(t, "Rules:\n#1 action=drop keyword=lottery\n#2 action=tag tag=groups group=*@g.us\n"+
+			"Dry-run mode is on, the rules are only logged", env.lastText(t))
+
+		env.rulesCommand(t, "remove", "#1")
+		assert.Equal(t, "Rule #1 is removed", env.lastText(t))
+		env.rulesCommand(t, "remove", "1")
+		assert.Equal(t, "There is no rule #1", env.lastText(t))
+
+		env.rulesCommand(t, "dryrun", "off")
+		assert.Equal(t, "Dry-run mode is off, the rules are applied", env.lastText(t))
+
+		env.rulesCommand(t, "explode")
+		assert.Contains(t, env.lastText(t), "Usage: /rules")
+	})
+
+	t.Run("rules are applied to incoming messages", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.rulesCommand(t, "add", "drop", "keyword=lottery")
+		env.rulesCommand(t, "add", "forward", "to=-100456", "sender=alice*")
+		env.rulesCommand(t, "add", "important", "regex=(?i)urgent")
+		env.rulesCommand(t, "add", "tag", "tag=work", "account=default")
+		sentBefore := len(env.telegramClient.TextMessages())
+
+		env.receive(t, "bob-jid", "Bob", "you won the lottery")
+		assert.Len(t, env.telegramClient.TextMessages(), sentBefore)
+
+		env.receive(t, "alice-jid", "Alice", "hi")
+		sent := env.telegramClient.TextMessages()
+		require.Len(t, sent, sentBefore+2)
+		assert.Equal(t, int64(-100456), sent[sentBefore].ChatID)
+		assert.Empty(t, sent[sentBefore].Buttons)
+		assert.Equal(t, testChatID, sent[sentBefore+1].ChatID)
+		assert.Contains(t, sent[sentBefore+1].Text, "[jid: alice-jid] #work")
+
+		// Important messages are delivered with notification even if the contact is silent
+		require.NoError(t, env.settings.SetMode(testChatID, testAccount, "bob-jid", domain.ContactModeSilent))
+		env.receive(t, "bob-jid", "Bob", "URGENT: call me")
+		msg := env.lastMessage(t)
+		assert.Contains(t, msg.Text, "[jid: bob-jid] [important] #work")
+		assert.False(t, msg.DisableNotification)
+	})
+
+	t.Run("rules match the type of the message", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.rulesCommand(t, "add", "drop", "type=location")
+		sentBefore := len(env.telegramClient.TextMessages())
+
+		env.location(t, testLocation, false)
+		assert.Len(t, env.telegramClient.TextMessages(), sentBefore)
+		assert.Empty(t, env.telegramClient.Locations())
+
+		env.receive(t, "alice-jid", "Alice", "hi")
+		assert.Contains(t, env.lastText(t), "Message: hi")
+	})
+
+	t.Run("dry-run mode", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.rulesCommand(t, "add", "drop")
+		env.rulesCommand(t, "dryrun", "on")
+
+		env.receive(t, "alice-jid", "Alice", "hi")
+		assert.Contains(t, env.lastText(t), "Message: hi")
+	})
+}
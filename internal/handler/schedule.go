@@ -0,0 +1,344 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"go.uber.org/zap"
+)
+
+const (
+	schedulesUnsupportedMsg = "Scheduled messages are not supported"
+	scheduleUsageMsg        = "Usage: /schedule <contact> <time> <text>, or reply to a message with " +
+		"/schedule <time> <text>. Time is 15:04, 2006-01-02T15:04 or +1h30m"
+	unscheduleUsageMsg      = "Usage: /unschedule <id>"
+	noScheduledMessagesMsg  = "There are no scheduled messages"
+	scheduledMessagesTitle  = "Scheduled messages:"
+	scheduledMessageLineFmt = "#%d %s to %s%s: %s"
+	scheduledFmt            = "The message #%d to %s [jid: %s]%s is scheduled at %s (%s)"
+	scheduledSentFmt        = "The scheduled message #%d to %s [jid: %s]%s is sent"
+	unscheduledFmt          = "The scheduled message #%d is cancelled"
+	noScheduledMessageFmt   = "There is no scheduled message #%s"
+	invalidScheduleTimeFmt  = "Invalid time %q, use 15:04, 2006-01-02T15:04 or +1h30m"
+	passedScheduleTimeFmt   = "The time %s has already passed"
+	severalScheduleFmt      = "Several contacts match %q, use a phone number or a jid"
+	unscheduleButtonFmt     = "Cancel #%d"
+	scheduleTimeLayout      = "Jan 2 15:04"
+	scheduleDateTimeLayout  = "2006-01-02T15:04"
+	scheduleDelayPrefix     = "+"
+	scheduledPreviewLength  = 50
+)
+
+var errInvalidScheduleTime = errors.New("invalid schedule time")
+
+// HandleScheduleEvent method handles schedule event.
+func (eh *EventsHandler) HandleScheduleEvent(event *domain.ScheduleEvent) error {
+	eh.log.Debug("handle schedule event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.String("contact", event.Contact),
+		zap.String("time", event.Time),
+		zap.String("remote_jid", event.RemoteJid),
+		zap.String("account", event.Account))
+
+	if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+		return err
+	}
+
+	msg, err := eh.applyScheduleCommand(event)
+	if err != nil {
+		return err
+	}
+
+	if _, err := eh.telegramClient.SendText(&msg); err != nil {
+		return fmt.Errorf("failed to send message to telegram: %w", err)
+	}
+
+	return nil
+}
+
+// HandleUnscheduleEvent method handles unschedule event.
+func (eh *EventsHandler) HandleUnscheduleEvent(event *domain.UnscheduleEvent) error {
+	eh.log.Debug("handle unschedule event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.String("id", event.ID))
+
+	if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+		return err
+	}
+
+	text, err := eh.applyUnscheduleCommand(event)
+	if err != nil {
+		return err
+	}
+
+	if event.CallbackID == "" {
+		if err := eh.notifyTelegram(text); err != nil {
+			return fmt.Errorf("failed to notify telegram: %w", err)
+		}
+
+		return nil
+	}
+
+	// Cancel buttons belong to the list of the scheduled messages, it's updated in place
+	if event.MessageID != 0 && eh.schedules != nil {
+		list := eh.scheduledMessagesList()
+		err := eh.telegramClient.EditMessage(&domain.TelegramEditMessage{
+			ChatID:    eh.chatID,
+			MessageID: event.MessageID,
+			Text:      list.Text,
+			Buttons:   list.Buttons,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to edit message in telegram: %w", err)
+		}
+	}
+
+	callbackAnswer := &domain.TelegramCallbackAnswer{
+		CallbackID: event.CallbackID,
+		Text:       text,
+	}
+	if err := eh.telegramClient.AnswerCallback(callbackAnswer); err != nil {
+		return fmt.Errorf("failed to answer callback query: %w", err)
+	}
+
+	return nil
+}
+
+// applyScheduleCommand schedules the message and returns a message to reply with.
+func (eh *EventsHandler) applyScheduleCommand(event *domain.ScheduleEvent) (domain.TelegramTextMessage, error) {
+	reply := func(text string) (domain.TelegramTextMessage, error) {
+		return domain.TelegramTextMessage{ChatID: eh.chatID, Text: text}, nil
+	}
+
+	switch {
+	case eh.schedules == nil:
+		return reply(schedulesUnsupportedMsg)
+	case event.RemoteJid == "" && event.Contact == "" && event.Time == "":
+		return eh.scheduledMessagesList(), nil
+	case event.Time == "" || strings.TrimSpace(event.Text) == "":
+		return reply(scheduleUsageMsg)
+	}
+
+	account, remoteJid, name := event.Account, event.RemoteJid, ""
+	if remoteJid == "" {
+		accounts := eh.loggedInAccounts()
+		if len(accounts) == 0 {
+			return reply(fmt.Sprintf(chatNotLoggedInFmt, loginCommand(domain.DefaultWhatsappAccount)))
+		}
+
+		matches := eh.findContacts(accounts, event.Contact)
+		switch len(matches) {
+		case 0:
+			return reply(fmt.Sprintf(noContactsFmt, event.Contact))
+		case 1:
+			account, remoteJid, name = matches[0].account, matches[0].jid, matches[0].name
+		default:
+			return reply(fmt.Sprintf(severalScheduleFmt, event.Contact))
+		}
+	} else {
+		name = eh.contactName(account, remoteJid)
+	}
+
+	loc, timeZone := eh.chatTimeZone()
+	now := eh.now().In(loc)
+	sendAt, err := parseScheduleTime(event.Time, now)
+	switch {
+	case err != nil:
+		return reply(fmt.Sprintf(invalidScheduleTimeFmt, event.Time))
+	case !sendAt.After(now):
+		return reply(fmt.Sprintf(passedScheduleTimeFmt, sendAt.Format(scheduleTimeLayout)))
+	}
+
+	scheduled, err := eh.schedules.Add(domain.ScheduledMessage{
+		ChatID:    eh.chatID,
+		Account:   account,
+		RemoteJid: remoteJid,
+		Name:      name,
+		Text:      event.Text,
+		SendAt:    sendAt,
+	})
+	if err != nil {
+		return domain.TelegramTextMessage{}, fmt.Errorf("failed to schedule message: %w", err)
+	}
+
+	return domain.TelegramTextMessage{
+		ChatID: eh.chatID,
+		Text: fmt.Sprintf(scheduledFmt,
+			scheduled.ID,
+			name,
+			remoteJid,
+			domain.AccountTag(account),
+			sendAt.Format(scheduleTimeLayout),
+			timeZone),
+		Buttons: unscheduleButtons([]domain.ScheduledMessage{scheduled}),
+	}, nil
+}
+
+// applyUnscheduleCommand cancels the scheduled message and returns a message to reply with.
+func (eh *EventsHandler) applyUnscheduleCommand(event *domain.UnscheduleEvent) (string, error) {
+	if eh.schedules == nil {
+		return schedulesUnsupportedMsg, nil
+	}
+	if event.ID == "" {
+		return unscheduleUsageMsg, nil
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(event.ID, "#"))
+	if err != nil {
+		return fmt.Sprintf(noScheduledMessageFmt, event.ID), nil
+	}
+
+	_, ok, err := eh.schedules.Remove(eh.chatID, id)
+	if err != nil {
+		return "", fmt.Errorf("failed to cancel scheduled message: %w", err)
+	}
+	if !ok {
+		return fmt.Sprintf(noScheduledMessageFmt, strconv.Itoa(id)), nil
+	}
+
+	return fmt.Sprintf(unscheduledFmt, id), nil
+}
+
+// scheduledMessagesList returns the list of the scheduled messages of the chat
+// with buttons to cancel them.
+func (eh *EventsHandler) scheduledMessagesList() domain.TelegramTextMessage {
+	messages := eh.schedules.Messages(eh.chatID)
+	if len(messages) == 0 {
+		return domain.TelegramTextMessage{ChatID: eh.chatID, Text: noScheduledMessagesMsg}
+	}
+
+	loc, timeZone := eh.chatTimeZone()
+	lines := make([]string, 0, len(messages)+1)
+	lines = append(lines, fmt.Sprintf("%s (%s)", scheduledMessagesTitle, timeZone))
+	for _, msg := range messages {
+		text := msg.Text
+		if runes := []rune(text); len(runes) > scheduledPreviewLength {
+			text = string(runes[:scheduledPreviewLength]) + "..."
+		}
+		lines = append(lines, fmt.Sprintf(scheduledMessageLineFmt,
+			msg.ID,
+			msg.SendAt.In(loc).Format(scheduleTimeLayout),
+			msg.Name,
+			domain.AccountTag(msg.Account),
+			text))
+	}
+
+	return domain.TelegramTextMessage{
+		ChatID:  eh.chatID,
+		Text:    strings.Join(lines, "\n"),
+		Buttons: unscheduleButtons(messages),
+	}
+}
+
+// sendScheduledMessages sends the scheduled messages of the chat that are due at the time,
+// they are put to the outbox, so messages of the accounts that are not logged in are sent later.
+// Messages that couldn't be put to the outbox are scheduled again, so they are sent on the next tick.
+func (eh *EventsHandler) sendScheduledMessages(now time.Time) error {
+	if eh.schedules == nil {
+		return nil
+	}
+
+	due, err := eh.schedules.TakeDue(eh.chatID, now)
+	if err != nil {
+		return fmt.Errorf("failed to take scheduled messages: %w", err)
+	}
+
+	var (
+		errs   []error
+		unsent []domain.ScheduledMessage
+	)
+	for _, msg := range due {
+		eh.log.Debug("send scheduled message",
+			zap.Int("id", msg.ID),
+			zap.String("remote_jid", msg.RemoteJid),
+			zap.String("account", msg.Account))
+
+		// The message is queued once it has an identifier, even if it hasn't been sent
+		queued, delivered, err := eh.queueWhatsappMessage(msg.Account, msg.RemoteJid, msg.Text)
+		if queued.ID == "" {
+			unsent = append(unsent, msg)
+			errs = append(errs, err)
+
+			continue
+		}
+		eh.archiveMessage(domain.ArchivedMessage{
+			Direction:         domain.MessageDirectionOut,
+			Account:           msg.Account,
+			RemoteJid:         msg.RemoteJid,
+			Text:              msg.Text,
+			WhatsappMessageID: queued.WhatsappMessageID,
+		}, postedMessage{})
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+		if !delivered {
+			continue
+		}
+
+		sentMsg := fmt.Sprintf(scheduledSentFmt, msg.ID, msg.Name, msg.RemoteJid, domain.AccountTag(msg.Account))
+		if err := eh.notifyTelegram(sentMsg); err != nil {
+			errs = append(errs, fmt.Errorf("failed to notify telegram: %w", err))
+		}
+	}
+
+	if len(unsent) != 0 {
+		if err := eh.schedules.Restore(unsent); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore scheduled messages: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// parseScheduleTime returns the time the message is sent at. The time of day means
+// the next time it comes, the delay is counted from now.
+func parseScheduleTime(s string, now time.Time) (time.Time, error) {
+	if strings.HasPrefix(s, scheduleDelayPrefix) {
+		delay, err := time.ParseDuration(strings.TrimPrefix(s, scheduleDelayPrefix))
+		if err != nil || delay <= 0 {
+			return time.Time{}, errInvalidScheduleTime
+		}
+
+		return now.Add(delay), nil
+	}
+
+	if minute, err := domain.ParseTimeOfDay(s); err == nil {
+		at := time.Date(now.Year(), now.Month(), now.Day(), minute/60, minute%60, 0, 0, now.Location())
+		if !at.After(now) {
+			at = at.AddDate(0, 0, 1)
+		}
+
+		return at, nil
+	}
+
+	at, err := time.ParseInLocation(scheduleDateTimeLayout, s, now.Location())
+	if err != nil {
+		return time.Time{}, errInvalidScheduleTime
+	}
+
+	return at, nil
+}
+
+func unscheduleButtons(messages []domain.ScheduledMessage) [][]domain.TelegramButton {
+	if len(messages) > maxContactButtons {
+		messages = messages[:maxContactButtons]
+	}
+
+	buttons := make([][]domain.TelegramButton, 0, len(messages))
+	for _, msg := range messages {
+		buttons = append(buttons, []domain.TelegramButton{{
+			Text:         fmt.Sprintf(unscheduleButtonFmt, msg.ID),
+			CallbackData: domain.NewCallbackData(domain.UnscheduleCallbackAction, strconv.Itoa(msg.ID)),
+		}})
+	}
+
+	return buttons
+}
@@ -0,0 +1,203 @@
+package handler_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/handler"
+	"github.com/dstdfx/twbridge/internal/outbox"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// scheduleCommand handles the schedule command.
+func (env *testEnv) scheduleCommand(t *testing.T, event *domain.ScheduleEvent) {
+	t.Helper()
+
+	event.ChatID = testChatID
+	event.FromUser = testUserName
+	require.NoError(t, env.eventsHandler.HandleScheduleEvent(event))
+}
+
+// unscheduleCommand handles the unschedule command.
+func (env *testEnv) unscheduleCommand(t *testing.T, event *domain.UnscheduleEvent) {
+	t.Helper()
+
+	event.ChatID = testChatID
+	event.FromUser = testUserName
+	require.NoError(t, env.eventsHandler.HandleUnscheduleEvent(event))
+}
+
+// newScheduleEnv returns test environment with the time zone of the chat set to UTC.
+func newScheduleEnv(t *testing.T, options ...func(opts *handler.Opts)) *testEnv {
+	t.Helper()
+
+	env := newTestEnv(t, options...)
+	require.NoError(t, env.quietHours.Set(domain.QuietHours{ChatID: testChatID, TimeZone: "UTC"}))
+	env.clock.now = time.Date(2022, 3, 31, 10, 0, 0, 0, time.UTC)
+
+	return env
+}
+
+func TestEventsHandlerSchedule(t *testing.T) {
+	t.Run("send scheduled message", func(t *testing.T) {
+		env := newScheduleEnv(t)
+		whatsappClientMock := newContactsClient()
+		env.login(t, whatsappClientMock)
+
+		// The time of day that has passed means tomorrow
+		env.scheduleCommand(t, &domain.ScheduleEvent{Contact: "alice", Time: "09:00", Text: "good morning"})
+		scheduled := env.telegramClient.TextMessages()[len(env.telegramClient.TextMessages())-1]
+		assert.Equal(t, "The message #1 to Alice [jid: alice-jid] is scheduled at Apr 1 09:00 (UTC)", scheduled.Text)
+		require.Len(t, scheduled.Buttons, 1)
+		assert.Equal(t, "Cancel #1", scheduled.Buttons[0][0].Text)
+		assert.Equal(t, domain.NewCallbackData(domain.UnscheduleCallbackAction, "1"),
+			scheduled.Buttons[0][0].CallbackData)
+
+		env.tick(t, time.Date(2022, 4, 1, 8, 59, 0, 0, time.UTC))
+		whatsappClientMock.AssertNotCalled(t, "Send", mock.Anything)
+
+		env.tick(t, time.Date(2022, 4, 1, 9, 0, 0, 0, time.UTC))
+		whatsappClientMock.AssertCalled(t, "Send", sentText("alice-jid", "good morning"))
+		assert.Equal(t, "The scheduled message #1 to Alice [jid: alice-jid] is sent", env.lastText(t))
+		assert.Empty(t, env.schedules.Messages(testChatID))
+	})
+
+	t.Run("schedule reply with delay", func(t *testing.T) {
+		env := newScheduleEnv(t)
+		env.login(t, newContactsClient())
+
+		env.scheduleCommand(t, &domain.ScheduleEvent{
+			RemoteJid: "bob-jid",
+			Account:   testAccount,
+			Time:      "+1h30m",
+			Text:      "ping",
+		})
+		assert.Equal(t, "The message #1 to Bob [jid: bob-jid] is scheduled at Mar 31 11:30 (UTC)", env.lastText(t))
+
+		messages := env.schedules.Messages(testChatID)
+		require.Len(t, messages, 1)
+		assert.True(t, messages[0].SendAt.Equal(time.Date(2022, 3, 31, 11, 30, 0, 0, time.UTC)))
+	})
+
+	t.Run("list and cancel", func(t *testing.T) {
+		env := newScheduleEnv(t)
+		env.login(t, newContactsClient())
+
+		env.scheduleCommand(t, &domain.ScheduleEvent{})
+		assert.Equal(t, "There are no scheduled messages", env.lastText(t))
+
+		env.scheduleCommand(t, &domain.ScheduleEvent{Contact: "bob", Time: "2022-04-02T18:00", Text: "see you"})
+		env.scheduleCommand(t, &domain.ScheduleEvent{Contact: "alice", Time: "12:00", Text: "lunch?"})
+
+		env.scheduleCommand(t, &domain.ScheduleEvent{})
+		list := env.telegramClient.TextMessages()[len(env.telegramClient.TextMessages())-1]
+		assert.Equal(t, "Scheduled messages: (UTC)\n"+
+			"#2 Mar 31 12:00 to Alice: lunch?\n"+
+			"#1 Apr 2 18:00 to Bob: see you", list.Text)
+		require.Len(t, list.Buttons, 2)
+		assert.Equal(t, "Cancel #2", list.Buttons[0][0].Text)
+
+		// The list is updated in place
+		env.unscheduleCommand(t, &domain.UnscheduleEvent{
+			CallbackID: "test-callback-id",
+			MessageID:  42,
+			ID:         "2",
+		})
+		assert.Equal(t, "The scheduled message #2 is cancelled", env.telegramClient.CallbackAnswers()[0].Text)
+		edits := env.telegramClient.Edits()
+		require.Len(t, edits, 1)
+		assert.Equal(t, 42, edits[0].MessageID)
+		assert.Equal(t, "Scheduled messages: (UTC)\n#1 Apr 2 18:00 to Bob: see you", edits[0].Text)
+
+		env.unscheduleCommand(t, &domain.UnscheduleEvent{ID: "#1"})
+		assert.Equal(t, "The scheduled message #1 is cancelled", env.lastText(t))
+
+		env.unscheduleCommand(t, &domain.UnscheduleEvent{ID: "1"})
+		assert.Equal(t, "There is no scheduled message #1", env.lastText(t))
+
+		env.unscheduleCommand(t, &domain.UnscheduleEvent{})
+		assert.Equal(t, "Usage: /unschedule <id>", env.lastText(t))
+	})
+
+	t.Run("invalid command", func(t *testing.T) {
+		env := newScheduleEnv(t)
+
+		env.scheduleCommand(t, &domain.ScheduleEvent{Contact: "alice", Time: "09:00"})
+		assert.Contains(t, env.lastText(t), "Usage: /schedule")
+
+		env.scheduleCommand(t, &domain.ScheduleEvent{Contact: "alice", Time: "09:00", Text: "hi"})
+		assert.Equal(t, "You're not logged in to WhatsApp, type /login first", env.lastText(t))
+
+		env.login(t, newContactsClient())
+		env.scheduleCommand(t, &domain.ScheduleEvent{Contact: "smith", Time: "09:00", Text: "hi"})
+		assert.Equal(t, `Several contacts match "smith", use a phone number or a jid`, env.lastText(t))
+
+		env.scheduleCommand(t, &domain.ScheduleEvent{Contact: "eve", Time: "09:00", Text: "hi"})
+		assert.Equal(t, `No contacts match "eve"`, env.lastText(t))
+
+		env.scheduleCommand(t, &domain.ScheduleEvent{Contact: "alice", Time: "25:00", Text: "hi"})
+		assert.Equal(t, `Invalid time "25:00", use 15:04, 2006-01-02T15:04 or +1h30m`, env.lastText(t))
+
+		env.scheduleCommand(t, &domain.ScheduleEvent{Contact: "alice", Time: "+-1h", Text: "hi"})
+		assert.Equal(t, `Invalid time "+-1h", use 15:04, 2006-01-02T15:04 or +1h30m`, env.lastText(t))
+
+		env.scheduleCommand(t, &domain.ScheduleEvent{Contact: "alice", Time: "2022-03-30T10:00", Text: "hi"})
+		assert.Equal(t, "The time Mar 30 10:00 has already passed", env.lastText(t))
+		assert.Empty(t, env.schedules.Messages(testChatID))
+	})
+
+	t.Run("scheduled message waits for login", func(t *testing.T) {
+		env := newScheduleEnv(t)
+
+		env.scheduleCommand(t, &domain.ScheduleEvent{
+			RemoteJid: "alice-jid",
+			Account:   testAccount,
+			Time:      "+1m",
+			Text:      "are you there?",
+		})
+		env.tick(t, time.Date(2022, 3, 31, 10, 1, 0, 0, time.UTC))
+		assert.Equal(t, "You're not logged in to WhatsApp, the message will be sent after /login", env.lastText(t))
+		assert.Empty(t, env.schedules.Messages(testChatID))
+
+		queued := env.outbox.Messages(testChatID)
+		require.Len(t, queued, 1)
+		assert.Equal(t, "are you there?", queued[0].Text)
+	})
+	t.Run("scheduled messages that couldn't be queued are sent on the next tick", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "outbox")
+		queue, err := outbox.New(&outbox.Opts{Path: filepath.Join(dir, "outbox.json")})
+		require.NoError(t, err)
+		env := newScheduleEnv(t, func(opts *handler.Opts) {
+			opts.Outbox = queue
+		})
+
+		for _, text := range []string{"are you there?", "call me"} {
+			env.scheduleCommand(t, &domain.ScheduleEvent{
+				RemoteJid: "alice-jid",
+				Account:   testAccount,
+				Time:      "+1m",
+				Text:      text,
+			})
+		}
+
+		// The outbox can't be saved while its directory is a file
+		require.NoError(t, os.WriteFile(dir, nil, 0o600))
+		env.tick(t, time.Date(2022, 3, 31, 10, 1, 0, 0, time.UTC))
+		assert.Len(t, env.schedules.Messages(testChatID), 2)
+		assert.Empty(t, queue.Messages(testChatID))
+
+		require.NoError(t, os.Remove(dir))
+		env.tick(t, time.Date(2022, 3, 31, 10, 2, 0, 0, time.UTC))
+		assert.Empty(t, env.schedules.Messages(testChatID))
+
+		queued := queue.Messages(testChatID)
+		require.Len(t, queued, 2)
+		assert.Equal(t, "are you there?", queued[0].Text)
+		assert.Equal(t, "call me", queued[1].Text)
+	})
+}
@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"go.uber.org/zap"
+)
+
+const (
+	settingsUnsupportedMsg = "Contact settings are not supported"
+	settingsUsageFmt       = "Reply to a message with %s or type %s <jid> [account]"
+	noSettingsMsg          = "Messages of all contacts are delivered as usual, " +
+		"reply to a message with /settings to change it"
+	contactModeFmt   = "Messages of [jid: %s]%s %s"
+	unknownModeFmt   = "Unknown mode %q"
+	blockedReplyFmt  = "The contact [jid: %s]%s is blocked, the reply is not sent. Type /unmute to unblock it"
+	settingsButton   = "Settings"
+	activeModeMarker = "✓ "
+)
+
+// maxCallbackDataLength is a maximum length of telegram callback data,
+// buttons with longer data are not sent.
+const maxCallbackDataLength = 64
+
+// contactModeDescriptions describes the way messages are delivered in every mode.
+var contactModeDescriptions = map[domain.ContactMode]string{
+	domain.ContactModeNormal: "are delivered as usual",
+	domain.ContactModeSilent: "are delivered without notification",
+	domain.ContactModeDigest: "are collected to the digest, type /digest to read them",
+	domain.ContactModeMute:   "are dropped",
+	domain.ContactModeBlock:  "are dropped and replies to the contact are not sent",
+}
+
+// contactModeLabels represents texts of the buttons that change the mode.
+var contactModeLabels = map[domain.ContactMode]string{
+	domain.ContactModeNormal: "Normal",
+	domain.ContactModeSilent: "Silent",
+	domain.ContactModeDigest: "Digest only",
+	domain.ContactModeMute:   "Mute",
+	domain.ContactModeBlock:  "Block",
+}
+
+// HandleSettingsEvent method handles settings event.
+func (eh *EventsHandler) HandleSettingsEvent(event *domain.SettingsEvent) error {
+	eh.log.Debug("handle settings event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.String("mode", string(event.Mode)),
+		zap.Strings("args", event.Args),
+		zap.String("remote_jid", event.RemoteJid),
+		zap.String("account", event.Account))
+
+	if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+		return err
+	}
+
+	msg, err := eh.applySettingsCommand(event)
+	if err != nil {
+		return err
+	}
+
+	if event.CallbackID == "" {
+		if _, err := eh.telegramClient.SendText(&msg); err != nil {
+			return fmt.Errorf("failed to send message to telegram: %w", err)
+		}
+
+		return nil
+	}
+
+	// Mode buttons belong to the settings message, it's updated in place
+	if event.Mode != "" && event.MessageID != 0 {
+		err = eh.telegramClient.EditMessage(&domain.TelegramEditMessage{
+			ChatID:    eh.chatID,
+			MessageID: event.MessageID,
+			Text:      msg.Text,
+			Buttons:   msg.Buttons,
+		})
+	} else {
+		_, err = eh.telegramClient.SendText(&msg)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send message to telegram: %w", err)
+	}
+
+	callbackAnswer := &domain.TelegramCallbackAnswer{
+		CallbackID: event.CallbackID,
+	}
+	if err := eh.telegramClient.AnswerCallback(callbackAnswer); err != nil {
+		return fmt.Errorf("failed to answer callback query: %w", err)
+	}
+
+	return nil
+}
+
+// applySettingsCommand applies the settings command and returns a message to reply with.
+func (eh *EventsHandler) applySettingsCommand(event *domain.SettingsEvent) (domain.TelegramTextMessage, error) {
+	msg := domain.TelegramTextMessage{ChatID: eh.chatID}
+	if eh.settings == nil {
+		msg.Text = settingsUnsupportedMsg
+
+		return msg, nil
+	}
+
+	account, remoteJid := event.Account, event.RemoteJid
+	if remoteJid == "" && len(event.Args) != 0 {
+		account, remoteJid = routeAccount(event.Args[1:]), event.Args[0]
+	}
+
+	if remoteJid == "" {
+		switch event.Mode {
+		case "":
+			msg.Text = eh.settingsStatus()
+		case domain.ContactModeNormal:
+			msg.Text = fmt.Sprintf(settingsUsageFmt, "/unmute", "/unmute")
+		default:
+			msg.Text = fmt.Sprintf(settingsUsageFmt, "/mute", "/mute")
+		}
+
+		return msg, nil
+	}
+
+	if event.Mode != "" {
+		if !event.Mode.IsValid() {
+			msg.Text = fmt.Sprintf(unknownModeFmt, event.Mode)
+
+			return msg, nil
+		}
+
+		if err := eh.settings.SetMode(eh.chatID, account, remoteJid, event.Mode); err != nil {
+			return msg, fmt.Errorf("failed to update settings: %w", err)
+		}
+	}
+
+	mode := eh.settings.Mode(eh.chatID, account, remoteJid)
+	msg.Text = fmt.Sprintf(contactModeFmt, remoteJid, domain.AccountTag(account), contactModeDescriptions[mode])
+	msg.Buttons = contactModeButtons(account, remoteJid, mode)
+
+	return msg, nil
+}
+
+// settingsStatus returns a description of the settings of the contacts.
+func (eh *EventsHandler) settingsStatus() string {
+	settings := eh.settings.Settings(eh.chatID)
+	if len(settings) == 0 {
+		return noSettingsMsg
+	}
+
+	var b strings.Builder
+
+	b.WriteString("Contact settings:")
+	for _, cs := range settings {
+		fmt.Fprintf(&b, "\n[jid: %s]%s - %s", cs.RemoteJid, domain.AccountTag(cs.Account), cs.Mode)
+	}
+	b.WriteString("\nMessages of other contacts are delivered as usual")
+
+	return b.String()
+}
+
+// contactMode returns the mode of the whatsapp contact.
+func (eh *EventsHandler) contactMode(account, remoteJid string) domain.ContactMode {
+	if eh.settings == nil {
+		return domain.ContactModeNormal
+	}
+
+	return eh.settings.Mode(eh.chatID, account, remoteJid)
+}
+
+// contactButtons returns buttons attached to incoming messages of the contact.
+func (eh *EventsHandler) contactButtons(account, remoteJid string) [][]domain.TelegramButton {
+	if eh.settings == nil {
+		return nil
+	}
+
+	data := domain.NewCallbackData(domain.SettingsCallbackAction, domain.NewConversationRef(account, remoteJid))
+	if len(data) > maxCallbackDataLength {
+		return nil
+	}
+
+	return [][]domain.TelegramButton{{
+		{Text: settingsButton, CallbackData: data},
+	}}
+}
+
+// contactModeButtons returns buttons that change the mode of the contact,
+// the current mode is marked.
+func contactModeButtons(account, remoteJid string, current domain.ContactMode) [][]domain.TelegramButton {
+	rows := [][]domain.ContactMode{
+		{domain.ContactModeNormal, domain.ContactModeSilent, domain.ContactModeDigest},
+		{domain.ContactModeMute, domain.ContactModeBlock},
+	}
+
+	ref := domain.NewConversationRef(account, remoteJid)
+	buttons := make([][]domain.TelegramButton, 0, len(rows))
+	for _, modes := range rows {
+		row := make([]domain.TelegramButton, 0, len(modes))
+		for _, mode := range modes {
+			data := domain.NewCallbackData(domain.ContactModeCallbackAction,
+				domain.NewCallbackData(string(mode), ref))
+			if len(data) > maxCallbackDataLength {
+				return nil
+			}
+
+			label := contactModeLabels[mode]
+			if mode == current {
+				label = activeModeMarker + label
+			}
+			row = append(row, domain.TelegramButton{Text: label, CallbackData: data})
+		}
+		buttons = append(buttons, row)
+	}
+
+	return buttons
+}
@@ -0,0 +1,151 @@
+package handler_test
+
+import (
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/whatsapp/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// settingsCommand handles the settings command.
+func (env *testEnv) settingsCommand(t *testing.T, event *domain.SettingsEvent) {
+	t.Helper()
+
+	event.ChatID = testChatID
+	event.FromUser = testUserName
+	require.NoError(t, env.eventsHandler.HandleSettingsEvent(event))
+}
+
+// lastMessage returns the last text message sent to telegram.
+func (env *testEnv) lastMessage(t *testing.T) domain.TelegramTextMessage {
+	t.Helper()
+
+	sent := env.telegramClient.TextMessages()
+	require.NotEmpty(t, sent)
+
+	return sent[len(sent)-1]
+}
+
+func TestEventsHandlerSettings(t *testing.T) {
+	t.Run("settings commands", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		env.settingsCommand(t, &domain.SettingsEvent{})
+		assert.Contains(t, env.lastText(t), "Messages of all contacts are delivered as usual")
+
+		env.settingsCommand(t, &domain.SettingsEvent{Mode: domain.ContactModeMute})
+		assert.Equal(t, "Reply to a message with /mute or type /mute <jid> [account]", env.lastText(t))
+
+		// Mute the conversation of the replied message
+		env.settingsCommand(t, &domain.SettingsEvent{
+			Mode:      domain.ContactModeMute,
+			RemoteJid: testRemoteJid,
+			Account:   "work",
+		})
+		msg := env.lastMessage(t)
+		assert.Equal(t, "Messages of [jid: test-remote-jid] [account: work] are dropped", msg.Text)
+		require.Len(t, msg.Buttons, 2)
+		assert.Equal(t, "Normal", msg.Buttons[0][0].Text)
+		assert.Equal(t, "✓ Mute", msg.Buttons[1][0].Text)
+		assert.Equal(t, domain.NewCallbackData(domain.ContactModeCallbackAction, "mute:work/test-remote-jid"),
+			msg.Buttons[1][0].CallbackData)
+
+		env.settingsCommand(t, &domain.SettingsEvent{Mode: domain.ContactModeSilent, Args: []string{"bob-jid"}})
+		assert.Equal(t, "Messages of [jid: bob-jid] are delivered without notification", env.lastText(t))
+
+		env.settingsCommand(t, &domain.SettingsEvent{})
+		assert.Equal(t, "Contact settings:\n[jid: test-remote-jid] [account: work] - mute\n"+
+			"[jid: bob-jid] - silent\nMessages of other contacts are delivered as usual", env.lastText(t))
+
+		env.settingsCommand(t, &domain.SettingsEvent{Mode: domain.ContactModeNormal, Args: []string{testRemoteJid, "work"}})
+		assert.Equal(t, "Messages of [jid: test-remote-jid] [account: work] are delivered as usual", env.lastText(t))
+		assert.Equal(t, domain.ContactModeNormal, env.settings.Mode(testChatID, "work", testRemoteJid))
+	})
+
+	t.Run("settings buttons", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		// Incoming messages have a button that shows the settings of the contact
+		env.receive(t, "alice-jid", "Alice", "hi")
+		settingsButton := env.lastMessage(t).Buttons[0][0]
+		assert.Equal(t, "Settings", settingsButton.Text)
+		assert.Equal(t, domain.NewCallbackData(domain.SettingsCallbackAction, "default/alice-jid"),
+			settingsButton.CallbackData)
+
+		env.settingsCommand(t, &domain.SettingsEvent{
+			CallbackID: "test-callback-id",
+			MessageID:  1,
+			RemoteJid:  "alice-jid",
+			Account:    testAccount,
+		})
+		assert.Equal(t, "Messages of [jid: alice-jid] are delivered as usual", env.lastText(t))
+		assert.Equal(t, "✓ Normal", env.lastMessage(t).Buttons[0][0].Text)
+		assert.Empty(t, env.telegramClient.Edits())
+
+		// Mode buttons update the settings message
+		env.settingsCommand(t, &domain.SettingsEvent{
+			CallbackID: "test-callback-id",
+			MessageID:  2,
+			Mode:       domain.ContactModeDigest,
+			RemoteJid:  "alice-jid",
+			Account:    testAccount,
+		})
+		edits := env.telegramClient.Edits()
+		require.Len(t, edits, 1)
+		assert.Equal(t, 2, edits[0].MessageID)
+		assert.Equal(t, "Messages of [jid: alice-jid] are collected to the digest, type /digest to read them",
+			edits[0].Text)
+		assert.Equal(t, "✓ Digest only", edits[0].Buttons[0][2].Text)
+		assert.Len(t, env.telegramClient.CallbackAnswers(), 2)
+
+		env.settingsCommand(t, &domain.SettingsEvent{
+			CallbackID: "test-callback-id",
+			MessageID:  2,
+			Mode:       "unknown",
+			RemoteJid:  "alice-jid",
+			Account:    testAccount,
+		})
+		assert.Equal(t, `Unknown mode "unknown"`, env.telegramClient.Edits()[1].Text)
+	})
+
+	t.Run("modes are applied to incoming messages", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		require.NoError(t, env.settings.SetMode(testChatID, testAccount, "muted-jid", domain.ContactModeMute))
+		require.NoError(t, env.settings.SetMode(testChatID, testAccount, "blocked-jid", domain.ContactModeBlock))
+		require.NoError(t, env.settings.SetMode(testChatID, testAccount, "silent-jid", domain.ContactModeSilent))
+
+		env.receive(t, "muted-jid", "Muted", "hi")
+		env.receive(t, "blocked-jid", "Blocked", "hi")
+		assert.Empty(t, env.telegramClient.TextMessages())
+
+		env.receive(t, "silent-jid", "Silent", "hi")
+		assert.True(t, env.lastMessage(t).DisableNotification)
+
+		env.receive(t, "alice-jid", "Alice", "hi")
+		assert.False(t, env.lastMessage(t).DisableNotification)
+	})
+
+	t.Run("replies to blocked contact are not sent", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		whatsappClientMock := &mocks.WhatsappClient{}
+		whatsappClientMock.On("Send", mock.Anything).Return(nil)
+		env.login(t, whatsappClientMock)
+		require.NoError(t, env.settings.SetMode(testChatID, testAccount, testRemoteJid, domain.ContactModeBlock))
+
+		require.NoError(t, env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+			ChatID:    testChatID,
+			FromUser:  testUserName,
+			Reply:     "hi",
+			RemoteJid: testRemoteJid,
+			Account:   testAccount,
+		}))
+		whatsappClientMock.AssertNotCalled(t, "Send", mock.Anything)
+		assert.Equal(t, "The contact [jid: test-remote-jid] is blocked, the reply is not sent. "+
+			"Type /unmute to unblock it", env.lastText(t))
+	})
+}
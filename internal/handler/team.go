@@ -0,0 +1,398 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/team"
+	"go.uber.org/zap"
+)
+
+const (
+	teamOffMsg           = "Team mode is off, type /team on to share the chat with other members"
+	teamOnMsg            = "Team mode is on, you're the owner of the team. Add members with /team add @username"
+	teamAlreadyOnMsg     = "Team mode is already on"
+	teamDisabledMsg      = "Team mode is off, every participant of the chat can use the bot"
+	teamNoUsernameMsg    = "Set a telegram username to own the team"
+	notTeamMemberMsg     = "You're not a member of the team, ask the owner to add you"
+	notTeamOwnerMsg      = "Only the owner of the team can do that"
+	teamMemberAddedFmt   = "@%s is a member of the team now"
+	teamMemberRemovedFmt = "@%s is not a member of the team anymore"
+	teamNotMemberFmt     = "@%s is not a member of the team"
+	teamSignRepliesFmt   = "Signed replies are %s"
+	teamUsageMsg         = "Usage: /team [on|off|add @username|remove @username|sign on|off]"
+	assignUsageMsg       = "Reply to a message with /assign [@username] to claim the conversation"
+	assignedFmt          = "The conversation [jid: %s]%s is assigned to @%s"
+	unassignedFmt        = "The conversation [jid: %s]%s is not assigned anymore"
+	alreadyAssignedFmt   = "The conversation [jid: %s]%s is assigned to @%s, ask them or the owner to reassign it"
+	replyNotAssigneeFmt  = "The conversation [jid: %s]%s is assigned to @%s, the reply is not sent"
+)
+
+const (
+	teamOnArg  = "on"
+	teamOffArg = "off"
+)
+
+// assignedTagFmt represents a format of the tag of incoming messages
+// that belong to an assigned conversation.
+const assignedTagFmt = " [assigned: @%s]"
+
+// signatureFmt represents a format of a reply signed with a name of the member.
+const signatureFmt = "%s\n\n— %s"
+
+// errUsage is returned by team updates when the command can't be applied,
+// the message is sent to telegram as is.
+type errUsage string
+
+func (e errUsage) Error() string {
+	return string(e)
+}
+
+// HandleTeamEvent method handles team event.
+func (eh *EventsHandler) HandleTeamEvent(event *domain.TeamEvent) error {
+	eh.log.Debug("handle team event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.Strings("args", event.Args))
+
+	msg, err := eh.applyTeamCommand(event)
+	if err != nil {
+		var usage errUsage
+		if !errors.As(err, &usage) {
+			return err
+		}
+		msg = usage.Error()
+	}
+
+	if err := eh.notifyTelegram(msg); err != nil {
+		return fmt.Errorf("failed to notify telegram: %w", err)
+	}
+
+	return nil
+}
+
+// HandleAssignEvent method handles assign event.
+func (eh *EventsHandler) HandleAssignEvent(event *domain.AssignEvent) error {
+	eh.log.Debug("handle assign event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.String("remote_jid", event.RemoteJid),
+		zap.String("account", event.Account),
+		zap.String("assignee", event.Assignee))
+
+	msg, err := eh.applyAssignCommand(event)
+	if err != nil {
+		var usage errUsage
+		if !errors.As(err, &usage) {
+			return err
+		}
+		msg = usage.Error()
+	}
+
+	if err := eh.notifyTelegram(msg); err != nil {
+		return fmt.Errorf("failed to notify telegram: %w", err)
+	}
+
+	return nil
+}
+
+// applyTeamCommand applies the team command and returns a message to reply with.
+func (eh *EventsHandler) applyTeamCommand(event *domain.TeamEvent) (string, error) {
+	if eh.teams == nil {
+		return teamOffMsg, nil
+	}
+
+	if len(event.Args) == 0 {
+		t, ok := eh.teams.Get(eh.chatID)
+		if !ok {
+			return teamOffMsg, nil
+		}
+
+		return teamStatus(&t), nil
+	}
+
+	if event.Args[0] == teamOnArg {
+		if event.FromUser == "" {
+			return teamNoUsernameMsg, nil
+		}
+
+		_, err := eh.teams.Create(eh.chatID, event.FromUser)
+		if errors.Is(err, team.ErrTeamExists) {
+			return teamAlreadyOnMsg, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to create team: %w", err)
+		}
+
+		return teamOnMsg, nil
+	}
+
+	t, ok := eh.teams.Get(eh.chatID)
+	if !ok {
+		return teamOffMsg, nil
+	}
+	if !t.IsOwner(event.FromUser) {
+		return notTeamOwnerMsg, nil
+	}
+
+	var (
+		msg    string
+		update func(t *domain.Team) error
+	)
+	switch {
+	case event.Args[0] == teamOffArg && len(event.Args) == 1:
+		if err := eh.teams.Delete(eh.chatID); err != nil {
+			return "", fmt.Errorf("failed to delete team: %w", err)
+		}
+
+		return teamDisabledMsg, nil
+	case event.Args[0] == "add" && len(event.Args) == 2:
+		member := username(event.Args[1])
+		msg = fmt.Sprintf(teamMemberAddedFmt, member)
+		update = func(t *domain.Team) error {
+			if !t.IsMember(member) {
+				t.Members = append(t.Members, member)
+			}
+
+			return nil
+		}
+	case event.Args[0] == "remove" && len(event.Args) == 2:
+		member := username(event.Args[1])
+		msg = fmt.Sprintf(teamMemberRemovedFmt, member)
+		update = func(t *domain.Team) error {
+			return removeMember(t, member)
+		}
+	case event.Args[0] == "sign" && len(event.Args) == 2 &&
+		(event.Args[1] == teamOnArg || event.Args[1] == teamOffArg):
+		msg = fmt.Sprintf(teamSignRepliesFmt, event.Args[1])
+		update = func(t *domain.Team) error {
+			t.SignReplies = event.Args[1] == teamOnArg
+
+			return nil
+		}
+	default:
+		return teamUsageMsg, nil
+	}
+
+	if _, err := eh.teams.Update(eh.chatID, update); err != nil {
+		return "", fmt.Errorf("failed to update team: %w", err)
+	}
+
+	return msg, nil
+}
+
+// applyAssignCommand applies the assign command and returns a message to reply with.
+func (eh *EventsHandler) applyAssignCommand(event *domain.AssignEvent) (string, error) {
+	if eh.teams == nil {
+		return teamOffMsg, nil
+	}
+
+	t, ok := eh.teams.Get(eh.chatID)
+	if !ok {
+		return teamOffMsg, nil
+	}
+	if !t.IsMember(event.FromUser) {
+		return notTeamMemberMsg, nil
+	}
+	if event.RemoteJid == "" {
+		return assignUsageMsg, nil
+	}
+
+	assignee := username(event.Assignee)
+	if assignee != "" && !t.IsMember(assignee) {
+		return fmt.Sprintf(teamNotMemberFmt, assignee), nil
+	}
+
+	accountTag := domain.AccountTag(event.Account)
+	update := func(t *domain.Team) error {
+		current := t.Assignee(event.Account, event.RemoteJid)
+		if current != "" && !strings.EqualFold(current, event.FromUser) && !t.IsOwner(event.FromUser) {
+			return errUsage(fmt.Sprintf(alreadyAssignedFmt, event.RemoteJid, accountTag, current))
+		}
+
+		assignments := make([]domain.Assignment, 0, len(t.Assignments)+1)
+		for _, assignment := range t.Assignments {
+			if assignment.Account != event.Account || assignment.RemoteJid != event.RemoteJid {
+				assignments = append(assignments, assignment)
+			}
+		}
+		if assignee != "" {
+			assignments = append(assignments, domain.Assignment{
+				Account:   event.Account,
+				RemoteJid: event.RemoteJid,
+				Assignee:  assignee,
+			})
+		}
+		t.Assignments = assignments
+
+		return nil
+	}
+	if _, err := eh.teams.Update(eh.chatID, update); err != nil {
+		return "", fmt.Errorf("failed to update team: %w", err)
+	}
+
+	if assignee == "" {
+		return fmt.Sprintf(unassignedFmt, event.RemoteJid, accountTag), nil
+	}
+
+	return fmt.Sprintf(assignedFmt, event.RemoteJid, accountTag, assignee), nil
+}
+
+// authorize returns true if the user may use the bot. Everyone may use it
+// unless the chat is shared by a team, otherwise the user is notified
+// that the action is not allowed.
+func (eh *EventsHandler) authorize(user string) (bool, error) {
+	if eh.teams == nil {
+		return true, nil
+	}
+
+	t, ok := eh.teams.Get(eh.chatID)
+	if !ok || t.IsMember(user) {
+		return true, nil
+	}
+
+	if err := eh.notifyTelegram(notTeamMemberMsg); err != nil {
+		return false, fmt.Errorf("failed to notify telegram: %w", err)
+	}
+
+	return false, nil
+}
+
+// teamReply checks that the member may reply to the conversation and returns
+// the reply text signed if the team wants it to be.
+func (eh *EventsHandler) teamReply(event *domain.ReplyEvent) (string, bool, error) {
+	if eh.teams == nil {
+		return event.Reply, true, nil
+	}
+
+	t, ok := eh.teams.Get(eh.chatID)
+	if !ok {
+		return event.Reply, true, nil
+	}
+
+	if assignee := t.Assignee(event.Account, event.RemoteJid); assignee != "" &&
+		!strings.EqualFold(assignee, event.FromUser) && !t.IsOwner(event.FromUser) {
+		msg := fmt.Sprintf(replyNotAssigneeFmt, event.RemoteJid, domain.AccountTag(event.Account), assignee)
+		if err := eh.notifyTelegram(msg); err != nil {
+			return "", false, fmt.Errorf("failed to notify telegram: %w", err)
+		}
+
+		return "", false, nil
+	}
+
+	return signReply(&t, event.Reply, event.FromName, event.FromUser), true, nil
+}
+
+// signedReply returns the reply signed with a name of the member if the team signs replies.
+func (eh *EventsHandler) signedReply(reply, fromName, fromUser string) string {
+	if eh.teams == nil {
+		return reply
+	}
+
+	t, ok := eh.teams.Get(eh.chatID)
+	if !ok {
+		return reply
+	}
+
+	return signReply(&t, reply, fromName, fromUser)
+}
+
+// signReply signs the reply with a name of the member unless the team doesn't sign replies.
+func signReply(t *domain.Team, reply, fromName, fromUser string) string {
+	if !t.SignReplies {
+		return reply
+	}
+
+	name := fromName
+	if name == "" {
+		name = fromUser
+	}
+
+	return fmt.Sprintf(signatureFmt, reply, name)
+}
+
+// assignedTag returns a tag of the conversation assigned to a member of the team,
+// empty string is returned if it's not assigned.
+func (eh *EventsHandler) assignedTag(account, remoteJid string) string {
+	if eh.teams == nil {
+		return ""
+	}
+
+	t, ok := eh.teams.Get(eh.chatID)
+	if !ok {
+		return ""
+	}
+
+	if assignee := t.Assignee(account, remoteJid); assignee != "" {
+		return fmt.Sprintf(assignedTagFmt, assignee)
+	}
+
+	return ""
+}
+
+// teamStatus returns a description of the team.
+func teamStatus(t *domain.Team) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Team mode is on\nOwner: @%s\n", t.Owner)
+
+	members := "none"
+	if len(t.Members) != 0 {
+		members = "@" + strings.Join(t.Members, ", @")
+	}
+	fmt.Fprintf(&b, "Members: %s\n", members)
+
+	signReplies := teamOffArg
+	if t.SignReplies {
+		signReplies = teamOnArg
+	}
+	fmt.Fprintf(&b, "Signed replies: %s", signReplies)
+
+	if len(t.Assignments) != 0 {
+		b.WriteString("\nAssigned conversations:")
+		for _, assignment := range t.Assignments {
+			fmt.Fprintf(&b, "\n[jid: %s]%s - @%s",
+				assignment.RemoteJid,
+				domain.AccountTag(assignment.Account),
+				assignment.Assignee)
+		}
+	}
+
+	return b.String()
+}
+
+// removeMember removes the member from the team and releases the conversations
+// assigned to them.
+func removeMember(t *domain.Team, member string) error {
+	if t.IsOwner(member) {
+		return errUsage("The owner can't be removed from the team, type /team off to disable team mode")
+	}
+	if !t.IsMember(member) {
+		return errUsage(fmt.Sprintf(teamNotMemberFmt, member))
+	}
+
+	members := make([]string, 0, len(t.Members))
+	for _, m := range t.Members {
+		if !strings.EqualFold(m, member) {
+			members = append(members, m)
+		}
+	}
+	t.Members = members
+
+	assignments := make([]domain.Assignment, 0, len(t.Assignments))
+	for _, assignment := range t.Assignments {
+		if !strings.EqualFold(assignment.Assignee, member) {
+			assignments = append(assignments, assignment)
+		}
+	}
+	t.Assignments = assignments
+
+	return nil
+}
+
+// username returns a telegram username without leading "@".
+func username(s string) string {
+	return strings.TrimPrefix(strings.TrimSpace(s), "@")
+}
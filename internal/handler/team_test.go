@@ -0,0 +1,265 @@
+package handler_test
+
+import (
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/whatsapp/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testMemberName = "test-member"
+
+// team handles the team command sent by the user.
+func (env *testEnv) team(t *testing.T, user string, args ...string) {
+	t.Helper()
+
+	require.NoError(t, env.eventsHandler.HandleTeamEvent(&domain.TeamEvent{
+		ChatID:   testChatID,
+		FromUser: user,
+		Args:     args,
+	}))
+}
+
+// lastText returns the last text sent to telegram.
+func (env *testEnv) lastText(t *testing.T) string {
+	t.Helper()
+
+	texts := env.telegramClient.Texts()
+	require.NotEmpty(t, texts)
+
+	return texts[len(texts)-1]
+}
+
+func TestEventsHandlerTeam(t *testing.T) {
+	t.Run("team mode is off", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		env.team(t, testUserName)
+		assert.Contains(t, env.lastText(t), "Team mode is off")
+
+		// Everyone can reply while team mode is off
+		whatsappClientMock := &mocks.WhatsappClient{}
+		whatsappClientMock.On("Send", mock.Anything).Return(nil)
+		env.login(t, whatsappClientMock)
+
+		require.NoError(t, env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+			ChatID:    testChatID,
+			FromUser:  "stranger",
+			Reply:     "test reply",
+			RemoteJid: testRemoteJid,
+			Account:   testAccount,
+		}))
+		whatsappClientMock.AssertNumberOfCalls(t, "Send", 1)
+	})
+
+	t.Run("manage members", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		env.team(t, testUserName, "on")
+		assert.Contains(t, env.lastText(t), "you're the owner")
+
+		env.team(t, testUserName, "on")
+		assert.Equal(t, "Team mode is already on", env.lastText(t))
+
+		env.team(t, testMemberName, "add", "@someone")
+		assert.Equal(t, "Only the owner of the team can do that", env.lastText(t))
+
+		env.team(t, testUserName, "add", "@"+testMemberName)
+		assert.Equal(t, "@test-member is a member of the team now", env.lastText(t))
+
+		env.team(t, testUserName, "sign", "on")
+		assert.Equal(t, "Signed replies are on", env.lastText(t))
+
+		env.team(t, testMemberName)
+		assert.Equal(t, "Team mode is on\nOwner: @test-user\nMembers: @test-member\nSigned replies: on",
+			env.lastText(t))
+
+		env.team(t, testUserName, "remove", testUserName)
+		assert.Contains(t, env.lastText(t), "The owner can't be removed")
+
+		env.team(t, testUserName, "remove", "someone")
+		assert.Equal(t, "@someone is not a member of the team", env.lastText(t))
+
+		env.team(t, testUserName, "remove", testMemberName)
+		assert.Equal(t, "@test-member is not a member of the team anymore", env.lastText(t))
+
+		env.team(t, testUserName, "invite")
+		assert.Contains(t, env.lastText(t), "Usage: /team")
+
+		env.team(t, testUserName, "off")
+		assert.Contains(t, env.lastText(t), "Team mode is off")
+
+		_, ok := env.teams.Get(testChatID)
+		assert.False(t, ok)
+	})
+
+	t.Run("only members can use the bot", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		whatsappClientMock := &mocks.WhatsappClient{}
+		whatsappClientMock.On("Send", mock.Anything).Return(nil)
+		env.login(t, whatsappClientMock)
+		env.team(t, testUserName, "on")
+
+		require.NoError(t, env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+			ChatID:    testChatID,
+			FromUser:  "stranger",
+			Reply:     "test reply",
+			RemoteJid: testRemoteJid,
+			Account:   testAccount,
+		}))
+		assert.Contains(t, env.lastText(t), "You're not a member of the team")
+
+		require.NoError(t, env.eventsHandler.HandleLogoutEvent(&domain.LogoutEvent{
+			ChatID:   testChatID,
+			FromUser: "stranger",
+			Account:  testAccount,
+		}))
+		assert.Contains(t, env.lastText(t), "You're not a member of the team")
+
+		require.NoError(t, env.eventsHandler.HandleRetryEvent(&domain.RetryEvent{
+			ChatID:     testChatID,
+			FromUser:   "stranger",
+			CallbackID: "test-callback-id",
+			MessageID:  "test-message-id",
+		}))
+		assert.Contains(t, env.lastText(t), "You're not a member of the team")
+
+		require.NoError(t, env.eventsHandler.HandleCancelLoginEvent(&domain.CancelLoginEvent{
+			ChatID:     testChatID,
+			FromUser:   "stranger",
+			CallbackID: "test-callback-id",
+			Account:    testAccount,
+		}))
+		assert.Contains(t, env.lastText(t), "You're not a member of the team")
+		assert.Empty(t, env.telegramClient.CallbackAnswers())
+
+		whatsappClientMock.AssertNotCalled(t, "Send", mock.Anything)
+		whatsappClientMock.AssertNotCalled(t, "Logout")
+		assert.True(t, env.eventsHandler.IsLoggedIn(testAccount))
+		assert.Empty(t, env.outbox.Messages(testChatID))
+	})
+
+	t.Run("signed replies", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		whatsappClientMock := &mocks.WhatsappClient{}
+		whatsappClientMock.On("Send", mock.Anything).Return(nil)
+		env.login(t, whatsappClientMock)
+		env.team(t, testUserName, "on")
+		env.team(t, testUserName, "add", testMemberName)
+		env.team(t, testUserName, "sign", "on")
+
+		require.NoError(t, env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+			ChatID:    testChatID,
+			FromUser:  testMemberName,
+			FromName:  "Test Member",
+			Reply:     "test reply",
+			RemoteJid: testRemoteJid,
+			Account:   testAccount,
+		}))
+
+		whatsappClientMock.AssertCalled(t, "Send", sentText(testRemoteJid, "test reply\n\n— Test Member"))
+	})
+
+	t.Run("assign conversation", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		whatsappClientMock := &mocks.WhatsappClient{}
+		whatsappClientMock.On("Send", mock.Anything).Return(nil)
+		env.login(t, whatsappClientMock)
+		env.team(t, testUserName, "on")
+		env.team(t, testUserName, "add", testMemberName)
+		env.team(t, testUserName, "add", "another-member")
+
+		// The member claims the conversation
+		require.NoError(t, env.eventsHandler.HandleAssignEvent(&domain.AssignEvent{
+			ChatID:    testChatID,
+			FromUser:  testMemberName,
+			Assignee:  testMemberName,
+			RemoteJid: testRemoteJid,
+			Account:   testAccount,
+		}))
+		assert.Equal(t, "The conversation [jid: test-remote-jid] is assigned to @test-member", env.lastText(t))
+
+		// Incoming messages are tagged with the assignee
+		require.NoError(t, env.eventsHandler.HandleTextMessageEvent(&domain.TextMessageEvent{
+			ChatID:             testChatID,
+			WhatsappRemoteJid:  testRemoteJid,
+			WhatsappSenderName: "test-sender",
+			Text:               "hello, world!",
+			Account:            testAccount,
+		}))
+		assert.Contains(t, env.lastText(t), "[jid: test-remote-jid] [assigned: @test-member]")
+		assert.Equal(t, testRemoteJid, domain.ExtractMsgJid(env.lastText(t)))
+
+		// Other members can neither reply nor claim it
+		require.NoError(t, env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+			ChatID:    testChatID,
+			FromUser:  "another-member",
+			Reply:     "test reply",
+			RemoteJid: testRemoteJid,
+			Account:   testAccount,
+		}))
+		assert.Contains(t, env.lastText(t), "is assigned to @test-member, the reply is not sent")
+		whatsappClientMock.AssertNotCalled(t, "Send", mock.Anything)
+
+		require.NoError(t, env.eventsHandler.HandleAssignEvent(&domain.AssignEvent{
+			ChatID:    testChatID,
+			FromUser:  "another-member",
+			Assignee:  "another-member",
+			RemoteJid: testRemoteJid,
+			Account:   testAccount,
+		}))
+		assert.Contains(t, env.lastText(t), "ask them or the owner to reassign it")
+
+		// The assignee and the owner can reply
+		for _, user := range []string{testMemberName, testUserName} {
+			require.NoError(t, env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+				ChatID:    testChatID,
+				FromUser:  user,
+				Reply:     "test reply",
+				RemoteJid: testRemoteJid,
+				Account:   testAccount,
+			}))
+		}
+		whatsappClientMock.AssertNumberOfCalls(t, "Send", 2)
+
+		// The owner releases the conversation
+		require.NoError(t, env.eventsHandler.HandleAssignEvent(&domain.AssignEvent{
+			ChatID:    testChatID,
+			FromUser:  testUserName,
+			RemoteJid: testRemoteJid,
+			Account:   testAccount,
+		}))
+		assert.Equal(t, "The conversation [jid: test-remote-jid] is not assigned anymore", env.lastText(t))
+
+		got, ok := env.teams.Get(testChatID)
+		require.True(t, ok)
+		assert.Empty(t, got.Assignments)
+	})
+
+	t.Run("assign conversation to a stranger", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.team(t, testUserName, "on")
+
+		require.NoError(t, env.eventsHandler.HandleAssignEvent(&domain.AssignEvent{
+			ChatID:    testChatID,
+			FromUser:  testUserName,
+			Assignee:  "@stranger",
+			RemoteJid: testRemoteJid,
+			Account:   testAccount,
+		}))
+		assert.Equal(t, "@stranger is not a member of the team", env.lastText(t))
+
+		require.NoError(t, env.eventsHandler.HandleAssignEvent(&domain.AssignEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+			Assignee: testUserName,
+		}))
+		assert.Contains(t, env.lastText(t), "Reply to a message with /assign")
+	})
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"go.uber.org/zap"
+)
+
+const (
+	topicsOnMsg = "WhatsApp conversations are bridged into topics now, every conversation gets its own topic. " +
+		"Make sure topics are enabled in the group and the bot is allowed to manage them"
+	topicsOffMsg         = "WhatsApp conversations are bridged into the chat itself"
+	topicsUnsupportedMsg = "Topics are not supported"
+	topicsUsageMsg       = "Usage: /topics [on|off]"
+)
+
+// maxTopicNameLength is a maximum length of a telegram forum topic name.
+const maxTopicNameLength = 128
+
+// HandleTopicsEvent method handles topics event.
+func (eh *EventsHandler) HandleTopicsEvent(event *domain.TopicsEvent) error {
+	eh.log.Debug("handle topics event",
+		zap.String("username", event.FromUser),
+		zap.Int64("chat_id", event.ChatID),
+		zap.Strings("args", event.Args))
+
+	if ok, err := eh.authorize(event.FromUser); !ok || err != nil {
+		return err
+	}
+
+	msg, err := eh.applyTopicsCommand(event)
+	if err != nil {
+		return err
+	}
+
+	if err := eh.notifyTelegram(msg); err != nil {
+		return fmt.Errorf("failed to notify telegram: %w", err)
+	}
+
+	return nil
+}
+
+// applyTopicsCommand applies the topics command and returns a message to reply with.
+func (eh *EventsHandler) applyTopicsCommand(event *domain.TopicsEvent) (string, error) {
+	if eh.topics == nil {
+		return topicsUnsupportedMsg, nil
+	}
+
+	switch {
+	case len(event.Args) == 0:
+		if eh.topics.Enabled(eh.chatID) {
+			return topicsOnMsg, nil
+		}
+
+		return topicsOffMsg, nil
+	case len(event.Args) == 1 && (event.Args[0] == teamOnArg || event.Args[0] == teamOffArg):
+		enabled := event.Args[0] == teamOnArg
+		if err := eh.topics.SetEnabled(eh.chatID, enabled); err != nil {
+			return "", fmt.Errorf("failed to update topics: %w", err)
+		}
+
+		if enabled {
+			return topicsOnMsg, nil
+		}
+
+		return topicsOffMsg, nil
+	default:
+		return topicsUsageMsg, nil
+	}
+}
+
+// sendConversationMessage sends the message that belongs to the whatsapp conversation,
+// it's sent to the topic of the conversation if topics are enabled in the chat.
+// The topic is created on the first message and recreated if it has been deleted.
+func (eh *EventsHandler) sendConversationMessage(account, remoteJid, name string,
+	msg domain.TelegramTextMessage) (postedMessage, error) {
+	if eh.topics == nil || !eh.topics.Enabled(eh.chatID) {
+		return eh.sendTopicMessage(0, msg)
+	}
+
+	topic, ok := eh.topics.Find(eh.chatID, account, remoteJid)
+	if ok {
+		posted, err := eh.sendTopicMessage(topic.ThreadID, msg)
+		if !errors.Is(err, domain.ErrTelegramTopicNotFound) {
+			return posted, err
+		}
+
+		eh.log.Debug("topic has been deleted, creating a new one",
+			zap.Int("thread_id", topic.ThreadID),
+			zap.String("remote_jid", remoteJid),
+			zap.String("account", account))
+	}
+
+	topic, err := eh.createTopic(account, remoteJid, name)
+	if err != nil {
+		return postedMessage{}, err
+	}
+
+	return eh.sendTopicMessage(topic.ThreadID, msg)
+}
+
+// createTopic creates a topic of the whatsapp conversation and saves it.
+func (eh *EventsHandler) createTopic(account, remoteJid, name string) (domain.TelegramTopic, error) {
+	if name == "" {
+		name = remoteJid
+	}
+	name += domain.AccountTag(account)
+	if runes := []rune(name); len(runes) > maxTopicNameLength {
+		name = string(runes[:maxTopicNameLength])
+	}
+
+	threadID, err := eh.telegramClient.CreateTopic(eh.chatID, name)
+	if err != nil {
+		return domain.TelegramTopic{}, fmt.Errorf("failed to create topic in telegram: %w", err)
+	}
+
+	topic := domain.TelegramTopic{
+		ChatID:    eh.chatID,
+		ThreadID:  threadID,
+		Account:   account,
+		RemoteJid: remoteJid,
+	}
+	if err := eh.topics.Add(topic); err != nil {
+		return domain.TelegramTopic{}, fmt.Errorf("failed to save topic: %w", err)
+	}
+
+	return topic, nil
+}
+
+// sendTopicMessage sends the message to the topic of this chat, it's sent
+// to the chat itself if the thread identifier is zero.
+func (eh *EventsHandler) sendTopicMessage(threadID int, msg domain.TelegramTextMessage) (postedMessage, error) {
+	msg.ChatID = eh.chatID
+	msg.ThreadID = threadID
+	messageID, err := eh.telegramClient.SendText(&msg)
+	if err != nil {
+		return postedMessage{}, fmt.Errorf("failed to send message to telegram: %w", err)
+	}
+
+	return postedMessage{chatID: eh.chatID, messageID: messageID}, nil
+}
+
+// resolveTopicReply fills the whatsapp conversation of the reply posted to its topic,
+// false is returned if the reply doesn't belong to any conversation.
+func (eh *EventsHandler) resolveTopicReply(event *domain.ReplyEvent) bool {
+	if event.RemoteJid != "" {
+		return true
+	}
+	if eh.topics == nil || event.ThreadID == 0 || event.ChatID != eh.chatID {
+		return false
+	}
+
+	topic, ok := eh.topics.FindByThread(eh.chatID, event.ThreadID)
+	if !ok {
+		return false
+	}
+	event.RemoteJid = topic.RemoteJid
+	event.Account = topic.Account
+
+	return true
+}
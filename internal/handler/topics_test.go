@@ -0,0 +1,145 @@
+package handler_test
+
+import (
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/whatsapp/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// topicsCommand handles the topics command.
+func (env *testEnv) topicsCommand(t *testing.T, args ...string) {
+	t.Helper()
+
+	require.NoError(t, env.eventsHandler.HandleTopicsEvent(&domain.TopicsEvent{
+		ChatID:   testChatID,
+		FromUser: testUserName,
+		Args:     args,
+	}))
+}
+
+// receive handles the text message received by the whatsapp account.
+func (env *testEnv) receive(t *testing.T, remoteJid, senderName, text string) {
+	t.Helper()
+
+	require.NoError(t, env.eventsHandler.HandleTextMessageEvent(&domain.TextMessageEvent{
+		ChatID:             testChatID,
+		WhatsappRemoteJid:  remoteJid,
+		WhatsappSenderName: senderName,
+		Text:               text,
+		Account:            testAccount,
+	}))
+}
+
+func TestEventsHandlerTopics(t *testing.T) {
+	t.Run("topics command", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		env.topicsCommand(t)
+		assert.Contains(t, env.lastText(t), "bridged into the chat itself")
+
+		env.topicsCommand(t, "on")
+		assert.Contains(t, env.lastText(t), "bridged into topics now")
+		assert.True(t, env.topics.Enabled(testChatID))
+
+		env.topicsCommand(t, "enable")
+		assert.Equal(t, "Usage: /topics [on|off]", env.lastText(t))
+
+		env.topicsCommand(t, "off")
+		assert.Contains(t, env.lastText(t), "bridged into the chat itself")
+		assert.False(t, env.topics.Enabled(testChatID))
+	})
+
+	t.Run("messages are bridged into topics", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.topicsCommand(t, "on")
+
+		env.receive(t, "alice-jid", "Alice", "hi")
+		env.receive(t, "bob-jid", "", "hello")
+		env.receive(t, "alice-jid", "Alice", "are you there?")
+
+		topics := env.telegramClient.Topics()
+		require.Len(t, topics, 2)
+
+		aliceTopic, ok := env.topics.Find(testChatID, testAccount, "alice-jid")
+		require.True(t, ok)
+		assert.Equal(t, "Alice", topics[aliceTopic.ThreadID])
+		bobTopic, ok := env.topics.Find(testChatID, testAccount, "bob-jid")
+		require.True(t, ok)
+		assert.Equal(t, "bob-jid", topics[bobTopic.ThreadID])
+
+		sent := env.telegramClient.TextMessages()
+		require.Len(t, sent, 4)
+		assert.Equal(t, aliceTopic.ThreadID, sent[1].ThreadID)
+		assert.Contains(t, sent[1].Text, "hi")
+		assert.Equal(t, bobTopic.ThreadID, sent[2].ThreadID)
+		assert.Equal(t, aliceTopic.ThreadID, sent[3].ThreadID)
+		assert.Equal(t, "alice-jid", domain.ExtractMsgJid(sent[3].Text))
+	})
+
+	t.Run("message posted to topic is sent to the conversation", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		whatsappClientMock := &mocks.WhatsappClient{}
+		whatsappClientMock.On("Send", mock.Anything).Return(nil)
+		env.login(t, whatsappClientMock)
+		env.topicsCommand(t, "on")
+		env.receive(t, "alice-jid", "Alice", "hi")
+
+		aliceTopic, ok := env.topics.Find(testChatID, testAccount, "alice-jid")
+		require.True(t, ok)
+
+		require.NoError(t, env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+			Reply:    "hi, Alice",
+			ThreadID: aliceTopic.ThreadID,
+		}))
+
+		whatsappClientMock.AssertCalled(t, "Send", sentText("alice-jid", "hi, Alice"))
+	})
+
+	t.Run("message posted outside of topics is ignored", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		whatsappClientMock := &mocks.WhatsappClient{}
+		env.login(t, whatsappClientMock)
+		env.topicsCommand(t, "on")
+		textsBefore := len(env.telegramClient.Texts())
+
+		require.NoError(t, env.eventsHandler.HandleReplyEvent(&domain.ReplyEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+			Reply:    "hi",
+			ThreadID: 100,
+		}))
+
+		whatsappClientMock.AssertNotCalled(t, "Send", mock.Anything)
+		assert.Len(t, env.telegramClient.Texts(), textsBefore)
+		assert.Empty(t, env.outbox.Messages(testChatID))
+	})
+
+	t.Run("deleted topic is recreated", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.topicsCommand(t, "on")
+
+		env.receive(t, "alice-jid", "Alice", "hi")
+		deletedTopic, ok := env.topics.Find(testChatID, testAccount, "alice-jid")
+		require.True(t, ok)
+		env.telegramClient.DeleteTopic(deletedTopic.ThreadID)
+
+		env.receive(t, "alice-jid", "Alice", "are you there?")
+
+		recreatedTopic, ok := env.topics.Find(testChatID, testAccount, "alice-jid")
+		require.True(t, ok)
+		assert.NotEqual(t, deletedTopic.ThreadID, recreatedTopic.ThreadID)
+		assert.Equal(t, map[int]string{recreatedTopic.ThreadID: "Alice"}, env.telegramClient.Topics())
+
+		sent := env.telegramClient.TextMessages()
+		assert.Equal(t, recreatedTopic.ThreadID, sent[len(sent)-1].ThreadID)
+		assert.Contains(t, sent[len(sent)-1].Text, "are you there?")
+	})
+}
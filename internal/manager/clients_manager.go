@@ -2,20 +2,52 @@ package manager
 
 import (
 	"context"
+	"time"
 
+	"github.com/dstdfx/twbridge/internal/archive"
+	"github.com/dstdfx/twbridge/internal/away"
+	"github.com/dstdfx/twbridge/internal/conversation"
+	"github.com/dstdfx/twbridge/internal/digest"
 	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/files"
 	"github.com/dstdfx/twbridge/internal/handler"
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/dstdfx/twbridge/internal/outbox"
+	"github.com/dstdfx/twbridge/internal/quiet"
+	"github.com/dstdfx/twbridge/internal/route"
+	"github.com/dstdfx/twbridge/internal/rules"
+	"github.com/dstdfx/twbridge/internal/schedule"
+	"github.com/dstdfx/twbridge/internal/settings"
+	"github.com/dstdfx/twbridge/internal/team"
+	"github.com/dstdfx/twbridge/internal/topic"
 	"go.uber.org/zap"
 )
 
 // Manager handles incoming events from telegram provider and manages new
 // and existing clients of the bot.
 type Manager struct {
-	log            *zap.Logger
-	incomingEvents chan domain.Event
-	telegramAPI    *tgbotapi.BotAPI
-	eventHandlers  map[int64]domain.EventsHandler
+	log             *zap.Logger
+	incomingEvents  chan domain.Event
+	telegramClient  domain.TelegramClient
+	whatsappBackend domain.WhatsappBackend
+	outbox          *outbox.Outbox
+	teams           *team.Store
+	topics          *topic.Store
+	routes          *route.Store
+	conversations   *conversation.Store
+	settings        *settings.Store
+	digests         *digest.Store
+	rules           *rules.Store
+	quietHours      *quiet.Store
+	schedules       *schedule.Store
+	away            *away.Store
+	archive         *archive.Store
+	files           *files.Store
+	maxUploadSize   int
+	historyChats    int
+	historyMessages int
+	presenceChats   int
+	tickInterval    time.Duration
+	eventHandlers   map[int64]domain.EventsHandler
 }
 
 // Opts represents options to create new instance of Manager.
@@ -23,27 +55,121 @@ type Opts struct {
 	// IncomingEvents is a channel to receive events from.
 	IncomingEvents chan domain.Event
 
-	// TelegramAPI is a client to interact with telegram API.
-	TelegramAPI *tgbotapi.BotAPI
+	// TelegramClient is a client to interact with telegram API.
+	TelegramClient domain.TelegramClient
+
+	// WhatsappBackend is a backend that establishes whatsapp sessions.
+	WhatsappBackend domain.WhatsappBackend
+
+	// Outbox is a queue of outgoing whatsapp messages shared by all clients.
+	Outbox *outbox.Outbox
+
+	// Teams is a storage of the teams shared by all clients.
+	Teams *team.Store
+
+	// Topics is a storage of the forum topics shared by all clients.
+	Topics *topic.Store
+
+	// Routes is a routing table of whatsapp conversations shared by all clients.
+	Routes *route.Store
+
+	// Conversations is a storage of the active conversations shared by all clients.
+	Conversations *conversation.Store
+
+	// Settings is a storage of the settings of whatsapp contacts shared by all clients.
+	Settings *settings.Store
+
+	// Digests is a storage of the messages collected to the digests shared by all clients.
+	Digests *digest.Store
+
+	// Rules is a storage of the rules applied to incoming messages shared by all clients.
+	Rules *rules.Store
+
+	// QuietHours is a storage of quiet hours of the chats shared by all clients.
+	QuietHours *quiet.Store
+
+	// Schedules is a storage of the scheduled messages shared by all clients.
+	Schedules *schedule.Store
+
+	// Away is a storage of away modes of the chats shared by all clients.
+	Away *away.Store
+
+	// Archive is a storage of the bridged messages shared by all clients, expired
+	// messages are removed every tick.
+	Archive *archive.Store
+
+	// Files is a local storage of the files too large to upload to telegram, expired
+	// files are removed every tick.
+	Files *files.Store
+
+	// MaxUploadSize is the largest file in bytes uploaded to telegram, the limit of the bot API
+	// is used if it's zero.
+	MaxUploadSize int
+
+	// HistoryChats is a number of the recent whatsapp chats whose history is loaded
+	// once an account is logged in, history isn't loaded on login if it's zero.
+	HistoryChats int
+
+	// HistoryMessages is a number of the last messages loaded per chat on login.
+	HistoryMessages int
+
+	// PresenceChats is a number of the recent whatsapp chats whose contacts are subscribed
+	// to presence updates once an account is logged in, presence is not supported if it's zero.
+	PresenceChats int
+
+	// TickInterval is an interval the clients run scheduled jobs with, a minute is used if it's zero.
+	TickInterval time.Duration
 }
 
+// defaultTickInterval is an interval the clients run scheduled jobs with by default.
+const defaultTickInterval = time.Minute
+
 // NewManager returns new instance of NewManager.
 func NewManager(log *zap.Logger, opts *Opts) *Manager {
+	tickInterval := opts.TickInterval
+	if tickInterval == 0 {
+		tickInterval = defaultTickInterval
+	}
+
 	return &Manager{
-		log:            log,
-		incomingEvents: opts.IncomingEvents,
-		eventHandlers:  make(map[int64]domain.EventsHandler),
-		telegramAPI:    opts.TelegramAPI,
+		log:             log,
+		incomingEvents:  opts.IncomingEvents,
+		eventHandlers:   make(map[int64]domain.EventsHandler),
+		telegramClient:  opts.TelegramClient,
+		whatsappBackend: opts.WhatsappBackend,
+		outbox:          opts.Outbox,
+		teams:           opts.Teams,
+		topics:          opts.Topics,
+		routes:          opts.Routes,
+		conversations:   opts.Conversations,
+		settings:        opts.Settings,
+		digests:         opts.Digests,
+		rules:           opts.Rules,
+		quietHours:      opts.QuietHours,
+		schedules:       opts.Schedules,
+		away:            opts.Away,
+		archive:         opts.Archive,
+		files:           opts.Files,
+		maxUploadSize:   opts.MaxUploadSize,
+		historyChats:    opts.HistoryChats,
+		historyMessages: opts.HistoryMessages,
+		presenceChats:   opts.PresenceChats,
+		tickInterval:    tickInterval,
 	}
 }
 
 // Run method starts the main goroutine of Manager.
 // The call is blocking.
 func (mgr *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(mgr.tickInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case now := <-ticker.C:
+			mgr.tick(now)
 		case event, ok := <-mgr.incomingEvents:
 			if !ok {
 				return
@@ -59,11 +185,7 @@ func (mgr *Manager) Run(ctx context.Context) {
 				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
 				if !ok {
 					// Create events handler for new client and handle event
-					eventsHandler = handler.NewEventsHandler(mgr.log, &handler.Opts{
-						ChatID:                 e.ChatID,
-						WhatsappProviderEvents: mgr.incomingEvents,
-						TelegramAPI:            mgr.telegramAPI,
-					})
+					eventsHandler = mgr.newEventsHandler(e.ChatID)
 
 					// Add it to the mapping
 					mgr.eventHandlers[e.ChatID] = eventsHandler
@@ -82,7 +204,7 @@ func (mgr *Manager) Run(ctx context.Context) {
 					continue
 				}
 
-				if eventsHandler.IsLoggedIn() {
+				if eventsHandler.IsLoggedIn(e.Account) {
 					if err := eventsHandler.HandleRepeatedLoginEvent(e); err != nil {
 						mgr.log.Error("failed to handle repeated login event", zap.Error(err))
 					}
@@ -118,7 +240,9 @@ func (mgr *Manager) Run(ctx context.Context) {
 					mgr.log.Error("failed to handle help event", zap.Error(err))
 				}
 			case *domain.ReplyEvent:
-				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				// Replies from the chats conversations are routed to are handled
+				// by the chat the whatsapp account is logged in from
+				eventsHandler, ok := mgr.eventHandlers[mgr.replyOwner(e)]
 				if !ok {
 					mgr.log.Error("failed to find events handler for the chat_id",
 						zap.Int64("chat_id", e.ChatID))
@@ -153,7 +277,472 @@ func (mgr *Manager) Run(ctx context.Context) {
 				if err := eventsHandler.HandleDisconnectEvent(e); err != nil {
 					mgr.log.Error("failed to handle disconnect event", zap.Error(err))
 				}
+			case *domain.RestoreEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleRestoreEvent(e); err != nil {
+					mgr.log.Error("failed to handle restore event", zap.Error(err))
+				}
+			case *domain.RetryEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleRetryEvent(e); err != nil {
+					mgr.log.Error("failed to handle retry event", zap.Error(err))
+				}
+			case *domain.CancelLoginEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleCancelLoginEvent(e); err != nil {
+					mgr.log.Error("failed to handle cancel login event", zap.Error(err))
+				}
+			case *domain.LoginResultEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleLoginResultEvent(e); err != nil {
+					mgr.log.Error("failed to handle login result event", zap.Error(err))
+				}
+			case *domain.TeamEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleTeamEvent(e); err != nil {
+					mgr.log.Error("failed to handle team event", zap.Error(err))
+				}
+			case *domain.AssignEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleAssignEvent(e); err != nil {
+					mgr.log.Error("failed to handle assign event", zap.Error(err))
+				}
+			case *domain.TopicsEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleTopicsEvent(e); err != nil {
+					mgr.log.Error("failed to handle topics event", zap.Error(err))
+				}
+			case *domain.RouteEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleRouteEvent(e); err != nil {
+					mgr.log.Error("failed to handle route event", zap.Error(err))
+				}
+			case *domain.ChatEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleChatEvent(e); err != nil {
+					mgr.log.Error("failed to handle chat event", zap.Error(err))
+				}
+			case *domain.CloseChatEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleCloseChatEvent(e); err != nil {
+					mgr.log.Error("failed to handle close chat event", zap.Error(err))
+				}
+			case *domain.SettingsEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleSettingsEvent(e); err != nil {
+					mgr.log.Error("failed to handle settings event", zap.Error(err))
+				}
+			case *domain.DigestEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleDigestEvent(e); err != nil {
+					mgr.log.Error("failed to handle digest event", zap.Error(err))
+				}
+			case *domain.RulesEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleRulesEvent(e); err != nil {
+					mgr.log.Error("failed to handle rules event", zap.Error(err))
+				}
+			case *domain.QuietEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleQuietEvent(e); err != nil {
+					mgr.log.Error("failed to handle quiet event", zap.Error(err))
+				}
+			case *domain.ScheduleEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleScheduleEvent(e); err != nil {
+					mgr.log.Error("failed to handle schedule event", zap.Error(err))
+				}
+			case *domain.UnscheduleEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleUnscheduleEvent(e); err != nil {
+					mgr.log.Error("failed to handle unschedule event", zap.Error(err))
+				}
+			case *domain.AwayEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleAwayEvent(e); err != nil {
+					mgr.log.Error("failed to handle away event", zap.Error(err))
+				}
+			case *domain.SearchEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleSearchEvent(e); err != nil {
+					mgr.log.Error("failed to handle search event", zap.Error(err))
+				}
+			case *domain.ExportEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleExportEvent(e); err != nil {
+					mgr.log.Error("failed to handle export event", zap.Error(err))
+				}
+			case *domain.HistoryEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleHistoryEvent(e); err != nil {
+					mgr.log.Error("failed to handle history event", zap.Error(err))
+				}
+			case *domain.ChatsEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleChatsEvent(e); err != nil {
+					mgr.log.Error("failed to handle chats event", zap.Error(err))
+				}
+			case *domain.PresenceEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandlePresenceEvent(e); err != nil {
+					mgr.log.Error("failed to handle presence event", zap.Error(err))
+				}
+			case *domain.LastSeenEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleLastSeenEvent(e); err != nil {
+					mgr.log.Error("failed to handle lastseen event", zap.Error(err))
+				}
+			case *domain.RevokeEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleRevokeEvent(e); err != nil {
+					mgr.log.Error("failed to handle revoke event", zap.Error(err))
+				}
+			case *domain.EditEvent:
+				// Bridged messages of the chats conversations are routed to are handled
+				// by the chat the whatsapp account is logged in from
+				eventsHandler, ok := mgr.eventHandlers[mgr.messageOwner(e.ChatID, e.MessageID)]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleEditEvent(e); err != nil {
+					mgr.log.Error("failed to handle edit event", zap.Error(err))
+				}
+			case *domain.DeleteEvent:
+				eventsHandler, ok := mgr.eventHandlers[mgr.messageOwner(e.ChatID, e.MessageID)]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleDeleteEvent(e); err != nil {
+					mgr.log.Error("failed to handle delete event", zap.Error(err))
+				}
+			case *domain.ReactionEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleReactionEvent(e); err != nil {
+					mgr.log.Error("failed to handle reaction event", zap.Error(err))
+				}
+			case *domain.ReactEvent:
+				eventsHandler, ok := mgr.eventHandlers[mgr.messageOwner(e.ChatID, e.MessageID)]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleReactEvent(e); err != nil {
+					mgr.log.Error("failed to handle react event", zap.Error(err))
+				}
+			case *domain.LocationEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleLocationEvent(e); err != nil {
+					mgr.log.Error("failed to handle location event", zap.Error(err))
+				}
+			case *domain.ContactCardEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleContactCardEvent(e); err != nil {
+					mgr.log.Error("failed to handle contact card event", zap.Error(err))
+				}
+			case *domain.DocumentEvent:
+				eventsHandler, ok := mgr.eventHandlers[e.ChatID]
+				if !ok {
+					mgr.log.Error("failed to find events handler for the chat_id",
+						zap.Int64("chat_id", e.ChatID))
+
+					continue
+				}
+
+				if err := eventsHandler.HandleDocumentEvent(e); err != nil {
+					mgr.log.Error("failed to handle document event", zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+// tick removes expired files and archived messages and sends tick event to every events handler,
+// so they run their scheduled jobs.
+func (mgr *Manager) tick(now time.Time) {
+	if mgr.files != nil {
+		if err := mgr.files.RemoveExpired(); err != nil {
+			mgr.log.Error("failed to remove expired files", zap.Error(err))
+		}
+	}
+	if mgr.archive != nil {
+		if err := mgr.archive.RemoveExpired(); err != nil {
+			mgr.log.Error("failed to remove expired archived messages", zap.Error(err))
+		}
+	}
+
+	// Scheduled messages are sent even if the chat hasn't started the bot since
+	// the restart, they are put to the outbox until the account is logged in
+	if mgr.schedules != nil {
+		for _, chatID := range mgr.schedules.Chats() {
+			if _, ok := mgr.eventHandlers[chatID]; !ok {
+				mgr.eventHandlers[chatID] = mgr.newEventsHandler(chatID)
 			}
 		}
 	}
+
+	for chatID, eventsHandler := range mgr.eventHandlers {
+		if err := eventsHandler.HandleTickEvent(&domain.TickEvent{
+			ChatID: chatID,
+			Time:   now,
+		}); err != nil {
+			mgr.log.Error("failed to handle tick event", zap.Error(err), zap.Int64("chat_id", chatID))
+		}
+	}
+}
+
+// newEventsHandler returns new events handler of the telegram chat.
+func (mgr *Manager) newEventsHandler(chatID int64) domain.EventsHandler {
+	return handler.NewEventsHandler(mgr.log, &handler.Opts{
+		ChatID:                 chatID,
+		WhatsappProviderEvents: mgr.incomingEvents,
+		TelegramClient:         mgr.telegramClient,
+		WhatsappBackend:        mgr.whatsappBackend,
+		Outbox:                 mgr.outbox,
+		Teams:                  mgr.teams,
+		Topics:                 mgr.topics,
+		Routes:                 mgr.routes,
+		Conversations:          mgr.conversations,
+		Settings:               mgr.settings,
+		Digests:                mgr.digests,
+		Rules:                  mgr.rules,
+		QuietHours:             mgr.quietHours,
+		Schedules:              mgr.schedules,
+		Away:                   mgr.away,
+		Archive:                mgr.archive,
+		Files:                  mgr.files,
+		MaxUploadSize:          mgr.maxUploadSize,
+		HistoryChats:           mgr.historyChats,
+		HistoryMessages:        mgr.historyMessages,
+		PresenceChats:          mgr.presenceChats,
+	})
+}
+
+// messageOwner returns telegram chat identifier of the events handler the telegram
+// message belongs to. It's the chat the message is posted to unless the message
+// is archived by another chat, e.g. it's posted to the chat the conversation is routed to.
+func (mgr *Manager) messageOwner(chatID int64, messageID int) int64 {
+	if mgr.archive == nil || messageID == 0 {
+		return chatID
+	}
+
+	if msg, ok := mgr.archive.Posted(chatID, messageID); ok {
+		return msg.ChatID
+	}
+
+	return chatID
+}
+
+// replyOwner returns telegram chat identifier of the events handler the reply
+// belongs to. It's the chat the reply is written in unless the conversation
+// is routed there from another chat.
+func (mgr *Manager) replyOwner(event *domain.ReplyEvent) int64 {
+	if mgr.routes == nil || event.RemoteJid == "" {
+		return event.ChatID
+	}
+
+	if r, ok := mgr.routes.Owner(event.ChatID, event.Account, event.RemoteJid); ok {
+		return r.OwnerChatID
+	}
+
+	return event.ChatID
 }
@@ -2,12 +2,23 @@ package manager //nolint
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/dstdfx/twbridge/internal/archive"
 	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/files"
 	"github.com/dstdfx/twbridge/internal/handler/mocks"
+	"github.com/dstdfx/twbridge/internal/outbox"
+	"github.com/dstdfx/twbridge/internal/route"
+	"github.com/dstdfx/twbridge/internal/schedule"
+	"github.com/dstdfx/twbridge/internal/telegram/fake"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
@@ -49,6 +60,40 @@ func TestManager(t *testing.T) {
 		eventsHandlerMock.AssertCalled(t, "HandleStartEvent", mock.Anything)
 	})
 
+	t.Run("handle start event, new client", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		telegramClient := fake.NewClient()
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+			TelegramClient: telegramClient,
+		})
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send start event
+		incomingEventsCh <- &domain.StartEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		assert.Contains(t, testMgr.eventHandlers, testChatID)
+
+		sent := telegramClient.TextMessages()
+		require.Len(t, sent, 1)
+		assert.Equal(t, testChatID, sent[0].ChatID)
+	})
+
 	t.Run("handle login event", func(t *testing.T) {
 		incomingEventsCh := make(chan domain.Event)
 		testMgr := NewManager(zap.NewNop(), &Opts{
@@ -56,7 +101,7 @@ func TestManager(t *testing.T) {
 		})
 
 		eventsHandlerMock := &mocks.EventsHandler{}
-		eventsHandlerMock.On("IsLoggedIn").Return(false)
+		eventsHandlerMock.On("IsLoggedIn", mock.Anything).Return(false)
 		eventsHandlerMock.On("HandleLoginEvent", mock.Anything).Return(nil)
 
 		// Add test events handler
@@ -75,13 +120,14 @@ func TestManager(t *testing.T) {
 		incomingEventsCh <- &domain.LoginEvent{
 			ChatID:   testChatID,
 			FromUser: testUserName,
+			Account:  domain.DefaultWhatsappAccount,
 		}
 
 		// Stop clients manager
 		cancel()
 		wg.Wait()
 
-		eventsHandlerMock.AssertCalled(t, "IsLoggedIn")
+		eventsHandlerMock.AssertCalled(t, "IsLoggedIn", domain.DefaultWhatsappAccount)
 		eventsHandlerMock.AssertCalled(t, "HandleLoginEvent", mock.Anything)
 	})
 
@@ -92,7 +138,7 @@ func TestManager(t *testing.T) {
 		})
 
 		eventsHandlerMock := &mocks.EventsHandler{}
-		eventsHandlerMock.On("IsLoggedIn").Return(true)
+		eventsHandlerMock.On("IsLoggedIn", mock.Anything).Return(true)
 		eventsHandlerMock.On("HandleRepeatedLoginEvent", mock.Anything).Return(nil)
 
 		// Add test events handler
@@ -111,13 +157,14 @@ func TestManager(t *testing.T) {
 		incomingEventsCh <- &domain.LoginEvent{
 			ChatID:   testChatID,
 			FromUser: testUserName,
+			Account:  domain.DefaultWhatsappAccount,
 		}
 
 		// Stop clients manager
 		cancel()
 		wg.Wait()
 
-		eventsHandlerMock.AssertCalled(t, "IsLoggedIn")
+		eventsHandlerMock.AssertCalled(t, "IsLoggedIn", domain.DefaultWhatsappAccount)
 		eventsHandlerMock.AssertCalled(t, "HandleRepeatedLoginEvent", mock.Anything)
 	})
 
@@ -327,4 +374,1369 @@ func TestManager(t *testing.T) {
 
 		eventsHandlerMock.AssertCalled(t, "HandleDisconnectEvent", mock.Anything)
 	})
+
+	t.Run("handle restore event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleRestoreEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send restore event
+		incomingEventsCh <- &domain.RestoreEvent{
+			ChatID: testChatID,
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleRestoreEvent", mock.Anything)
+	})
+
+	t.Run("handle retry event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleRetryEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send retry event
+		incomingEventsCh <- &domain.RetryEvent{
+			ChatID:     testChatID,
+			FromUser:   testUserName,
+			CallbackID: "test-callback-id",
+			MessageID:  "test-message-id",
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleRetryEvent", mock.Anything)
+	})
+
+	t.Run("handle cancel login event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleCancelLoginEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send cancel login event
+		incomingEventsCh <- &domain.CancelLoginEvent{
+			ChatID:     testChatID,
+			FromUser:   testUserName,
+			CallbackID: "test-callback-id",
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleCancelLoginEvent", mock.Anything)
+	})
+
+	t.Run("handle login result event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleLoginResultEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send login result event
+		incomingEventsCh <- &domain.LoginResultEvent{
+			ChatID:      testChatID,
+			QRMessageID: 1,
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleLoginResultEvent", mock.Anything)
+	})
+
+	t.Run("handle team event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleTeamEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send team event
+		incomingEventsCh <- &domain.TeamEvent{
+			ChatID:   testChatID,
+			FromUser: "testuser",
+			Args:     []string{"on"},
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleTeamEvent", mock.Anything)
+	})
+
+	t.Run("handle assign event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleAssignEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send assign event
+		incomingEventsCh <- &domain.AssignEvent{
+			ChatID:    testChatID,
+			FromUser:  "testuser",
+			Assignee:  "testuser",
+			RemoteJid: "test-jid",
+			Account:   domain.DefaultWhatsappAccount,
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleAssignEvent", mock.Anything)
+	})
+
+	t.Run("handle topics event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleTopicsEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send topics event
+		incomingEventsCh <- &domain.TopicsEvent{
+			ChatID:   testChatID,
+			FromUser: "testuser",
+			Args:     []string{"on"},
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleTopicsEvent", mock.Anything)
+	})
+
+	t.Run("handle route event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleRouteEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send route event
+		incomingEventsCh <- &domain.RouteEvent{
+			ChatID:   testChatID,
+			FromUser: "testuser",
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleRouteEvent", mock.Anything)
+	})
+
+	t.Run("handle reply event from the routed chat", func(t *testing.T) {
+		routedChatID := int64(-100456)
+		routes, err := route.New(&route.Opts{Path: filepath.Join(t.TempDir(), "routes.json")})
+		require.NoError(t, err)
+		require.NoError(t, routes.Add(domain.Route{
+			OwnerChatID: testChatID,
+			Account:     domain.DefaultWhatsappAccount,
+			Jid:         "test-jid",
+			ChatID:      routedChatID,
+		}))
+
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+			Routes:         routes,
+		})
+
+		ownerHandlerMock := &mocks.EventsHandler{}
+		ownerHandlerMock.On("HandleReplyEvent", mock.Anything).Return(nil)
+		routedHandlerMock := &mocks.EventsHandler{}
+
+		// Add test events handlers, the routed chat may have its own one
+		testMgr.eventHandlers[testChatID] = ownerHandlerMock
+		testMgr.eventHandlers[routedChatID] = routedHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send reply event from the routed chat
+		replyEvent := &domain.ReplyEvent{
+			ChatID:    routedChatID,
+			FromUser:  testUserName,
+			Reply:     "test reply",
+			RemoteJid: "test-jid",
+			Account:   domain.DefaultWhatsappAccount,
+		}
+		incomingEventsCh <- replyEvent
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		ownerHandlerMock.AssertCalled(t, "HandleReplyEvent", replyEvent)
+		routedHandlerMock.AssertNotCalled(t, "HandleReplyEvent", mock.Anything)
+	})
+
+	t.Run("handle chat event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleChatEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send chat event
+		incomingEventsCh <- &domain.ChatEvent{
+			ChatID:  testChatID,
+			Contact: "Test User",
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleChatEvent", mock.Anything)
+	})
+
+	t.Run("handle close chat event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleCloseChatEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send close chat event
+		incomingEventsCh <- &domain.CloseChatEvent{
+			ChatID: testChatID,
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleCloseChatEvent", mock.Anything)
+	})
+
+	t.Run("handle settings event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleSettingsEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send settings event
+		incomingEventsCh <- &domain.SettingsEvent{
+			ChatID: testChatID,
+			Mode:   domain.ContactModeMute,
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleSettingsEvent", mock.Anything)
+	})
+
+	t.Run("handle digest event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleDigestEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send digest event
+		incomingEventsCh <- &domain.DigestEvent{
+			ChatID: testChatID,
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleDigestEvent", mock.Anything)
+	})
+
+	t.Run("handle rules event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleRulesEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send rules event
+		incomingEventsCh <- &domain.RulesEvent{
+			ChatID: testChatID,
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleRulesEvent", mock.Anything)
+	})
+
+	t.Run("handle quiet event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleQuietEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send quiet event
+		incomingEventsCh <- &domain.QuietEvent{
+			ChatID: testChatID,
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleQuietEvent", mock.Anything)
+	})
+
+	t.Run("tick events handlers", func(t *testing.T) {
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: make(chan domain.Event),
+			TickInterval:   time.Millisecond,
+		})
+
+		ticked := make(chan struct{}, 1)
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleTickEvent", mock.MatchedBy(func(event *domain.TickEvent) bool {
+			return event.ChatID == testChatID && !event.Time.IsZero()
+		})).Return(nil).Run(func(mock.Arguments) {
+			select {
+			case ticked <- struct{}{}:
+			default:
+			}
+		})
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		select {
+		case <-ticked:
+		case <-time.After(time.Second):
+			t.Error("events handler is not ticked")
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+	})
+
+	t.Run("handle schedule event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleScheduleEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send schedule event
+		incomingEventsCh <- &domain.ScheduleEvent{
+			ChatID: testChatID,
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleScheduleEvent", mock.Anything)
+	})
+
+	t.Run("handle unschedule event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleUnscheduleEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send unschedule event
+		incomingEventsCh <- &domain.UnscheduleEvent{
+			ChatID: testChatID,
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleUnscheduleEvent", mock.Anything)
+	})
+
+	t.Run("handle away event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleAwayEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send away event
+		incomingEventsCh <- &domain.AwayEvent{
+			ChatID: testChatID,
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleAwayEvent", mock.Anything)
+	})
+
+	t.Run("handle search event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleSearchEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send search event
+		incomingEventsCh <- &domain.SearchEvent{
+			ChatID: testChatID,
+			Query:  "lunch",
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleSearchEvent", mock.Anything)
+	})
+
+	t.Run("handle export event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleExportEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send export event
+		incomingEventsCh <- &domain.ExportEvent{
+			ChatID:  testChatID,
+			Contact: "Alice",
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleExportEvent", mock.Anything)
+	})
+
+	t.Run("handle history event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleHistoryEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send history event
+		incomingEventsCh <- &domain.HistoryEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+			Contact:  "Alice",
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleHistoryEvent", mock.Anything)
+	})
+
+	t.Run("handle chats event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleChatsEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send chats event
+		incomingEventsCh <- &domain.ChatsEvent{
+			ChatID:   testChatID,
+			FromUser: testUserName,
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleChatsEvent", mock.Anything)
+	})
+
+	t.Run("handle presence event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandlePresenceEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send presence event
+		incomingEventsCh <- &domain.PresenceEvent{
+			ChatID:            testChatID,
+			WhatsappRemoteJid: "alice@s.whatsapp.net",
+			Presence:          domain.WhatsappPresenceComposing,
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandlePresenceEvent", mock.Anything)
+	})
+
+	t.Run("handle last seen event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleLastSeenEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send last seen event
+		incomingEventsCh <- &domain.LastSeenEvent{
+			ChatID:   testChatID,
+			FromUser: "testuser",
+			Contact:  "Alice",
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleLastSeenEvent", mock.Anything)
+	})
+
+	t.Run("handle revoke event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleRevokeEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send revoke event
+		incomingEventsCh <- &domain.RevokeEvent{
+			ChatID:            testChatID,
+			WhatsappRemoteJid: "test-remote-jid",
+			WhatsappMessageID: "test-message-id",
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleRevokeEvent", mock.Anything)
+	})
+
+	t.Run("handle edit event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleEditEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send edit event
+		incomingEventsCh <- &domain.EditEvent{
+			ChatID:    testChatID,
+			FromUser:  "testuser",
+			MessageID: 17,
+			Text:      "see you at 6",
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleEditEvent", mock.Anything)
+	})
+
+	t.Run("handle edit event from the routed chat", func(t *testing.T) {
+		routedChatID := int64(-100456)
+		messageArchive, err := archive.New(zap.NewNop(), &archive.Opts{Path: filepath.Join(t.TempDir(), "archive.jsonl")})
+		require.NoError(t, err)
+		_, err = messageArchive.Add(domain.ArchivedMessage{
+			ChatID:            testChatID,
+			Direction:         domain.MessageDirectionOut,
+			TelegramChatID:    routedChatID,
+			TelegramMessageID: 17,
+		})
+		require.NoError(t, err)
+
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+			Archive:        messageArchive,
+		})
+
+		ownerHandlerMock := &mocks.EventsHandler{}
+		ownerHandlerMock.On("HandleEditEvent", mock.Anything).Return(nil)
+		routedHandlerMock := &mocks.EventsHandler{}
+		routedHandlerMock.On("HandleEditEvent", mock.Anything).Return(nil)
+
+		// Add test events handlers, the routed chat may have its own one
+		testMgr.eventHandlers[testChatID] = ownerHandlerMock
+		testMgr.eventHandlers[routedChatID] = routedHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send edit events of the message archived by the owner chat and of another one
+		editEvent := &domain.EditEvent{
+			ChatID:    routedChatID,
+			FromUser:  "routed-chat-user",
+			MessageID: 17,
+			Text:      "see you at 6",
+		}
+		incomingEventsCh <- editEvent
+		otherEditEvent := &domain.EditEvent{
+			ChatID:    routedChatID,
+			FromUser:  "routed-chat-user",
+			MessageID: 18,
+			Text:      "hi",
+		}
+		incomingEventsCh <- otherEditEvent
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		ownerHandlerMock.AssertCalled(t, "HandleEditEvent", editEvent)
+		ownerHandlerMock.AssertNumberOfCalls(t, "HandleEditEvent", 1)
+		routedHandlerMock.AssertCalled(t, "HandleEditEvent", otherEditEvent)
+		routedHandlerMock.AssertNumberOfCalls(t, "HandleEditEvent", 1)
+	})
+
+	t.Run("handle react event from the routed chat", func(t *testing.T) {
+		routedChatID := int64(-100456)
+		messageArchive, err := archive.New(zap.NewNop(), &archive.Opts{Path: filepath.Join(t.TempDir(), "archive.jsonl")})
+		require.NoError(t, err)
+		_, err = messageArchive.Add(domain.ArchivedMessage{
+			ChatID:            testChatID,
+			Direction:         domain.MessageDirectionOut,
+			TelegramChatID:    routedChatID,
+			TelegramMessageID: 17,
+		})
+		require.NoError(t, err)
+
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+			Archive:        messageArchive,
+		})
+
+		ownerHandlerMock := &mocks.EventsHandler{}
+		ownerHandlerMock.On("HandleReactEvent", mock.Anything).Return(nil)
+		routedHandlerMock := &mocks.EventsHandler{}
+		routedHandlerMock.On("HandleReactEvent", mock.Anything).Return(nil)
+
+		// Add test events handlers, the routed chat may have its own one
+		testMgr.eventHandlers[testChatID] = ownerHandlerMock
+		testMgr.eventHandlers[routedChatID] = routedHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send react events to the message archived by the owner chat and to another one
+		reactEvent := &domain.ReactEvent{
+			ChatID:    routedChatID,
+			FromUser:  "routed-chat-user",
+			MessageID: 17,
+			Emoji:     "👍",
+		}
+		incomingEventsCh <- reactEvent
+		otherReactEvent := &domain.ReactEvent{
+			ChatID:    routedChatID,
+			FromUser:  "routed-chat-user",
+			MessageID: 18,
+			Emoji:     "👍",
+		}
+		incomingEventsCh <- otherReactEvent
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		ownerHandlerMock.AssertCalled(t, "HandleReactEvent", reactEvent)
+		ownerHandlerMock.AssertNumberOfCalls(t, "HandleReactEvent", 1)
+		routedHandlerMock.AssertCalled(t, "HandleReactEvent", otherReactEvent)
+		routedHandlerMock.AssertNumberOfCalls(t, "HandleReactEvent", 1)
+	})
+
+	t.Run("handle delete event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleDeleteEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send delete event
+		incomingEventsCh <- &domain.DeleteEvent{
+			ChatID:    testChatID,
+			FromUser:  "testuser",
+			MessageID: 17,
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleDeleteEvent", mock.Anything)
+	})
+
+	t.Run("handle reaction event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleReactionEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send reaction event
+		incomingEventsCh <- &domain.ReactionEvent{
+			ChatID:             testChatID,
+			WhatsappRemoteJid:  "alice-jid",
+			WhatsappSenderName: "Alice",
+			WhatsappMessageID:  "alice-message-id",
+			Emoji:              "❤️",
+			Account:            domain.DefaultWhatsappAccount,
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleReactionEvent", mock.Anything)
+	})
+
+	t.Run("handle react event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleReactEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send react event
+		incomingEventsCh <- &domain.ReactEvent{
+			ChatID:    testChatID,
+			FromUser:  "testuser",
+			MessageID: 17,
+			Emoji:     "👍",
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleReactEvent", mock.Anything)
+	})
+
+	t.Run("handle location event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleLocationEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send location event
+		incomingEventsCh <- &domain.LocationEvent{
+			ChatID:             testChatID,
+			WhatsappRemoteJid:  "alice-jid",
+			WhatsappSenderName: "Alice",
+			Location:           domain.WhatsappLocation{Latitude: 55.751244, Longitude: 37.618423},
+			Account:            domain.DefaultWhatsappAccount,
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleLocationEvent", mock.Anything)
+	})
+
+	t.Run("handle contact card event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleContactCardEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send contact card event
+		incomingEventsCh <- &domain.ContactCardEvent{
+			ChatID:             testChatID,
+			WhatsappRemoteJid:  "alice-jid",
+			WhatsappSenderName: "Alice",
+			ContactCard:        domain.WhatsappContactCard{DisplayName: "Bob", Vcard: "BEGIN:VCARD\nEND:VCARD"},
+			Account:            domain.DefaultWhatsappAccount,
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleContactCardEvent", mock.Anything)
+	})
+
+	t.Run("handle document event", func(t *testing.T) {
+		incomingEventsCh := make(chan domain.Event)
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: incomingEventsCh,
+		})
+
+		eventsHandlerMock := &mocks.EventsHandler{}
+		eventsHandlerMock.On("HandleDocumentEvent", mock.Anything).Return(nil)
+
+		// Add test events handler
+		testMgr.eventHandlers[testChatID] = eventsHandlerMock
+
+		// Run clients manager in a separate goroutine
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testMgr.Run(ctx)
+		}()
+
+		// Send document event
+		incomingEventsCh <- &domain.DocumentEvent{
+			ChatID:             testChatID,
+			WhatsappRemoteJid:  "alice-jid",
+			WhatsappSenderName: "Alice",
+			Document:           domain.WhatsappDocument{FileName: "report.pdf", Size: 4, Data: []byte("%PDF")},
+			Account:            domain.DefaultWhatsappAccount,
+		}
+
+		// Stop clients manager
+		cancel()
+		wg.Wait()
+
+		eventsHandlerMock.AssertCalled(t, "HandleDocumentEvent", mock.Anything)
+	})
+
+	t.Run("tick sends scheduled messages of the chats without events handler", func(t *testing.T) {
+		now := time.Date(2022, 3, 31, 10, 0, 0, 0, time.UTC)
+		schedules, err := schedule.New(&schedule.Opts{Path: filepath.Join(t.TempDir(), "schedule.json")})
+		require.NoError(t, err)
+		_, err = schedules.Add(domain.ScheduledMessage{
+			ChatID:    testChatID,
+			Account:   "test",
+			RemoteJid: "987654321@s.whatsapp.net",
+			Text:      "hi",
+			SendAt:    now,
+		})
+		require.NoError(t, err)
+		queue, err := outbox.New(&outbox.Opts{Path: filepath.Join(t.TempDir(), "outbox.json")})
+		require.NoError(t, err)
+
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: make(chan domain.Event),
+			TelegramClient: fake.NewClient(),
+			Outbox:         queue,
+			Schedules:      schedules,
+		})
+		testMgr.tick(now)
+
+		assert.Contains(t, testMgr.eventHandlers, testChatID)
+		assert.Empty(t, schedules.Messages(testChatID))
+		queued := queue.Messages(testChatID)
+		require.Len(t, queued, 1)
+		assert.Equal(t, "hi", queued[0].Text)
+	})
+
+	t.Run("remove expired archived messages", func(t *testing.T) {
+		now := time.Now()
+		messageArchive, err := archive.New(zap.NewNop(), &archive.Opts{
+			Path:   filepath.Join(t.TempDir(), "archive.jsonl"),
+			MaxAge: time.Hour,
+			Clock:  func() time.Time { return now },
+		})
+		require.NoError(t, err)
+		_, err = messageArchive.Add(domain.ArchivedMessage{ChatID: testChatID, Text: "hi", Timestamp: now})
+		require.NoError(t, err)
+		now = now.Add(time.Hour + time.Minute)
+
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: make(chan domain.Event),
+			Archive:        messageArchive,
+		})
+		testMgr.tick(now)
+
+		assert.Empty(t, messageArchive.Messages(testChatID))
+	})
+
+	t.Run("remove expired files", func(t *testing.T) {
+		now := time.Now()
+		store, err := files.New(&files.Opts{
+			Dir:   t.TempDir(),
+			TTL:   time.Hour,
+			Clock: func() time.Time { return now },
+		})
+		require.NoError(t, err)
+		file, err := store.Put("report.pdf", "application/pdf", []byte("%PDF"))
+		require.NoError(t, err)
+		now = now.Add(time.Hour)
+
+		testMgr := NewManager(zap.NewNop(), &Opts{
+			IncomingEvents: make(chan domain.Event),
+			Files:          store,
+		})
+		testMgr.tick(now)
+
+		_, err = os.Stat(file.Path)
+		assert.True(t, os.IsNotExist(err))
+	})
 }
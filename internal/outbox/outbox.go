@@ -0,0 +1,396 @@
+package outbox
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/storage"
+)
+
+const (
+	defaultMaxAttempts = 3
+
+	messageIDLength = 8
+
+	// Whatsapp web prefixes identifiers of the messages it sends with it
+	whatsappMessageIDPrefix = "3EB0"
+)
+
+// ErrFlushInProgress is returned when the outbox of a chat is already being flushed.
+var ErrFlushInProgress = errors.New("outbox flush is in progress")
+
+// SendFunc represents a function that delivers a message to whatsapp.
+type SendFunc func(msg domain.WhatsappMessage) error
+
+// Report represents a result of the outbox flush.
+type Report struct {
+	// Delivered contains messages that have been sent.
+	Delivered []domain.OutboxMessage
+
+	// Postponed contains messages that are kept in the outbox to be sent later.
+	Postponed []domain.OutboxMessage
+
+	// Blocked contains messages that are kept in the outbox behind a failed message
+	// to the same contact, they are sent once it's retried or removed.
+	Blocked []domain.OutboxMessage
+
+	// Failed contains messages that have run out of delivery attempts
+	// during the flush.
+	Failed []domain.OutboxMessage
+}
+
+// Outbox represents a durable queue of outgoing whatsapp messages.
+// Messages that are sent to the same whatsapp contact are delivered in the
+// order they have been queued.
+type Outbox struct {
+	mu          sync.Mutex
+	file        *storage.JSONFile
+	maxAttempts int
+	messages    []*domain.OutboxMessage
+	flushing    map[flushKey]bool
+}
+
+// flushKey identifies messages of the whatsapp account of the chat.
+type flushKey struct {
+	chatID  int64
+	account string
+}
+
+// Opts represents options to create new instance of Outbox.
+type Opts struct {
+	// Path is a path to the file the outbox is persisted to.
+	Path string
+
+	// MaxAttempts is a number of delivery attempts after which the message
+	// is considered as failed.
+	MaxAttempts int
+}
+
+// New creates new instance of Outbox and loads previously queued messages.
+func New(opts *Opts) (*Outbox, error) {
+	o := &Outbox{
+		file:        storage.NewJSONFile(opts.Path),
+		maxAttempts: opts.MaxAttempts,
+		messages:    make([]*domain.OutboxMessage, 0),
+		flushing:    make(map[flushKey]bool),
+	}
+	if o.maxAttempts <= 0 {
+		o.maxAttempts = defaultMaxAttempts
+	}
+
+	if err := o.file.Load(&o.messages); err != nil {
+		return nil, fmt.Errorf("failed to load outbox: %w", err)
+	}
+
+	// Messages queued before multiple accounts were supported belong to the default one
+	for _, msg := range o.messages {
+		if msg.Account == "" {
+			msg.Account = domain.DefaultWhatsappAccount
+		}
+	}
+
+	return o, nil
+}
+
+// Enqueue method adds a new message to the outbox.
+func (o *Outbox) Enqueue(chatID int64, account, remoteJid, text string) (domain.OutboxMessage, error) {
+	return o.enqueue(&domain.OutboxMessage{
+		ChatID:    chatID,
+		Account:   account,
+		RemoteJid: remoteJid,
+		Text:      text,
+	})
+}
+
+// EnqueueLocation method adds the location to the outbox, its text describes the location.
+func (o *Outbox) EnqueueLocation(chatID int64, account, remoteJid string,
+	location domain.WhatsappLocation) (domain.OutboxMessage, error) {
+	return o.enqueue(&domain.OutboxMessage{
+		ChatID:    chatID,
+		Account:   account,
+		RemoteJid: remoteJid,
+		Text:      domain.LocationText(location, false),
+		Location:  &location,
+	})
+}
+
+// EnqueueContactCard method adds the contact card to the outbox, its text describes the card.
+func (o *Outbox) EnqueueContactCard(chatID int64, account, remoteJid string,
+	contactCard domain.WhatsappContactCard) (domain.OutboxMessage, error) {
+	return o.enqueue(&domain.OutboxMessage{
+		ChatID:      chatID,
+		Account:     account,
+		RemoteJid:   remoteJid,
+		Text:        domain.ContactCardText(contactCard),
+		ContactCard: &contactCard,
+	})
+}
+
+// EnqueueMedia method adds the photo or the document to the outbox, its text describes the media.
+func (o *Outbox) EnqueueMedia(chatID int64, account, remoteJid string,
+	media domain.WhatsappMedia) (domain.OutboxMessage, error) {
+	return o.enqueue(&domain.OutboxMessage{
+		ChatID:    chatID,
+		Account:   account,
+		RemoteJid: remoteJid,
+		Text:      domain.MediaText(media),
+		Media:     &media,
+	})
+}
+
+func (o *Outbox) enqueue(msg *domain.OutboxMessage) (domain.OutboxMessage, error) {
+	id, err := newMessageID()
+	if err != nil {
+		return domain.OutboxMessage{}, err
+	}
+
+	// The whatsapp identifier is known in advance, so the message can be edited
+	// or deleted once it's delivered
+	whatsappMessageID, err := newMessageID()
+	if err != nil {
+		return domain.OutboxMessage{}, err
+	}
+
+	msg.ID = id
+	msg.WhatsappMessageID = whatsappMessageIDPrefix + strings.ToUpper(whatsappMessageID)
+	msg.CreatedAt = time.Now().UTC()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.messages = append(o.messages, msg)
+	if err := o.file.Save(o.messages); err != nil {
+		o.messages = o.messages[:len(o.messages)-1]
+
+		return domain.OutboxMessage{}, fmt.Errorf("failed to save outbox: %w", err)
+	}
+
+	return *msg, nil
+}
+
+// Messages method returns all queued messages of the chat.
+func (o *Outbox) Messages(chatID int64) []domain.OutboxMessage {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	messages := make([]domain.OutboxMessage, 0)
+	for _, msg := range o.messages {
+		if msg.ChatID == chatID {
+			messages = append(messages, *msg)
+		}
+	}
+
+	return messages
+}
+
+// Flush method tries to deliver all pending messages of the chat that are sent
+// from the whatsapp account. Once a message to a contact can't be delivered,
+// the rest of the messages to that contact are postponed to keep the order, messages
+// behind a failed message are blocked until it's retried or removed.
+func (o *Outbox) Flush(chatID int64, account string, send SendFunc) (*Report, error) {
+	key := flushKey{chatID: chatID, account: account}
+	pending, err := o.startFlush(key)
+	if err != nil {
+		return nil, err
+	}
+	defer o.finishFlush(key)
+
+	report := &Report{}
+	postponedJids := make(map[string]bool)
+	failedJids := make(map[string]bool)
+	for _, msg := range pending {
+		if msg.Failed {
+			failedJids[msg.RemoteJid] = true
+
+			continue
+		}
+		if failedJids[msg.RemoteJid] {
+			report.Blocked = append(report.Blocked, msg)
+
+			continue
+		}
+		if postponedJids[msg.RemoteJid] {
+			report.Postponed = append(report.Postponed, msg)
+
+			continue
+		}
+
+		err := send(msg.WhatsappMessage())
+		if err == nil {
+			report.Delivered = append(report.Delivered, msg)
+
+			continue
+		}
+
+		msg.Attempts++
+		msg.LastError = err.Error()
+		if msg.Attempts >= o.maxAttempts {
+			msg.Failed = true
+			failedJids[msg.RemoteJid] = true
+			report.Failed = append(report.Failed, msg)
+
+			continue
+		}
+
+		postponedJids[msg.RemoteJid] = true
+		report.Postponed = append(report.Postponed, msg)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	delivered := make(map[string]bool, len(report.Delivered))
+	for _, msg := range report.Delivered {
+		delivered[msg.ID] = true
+	}
+
+	updated := make(map[string]domain.OutboxMessage, len(report.Postponed)+len(report.Failed))
+	for _, msg := range report.Postponed {
+		updated[msg.ID] = msg
+	}
+	for _, msg := range report.Failed {
+		updated[msg.ID] = msg
+	}
+
+	messages := make([]*domain.OutboxMessage, 0, len(o.messages))
+	for _, msg := range o.messages {
+		if delivered[msg.ID] {
+			continue
+		}
+		if u, ok := updated[msg.ID]; ok {
+			u := u
+			msg = &u
+		}
+		messages = append(messages, msg)
+	}
+	o.messages = messages
+
+	if err := o.file.Save(o.messages); err != nil {
+		return report, fmt.Errorf("failed to save outbox: %w", err)
+	}
+
+	return report, nil
+}
+
+// Retry method resets delivery attempts of the failed message, it keeps its place
+// in the queue, so it's delivered before the later messages to the contact. It returns false if there is no such failed message.
+func (o *Outbox) Retry(chatID int64, id string) (domain.OutboxMessage, bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for i, msg := range o.messages {
+		if msg.ChatID != chatID || msg.ID != id || !msg.Failed {
+			continue
+		}
+
+		retried := *msg
+		retried.Attempts = 0
+		retried.LastError = ""
+		retried.Failed = false
+
+		messages := append([]*domain.OutboxMessage(nil), o.messages...)
+		messages[i] = &retried
+		if err := o.file.Save(messages); err != nil {
+			return domain.OutboxMessage{}, false, fmt.Errorf("failed to save outbox: %w", err)
+		}
+		o.messages = messages
+
+		return retried, true, nil
+	}
+
+	return domain.OutboxMessage{}, false, nil
+}
+
+// Edit method replaces the text of the queued message that is sent to whatsapp with
+// the identifier. It returns false if there is no such message, e.g. it's delivered already.
+func (o *Outbox) Edit(chatID int64, whatsappMessageID, text string) (bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for i, msg := range o.messages {
+		if msg.ChatID != chatID || msg.WhatsappMessageID != whatsappMessageID {
+			continue
+		}
+
+		edited := *msg
+		edited.Text = text
+
+		messages := append([]*domain.OutboxMessage(nil), o.messages...)
+		messages[i] = &edited
+		if err := o.file.Save(messages); err != nil {
+			return false, fmt.Errorf("failed to save outbox: %w", err)
+		}
+		o.messages = messages
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// Remove method removes the queued message that is sent to whatsapp with the identifier.
+// It returns false if there is no such message, e.g. it's delivered already.
+func (o *Outbox) Remove(chatID int64, whatsappMessageID string) (bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for i, msg := range o.messages {
+		if msg.ChatID != chatID || msg.WhatsappMessageID != whatsappMessageID {
+			continue
+		}
+
+		messages := make([]*domain.OutboxMessage, 0, len(o.messages)-1)
+		messages = append(messages, o.messages[:i]...)
+		messages = append(messages, o.messages[i+1:]...)
+		if err := o.file.Save(messages); err != nil {
+			return false, fmt.Errorf("failed to save outbox: %w", err)
+		}
+		o.messages = messages
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (o *Outbox) startFlush(key flushKey) ([]domain.OutboxMessage, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.flushing[key] {
+		return nil, ErrFlushInProgress
+	}
+	o.flushing[key] = true
+
+	// Failed messages are returned too since they hold the later messages to the contact
+	pending := make([]domain.OutboxMessage, 0)
+	for _, msg := range o.messages {
+		if msg.ChatID == key.chatID && msg.Account == key.account {
+			pending = append(pending, *msg)
+		}
+	}
+
+	return pending, nil
+}
+
+func (o *Outbox) finishFlush(key flushKey) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	delete(o.flushing, key)
+}
+
+func newMessageID() (string, error) {
+	raw := make([]byte, messageIDLength)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate message id: %w", err)
+	}
+
+	return hex.EncodeToString(raw), nil
+}
@@ -0,0 +1,393 @@
+package outbox_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/outbox"
+	"github.com/dstdfx/twbridge/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTestSend = errors.New("failed to send")
+
+type sentMessages []*domain.WhatsappTextMessage
+
+func (s *sentMessages) send(failingJids ...string) outbox.SendFunc {
+	return func(msg domain.WhatsappMessage) error {
+		textMessage := msg.(*domain.WhatsappTextMessage)
+		for _, jid := range failingJids {
+			if textMessage.RemoteJid == jid {
+				return errTestSend
+			}
+		}
+		// Identifiers are random, they are checked separately
+		sentMessage := *textMessage
+		sentMessage.ID = ""
+		*s = append(*s, &sentMessage)
+
+		return nil
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	testChatID := int64(123)
+	testAccount := domain.DefaultWhatsappAccount
+
+	t.Run("flush delivers messages in order", func(t *testing.T) {
+		testOutbox, err := outbox.New(&outbox.Opts{Path: filepath.Join(t.TempDir(), "outbox.json")})
+		require.NoError(t, err)
+
+		for _, text := range []string{"first", "second", "third"} {
+			_, err := testOutbox.Enqueue(testChatID, testAccount, "test-jid", text)
+			require.NoError(t, err)
+		}
+
+		var sent sentMessages
+		report, err := testOutbox.Flush(testChatID, testAccount, sent.send())
+		require.NoError(t, err)
+
+		assert.Len(t, report.Delivered, 3)
+		assert.Empty(t, report.Postponed)
+		assert.Empty(t, report.Failed)
+		assert.Equal(t, sentMessages{
+			{RemoteJid: "test-jid", Text: "first"},
+			{RemoteJid: "test-jid", Text: "second"},
+			{RemoteJid: "test-jid", Text: "third"},
+		}, sent)
+		assert.Empty(t, testOutbox.Messages(testChatID))
+	})
+
+	t.Run("flush postpones messages to the failing contact", func(t *testing.T) {
+		testOutbox, err := outbox.New(&outbox.Opts{Path: filepath.Join(t.TempDir(), "outbox.json")})
+		require.NoError(t, err)
+
+		_, err = testOutbox.Enqueue(testChatID, testAccount, "failing-jid", "first")
+		require.NoError(t, err)
+		_, err = testOutbox.Enqueue(testChatID, testAccount, "test-jid", "second")
+		require.NoError(t, err)
+		_, err = testOutbox.Enqueue(testChatID, testAccount, "failing-jid", "third")
+		require.NoError(t, err)
+
+		var sent sentMessages
+		report, err := testOutbox.Flush(testChatID, testAccount, sent.send("failing-jid"))
+		require.NoError(t, err)
+
+		assert.Equal(t, sentMessages{{RemoteJid: "test-jid", Text: "second"}}, sent)
+		require.Len(t, report.Postponed, 2)
+		assert.Equal(t, "first", report.Postponed[0].Text)
+		assert.Equal(t, 1, report.Postponed[0].Attempts)
+		assert.Equal(t, errTestSend.Error(), report.Postponed[0].LastError)
+		assert.Equal(t, "third", report.Postponed[1].Text)
+		assert.Equal(t, 0, report.Postponed[1].Attempts)
+
+		// The next flush keeps the order once the contact is reachable again
+		sent = nil
+		_, err = testOutbox.Flush(testChatID, testAccount, sent.send())
+		require.NoError(t, err)
+		assert.Equal(t, sentMessages{
+			{RemoteJid: "failing-jid", Text: "first"},
+			{RemoteJid: "failing-jid", Text: "third"},
+		}, sent)
+	})
+
+	t.Run("message fails after max attempts and can be retried", func(t *testing.T) {
+		testOutbox, err := outbox.New(&outbox.Opts{
+			Path:        filepath.Join(t.TempDir(), "outbox.json"),
+			MaxAttempts: 2,
+		})
+		require.NoError(t, err)
+
+		queued, err := testOutbox.Enqueue(testChatID, testAccount, "failing-jid", "hello")
+		require.NoError(t, err)
+
+		var sent sentMessages
+		report, err := testOutbox.Flush(testChatID, testAccount, sent.send("failing-jid"))
+		require.NoError(t, err)
+		assert.Empty(t, report.Failed)
+
+		report, err = testOutbox.Flush(testChatID, testAccount, sent.send("failing-jid"))
+		require.NoError(t, err)
+		require.Len(t, report.Failed, 1)
+		assert.Equal(t, queued.ID, report.Failed[0].ID)
+		assert.True(t, report.Failed[0].Failed)
+
+		// Failed messages are not flushed anymore
+		report, err = testOutbox.Flush(testChatID, testAccount, sent.send())
+		require.NoError(t, err)
+		assert.Empty(t, report.Delivered)
+		assert.Empty(t, sent)
+
+		retriedMsg, retried, err := testOutbox.Retry(testChatID, queued.ID)
+		require.NoError(t, err)
+		assert.True(t, retried)
+		assert.Equal(t, queued.ID, retriedMsg.ID)
+		assert.Zero(t, retriedMsg.Attempts)
+
+		report, err = testOutbox.Flush(testChatID, testAccount, sent.send())
+		require.NoError(t, err)
+		assert.Len(t, report.Delivered, 1)
+		assert.Equal(t, sentMessages{{RemoteJid: "failing-jid", Text: "hello"}}, sent)
+	})
+
+	t.Run("failed message holds later messages to the contact", func(t *testing.T) {
+		testOutbox, err := outbox.New(&outbox.Opts{
+			Path:        filepath.Join(t.TempDir(), "outbox.json"),
+			MaxAttempts: 1,
+		})
+		require.NoError(t, err)
+
+		first, err := testOutbox.Enqueue(testChatID, testAccount, "failing-jid", "first")
+		require.NoError(t, err)
+		_, err = testOutbox.Enqueue(testChatID, testAccount, "failing-jid", "second")
+		require.NoError(t, err)
+
+		var sent sentMessages
+		report, err := testOutbox.Flush(testChatID, testAccount, sent.send("failing-jid"))
+		require.NoError(t, err)
+		require.Len(t, report.Failed, 1)
+		assert.Equal(t, first.ID, report.Failed[0].ID)
+		assert.Empty(t, report.Postponed)
+		require.Len(t, report.Blocked, 1)
+		assert.Equal(t, "second", report.Blocked[0].Text)
+
+		// The contact is reachable again, but the failed message still holds the rest
+		_, err = testOutbox.Enqueue(testChatID, testAccount, "failing-jid", "third")
+		require.NoError(t, err)
+		_, err = testOutbox.Enqueue(testChatID, testAccount, "test-jid", "another contact")
+		require.NoError(t, err)
+
+		report, err = testOutbox.Flush(testChatID, testAccount, sent.send())
+		require.NoError(t, err)
+		assert.Equal(t, sentMessages{{RemoteJid: "test-jid", Text: "another contact"}}, sent)
+		assert.Empty(t, report.Postponed)
+		assert.Len(t, report.Blocked, 2)
+		assert.Empty(t, report.Failed)
+
+		// The retried message keeps its place in the queue
+		_, retried, err := testOutbox.Retry(testChatID, first.ID)
+		require.NoError(t, err)
+		require.True(t, retried)
+		assert.Equal(t, first.ID, testOutbox.Messages(testChatID)[0].ID)
+
+		sent = nil
+		_, err = testOutbox.Flush(testChatID, testAccount, sent.send())
+		require.NoError(t, err)
+		assert.Equal(t, sentMessages{
+			{RemoteJid: "failing-jid", Text: "first"},
+			{RemoteJid: "failing-jid", Text: "second"},
+			{RemoteJid: "failing-jid", Text: "third"},
+		}, sent)
+	})
+
+	t.Run("retry unknown message", func(t *testing.T) {
+		testOutbox, err := outbox.New(&outbox.Opts{Path: filepath.Join(t.TempDir(), "outbox.json")})
+		require.NoError(t, err)
+
+		queued, err := testOutbox.Enqueue(testChatID, testAccount, "test-jid", "hello")
+		require.NoError(t, err)
+
+		// Pending messages can't be retried
+		_, retried, err := testOutbox.Retry(testChatID, queued.ID)
+		require.NoError(t, err)
+		assert.False(t, retried)
+
+		_, retried, err = testOutbox.Retry(testChatID, "unknown")
+		require.NoError(t, err)
+		assert.False(t, retried)
+	})
+
+	t.Run("messages survive restart", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outbox.json")
+		testOutbox, err := outbox.New(&outbox.Opts{Path: path})
+		require.NoError(t, err)
+
+		_, err = testOutbox.Enqueue(testChatID, testAccount, "test-jid", "hello")
+		require.NoError(t, err)
+		_, err = testOutbox.Enqueue(testChatID+1, testAccount, "test-jid", "another chat")
+		require.NoError(t, err)
+
+		restoredOutbox, err := outbox.New(&outbox.Opts{Path: path})
+		require.NoError(t, err)
+
+		messages := restoredOutbox.Messages(testChatID)
+		require.Len(t, messages, 1)
+		assert.Equal(t, "hello", messages[0].Text)
+
+		// Other chats' messages are not flushed
+		var sent sentMessages
+		_, err = restoredOutbox.Flush(testChatID, testAccount, sent.send())
+		require.NoError(t, err)
+		assert.Equal(t, sentMessages{{RemoteJid: "test-jid", Text: "hello"}}, sent)
+		assert.Len(t, restoredOutbox.Messages(testChatID+1), 1)
+	})
+	t.Run("messages are flushed per account", func(t *testing.T) {
+		testOutbox, err := outbox.New(&outbox.Opts{Path: filepath.Join(t.TempDir(), "outbox.json")})
+		require.NoError(t, err)
+
+		_, err = testOutbox.Enqueue(testChatID, "work", "test-jid", "from work")
+		require.NoError(t, err)
+		_, err = testOutbox.Enqueue(testChatID, testAccount, "test-jid", "from default")
+		require.NoError(t, err)
+
+		var sent sentMessages
+		report, err := testOutbox.Flush(testChatID, "work", sent.send())
+		require.NoError(t, err)
+		require.Len(t, report.Delivered, 1)
+		assert.Equal(t, "work", report.Delivered[0].Account)
+		assert.Equal(t, sentMessages{{RemoteJid: "test-jid", Text: "from work"}}, sent)
+
+		messages := testOutbox.Messages(testChatID)
+		require.Len(t, messages, 1)
+		assert.Equal(t, testAccount, messages[0].Account)
+	})
+
+	t.Run("messages without account belong to the default one", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outbox.json")
+		require.NoError(t, storage.NewJSONFile(path).Save([]domain.OutboxMessage{
+			{ID: "test-id", ChatID: testChatID, RemoteJid: "test-jid", Text: "hello"},
+		}))
+
+		testOutbox, err := outbox.New(&outbox.Opts{Path: path})
+		require.NoError(t, err)
+
+		var sent sentMessages
+		_, err = testOutbox.Flush(testChatID, domain.DefaultWhatsappAccount, sent.send())
+		require.NoError(t, err)
+		assert.Equal(t, sentMessages{{RemoteJid: "test-jid", Text: "hello"}}, sent)
+	})
+
+	t.Run("queued messages are edited and removed by whatsapp identifier", func(t *testing.T) {
+		testOutbox, err := outbox.New(&outbox.Opts{Path: filepath.Join(t.TempDir(), "outbox.json")})
+		require.NoError(t, err)
+
+		first, err := testOutbox.Enqueue(testChatID, testAccount, "test-jid", "hello")
+		require.NoError(t, err)
+		second, err := testOutbox.Enqueue(testChatID, testAccount, "test-jid", "how are you?")
+		require.NoError(t, err)
+		assert.Regexp(t, "^3EB0[0-9A-F]{16}$", first.WhatsappMessageID)
+		assert.NotEqual(t, first.WhatsappMessageID, second.WhatsappMessageID)
+
+		edited, err := testOutbox.Edit(testChatID, first.WhatsappMessageID, "hi")
+		require.NoError(t, err)
+		assert.True(t, edited)
+
+		removed, err := testOutbox.Remove(testChatID, second.WhatsappMessageID)
+		require.NoError(t, err)
+		assert.True(t, removed)
+
+		// Messages of other chats are not touched
+		edited, err = testOutbox.Edit(testChatID+1, first.WhatsappMessageID, "hey")
+		require.NoError(t, err)
+		assert.False(t, edited)
+
+		var sent []domain.WhatsappMessage
+		_, err = testOutbox.Flush(testChatID, testAccount, func(msg domain.WhatsappMessage) error {
+			sent = append(sent, msg)
+
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.WhatsappMessage{
+			&domain.WhatsappTextMessage{ID: first.WhatsappMessageID, RemoteJid: "test-jid", Text: "hi"},
+		}, sent)
+
+		// Delivered messages are not in the outbox anymore
+		removed, err = testOutbox.Remove(testChatID, first.WhatsappMessageID)
+		require.NoError(t, err)
+		assert.False(t, removed)
+	})
+
+	t.Run("locations are sent as location messages", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outbox.json")
+		testOutbox, err := outbox.New(&outbox.Opts{Path: path})
+		require.NoError(t, err)
+
+		location := domain.WhatsappLocation{Latitude: 55.751244, Longitude: 37.618423, Name: "Cafe"}
+		queued, err := testOutbox.EnqueueLocation(testChatID, testAccount, "test-jid", location)
+		require.NoError(t, err)
+		assert.Equal(t, "📍 Location: Cafe\nhttps://maps.google.com/?q=55.751244,37.618423", queued.Text)
+
+		// The location survives restart
+		restoredOutbox, err := outbox.New(&outbox.Opts{Path: path})
+		require.NoError(t, err)
+
+		var sent []domain.WhatsappMessage
+		_, err = restoredOutbox.Flush(testChatID, testAccount, func(msg domain.WhatsappMessage) error {
+			sent = append(sent, msg)
+
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.WhatsappMessage{
+			&domain.WhatsappLocationMessage{ID: queued.WhatsappMessageID, RemoteJid: "test-jid", Location: location},
+		}, sent)
+	})
+
+	t.Run("media is sent as media messages", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outbox.json")
+		testOutbox, err := outbox.New(&outbox.Opts{Path: path})
+		require.NoError(t, err)
+
+		media := domain.WhatsappMedia{
+			Kind:           domain.WhatsappMediaImage,
+			TelegramFileID: "photo-file-id",
+			MimeType:       "image/jpeg",
+			Caption:        "look",
+		}
+		queued, err := testOutbox.EnqueueMedia(testChatID, testAccount, "test-jid", media)
+		require.NoError(t, err)
+		assert.Equal(t, "🖼 Photo\nlook", queued.Text)
+
+		// The media survives restart, its content is downloaded by the sender
+		restoredOutbox, err := outbox.New(&outbox.Opts{Path: path})
+		require.NoError(t, err)
+
+		var sent []domain.WhatsappMessage
+		_, err = restoredOutbox.Flush(testChatID, testAccount, func(msg domain.WhatsappMessage) error {
+			sent = append(sent, msg)
+
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.WhatsappMessage{
+			&domain.WhatsappMediaMessage{ID: queued.WhatsappMessageID, RemoteJid: "test-jid", Media: media},
+		}, sent)
+	})
+
+	t.Run("contact cards are sent as contact messages", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outbox.json")
+		testOutbox, err := outbox.New(&outbox.Opts{Path: path})
+		require.NoError(t, err)
+
+		contactCard := domain.WhatsappContactCard{
+			DisplayName: "Bob",
+			Vcard:       "BEGIN:VCARD\nVERSION:3.0\nFN:Bob\nTEL:+1 555-123-4567\nEND:VCARD",
+		}
+		queued, err := testOutbox.EnqueueContactCard(testChatID, testAccount, "test-jid", contactCard)
+		require.NoError(t, err)
+		assert.Equal(t, "👤 Contact: Bob, +1 555-123-4567", queued.Text)
+
+		// The contact card survives restart
+		restoredOutbox, err := outbox.New(&outbox.Opts{Path: path})
+		require.NoError(t, err)
+
+		var sent []domain.WhatsappMessage
+		_, err = restoredOutbox.Flush(testChatID, testAccount, func(msg domain.WhatsappMessage) error {
+			sent = append(sent, msg)
+
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.WhatsappMessage{
+			&domain.WhatsappContactCardMessage{
+				ID:          queued.WhatsappMessageID,
+				RemoteJid:   "test-jid",
+				ContactCard: contactCard,
+			},
+		}, sent)
+	})
+}
@@ -0,0 +1,75 @@
+package quiet
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/storage"
+)
+
+// Store represents a durable storage of quiet hours and daily digest schedules
+// of telegram chats.
+type Store struct {
+	mu    sync.Mutex
+	file  *storage.JSONFile
+	hours []domain.QuietHours
+}
+
+// Opts represents options to create new instance of Store.
+type Opts struct {
+	// Path is a path to the file the quiet hours are persisted to.
+	Path string
+}
+
+// New creates new instance of Store and loads previously saved quiet hours.
+func New(opts *Opts) (*Store, error) {
+	s := &Store{
+		file:  storage.NewJSONFile(opts.Path),
+		hours: make([]domain.QuietHours, 0),
+	}
+
+	if err := s.file.Load(&s.hours); err != nil {
+		return nil, fmt.Errorf("failed to load quiet hours: %w", err)
+	}
+
+	return s, nil
+}
+
+// Get method returns quiet hours of the chat, false is returned if
+// nothing is set.
+func (s *Store) Get(chatID int64) (domain.QuietHours, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, qh := range s.hours {
+		if qh.ChatID == chatID {
+			return qh, true
+		}
+	}
+
+	return domain.QuietHours{ChatID: chatID}, false
+}
+
+// Set method saves quiet hours of the chat, they are removed if nothing is set.
+func (s *Store) Set(qh domain.QuietHours) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hours := make([]domain.QuietHours, 0, len(s.hours)+1)
+	for _, h := range s.hours {
+		if h.ChatID != qh.ChatID {
+			hours = append(hours, h)
+		}
+	}
+	if qh.Hours != "" || qh.DailyDigestAt != "" || qh.TimeZone != "" {
+		hours = append(hours, qh)
+	}
+
+	if err := s.file.Save(hours); err != nil {
+		return fmt.Errorf("failed to save quiet hours: %w", err)
+	}
+	s.hours = hours
+
+	return nil
+}
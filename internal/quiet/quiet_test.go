@@ -0,0 +1,65 @@
+package quiet_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/quiet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testChatID = int64(123)
+
+func TestStore(t *testing.T) {
+	t.Run("set and reset", func(t *testing.T) {
+		store, err := quiet.New(&quiet.Opts{Path: filepath.Join(t.TempDir(), "quiet.json")})
+		require.NoError(t, err)
+
+		qh, ok := store.Get(testChatID)
+		assert.False(t, ok)
+		assert.Equal(t, domain.QuietHours{ChatID: testChatID}, qh)
+
+		qh.Hours = "23:00-07:00"
+		qh.TimeZone = "Europe/Berlin"
+		require.NoError(t, store.Set(qh))
+
+		got, ok := store.Get(testChatID)
+		assert.True(t, ok)
+		assert.Equal(t, qh, got)
+
+		_, ok = store.Get(456)
+		assert.False(t, ok)
+
+		// The time zone is kept
+		qh.Hours = ""
+		require.NoError(t, store.Set(qh))
+		_, ok = store.Get(testChatID)
+		assert.True(t, ok)
+
+		qh.TimeZone = ""
+		require.NoError(t, store.Set(qh))
+		_, ok = store.Get(testChatID)
+		assert.False(t, ok)
+	})
+
+	t.Run("quiet hours are persisted", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "quiet.json")
+		store, err := quiet.New(&quiet.Opts{Path: path})
+		require.NoError(t, err)
+
+		qh := domain.QuietHours{
+			ChatID:          testChatID,
+			DailyDigestAt:   "20:00",
+			LastDailyDigest: "2022-03-31",
+		}
+		require.NoError(t, store.Set(qh))
+
+		reloaded, err := quiet.New(&quiet.Opts{Path: path})
+		require.NoError(t, err)
+		got, ok := reloaded.Get(testChatID)
+		assert.True(t, ok)
+		assert.Equal(t, qh, got)
+	})
+}
@@ -0,0 +1,153 @@
+package route
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sync"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/storage"
+)
+
+// ErrInvalidPattern is returned when the jid pattern of a route is malformed.
+var ErrInvalidPattern = errors.New("invalid jid pattern")
+
+// Store represents a durable routing table of whatsapp conversations.
+type Store struct {
+	mu     sync.Mutex
+	file   *storage.JSONFile
+	routes []domain.Route
+}
+
+// Opts represents options to create new instance of Store.
+type Opts struct {
+	// Path is a path to the file the routes are persisted to.
+	Path string
+}
+
+// New creates new instance of Store and loads previously saved routes.
+func New(opts *Opts) (*Store, error) {
+	s := &Store{
+		file:   storage.NewJSONFile(opts.Path),
+		routes: make([]domain.Route, 0),
+	}
+
+	if err := s.file.Load(&s.routes); err != nil {
+		return nil, fmt.Errorf("failed to load routes: %w", err)
+	}
+
+	return s, nil
+}
+
+// Routes method returns the routes of the whatsapp accounts logged in from the chat.
+func (s *Store) Routes(ownerChatID int64) []domain.Route {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	routes := make([]domain.Route, 0)
+	for _, r := range s.routes {
+		if r.OwnerChatID == ownerChatID {
+			routes = append(routes, r)
+		}
+	}
+
+	return routes
+}
+
+// Add method saves the route, a previous route of the same jid is replaced.
+func (s *Store) Add(route domain.Route) error {
+	if _, err := path.Match(route.Jid, ""); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidPattern, route.Jid)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	routes := make([]domain.Route, 0, len(s.routes)+1)
+	for _, r := range s.routes {
+		if !sameJid(&r, &route) {
+			routes = append(routes, r)
+		}
+	}
+	routes = append(routes, route)
+
+	return s.save(routes)
+}
+
+// Remove method removes the route of the jid, false is returned if there
+// is no such route.
+func (s *Store) Remove(ownerChatID int64, account, jid string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := domain.Route{OwnerChatID: ownerChatID, Account: account, Jid: jid}
+	routes := make([]domain.Route, 0, len(s.routes))
+	for _, r := range s.routes {
+		if !sameJid(&r, &removed) {
+			routes = append(routes, r)
+		}
+	}
+	if len(routes) == len(s.routes) {
+		return false, nil
+	}
+
+	return true, s.save(routes)
+}
+
+// Target method returns the route the messages of the whatsapp conversation
+// are delivered by, false is returned if they are delivered to the owner chat.
+// Routes of the exact jid take precedence over patterns.
+func (s *Store) Target(ownerChatID int64, account, remoteJid string) (domain.Route, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.match(func(r *domain.Route) bool {
+		return r.OwnerChatID == ownerChatID && r.Matches(account, remoteJid)
+	})
+}
+
+// Owner method returns the route of the whatsapp conversation that delivers
+// its messages to the chat, it's used to send replies from that chat.
+func (s *Store) Owner(chatID int64, account, remoteJid string) (domain.Route, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.match(func(r *domain.Route) bool {
+		return r.ChatID == chatID && r.Matches(account, remoteJid)
+	})
+}
+
+func (s *Store) match(fn func(r *domain.Route) bool) (domain.Route, bool) {
+	var (
+		found domain.Route
+		ok    bool
+	)
+	for i := range s.routes {
+		r := &s.routes[i]
+		if !fn(r) {
+			continue
+		}
+		if !r.IsPattern() {
+			return *r, true
+		}
+		if !ok {
+			found, ok = *r, true
+		}
+	}
+
+	return found, ok
+}
+
+func (s *Store) save(routes []domain.Route) error {
+	if err := s.file.Save(routes); err != nil {
+		return fmt.Errorf("failed to save routes: %w", err)
+	}
+	s.routes = routes
+
+	return nil
+}
+
+func sameJid(a, b *domain.Route) bool {
+	return a.OwnerChatID == b.OwnerChatID && a.Account == b.Account && a.Jid == b.Jid
+}
@@ -0,0 +1,114 @@
+package route_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/route"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testOwnerChatID = int64(123)
+	testChatID      = int64(-100456)
+	testGroupChatID = int64(-100789)
+	testAccount     = domain.DefaultWhatsappAccount
+)
+
+func TestStore(t *testing.T) {
+	aliceRoute := domain.Route{
+		OwnerChatID: testOwnerChatID,
+		Account:     testAccount,
+		Jid:         "alice@s.whatsapp.net",
+		ChatID:      testChatID,
+	}
+	groupsRoute := domain.Route{
+		OwnerChatID: testOwnerChatID,
+		Account:     testAccount,
+		Jid:         "*@g.us",
+		ChatID:      testGroupChatID,
+	}
+
+	t.Run("route by jid and pattern", func(t *testing.T) {
+		store, err := route.New(&route.Opts{Path: filepath.Join(t.TempDir(), "routes.json")})
+		require.NoError(t, err)
+
+		require.NoError(t, store.Add(groupsRoute))
+		require.NoError(t, store.Add(aliceRoute))
+
+		got, ok := store.Target(testOwnerChatID, testAccount, "alice@s.whatsapp.net")
+		require.True(t, ok)
+		assert.Equal(t, aliceRoute, got)
+
+		got, ok = store.Target(testOwnerChatID, testAccount, "12345-67890@g.us")
+		require.True(t, ok)
+		assert.Equal(t, groupsRoute, got)
+
+		_, ok = store.Target(testOwnerChatID, testAccount, "bob@s.whatsapp.net")
+		assert.False(t, ok)
+		_, ok = store.Target(testOwnerChatID, "work", "alice@s.whatsapp.net")
+		assert.False(t, ok)
+		_, ok = store.Target(42, testAccount, "alice@s.whatsapp.net")
+		assert.False(t, ok)
+
+		got, ok = store.Owner(testGroupChatID, testAccount, "12345-67890@g.us")
+		require.True(t, ok)
+		assert.Equal(t, testOwnerChatID, got.OwnerChatID)
+		_, ok = store.Owner(testGroupChatID, testAccount, "alice@s.whatsapp.net")
+		assert.False(t, ok)
+
+		assert.Equal(t, []domain.Route{groupsRoute, aliceRoute}, store.Routes(testOwnerChatID))
+		assert.Empty(t, store.Routes(testChatID))
+	})
+
+	t.Run("exact jid takes precedence over pattern", func(t *testing.T) {
+		store, err := route.New(&route.Opts{Path: filepath.Join(t.TempDir(), "routes.json")})
+		require.NoError(t, err)
+
+		everyone := aliceRoute
+		everyone.Jid = "*"
+		everyone.ChatID = testGroupChatID
+		require.NoError(t, store.Add(everyone))
+		require.NoError(t, store.Add(aliceRoute))
+
+		got, ok := store.Target(testOwnerChatID, testAccount, "alice@s.whatsapp.net")
+		require.True(t, ok)
+		assert.Equal(t, testChatID, got.ChatID)
+	})
+
+	t.Run("replace and remove route", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "routes.json")
+		store, err := route.New(&route.Opts{Path: path})
+		require.NoError(t, err)
+
+		require.NoError(t, store.Add(aliceRoute))
+		moved := aliceRoute
+		moved.ChatID = testGroupChatID
+		require.NoError(t, store.Add(moved))
+		assert.Equal(t, []domain.Route{moved}, store.Routes(testOwnerChatID))
+
+		reloaded, err := route.New(&route.Opts{Path: path})
+		require.NoError(t, err)
+		assert.Equal(t, []domain.Route{moved}, reloaded.Routes(testOwnerChatID))
+
+		removed, err := reloaded.Remove(testOwnerChatID, testAccount, aliceRoute.Jid)
+		require.NoError(t, err)
+		assert.True(t, removed)
+		assert.Empty(t, reloaded.Routes(testOwnerChatID))
+
+		removed, err = reloaded.Remove(testOwnerChatID, testAccount, aliceRoute.Jid)
+		require.NoError(t, err)
+		assert.False(t, removed)
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		store, err := route.New(&route.Opts{Path: filepath.Join(t.TempDir(), "routes.json")})
+		require.NoError(t, err)
+
+		invalid := aliceRoute
+		invalid.Jid = "[a-"
+		assert.ErrorIs(t, store.Add(invalid), route.ErrInvalidPattern)
+	})
+}
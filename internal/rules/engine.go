@@ -0,0 +1,266 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+)
+
+// ErrInvalidRule is returned when the rule can't be applied.
+var ErrInvalidRule = errors.New("invalid rule")
+
+const (
+	groupJidSuffix   = "@g.us"
+	ruleArgSeparator = "="
+)
+
+// tagRegexp represents a valid telegram hashtag without leading "#".
+var tagRegexp = regexp.MustCompile(`^[\p{L}\p{N}_]+$`)
+
+// messageTypes is a list of message types the rules may match.
+var messageTypes = []domain.MessageType{
+	domain.MessageTypeText,
+	domain.MessageTypeLocation,
+	domain.MessageTypeContact,
+	domain.MessageTypeDocument,
+}
+
+// Message represents an incoming whatsapp message the rules are evaluated against.
+type Message struct {
+	// Account is a name of the whatsapp account that has received the message.
+	Account string
+
+	// RemoteJid is a whatsapp user or group identifier of the conversation.
+	RemoteJid string
+
+	// SenderName is a name of the contact or group.
+	SenderName string
+
+	// Text is a text of the message.
+	Text string
+
+	// Type is a type of the message.
+	Type domain.MessageType
+
+	// ReceivedAt is the time the message has been received.
+	ReceivedAt time.Time
+}
+
+// Result represents actions of the rules that match the message.
+type Result struct {
+	// Matched is a list of the rules that match the message.
+	Matched []domain.Rule
+
+	// Drop means that the message must be dropped.
+	Drop bool
+
+	// Important means that the message must be marked and delivered with notification.
+	Important bool
+
+	// Forward is a list of telegram chats the message must be forwarded to.
+	Forward []int64
+
+	// Tags is a list of hashtags the message must be tagged with.
+	Tags []string
+}
+
+// Evaluate returns actions of the rules that match the message,
+// all matched rules are applied.
+func Evaluate(rules []domain.Rule, msg *Message) Result {
+	var result Result
+	for i := range rules {
+		rule := &rules[i]
+		if !Matches(rule, msg) {
+			continue
+		}
+
+		result.Matched = append(result.Matched, *rule)
+		switch rule.Action {
+		case domain.RuleActionDrop:
+			result.Drop = true
+		case domain.RuleActionImportant:
+			result.Important = true
+		case domain.RuleActionForward:
+			result.Forward = append(result.Forward, rule.ForwardTo)
+		case domain.RuleActionTag:
+			result.Tags = append(result.Tags, rule.Tag)
+		}
+	}
+
+	return result
+}
+
+// Matches returns true if all conditions of the rule match the message.
+func Matches(rule *domain.Rule, msg *Message) bool {
+	isGroup := strings.HasSuffix(msg.RemoteJid, groupJidSuffix)
+
+	switch {
+	case rule.Account != "" && rule.Account != msg.Account,
+		rule.MessageType != "" && rule.MessageType != msg.Type,
+		rule.Keyword != "" && !strings.Contains(strings.ToLower(msg.Text), strings.ToLower(rule.Keyword)),
+		rule.Sender != "" && (isGroup || !matchesContact(rule.Sender, msg)),
+		rule.Group != "" && (!isGroup || !matchesContact(rule.Group, msg)),
+		rule.Hours != "" && !domain.InHours(rule.Hours, msg.ReceivedAt):
+		return false
+	}
+
+	if rule.Regex != "" {
+		re, err := regexp.Compile(rule.Regex)
+		if err != nil || !re.MatchString(msg.Text) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Validate returns an error if the rule can't be applied.
+func Validate(rule *domain.Rule) error {
+	switch rule.Action {
+	case domain.RuleActionDrop, domain.RuleActionImportant:
+	case domain.RuleActionForward:
+		if rule.ForwardTo == 0 {
+			return fmt.Errorf("%w: forward requires a chat to forward to", ErrInvalidRule)
+		}
+	case domain.RuleActionTag:
+		if !tagRegexp.MatchString(rule.Tag) {
+			return fmt.Errorf("%w: tag may contain only letters, digits and '_'", ErrInvalidRule)
+		}
+	default:
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidRule, rule.Action)
+	}
+
+	if rule.Regex != "" {
+		if _, err := regexp.Compile(rule.Regex); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidRule, err)
+		}
+	}
+	for _, pattern := range []string{rule.Sender, rule.Group} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w: malformed pattern %q", ErrInvalidRule, pattern)
+		}
+	}
+	if rule.Hours != "" {
+		if _, _, err := domain.ParseHours(rule.Hours); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidRule, err)
+		}
+	}
+	if rule.MessageType != "" && !isMessageType(rule.MessageType) {
+		return fmt.Errorf("%w: unknown message type %q", ErrInvalidRule, rule.MessageType)
+	}
+
+	return nil
+}
+
+// Parse returns the rule described by "key=value" arguments, the first argument
+// may be an action without the key, e.g. "drop keyword=lottery group=*@g.us".
+func Parse(args []string) (domain.Rule, error) {
+	var rule domain.Rule
+	for i, arg := range args {
+		parts := strings.SplitN(arg, ruleArgSeparator, 2)
+		if len(parts) != 2 {
+			if i != 0 {
+				return domain.Rule{}, fmt.Errorf("%w: %q is not a key=value pair", ErrInvalidRule, arg)
+			}
+			parts = []string{"action", arg}
+		}
+
+		key, value := parts[0], parts[1]
+		switch key {
+		case "action":
+			rule.Action = domain.RuleAction(value)
+		case "keyword":
+			rule.Keyword = value
+		case "regex":
+			rule.Regex = value
+		case "sender":
+			rule.Sender = value
+		case "group":
+			rule.Group = value
+		case "hours":
+			rule.Hours = value
+		case "type":
+			rule.MessageType = domain.MessageType(value)
+		case "account":
+			rule.Account = value
+		case "to":
+			chatID, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return domain.Rule{}, fmt.Errorf("%w: invalid chat ID %q", ErrInvalidRule, value)
+			}
+			rule.ForwardTo = chatID
+		case "tag":
+			rule.Tag = strings.TrimPrefix(value, "#")
+		default:
+			return domain.Rule{}, fmt.Errorf("%w: unknown key %q", ErrInvalidRule, key)
+		}
+	}
+
+	if err := Validate(&rule); err != nil {
+		return domain.Rule{}, err
+	}
+
+	return rule, nil
+}
+
+// Describe returns a description of the rule in the form it's parsed from.
+func Describe(rule *domain.Rule) string {
+	fields := []string{"#" + rule.ID, "action=" + string(rule.Action)}
+	for _, field := range []struct {
+		key   string
+		value string
+	}{
+		{"to", formatChatID(rule.ForwardTo)},
+		{"tag", rule.Tag},
+		{"keyword", rule.Keyword},
+		{"regex", rule.Regex},
+		{"sender", rule.Sender},
+		{"group", rule.Group},
+		{"hours", rule.Hours},
+		{"type", string(rule.MessageType)},
+		{"account", rule.Account},
+	} {
+		if field.value != "" {
+			fields = append(fields, field.key+ruleArgSeparator+field.value)
+		}
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// matchesContact returns true if the pattern matches jid or name
+// of the conversation, the case is ignored.
+func matchesContact(pattern string, msg *Message) bool {
+	pattern = strings.ToLower(pattern)
+	for _, s := range []string{msg.RemoteJid, msg.SenderName} {
+		if matched, err := path.Match(pattern, strings.ToLower(s)); err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isMessageType(messageType domain.MessageType) bool {
+	for _, t := range messageTypes {
+		if t == messageType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func formatChatID(chatID int64) string {
+	if chatID == 0 {
+		return ""
+	}
+
+	return strconv.FormatInt(chatID, 10)
+}
@@ -0,0 +1,134 @@
+package rules_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatches(t *testing.T) {
+	night := time.Date(2021, 5, 1, 23, 30, 0, 0, time.UTC)
+	day := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+	contactMsg := &rules.Message{
+		Account:    domain.DefaultWhatsappAccount,
+		RemoteJid:  "alice@s.whatsapp.net",
+		SenderName: "Alice",
+		Text:       "Big SALE today",
+		Type:       domain.MessageTypeText,
+		ReceivedAt: night,
+	}
+	groupMsg := &rules.Message{
+		Account:    "work",
+		RemoteJid:  "1234567890-1600000000@g.us",
+		SenderName: "Project chat",
+		Text:       "Invoice #42 is ready",
+		Type:       domain.MessageTypeText,
+		ReceivedAt: day,
+	}
+
+	testCases := []struct {
+		name          string
+		rule          domain.Rule
+		matchContact  bool
+		matchGroupMsg bool
+	}{
+		{"empty rule", domain.Rule{}, true, true},
+		{"keyword", domain.Rule{Keyword: "sale"}, true, false},
+		{"regex", domain.Rule{Regex: `(?i)invoice #\d+`}, false, true},
+		{"sender jid", domain.Rule{Sender: "alice@*"}, true, false},
+		{"sender name", domain.Rule{Sender: "alice"}, true, false},
+		{"sender doesn't match groups", domain.Rule{Sender: "*"}, true, false},
+		{"group", domain.Rule{Group: "*@g.us"}, false, true},
+		{"group name", domain.Rule{Group: "project*"}, false, true},
+		{"hours over midnight", domain.Rule{Hours: "22:00-07:00"}, true, false},
+		{"hours", domain.Rule{Hours: "09:00-18:00"}, false, true},
+		{"type", domain.Rule{MessageType: domain.MessageTypeText}, true, true},
+		{"other type", domain.Rule{MessageType: domain.MessageTypeDocument}, false, false},
+		{"account", domain.Rule{Account: "work"}, false, true},
+		{"all conditions", domain.Rule{Keyword: "invoice", Group: "*@g.us", Account: "work"}, false, true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.matchContact, rules.Matches(&tc.rule, contactMsg))
+			assert.Equal(t, tc.matchGroupMsg, rules.Matches(&tc.rule, groupMsg))
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	testRules := []domain.Rule{
+		{ID: "1", Keyword: "sale", Action: domain.RuleActionDrop},
+		{ID: "2", Keyword: "urgent", Action: domain.RuleActionImportant},
+		{ID: "3", Group: "*@g.us", Action: domain.RuleActionForward, ForwardTo: -100456},
+		{ID: "4", Group: "*@g.us", Action: domain.RuleActionTag, Tag: "groups"},
+	}
+
+	result := rules.Evaluate(testRules, &rules.Message{
+		RemoteJid: "1234567890-1600000000@g.us",
+		Text:      "urgent: server is down",
+	})
+	assert.False(t, result.Drop)
+	assert.True(t, result.Important)
+	assert.Equal(t, []int64{-100456}, result.Forward)
+	assert.Equal(t, []string{"groups"}, result.Tags)
+	require.Len(t, result.Matched, 3)
+
+	result = rules.Evaluate(testRules, &rules.Message{
+		RemoteJid: "alice@s.whatsapp.net",
+		Text:      "sale",
+	})
+	assert.True(t, result.Drop)
+	assert.Len(t, result.Matched, 1)
+
+	result = rules.Evaluate(testRules, &rules.Message{
+		RemoteJid: "alice@s.whatsapp.net",
+		Text:      "hello",
+	})
+	assert.Empty(t, result.Matched)
+}
+
+func TestParse(t *testing.T) {
+	rule, err := rules.Parse([]string{"forward", "to=-100456", "group=*@g.us", "hours=22:00-07:00"})
+	require.NoError(t, err)
+	assert.Equal(t, domain.Rule{
+		Action:    domain.RuleActionForward,
+		ForwardTo: -100456,
+		Group:     "*@g.us",
+		Hours:     "22:00-07:00",
+	}, rule)
+
+	rule.ID = "1"
+	assert.Equal(t, "#1 action=forward to=-100456 group=*@g.us hours=22:00-07:00", rules.Describe(&rule))
+
+	rule, err = rules.Parse([]string{"action=tag", "tag=#promo", "regex=(?i)discount"})
+	require.NoError(t, err)
+	assert.Equal(t, "promo", rule.Tag)
+
+	rule, err = rules.Parse([]string{"drop", "type=document"})
+	require.NoError(t, err)
+	assert.Equal(t, domain.MessageTypeDocument, rule.MessageType)
+
+	for _, args := range [][]string{
+		{},
+		{"explode"},
+		{"forward"},
+		{"tag", "tag=two words"},
+		{"drop", "regex=("},
+		{"drop", "sender=["},
+		{"drop", "hours=22:00"},
+		{"drop", "hours=25:00-07:00"},
+		{"drop", "type=sticker"},
+		{"drop", "color=red"},
+		{"drop", "keyword"},
+		{"forward", "to=chat"},
+	} {
+		_, err := rules.Parse(args)
+		assert.ErrorIs(t, err, rules.ErrInvalidRule, "args: %v", args)
+	}
+}
@@ -0,0 +1,216 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/storage"
+)
+
+// configRuleIDPrefix is a prefix of identifiers of the rules from the config file,
+// it's used if the rule doesn't have an identifier.
+const configRuleIDPrefix = "f"
+
+// Config represents the rules that apply to every telegram chat.
+type Config struct {
+	// DryRun means that the rules of every chat are only logged, not applied.
+	DryRun bool `json:"dry_run"`
+
+	// Rules is a list of the rules in the order they are evaluated.
+	Rules []domain.Rule `json:"rules"`
+}
+
+// Store represents a durable storage of the rules of telegram chats
+// together with the rules of the config file.
+type Store struct {
+	mu       sync.Mutex
+	file     *storage.JSONFile
+	config   Config
+	ruleSets []domain.RuleSet
+}
+
+// Opts represents options to create new instance of Store.
+type Opts struct {
+	// Path is a path to the file the rules of the chats are persisted to.
+	Path string
+
+	// ConfigPath is a path to the config file with the rules of every chat, it's optional.
+	ConfigPath string
+}
+
+// New creates new instance of Store, loads the config file and previously
+// saved rules.
+func New(opts *Opts) (*Store, error) {
+	s := &Store{
+		file:     storage.NewJSONFile(opts.Path),
+		ruleSets: make([]domain.RuleSet, 0),
+	}
+
+	if opts.ConfigPath != "" {
+		if err := storage.NewJSONFile(opts.ConfigPath).Load(&s.config); err != nil {
+			return nil, fmt.Errorf("failed to load rules config: %w", err)
+		}
+
+		for i := range s.config.Rules {
+			rule := &s.config.Rules[i]
+			if rule.ID == "" {
+				rule.ID = configRuleIDPrefix + strconv.Itoa(i+1)
+			}
+			if err := Validate(rule); err != nil {
+				return nil, fmt.Errorf("failed to load rule %s of rules config: %w", rule.ID, err)
+			}
+		}
+	}
+
+	if err := s.file.Load(&s.ruleSets); err != nil {
+		return nil, fmt.Errorf("failed to load rules: %w", err)
+	}
+
+	return s, nil
+}
+
+// Rules method returns the rules evaluated for the chat, the rules
+// of the config file go first.
+func (s *Store) Rules(chatID int64) []domain.Rule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules := make([]domain.Rule, 0, len(s.config.Rules))
+	rules = append(rules, s.config.Rules...)
+	if i := s.find(chatID); i != -1 {
+		rules = append(rules, s.ruleSets[i].Rules...)
+	}
+
+	return rules
+}
+
+// IsConfigRule method returns true if the rule comes from the config file.
+func (s *Store) IsConfigRule(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rule := range s.config.Rules {
+		if rule.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DryRun method returns true if the rules of the chat are only logged.
+func (s *Store) DryRun(chatID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.config.DryRun {
+		return true
+	}
+	if i := s.find(chatID); i != -1 {
+		return s.ruleSets[i].DryRun
+	}
+
+	return false
+}
+
+// SetDryRun method enables or disables dry-run mode of the chat.
+func (s *Store) SetDryRun(chatID int64, dryRun bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.update(chatID, func(rs *domain.RuleSet) {
+		rs.DryRun = dryRun
+	})
+}
+
+// Add method validates the rule and adds it to the chat, the rule
+// with assigned identifier is returned.
+func (s *Store) Add(chatID int64, rule domain.Rule) (domain.Rule, error) {
+	if err := Validate(&rule); err != nil {
+		return domain.Rule{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.update(chatID, func(rs *domain.RuleSet) {
+		lastID := 0
+		for _, r := range rs.Rules {
+			if id, err := strconv.Atoi(r.ID); err == nil && id > lastID {
+				lastID = id
+			}
+		}
+		rule.ID = strconv.Itoa(lastID + 1)
+		rs.Rules = append(rs.Rules, rule)
+	})
+	if err != nil {
+		return domain.Rule{}, err
+	}
+
+	return rule, nil
+}
+
+// Remove method removes the rule of the chat, false is returned if there
+// is no such rule.
+func (s *Store) Remove(chatID int64, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := false
+	err := s.update(chatID, func(rs *domain.RuleSet) {
+		rules := make([]domain.Rule, 0, len(rs.Rules))
+		for _, r := range rs.Rules {
+			if r.ID == id {
+				removed = true
+
+				continue
+			}
+			rules = append(rules, r)
+		}
+		rs.Rules = rules
+	})
+	if err != nil || !removed {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *Store) find(chatID int64) int {
+	for i := range s.ruleSets {
+		if s.ruleSets[i].ChatID == chatID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// update applies fn to a copy of the rule set of the chat and saves it.
+func (s *Store) update(chatID int64, fn func(rs *domain.RuleSet)) error {
+	ruleSets := make([]domain.RuleSet, 0, len(s.ruleSets)+1)
+	updated := domain.RuleSet{ChatID: chatID}
+	for _, rs := range s.ruleSets {
+		if rs.ChatID == chatID {
+			updated = rs
+			updated.Rules = append([]domain.Rule(nil), rs.Rules...)
+
+			continue
+		}
+		ruleSets = append(ruleSets, rs)
+	}
+
+	fn(&updated)
+	if updated.DryRun || len(updated.Rules) != 0 {
+		ruleSets = append(ruleSets, updated)
+	}
+
+	if err := s.file.Save(ruleSets); err != nil {
+		return fmt.Errorf("failed to save rules: %w", err)
+	}
+	s.ruleSets = ruleSets
+
+	return nil
+}
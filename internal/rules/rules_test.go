@@ -0,0 +1,107 @@
+package rules_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testChatID = int64(123)
+
+func TestStore(t *testing.T) {
+	dropRule := domain.Rule{Keyword: "sale", Action: domain.RuleActionDrop}
+
+	t.Run("add and remove", func(t *testing.T) {
+		store, err := rules.New(&rules.Opts{Path: filepath.Join(t.TempDir(), "rules.json")})
+		require.NoError(t, err)
+
+		assert.Empty(t, store.Rules(testChatID))
+
+		first, err := store.Add(testChatID, dropRule)
+		require.NoError(t, err)
+		assert.Equal(t, "1", first.ID)
+		second, err := store.Add(testChatID, domain.Rule{Action: domain.RuleActionTag, Tag: "all"})
+		require.NoError(t, err)
+		assert.Equal(t, "2", second.ID)
+
+		_, err = store.Add(testChatID, domain.Rule{Action: "explode"})
+		assert.ErrorIs(t, err, rules.ErrInvalidRule)
+
+		assert.Equal(t, []domain.Rule{first, second}, store.Rules(testChatID))
+		assert.Empty(t, store.Rules(456))
+
+		removed, err := store.Remove(testChatID, "1")
+		require.NoError(t, err)
+		assert.True(t, removed)
+		removed, err = store.Remove(testChatID, "1")
+		require.NoError(t, err)
+		assert.False(t, removed)
+		assert.Equal(t, []domain.Rule{second}, store.Rules(testChatID))
+
+		// Identifiers are not reused
+		third, err := store.Add(testChatID, dropRule)
+		require.NoError(t, err)
+		assert.Equal(t, "3", third.ID)
+	})
+
+	t.Run("dry-run mode and persistence", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rules.json")
+		store, err := rules.New(&rules.Opts{Path: path})
+		require.NoError(t, err)
+
+		assert.False(t, store.DryRun(testChatID))
+		require.NoError(t, store.SetDryRun(testChatID, true))
+		_, err = store.Add(testChatID, dropRule)
+		require.NoError(t, err)
+
+		reloaded, err := rules.New(&rules.Opts{Path: path})
+		require.NoError(t, err)
+		assert.True(t, reloaded.DryRun(testChatID))
+		assert.Len(t, reloaded.Rules(testChatID), 1)
+	})
+
+	t.Run("config file", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "rules-config.json")
+		require.NoError(t, os.WriteFile(configPath, []byte(`{
+			"dry_run": true,
+			"rules": [
+				{"keyword": "lottery", "action": "drop"},
+				{"id": "groups", "group": "*@g.us", "action": "tag", "tag": "groups"}
+			]
+		}`), 0o600))
+
+		store, err := rules.New(&rules.Opts{
+			Path:       filepath.Join(t.TempDir(), "rules.json"),
+			ConfigPath: configPath,
+		})
+		require.NoError(t, err)
+
+		chatRule, err := store.Add(testChatID, dropRule)
+		require.NoError(t, err)
+
+		got := store.Rules(testChatID)
+		require.Len(t, got, 3)
+		assert.Equal(t, "f1", got[0].ID)
+		assert.Equal(t, "groups", got[1].ID)
+		assert.Equal(t, chatRule, got[2])
+		assert.True(t, store.IsConfigRule("groups"))
+		assert.False(t, store.IsConfigRule(chatRule.ID))
+		assert.True(t, store.DryRun(456))
+	})
+
+	t.Run("invalid config file", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "rules-config.json")
+		require.NoError(t, os.WriteFile(configPath, []byte(`{"rules": [{"action": "explode"}]}`), 0o600))
+
+		_, err := rules.New(&rules.Opts{
+			Path:       filepath.Join(t.TempDir(), "rules.json"),
+			ConfigPath: configPath,
+		})
+		assert.ErrorIs(t, err, rules.ErrInvalidRule)
+	})
+}
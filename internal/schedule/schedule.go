@@ -0,0 +1,179 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/storage"
+)
+
+// Store represents a durable storage of whatsapp messages scheduled to be sent later.
+type Store struct {
+	mu       sync.Mutex
+	file     *storage.JSONFile
+	messages []domain.ScheduledMessage
+}
+
+// Opts represents options to create new instance of Store.
+type Opts struct {
+	// Path is a path to the file the scheduled messages are persisted to.
+	Path string
+}
+
+// New creates new instance of Store and loads previously saved messages.
+func New(opts *Opts) (*Store, error) {
+	s := &Store{
+		file:     storage.NewJSONFile(opts.Path),
+		messages: make([]domain.ScheduledMessage, 0),
+	}
+
+	if err := s.file.Load(&s.messages); err != nil {
+		return nil, fmt.Errorf("failed to load scheduled messages: %w", err)
+	}
+
+	return s, nil
+}
+
+// Add method schedules the message and returns it with the assigned identifier.
+func (s *Store) Add(msg domain.ScheduledMessage) (domain.ScheduledMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lastID := 0
+	for _, m := range s.messages {
+		if m.ChatID == msg.ChatID && m.ID > lastID {
+			lastID = m.ID
+		}
+	}
+	msg.ID = lastID + 1
+
+	messages := make([]domain.ScheduledMessage, 0, len(s.messages)+1)
+	messages = append(messages, s.messages...)
+	messages = append(messages, msg)
+	if err := s.save(messages); err != nil {
+		return domain.ScheduledMessage{}, err
+	}
+
+	return msg, nil
+}
+
+// Messages method returns scheduled messages of the chat in the order they are sent.
+func (s *Store) Messages(chatID int64) []domain.ScheduledMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := make([]domain.ScheduledMessage, 0)
+	for _, m := range s.messages {
+		if m.ChatID == chatID {
+			messages = append(messages, m)
+		}
+	}
+	sortMessages(messages)
+
+	return messages
+}
+
+// Remove method cancels the scheduled message of the chat, false is returned
+// if there is no such message.
+func (s *Store) Remove(chatID int64, id int) (domain.ScheduledMessage, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		removed domain.ScheduledMessage
+		found   bool
+	)
+	messages := make([]domain.ScheduledMessage, 0, len(s.messages))
+	for _, m := range s.messages {
+		if m.ChatID == chatID && m.ID == id {
+			removed, found = m, true
+
+			continue
+		}
+		messages = append(messages, m)
+	}
+	if !found {
+		return domain.ScheduledMessage{}, false, nil
+	}
+
+	if err := s.save(messages); err != nil {
+		return domain.ScheduledMessage{}, false, err
+	}
+
+	return removed, true, nil
+}
+
+// TakeDue method removes the messages of the chat that are due at the time
+// and returns them in the order they are sent.
+func (s *Store) TakeDue(chatID int64, now time.Time) ([]domain.ScheduledMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	due := make([]domain.ScheduledMessage, 0)
+	messages := make([]domain.ScheduledMessage, 0, len(s.messages))
+	for _, m := range s.messages {
+		if m.ChatID == chatID && !m.SendAt.After(now) {
+			due = append(due, m)
+		} else {
+			messages = append(messages, m)
+		}
+	}
+	if len(due) == 0 {
+		return due, nil
+	}
+
+	if err := s.save(messages); err != nil {
+		return nil, err
+	}
+	sortMessages(due)
+
+	return due, nil
+}
+
+// Restore method puts back the messages taken by TakeDue, e.g. they couldn't be sent,
+// so they are due again. The messages keep their identifiers.
+func (s *Store) Restore(restored []domain.ScheduledMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := make([]domain.ScheduledMessage, 0, len(s.messages)+len(restored))
+	messages = append(messages, s.messages...)
+	messages = append(messages, restored...)
+
+	return s.save(messages)
+}
+
+// Chats method returns identifiers of the chats that have scheduled messages.
+func (s *Store) Chats() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[int64]bool)
+	chatIDs := make([]int64, 0)
+	for _, m := range s.messages {
+		if !seen[m.ChatID] {
+			seen[m.ChatID] = true
+			chatIDs = append(chatIDs, m.ChatID)
+		}
+	}
+
+	return chatIDs
+}
+
+func (s *Store) save(messages []domain.ScheduledMessage) error {
+	if err := s.file.Save(messages); err != nil {
+		return fmt.Errorf("failed to save scheduled messages: %w", err)
+	}
+	s.messages = messages
+
+	return nil
+}
+
+func sortMessages(messages []domain.ScheduledMessage) {
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].SendAt.Before(messages[j].SendAt)
+	})
+}
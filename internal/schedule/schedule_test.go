@@ -0,0 +1,89 @@
+package schedule_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/schedule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testChatID = int64(123)
+
+func TestStore(t *testing.T) {
+	now := time.Date(2022, 3, 31, 10, 0, 0, 0, time.UTC)
+
+	t.Run("add, remove and take due", func(t *testing.T) {
+		store, err := schedule.New(&schedule.Opts{Path: filepath.Join(t.TempDir(), "schedule.json")})
+		require.NoError(t, err)
+
+		later, err := store.Add(domain.ScheduledMessage{ChatID: testChatID, Text: "later", SendAt: now.Add(time.Hour)})
+		require.NoError(t, err)
+		assert.Equal(t, 1, later.ID)
+		sooner, err := store.Add(domain.ScheduledMessage{ChatID: testChatID, Text: "sooner", SendAt: now})
+		require.NoError(t, err)
+		assert.Equal(t, 2, sooner.ID)
+		other, err := store.Add(domain.ScheduledMessage{ChatID: 456, Text: "other", SendAt: now})
+		require.NoError(t, err)
+		assert.Equal(t, 1, other.ID)
+
+		assert.Equal(t, []domain.ScheduledMessage{sooner, later}, store.Messages(testChatID))
+
+		due, err := store.TakeDue(testChatID, now)
+		require.NoError(t, err)
+		assert.Equal(t, []domain.ScheduledMessage{sooner}, due)
+		assert.Equal(t, []domain.ScheduledMessage{later}, store.Messages(testChatID))
+
+		removed, ok, err := store.Remove(testChatID, later.ID)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, later, removed)
+
+		_, ok, err = store.Remove(testChatID, later.ID)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Empty(t, store.Messages(testChatID))
+		assert.Len(t, store.Messages(456), 1)
+	})
+
+	t.Run("restore and chats", func(t *testing.T) {
+		store, err := schedule.New(&schedule.Opts{Path: filepath.Join(t.TempDir(), "schedule.json")})
+		require.NoError(t, err)
+
+		msg, err := store.Add(domain.ScheduledMessage{ChatID: testChatID, Text: "hi", SendAt: now})
+		require.NoError(t, err)
+		_, err = store.Add(domain.ScheduledMessage{ChatID: 456, Text: "other", SendAt: now.Add(time.Hour)})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []int64{testChatID, 456}, store.Chats())
+
+		due, err := store.TakeDue(testChatID, now)
+		require.NoError(t, err)
+		assert.Equal(t, []domain.ScheduledMessage{msg}, due)
+		assert.Equal(t, []int64{456}, store.Chats())
+
+		require.NoError(t, store.Restore(due))
+		assert.Equal(t, []domain.ScheduledMessage{msg}, store.Messages(testChatID))
+		assert.ElementsMatch(t, []int64{testChatID, 456}, store.Chats())
+	})
+
+	t.Run("messages are persisted", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "schedule.json")
+		store, err := schedule.New(&schedule.Opts{Path: path})
+		require.NoError(t, err)
+
+		msg, err := store.Add(domain.ScheduledMessage{ChatID: testChatID, Text: "hi", SendAt: now})
+		require.NoError(t, err)
+
+		reloaded, err := schedule.New(&schedule.Opts{Path: path})
+		require.NoError(t, err)
+
+		due, err := reloaded.TakeDue(testChatID, now.Add(time.Minute))
+		require.NoError(t, err)
+		require.Len(t, due, 1)
+		assert.Equal(t, msg.Text, due[0].Text)
+		assert.True(t, msg.SendAt.Equal(due[0].SendAt))
+	})
+}
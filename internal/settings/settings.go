@@ -0,0 +1,95 @@
+package settings
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/storage"
+)
+
+// Store represents a durable storage of settings of whatsapp contacts.
+// Only contacts with a mode other than normal are stored.
+type Store struct {
+	mu       sync.Mutex
+	file     *storage.JSONFile
+	settings []domain.ContactSettings
+}
+
+// Opts represents options to create new instance of Store.
+type Opts struct {
+	// Path is a path to the file the settings are persisted to.
+	Path string
+}
+
+// New creates new instance of Store and loads previously saved settings.
+func New(opts *Opts) (*Store, error) {
+	s := &Store{
+		file:     storage.NewJSONFile(opts.Path),
+		settings: make([]domain.ContactSettings, 0),
+	}
+
+	if err := s.file.Load(&s.settings); err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	return s, nil
+}
+
+// Settings method returns settings of the contacts of the chat.
+func (s *Store) Settings(chatID int64) []domain.ContactSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings := make([]domain.ContactSettings, 0)
+	for _, cs := range s.settings {
+		if cs.ChatID == chatID {
+			settings = append(settings, cs)
+		}
+	}
+
+	return settings
+}
+
+// Mode method returns the mode of the contact, it's normal unless
+// it has been changed.
+func (s *Store) Mode(chatID int64, account, remoteJid string) domain.ContactMode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, cs := range s.settings {
+		if cs.ChatID == chatID && cs.Account == account && cs.RemoteJid == remoteJid {
+			return cs.Mode
+		}
+	}
+
+	return domain.ContactModeNormal
+}
+
+// SetMode method saves the mode of the contact.
+func (s *Store) SetMode(chatID int64, account, remoteJid string, mode domain.ContactMode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings := make([]domain.ContactSettings, 0, len(s.settings)+1)
+	for _, cs := range s.settings {
+		if cs.ChatID != chatID || cs.Account != account || cs.RemoteJid != remoteJid {
+			settings = append(settings, cs)
+		}
+	}
+	if mode != domain.ContactModeNormal {
+		settings = append(settings, domain.ContactSettings{
+			ChatID:    chatID,
+			Account:   account,
+			RemoteJid: remoteJid,
+			Mode:      mode,
+		})
+	}
+
+	if err := s.file.Save(settings); err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+	s.settings = settings
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package settings_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testChatID    = int64(123)
+	testRemoteJid = "alice@s.whatsapp.net"
+)
+
+func TestStore(t *testing.T) {
+	t.Run("set mode", func(t *testing.T) {
+		store, err := settings.New(&settings.Opts{Path: filepath.Join(t.TempDir(), "settings.json")})
+		require.NoError(t, err)
+
+		assert.Equal(t, domain.ContactModeNormal, store.Mode(testChatID, domain.DefaultWhatsappAccount, testRemoteJid))
+		assert.Empty(t, store.Settings(testChatID))
+
+		require.NoError(t, store.SetMode(testChatID, domain.DefaultWhatsappAccount, testRemoteJid, domain.ContactModeMute))
+		require.NoError(t, store.SetMode(testChatID, domain.DefaultWhatsappAccount, testRemoteJid, domain.ContactModeSilent))
+		assert.Equal(t, domain.ContactModeSilent, store.Mode(testChatID, domain.DefaultWhatsappAccount, testRemoteJid))
+
+		// Settings belong to the account and the chat
+		assert.Equal(t, domain.ContactModeNormal, store.Mode(testChatID, "work", testRemoteJid))
+		assert.Equal(t, domain.ContactModeNormal, store.Mode(456, domain.DefaultWhatsappAccount, testRemoteJid))
+
+		assert.Equal(t, []domain.ContactSettings{{
+			ChatID:    testChatID,
+			Account:   domain.DefaultWhatsappAccount,
+			RemoteJid: testRemoteJid,
+			Mode:      domain.ContactModeSilent,
+		}}, store.Settings(testChatID))
+
+		// Normal mode is not stored
+		require.NoError(t, store.SetMode(testChatID, domain.DefaultWhatsappAccount, testRemoteJid, domain.ContactModeNormal))
+		assert.Equal(t, domain.ContactModeNormal, store.Mode(testChatID, domain.DefaultWhatsappAccount, testRemoteJid))
+		assert.Empty(t, store.Settings(testChatID))
+	})
+
+	t.Run("settings are persisted", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "settings.json")
+		store, err := settings.New(&settings.Opts{Path: path})
+		require.NoError(t, err)
+
+		require.NoError(t, store.SetMode(testChatID, "work", testRemoteJid, domain.ContactModeBlock))
+
+		reloaded, err := settings.New(&settings.Opts{Path: path})
+		require.NoError(t, err)
+		assert.Equal(t, domain.ContactModeBlock, reloaded.Mode(testChatID, "work", testRemoteJid))
+	})
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	defaultDirPerm  = 0o700
+	defaultFilePerm = 0o600
+)
+
+// JSONFile represents a file that keeps a JSON encoded value.
+type JSONFile struct {
+	path string
+}
+
+// NewJSONFile returns new instance of JSONFile.
+func NewJSONFile(path string) *JSONFile {
+	return &JSONFile{path: path}
+}
+
+// Path method returns path to the file.
+func (f *JSONFile) Path() string {
+	return f.path
+}
+
+// Load method decodes content of the file into v.
+// It's not an error if the file doesn't exist, v stays untouched in that case.
+func (f *JSONFile) Load(v interface{}) error {
+	raw, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", f.path, err)
+	}
+
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", f.path, err)
+	}
+
+	return nil
+}
+
+// Save method encodes v and replaces content of the file with it.
+// The file is replaced atomically, so it's never left partially written.
+func (f *JSONFile) Save(v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", f.path, err)
+	}
+
+	dir := filepath.Dir(f.path)
+	if err := os.MkdirAll(dir, defaultDirPerm); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // nolint
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close() // nolint
+
+		return fmt.Errorf("failed to write %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Chmod(defaultFilePerm); err != nil {
+		tmp.Close() // nolint
+
+		return fmt.Errorf("failed to chmod %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", tmp.Name(), err)
+	}
+
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", f.path, err)
+	}
+
+	return nil
+}
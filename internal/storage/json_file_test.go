@@ -0,0 +1,50 @@
+package storage_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testValue struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestJSONFile(t *testing.T) {
+	t.Run("load missing file", func(t *testing.T) {
+		file := storage.NewJSONFile(filepath.Join(t.TempDir(), "missing.json"))
+
+		got := testValue{Name: "untouched"}
+		require.NoError(t, file.Load(&got))
+		assert.Equal(t, testValue{Name: "untouched"}, got)
+	})
+
+	t.Run("save and load", func(t *testing.T) {
+		file := storage.NewJSONFile(filepath.Join(t.TempDir(), "nested", "value.json"))
+
+		expected := testValue{Name: "test", Count: 42}
+		require.NoError(t, file.Save(expected))
+
+		var got testValue
+		require.NoError(t, file.Load(&got))
+		assert.Equal(t, expected, got)
+
+		// Check that temporary files are cleaned up
+		entries, err := os.ReadDir(filepath.Dir(file.Path()))
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("load corrupted file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "corrupted.json")
+		require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+
+		var got testValue
+		assert.Error(t, storage.NewJSONFile(path).Load(&got))
+	})
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// maxLineSize is the maximum size of a single line of JSONLinesFile.
+const maxLineSize = 16 * 1024 * 1024
+
+// JSONLinesFile represents a file that keeps JSON encoded values, one per line.
+// Values are only appended to the file, so it suits logs that grow over time.
+type JSONLinesFile struct {
+	path string
+}
+
+// NewJSONLinesFile returns new instance of JSONLinesFile.
+func NewJSONLinesFile(path string) *JSONLinesFile {
+	return &JSONLinesFile{path: path}
+}
+
+// Path method returns path to the file.
+func (f *JSONLinesFile) Path() string {
+	return f.path
+}
+
+// Load method calls decode for every line of the file in order.
+// It's not an error if the file doesn't exist, decode is never called in that case.
+func (f *JSONLinesFile) Load(decode func(line []byte) error) error {
+	file, err := os.Open(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", f.path, err)
+	}
+	defer file.Close() // nolint
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxLineSize)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := decode(scanner.Bytes()); err != nil {
+			return fmt.Errorf("failed to decode %s line %d: %w", f.path, lineNum, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", f.path, err)
+	}
+
+	return nil
+}
+
+// Append method encodes v and appends it to the file as a new line.
+func (f *JSONLinesFile) Append(v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", f.path, err)
+	}
+
+	dir := filepath.Dir(f.path)
+	if err := os.MkdirAll(dir, defaultDirPerm); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, defaultFilePerm)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", f.path, err)
+	}
+
+	if _, err := file.Write(append(raw, '\n')); err != nil {
+		file.Close() // nolint
+
+		return fmt.Errorf("failed to write %s: %w", f.path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", f.path, err)
+	}
+
+	return nil
+}
+
+// Replace method replaces content of the file with n values returned by value, one per line.
+// The file is replaced atomically like JSONFile, so old values can be dropped without losing the rest.
+func (f *JSONLinesFile) Replace(n int, value func(i int) interface{}) error {
+	dir := filepath.Dir(f.path)
+	if err := os.MkdirAll(dir, defaultDirPerm); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // nolint
+
+	w := bufio.NewWriter(tmp)
+	for i := 0; i < n; i++ {
+		raw, err := json.Marshal(value(i))
+		if err != nil {
+			tmp.Close() // nolint
+
+			return fmt.Errorf("failed to encode %s: %w", f.path, err)
+		}
+		if _, err := w.Write(append(raw, '\n')); err != nil {
+			tmp.Close() // nolint
+
+			return fmt.Errorf("failed to write %s: %w", tmp.Name(), err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close() // nolint
+
+		return fmt.Errorf("failed to write %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Chmod(defaultFilePerm); err != nil {
+		tmp.Close() // nolint
+
+		return fmt.Errorf("failed to chmod %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", tmp.Name(), err)
+	}
+
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", f.path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package storage_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONLinesFile(t *testing.T) {
+	load := func(t *testing.T, file *storage.JSONLinesFile) []testValue {
+		t.Helper()
+
+		var got []testValue
+		require.NoError(t, file.Load(func(line []byte) error {
+			var v testValue
+			if err := json.Unmarshal(line, &v); err != nil {
+				return err
+			}
+			got = append(got, v)
+
+			return nil
+		}))
+
+		return got
+	}
+
+	t.Run("load missing file", func(t *testing.T) {
+		file := storage.NewJSONLinesFile(filepath.Join(t.TempDir(), "missing.jsonl"))
+		assert.Empty(t, load(t, file))
+	})
+
+	t.Run("append and load", func(t *testing.T) {
+		file := storage.NewJSONLinesFile(filepath.Join(t.TempDir(), "nested", "values.jsonl"))
+
+		require.NoError(t, file.Append(testValue{Name: "first", Count: 1}))
+		require.NoError(t, file.Append(testValue{Name: "second", Count: 2}))
+
+		assert.Equal(t, []testValue{{Name: "first", Count: 1}, {Name: "second", Count: 2}}, load(t, file))
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		file := storage.NewJSONLinesFile(filepath.Join(t.TempDir(), "values.jsonl"))
+		require.NoError(t, file.Append(testValue{Name: "old", Count: 1}))
+
+		values := []testValue{{Name: "first", Count: 1}, {Name: "second", Count: 2}}
+		require.NoError(t, file.Replace(len(values), func(i int) interface{} {
+			return values[i]
+		}))
+		assert.Equal(t, values, load(t, file))
+
+		require.NoError(t, file.Append(testValue{Name: "third", Count: 3}))
+		assert.Len(t, load(t, file), 3)
+	})
+
+	t.Run("load corrupted file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "corrupted.jsonl")
+		require.NoError(t, os.WriteFile(path, []byte("{\"name\":\"ok\"}\n{"), 0o600))
+
+		err := storage.NewJSONLinesFile(path).Load(func(line []byte) error {
+			var v testValue
+
+			return json.Unmarshal(line, &v)
+		})
+		assert.Error(t, err)
+	})
+}
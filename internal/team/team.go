@@ -0,0 +1,152 @@
+package team
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/storage"
+)
+
+var (
+	// ErrTeamExists is returned when the chat already has a team.
+	ErrTeamExists = errors.New("team already exists")
+
+	// ErrNoTeam is returned when the chat has no team.
+	ErrNoTeam = errors.New("team doesn't exist")
+)
+
+// Store represents a durable storage of the teams.
+type Store struct {
+	mu    sync.Mutex
+	file  *storage.JSONFile
+	teams []*domain.Team
+}
+
+// Opts represents options to create new instance of Store.
+type Opts struct {
+	// Path is a path to the file the teams are persisted to.
+	Path string
+}
+
+// New creates new instance of Store and loads previously saved teams.
+func New(opts *Opts) (*Store, error) {
+	s := &Store{
+		file:  storage.NewJSONFile(opts.Path),
+		teams: make([]*domain.Team, 0),
+	}
+
+	if err := s.file.Load(&s.teams); err != nil {
+		return nil, fmt.Errorf("failed to load teams: %w", err)
+	}
+
+	return s, nil
+}
+
+// Get method returns the team of the chat, false is returned if the chat
+// has no team.
+func (s *Store) Get(chatID int64) (domain.Team, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if idx := s.find(chatID); idx != -1 {
+		return clone(s.teams[idx]), true
+	}
+
+	return domain.Team{}, false
+}
+
+// Create method creates a new team of the chat owned by the user.
+func (s *Store) Create(chatID int64, owner string) (domain.Team, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.find(chatID) != -1 {
+		return domain.Team{}, ErrTeamExists
+	}
+
+	t := &domain.Team{
+		ChatID:      chatID,
+		Owner:       owner,
+		Members:     make([]string, 0),
+		Assignments: make([]domain.Assignment, 0),
+	}
+
+	teams := make([]*domain.Team, 0, len(s.teams)+1)
+	teams = append(teams, s.teams...)
+	teams = append(teams, t)
+	if err := s.file.Save(teams); err != nil {
+		return domain.Team{}, fmt.Errorf("failed to save teams: %w", err)
+	}
+	s.teams = teams
+
+	return clone(t), nil
+}
+
+// Delete method removes the team of the chat.
+func (s *Store) Delete(chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := s.find(chatID)
+	if idx == -1 {
+		return ErrNoTeam
+	}
+
+	teams := make([]*domain.Team, 0, len(s.teams))
+	teams = append(teams, s.teams[:idx]...)
+	teams = append(teams, s.teams[idx+1:]...)
+	if err := s.file.Save(teams); err != nil {
+		return fmt.Errorf("failed to save teams: %w", err)
+	}
+	s.teams = teams
+
+	return nil
+}
+
+// Update method applies fn to the team of the chat and saves the result.
+// The team is left untouched if fn returns an error.
+func (s *Store) Update(chatID int64, fn func(t *domain.Team) error) (domain.Team, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := s.find(chatID)
+	if idx == -1 {
+		return domain.Team{}, ErrNoTeam
+	}
+
+	updated := clone(s.teams[idx])
+	if err := fn(&updated); err != nil {
+		return domain.Team{}, err
+	}
+
+	teams := make([]*domain.Team, len(s.teams))
+	copy(teams, s.teams)
+	teams[idx] = &updated
+	if err := s.file.Save(teams); err != nil {
+		return domain.Team{}, fmt.Errorf("failed to save teams: %w", err)
+	}
+	s.teams = teams
+
+	return clone(&updated), nil
+}
+
+func (s *Store) find(chatID int64) int {
+	for i, t := range s.teams {
+		if t.ChatID == chatID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// clone returns a copy of the team that doesn't share slices with the original.
+func clone(t *domain.Team) domain.Team {
+	c := *t
+	c.Members = append(make([]string, 0, len(t.Members)), t.Members...)
+	c.Assignments = append(make([]domain.Assignment, 0, len(t.Assignments)), t.Assignments...)
+
+	return c
+}
@@ -0,0 +1,107 @@
+package team_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/team"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTestUpdate = errors.New("update failed")
+
+func TestStore(t *testing.T) {
+	testChatID := int64(-100123)
+
+	t.Run("create and get", func(t *testing.T) {
+		store, err := team.New(&team.Opts{Path: filepath.Join(t.TempDir(), "teams.json")})
+		require.NoError(t, err)
+
+		_, ok := store.Get(testChatID)
+		assert.False(t, ok)
+
+		created, err := store.Create(testChatID, "alice")
+		require.NoError(t, err)
+		assert.Equal(t, "alice", created.Owner)
+
+		got, ok := store.Get(testChatID)
+		require.True(t, ok)
+		assert.Equal(t, created, got)
+
+		_, err = store.Create(testChatID, "bob")
+		assert.ErrorIs(t, err, team.ErrTeamExists)
+	})
+
+	t.Run("teams are persisted", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "teams.json")
+		store, err := team.New(&team.Opts{Path: path})
+		require.NoError(t, err)
+
+		_, err = store.Create(testChatID, "alice")
+		require.NoError(t, err)
+		_, err = store.Update(testChatID, func(t *domain.Team) error {
+			t.Members = append(t.Members, "bob")
+			t.SignReplies = true
+			t.Assignments = append(t.Assignments, domain.Assignment{
+				Account:   domain.DefaultWhatsappAccount,
+				RemoteJid: "test-jid",
+				Assignee:  "bob",
+			})
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		reloaded, err := team.New(&team.Opts{Path: path})
+		require.NoError(t, err)
+
+		got, ok := reloaded.Get(testChatID)
+		require.True(t, ok)
+		assert.Equal(t, []string{"bob"}, got.Members)
+		assert.True(t, got.SignReplies)
+		assert.Equal(t, "bob", got.Assignee(domain.DefaultWhatsappAccount, "test-jid"))
+	})
+
+	t.Run("failed update leaves the team untouched", func(t *testing.T) {
+		store, err := team.New(&team.Opts{Path: filepath.Join(t.TempDir(), "teams.json")})
+		require.NoError(t, err)
+
+		_, err = store.Create(testChatID, "alice")
+		require.NoError(t, err)
+
+		_, err = store.Update(testChatID, func(t *domain.Team) error {
+			t.Members = append(t.Members, "bob")
+
+			return errTestUpdate
+		})
+		assert.ErrorIs(t, err, errTestUpdate)
+
+		got, ok := store.Get(testChatID)
+		require.True(t, ok)
+		assert.Empty(t, got.Members)
+	})
+
+	t.Run("update and delete missing team", func(t *testing.T) {
+		store, err := team.New(&team.Opts{Path: filepath.Join(t.TempDir(), "teams.json")})
+		require.NoError(t, err)
+
+		_, err = store.Update(testChatID, func(t *domain.Team) error { return nil })
+		assert.ErrorIs(t, err, team.ErrNoTeam)
+		assert.ErrorIs(t, store.Delete(testChatID), team.ErrNoTeam)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		store, err := team.New(&team.Opts{Path: filepath.Join(t.TempDir(), "teams.json")})
+		require.NoError(t, err)
+
+		_, err = store.Create(testChatID, "alice")
+		require.NoError(t, err)
+		require.NoError(t, store.Delete(testChatID))
+
+		_, ok := store.Get(testChatID)
+		assert.False(t, ok)
+	})
+}
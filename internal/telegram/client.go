@@ -0,0 +1,423 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// editedPhotoField is a name of the multipart field a replacement photo is uploaded as.
+const editedPhotoField = "photo"
+
+// topicNotFoundError is a description of the error returned by telegram API
+// when a message is sent to a deleted forum topic.
+const topicNotFoundError = "message thread not found"
+
+// maxVcardSize is the largest vCard in bytes telegram accepts with a contact.
+const maxVcardSize = 2048
+
+// errDownloadFailed is returned when telegram doesn't return content of the file.
+var errDownloadFailed = errors.New("failed to download file")
+
+// Client represents a telegram bot API wrapper.
+type Client struct {
+	api *tgbotapi.BotAPI
+}
+
+// NewClient returns new instance of Client.
+func NewClient(api *tgbotapi.BotAPI) *Client {
+	return &Client{api: api}
+}
+
+// SendText method sends a text message and returns its identifier.
+func (c *Client) SendText(msg *domain.TelegramTextMessage) (int, error) {
+	if msg.ThreadID != 0 {
+		return c.sendTopicText(msg)
+	}
+
+	config := tgbotapi.NewMessage(msg.ChatID, msg.Text)
+	config.DisableNotification = msg.DisableNotification
+	config.ReplyToMessageID = msg.ReplyToMessageID
+	if keyboard := inlineKeyboard(msg.Buttons); keyboard != nil {
+		config.ReplyMarkup = keyboard
+	}
+
+	sent, err := c.api.Send(config)
+	if err != nil {
+		return 0, err
+	}
+
+	return sent.MessageID, nil
+}
+
+// SendPhoto method uploads a photo and returns identifier of the message.
+func (c *Client) SendPhoto(msg *domain.TelegramPhotoMessage) (int, error) {
+	config := tgbotapi.NewPhotoUpload(msg.ChatID, fileReader(msg.Photo))
+	config.Caption = msg.Caption
+	if keyboard := inlineKeyboard(msg.Buttons); keyboard != nil {
+		config.ReplyMarkup = keyboard
+	}
+
+	sent, err := c.api.Send(config)
+	if err != nil {
+		return 0, err
+	}
+
+	return sent.MessageID, nil
+}
+
+// SendDocument method uploads a document and returns identifier of the message.
+func (c *Client) SendDocument(msg *domain.TelegramDocumentMessage) (int, error) {
+	config := tgbotapi.NewDocumentUpload(msg.ChatID, fileReader(msg.Document))
+	config.MimeType = msg.MimeType
+	config.Caption = msg.Caption
+	config.DisableNotification = msg.DisableNotification
+	config.ReplyToMessageID = msg.ReplyToMessageID
+	if keyboard := inlineKeyboard(msg.Buttons); keyboard != nil {
+		config.ReplyMarkup = keyboard
+	}
+
+	sent, err := c.api.Send(config)
+	if err != nil {
+		return 0, err
+	}
+
+	return sent.MessageID, nil
+}
+
+// EditMessage method replaces text and inline keyboard of a text message.
+func (c *Client) EditMessage(msg *domain.TelegramEditMessage) error {
+	config := tgbotapi.NewEditMessageText(msg.ChatID, msg.MessageID, msg.Text)
+	config.ReplyMarkup = inlineKeyboard(msg.Buttons)
+
+	_, err := c.api.Send(config)
+
+	return err
+}
+
+// EditPhoto method replaces photo, caption and inline keyboard of a photo message.
+func (c *Client) EditPhoto(msg *domain.TelegramEditPhotoMessage) error {
+	// The library doesn't support editMessageMedia, so call it directly
+	media, err := json.Marshal(map[string]string{
+		"type":    "photo",
+		"media":   "attach://" + editedPhotoField,
+		"caption": msg.Caption,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode media: %w", err)
+	}
+
+	params := map[string]string{
+		"chat_id":    strconv.FormatInt(msg.ChatID, 10),
+		"message_id": strconv.Itoa(msg.MessageID),
+		"media":      string(media),
+	}
+	if keyboard := inlineKeyboard(msg.Buttons); keyboard != nil {
+		rawKeyboard, err := json.Marshal(keyboard)
+		if err != nil {
+			return fmt.Errorf("failed to encode inline keyboard: %w", err)
+		}
+		params["reply_markup"] = string(rawKeyboard)
+	}
+
+	_, err = c.api.UploadFile("editMessageMedia", params, editedPhotoField, fileReader(msg.Photo))
+
+	return err
+}
+
+// EditCaption method replaces caption and inline keyboard of a media message.
+func (c *Client) EditCaption(msg *domain.TelegramEditCaptionMessage) error {
+	config := tgbotapi.NewEditMessageCaption(msg.ChatID, msg.MessageID, msg.Caption)
+	config.ReplyMarkup = inlineKeyboard(msg.Buttons)
+
+	_, err := c.api.Send(config)
+
+	return err
+}
+
+// AnswerCallback method answers a callback query.
+func (c *Client) AnswerCallback(answer *domain.TelegramCallbackAnswer) error {
+	_, err := c.api.AnswerCallbackQuery(tgbotapi.NewCallback(answer.CallbackID, answer.Text))
+
+	return err
+}
+
+// CreateTopic method creates a forum topic in the chat and returns its identifier.
+func (c *Client) CreateTopic(chatID int64, name string) (int, error) {
+	// The library doesn't support forum topics, so call the API directly
+	params := url.Values{}
+	params.Set("chat_id", strconv.FormatInt(chatID, 10))
+	params.Set("name", name)
+
+	resp, err := c.api.MakeRequest("createForumTopic", params)
+	if err != nil {
+		return 0, err
+	}
+
+	var topic struct {
+		MessageThreadID int `json:"message_thread_id"`
+	}
+	if err := json.Unmarshal(resp.Result, &topic); err != nil {
+		return 0, fmt.Errorf("failed to decode forum topic: %w", err)
+	}
+
+	return topic.MessageThreadID, nil
+}
+
+// PinMessage method pins the message in the chat without notifying its members.
+func (c *Client) PinMessage(chatID int64, messageID int) error {
+	_, err := c.api.PinChatMessage(tgbotapi.PinChatMessageConfig{
+		ChatID:              chatID,
+		MessageID:           messageID,
+		DisableNotification: true,
+	})
+
+	return err
+}
+
+// UnpinMessage method unpins the message in the chat.
+func (c *Client) UnpinMessage(chatID int64, messageID int) error {
+	// The library can unpin only the latest pinned message, so call the API directly
+	params := url.Values{}
+	params.Set("chat_id", strconv.FormatInt(chatID, 10))
+	params.Set("message_id", strconv.Itoa(messageID))
+
+	_, err := c.api.MakeRequest("unpinChatMessage", params)
+
+	return err
+}
+
+// SendChatAction method shows the chat action to the users of the chat, e.g. that somebody is typing.
+func (c *Client) SendChatAction(action *domain.TelegramChatAction) error {
+	// The library doesn't support message_thread_id parameter, so call the API directly
+	params := url.Values{}
+	params.Set("chat_id", strconv.FormatInt(action.ChatID, 10))
+	params.Set("action", action.Action)
+	if action.ThreadID != 0 {
+		params.Set("message_thread_id", strconv.Itoa(action.ThreadID))
+	}
+
+	_, err := c.api.MakeRequest("sendChatAction", params)
+
+	return err
+}
+
+// SetReaction method sets the reaction of the bot to the message, the reaction is removed
+// if the emoji is empty.
+func (c *Client) SetReaction(reaction *domain.TelegramReaction) error {
+	// The library doesn't support reactions, so call the API directly
+	reactions := []ReactionType{}
+	if reaction.Emoji != "" {
+		reactions = append(reactions, ReactionType{Type: emojiReactionType, Emoji: reaction.Emoji})
+	}
+
+	rawReactions, err := json.Marshal(reactions)
+	if err != nil {
+		return fmt.Errorf("failed to encode reaction: %w", err)
+	}
+
+	params := url.Values{}
+	params.Set("chat_id", strconv.FormatInt(reaction.ChatID, 10))
+	params.Set("message_id", strconv.Itoa(reaction.MessageID))
+	params.Set("reaction", string(rawReactions))
+
+	_, err = c.api.MakeRequest("setMessageReaction", params)
+
+	return err
+}
+
+// SendLocation method sends a location or a venue and returns identifier of the message.
+func (c *Client) SendLocation(msg *domain.TelegramLocationMessage) (int, error) {
+	// The library doesn't support live locations and replies with venues, so call the API directly
+	method := "sendLocation"
+	params := url.Values{}
+	params.Set("chat_id", strconv.FormatInt(msg.ChatID, 10))
+	params.Set("latitude", formatDegrees(msg.Latitude))
+	params.Set("longitude", formatDegrees(msg.Longitude))
+	if msg.Title != "" && msg.Address != "" {
+		method = "sendVenue"
+		params.Set("title", msg.Title)
+		params.Set("address", msg.Address)
+	} else if msg.LivePeriod != 0 {
+		params.Set("live_period", strconv.Itoa(msg.LivePeriod))
+	}
+	if msg.DisableNotification {
+		params.Set("disable_notification", strconv.FormatBool(msg.DisableNotification))
+	}
+	if msg.ReplyToMessageID != 0 {
+		params.Set("reply_to_message_id", strconv.Itoa(msg.ReplyToMessageID))
+	}
+
+	resp, err := c.api.MakeRequest(method, params)
+	if err != nil {
+		return 0, err
+	}
+
+	var sent tgbotapi.Message
+	if err := json.Unmarshal(resp.Result, &sent); err != nil {
+		return 0, fmt.Errorf("failed to decode message: %w", err)
+	}
+
+	return sent.MessageID, nil
+}
+
+// EditLocation method moves the live location to the new place.
+func (c *Client) EditLocation(msg *domain.TelegramEditLocationMessage) error {
+	params := url.Values{}
+	params.Set("chat_id", strconv.FormatInt(msg.ChatID, 10))
+	params.Set("message_id", strconv.Itoa(msg.MessageID))
+	params.Set("latitude", formatDegrees(msg.Latitude))
+	params.Set("longitude", formatDegrees(msg.Longitude))
+
+	_, err := c.api.MakeRequest("editMessageLiveLocation", params)
+
+	return err
+}
+
+// StopLocation method stops updating the live location, it stays at the last place.
+func (c *Client) StopLocation(chatID int64, messageID int) error {
+	params := url.Values{}
+	params.Set("chat_id", strconv.FormatInt(chatID, 10))
+	params.Set("message_id", strconv.Itoa(messageID))
+
+	_, err := c.api.MakeRequest("stopMessageLiveLocation", params)
+
+	return err
+}
+
+// SendContact method sends a contact and returns identifier of the message.
+func (c *Client) SendContact(msg *domain.TelegramContactMessage) (int, error) {
+	// The library doesn't support vCards, so call the API directly
+	params := url.Values{}
+	params.Set("chat_id", strconv.FormatInt(msg.ChatID, 10))
+	params.Set("phone_number", msg.PhoneNumber)
+	params.Set("first_name", msg.FirstName)
+	if msg.LastName != "" {
+		params.Set("last_name", msg.LastName)
+	}
+	// Telegram refuses too large vCards, the contact is sent without it then
+	if msg.Vcard != "" && len(msg.Vcard) <= maxVcardSize {
+		params.Set("vcard", msg.Vcard)
+	}
+	if msg.DisableNotification {
+		params.Set("disable_notification", strconv.FormatBool(msg.DisableNotification))
+	}
+	if msg.ReplyToMessageID != 0 {
+		params.Set("reply_to_message_id", strconv.Itoa(msg.ReplyToMessageID))
+	}
+
+	resp, err := c.api.MakeRequest("sendContact", params)
+	if err != nil {
+		return 0, err
+	}
+
+	var sent tgbotapi.Message
+	if err := json.Unmarshal(resp.Result, &sent); err != nil {
+		return 0, fmt.Errorf("failed to decode message: %w", err)
+	}
+
+	return sent.MessageID, nil
+}
+
+// DownloadFile method downloads content of the file sent to the bot,
+// telegram lets bots download files up to 20 MB.
+func (c *Client) DownloadFile(fileID string) ([]byte, error) {
+	link, err := c.api.GetFileDirectURL(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, link, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.api.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errDownloadFailed, err.Error())
+	}
+	defer resp.Body.Close() // nolint
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", errDownloadFailed, resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// sendTopicText sends a text message to the forum topic, the library
+// doesn't support message_thread_id parameter.
+func (c *Client) sendTopicText(msg *domain.TelegramTextMessage) (int, error) {
+	params := url.Values{}
+	params.Set("chat_id", strconv.FormatInt(msg.ChatID, 10))
+	params.Set("message_thread_id", strconv.Itoa(msg.ThreadID))
+	params.Set("text", msg.Text)
+	if msg.DisableNotification {
+		params.Set("disable_notification", strconv.FormatBool(msg.DisableNotification))
+	}
+	if msg.ReplyToMessageID != 0 {
+		params.Set("reply_to_message_id", strconv.Itoa(msg.ReplyToMessageID))
+	}
+	if keyboard := inlineKeyboard(msg.Buttons); keyboard != nil {
+		rawKeyboard, err := json.Marshal(keyboard)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode inline keyboard: %w", err)
+		}
+		params.Set("reply_markup", string(rawKeyboard))
+	}
+
+	resp, err := c.api.MakeRequest("sendMessage", params)
+	if err != nil {
+		if strings.Contains(err.Error(), topicNotFoundError) {
+			return 0, fmt.Errorf("%w: %s", domain.ErrTelegramTopicNotFound, err.Error())
+		}
+
+		return 0, err
+	}
+
+	var sent tgbotapi.Message
+	if err := json.Unmarshal(resp.Result, &sent); err != nil {
+		return 0, fmt.Errorf("failed to decode message: %w", err)
+	}
+
+	return sent.MessageID, nil
+}
+
+func formatDegrees(degrees float64) string {
+	return strconv.FormatFloat(degrees, 'f', -1, 64)
+}
+
+func fileReader(file domain.TelegramFile) tgbotapi.FileReader {
+	return tgbotapi.FileReader{
+		Name:   file.Name,
+		Reader: bytes.NewReader(file.Bytes),
+		Size:   int64(len(file.Bytes)),
+	}
+}
+
+func inlineKeyboard(buttons [][]domain.TelegramButton) *tgbotapi.InlineKeyboardMarkup {
+	if len(buttons) == 0 {
+		return nil
+	}
+
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(buttons))
+	for _, row := range buttons {
+		keyboardRow := make([]tgbotapi.InlineKeyboardButton, 0, len(row))
+		for _, button := range row {
+			keyboardRow = append(keyboardRow,
+				tgbotapi.NewInlineKeyboardButtonData(button.Text, button.CallbackData))
+		}
+		rows = append(rows, keyboardRow)
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+
+	return &keyboard
+}
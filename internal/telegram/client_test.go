@@ -0,0 +1,502 @@
+package telegram_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/telegram"
+	"github.com/dstdfx/twbridge/internal/telegram/fake"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ domain.TelegramClient = &telegram.Client{}
+	_ domain.TelegramClient = &fake.Client{}
+)
+
+// apiRecorder is a http.RoundTripper that emulates telegram bot API
+// and records the requests made.
+type apiRecorder struct {
+	mu       sync.Mutex
+	requests map[string][]*http.Request
+}
+
+func (r *apiRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := req.ParseMultipartForm(1 << 20); err != nil {
+			return nil, err
+		}
+	} else if err := req.ParseForm(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.requests[method] = append(r.requests[method], req)
+	r.mu.Unlock()
+
+	// Files are downloaded as is, they aren't wrapped into API responses
+	if strings.HasPrefix(req.URL.Path, "/file/bot") {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewBufferString("test content of " + method)),
+			Request:    req,
+		}, nil
+	}
+
+	var result string
+	switch method {
+	case "getMe":
+		result = `{"id":1,"is_bot":true,"username":"test_bot"}`
+	case "answerCallbackQuery", "pinChatMessage", "unpinChatMessage", "sendChatAction", "setMessageReaction":
+		result = `true`
+	case "getUpdates":
+		result = `[{"update_id":5,"message":{"message_id":17,"chat":{"id":123},"text":"hi",` +
+			`"message_thread_id":3,"is_topic_message":true}},` +
+			`{"update_id":6,"message":{"message_id":18,"chat":{"id":123},"text":"reply","message_thread_id":17}},` +
+			`{"update_id":7,"message_reaction":{"chat":{"id":123},"message_id":17,` +
+			`"user":{"id":1,"username":"testuser"},"new_reaction":[{"type":"emoji","emoji":"👍"}]}}]`
+	case "getFile":
+		result = `{"file_id":"test-file-id","file_path":"documents/file_1.pdf"}`
+	case "createForumTopic":
+		result = `{"message_thread_id":7,"name":"Alice","icon_color":7322096}`
+	default:
+		result = `{"message_id":42,"chat":{"id":123}}`
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewBufferString(`{"ok":true,"result":` + result + `}`)),
+		Request:    req,
+	}, nil
+}
+
+func (r *apiRecorder) lastRequest(t *testing.T, method string) *http.Request {
+	t.Helper()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	require.NotEmpty(t, r.requests[method], "no %s requests", method)
+
+	return r.requests[method][len(r.requests[method])-1]
+}
+
+func newTestAPI(t *testing.T) (*tgbotapi.BotAPI, *apiRecorder) {
+	t.Helper()
+
+	recorder := &apiRecorder{requests: make(map[string][]*http.Request)}
+	api, err := tgbotapi.NewBotAPIWithClient("test-token", &http.Client{Transport: recorder})
+	require.NoError(t, err)
+
+	return api, recorder
+}
+
+func newTestClient(t *testing.T) (*telegram.Client, *apiRecorder) {
+	t.Helper()
+
+	api, recorder := newTestAPI(t)
+
+	return telegram.NewClient(api), recorder
+}
+
+func TestClient(t *testing.T) {
+	testButtons := [][]domain.TelegramButton{{
+		{Text: "Retry", CallbackData: "retry:1"},
+	}}
+
+	t.Run("send text", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		messageID, err := client.SendText(&domain.TelegramTextMessage{
+			ChatID:  123,
+			Text:    "hello, world!",
+			Buttons: testButtons,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 42, messageID)
+
+		req := recorder.lastRequest(t, "sendMessage")
+		assert.Equal(t, "123", req.Form.Get("chat_id"))
+		assert.Equal(t, "hello, world!", req.Form.Get("text"))
+		assert.Contains(t, req.Form.Get("reply_markup"), `"callback_data":"retry:1"`)
+	})
+
+	t.Run("send text without buttons", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		_, err := client.SendText(&domain.TelegramTextMessage{
+			ChatID: 123,
+			Text:   "hello, world!",
+		})
+		require.NoError(t, err)
+
+		req := recorder.lastRequest(t, "sendMessage")
+		assert.Empty(t, req.Form.Get("reply_markup"))
+	})
+
+	t.Run("send text silently", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		_, err := client.SendText(&domain.TelegramTextMessage{
+			ChatID:              123,
+			Text:                "hello, world!",
+			DisableNotification: true,
+		})
+		require.NoError(t, err)
+
+		req := recorder.lastRequest(t, "sendMessage")
+		assert.Equal(t, "true", req.Form.Get("disable_notification"))
+	})
+
+	t.Run("send text as reply", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		_, err := client.SendText(&domain.TelegramTextMessage{
+			ChatID:           123,
+			Text:             "❤️ from Alice",
+			ReplyToMessageID: 17,
+		})
+		require.NoError(t, err)
+
+		req := recorder.lastRequest(t, "sendMessage")
+		assert.Equal(t, "17", req.Form.Get("reply_to_message_id"))
+	})
+
+	t.Run("send text to topic", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		messageID, err := client.SendText(&domain.TelegramTextMessage{
+			ChatID:   123,
+			Text:     "hello, world!",
+			ThreadID: 7,
+			Buttons:  testButtons,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 42, messageID)
+
+		req := recorder.lastRequest(t, "sendMessage")
+		assert.Equal(t, "123", req.Form.Get("chat_id"))
+		assert.Equal(t, "7", req.Form.Get("message_thread_id"))
+		assert.Equal(t, "hello, world!", req.Form.Get("text"))
+		assert.Contains(t, req.Form.Get("reply_markup"), `"callback_data":"retry:1"`)
+	})
+
+	t.Run("create topic", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		threadID, err := client.CreateTopic(123, "Alice")
+		require.NoError(t, err)
+		assert.Equal(t, 7, threadID)
+
+		req := recorder.lastRequest(t, "createForumTopic")
+		assert.Equal(t, "123", req.Form.Get("chat_id"))
+		assert.Equal(t, "Alice", req.Form.Get("name"))
+	})
+
+	t.Run("pin message", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		require.NoError(t, client.PinMessage(123, 42))
+
+		req := recorder.lastRequest(t, "pinChatMessage")
+		assert.Equal(t, "123", req.Form.Get("chat_id"))
+		assert.Equal(t, "42", req.Form.Get("message_id"))
+		assert.Equal(t, "true", req.Form.Get("disable_notification"))
+	})
+
+	t.Run("unpin message", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		require.NoError(t, client.UnpinMessage(123, 42))
+
+		req := recorder.lastRequest(t, "unpinChatMessage")
+		assert.Equal(t, "123", req.Form.Get("chat_id"))
+		assert.Equal(t, "42", req.Form.Get("message_id"))
+	})
+
+	t.Run("send chat action", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		require.NoError(t, client.SendChatAction(&domain.TelegramChatAction{
+			ChatID:   123,
+			ThreadID: 7,
+			Action:   domain.TelegramChatActionTyping,
+		}))
+
+		req := recorder.lastRequest(t, "sendChatAction")
+		assert.Equal(t, "123", req.Form.Get("chat_id"))
+		assert.Equal(t, "7", req.Form.Get("message_thread_id"))
+		assert.Equal(t, "typing", req.Form.Get("action"))
+	})
+
+	t.Run("send location", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		messageID, err := client.SendLocation(&domain.TelegramLocationMessage{
+			ChatID:              123,
+			Latitude:            55.751244,
+			Longitude:           37.618423,
+			LivePeriod:          28800,
+			DisableNotification: true,
+			ReplyToMessageID:    17,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 42, messageID)
+
+		req := recorder.lastRequest(t, "sendLocation")
+		assert.Equal(t, "123", req.Form.Get("chat_id"))
+		assert.Equal(t, "55.751244", req.Form.Get("latitude"))
+		assert.Equal(t, "37.618423", req.Form.Get("longitude"))
+		assert.Equal(t, "28800", req.Form.Get("live_period"))
+		assert.Equal(t, "true", req.Form.Get("disable_notification"))
+		assert.Equal(t, "17", req.Form.Get("reply_to_message_id"))
+	})
+
+	t.Run("send venue", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		_, err := client.SendLocation(&domain.TelegramLocationMessage{
+			ChatID:    123,
+			Latitude:  55.751244,
+			Longitude: 37.618423,
+			Title:     "Cafe",
+			Address:   "1 Main St",
+		})
+		require.NoError(t, err)
+
+		req := recorder.lastRequest(t, "sendVenue")
+		assert.Equal(t, "55.751244", req.Form.Get("latitude"))
+		assert.Equal(t, "Cafe", req.Form.Get("title"))
+		assert.Equal(t, "1 Main St", req.Form.Get("address"))
+	})
+
+	t.Run("edit location", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		require.NoError(t, client.EditLocation(&domain.TelegramEditLocationMessage{
+			ChatID:    123,
+			MessageID: 42,
+			Latitude:  55.75,
+			Longitude: 37.62,
+		}))
+
+		req := recorder.lastRequest(t, "editMessageLiveLocation")
+		assert.Equal(t, "123", req.Form.Get("chat_id"))
+		assert.Equal(t, "42", req.Form.Get("message_id"))
+		assert.Equal(t, "55.75", req.Form.Get("latitude"))
+		assert.Equal(t, "37.62", req.Form.Get("longitude"))
+	})
+
+	t.Run("stop location", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		require.NoError(t, client.StopLocation(123, 42))
+
+		req := recorder.lastRequest(t, "stopMessageLiveLocation")
+		assert.Equal(t, "123", req.Form.Get("chat_id"))
+		assert.Equal(t, "42", req.Form.Get("message_id"))
+	})
+
+	t.Run("send contact", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		card := "BEGIN:VCARD\nVERSION:3.0\nFN:Bob Smith\nTEL:+1 555-123-4567\nEND:VCARD"
+		messageID, err := client.SendContact(&domain.TelegramContactMessage{
+			ChatID:              123,
+			PhoneNumber:         "+1 555-123-4567",
+			FirstName:           "Bob",
+			LastName:            "Smith",
+			Vcard:               card,
+			DisableNotification: true,
+			ReplyToMessageID:    17,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 42, messageID)
+
+		req := recorder.lastRequest(t, "sendContact")
+		assert.Equal(t, "123", req.Form.Get("chat_id"))
+		assert.Equal(t, "+1 555-123-4567", req.Form.Get("phone_number"))
+		assert.Equal(t, "Bob", req.Form.Get("first_name"))
+		assert.Equal(t, "Smith", req.Form.Get("last_name"))
+		assert.Equal(t, card, req.Form.Get("vcard"))
+		assert.Equal(t, "true", req.Form.Get("disable_notification"))
+		assert.Equal(t, "17", req.Form.Get("reply_to_message_id"))
+	})
+
+	t.Run("send contact with large vcard", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		_, err := client.SendContact(&domain.TelegramContactMessage{
+			ChatID:      123,
+			PhoneNumber: "+1 555-123-4567",
+			FirstName:   "Bob",
+			Vcard:       "BEGIN:VCARD\nNOTE:" + strings.Repeat("x", 2048) + "\nEND:VCARD",
+		})
+		require.NoError(t, err)
+
+		req := recorder.lastRequest(t, "sendContact")
+		assert.Equal(t, "Bob", req.Form.Get("first_name"))
+		_, ok := req.Form["vcard"]
+		assert.False(t, ok)
+	})
+
+	t.Run("download file", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		data, err := client.DownloadFile("test-file-id")
+		require.NoError(t, err)
+		assert.Equal(t, "test content of file_1.pdf", string(data))
+
+		req := recorder.lastRequest(t, "getFile")
+		assert.Equal(t, "test-file-id", req.Form.Get("file_id"))
+		req = recorder.lastRequest(t, "file_1.pdf")
+		assert.Equal(t, "/file/bottest-token/documents/file_1.pdf", req.URL.Path)
+	})
+
+	t.Run("set reaction", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		require.NoError(t, client.SetReaction(&domain.TelegramReaction{
+			ChatID:    123,
+			MessageID: 42,
+			Emoji:     "👍",
+		}))
+
+		req := recorder.lastRequest(t, "setMessageReaction")
+		assert.Equal(t, "123", req.Form.Get("chat_id"))
+		assert.Equal(t, "42", req.Form.Get("message_id"))
+		assert.JSONEq(t, `[{"type":"emoji","emoji":"👍"}]`, req.Form.Get("reaction"))
+
+		// The reaction is removed with an empty list
+		require.NoError(t, client.SetReaction(&domain.TelegramReaction{ChatID: 123, MessageID: 42}))
+		req = recorder.lastRequest(t, "setMessageReaction")
+		assert.Equal(t, `[]`, req.Form.Get("reaction"))
+	})
+
+	t.Run("send photo", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		messageID, err := client.SendPhoto(&domain.TelegramPhotoMessage{
+			ChatID:  123,
+			Photo:   domain.TelegramFile{Name: "photo.png", Bytes: []byte("test-photo")},
+			Caption: "test caption",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 42, messageID)
+
+		req := recorder.lastRequest(t, "sendPhoto")
+		assert.Equal(t, "123", req.MultipartForm.Value["chat_id"][0])
+		assert.Equal(t, "test caption", req.MultipartForm.Value["caption"][0])
+		require.Len(t, req.MultipartForm.File["photo"], 1)
+		assert.Equal(t, "photo.png", req.MultipartForm.File["photo"][0].Filename)
+	})
+
+	t.Run("send document", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		_, err := client.SendDocument(&domain.TelegramDocumentMessage{
+			ChatID:   123,
+			Document: domain.TelegramFile{Name: "report.pdf", Bytes: []byte("test-document")},
+		})
+		require.NoError(t, err)
+
+		req := recorder.lastRequest(t, "sendDocument")
+		require.Len(t, req.MultipartForm.File["document"], 1)
+		assert.Equal(t, "report.pdf", req.MultipartForm.File["document"][0].Filename)
+	})
+
+	t.Run("send document reply", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		_, err := client.SendDocument(&domain.TelegramDocumentMessage{
+			ChatID:              123,
+			Document:            domain.TelegramFile{Name: "report.pdf", Bytes: []byte("test-document")},
+			MimeType:            "application/pdf",
+			Caption:             "Q1 numbers",
+			DisableNotification: true,
+			ReplyToMessageID:    17,
+		})
+		require.NoError(t, err)
+
+		req := recorder.lastRequest(t, "sendDocument")
+		assert.Equal(t, "application/pdf", req.FormValue("mime_type"))
+		assert.Equal(t, "Q1 numbers", req.FormValue("caption"))
+		assert.Equal(t, "true", req.FormValue("disable_notification"))
+		assert.Equal(t, "17", req.FormValue("reply_to_message_id"))
+	})
+
+	t.Run("edit message", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		err := client.EditMessage(&domain.TelegramEditMessage{
+			ChatID:    123,
+			MessageID: 42,
+			Text:      "edited",
+		})
+		require.NoError(t, err)
+
+		req := recorder.lastRequest(t, "editMessageText")
+		assert.Equal(t, "42", req.Form.Get("message_id"))
+		assert.Equal(t, "edited", req.Form.Get("text"))
+	})
+
+	t.Run("edit photo", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		err := client.EditPhoto(&domain.TelegramEditPhotoMessage{
+			ChatID:    123,
+			MessageID: 42,
+			Photo:     domain.TelegramFile{Name: "photo.png", Bytes: []byte("test-photo")},
+			Caption:   "new caption",
+			Buttons:   testButtons,
+		})
+		require.NoError(t, err)
+
+		req := recorder.lastRequest(t, "editMessageMedia")
+		assert.Equal(t, "42", req.MultipartForm.Value["message_id"][0])
+		assert.JSONEq(t, `{"type":"photo","media":"attach://photo","caption":"new caption"}`,
+			req.MultipartForm.Value["media"][0])
+		assert.Contains(t, req.MultipartForm.Value["reply_markup"][0], `"callback_data":"retry:1"`)
+		require.Len(t, req.MultipartForm.File["photo"], 1)
+		assert.Equal(t, "photo.png", req.MultipartForm.File["photo"][0].Filename)
+	})
+
+	t.Run("edit caption", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		err := client.EditCaption(&domain.TelegramEditCaptionMessage{
+			ChatID:    123,
+			MessageID: 42,
+			Caption:   "new caption",
+		})
+		require.NoError(t, err)
+
+		req := recorder.lastRequest(t, "editMessageCaption")
+		assert.Equal(t, "42", req.Form.Get("message_id"))
+		assert.Equal(t, "new caption", req.Form.Get("caption"))
+	})
+
+	t.Run("answer callback", func(t *testing.T) {
+		client, recorder := newTestClient(t)
+
+		err := client.AnswerCallback(&domain.TelegramCallbackAnswer{
+			CallbackID: "test-callback-id",
+			Text:       "done",
+		})
+		require.NoError(t, err)
+
+		req := recorder.lastRequest(t, "answerCallbackQuery")
+		assert.Equal(t, "test-callback-id", req.Form.Get("callback_query_id"))
+		assert.Equal(t, "done", req.Form.Get("text"))
+	})
+}
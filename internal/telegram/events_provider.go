@@ -2,23 +2,38 @@ package telegram
 
 import (
 	"context"
+	"strings"
+	"unicode"
 
 	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/vcard"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 	"go.uber.org/zap"
 )
 
+const (
+	// photoMimeType is a mime type of the photos, telegram converts them to JPEG.
+	photoMimeType = "image/jpeg"
+
+	defaultDocumentName     = "document"
+	defaultDocumentMimeType = "application/octet-stream"
+)
+
 // EventsProvider represents telegram events provider.
 type EventsProvider struct {
 	log               *zap.Logger
 	eventsCh          chan domain.Event
-	telegramUpdatesCh tgbotapi.UpdatesChannel
+	telegramUpdatesCh <-chan Update
+	reactionsCh       <-chan MessageReaction
 }
 
 // Opts represents options to create new instance of EventsProvider.
 type Opts struct {
 	// TelegramUpdates is a channel to receive telegram updates from.
-	TelegramUpdates tgbotapi.UpdatesChannel
+	TelegramUpdates <-chan Update
+
+	// Reactions is a channel to receive reactions to telegram messages from, optional.
+	Reactions <-chan MessageReaction
 }
 
 // NewEventsProvider creates new instance of EventsProvider.
@@ -26,6 +41,7 @@ func NewEventsProvider(log *zap.Logger, opts *Opts) *EventsProvider {
 	return &EventsProvider{
 		log:               log,
 		telegramUpdatesCh: opts.TelegramUpdates,
+		reactionsCh:       opts.Reactions,
 		eventsCh:          make(chan domain.Event, 1),
 	}
 }
@@ -36,18 +52,35 @@ func (ep *EventsProvider) Run(ctx context.Context) error {
 	for {
 		select {
 		case <-ctx.Done():
-			ep.telegramUpdatesCh.Clear()
+			for len(ep.telegramUpdatesCh) != 0 {
+				<-ep.telegramUpdatesCh
+			}
 			close(ep.eventsCh)
 
 			return nil
+		case reaction := <-ep.reactionsCh:
+			ep.handleReaction(&reaction)
 		case update := <-ep.telegramUpdatesCh:
+			if update.CallbackQuery != nil {
+				ep.handleCallbackQuery(update.CallbackQuery)
+
+				continue
+			}
+
+			if update.EditedMessage != nil {
+				ep.handleEditedMessage(update.EditedMessage)
+
+				continue
+			}
+
 			if update.Message == nil { // ignore any non-Message Updates
 				continue
 			}
 
 			// TODO: detect chat deletion
 
-			switch update.Message.Text {
+			command, args := parseCommand(update.Message.Text)
+			switch command {
 			case "/start":
 				ep.eventsCh <- &domain.StartEvent{
 					ChatID:   update.Message.Chat.ID,
@@ -57,31 +90,242 @@ func (ep *EventsProvider) Run(ctx context.Context) error {
 				ep.eventsCh <- &domain.LoginEvent{
 					ChatID:   update.Message.Chat.ID,
 					FromUser: update.Message.From.UserName,
+					Account:  accountName(args),
 				}
 			case "/logout":
 				ep.eventsCh <- &domain.LogoutEvent{
 					ChatID:   update.Message.Chat.ID,
 					FromUser: update.Message.From.UserName,
+					Account:  accountName(args),
 				}
 			case "/help":
 				ep.eventsCh <- &domain.HelpEvent{
 					ChatID:   update.Message.Chat.ID,
 					FromUser: update.Message.From.UserName,
 				}
+			case "/team":
+				ep.eventsCh <- &domain.TeamEvent{
+					ChatID:   update.Message.Chat.ID,
+					FromUser: update.Message.From.UserName,
+					Args:     strings.Fields(args),
+				}
+			case "/topics":
+				ep.eventsCh <- &domain.TopicsEvent{
+					ChatID:   update.Message.Chat.ID,
+					FromUser: update.Message.From.UserName,
+					Args:     strings.Fields(args),
+				}
+			case "/route":
+				routeEvent := &domain.RouteEvent{
+					ChatID:   update.Message.Chat.ID,
+					FromUser: update.Message.From.UserName,
+					Args:     strings.Fields(args),
+				}
+				if update.Message.ReplyToMessage != nil {
+					routeEvent.RemoteJid = domain.ExtractMsgJid(update.Message.ReplyToMessage.Text)
+					routeEvent.Account = domain.ExtractMsgAccount(update.Message.ReplyToMessage.Text)
+				}
+				ep.eventsCh <- routeEvent
+			case "/chat":
+				chatEvent := &domain.ChatEvent{
+					ChatID:   update.Message.Chat.ID,
+					FromUser: update.Message.From.UserName,
+					Contact:  args,
+				}
+				if update.Message.ReplyToMessage != nil {
+					chatEvent.RemoteJid = domain.ExtractMsgJid(update.Message.ReplyToMessage.Text)
+					chatEvent.Account = domain.ExtractMsgAccount(update.Message.ReplyToMessage.Text)
+				}
+				ep.eventsCh <- chatEvent
+			case "/close":
+				ep.eventsCh <- &domain.CloseChatEvent{
+					ChatID:   update.Message.Chat.ID,
+					FromUser: update.Message.From.UserName,
+				}
+			case "/mute", "/unmute", "/settings":
+				settingsEvent := &domain.SettingsEvent{
+					ChatID:   update.Message.Chat.ID,
+					FromUser: update.Message.From.UserName,
+					Mode:     commandContactModes[command],
+					Args:     strings.Fields(args),
+				}
+				if update.Message.ReplyToMessage != nil {
+					settingsEvent.RemoteJid = domain.ExtractMsgJid(update.Message.ReplyToMessage.Text)
+					settingsEvent.Account = domain.ExtractMsgAccount(update.Message.ReplyToMessage.Text)
+				}
+				ep.eventsCh <- settingsEvent
+			case "/rules":
+				ep.eventsCh <- &domain.RulesEvent{
+					ChatID:   update.Message.Chat.ID,
+					FromUser: update.Message.From.UserName,
+					Args:     strings.Fields(args),
+				}
+			case "/digest":
+				ep.eventsCh <- &domain.DigestEvent{
+					ChatID:   update.Message.Chat.ID,
+					FromUser: update.Message.From.UserName,
+					Args:     strings.Fields(args),
+				}
+			case "/quiet":
+				ep.eventsCh <- &domain.QuietEvent{
+					ChatID:   update.Message.Chat.ID,
+					FromUser: update.Message.From.UserName,
+					Args:     strings.Fields(args),
+				}
+			case "/schedule":
+				scheduleEvent := &domain.ScheduleEvent{
+					ChatID:   update.Message.Chat.ID,
+					FromUser: update.Message.From.UserName,
+				}
+				if update.Message.ReplyToMessage != nil {
+					scheduleEvent.RemoteJid = domain.ExtractMsgJid(update.Message.ReplyToMessage.Text)
+					scheduleEvent.Account = domain.ExtractMsgAccount(update.Message.ReplyToMessage.Text)
+				}
+				if scheduleEvent.RemoteJid == "" {
+					scheduleEvent.Contact, args = cutArg(args)
+				}
+				scheduleEvent.Time, scheduleEvent.Text = cutArg(args)
+				ep.eventsCh <- scheduleEvent
+			case "/unschedule":
+				ep.eventsCh <- &domain.UnscheduleEvent{
+					ChatID:   update.Message.Chat.ID,
+					FromUser: update.Message.From.UserName,
+					ID:       args,
+				}
+			case "/away":
+				ep.eventsCh <- &domain.AwayEvent{
+					ChatID:   update.Message.Chat.ID,
+					FromUser: update.Message.From.UserName,
+					Args:     args,
+				}
+			case "/search":
+				searchEvent := &domain.SearchEvent{
+					ChatID:   update.Message.Chat.ID,
+					FromUser: update.Message.From.UserName,
+				}
+				searchEvent.Query, searchEvent.Contact = cutQuotedArg(args)
+				ep.eventsCh <- searchEvent
+			case "/export":
+				exportEvent := &domain.ExportEvent{
+					ChatID:   update.Message.Chat.ID,
+					FromUser: update.Message.From.UserName,
+				}
+				if update.Message.ReplyToMessage != nil {
+					exportEvent.Contact = domain.ExtractMsgJid(update.Message.ReplyToMessage.Text)
+				}
+				if exportEvent.Contact == "" {
+					exportEvent.Contact, args = cutQuotedArg(args)
+				}
+				exportEvent.From, args = cutArg(args)
+				exportEvent.To, _ = cutArg(args)
+				ep.eventsCh <- exportEvent
+			case "/history":
+				historyEvent := &domain.HistoryEvent{
+					ChatID:   update.Message.Chat.ID,
+					FromUser: update.Message.From.UserName,
+				}
+				if update.Message.ReplyToMessage != nil {
+					historyEvent.RemoteJid = domain.ExtractMsgJid(update.Message.ReplyToMessage.Text)
+					historyEvent.Account = domain.ExtractMsgAccount(update.Message.ReplyToMessage.Text)
+				}
+				if historyEvent.RemoteJid == "" {
+					historyEvent.Contact, args = cutQuotedArg(args)
+				}
+				historyEvent.Count, _ = cutArg(args)
+				ep.eventsCh <- historyEvent
+			case "/chats":
+				ep.eventsCh <- &domain.ChatsEvent{
+					ChatID:   update.Message.Chat.ID,
+					FromUser: update.Message.From.UserName,
+				}
+			case "/lastseen":
+				lastSeenEvent := &domain.LastSeenEvent{
+					ChatID:   update.Message.Chat.ID,
+					FromUser: update.Message.From.UserName,
+					Contact:  args,
+				}
+				if update.Message.ReplyToMessage != nil {
+					lastSeenEvent.RemoteJid = domain.ExtractMsgJid(update.Message.ReplyToMessage.Text)
+					lastSeenEvent.Account = domain.ExtractMsgAccount(update.Message.ReplyToMessage.Text)
+				}
+				ep.eventsCh <- lastSeenEvent
+			case "/delete":
+				deleteEvent := &domain.DeleteEvent{
+					ChatID:   update.Message.Chat.ID,
+					FromUser: update.Message.From.UserName,
+				}
+				if update.Message.ReplyToMessage != nil {
+					deleteEvent.MessageID = update.Message.ReplyToMessage.MessageID
+				}
+				ep.eventsCh <- deleteEvent
+			case "/assign", "/unassign":
+				assignEvent := &domain.AssignEvent{
+					ChatID:   update.Message.Chat.ID,
+					FromUser: update.Message.From.UserName,
+				}
+				if command == "/assign" {
+					// Members claim the conversation unless it's assigned to someone else explicitly
+					assignEvent.Assignee = update.Message.From.UserName
+					if args != "" {
+						assignEvent.Assignee = args
+					}
+				}
+				if update.Message.ReplyToMessage != nil {
+					assignEvent.RemoteJid = domain.ExtractMsgJid(update.Message.ReplyToMessage.Text)
+					assignEvent.Account = domain.ExtractMsgAccount(update.Message.ReplyToMessage.Text)
+				}
+				ep.eventsCh <- assignEvent
 			default:
+				location := messageLocation(update.Message)
+				contactCard := messageContactCard(update.Message)
+				media := messageMedia(update.Message)
+				unsupportedMedia := messageUnsupportedMedia(update.Message)
+				// Captions of the media are sent as their text
+				text := update.Message.Text
+				if media != nil {
+					text = update.Message.Caption
+				}
 				if update.Message.ReplyToMessage != nil {
-					// Extract jid from the message that is replied to
-					remoteJid := domain.ExtractMsgJid(update.Message.ReplyToMessage.Text)
-					if remoteJid == "" {
-						continue
+					replyEvent := &domain.ReplyEvent{
+						ChatID:           update.Message.Chat.ID,
+						FromUser:         update.Message.From.UserName,
+						FromName:         fullName(update.Message.From),
+						Reply:            text,
+						MessageID:        update.Message.MessageID,
+						Location:         location,
+						ContactCard:      contactCard,
+						Media:            media,
+						UnsupportedMedia: unsupportedMedia,
+					}
+
+					// Extract jid from the message that is replied to,
+					// it's sent from the account the original message came to
+					replyEvent.RemoteJid = domain.ExtractMsgJid(update.Message.ReplyToMessage.Text)
+					replyEvent.Account = domain.ExtractMsgAccount(update.Message.ReplyToMessage.Text)
+
+					// Replies in a forum topic to messages that aren't bridged, e.g. the service
+					// message that has created the topic, belong to the conversation of the topic
+					if replyEvent.RemoteJid == "" {
+						replyEvent.Account = ""
+						replyEvent.ThreadID = update.MessageThreadID
 					}
 
-					// Send reply event
+					ep.eventsCh <- replyEvent
+				} else if command == "" && (text != "" || location != nil || contactCard != nil ||
+					media != nil || unsupportedMedia != "") {
+					// Plain messages are sent to the conversation of the topic or
+					// to the active conversation of the chat
 					ep.eventsCh <- &domain.ReplyEvent{
-						ChatID:    update.Message.Chat.ID,
-						FromUser:  update.Message.From.UserName,
-						Reply:     update.Message.Text,
-						RemoteJid: remoteJid,
+						ChatID:           update.Message.Chat.ID,
+						ThreadID:         update.MessageThreadID,
+						FromUser:         update.Message.From.UserName,
+						FromName:         fullName(update.Message.From),
+						Reply:            text,
+						MessageID:        update.Message.MessageID,
+						Location:         location,
+						ContactCard:      contactCard,
+						Media:            media,
+						UnsupportedMedia: unsupportedMedia,
 					}
 				}
 			}
@@ -89,6 +333,281 @@ func (ep *EventsProvider) Run(ctx context.Context) error {
 	}
 }
 
+// messageLocation returns the location or the venue shared by the message,
+// nil is returned if the message doesn't share a location.
+func messageLocation(message *tgbotapi.Message) *domain.WhatsappLocation {
+	switch {
+	case message.Venue != nil:
+		return &domain.WhatsappLocation{
+			Latitude:  message.Venue.Location.Latitude,
+			Longitude: message.Venue.Location.Longitude,
+			Name:      message.Venue.Title,
+			Address:   message.Venue.Address,
+		}
+	case message.Location != nil:
+		return &domain.WhatsappLocation{
+			Latitude:  message.Location.Latitude,
+			Longitude: message.Location.Longitude,
+		}
+	default:
+		return nil
+	}
+}
+
+// messageContactCard returns the contact shared by the message as a contact card,
+// nil is returned if the message doesn't share a contact.
+func messageContactCard(message *tgbotapi.Message) *domain.WhatsappContactCard {
+	if message.Contact == nil {
+		return nil
+	}
+
+	card := vcard.Card{
+		FirstName: message.Contact.FirstName,
+		LastName:  message.Contact.LastName,
+		Phones: []vcard.Phone{{
+			Number:     message.Contact.PhoneNumber,
+			WhatsappID: vcard.WhatsappID(message.Contact.PhoneNumber),
+		}},
+	}
+
+	return &domain.WhatsappContactCard{
+		DisplayName: card.Name(),
+		Vcard:       vcard.Encode(card),
+	}
+}
+
+// messageMedia returns the photo or the document sent by the message,
+// nil is returned if the message has neither of them.
+func messageMedia(message *tgbotapi.Message) *domain.WhatsappMedia {
+	switch {
+	case message.Photo != nil && len(*message.Photo) != 0:
+		// Telegram sends several sizes of the photo, the largest one is the last
+		photos := *message.Photo
+		photo := photos[len(photos)-1]
+
+		return &domain.WhatsappMedia{
+			Kind:           domain.WhatsappMediaImage,
+			TelegramFileID: photo.FileID,
+			MimeType:       photoMimeType,
+			Caption:        message.Caption,
+			Size:           int64(photo.FileSize),
+		}
+	case message.Document != nil && message.Animation == nil:
+		media := &domain.WhatsappMedia{
+			Kind:           domain.WhatsappMediaDocument,
+			TelegramFileID: message.Document.FileID,
+			FileName:       message.Document.FileName,
+			MimeType:       message.Document.MimeType,
+			Caption:        message.Caption,
+			Size:           int64(message.Document.FileSize),
+		}
+		if media.FileName == "" {
+			media.FileName = defaultDocumentName
+		}
+		if media.MimeType == "" {
+			media.MimeType = defaultDocumentMimeType
+		}
+
+		return media
+	default:
+		return nil
+	}
+}
+
+// messageUnsupportedMedia returns a name of the media sent by the message that
+// can't be sent to whatsapp, it's empty if the message has no such media.
+func messageUnsupportedMedia(message *tgbotapi.Message) string {
+	switch {
+	case message.Animation != nil:
+		return "GIFs"
+	case message.Sticker != nil:
+		return "Stickers"
+	case message.Video != nil, message.VideoNote != nil:
+		return "Videos"
+	case message.Voice != nil:
+		return "Voice messages"
+	case message.Audio != nil:
+		return "Audio files"
+	default:
+		return ""
+	}
+}
+
+// handleEditedMessage sends an edit event for the edited text message,
+// the handler finds out whether it has been sent to whatsapp.
+func (ep *EventsProvider) handleEditedMessage(message *tgbotapi.Message) {
+	if command, _ := parseCommand(message.Text); command != "" || message.Text == "" {
+		return
+	}
+
+	ep.eventsCh <- &domain.EditEvent{
+		ChatID:    message.Chat.ID,
+		FromUser:  message.From.UserName,
+		FromName:  fullName(message.From),
+		MessageID: message.MessageID,
+		Text:      message.Text,
+	}
+}
+
+func (ep *EventsProvider) handleReaction(reaction *MessageReaction) {
+	if reaction.Chat == nil || reaction.User == nil { // ignore anonymous reactions
+		return
+	}
+
+	ep.eventsCh <- &domain.ReactEvent{
+		ChatID:    reaction.Chat.ID,
+		FromUser:  reaction.User.UserName,
+		MessageID: reaction.MessageID,
+		Emoji:     reaction.Emoji(),
+	}
+}
+
+func (ep *EventsProvider) handleCallbackQuery(query *tgbotapi.CallbackQuery) {
+	if query.Message == nil { // ignore callbacks from inline messages
+		return
+	}
+
+	action, arg := domain.ParseCallbackData(query.Data)
+	switch action {
+	case domain.RetryCallbackAction:
+		ep.eventsCh <- &domain.RetryEvent{
+			ChatID:     query.Message.Chat.ID,
+			FromUser:   query.From.UserName,
+			CallbackID: query.ID,
+			MessageID:  arg,
+		}
+	case domain.CancelLoginCallbackAction:
+		ep.eventsCh <- &domain.CancelLoginEvent{
+			ChatID:     query.Message.Chat.ID,
+			FromUser:   query.From.UserName,
+			CallbackID: query.ID,
+			Account:    accountName(arg),
+		}
+	case domain.ChatCallbackAction:
+		account, remoteJid := domain.ParseConversationRef(arg)
+		ep.eventsCh <- &domain.ChatEvent{
+			ChatID:     query.Message.Chat.ID,
+			FromUser:   query.From.UserName,
+			CallbackID: query.ID,
+			RemoteJid:  remoteJid,
+			Account:    account,
+		}
+	case domain.CloseChatCallbackAction:
+		ep.eventsCh <- &domain.CloseChatEvent{
+			ChatID:     query.Message.Chat.ID,
+			FromUser:   query.From.UserName,
+			CallbackID: query.ID,
+		}
+	case domain.SettingsCallbackAction, domain.ContactModeCallbackAction:
+		var mode string
+		if action == domain.ContactModeCallbackAction {
+			mode, arg = domain.ParseCallbackData(arg)
+		}
+		account, remoteJid := domain.ParseConversationRef(arg)
+		ep.eventsCh <- &domain.SettingsEvent{
+			ChatID:     query.Message.Chat.ID,
+			FromUser:   query.From.UserName,
+			CallbackID: query.ID,
+			MessageID:  query.Message.MessageID,
+			Mode:       domain.ContactMode(mode),
+			RemoteJid:  remoteJid,
+			Account:    account,
+		}
+	case domain.UnscheduleCallbackAction:
+		ep.eventsCh <- &domain.UnscheduleEvent{
+			ChatID:     query.Message.Chat.ID,
+			FromUser:   query.From.UserName,
+			CallbackID: query.ID,
+			MessageID:  query.Message.MessageID,
+			ID:         arg,
+		}
+	case domain.ChatsCallbackAction:
+		chatsAction, ref := domain.ParseCallbackData(arg)
+		account, remoteJid := domain.ParseConversationRef(ref)
+		ep.eventsCh <- &domain.ChatsEvent{
+			ChatID:     query.Message.Chat.ID,
+			FromUser:   query.From.UserName,
+			CallbackID: query.ID,
+			MessageID:  query.Message.MessageID,
+			Action:     domain.ChatsAction(chatsAction),
+			RemoteJid:  remoteJid,
+			Account:    account,
+		}
+	default:
+		ep.log.Debug("got unknown callback query", zap.String("data", query.Data))
+	}
+}
+
+// commandContactModes represents the modes of the contact set by the commands,
+// the settings command only shows them.
+var commandContactModes = map[string]domain.ContactMode{
+	"/mute":   domain.ContactModeMute,
+	"/unmute": domain.ContactModeNormal,
+}
+
+// parseCommand returns a bot command and its arguments from the message text,
+// the command is empty if the text is not a command.
+func parseCommand(text string) (command, args string) {
+	if !strings.HasPrefix(text, "/") {
+		return "", ""
+	}
+
+	fields := strings.SplitN(text, " ", 2)
+	command = fields[0]
+
+	// Commands may be addressed to the bot explicitly, e.g. /login@twbridge_bot
+	if mentionIdx := strings.Index(command, "@"); mentionIdx != -1 {
+		command = command[:mentionIdx]
+	}
+	if len(fields) > 1 {
+		args = strings.TrimSpace(fields[1])
+	}
+
+	return command, args
+}
+
+// cutArg returns the first argument of the command and the rest of the arguments,
+// the rest is kept as is, so line breaks of the text are preserved.
+func cutArg(args string) (arg, rest string) {
+	args = strings.TrimSpace(args)
+	sepIdx := strings.IndexFunc(args, unicode.IsSpace)
+	if sepIdx == -1 {
+		return args, ""
+	}
+
+	return args[:sepIdx], strings.TrimSpace(args[sepIdx:])
+}
+
+// cutQuotedArg returns the first argument and the rest of the arguments,
+// the first argument may be put in double quotes to keep spaces in it.
+func cutQuotedArg(args string) (arg, rest string) {
+	args = strings.TrimSpace(args)
+	if !strings.HasPrefix(args, `"`) {
+		return cutArg(args)
+	}
+
+	endIdx := strings.Index(args[1:], `"`)
+	if endIdx == -1 {
+		return strings.TrimSpace(args[1:]), ""
+	}
+
+	return strings.TrimSpace(args[1 : endIdx+1]), strings.TrimSpace(args[endIdx+2:])
+}
+
+// accountName returns a name of the whatsapp account from the command arguments.
+func accountName(args string) string {
+	if args == "" {
+		return domain.DefaultWhatsappAccount
+	}
+
+	return args
+}
+
+// fullName returns a full name of the telegram user.
+func fullName(user *tgbotapi.User) string {
+	return strings.TrimSpace(user.FirstName + " " + user.LastName)
+}
+
 // EventsStream method returns a stream of domain.Event.
 func (ep *EventsProvider) EventsStream() chan domain.Event {
 	return ep.eventsCh
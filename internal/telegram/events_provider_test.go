@@ -14,8 +14,12 @@ import (
 )
 
 func TestEventsProvider(t *testing.T) {
-	tgUpdatesCh := make(chan tgbotapi.Update)
-	eventsProvider := telegram.NewEventsProvider(zap.NewNop(), &telegram.Opts{TelegramUpdates: tgUpdatesCh})
+	tgUpdatesCh := make(chan telegram.Update)
+	reactionsCh := make(chan telegram.MessageReaction)
+	eventsProvider := telegram.NewEventsProvider(zap.NewNop(), &telegram.Opts{
+		TelegramUpdates: tgUpdatesCh,
+		Reactions:       reactionsCh,
+	})
 
 	rootCtx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -52,7 +56,7 @@ func TestEventsProvider(t *testing.T) {
 				Text: "/start",
 			},
 		}
-		tgUpdatesCh <- testUpdate
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
 
 		// Wait for the event to be processed
 		wg.Wait()
@@ -90,7 +94,46 @@ func TestEventsProvider(t *testing.T) {
 				Text: "/login",
 			},
 		}
-		tgUpdatesCh <- testUpdate
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.LoginEventType, gotEvent.Type())
+		gotLoginEvent := gotEvent.(*domain.LoginEvent)
+
+		assert.Equal(t, testUpdate.Message.Chat.ID, gotLoginEvent.ChatID)
+		assert.Equal(t, testUpdate.Message.From.UserName, gotLoginEvent.FromUser)
+		assert.Equal(t, domain.DefaultWhatsappAccount, gotLoginEvent.Account)
+	})
+
+	t.Run("login event with account", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram update message
+		testUpdate := tgbotapi.Update{
+			UpdateID: 1,
+			Message: &tgbotapi.Message{
+				MessageID: 1,
+				From: &tgbotapi.User{
+					FirstName: "test name",
+					LastName:  "test surname",
+					UserName:  "testuser",
+				},
+				Chat: &tgbotapi.Chat{
+					ID: 42,
+				},
+				Text: "/login@test_bot work",
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
 
 		// Wait for the event to be processed
 		wg.Wait()
@@ -100,6 +143,7 @@ func TestEventsProvider(t *testing.T) {
 
 		assert.Equal(t, testUpdate.Message.Chat.ID, gotLoginEvent.ChatID)
 		assert.Equal(t, testUpdate.Message.From.UserName, gotLoginEvent.FromUser)
+		assert.Equal(t, "work", gotLoginEvent.Account)
 	})
 
 	t.Run("logout event", func(t *testing.T) {
@@ -128,7 +172,7 @@ func TestEventsProvider(t *testing.T) {
 				Text: "/logout",
 			},
 		}
-		tgUpdatesCh <- testUpdate
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
 
 		// Wait for the event to be processed
 		wg.Wait()
@@ -166,7 +210,7 @@ func TestEventsProvider(t *testing.T) {
 				Text: "/help",
 			},
 		}
-		tgUpdatesCh <- testUpdate
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
 
 		// Wait for the event to be processed
 		wg.Wait()
@@ -184,7 +228,7 @@ func TestEventsProvider(t *testing.T) {
 			UpdateID: 1,
 			Message:  nil,
 		}
-		tgUpdatesCh <- testUpdate
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
 
 		eventsCh := eventsProvider.EventsStream()
 		assert.Len(t, eventsCh, 0)
@@ -219,11 +263,11 @@ func TestEventsProvider(t *testing.T) {
 					Chat: &tgbotapi.Chat{
 						ID: 42,
 					},
-					Text: "From: Username Surename [jid: example@mail.com]\n==========\nMessage: Hello, world!",
+					Text: "From: Username Surename [jid: example@mail.com] [account: work]\n==========\nMessage: Hello, world!",
 				},
 			},
 		}
-		tgUpdatesCh <- testUpdate
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
 
 		// Wait for the event to be processed
 		wg.Wait()
@@ -234,6 +278,1574 @@ func TestEventsProvider(t *testing.T) {
 		assert.Equal(t, testUpdate.Message.Chat.ID, gotReplyEvent.ChatID)
 		assert.Equal(t, testUpdate.Message.From.UserName, gotReplyEvent.FromUser)
 		assert.Equal(t, "example@mail.com", gotReplyEvent.RemoteJid)
+		assert.Equal(t, "work", gotReplyEvent.Account)
+		assert.Equal(t, "test name test surname", gotReplyEvent.FromName)
 		assert.Equal(t, testUpdate.Message.Text, gotReplyEvent.Reply)
+		assert.Equal(t, testUpdate.Message.MessageID, gotReplyEvent.MessageID)
+	})
+
+	t.Run("location reply event", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(2)
+
+		var gotEvents []domain.Event
+		go func() {
+			for i := 0; i < 2; i++ {
+				gotEvents = append(gotEvents, <-eventsProvider.EventsStream())
+				wg.Done()
+			}
+		}()
+
+		// Emulate telegram update messages, the venue replies to a message,
+		// the location is sent to the active conversation
+		tgUpdatesCh <- telegram.Update{Update: tgbotapi.Update{
+			UpdateID: 33,
+			Message: &tgbotapi.Message{
+				MessageID: 33,
+				From:      &tgbotapi.User{UserName: "testuser"},
+				Chat:      &tgbotapi.Chat{ID: 42},
+				Venue: &tgbotapi.Venue{
+					Location: tgbotapi.Location{Latitude: 55.751244, Longitude: 37.618423},
+					Title:    "Cafe",
+					Address:  "1 Main St",
+				},
+				ReplyToMessage: &tgbotapi.Message{
+					MessageID: 1,
+					Chat:      &tgbotapi.Chat{ID: 42},
+					Text:      "From: Alice [jid: alice@s.whatsapp.net] \n==========\nMessage: where?",
+				},
+			},
+		}}
+		tgUpdatesCh <- telegram.Update{Update: tgbotapi.Update{
+			UpdateID: 34,
+			Message: &tgbotapi.Message{
+				MessageID: 34,
+				From:      &tgbotapi.User{UserName: "testuser"},
+				Chat:      &tgbotapi.Chat{ID: 42},
+				Location:  &tgbotapi.Location{Latitude: 55.75, Longitude: 37.62},
+			},
+		}}
+
+		// Wait for the events to be processed
+		wg.Wait()
+
+		assert.Equal(t, []domain.Event{
+			&domain.ReplyEvent{
+				ChatID:    42,
+				FromUser:  "testuser",
+				RemoteJid: "alice@s.whatsapp.net",
+				Account:   domain.DefaultWhatsappAccount,
+				MessageID: 33,
+				Location: &domain.WhatsappLocation{
+					Latitude:  55.751244,
+					Longitude: 37.618423,
+					Name:      "Cafe",
+					Address:   "1 Main St",
+				},
+			},
+			&domain.ReplyEvent{
+				ChatID:    42,
+				FromUser:  "testuser",
+				MessageID: 34,
+				Location:  &domain.WhatsappLocation{Latitude: 55.75, Longitude: 37.62},
+			},
+		}, gotEvents)
+	})
+
+	t.Run("media reply events", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(4)
+
+		var gotEvents []domain.Event
+		go func() {
+			for i := 0; i < 4; i++ {
+				gotEvents = append(gotEvents, <-eventsProvider.EventsStream())
+				wg.Done()
+			}
+		}()
+
+		// Emulate telegram update messages, the photo is sent to the active conversation,
+		// the document replies to a message, GIFs and stickers can't be sent
+		photos := []tgbotapi.PhotoSize{
+			{FileID: "small-photo-id", Width: 90, FileSize: 1024},
+			{FileID: "large-photo-id", Width: 1280, FileSize: 102400},
+		}
+		tgUpdatesCh <- telegram.Update{Update: tgbotapi.Update{
+			UpdateID: 36,
+			Message: &tgbotapi.Message{
+				MessageID: 36,
+				From:      &tgbotapi.User{UserName: "testuser"},
+				Chat:      &tgbotapi.Chat{ID: 42},
+				Photo:     &photos,
+				Caption:   "look",
+			},
+		}}
+		tgUpdatesCh <- telegram.Update{Update: tgbotapi.Update{
+			UpdateID: 37,
+			Message: &tgbotapi.Message{
+				MessageID: 37,
+				From:      &tgbotapi.User{UserName: "testuser"},
+				Chat:      &tgbotapi.Chat{ID: 42},
+				Document:  &tgbotapi.Document{FileID: "document-id", FileSize: 2048},
+				ReplyToMessage: &tgbotapi.Message{
+					MessageID: 1,
+					Chat:      &tgbotapi.Chat{ID: 42},
+					Text:      "From: Alice [jid: alice@s.whatsapp.net] \n==========\nMessage: the report?",
+				},
+			},
+		}}
+		tgUpdatesCh <- telegram.Update{Update: tgbotapi.Update{
+			UpdateID: 38,
+			Message: &tgbotapi.Message{
+				MessageID: 38,
+				From:      &tgbotapi.User{UserName: "testuser"},
+				Chat:      &tgbotapi.Chat{ID: 42},
+				Animation: &tgbotapi.ChatAnimation{FileID: "animation-id"},
+				Document:  &tgbotapi.Document{FileID: "animation-id"},
+			},
+		}}
+		tgUpdatesCh <- telegram.Update{Update: tgbotapi.Update{
+			UpdateID: 39,
+			Message: &tgbotapi.Message{
+				MessageID: 39,
+				From:      &tgbotapi.User{UserName: "testuser"},
+				Chat:      &tgbotapi.Chat{ID: 42},
+				Sticker:   &tgbotapi.Sticker{FileID: "sticker-id"},
+			},
+		}}
+
+		// Wait for the events to be processed
+		wg.Wait()
+
+		assert.Equal(t, []domain.Event{
+			&domain.ReplyEvent{
+				ChatID:    42,
+				FromUser:  "testuser",
+				Reply:     "look",
+				MessageID: 36,
+				Media: &domain.WhatsappMedia{
+					Kind:           domain.WhatsappMediaImage,
+					TelegramFileID: "large-photo-id",
+					MimeType:       "image/jpeg",
+					Caption:        "look",
+					Size:           102400,
+				},
+			},
+			&domain.ReplyEvent{
+				ChatID:    42,
+				FromUser:  "testuser",
+				RemoteJid: "alice@s.whatsapp.net",
+				Account:   domain.DefaultWhatsappAccount,
+				MessageID: 37,
+				Media: &domain.WhatsappMedia{
+					Kind:           domain.WhatsappMediaDocument,
+					TelegramFileID: "document-id",
+					FileName:       "document",
+					MimeType:       "application/octet-stream",
+					Size:           2048,
+				},
+			},
+			&domain.ReplyEvent{
+				ChatID:           42,
+				FromUser:         "testuser",
+				MessageID:        38,
+				UnsupportedMedia: "GIFs",
+			},
+			&domain.ReplyEvent{
+				ChatID:           42,
+				FromUser:         "testuser",
+				MessageID:        39,
+				UnsupportedMedia: "Stickers",
+			},
+		}, gotEvents)
+	})
+
+	t.Run("contact reply event", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			gotEvent = <-eventsProvider.EventsStream()
+			wg.Done()
+		}()
+
+		// Emulate telegram update message
+		tgUpdatesCh <- telegram.Update{Update: tgbotapi.Update{
+			UpdateID: 35,
+			Message: &tgbotapi.Message{
+				MessageID: 35,
+				From:      &tgbotapi.User{UserName: "testuser"},
+				Chat:      &tgbotapi.Chat{ID: 42},
+				Contact: &tgbotapi.Contact{
+					PhoneNumber: "+1 555-123-4567",
+					FirstName:   "Bob",
+					LastName:    "Smith",
+				},
+				ReplyToMessage: &tgbotapi.Message{
+					MessageID: 1,
+					Chat:      &tgbotapi.Chat{ID: 42},
+					Text:      "From: Alice [jid: alice@s.whatsapp.net] \n==========\nMessage: number?",
+				},
+			},
+		}}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, &domain.ReplyEvent{
+			ChatID:    42,
+			FromUser:  "testuser",
+			RemoteJid: "alice@s.whatsapp.net",
+			Account:   domain.DefaultWhatsappAccount,
+			MessageID: 35,
+			ContactCard: &domain.WhatsappContactCard{
+				DisplayName: "Bob Smith",
+				Vcard: "BEGIN:VCARD\nVERSION:3.0\nN:Smith;Bob;;;\nFN:Bob Smith\n" +
+					"TEL;type=CELL;type=VOICE;waid=15551234567:+1 555-123-4567\nEND:VCARD",
+			},
+		}, gotEvent)
+	})
+
+	t.Run("retry event", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram callback query
+		testUpdate := tgbotapi.Update{
+			UpdateID: 3,
+			CallbackQuery: &tgbotapi.CallbackQuery{
+				ID: "test-callback-id",
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Message: &tgbotapi.Message{
+					MessageID: 3,
+					Chat: &tgbotapi.Chat{
+						ID: 42,
+					},
+				},
+				Data: domain.NewCallbackData(domain.RetryCallbackAction, "test-message-id"),
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.RetryEventType, gotEvent.Type())
+		gotRetryEvent := gotEvent.(*domain.RetryEvent)
+
+		assert.Equal(t, testUpdate.CallbackQuery.Message.Chat.ID, gotRetryEvent.ChatID)
+		assert.Equal(t, testUpdate.CallbackQuery.From.UserName, gotRetryEvent.FromUser)
+		assert.Equal(t, testUpdate.CallbackQuery.ID, gotRetryEvent.CallbackID)
+		assert.Equal(t, "test-message-id", gotRetryEvent.MessageID)
+	})
+	t.Run("cancel login event", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram callback query
+		testUpdate := tgbotapi.Update{
+			UpdateID: 4,
+			CallbackQuery: &tgbotapi.CallbackQuery{
+				ID: "test-callback-id",
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Message: &tgbotapi.Message{
+					MessageID: 4,
+					Chat: &tgbotapi.Chat{
+						ID: 42,
+					},
+				},
+				Data: domain.NewCallbackData(domain.CancelLoginCallbackAction, ""),
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.CancelLoginEventType, gotEvent.Type())
+		gotCancelEvent := gotEvent.(*domain.CancelLoginEvent)
+
+		assert.Equal(t, testUpdate.CallbackQuery.Message.Chat.ID, gotCancelEvent.ChatID)
+		assert.Equal(t, testUpdate.CallbackQuery.From.UserName, gotCancelEvent.FromUser)
+		assert.Equal(t, testUpdate.CallbackQuery.ID, gotCancelEvent.CallbackID)
+		assert.Equal(t, domain.DefaultWhatsappAccount, gotCancelEvent.Account)
+	})
+
+	t.Run("team event", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram update message
+		testUpdate := tgbotapi.Update{
+			UpdateID: 5,
+			Message: &tgbotapi.Message{
+				MessageID: 5,
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Chat: &tgbotapi.Chat{
+					ID: 42,
+				},
+				Text: "/team add  @bob",
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.TeamEventType, gotEvent.Type())
+		gotTeamEvent := gotEvent.(*domain.TeamEvent)
+
+		assert.Equal(t, testUpdate.Message.Chat.ID, gotTeamEvent.ChatID)
+		assert.Equal(t, testUpdate.Message.From.UserName, gotTeamEvent.FromUser)
+		assert.Equal(t, []string{"add", "@bob"}, gotTeamEvent.Args)
+	})
+
+	t.Run("assign event", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram update message
+		testUpdate := tgbotapi.Update{
+			UpdateID: 6,
+			Message: &tgbotapi.Message{
+				MessageID: 6,
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Chat: &tgbotapi.Chat{
+					ID: 42,
+				},
+				Text: "/assign",
+				ReplyToMessage: &tgbotapi.Message{
+					MessageID: 1,
+					Chat: &tgbotapi.Chat{
+						ID: 42,
+					},
+					Text: "From: Username Surename [jid: example@mail.com] [account: work]\n==========\nMessage: Hello, world!",
+				},
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.AssignEventType, gotEvent.Type())
+		gotAssignEvent := gotEvent.(*domain.AssignEvent)
+
+		assert.Equal(t, testUpdate.Message.Chat.ID, gotAssignEvent.ChatID)
+		assert.Equal(t, testUpdate.Message.From.UserName, gotAssignEvent.FromUser)
+		assert.Equal(t, testUpdate.Message.From.UserName, gotAssignEvent.Assignee)
+		assert.Equal(t, "example@mail.com", gotAssignEvent.RemoteJid)
+		assert.Equal(t, "work", gotAssignEvent.Account)
+	})
+
+	t.Run("unassign event", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram update message without a replied message
+		testUpdate := tgbotapi.Update{
+			UpdateID: 7,
+			Message: &tgbotapi.Message{
+				MessageID: 7,
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Chat: &tgbotapi.Chat{
+					ID: 42,
+				},
+				Text: "/unassign",
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.AssignEventType, gotEvent.Type())
+		gotAssignEvent := gotEvent.(*domain.AssignEvent)
+
+		assert.Empty(t, gotAssignEvent.Assignee)
+		assert.Empty(t, gotAssignEvent.RemoteJid)
+	})
+
+	t.Run("topics event", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram update message
+		testUpdate := tgbotapi.Update{
+			UpdateID: 8,
+			Message: &tgbotapi.Message{
+				MessageID: 8,
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Chat: &tgbotapi.Chat{
+					ID: 42,
+				},
+				Text: "/topics on",
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.TopicsEventType, gotEvent.Type())
+		gotTopicsEvent := gotEvent.(*domain.TopicsEvent)
+
+		assert.Equal(t, testUpdate.Message.Chat.ID, gotTopicsEvent.ChatID)
+		assert.Equal(t, testUpdate.Message.From.UserName, gotTopicsEvent.FromUser)
+		assert.Equal(t, []string{"on"}, gotTopicsEvent.Args)
+	})
+
+	t.Run("message posted to topic", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram update message that replies to the topic creation message
+		testUpdate := tgbotapi.Update{
+			UpdateID: 9,
+			Message: &tgbotapi.Message{
+				MessageID: 9,
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Chat: &tgbotapi.Chat{
+					ID: 42,
+				},
+				Text: "message to the topic",
+				ReplyToMessage: &tgbotapi.Message{
+					MessageID: 5,
+					Chat: &tgbotapi.Chat{
+						ID: 42,
+					},
+				},
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate, MessageThreadID: 5}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.ReplyEventType, gotEvent.Type())
+		gotReplyEvent := gotEvent.(*domain.ReplyEvent)
+
+		assert.Equal(t, testUpdate.Message.Chat.ID, gotReplyEvent.ChatID)
+		assert.Equal(t, 5, gotReplyEvent.ThreadID)
+		assert.Empty(t, gotReplyEvent.RemoteJid)
+		assert.Empty(t, gotReplyEvent.Account)
+		assert.Equal(t, testUpdate.Message.Text, gotReplyEvent.Reply)
+	})
+
+	t.Run("reply in topic to a message that isn't bridged", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// The reply belongs to the topic it's posted to, not to the message it replies to
+		testUpdate := tgbotapi.Update{
+			UpdateID: 10,
+			Message: &tgbotapi.Message{
+				MessageID: 10,
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Chat: &tgbotapi.Chat{
+					ID: 42,
+				},
+				Text: "reply to a note",
+				ReplyToMessage: &tgbotapi.Message{
+					MessageID: 8,
+					Chat: &tgbotapi.Chat{
+						ID: 42,
+					},
+					Text: "The message is sent",
+				},
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate, MessageThreadID: 5}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.ReplyEventType, gotEvent.Type())
+		gotReplyEvent := gotEvent.(*domain.ReplyEvent)
+
+		assert.Equal(t, 5, gotReplyEvent.ThreadID)
+		assert.Empty(t, gotReplyEvent.RemoteJid)
+		assert.Equal(t, testUpdate.Message.Text, gotReplyEvent.Reply)
+	})
+	t.Run("route event", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram update message
+		testUpdate := tgbotapi.Update{
+			UpdateID: 10,
+			Message: &tgbotapi.Message{
+				MessageID: 10,
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Chat: &tgbotapi.Chat{
+					ID: 42,
+				},
+				Text: "/route -100456",
+				ReplyToMessage: &tgbotapi.Message{
+					MessageID: 1,
+					Chat: &tgbotapi.Chat{
+						ID: 42,
+					},
+					Text: "From: Username Surename [jid: example@mail.com] [account: work]\n==========\nMessage: Hello, world!",
+				},
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.RouteEventType, gotEvent.Type())
+		gotRouteEvent := gotEvent.(*domain.RouteEvent)
+
+		assert.Equal(t, testUpdate.Message.Chat.ID, gotRouteEvent.ChatID)
+		assert.Equal(t, testUpdate.Message.From.UserName, gotRouteEvent.FromUser)
+		assert.Equal(t, []string{"-100456"}, gotRouteEvent.Args)
+		assert.Equal(t, "example@mail.com", gotRouteEvent.RemoteJid)
+		assert.Equal(t, "work", gotRouteEvent.Account)
+	})
+
+	t.Run("chat event", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram update message
+		testUpdate := tgbotapi.Update{
+			UpdateID: 11,
+			Message: &tgbotapi.Message{
+				MessageID: 11,
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Chat: &tgbotapi.Chat{
+					ID: 42,
+				},
+				Text: "/chat Test User",
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.ChatEventType, gotEvent.Type())
+		gotChatEvent := gotEvent.(*domain.ChatEvent)
+
+		assert.Equal(t, testUpdate.Message.Chat.ID, gotChatEvent.ChatID)
+		assert.Equal(t, testUpdate.Message.From.UserName, gotChatEvent.FromUser)
+		assert.Equal(t, "Test User", gotChatEvent.Contact)
+		assert.Empty(t, gotChatEvent.RemoteJid)
+	})
+
+	t.Run("chat callback", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram callback query
+		testUpdate := tgbotapi.Update{
+			UpdateID: 12,
+			CallbackQuery: &tgbotapi.CallbackQuery{
+				ID: "test-callback-id",
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Message: &tgbotapi.Message{
+					MessageID: 12,
+					Chat: &tgbotapi.Chat{
+						ID: 42,
+					},
+				},
+				Data: domain.NewCallbackData(domain.ChatCallbackAction,
+					domain.NewConversationRef("work", "example@mail.com")),
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.ChatEventType, gotEvent.Type())
+		gotChatEvent := gotEvent.(*domain.ChatEvent)
+
+		assert.Equal(t, testUpdate.CallbackQuery.Message.Chat.ID, gotChatEvent.ChatID)
+		assert.Equal(t, testUpdate.CallbackQuery.ID, gotChatEvent.CallbackID)
+		assert.Equal(t, "example@mail.com", gotChatEvent.RemoteJid)
+		assert.Equal(t, "work", gotChatEvent.Account)
+	})
+
+	t.Run("close chat event", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram callback query
+		testUpdate := tgbotapi.Update{
+			UpdateID: 13,
+			CallbackQuery: &tgbotapi.CallbackQuery{
+				ID: "test-callback-id",
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Message: &tgbotapi.Message{
+					MessageID: 13,
+					Chat: &tgbotapi.Chat{
+						ID: 42,
+					},
+				},
+				Data: domain.NewCallbackData(domain.CloseChatCallbackAction, ""),
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.CloseChatEventType, gotEvent.Type())
+		gotCloseChatEvent := gotEvent.(*domain.CloseChatEvent)
+
+		assert.Equal(t, testUpdate.CallbackQuery.Message.Chat.ID, gotCloseChatEvent.ChatID)
+		assert.Equal(t, testUpdate.CallbackQuery.From.UserName, gotCloseChatEvent.FromUser)
+		assert.Equal(t, testUpdate.CallbackQuery.ID, gotCloseChatEvent.CallbackID)
+	})
+
+	t.Run("plain message", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram update message
+		testUpdate := tgbotapi.Update{
+			UpdateID: 14,
+			Message: &tgbotapi.Message{
+				MessageID: 14,
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Chat: &tgbotapi.Chat{
+					ID: 42,
+				},
+				Text: "hello, world!",
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.ReplyEventType, gotEvent.Type())
+		gotReplyEvent := gotEvent.(*domain.ReplyEvent)
+
+		assert.Equal(t, testUpdate.Message.Chat.ID, gotReplyEvent.ChatID)
+		assert.Equal(t, testUpdate.Message.Text, gotReplyEvent.Reply)
+		assert.Empty(t, gotReplyEvent.RemoteJid)
+		assert.Zero(t, gotReplyEvent.ThreadID)
+	})
+
+	t.Run("mute event", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram update message
+		testUpdate := tgbotapi.Update{
+			UpdateID: 15,
+			Message: &tgbotapi.Message{
+				MessageID: 15,
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Chat: &tgbotapi.Chat{
+					ID: 42,
+				},
+				Text: "/mute",
+				ReplyToMessage: &tgbotapi.Message{
+					MessageID: 1,
+					Chat: &tgbotapi.Chat{
+						ID: 42,
+					},
+					Text: "From: Username Surename [jid: example@mail.com] [account: work]\n==========\nMessage: Hello, world!",
+				},
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.SettingsEventType, gotEvent.Type())
+		gotSettingsEvent := gotEvent.(*domain.SettingsEvent)
+
+		assert.Equal(t, testUpdate.Message.Chat.ID, gotSettingsEvent.ChatID)
+		assert.Equal(t, testUpdate.Message.From.UserName, gotSettingsEvent.FromUser)
+		assert.Equal(t, domain.ContactModeMute, gotSettingsEvent.Mode)
+		assert.Equal(t, "example@mail.com", gotSettingsEvent.RemoteJid)
+		assert.Equal(t, "work", gotSettingsEvent.Account)
+	})
+
+	t.Run("contact mode callback", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram callback query
+		testUpdate := tgbotapi.Update{
+			UpdateID: 16,
+			CallbackQuery: &tgbotapi.CallbackQuery{
+				ID: "test-callback-id",
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Message: &tgbotapi.Message{
+					MessageID: 16,
+					Chat: &tgbotapi.Chat{
+						ID: 42,
+					},
+				},
+				Data: domain.NewCallbackData(domain.ContactModeCallbackAction,
+					domain.NewCallbackData("digest", domain.NewConversationRef("work", "example@mail.com"))),
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.SettingsEventType, gotEvent.Type())
+		gotSettingsEvent := gotEvent.(*domain.SettingsEvent)
+
+		assert.Equal(t, testUpdate.CallbackQuery.Message.Chat.ID, gotSettingsEvent.ChatID)
+		assert.Equal(t, testUpdate.CallbackQuery.ID, gotSettingsEvent.CallbackID)
+		assert.Equal(t, testUpdate.CallbackQuery.Message.MessageID, gotSettingsEvent.MessageID)
+		assert.Equal(t, domain.ContactModeDigest, gotSettingsEvent.Mode)
+		assert.Equal(t, "example@mail.com", gotSettingsEvent.RemoteJid)
+		assert.Equal(t, "work", gotSettingsEvent.Account)
+	})
+
+	t.Run("digest event", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram update message
+		testUpdate := tgbotapi.Update{
+			UpdateID: 17,
+			Message: &tgbotapi.Message{
+				MessageID: 17,
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Chat: &tgbotapi.Chat{
+					ID: 42,
+				},
+				Text: "/digest",
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.DigestEventType, gotEvent.Type())
+		gotDigestEvent := gotEvent.(*domain.DigestEvent)
+
+		assert.Equal(t, testUpdate.Message.Chat.ID, gotDigestEvent.ChatID)
+		assert.Equal(t, testUpdate.Message.From.UserName, gotDigestEvent.FromUser)
+		assert.Empty(t, gotDigestEvent.Args)
+	})
+
+	t.Run("rules event", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram update message
+		testUpdate := tgbotapi.Update{
+			UpdateID: 18,
+			Message: &tgbotapi.Message{
+				MessageID: 18,
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Chat: &tgbotapi.Chat{
+					ID: 42,
+				},
+				Text: "/rules add drop keyword=lottery",
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.RulesEventType, gotEvent.Type())
+		gotRulesEvent := gotEvent.(*domain.RulesEvent)
+
+		assert.Equal(t, testUpdate.Message.Chat.ID, gotRulesEvent.ChatID)
+		assert.Equal(t, testUpdate.Message.From.UserName, gotRulesEvent.FromUser)
+		assert.Equal(t, []string{"add", "drop", "keyword=lottery"}, gotRulesEvent.Args)
+	})
+
+	t.Run("quiet event", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram update message
+		testUpdate := tgbotapi.Update{
+			UpdateID: 19,
+			Message: &tgbotapi.Message{
+				MessageID: 19,
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Chat: &tgbotapi.Chat{
+					ID: 42,
+				},
+				Text: "/quiet 23:00-07:00 Europe/Berlin",
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.QuietEventType, gotEvent.Type())
+		gotQuietEvent := gotEvent.(*domain.QuietEvent)
+
+		assert.Equal(t, testUpdate.Message.Chat.ID, gotQuietEvent.ChatID)
+		assert.Equal(t, testUpdate.Message.From.UserName, gotQuietEvent.FromUser)
+		assert.Equal(t, []string{"23:00-07:00", "Europe/Berlin"}, gotQuietEvent.Args)
+	})
+
+	t.Run("schedule event", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram update message
+		testUpdate := tgbotapi.Update{
+			UpdateID: 20,
+			Message: &tgbotapi.Message{
+				MessageID: 20,
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Chat: &tgbotapi.Chat{
+					ID: 42,
+				},
+				Text: "/schedule Alice 09:00 call me\nplease",
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.ScheduleEventType, gotEvent.Type())
+		gotScheduleEvent := gotEvent.(*domain.ScheduleEvent)
+
+		assert.Equal(t, testUpdate.Message.Chat.ID, gotScheduleEvent.ChatID)
+		assert.Equal(t, testUpdate.Message.From.UserName, gotScheduleEvent.FromUser)
+		assert.Equal(t, "Alice", gotScheduleEvent.Contact)
+		assert.Equal(t, "09:00", gotScheduleEvent.Time)
+		assert.Equal(t, "call me\nplease", gotScheduleEvent.Text)
+	})
+
+	t.Run("schedule reply event", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram update message
+		testUpdate := tgbotapi.Update{
+			UpdateID: 21,
+			Message: &tgbotapi.Message{
+				MessageID: 21,
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Chat: &tgbotapi.Chat{
+					ID: 42,
+				},
+				Text: "/schedule +1h call me",
+				ReplyToMessage: &tgbotapi.Message{
+					Text: "From: Alice [jid: alice@s.whatsapp.net] [account: work]",
+				},
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.ScheduleEventType, gotEvent.Type())
+		gotScheduleEvent := gotEvent.(*domain.ScheduleEvent)
+
+		assert.Empty(t, gotScheduleEvent.Contact)
+		assert.Equal(t, "+1h", gotScheduleEvent.Time)
+		assert.Equal(t, "call me", gotScheduleEvent.Text)
+		assert.Equal(t, "alice@s.whatsapp.net", gotScheduleEvent.RemoteJid)
+		assert.Equal(t, "work", gotScheduleEvent.Account)
+	})
+
+	t.Run("unschedule callback", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram callback query
+		testUpdate := tgbotapi.Update{
+			UpdateID: 22,
+			CallbackQuery: &tgbotapi.CallbackQuery{
+				ID: "test-callback-id",
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Message: &tgbotapi.Message{
+					MessageID: 22,
+					Chat: &tgbotapi.Chat{
+						ID: 42,
+					},
+				},
+				Data: domain.NewCallbackData(domain.UnscheduleCallbackAction, "3"),
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.UnscheduleEventType, gotEvent.Type())
+		gotUnscheduleEvent := gotEvent.(*domain.UnscheduleEvent)
+
+		assert.Equal(t, testUpdate.CallbackQuery.Message.Chat.ID, gotUnscheduleEvent.ChatID)
+		assert.Equal(t, testUpdate.CallbackQuery.ID, gotUnscheduleEvent.CallbackID)
+		assert.Equal(t, testUpdate.CallbackQuery.Message.MessageID, gotUnscheduleEvent.MessageID)
+		assert.Equal(t, "3", gotUnscheduleEvent.ID)
+	})
+
+	t.Run("away event", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram update message
+		testUpdate := tgbotapi.Update{
+			UpdateID: 23,
+			Message: &tgbotapi.Message{
+				MessageID: 23,
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Chat: &tgbotapi.Chat{
+					ID: 42,
+				},
+				Text: "/away I'm on vacation until  Monday",
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.AwayEventType, gotEvent.Type())
+		gotAwayEvent := gotEvent.(*domain.AwayEvent)
+
+		assert.Equal(t, testUpdate.Message.Chat.ID, gotAwayEvent.ChatID)
+		assert.Equal(t, testUpdate.Message.From.UserName, gotAwayEvent.FromUser)
+		assert.Equal(t, "I'm on vacation until  Monday", gotAwayEvent.Args)
+	})
+
+	t.Run("search event", func(t *testing.T) {
+		for _, test := range []struct {
+			text            string
+			expectedQuery   string
+			expectedContact string
+		}{
+			{text: "/search lunch", expectedQuery: "lunch"},
+			{text: "/search lunch Alice Smith", expectedQuery: "lunch", expectedContact: "Alice Smith"},
+			{text: `/search "lunch plans" Alice`, expectedQuery: "lunch plans", expectedContact: "Alice"},
+			{text: `/search "lunch plans`, expectedQuery: "lunch plans"},
+		} {
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			var gotEvent domain.Event
+			go func() {
+				defer wg.Done()
+				gotEvent = <-eventsProvider.EventsStream()
+			}()
+
+			// Emulate telegram update message
+			testUpdate := tgbotapi.Update{
+				UpdateID: 24,
+				Message: &tgbotapi.Message{
+					MessageID: 24,
+					From: &tgbotapi.User{
+						UserName: "testuser",
+					},
+					Chat: &tgbotapi.Chat{
+						ID: 42,
+					},
+					Text: test.text,
+				},
+			}
+			tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+			// Wait for the event to be processed
+			wg.Wait()
+
+			assert.Equal(t, domain.SearchEventType, gotEvent.Type())
+			gotSearchEvent := gotEvent.(*domain.SearchEvent)
+
+			assert.Equal(t, testUpdate.Message.Chat.ID, gotSearchEvent.ChatID)
+			assert.Equal(t, testUpdate.Message.From.UserName, gotSearchEvent.FromUser)
+			assert.Equal(t, test.expectedQuery, gotSearchEvent.Query)
+			assert.Equal(t, test.expectedContact, gotSearchEvent.Contact)
+		}
+	})
+
+	t.Run("export event", func(t *testing.T) {
+		for _, test := range []struct {
+			text            string
+			replyTo         string
+			expectedContact string
+			expectedFrom    string
+			expectedTo      string
+		}{
+			{text: "/export Alice", expectedContact: "Alice"},
+			{
+				text:            `/export "Alice Smith" 2022-03-01 2022-03-31`,
+				expectedContact: "Alice Smith",
+				expectedFrom:    "2022-03-01",
+				expectedTo:      "2022-03-31",
+			},
+			{
+				text:            "/export 2022-03-01",
+				replyTo:         "From: Alice [jid: alice@s.whatsapp.net]\n==========\nMessage: hi",
+				expectedContact: "alice@s.whatsapp.net",
+				expectedFrom:    "2022-03-01",
+			},
+		} {
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			var gotEvent domain.Event
+			go func() {
+				defer wg.Done()
+				gotEvent = <-eventsProvider.EventsStream()
+			}()
+
+			// Emulate telegram update message
+			testUpdate := tgbotapi.Update{
+				UpdateID: 25,
+				Message: &tgbotapi.Message{
+					MessageID: 25,
+					From: &tgbotapi.User{
+						UserName: "testuser",
+					},
+					Chat: &tgbotapi.Chat{
+						ID: 42,
+					},
+					Text: test.text,
+				},
+			}
+			if test.replyTo != "" {
+				testUpdate.Message.ReplyToMessage = &tgbotapi.Message{
+					MessageID: 1,
+					Text:      test.replyTo,
+				}
+			}
+			tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+			// Wait for the event to be processed
+			wg.Wait()
+
+			assert.Equal(t, domain.ExportEventType, gotEvent.Type())
+			gotExportEvent := gotEvent.(*domain.ExportEvent)
+
+			assert.Equal(t, testUpdate.Message.Chat.ID, gotExportEvent.ChatID)
+			assert.Equal(t, testUpdate.Message.From.UserName, gotExportEvent.FromUser)
+			assert.Equal(t, test.expectedContact, gotExportEvent.Contact)
+			assert.Equal(t, test.expectedFrom, gotExportEvent.From)
+			assert.Equal(t, test.expectedTo, gotExportEvent.To)
+		}
+	})
+
+	t.Run("history event", func(t *testing.T) {
+		for _, test := range []struct {
+			text              string
+			replyTo           string
+			expectedContact   string
+			expectedCount     string
+			expectedRemoteJid string
+			expectedAccount   string
+		}{
+			{text: "/history Alice", expectedContact: "Alice"},
+			{text: `/history "Alice Smith" 50`, expectedContact: "Alice Smith", expectedCount: "50"},
+			{
+				text:              "/history 10",
+				replyTo:           "From: Alice [jid: alice@s.whatsapp.net] [account: work]\n==========\nMessage: hi",
+				expectedCount:     "10",
+				expectedRemoteJid: "alice@s.whatsapp.net",
+				expectedAccount:   "work",
+			},
+		} {
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			var gotEvent domain.Event
+			go func() {
+				defer wg.Done()
+				gotEvent = <-eventsProvider.EventsStream()
+			}()
+
+			// Emulate telegram update message
+			testUpdate := tgbotapi.Update{
+				UpdateID: 26,
+				Message: &tgbotapi.Message{
+					MessageID: 26,
+					From: &tgbotapi.User{
+						UserName: "testuser",
+					},
+					Chat: &tgbotapi.Chat{
+						ID: 42,
+					},
+					Text: test.text,
+				},
+			}
+			if test.replyTo != "" {
+				testUpdate.Message.ReplyToMessage = &tgbotapi.Message{
+					MessageID: 1,
+					Text:      test.replyTo,
+				}
+			}
+			tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+			// Wait for the event to be processed
+			wg.Wait()
+
+			assert.Equal(t, domain.HistoryEventType, gotEvent.Type())
+			gotHistoryEvent := gotEvent.(*domain.HistoryEvent)
+
+			assert.Equal(t, testUpdate.Message.Chat.ID, gotHistoryEvent.ChatID)
+			assert.Equal(t, testUpdate.Message.From.UserName, gotHistoryEvent.FromUser)
+			assert.Equal(t, test.expectedContact, gotHistoryEvent.Contact)
+			assert.Equal(t, test.expectedCount, gotHistoryEvent.Count)
+			assert.Equal(t, test.expectedRemoteJid, gotHistoryEvent.RemoteJid)
+			assert.Equal(t, test.expectedAccount, gotHistoryEvent.Account)
+		}
+	})
+
+	t.Run("chats event", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram update message
+		testUpdate := tgbotapi.Update{
+			UpdateID: 27,
+			Message: &tgbotapi.Message{
+				MessageID: 27,
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Chat: &tgbotapi.Chat{
+					ID: 42,
+				},
+				Text: "/chats",
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.ChatsEventType, gotEvent.Type())
+		gotChatsEvent := gotEvent.(*domain.ChatsEvent)
+
+		assert.Equal(t, testUpdate.Message.Chat.ID, gotChatsEvent.ChatID)
+		assert.Equal(t, testUpdate.Message.From.UserName, gotChatsEvent.FromUser)
+		assert.Empty(t, gotChatsEvent.Action)
+	})
+
+	t.Run("chats callback", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Emulate telegram callback query
+		testUpdate := tgbotapi.Update{
+			UpdateID: 28,
+			CallbackQuery: &tgbotapi.CallbackQuery{
+				ID: "test-callback-id",
+				From: &tgbotapi.User{
+					UserName: "testuser",
+				},
+				Message: &tgbotapi.Message{
+					MessageID: 28,
+					Chat: &tgbotapi.Chat{
+						ID: 42,
+					},
+				},
+				Data: domain.NewCallbackData(domain.ChatsCallbackAction,
+					domain.NewCallbackData(string(domain.ChatsActionRead),
+						domain.NewConversationRef("work", "alice@s.whatsapp.net"))),
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, domain.ChatsEventType, gotEvent.Type())
+		gotChatsEvent := gotEvent.(*domain.ChatsEvent)
+
+		assert.Equal(t, testUpdate.CallbackQuery.Message.Chat.ID, gotChatsEvent.ChatID)
+		assert.Equal(t, testUpdate.CallbackQuery.ID, gotChatsEvent.CallbackID)
+		assert.Equal(t, testUpdate.CallbackQuery.Message.MessageID, gotChatsEvent.MessageID)
+		assert.Equal(t, domain.ChatsActionRead, gotChatsEvent.Action)
+		assert.Equal(t, "alice@s.whatsapp.net", gotChatsEvent.RemoteJid)
+		assert.Equal(t, "work", gotChatsEvent.Account)
+	})
+
+	t.Run("last seen event", func(t *testing.T) {
+		for _, test := range []struct {
+			text              string
+			replyTo           string
+			expectedContact   string
+			expectedRemoteJid string
+			expectedAccount   string
+		}{
+			{text: "/lastseen Alice Smith", expectedContact: "Alice Smith"},
+			{
+				text:              "/lastseen",
+				replyTo:           "From: Alice [jid: alice@s.whatsapp.net] [account: work]\n==========\nMessage: hi",
+				expectedRemoteJid: "alice@s.whatsapp.net",
+				expectedAccount:   "work",
+			},
+		} {
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			var gotEvent domain.Event
+			go func() {
+				defer wg.Done()
+				gotEvent = <-eventsProvider.EventsStream()
+			}()
+
+			// Emulate telegram update message
+			testUpdate := tgbotapi.Update{
+				UpdateID: 29,
+				Message: &tgbotapi.Message{
+					MessageID: 29,
+					From: &tgbotapi.User{
+						UserName: "testuser",
+					},
+					Chat: &tgbotapi.Chat{
+						ID: 42,
+					},
+					Text: test.text,
+				},
+			}
+			if test.replyTo != "" {
+				testUpdate.Message.ReplyToMessage = &tgbotapi.Message{
+					MessageID: 1,
+					Text:      test.replyTo,
+				}
+			}
+			tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+			// Wait for the event to be processed
+			wg.Wait()
+
+			assert.Equal(t, domain.LastSeenEventType, gotEvent.Type())
+			gotLastSeenEvent := gotEvent.(*domain.LastSeenEvent)
+
+			assert.Equal(t, testUpdate.Message.Chat.ID, gotLastSeenEvent.ChatID)
+			assert.Equal(t, testUpdate.Message.From.UserName, gotLastSeenEvent.FromUser)
+			assert.Equal(t, test.expectedContact, gotLastSeenEvent.Contact)
+			assert.Equal(t, test.expectedRemoteJid, gotLastSeenEvent.RemoteJid)
+			assert.Equal(t, test.expectedAccount, gotLastSeenEvent.Account)
+		}
+	})
+
+	t.Run("delete event", func(t *testing.T) {
+		for _, replyToID := range []int{0, 17} {
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			var gotEvent domain.Event
+			go func() {
+				defer wg.Done()
+				gotEvent = <-eventsProvider.EventsStream()
+			}()
+
+			// Emulate telegram update message
+			testUpdate := tgbotapi.Update{
+				UpdateID: 30,
+				Message: &tgbotapi.Message{
+					MessageID: 30,
+					From: &tgbotapi.User{
+						UserName: "testuser",
+					},
+					Chat: &tgbotapi.Chat{
+						ID: 42,
+					},
+					Text: "/delete",
+				},
+			}
+			if replyToID != 0 {
+				testUpdate.Message.ReplyToMessage = &tgbotapi.Message{
+					MessageID: replyToID,
+					Text:      "see you at 5",
+				}
+			}
+			tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+			// Wait for the event to be processed
+			wg.Wait()
+
+			assert.Equal(t, &domain.DeleteEvent{
+				ChatID:    testUpdate.Message.Chat.ID,
+				FromUser:  testUpdate.Message.From.UserName,
+				MessageID: replyToID,
+			}, gotEvent)
+		}
+	})
+
+	t.Run("edit event", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Edited commands are ignored, they have been handled already
+		tgUpdatesCh <- telegram.Update{Update: tgbotapi.Update{
+			UpdateID: 31,
+			EditedMessage: &tgbotapi.Message{
+				MessageID: 31,
+				From:      &tgbotapi.User{UserName: "testuser"},
+				Chat:      &tgbotapi.Chat{ID: 42},
+				Text:      "/chat Alice",
+			},
+		}}
+
+		// Emulate telegram update message
+		testUpdate := tgbotapi.Update{
+			UpdateID: 32,
+			EditedMessage: &tgbotapi.Message{
+				MessageID: 17,
+				From: &tgbotapi.User{
+					UserName:  "testuser",
+					FirstName: "Test",
+					LastName:  "User",
+				},
+				Chat: &tgbotapi.Chat{
+					ID: 42,
+				},
+				Text: "see you at 6",
+			},
+		}
+		tgUpdatesCh <- telegram.Update{Update: testUpdate}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		assert.Equal(t, &domain.EditEvent{
+			ChatID:    42,
+			FromUser:  "testuser",
+			FromName:  "Test User",
+			MessageID: 17,
+			Text:      "see you at 6",
+		}, gotEvent)
+	})
+
+	t.Run("react event", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+
+		var gotEvent domain.Event
+		go func() {
+			defer wg.Done()
+			gotEvent = <-eventsProvider.EventsStream()
+		}()
+
+		// Anonymous reactions of the chats are ignored
+		reactionsCh <- telegram.MessageReaction{
+			Chat:      &tgbotapi.Chat{ID: 42},
+			MessageID: 17,
+		}
+
+		// Emulate telegram reaction
+		reactionsCh <- telegram.MessageReaction{
+			Chat:      &tgbotapi.Chat{ID: 42},
+			MessageID: 17,
+			User:      &tgbotapi.User{UserName: "testuser"},
+		}
+
+		// Wait for the event to be processed
+		wg.Wait()
+
+		// The reaction is removed
+		assert.Equal(t, &domain.ReactEvent{
+			ChatID:    42,
+			FromUser:  "testuser",
+			MessageID: 17,
+		}, gotEvent)
 	})
 }
@@ -0,0 +1,485 @@
+package fake
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+)
+
+// errFileNotFound is returned when the file to download hasn't been added.
+var errFileNotFound = errors.New("file not found")
+
+// Client is an in-memory implementation of domain.TelegramClient that
+// records all the messages sent through it.
+type Client struct {
+	mu               sync.Mutex
+	lastMessageID    int
+	err              error
+	chatErrs         map[int64]error
+	textMessages     []domain.TelegramTextMessage
+	photoMessages    []domain.TelegramPhotoMessage
+	documents        []domain.TelegramDocumentMessage
+	edits            []domain.TelegramEditMessage
+	photoEdits       []domain.TelegramEditPhotoMessage
+	captionEdits     []domain.TelegramEditCaptionMessage
+	callbackAnswers  []domain.TelegramCallbackAnswer
+	chatActions      []domain.TelegramChatAction
+	reactions        []domain.TelegramReaction
+	locations        []domain.TelegramLocationMessage
+	locationEdits    []domain.TelegramEditLocationMessage
+	stoppedLocations []int
+	contacts         []domain.TelegramContactMessage
+	files            map[string][]byte
+	reactionErr      error
+	topics           map[int]string
+	pinned           map[int64][]int
+	deletedTopics    map[int]bool
+}
+
+// NewClient returns new instance of Client.
+func NewClient() *Client {
+	return &Client{
+		chatErrs:      make(map[int64]error),
+		topics:        make(map[int]string),
+		pinned:        make(map[int64][]int),
+		deletedTopics: make(map[int]bool),
+		files:         make(map[string][]byte),
+	}
+}
+
+// SetError method makes all subsequent calls fail with the provided error.
+// Passing nil makes the client work normally again.
+func (c *Client) SetError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.err = err
+}
+
+// SetChatError method makes subsequent text messages to the chat fail with
+// the provided error. Passing nil makes the chat reachable again.
+func (c *Client) SetChatError(chatID int64, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err == nil {
+		delete(c.chatErrs, chatID)
+
+		return
+	}
+	c.chatErrs[chatID] = err
+}
+
+// SetReactionError method makes subsequent reactions fail with the provided error,
+// e.g. the emoji isn't available. Passing nil makes reactions work again.
+func (c *Client) SetReactionError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.reactionErr = err
+}
+
+// SendText method records a text message.
+func (c *Client) SendText(msg *domain.TelegramTextMessage) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return 0, c.err
+	}
+	if err := c.chatErrs[msg.ChatID]; err != nil {
+		return 0, err
+	}
+	if c.deletedTopics[msg.ThreadID] {
+		return 0, domain.ErrTelegramTopicNotFound
+	}
+	c.textMessages = append(c.textMessages, *msg)
+
+	return c.nextMessageID(), nil
+}
+
+// SendPhoto method records a photo message.
+func (c *Client) SendPhoto(msg *domain.TelegramPhotoMessage) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return 0, c.err
+	}
+	c.photoMessages = append(c.photoMessages, *msg)
+
+	return c.nextMessageID(), nil
+}
+
+// SendDocument method records a document message.
+func (c *Client) SendDocument(msg *domain.TelegramDocumentMessage) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return 0, c.err
+	}
+	c.documents = append(c.documents, *msg)
+
+	return c.nextMessageID(), nil
+}
+
+// EditMessage method records a message edit.
+func (c *Client) EditMessage(msg *domain.TelegramEditMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+	c.edits = append(c.edits, *msg)
+
+	return nil
+}
+
+// EditPhoto method records a photo replacement.
+func (c *Client) EditPhoto(msg *domain.TelegramEditPhotoMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+	c.photoEdits = append(c.photoEdits, *msg)
+
+	return nil
+}
+
+// EditCaption method records a caption edit.
+func (c *Client) EditCaption(msg *domain.TelegramEditCaptionMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+	c.captionEdits = append(c.captionEdits, *msg)
+
+	return nil
+}
+
+// AnswerCallback method records a callback query answer.
+func (c *Client) AnswerCallback(answer *domain.TelegramCallbackAnswer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+	c.callbackAnswers = append(c.callbackAnswers, *answer)
+
+	return nil
+}
+
+// CreateTopic method records a forum topic, its identifier is the identifier
+// of the service message that opens the topic.
+func (c *Client) CreateTopic(_ int64, name string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return 0, c.err
+	}
+	threadID := c.nextMessageID()
+	c.topics[threadID] = name
+
+	return threadID, nil
+}
+
+// PinMessage method records a pinned message.
+func (c *Client) PinMessage(chatID int64, messageID int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+	c.pinned[chatID] = append(c.pinned[chatID], messageID)
+
+	return nil
+}
+
+// UnpinMessage method removes the message from the pinned ones.
+func (c *Client) UnpinMessage(chatID int64, messageID int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+
+	pinned := make([]int, 0, len(c.pinned[chatID]))
+	for _, id := range c.pinned[chatID] {
+		if id != messageID {
+			pinned = append(pinned, id)
+		}
+	}
+	c.pinned[chatID] = pinned
+
+	return nil
+}
+
+// SendChatAction method records a chat action.
+func (c *Client) SendChatAction(action *domain.TelegramChatAction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+	c.chatActions = append(c.chatActions, *action)
+
+	return nil
+}
+
+// SetReaction method records a reaction.
+func (c *Client) SetReaction(reaction *domain.TelegramReaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+	if c.reactionErr != nil {
+		return c.reactionErr
+	}
+	c.reactions = append(c.reactions, *reaction)
+
+	return nil
+}
+
+// SendLocation method records a location message.
+func (c *Client) SendLocation(msg *domain.TelegramLocationMessage) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return 0, c.err
+	}
+	c.locations = append(c.locations, *msg)
+
+	return c.nextMessageID(), nil
+}
+
+// EditLocation method records an update of the live location.
+func (c *Client) EditLocation(msg *domain.TelegramEditLocationMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+	c.locationEdits = append(c.locationEdits, *msg)
+
+	return nil
+}
+
+// StopLocation method records the live location that is no longer updated.
+func (c *Client) StopLocation(_ int64, messageID int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+	c.stoppedLocations = append(c.stoppedLocations, messageID)
+
+	return nil
+}
+
+// SendContact method records a contact message.
+func (c *Client) SendContact(msg *domain.TelegramContactMessage) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return 0, c.err
+	}
+	c.contacts = append(c.contacts, *msg)
+
+	return c.nextMessageID(), nil
+}
+
+// DownloadFile method returns content of the file added by SetFile.
+func (c *Client) DownloadFile(fileID string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return nil, c.err
+	}
+	data, ok := c.files[fileID]
+	if !ok {
+		return nil, errFileNotFound
+	}
+
+	return data, nil
+}
+
+// SetFile method adds the file that has been sent to the bot, so it can be downloaded.
+func (c *Client) SetFile(fileID string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.files[fileID] = data
+}
+
+// Pinned method returns identifiers of the messages pinned in the chat.
+func (c *Client) Pinned(chatID int64) []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]int(nil), c.pinned[chatID]...)
+}
+
+// DeleteTopic method emulates a forum topic deleted by a user,
+// subsequent messages to the topic fail.
+func (c *Client) DeleteTopic(threadID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.topics, threadID)
+	c.deletedTopics[threadID] = true
+}
+
+// Topics method returns names of the existing forum topics by their identifiers.
+func (c *Client) Topics() map[int]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	topics := make(map[int]string, len(c.topics))
+	for threadID, name := range c.topics {
+		topics[threadID] = name
+	}
+
+	return topics
+}
+
+// TextMessages method returns text messages sent so far.
+func (c *Client) TextMessages() []domain.TelegramTextMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]domain.TelegramTextMessage(nil), c.textMessages...)
+}
+
+// Texts method returns texts of the text messages sent so far.
+func (c *Client) Texts() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	texts := make([]string, 0, len(c.textMessages))
+	for _, msg := range c.textMessages {
+		texts = append(texts, msg.Text)
+	}
+
+	return texts
+}
+
+// PhotoMessages method returns photo messages sent so far.
+func (c *Client) PhotoMessages() []domain.TelegramPhotoMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]domain.TelegramPhotoMessage(nil), c.photoMessages...)
+}
+
+// Documents method returns document messages sent so far.
+func (c *Client) Documents() []domain.TelegramDocumentMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]domain.TelegramDocumentMessage(nil), c.documents...)
+}
+
+// Edits method returns message edits made so far.
+func (c *Client) Edits() []domain.TelegramEditMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]domain.TelegramEditMessage(nil), c.edits...)
+}
+
+// PhotoEdits method returns photo replacements made so far.
+func (c *Client) PhotoEdits() []domain.TelegramEditPhotoMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]domain.TelegramEditPhotoMessage(nil), c.photoEdits...)
+}
+
+// CaptionEdits method returns caption edits made so far.
+func (c *Client) CaptionEdits() []domain.TelegramEditCaptionMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]domain.TelegramEditCaptionMessage(nil), c.captionEdits...)
+}
+
+// CallbackAnswers method returns callback query answers sent so far.
+func (c *Client) CallbackAnswers() []domain.TelegramCallbackAnswer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]domain.TelegramCallbackAnswer(nil), c.callbackAnswers...)
+}
+
+// ChatActions method returns chat actions sent so far.
+func (c *Client) ChatActions() []domain.TelegramChatAction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]domain.TelegramChatAction(nil), c.chatActions...)
+}
+
+// Reactions method returns reactions set so far.
+func (c *Client) Reactions() []domain.TelegramReaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]domain.TelegramReaction(nil), c.reactions...)
+}
+
+// Locations method returns location messages sent so far.
+func (c *Client) Locations() []domain.TelegramLocationMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]domain.TelegramLocationMessage(nil), c.locations...)
+}
+
+// LocationEdits method returns updates of the live locations made so far.
+func (c *Client) LocationEdits() []domain.TelegramEditLocationMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]domain.TelegramEditLocationMessage(nil), c.locationEdits...)
+}
+
+// StoppedLocations method returns identifiers of the live location messages stopped so far.
+func (c *Client) StoppedLocations() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]int(nil), c.stoppedLocations...)
+}
+
+// Contacts method returns contact messages sent so far.
+func (c *Client) Contacts() []domain.TelegramContactMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]domain.TelegramContactMessage(nil), c.contacts...)
+}
+
+func (c *Client) nextMessageID() int {
+	c.lastMessageID++
+
+	return c.lastMessageID
+}
@@ -0,0 +1,193 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"go.uber.org/zap"
+)
+
+const (
+	// emojiReactionType is a type of the reaction that is a regular emoji.
+	emojiReactionType = "emoji"
+
+	// pollRetryDelay is a delay before getting updates again once it has failed.
+	pollRetryDelay = 3 * time.Second
+)
+
+// allowedUpdates are the kinds of updates the bot receives. Reactions are sent
+// only to the bots that explicitly ask for them.
+var allowedUpdates = []string{"message", "edited_message", "callback_query", "message_reaction"}
+
+// ReactionType represents a reaction to a telegram message, only emoji reactions are bridged.
+type ReactionType struct {
+	Type  string `json:"type"`
+	Emoji string `json:"emoji,omitempty"`
+}
+
+// MessageReaction represents a change of the reaction of the user to a telegram message.
+type MessageReaction struct {
+	Chat        *tgbotapi.Chat `json:"chat"`
+	MessageID   int            `json:"message_id"`
+	User        *tgbotapi.User `json:"user"`
+	NewReaction []ReactionType `json:"new_reaction"`
+}
+
+// Emoji returns the emoji the user has reacted with, it's empty if the reaction
+// is removed or it's a custom emoji.
+func (r *MessageReaction) Emoji() string {
+	for _, reaction := range r.NewReaction {
+		if reaction.Type == emojiReactionType {
+			return reaction.Emoji
+		}
+	}
+
+	return ""
+}
+
+// Update represents a telegram update with the fields of its message the library doesn't know about.
+type Update struct {
+	tgbotapi.Update
+
+	// MessageThreadID is an identifier of the forum topic the message is posted to,
+	// it's zero if the message isn't posted to a topic.
+	MessageThreadID int
+}
+
+// update represents a telegram update, the library doesn't know about reactions.
+type update struct {
+	tgbotapi.Update
+	MessageReaction *MessageReaction `json:"message_reaction"`
+
+	messageThreadID int
+}
+
+// topicUpdate represents the forum topic fields of the update message. Replies in regular
+// groups have a thread too, so only the messages marked as topic ones belong to a topic.
+type topicUpdate struct {
+	Message *struct {
+		MessageThreadID int  `json:"message_thread_id"`
+		IsTopicMessage  bool `json:"is_topic_message"`
+	} `json:"message"`
+}
+
+// UpdatesPoller receives telegram updates by long polling, it replaces the library
+// poller that can't receive reactions.
+type UpdatesPoller struct {
+	log         *zap.Logger
+	api         *tgbotapi.BotAPI
+	timeout     int
+	updatesCh   chan Update
+	reactionsCh chan MessageReaction
+}
+
+// NewUpdatesPoller returns new instance of UpdatesPoller, the timeout of long polling is in seconds.
+func NewUpdatesPoller(log *zap.Logger, api *tgbotapi.BotAPI, timeout int) *UpdatesPoller {
+	return &UpdatesPoller{
+		log:         log,
+		api:         api,
+		timeout:     timeout,
+		updatesCh:   make(chan Update, api.Buffer),
+		reactionsCh: make(chan MessageReaction, api.Buffer),
+	}
+}
+
+// Updates returns a channel of received updates except reactions.
+func (p *UpdatesPoller) Updates() <-chan Update {
+	return p.updatesCh
+}
+
+// Reactions returns a channel of received reactions.
+func (p *UpdatesPoller) Reactions() <-chan MessageReaction {
+	return p.reactionsCh
+}
+
+// Run method receives updates until the context is done.
+// The call is blocking.
+func (p *UpdatesPoller) Run(ctx context.Context) error {
+	offset := 0
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		updates, err := p.getUpdates(offset)
+		if err != nil {
+			p.log.Error("failed to get telegram updates", zap.Error(err))
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(pollRetryDelay):
+			}
+
+			continue
+		}
+
+		for i := range updates {
+			if updates[i].UpdateID >= offset {
+				offset = updates[i].UpdateID + 1
+			}
+			if !p.dispatch(ctx, &updates[i]) {
+				return nil
+			}
+		}
+	}
+}
+
+// dispatch sends the update to its channel, false is returned if the context is done.
+func (p *UpdatesPoller) dispatch(ctx context.Context, u *update) bool {
+	if u.MessageReaction != nil {
+		select {
+		case <-ctx.Done():
+			return false
+		case p.reactionsCh <- *u.MessageReaction:
+			return true
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case p.updatesCh <- Update{Update: u.Update, MessageThreadID: u.messageThreadID}:
+		return true
+	}
+}
+
+func (p *UpdatesPoller) getUpdates(offset int) ([]update, error) {
+	rawAllowedUpdates, err := json.Marshal(allowedUpdates)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("offset", strconv.Itoa(offset))
+	params.Set("timeout", strconv.Itoa(p.timeout))
+	params.Set("allowed_updates", string(rawAllowedUpdates))
+
+	resp, err := p.api.MakeRequest("getUpdates", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var updates []update
+	if err := json.Unmarshal(resp.Result, &updates); err != nil {
+		return nil, err
+	}
+
+	var topicUpdates []topicUpdate
+	if err := json.Unmarshal(resp.Result, &topicUpdates); err != nil {
+		return nil, err
+	}
+	for i, u := range topicUpdates {
+		if u.Message != nil && u.Message.IsTopicMessage && i < len(updates) {
+			updates[i].messageThreadID = u.Message.MessageThreadID
+		}
+	}
+
+	return updates, nil
+}
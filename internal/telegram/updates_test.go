@@ -0,0 +1,57 @@
+package telegram_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/telegram"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestUpdatesPoller(t *testing.T) {
+	api, recorder := newTestAPI(t)
+	poller := telegram.NewUpdatesPoller(zap.NewNop(), api, 60)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, poller.Run(ctx))
+	}()
+
+	update := <-poller.Updates()
+	require.NotNil(t, update.Message)
+	assert.Equal(t, 5, update.UpdateID)
+	assert.Equal(t, "hi", update.Message.Text)
+	assert.Equal(t, 3, update.MessageThreadID)
+
+	// Replies in regular groups have a thread too, but they aren't posted to a topic
+	update = <-poller.Updates()
+	require.NotNil(t, update.Message)
+	assert.Equal(t, "reply", update.Message.Text)
+	assert.Zero(t, update.MessageThreadID)
+
+	reaction := <-poller.Reactions()
+	assert.Equal(t, int64(123), reaction.Chat.ID)
+	assert.Equal(t, 17, reaction.MessageID)
+	assert.Equal(t, "testuser", reaction.User.UserName)
+	assert.Equal(t, "👍", reaction.Emoji())
+
+	cancel()
+	wg.Wait()
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	// Reactions are received only if they are asked for, the next updates follow the received ones
+	requests := recorder.requests["getUpdates"]
+	require.GreaterOrEqual(t, len(requests), 2)
+	assert.Equal(t, "0", requests[0].Form.Get("offset"))
+	assert.Equal(t, "60", requests[0].Form.Get("timeout"))
+	assert.Contains(t, requests[0].Form.Get("allowed_updates"), `"message_reaction"`)
+	assert.Equal(t, "8", requests[1].Form.Get("offset"))
+}
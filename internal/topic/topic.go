@@ -0,0 +1,146 @@
+package topic
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/storage"
+)
+
+// Store represents a durable storage of telegram forum topics whatsapp
+// conversations are bridged to.
+type Store struct {
+	mu    sync.Mutex
+	file  *storage.JSONFile
+	state state
+}
+
+// state represents content of the file the topics are persisted to.
+type state struct {
+	// Chats is a list of telegram chats the topics are enabled in.
+	Chats []int64 `json:"chats"`
+
+	// Topics is a list of the topics created so far.
+	Topics []domain.TelegramTopic `json:"topics"`
+}
+
+// Opts represents options to create new instance of Store.
+type Opts struct {
+	// Path is a path to the file the topics are persisted to.
+	Path string
+}
+
+// New creates new instance of Store and loads previously saved topics.
+func New(opts *Opts) (*Store, error) {
+	s := &Store{
+		file: storage.NewJSONFile(opts.Path),
+		state: state{
+			Chats:  make([]int64, 0),
+			Topics: make([]domain.TelegramTopic, 0),
+		},
+	}
+
+	if err := s.file.Load(&s.state); err != nil {
+		return nil, fmt.Errorf("failed to load topics: %w", err)
+	}
+
+	return s, nil
+}
+
+// Enabled method returns true if whatsapp conversations are bridged into
+// topics of the chat.
+func (s *Store) Enabled(chatID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.enabled(chatID)
+}
+
+// SetEnabled method turns bridging into topics of the chat on and off.
+// The topics created so far are kept, so they are reused once it's turned on again.
+func (s *Store) SetEnabled(chatID int64, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.enabled(chatID) == enabled {
+		return nil
+	}
+
+	chats := make([]int64, 0, len(s.state.Chats)+1)
+	for _, id := range s.state.Chats {
+		if id != chatID {
+			chats = append(chats, id)
+		}
+	}
+	if enabled {
+		chats = append(chats, chatID)
+	}
+
+	return s.save(state{Chats: chats, Topics: s.state.Topics})
+}
+
+// Find method returns the topic of the whatsapp conversation in the chat.
+func (s *Store) Find(chatID int64, account, remoteJid string) (domain.TelegramTopic, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.state.Topics {
+		if t.ChatID == chatID && t.Account == account && t.RemoteJid == remoteJid {
+			return t, true
+		}
+	}
+
+	return domain.TelegramTopic{}, false
+}
+
+// FindByThread method returns the topic of the chat by its identifier.
+func (s *Store) FindByThread(chatID int64, threadID int) (domain.TelegramTopic, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.state.Topics {
+		if t.ChatID == chatID && t.ThreadID == threadID {
+			return t, true
+		}
+	}
+
+	return domain.TelegramTopic{}, false
+}
+
+// Add method saves the topic, a previous topic of the same whatsapp
+// conversation is replaced.
+func (s *Store) Add(topic domain.TelegramTopic) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	topics := make([]domain.TelegramTopic, 0, len(s.state.Topics)+1)
+	for _, t := range s.state.Topics {
+		if t.ChatID == topic.ChatID && t.Account == topic.Account && t.RemoteJid == topic.RemoteJid {
+			continue
+		}
+		topics = append(topics, t)
+	}
+	topics = append(topics, topic)
+
+	return s.save(state{Chats: s.state.Chats, Topics: topics})
+}
+
+func (s *Store) enabled(chatID int64) bool {
+	for _, id := range s.state.Chats {
+		if id == chatID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Store) save(newState state) error {
+	if err := s.file.Save(newState); err != nil {
+		return fmt.Errorf("failed to save topics: %w", err)
+	}
+	s.state = newState
+
+	return nil
+}
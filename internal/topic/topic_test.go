@@ -0,0 +1,91 @@
+package topic_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/topic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	testChatID := int64(-100123)
+	testTopic := domain.TelegramTopic{
+		ChatID:    testChatID,
+		ThreadID:  7,
+		Account:   domain.DefaultWhatsappAccount,
+		RemoteJid: "test-jid",
+	}
+
+	t.Run("enable and disable", func(t *testing.T) {
+		store, err := topic.New(&topic.Opts{Path: filepath.Join(t.TempDir(), "topics.json")})
+		require.NoError(t, err)
+
+		assert.False(t, store.Enabled(testChatID))
+
+		require.NoError(t, store.SetEnabled(testChatID, true))
+		require.NoError(t, store.SetEnabled(testChatID, true))
+		assert.True(t, store.Enabled(testChatID))
+		assert.False(t, store.Enabled(42))
+
+		require.NoError(t, store.SetEnabled(testChatID, false))
+		assert.False(t, store.Enabled(testChatID))
+	})
+
+	t.Run("find topics", func(t *testing.T) {
+		store, err := topic.New(&topic.Opts{Path: filepath.Join(t.TempDir(), "topics.json")})
+		require.NoError(t, err)
+
+		require.NoError(t, store.Add(testTopic))
+
+		got, ok := store.Find(testChatID, domain.DefaultWhatsappAccount, "test-jid")
+		require.True(t, ok)
+		assert.Equal(t, testTopic, got)
+
+		got, ok = store.FindByThread(testChatID, 7)
+		require.True(t, ok)
+		assert.Equal(t, testTopic, got)
+
+		_, ok = store.Find(testChatID, "work", "test-jid")
+		assert.False(t, ok)
+		_, ok = store.FindByThread(42, 7)
+		assert.False(t, ok)
+	})
+
+	t.Run("topic of the conversation is replaced", func(t *testing.T) {
+		store, err := topic.New(&topic.Opts{Path: filepath.Join(t.TempDir(), "topics.json")})
+		require.NoError(t, err)
+
+		require.NoError(t, store.Add(testTopic))
+
+		recreated := testTopic
+		recreated.ThreadID = 8
+		require.NoError(t, store.Add(recreated))
+
+		got, ok := store.Find(testChatID, domain.DefaultWhatsappAccount, "test-jid")
+		require.True(t, ok)
+		assert.Equal(t, 8, got.ThreadID)
+
+		_, ok = store.FindByThread(testChatID, 7)
+		assert.False(t, ok)
+	})
+
+	t.Run("topics are persisted", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "topics.json")
+		store, err := topic.New(&topic.Opts{Path: path})
+		require.NoError(t, err)
+
+		require.NoError(t, store.SetEnabled(testChatID, true))
+		require.NoError(t, store.Add(testTopic))
+
+		reloaded, err := topic.New(&topic.Opts{Path: path})
+		require.NoError(t, err)
+
+		assert.True(t, reloaded.Enabled(testChatID))
+		got, ok := reloaded.FindByThread(testChatID, 7)
+		require.True(t, ok)
+		assert.Equal(t, testTopic, got)
+	})
+}
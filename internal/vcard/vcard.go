@@ -0,0 +1,220 @@
+package vcard
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	beginLine = "BEGIN:VCARD"
+	endLine   = "END:VCARD"
+
+	// lineBreak is a line break of the encoded cards, whatsapp uses bare line feeds
+	// instead of CRLF required by the RFC.
+	lineBreak = "\n"
+)
+
+// ErrInvalidCard is returned when the text isn't a vCard.
+var ErrInvalidCard = errors.New("invalid vcard")
+
+// Phone represents a phone number of the contact.
+type Phone struct {
+	// Number is a phone number as it's written in the card.
+	Number string
+
+	// WhatsappID is a whatsapp identifier of the number, it's the number without
+	// punctuation and it's known only if the contact uses whatsapp.
+	WhatsappID string
+}
+
+// Card represents a contact card, only the fields bridged between whatsapp and telegram
+// are supported, other fields are ignored.
+type Card struct {
+	// FullName is a formatted name of the contact.
+	FullName string
+
+	// FirstName is a given name of the contact.
+	FirstName string
+
+	// LastName is a family name of the contact.
+	LastName string
+
+	// Phones is a list of phone numbers of the contact.
+	Phones []Phone
+}
+
+// Name returns a name of the contact to show, it's made of the first and the last names
+// if the card has no formatted name.
+func (c *Card) Name() string {
+	if c.FullName != "" {
+		return c.FullName
+	}
+
+	return strings.TrimSpace(c.FirstName + " " + c.LastName)
+}
+
+// Decode parses the first vCard of the text.
+func Decode(text string) (Card, error) {
+	lines := unfold(text)
+	begin := -1
+	for i, line := range lines {
+		if strings.EqualFold(line, beginLine) {
+			begin = i
+
+			break
+		}
+	}
+	if begin == -1 {
+		return Card{}, ErrInvalidCard
+	}
+
+	var card Card
+	for _, line := range lines[begin+1:] {
+		if strings.EqualFold(line, endLine) {
+			return card, nil
+		}
+
+		name, params, value, ok := parseProperty(line)
+		if !ok {
+			continue
+		}
+
+		switch name {
+		case "FN":
+			card.FullName = unescape(value)
+		case "N":
+			parts := splitValue(value)
+			card.LastName = parts[0]
+			if len(parts) > 1 {
+				card.FirstName = parts[1]
+			}
+		case "TEL":
+			card.Phones = append(card.Phones, Phone{
+				Number:     unescape(value),
+				WhatsappID: params["WAID"],
+			})
+		}
+	}
+
+	// The card isn't terminated
+	return Card{}, ErrInvalidCard
+}
+
+// Encode returns vCard 3.0 of the contact.
+func Encode(card Card) string {
+	fullName := card.Name()
+	lines := []string{
+		beginLine,
+		"VERSION:3.0",
+		"N:" + escape(card.LastName) + ";" + escape(card.FirstName) + ";;;",
+		"FN:" + escape(fullName),
+	}
+	for _, phone := range card.Phones {
+		tel := "TEL;type=CELL;type=VOICE"
+		if phone.WhatsappID != "" {
+			tel += ";waid=" + phone.WhatsappID
+		}
+		lines = append(lines, tel+":"+escape(phone.Number))
+	}
+	lines = append(lines, endLine)
+
+	return strings.Join(lines, lineBreak)
+}
+
+// WhatsappID returns whatsapp identifier of the phone number, it's the digits of the number.
+func WhatsappID(number string) string {
+	var b strings.Builder
+	for _, r := range number {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// unfold splits the text to lines joining the folded ones, a folded line starts
+// with a space or a tab.
+func unfold(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(lines) != 0 {
+			lines[len(lines)-1] += line[1:]
+
+			continue
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+
+	return lines
+}
+
+// parseProperty splits the content line to upper-cased property name without the group,
+// its parameters and the value.
+func parseProperty(line string) (string, map[string]string, string, bool) {
+	i := strings.IndexByte(line, ':')
+	if i == -1 {
+		return "", nil, "", false
+	}
+
+	parts := strings.Split(line[:i], ";")
+	name := strings.ToUpper(parts[0])
+	// Properties may be grouped, e.g. item1.TEL
+	if j := strings.LastIndexByte(name, '.'); j != -1 {
+		name = name[j+1:]
+	}
+
+	params := make(map[string]string, len(parts)-1)
+	for _, param := range parts[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+	}
+
+	return name, params, line[i+1:], true
+}
+
+// splitValue splits the structured value to unescaped components.
+func splitValue(value string) []string {
+	var (
+		parts   []string
+		current strings.Builder
+		escaped bool
+	)
+	for _, r := range value {
+		switch {
+		case escaped:
+			current.WriteString(unescape(`\` + string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ';':
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	return append(parts, current.String())
+}
+
+var (
+	escaper   = strings.NewReplacer(`\`, `\\`, "\n", `\n`, ",", `\,`, ";", `\;`)
+	unescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";")
+)
+
+func escape(value string) string {
+	return escaper.Replace(value)
+}
+
+func unescape(value string) string {
+	return unescaper.Replace(value)
+}
@@ -0,0 +1,72 @@
+package vcard_test
+
+import (
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/vcard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode(t *testing.T) {
+	t.Run("whatsapp card", func(t *testing.T) {
+		card, err := vcard.Decode("BEGIN:VCARD\nVERSION:3.0\nN:Smith;Alice;;;\nFN:Alice Smith\n" +
+			"item1.TEL;waid=15551234567:+1 555-123-4567\nitem1.X-ABLabel:Mobile\n" +
+			"TEL;type=HOME:+1 555 000 0000\nEND:VCARD")
+		require.NoError(t, err)
+		assert.Equal(t, vcard.Card{
+			FullName:  "Alice Smith",
+			FirstName: "Alice",
+			LastName:  "Smith",
+			Phones: []vcard.Phone{
+				{Number: "+1 555-123-4567", WhatsappID: "15551234567"},
+				{Number: "+1 555 000 0000"},
+			},
+		}, card)
+	})
+
+	t.Run("folded lines and escaping", func(t *testing.T) {
+		card, err := vcard.Decode("junk\r\nbegin:vcard\r\nversion:4.0\r\nn:O\\;Neil;Bob\\, Jr.\r\n" +
+			"fn:Bob O\\;Neil\\,\r\n  Jr.\r\nend:vcard\r\n")
+		require.NoError(t, err)
+		assert.Equal(t, "Bob O;Neil, Jr.", card.FullName)
+		assert.Equal(t, "O;Neil", card.LastName)
+		assert.Equal(t, "Bob, Jr.", card.FirstName)
+		assert.Empty(t, card.Phones)
+	})
+
+	t.Run("invalid card", func(t *testing.T) {
+		_, err := vcard.Decode("Alice +1 555-123-4567")
+		assert.ErrorIs(t, err, vcard.ErrInvalidCard)
+
+		_, err = vcard.Decode("BEGIN:VCARD\nFN:Alice")
+		assert.ErrorIs(t, err, vcard.ErrInvalidCard)
+	})
+}
+
+func TestEncode(t *testing.T) {
+	card := vcard.Card{
+		FirstName: "Alice",
+		LastName:  "Smith, Jr.",
+		Phones:    []vcard.Phone{{Number: "+1 555-123-4567", WhatsappID: "15551234567"}},
+	}
+
+	encoded := vcard.Encode(card)
+	assert.Equal(t, "BEGIN:VCARD\nVERSION:3.0\nN:Smith\\, Jr.;Alice;;;\nFN:Alice Smith\\, Jr.\n"+
+		"TEL;type=CELL;type=VOICE;waid=15551234567:+1 555-123-4567\nEND:VCARD", encoded)
+
+	decoded, err := vcard.Decode(encoded)
+	require.NoError(t, err)
+	card.FullName = "Alice Smith, Jr."
+	assert.Equal(t, card, decoded)
+}
+
+func TestCardName(t *testing.T) {
+	assert.Equal(t, "Alice Smith", (&vcard.Card{FullName: "Alice Smith", FirstName: "Al"}).Name())
+	assert.Equal(t, "Alice Smith", (&vcard.Card{FirstName: "Alice", LastName: "Smith"}).Name())
+	assert.Equal(t, "Smith", (&vcard.Card{LastName: "Smith"}).Name())
+}
+
+func TestWhatsappID(t *testing.T) {
+	assert.Equal(t, "15551234567", vcard.WhatsappID("+1 (555) 123-45-67"))
+}
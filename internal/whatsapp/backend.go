@@ -0,0 +1,132 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Rhymen/go-whatsapp"
+	"github.com/dstdfx/twbridge/internal/domain"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultWhatsappClientMajorVersion = 2
+	defaultWhatsappClientMinorVersion = 2134
+	defaultWhatsappClientPatchVersion = 10
+	defaultWhatsappConnTimeout        = 20 * time.Second
+
+	// defaultQRCodeTimeout is a time whatsapp keeps the QR-code valid for.
+	defaultQRCodeTimeout = 20 * time.Second
+
+	// defaultQRCodeRefreshes is a number of QR-codes to show before giving up.
+	defaultQRCodeRefreshes = 6
+)
+
+// qrCodeTimeoutErrMsg is a message of the error go-whatsapp returns once
+// the QR-code has expired, the library doesn't provide a typed error for it.
+const qrCodeTimeoutErrMsg = "qr code scan timed out"
+
+var (
+	errQRCodeExpired = errors.New("QR-code has expired")
+	errLoginTimedOut = errors.New("QR-code hasn't been scanned")
+)
+
+type loginResult struct {
+	session whatsapp.Session
+	err     error
+}
+
+// Backend represents a backend that establishes sessions via WhatsApp Web protocol.
+type Backend struct {
+	log *zap.Logger
+}
+
+// NewBackend returns new instance of Backend.
+func NewBackend(log *zap.Logger) *Backend {
+	return &Backend{log: log}
+}
+
+// Login method establishes a new whatsapp connection and authenticates it
+// via QR-code challenge. The QR-code is refreshed a few times until it's scanned.
+func (b *Backend) Login(ctx context.Context,
+	opts *domain.WhatsappSessionOpts,
+	qrCodes chan<- domain.WhatsappQRCode) (domain.WhatsappClient, error) {
+	for i := 0; i < defaultQRCodeRefreshes; i++ {
+		client, err := b.login(ctx, opts, qrCodes)
+		if !errors.Is(err, errQRCodeExpired) {
+			return client, err
+		}
+
+		b.log.Debug("QR-code has expired, refreshing it", zap.Int64("chat_id", opts.ChatID))
+	}
+
+	return nil, errLoginTimedOut
+}
+
+func (b *Backend) login(ctx context.Context,
+	opts *domain.WhatsappSessionOpts,
+	qrCodes chan<- domain.WhatsappQRCode) (domain.WhatsappClient, error) {
+	wac, err := whatsapp.NewConnWithOptions(&whatsapp.Options{
+		Timeout: defaultWhatsappConnTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to establish new whatsapp connection: %w", err)
+	}
+
+	// Initialize new whatsapp client
+	client := NewClient(wac)
+
+	// Initialize whatsapp events provider
+	eventsProvider := NewEventsProvider(b.log, &Opts{
+		ChatID:         opts.ChatID,
+		Account:        opts.Account,
+		OutgoingEvents: opts.Events,
+		WhatsappClient: client,
+	})
+	wac.AddHandler(eventsProvider)
+	wac.SetClientVersion(
+		defaultWhatsappClientMajorVersion,
+		defaultWhatsappClientMinorVersion,
+		defaultWhatsappClientPatchVersion)
+
+	// TODO: save and restore sessions
+
+	// go-whatsapp login can't be interrupted, so it's run in background
+	// and the connection is dropped once the login is cancelled
+	qr := make(chan string, 1)
+	result := make(chan loginResult, 1)
+	go func() {
+		session, err := wac.Login(qr)
+		result <- loginResult{session: session, err: err}
+	}()
+
+	for {
+		select {
+		case content := <-qr:
+			qrCodes <- domain.WhatsappQRCode{
+				Content: content,
+				Timeout: defaultQRCodeTimeout,
+			}
+		case res := <-result:
+			if res.err != nil {
+				if res.err.Error() == qrCodeTimeoutErrMsg {
+					return nil, errQRCodeExpired
+				}
+
+				return nil, fmt.Errorf("failed to login to whatsapp: %w", res.err)
+			}
+
+			b.log.Debug("login successful", zap.String("client_id", res.session.ClientId))
+
+			return client, nil
+		case <-ctx.Done():
+			if _, err := wac.Disconnect(); err != nil {
+				b.log.Error("failed to disconnect cancelled login", zap.Error(err))
+			}
+
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -1,7 +1,11 @@
 package whatsapp
 
 import (
+	"bytes"
 	"errors"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/Rhymen/go-whatsapp"
 	"github.com/dstdfx/twbridge/internal/domain"
@@ -57,10 +61,38 @@ func (c *Client) Send(msg domain.WhatsappMessage) (err error) {
 		textMessage := msg.(*domain.WhatsappTextMessage)
 		whatsappMessage = whatsapp.TextMessage{
 			Info: whatsapp.MessageInfo{
+				Id:        textMessage.ID,
 				RemoteJid: textMessage.RemoteJid,
 			},
 			Text: textMessage.Text,
 		}
+	case domain.WhatsappLocationMessageType:
+		locationMessage := msg.(*domain.WhatsappLocationMessage)
+		whatsappMessage = whatsapp.LocationMessage{
+			Info: whatsapp.MessageInfo{
+				Id:        locationMessage.ID,
+				RemoteJid: locationMessage.RemoteJid,
+			},
+			DegreesLatitude:  locationMessage.Location.Latitude,
+			DegreesLongitude: locationMessage.Location.Longitude,
+			Name:             locationMessage.Location.Name,
+			Address:          locationMessage.Location.Address,
+		}
+	case domain.WhatsappContactCardMessageType:
+		contactCardMessage := msg.(*domain.WhatsappContactCardMessage)
+		whatsappMessage = whatsapp.ContactMessage{
+			Info: whatsapp.MessageInfo{
+				Id:        contactCardMessage.ID,
+				RemoteJid: contactCardMessage.RemoteJid,
+			},
+			DisplayName: contactCardMessage.ContactCard.DisplayName,
+			Vcard:       contactCardMessage.ContactCard.Vcard,
+		}
+	case domain.WhatsappMediaMessageType:
+		whatsappMessage = mediaMessage(msg.(*domain.WhatsappMediaMessage))
+	case domain.WhatsappReactionMessageType:
+		// The web client protocol doesn't have reactions
+		return domain.ErrWhatsappNotSupported
 	default:
 		return ErrUnsupportedMessageType
 	}
@@ -69,3 +101,153 @@ func (c *Client) Send(msg domain.WhatsappMessage) (err error) {
 
 	return
 }
+
+// mediaMessage returns whatsapp message with the photo or the document.
+func mediaMessage(msg *domain.WhatsappMediaMessage) interface{} {
+	info := whatsapp.MessageInfo{
+		Id:        msg.ID,
+		RemoteJid: msg.RemoteJid,
+	}
+
+	if msg.Media.Kind == domain.WhatsappMediaImage {
+		return whatsapp.ImageMessage{
+			Info:    info,
+			Caption: msg.Media.Caption,
+			Type:    msg.Media.MimeType,
+			Content: bytes.NewReader(msg.Data),
+		}
+	}
+
+	// The web client protocol doesn't have captions of documents
+	return whatsapp.DocumentMessage{
+		Info:     info,
+		Title:    msg.Media.FileName,
+		FileName: msg.Media.FileName,
+		Type:     msg.Media.MimeType,
+		Content:  bytes.NewReader(msg.Data),
+	}
+}
+
+// RecentChats method returns up to count chats of the store, the most recent first.
+func (c *Client) RecentChats(count int) ([]domain.WhatsappChat, error) {
+	chats := make([]domain.WhatsappChat, 0)
+	if c.wc.Store == nil {
+		return chats, nil
+	}
+
+	for jid, chat := range c.wc.Store.Chats {
+		lastMessageAt, _ := strconv.ParseInt(chat.LastMessageTime, 10, 64)
+		unread, _ := strconv.Atoi(chat.Unread)
+		chats = append(chats, domain.WhatsappChat{
+			Jid:           jid,
+			Name:          chat.Name,
+			LastMessageAt: time.Unix(lastMessageAt, 0),
+			Unread:        unread,
+		})
+	}
+	sort.Slice(chats, func(i, j int) bool {
+		return chats[i].LastMessageAt.After(chats[j].LastMessageAt)
+	})
+	if len(chats) > count {
+		chats = chats[:count]
+	}
+
+	return chats, nil
+}
+
+// History method loads up to count last text messages of the chat.
+func (c *Client) History(remoteJid string, count int) ([]domain.WhatsappHistoryMessage, error) {
+	collector := &historyCollector{contacts: c.GetContacts()}
+	if err := c.wc.LoadChatMessages(remoteJid, count, "", true, false, collector); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(collector.messages, func(i, j int) bool {
+		return collector.messages[i].Timestamp.Before(collector.messages[j].Timestamp)
+	})
+
+	return collector.messages, nil
+}
+
+// MarkRead method marks the chat as read up to its last message.
+func (c *Client) MarkRead(remoteJid string) error {
+	messages, err := c.History(remoteJid, 1)
+	if err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	if _, err := c.wc.Read(remoteJid, messages[0].ID); err != nil {
+		return err
+	}
+
+	if chat, ok := c.wc.Store.Chats[remoteJid]; ok {
+		chat.Unread = "0"
+		c.wc.Store.Chats[remoteJid] = chat
+	}
+
+	return nil
+}
+
+// SubscribePresence method subscribes to presence updates of the contact,
+// they are handled by the events provider.
+func (c *Client) SubscribePresence(remoteJid string) error {
+	_, err := c.wc.SubscribePresence(remoteJid)
+
+	return err
+}
+
+// SendPresence method lets the conversation know whether the user is composing a message.
+func (c *Client) SendPresence(remoteJid string, presence domain.WhatsappPresence) error {
+	_, err := c.wc.Presence(remoteJid, whatsapp.Presence(presence))
+
+	return err
+}
+
+// Revoke method deletes the message sent to the conversation for everyone.
+func (c *Client) Revoke(remoteJid, messageID string) error {
+	_, err := c.wc.RevokeMessage(remoteJid, messageID, true)
+
+	return err
+}
+
+// Edit method isn't supported, whatsapp web doesn't allow editing messages.
+func (c *Client) Edit(remoteJid, messageID, text string) error {
+	return domain.ErrWhatsappNotSupported
+}
+
+// historyCollector is a handler that collects text messages loaded from the chat history.
+type historyCollector struct {
+	contacts map[string]domain.WhatsappContact
+	messages []domain.WhatsappHistoryMessage
+}
+
+// ShouldCallSynchronously method makes the messages handled before loading returns.
+func (hc *historyCollector) ShouldCallSynchronously() bool {
+	return true
+}
+
+// HandleError method ignores errors, loading returns them anyway.
+func (hc *historyCollector) HandleError(error) {}
+
+// HandleTextMessage method collects the text message.
+func (hc *historyCollector) HandleTextMessage(message whatsapp.TextMessage) {
+	senderJid := message.Info.RemoteJid
+	if message.Info.SenderJid != "" {
+		senderJid = message.Info.SenderJid
+	}
+	senderName := message.Info.PushName
+	if contact, ok := hc.contacts[senderJid]; ok && contact.Name != "" {
+		senderName = contact.Name
+	}
+
+	hc.messages = append(hc.messages, domain.WhatsappHistoryMessage{
+		ID:         message.Info.Id,
+		RemoteJid:  message.Info.RemoteJid,
+		SenderName: senderName,
+		FromMe:     message.Info.FromMe,
+		Text:       message.Text,
+		Timestamp:  time.Unix(int64(message.Info.Timestamp), 0),
+	})
+}
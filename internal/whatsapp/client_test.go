@@ -2,6 +2,7 @@ package whatsapp_test
 
 import (
 	"testing"
+	"time"
 
 	whatsappsdk "github.com/Rhymen/go-whatsapp"
 	"github.com/dstdfx/twbridge/internal/domain"
@@ -54,3 +55,25 @@ func TestClient_GetContacts(t *testing.T) {
 	gotContacts := testClient.GetContacts()
 	assert.Equal(t, expectedContacts, gotContacts)
 }
+
+var _ domain.WhatsappBackend = &whatsapp.Backend{}
+
+func TestClient_RecentChats(t *testing.T) {
+	testConn := &whatsappsdk.Conn{
+		Store: &whatsappsdk.Store{
+			Chats: map[string]whatsappsdk.Chat{
+				"1": {Jid: "1", Name: "test1-name", LastMessageTime: "1648720800", Unread: "3"},
+				"2": {Jid: "2", Name: "test2-name", LastMessageTime: "1648724400"},
+				"3": {Jid: "3", Name: "test3-name", LastMessageTime: "1648717200"},
+			},
+		},
+	}
+	testClient := whatsapp.NewClient(testConn)
+
+	chats, err := testClient.RecentChats(2)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.WhatsappChat{
+		{Jid: "2", Name: "test2-name", LastMessageAt: time.Unix(1648724400, 0)},
+		{Jid: "1", Name: "test1-name", LastMessageAt: time.Unix(1648720800, 0), Unread: 3},
+	}, chats)
+}
@@ -1,11 +1,13 @@
 package whatsapp
 
 import (
+	"encoding/json"
 	"errors"
 	"strings"
 	"time"
 
 	"github.com/Rhymen/go-whatsapp"
+	"github.com/Rhymen/go-whatsapp/binary/proto"
 	"github.com/dstdfx/twbridge/internal/domain"
 	"go.uber.org/zap"
 )
@@ -15,13 +17,32 @@ const (
 	restoreInterval = time.Second
 )
 
+const (
+	presenceJSONTag   = "Presence"
+	legacyUserJidHost = "@c.us"
+	userJidHost       = "@s.whatsapp.net"
+)
+
+// presenceUpdate represents a presence update sent by whatsapp web as a JSON message,
+// e.g. ["Presence",{"id":"1234567890@c.us","type":"composing"}].
+type presenceUpdate struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	LastSeen int64  `json:"t"`
+}
+
 // EventsProvider represents whatsapp events provider.
 type EventsProvider struct {
 	log            *zap.Logger
 	startAt        int64
 	chatID         int64
+	account        string
 	whatsappClient domain.WhatsappClient
 	outgoingEvents chan domain.Event
+
+	// documentSizes are sizes of the documents by their message identifiers, the sizes are
+	// taken from the raw messages that are handled right before the documents.
+	documentSizes map[string]int64
 }
 
 // Opts represents options to create new instance of EventsProvider.
@@ -29,6 +50,9 @@ type Opts struct {
 	// ChatID is identifier of telegram chat.
 	ChatID int64
 
+	// Account is a name of the whatsapp account the events belong to.
+	Account string
+
 	// OutgoingEvents is a channel to send events to.
 	OutgoingEvents chan domain.Event
 
@@ -41,9 +65,11 @@ func NewEventsProvider(log *zap.Logger, opts *Opts) *EventsProvider {
 	return &EventsProvider{
 		log:            log,
 		chatID:         opts.ChatID,
+		account:        opts.Account,
 		startAt:        time.Now().Unix(),
 		outgoingEvents: opts.OutgoingEvents,
 		whatsappClient: opts.WhatsappClient,
+		documentSizes:  make(map[string]int64),
 	}
 }
 
@@ -60,18 +86,25 @@ func (wh *EventsProvider) HandleError(err error) {
 
 	switch err.(type) { // nolint
 	case *whatsapp.ErrConnectionClosed, *whatsapp.ErrConnectionFailed:
-		if !wh.restoreSession() {
-			wh.outgoingEvents <- &domain.DisconnectEvent{ChatID: wh.chatID}
-		}
+		wh.handleConnectionLoss()
 	default:
 		if errors.Is(err, whatsapp.ErrConnectionTimeout) {
-			if !wh.restoreSession() {
-				wh.outgoingEvents <- &domain.DisconnectEvent{ChatID: wh.chatID}
-			}
+			wh.handleConnectionLoss()
 		}
 	}
 }
 
+func (wh *EventsProvider) handleConnectionLoss() {
+	if !wh.restoreSession() {
+		wh.outgoingEvents <- &domain.DisconnectEvent{ChatID: wh.chatID, Account: wh.account}
+
+		return
+	}
+
+	// Let the handler know that it's possible to send messages again
+	wh.outgoingEvents <- &domain.RestoreEvent{ChatID: wh.chatID, Account: wh.account}
+}
+
 func (wh *EventsProvider) restoreSession() (restored bool) {
 	for i := 1; i <= restoreAttempts; i++ {
 		wh.log.Debug("trying to restore whatsapp session...",
@@ -117,20 +150,223 @@ func (wh *EventsProvider) HandleTextMessage(message whatsapp.TextMessage) {
 		zap.String("remote_jid", message.Info.RemoteJid),
 		zap.String("sender_jid", message.Info.SenderJid))
 
-	// Get the contact name from the contacts store
-	var contactName string
-	contacts := wh.whatsappClient.GetContacts()
-	contact, ok := contacts[message.Info.RemoteJid]
-	if !ok {
-		contactName = "<unknown>"
-	} else {
-		contactName = contact.Name
-	}
-
 	wh.outgoingEvents <- &domain.TextMessageEvent{
 		WhatsappRemoteJid:  message.Info.RemoteJid,
-		WhatsappSenderName: contactName,
+		WhatsappSenderName: wh.contactName(message.Info.RemoteJid),
 		Text:               message.Text,
 		ChatID:             wh.chatID,
+		Account:            wh.account,
+		WhatsappMessageID:  message.Info.Id,
+		WhatsappSenderJid:  message.Info.SenderJid,
+	}
+}
+
+// HandleLocationMessage method is called when new location message is received.
+func (wh *EventsProvider) HandleLocationMessage(message whatsapp.LocationMessage) {
+	if message.Info.Timestamp < uint64(wh.startAt) || message.Info.FromMe {
+		return
 	}
+
+	wh.log.Debug("got location message",
+		zap.Uint64("timestamp", message.Info.Timestamp),
+		zap.String("remote_jid", message.Info.RemoteJid),
+		zap.String("sender_jid", message.Info.SenderJid))
+
+	wh.outgoingEvents <- &domain.LocationEvent{
+		ChatID:             wh.chatID,
+		WhatsappRemoteJid:  message.Info.RemoteJid,
+		WhatsappSenderName: wh.contactName(message.Info.RemoteJid),
+		WhatsappSenderJid:  message.Info.SenderJid,
+		WhatsappMessageID:  message.Info.Id,
+		Location: domain.WhatsappLocation{
+			Latitude:  message.DegreesLatitude,
+			Longitude: message.DegreesLongitude,
+			Name:      message.Name,
+			Address:   message.Address,
+		},
+		Account: wh.account,
+	}
+}
+
+// HandleLiveLocationMessage method is called when a live location is shared or moved.
+func (wh *EventsProvider) HandleLiveLocationMessage(message whatsapp.LiveLocationMessage) {
+	if message.Info.Timestamp < uint64(wh.startAt) || message.Info.FromMe {
+		return
+	}
+
+	wh.log.Debug("got live location message",
+		zap.Uint64("timestamp", message.Info.Timestamp),
+		zap.String("remote_jid", message.Info.RemoteJid),
+		zap.String("sender_jid", message.Info.SenderJid),
+		zap.Int64("sequence_number", message.SequenceNumber))
+
+	wh.outgoingEvents <- &domain.LocationEvent{
+		ChatID:             wh.chatID,
+		WhatsappRemoteJid:  message.Info.RemoteJid,
+		WhatsappSenderName: wh.contactName(message.Info.RemoteJid),
+		WhatsappSenderJid:  message.Info.SenderJid,
+		WhatsappMessageID:  message.Info.Id,
+		Location: domain.WhatsappLocation{
+			Latitude:  message.DegreesLatitude,
+			Longitude: message.DegreesLongitude,
+		},
+		Live:         true,
+		LiveSequence: message.SequenceNumber,
+		Account:      wh.account,
+	}
+}
+
+// HandleContactMessage method is called when new contact card is received.
+func (wh *EventsProvider) HandleContactMessage(message whatsapp.ContactMessage) {
+	if message.Info.Timestamp < uint64(wh.startAt) || message.Info.FromMe {
+		return
+	}
+
+	wh.log.Debug("got contact message",
+		zap.Uint64("timestamp", message.Info.Timestamp),
+		zap.String("remote_jid", message.Info.RemoteJid),
+		zap.String("sender_jid", message.Info.SenderJid))
+
+	wh.outgoingEvents <- &domain.ContactCardEvent{
+		ChatID:             wh.chatID,
+		WhatsappRemoteJid:  message.Info.RemoteJid,
+		WhatsappSenderName: wh.contactName(message.Info.RemoteJid),
+		WhatsappSenderJid:  message.Info.SenderJid,
+		WhatsappMessageID:  message.Info.Id,
+		ContactCard: domain.WhatsappContactCard{
+			DisplayName: message.DisplayName,
+			Vcard:       message.Vcard,
+		},
+		Account: wh.account,
+	}
+}
+
+// HandleDocumentMessage method is called when new document is received, the document
+// is downloaded before it's passed to the bridge.
+func (wh *EventsProvider) HandleDocumentMessage(message whatsapp.DocumentMessage) {
+	if message.Info.Timestamp < uint64(wh.startAt) || message.Info.FromMe {
+		return
+	}
+
+	wh.log.Debug("got document message",
+		zap.Uint64("timestamp", message.Info.Timestamp),
+		zap.String("remote_jid", message.Info.RemoteJid),
+		zap.String("sender_jid", message.Info.SenderJid),
+		zap.String("file_name", message.FileName))
+
+	document := domain.WhatsappDocument{
+		FileName: message.FileName,
+		MimeType: message.Type,
+		Size:     wh.documentSizes[message.Info.Id],
+	}
+	delete(wh.documentSizes, message.Info.Id)
+	// The title is the name of the file unless the sender has changed it
+	if message.Title != message.FileName {
+		document.Caption = message.Title
+	}
+	// The document is bridged anyway, so it's not lost if it can't be downloaded
+	data, err := message.Download()
+	if err != nil {
+		wh.log.Error("failed to download document", zap.Error(err))
+	} else {
+		document.Data = data
+		document.Size = int64(len(data))
+	}
+
+	wh.outgoingEvents <- &domain.DocumentEvent{
+		ChatID:             wh.chatID,
+		WhatsappRemoteJid:  message.Info.RemoteJid,
+		WhatsappSenderName: wh.contactName(message.Info.RemoteJid),
+		WhatsappSenderJid:  message.Info.SenderJid,
+		WhatsappMessageID:  message.Info.Id,
+		Document:           document,
+		Account:            wh.account,
+	}
+}
+
+// contactName returns a name of the contact from the contacts store.
+func (wh *EventsProvider) contactName(remoteJid string) string {
+	contact, ok := wh.whatsappClient.GetContacts()[remoteJid]
+	if !ok {
+		return "<unknown>"
+	}
+
+	return contact.Name
+}
+
+// HandleRawMessage method is called when any message is received, only revokes are handled
+// since they are not passed to the other handlers. Sizes of the documents are remembered,
+// the documents passed to HandleDocumentMessage don't have them.
+func (wh *EventsProvider) HandleRawMessage(message *proto.WebMessageInfo) {
+	if message.GetMessageTimestamp() < uint64(wh.startAt) || message.GetKey().GetFromMe() {
+		return
+	}
+
+	if document := message.GetMessage().GetDocumentMessage(); document != nil {
+		wh.documentSizes[message.GetKey().GetId()] = int64(document.GetFileLength())
+
+		return
+	}
+
+	protocolMessage := message.GetMessage().GetProtocolMessage()
+	if protocolMessage == nil || protocolMessage.GetType() != proto.ProtocolMessage_REVOKE {
+		return
+	}
+
+	wh.log.Debug("got revoke message",
+		zap.String("remote_jid", message.GetKey().GetRemoteJid()),
+		zap.String("message_id", protocolMessage.GetKey().GetId()))
+
+	wh.outgoingEvents <- &domain.RevokeEvent{
+		ChatID:            wh.chatID,
+		Account:           wh.account,
+		WhatsappRemoteJid: message.GetKey().GetRemoteJid(),
+		WhatsappMessageID: protocolMessage.GetKey().GetId(),
+	}
+}
+
+// HandleJsonMessage method is called when a JSON message is received, only presence updates are handled.
+func (wh *EventsProvider) HandleJsonMessage(message string) { // nolint
+	var raw []json.RawMessage
+	if err := json.Unmarshal([]byte(message), &raw); err != nil || len(raw) != 2 {
+		return
+	}
+
+	var tag string
+	if err := json.Unmarshal(raw[0], &tag); err != nil || tag != presenceJSONTag {
+		return
+	}
+
+	var update presenceUpdate
+	if err := json.Unmarshal(raw[1], &update); err != nil || update.ID == "" {
+		wh.log.Debug("failed to decode presence update", zap.String("message", message))
+
+		return
+	}
+
+	presence := domain.WhatsappPresence(update.Type)
+	switch presence {
+	case domain.WhatsappPresenceAvailable, domain.WhatsappPresenceUnavailable,
+		domain.WhatsappPresenceComposing, domain.WhatsappPresencePaused:
+	default:
+		return
+	}
+
+	// The store keeps contacts with the new user host, presence updates come with the old one
+	remoteJid := update.ID
+	if strings.HasSuffix(remoteJid, legacyUserJidHost) {
+		remoteJid = strings.TrimSuffix(remoteJid, legacyUserJidHost) + userJidHost
+	}
+
+	event := &domain.PresenceEvent{
+		ChatID:            wh.chatID,
+		WhatsappRemoteJid: remoteJid,
+		Presence:          presence,
+		Account:           wh.account,
+	}
+	if update.LastSeen != 0 {
+		event.LastSeen = time.Unix(update.LastSeen, 0)
+	}
+
+	wh.outgoingEvents <- event
 }
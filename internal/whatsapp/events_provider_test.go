@@ -7,6 +7,7 @@ import (
 	"time"
 
 	whatsappsdk "github.com/Rhymen/go-whatsapp"
+	"github.com/Rhymen/go-whatsapp/binary/proto"
 	"github.com/dstdfx/twbridge/internal/domain"
 	"github.com/dstdfx/twbridge/internal/whatsapp"
 	"github.com/dstdfx/twbridge/internal/whatsapp/mocks"
@@ -38,6 +39,8 @@ func TestEventsProvider(t *testing.T) {
 		whatsappClientMock := &mocks.WhatsappClient{}
 		whatsappClientMock.On("Restore").Return(nil)
 		eventsProvider := whatsapp.NewEventsProvider(zap.NewNop(), &whatsapp.Opts{
+			ChatID:         testChatID,
+			Account:        "work",
 			OutgoingEvents: outgoingEvents,
 			WhatsappClient: whatsappClientMock,
 		})
@@ -45,6 +48,12 @@ func TestEventsProvider(t *testing.T) {
 		// Call method in order to emulate whatsapp event
 		eventsProvider.HandleError(whatsappsdk.ErrConnectionTimeout)
 		whatsappClientMock.AssertCalled(t, "Restore")
+
+		// Check that restore event has been sent to outgoing channel
+		gotEvent := <-outgoingEvents
+		assert.Equal(t, domain.RestoreEventType, gotEvent.Type())
+		assert.Equal(t, testChatID, gotEvent.(*domain.RestoreEvent).ChatID)
+		assert.Equal(t, "work", gotEvent.(*domain.RestoreEvent).Account)
 	})
 
 	t.Run("handle error, failed to restore session", func(t *testing.T) {
@@ -140,6 +149,7 @@ func TestEventsProvider(t *testing.T) {
 		assert.Equal(t, testMessage.Text, gotTextEvent.Text)
 		assert.Equal(t, testMessage.Info.RemoteJid, gotTextEvent.WhatsappRemoteJid)
 		assert.Equal(t, contacts[testMessage.Info.RemoteJid].Name, gotTextEvent.WhatsappSenderName)
+		assert.Equal(t, testMessage.Info.Id, gotTextEvent.WhatsappMessageID)
 	})
 
 	t.Run("handle text message, unknown user", func(t *testing.T) {
@@ -253,4 +263,248 @@ func TestEventsProvider(t *testing.T) {
 		eventsProvider.HandleTextMessage(testMessage)
 		whatsappClientMock.AssertNotCalled(t, "GetContacts")
 	})
+
+	t.Run("handle presence update", func(t *testing.T) {
+		// Init test events provider
+		outgoingEvents := make(chan domain.Event, 1)
+		eventsProvider := whatsapp.NewEventsProvider(zap.NewNop(), &whatsapp.Opts{
+			ChatID:         testChatID,
+			Account:        "work",
+			OutgoingEvents: outgoingEvents,
+			WhatsappClient: &mocks.WhatsappClient{},
+		})
+
+		// Call method in order to emulate whatsapp event
+		eventsProvider.HandleJsonMessage(`["Presence",{"id":"1234567890@c.us","type":"unavailable","t":1648720800}]`)
+
+		gotEvent := <-outgoingEvents
+		assert.Equal(t, &domain.PresenceEvent{
+			ChatID:            testChatID,
+			WhatsappRemoteJid: "1234567890@s.whatsapp.net",
+			Presence:          domain.WhatsappPresenceUnavailable,
+			LastSeen:          time.Unix(1648720800, 0),
+			Account:           "work",
+		}, gotEvent)
+	})
+
+	t.Run("handle json message, not a presence update", func(t *testing.T) {
+		// Init test events provider
+		outgoingEvents := make(chan domain.Event, 1)
+		eventsProvider := whatsapp.NewEventsProvider(zap.NewNop(), &whatsapp.Opts{
+			OutgoingEvents: outgoingEvents,
+			WhatsappClient: &mocks.WhatsappClient{},
+		})
+
+		// Call method in order to emulate whatsapp event
+		eventsProvider.HandleJsonMessage(`["Conn",{"ref":"test"}]`)
+		eventsProvider.HandleJsonMessage(`["Presence",{"id":"1234567890@c.us","type":"recording"}]`)
+		eventsProvider.HandleJsonMessage(`not a json`)
+
+		assert.Empty(t, outgoingEvents)
+	})
+
+	t.Run("handle revoke message", func(t *testing.T) {
+		// Init test events provider
+		outgoingEvents := make(chan domain.Event, 1)
+		eventsProvider := whatsapp.NewEventsProvider(zap.NewNop(), &whatsapp.Opts{
+			ChatID:         testChatID,
+			Account:        "work",
+			OutgoingEvents: outgoingEvents,
+			WhatsappClient: &mocks.WhatsappClient{},
+		})
+
+		remoteJid, revokeID, messageID := "test-remote-jid", "revoke-id", "message-id"
+		revokeType := proto.ProtocolMessage_REVOKE
+		timestamp := uint64(time.Now().Add(time.Minute).Unix())
+		testMessage := &proto.WebMessageInfo{
+			Key:              &proto.MessageKey{RemoteJid: &remoteJid, Id: &revokeID},
+			MessageTimestamp: &timestamp,
+			Message: &proto.Message{
+				ProtocolMessage: &proto.ProtocolMessage{
+					Type: &revokeType,
+					Key:  &proto.MessageKey{RemoteJid: &remoteJid, Id: &messageID},
+				},
+			},
+		}
+
+		// Call method in order to emulate whatsapp event
+		eventsProvider.HandleRawMessage(testMessage)
+
+		gotEvent := <-outgoingEvents
+		assert.Equal(t, &domain.RevokeEvent{
+			ChatID:            testChatID,
+			WhatsappRemoteJid: "test-remote-jid",
+			WhatsappMessageID: "message-id",
+			Account:           "work",
+		}, gotEvent)
+
+		// Other raw messages are handled by the specific handlers
+		eventsProvider.HandleRawMessage(&proto.WebMessageInfo{
+			Key:              testMessage.Key,
+			MessageTimestamp: testMessage.MessageTimestamp,
+			Message:          &proto.Message{Conversation: &messageID},
+		})
+		assert.Empty(t, outgoingEvents)
+	})
+
+	t.Run("handle location messages", func(t *testing.T) {
+		// Init test events provider
+		outgoingEvents := make(chan domain.Event, 1)
+		whatsappClientMock := &mocks.WhatsappClient{}
+		whatsappClientMock.On("GetContacts").Return(map[string]domain.WhatsappContact{
+			"test-remote-jid": {Jid: "test-remote-jid", Name: "Alice"},
+		})
+		eventsProvider := whatsapp.NewEventsProvider(zap.NewNop(), &whatsapp.Opts{
+			ChatID:         testChatID,
+			Account:        "work",
+			OutgoingEvents: outgoingEvents,
+			WhatsappClient: whatsappClientMock,
+		})
+
+		info := whatsappsdk.MessageInfo{
+			Id:        "message-id",
+			RemoteJid: "test-remote-jid",
+			Timestamp: uint64(time.Now().Add(time.Minute).Unix()),
+		}
+
+		// Call methods in order to emulate whatsapp events
+		eventsProvider.HandleLocationMessage(whatsappsdk.LocationMessage{
+			Info:             info,
+			DegreesLatitude:  55.751244,
+			DegreesLongitude: 37.618423,
+			Name:             "Cafe",
+			Address:          "1 Main St",
+		})
+		assert.Equal(t, &domain.LocationEvent{
+			ChatID:             testChatID,
+			WhatsappRemoteJid:  "test-remote-jid",
+			WhatsappSenderName: "Alice",
+			WhatsappMessageID:  "message-id",
+			Location: domain.WhatsappLocation{
+				Latitude:  55.751244,
+				Longitude: 37.618423,
+				Name:      "Cafe",
+				Address:   "1 Main St",
+			},
+			Account: "work",
+		}, <-outgoingEvents)
+
+		eventsProvider.HandleLiveLocationMessage(whatsappsdk.LiveLocationMessage{
+			Info:             info,
+			DegreesLatitude:  55.75,
+			DegreesLongitude: 37.62,
+			SequenceNumber:   3,
+		})
+		assert.Equal(t, &domain.LocationEvent{
+			ChatID:             testChatID,
+			WhatsappRemoteJid:  "test-remote-jid",
+			WhatsappSenderName: "Alice",
+			WhatsappMessageID:  "message-id",
+			Location:           domain.WhatsappLocation{Latitude: 55.75, Longitude: 37.62},
+			Live:               true,
+			LiveSequence:       3,
+			Account:            "work",
+		}, <-outgoingEvents)
+
+		// Own locations are ignored
+		info.FromMe = true
+		eventsProvider.HandleLocationMessage(whatsappsdk.LocationMessage{Info: info})
+		eventsProvider.HandleLiveLocationMessage(whatsappsdk.LiveLocationMessage{Info: info})
+		assert.Empty(t, outgoingEvents)
+	})
+
+	t.Run("handle contact message", func(t *testing.T) {
+		// Init test events provider
+		outgoingEvents := make(chan domain.Event, 1)
+		whatsappClientMock := &mocks.WhatsappClient{}
+		whatsappClientMock.On("GetContacts").Return(map[string]domain.WhatsappContact{
+			"test-remote-jid": {Jid: "test-remote-jid", Name: "Alice"},
+		})
+		eventsProvider := whatsapp.NewEventsProvider(zap.NewNop(), &whatsapp.Opts{
+			ChatID:         testChatID,
+			Account:        "work",
+			OutgoingEvents: outgoingEvents,
+			WhatsappClient: whatsappClientMock,
+		})
+
+		info := whatsappsdk.MessageInfo{
+			Id:        "message-id",
+			RemoteJid: "test-remote-jid",
+			Timestamp: uint64(time.Now().Add(time.Minute).Unix()),
+		}
+
+		// Call methods in order to emulate whatsapp events
+		eventsProvider.HandleContactMessage(whatsappsdk.ContactMessage{
+			Info:        info,
+			DisplayName: "Bob",
+			Vcard:       "BEGIN:VCARD\nFN:Bob\nEND:VCARD",
+		})
+		assert.Equal(t, &domain.ContactCardEvent{
+			ChatID:             testChatID,
+			WhatsappRemoteJid:  "test-remote-jid",
+			WhatsappSenderName: "Alice",
+			WhatsappMessageID:  "message-id",
+			ContactCard:        domain.WhatsappContactCard{DisplayName: "Bob", Vcard: "BEGIN:VCARD\nFN:Bob\nEND:VCARD"},
+			Account:            "work",
+		}, <-outgoingEvents)
+
+		// Own contact cards are ignored
+		info.FromMe = true
+		eventsProvider.HandleContactMessage(whatsappsdk.ContactMessage{Info: info})
+		assert.Empty(t, outgoingEvents)
+	})
+
+	t.Run("handle document message", func(t *testing.T) {
+		// Init test events provider
+		outgoingEvents := make(chan domain.Event, 1)
+		whatsappClientMock := &mocks.WhatsappClient{}
+		whatsappClientMock.On("GetContacts").Return(map[string]domain.WhatsappContact{
+			"test-remote-jid": {Jid: "test-remote-jid", Name: "Alice"},
+		})
+		eventsProvider := whatsapp.NewEventsProvider(zap.NewNop(), &whatsapp.Opts{
+			ChatID:         testChatID,
+			Account:        "work",
+			OutgoingEvents: outgoingEvents,
+			WhatsappClient: whatsappClientMock,
+		})
+
+		info := whatsappsdk.MessageInfo{
+			Id:        "message-id",
+			RemoteJid: "test-remote-jid",
+			Timestamp: uint64(time.Now().Add(time.Minute).Unix()),
+		}
+
+		// Call methods in order to emulate whatsapp events, the raw message comes first.
+		// The document has no media to download, so it's bridged without the file
+		fileLength := uint64(1 << 20)
+		eventsProvider.HandleRawMessage(&proto.WebMessageInfo{
+			Key:              &proto.MessageKey{RemoteJid: &info.RemoteJid, Id: &info.Id},
+			MessageTimestamp: &info.Timestamp,
+			Message:          &proto.Message{DocumentMessage: &proto.DocumentMessage{FileLength: &fileLength}},
+		})
+		eventsProvider.HandleDocumentMessage(whatsappsdk.DocumentMessage{
+			Info:     info,
+			Title:    "Q1 numbers",
+			FileName: "report.pdf",
+			Type:     "application/pdf",
+		})
+		assert.Equal(t, &domain.DocumentEvent{
+			ChatID:             testChatID,
+			WhatsappRemoteJid:  "test-remote-jid",
+			WhatsappSenderName: "Alice",
+			WhatsappMessageID:  "message-id",
+			Document: domain.WhatsappDocument{
+				FileName: "report.pdf",
+				MimeType: "application/pdf",
+				Caption:  "Q1 numbers",
+				Size:     1 << 20,
+			},
+			Account: "work",
+		}, <-outgoingEvents)
+
+		// Own documents are ignored
+		info.FromMe = true
+		eventsProvider.HandleDocumentMessage(whatsappsdk.DocumentMessage{Info: info})
+		assert.Empty(t, outgoingEvents)
+	})
 }
@@ -12,6 +12,20 @@ type WhatsappClient struct {
 	mock.Mock
 }
 
+// Edit provides a mock function with given fields: remoteJid, messageID, text
+func (_m *WhatsappClient) Edit(remoteJid string, messageID string, text string) error {
+	ret := _m.Called(remoteJid, messageID, text)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, string) error); ok {
+		r0 = rf(remoteJid, messageID, text)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // GetContacts provides a mock function with given fields:
 func (_m *WhatsappClient) GetContacts() map[string]domain.WhatsappContact {
 	ret := _m.Called()
@@ -28,6 +42,29 @@ func (_m *WhatsappClient) GetContacts() map[string]domain.WhatsappContact {
 	return r0
 }
 
+// History provides a mock function with given fields: remoteJid, count
+func (_m *WhatsappClient) History(remoteJid string, count int) ([]domain.WhatsappHistoryMessage, error) {
+	ret := _m.Called(remoteJid, count)
+
+	var r0 []domain.WhatsappHistoryMessage
+	if rf, ok := ret.Get(0).(func(string, int) []domain.WhatsappHistoryMessage); ok {
+		r0 = rf(remoteJid, count)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.WhatsappHistoryMessage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(remoteJid, count)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Logout provides a mock function with given fields:
 func (_m *WhatsappClient) Logout() error {
 	ret := _m.Called()
@@ -42,6 +79,43 @@ func (_m *WhatsappClient) Logout() error {
 	return r0
 }
 
+// MarkRead provides a mock function with given fields: remoteJid
+func (_m *WhatsappClient) MarkRead(remoteJid string) error {
+	ret := _m.Called(remoteJid)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(remoteJid)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RecentChats provides a mock function with given fields: count
+func (_m *WhatsappClient) RecentChats(count int) ([]domain.WhatsappChat, error) {
+	ret := _m.Called(count)
+
+	var r0 []domain.WhatsappChat
+	if rf, ok := ret.Get(0).(func(int) []domain.WhatsappChat); ok {
+		r0 = rf(count)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.WhatsappChat)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(int) error); ok {
+		r1 = rf(count)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Restore provides a mock function with given fields:
 func (_m *WhatsappClient) Restore() error {
 	ret := _m.Called()
@@ -56,6 +130,20 @@ func (_m *WhatsappClient) Restore() error {
 	return r0
 }
 
+// Revoke provides a mock function with given fields: remoteJid, messageID
+func (_m *WhatsappClient) Revoke(remoteJid string, messageID string) error {
+	ret := _m.Called(remoteJid, messageID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(remoteJid, messageID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Send provides a mock function with given fields: msg
 func (_m *WhatsappClient) Send(msg domain.WhatsappMessage) error {
 	ret := _m.Called(msg)
@@ -69,3 +157,31 @@ func (_m *WhatsappClient) Send(msg domain.WhatsappMessage) error {
 
 	return r0
 }
+
+// SendPresence provides a mock function with given fields: remoteJid, presence
+func (_m *WhatsappClient) SendPresence(remoteJid string, presence domain.WhatsappPresence) error {
+	ret := _m.Called(remoteJid, presence)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, domain.WhatsappPresence) error); ok {
+		r0 = rf(remoteJid, presence)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SubscribePresence provides a mock function with given fields: remoteJid
+func (_m *WhatsappClient) SubscribePresence(remoteJid string) error {
+	ret := _m.Called(remoteJid)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(remoteJid)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -0,0 +1,100 @@
+//go:build multidevice
+
+package multidevice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	_ "github.com/mattn/go-sqlite3" // sqlite3 driver of the device store
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/store/sqlstore"
+	"go.uber.org/zap"
+)
+
+const qrCodeEvent = "code"
+
+var errQRCodeTimeout = errors.New("QR-code scanning timed out")
+
+// Backend represents a backend that establishes sessions via WhatsApp
+// multi-device protocol.
+type Backend struct {
+	log       *zap.Logger
+	container *sqlstore.Container
+}
+
+// NewBackend returns new instance of Backend and prepares the device store.
+func NewBackend(log *zap.Logger, opts *Opts) (*Backend, error) {
+	container, err := sqlstore.New(context.Background(), opts.Dialect, opts.Address, newLogger(log, "store"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open device store: %w", err)
+	}
+
+	return &Backend{
+		log:       log,
+		container: container,
+	}, nil
+}
+
+// Login method links a new device to the whatsapp account. QR-codes are
+// rotated by whatsapp, every new one is sent to qrCodes.
+func (b *Backend) Login(ctx context.Context,
+	opts *domain.WhatsappSessionOpts,
+	qrCodes chan<- domain.WhatsappQRCode) (domain.WhatsappClient, error) {
+	device := b.container.NewDevice()
+	wac := whatsmeow.NewClient(device, newLogger(b.log, "client"))
+
+	client := NewClient(wac)
+	eventsProvider := NewEventsProvider(b.log, &EventsProviderOpts{
+		ChatID:         opts.ChatID,
+		Account:        opts.Account,
+		OutgoingEvents: opts.Events,
+		WhatsappClient: client,
+		Download:       client.download,
+	})
+	wac.AddEventHandler(client.HandleEvent)
+	wac.AddEventHandler(eventsProvider.HandleEvent)
+
+	qrItems, err := wac.GetQRChannel(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get QR-code channel: %w", err)
+	}
+	if err := wac.Connect(); err != nil {
+		return nil, fmt.Errorf("failed to connect to whatsapp: %w", err)
+	}
+
+	for item := range qrItems {
+		switch {
+		case item.Event == qrCodeEvent:
+			qrCodes <- domain.WhatsappQRCode{
+				Content: item.Code,
+				Timeout: item.Timeout,
+			}
+		case item == whatsmeow.QRChannelSuccess:
+			b.log.Debug("login successful", zap.String("jid", wac.Store.ID.String()))
+
+			return client, nil
+		case item.Error != nil:
+			wac.Disconnect()
+
+			return nil, fmt.Errorf("failed to login to whatsapp: %w", item.Error)
+		case ctx.Err() != nil:
+			wac.Disconnect()
+
+			return nil, ctx.Err()
+		default:
+			wac.Disconnect()
+
+			return nil, fmt.Errorf("failed to login to whatsapp: %w: %s", errQRCodeTimeout, item.Event)
+		}
+	}
+	wac.Disconnect()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return nil, fmt.Errorf("failed to login to whatsapp: %w", errQRCodeTimeout)
+}
@@ -0,0 +1,30 @@
+//go:build !multidevice
+
+package multidevice
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"go.uber.org/zap"
+)
+
+// ErrNotSupported is returned when twbridge is built without multi-device support.
+var ErrNotSupported = errors.New("twbridge is built without multi-device support, rebuild it with -tags multidevice")
+
+// Backend is a placeholder of the multi-device backend.
+type Backend struct{}
+
+// NewBackend returns ErrNotSupported since twbridge is built without
+// multi-device support.
+func NewBackend(_ *zap.Logger, _ *Opts) (*Backend, error) {
+	return nil, ErrNotSupported
+}
+
+// Login method returns ErrNotSupported.
+func (b *Backend) Login(_ context.Context,
+	_ *domain.WhatsappSessionOpts,
+	_ chan<- domain.WhatsappQRCode) (domain.WhatsappClient, error) {
+	return nil, ErrNotSupported
+}
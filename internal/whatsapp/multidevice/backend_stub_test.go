@@ -0,0 +1,19 @@
+//go:build !multidevice
+
+package multidevice_test
+
+import (
+	"testing"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/whatsapp/multidevice"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+var _ domain.WhatsappBackend = &multidevice.Backend{}
+
+func TestNewBackend(t *testing.T) {
+	_, err := multidevice.NewBackend(zap.NewNop(), &multidevice.Opts{})
+	assert.ErrorIs(t, err, multidevice.ErrNotSupported)
+}
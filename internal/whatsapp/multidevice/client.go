@@ -0,0 +1,462 @@
+//go:build multidevice
+
+package multidevice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+var errUnsupportedMessage = errors.New("unsupported message type")
+
+// maxHistoryMessages is a maximum number of the messages kept per chat.
+const maxHistoryMessages = 100
+
+// Client represents a multi-device whatsapp client.
+// Multi-device whatsapp doesn't load history on demand, instead the phone
+// syncs recent chats once the device is linked, so the client keeps them.
+type Client struct {
+	wac       *whatsmeow.Client
+	mu        sync.Mutex
+	chats     map[string]*historyChat
+	available bool
+}
+
+// historyChat represents a chat synced from the phone.
+type historyChat struct {
+	name     string
+	messages []domain.WhatsappHistoryMessage
+	unread   []unreadMessage
+}
+
+// unreadMessage represents an incoming message that hasn't been read yet.
+type unreadMessage struct {
+	id        types.MessageID
+	sender    types.JID
+	timestamp time.Time
+}
+
+// NewClient returns new instance of Client.
+func NewClient(wac *whatsmeow.Client) *Client {
+	return &Client{
+		wac:   wac,
+		chats: make(map[string]*historyChat),
+	}
+}
+
+// download method downloads and decrypts media of the message.
+func (c *Client) download(msg whatsmeow.DownloadableMessage) ([]byte, error) {
+	return c.wac.Download(context.Background(), msg)
+}
+
+// Restore method reconnects the client unless it's already connected.
+func (c *Client) Restore() error {
+	if c.wac.IsConnected() {
+		return nil
+	}
+
+	return c.wac.Connect()
+}
+
+// GetContacts method returns contacts of the linked account.
+func (c *Client) GetContacts() map[string]domain.WhatsappContact {
+	contacts, err := c.wac.Store.Contacts.GetAllContacts(context.Background())
+	if err != nil {
+		return map[string]domain.WhatsappContact{}
+	}
+
+	result := make(map[string]domain.WhatsappContact, len(contacts))
+	for jid, info := range contacts {
+		name := info.FullName
+		if name == "" {
+			name = info.PushName
+		}
+		result[jid.String()] = domain.WhatsappContact{
+			Jid:  jid.String(),
+			Name: name,
+		}
+	}
+
+	return result
+}
+
+// Send method sends a message to whatsapp.
+func (c *Client) Send(msg domain.WhatsappMessage) error {
+	switch m := msg.(type) {
+	case *domain.WhatsappTextMessage:
+		jid, err := types.ParseJID(m.RemoteJid)
+		if err != nil {
+			return fmt.Errorf("failed to parse jid: %w", err)
+		}
+
+		_, err = c.wac.SendMessage(context.Background(), jid, &waE2E.Message{
+			Conversation: proto.String(m.Text),
+		}, whatsmeow.SendRequestExtra{ID: types.MessageID(m.ID)})
+
+		return err
+	case *domain.WhatsappLocationMessage:
+		jid, err := types.ParseJID(m.RemoteJid)
+		if err != nil {
+			return fmt.Errorf("failed to parse jid: %w", err)
+		}
+
+		_, err = c.wac.SendMessage(context.Background(), jid, &waE2E.Message{
+			LocationMessage: &waE2E.LocationMessage{
+				DegreesLatitude:  proto.Float64(m.Location.Latitude),
+				DegreesLongitude: proto.Float64(m.Location.Longitude),
+				Name:             proto.String(m.Location.Name),
+				Address:          proto.String(m.Location.Address),
+			},
+		}, whatsmeow.SendRequestExtra{ID: types.MessageID(m.ID)})
+
+		return err
+	case *domain.WhatsappContactCardMessage:
+		jid, err := types.ParseJID(m.RemoteJid)
+		if err != nil {
+			return fmt.Errorf("failed to parse jid: %w", err)
+		}
+
+		_, err = c.wac.SendMessage(context.Background(), jid, &waE2E.Message{
+			ContactMessage: &waE2E.ContactMessage{
+				DisplayName: proto.String(m.ContactCard.DisplayName),
+				Vcard:       proto.String(m.ContactCard.Vcard),
+			},
+		}, whatsmeow.SendRequestExtra{ID: types.MessageID(m.ID)})
+
+		return err
+	case *domain.WhatsappMediaMessage:
+		return c.sendMedia(m)
+	case *domain.WhatsappReactionMessage:
+		return c.sendReaction(m)
+	default:
+		return fmt.Errorf("%w: %T", errUnsupportedMessage, msg)
+	}
+}
+
+// sendMedia uploads the photo or the document and sends it to the conversation.
+func (c *Client) sendMedia(msg *domain.WhatsappMediaMessage) error {
+	jid, err := types.ParseJID(msg.RemoteJid)
+	if err != nil {
+		return fmt.Errorf("failed to parse jid: %w", err)
+	}
+
+	mediaType := whatsmeow.MediaDocument
+	if msg.Media.Kind == domain.WhatsappMediaImage {
+		mediaType = whatsmeow.MediaImage
+	}
+	uploaded, err := c.wac.Upload(context.Background(), msg.Data, mediaType)
+	if err != nil {
+		return fmt.Errorf("failed to upload media: %w", err)
+	}
+
+	message := &waE2E.Message{}
+	if msg.Media.Kind == domain.WhatsappMediaImage {
+		message.ImageMessage = &waE2E.ImageMessage{
+			Caption:       proto.String(msg.Media.Caption),
+			Mimetype:      proto.String(msg.Media.MimeType),
+			URL:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uploaded.FileLength),
+		}
+	} else {
+		message.DocumentMessage = &waE2E.DocumentMessage{
+			Caption:       proto.String(msg.Media.Caption),
+			Title:         proto.String(msg.Media.FileName),
+			FileName:      proto.String(msg.Media.FileName),
+			Mimetype:      proto.String(msg.Media.MimeType),
+			URL:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uploaded.FileLength),
+		}
+	}
+
+	_, err = c.wac.SendMessage(context.Background(), jid, message,
+		whatsmeow.SendRequestExtra{ID: types.MessageID(msg.ID)})
+
+	return err
+}
+
+// sendReaction sends the reaction to the message, the empty sender stands for the account itself.
+func (c *Client) sendReaction(msg *domain.WhatsappReactionMessage) error {
+	jid, err := types.ParseJID(msg.RemoteJid)
+	if err != nil {
+		return fmt.Errorf("failed to parse jid: %w", err)
+	}
+
+	sender := types.EmptyJID
+	if !msg.FromMe {
+		// Messages of the contacts are sent by the conversation itself, except in groups
+		sender = jid
+		if msg.SenderJid != "" {
+			if sender, err = types.ParseJID(msg.SenderJid); err != nil {
+				return fmt.Errorf("failed to parse sender jid: %w", err)
+			}
+		}
+	}
+
+	_, err = c.wac.SendMessage(context.Background(), jid, c.wac.BuildReaction(jid, sender, msg.MessageID, msg.Emoji))
+
+	return err
+}
+
+// Revoke method deletes the message sent to the conversation for everyone.
+func (c *Client) Revoke(remoteJid, messageID string) error {
+	jid, err := types.ParseJID(remoteJid)
+	if err != nil {
+		return fmt.Errorf("failed to parse jid: %w", err)
+	}
+
+	_, err = c.wac.SendMessage(context.Background(), jid, c.wac.BuildRevoke(jid, types.EmptyJID, messageID))
+
+	return err
+}
+
+// Edit method replaces the text of the message sent to the conversation.
+func (c *Client) Edit(remoteJid, messageID, text string) error {
+	jid, err := types.ParseJID(remoteJid)
+	if err != nil {
+		return fmt.Errorf("failed to parse jid: %w", err)
+	}
+
+	_, err = c.wac.SendMessage(context.Background(), jid, c.wac.BuildEdit(jid, messageID, &waE2E.Message{
+		Conversation: proto.String(text),
+	}))
+
+	return err
+}
+
+// Logout method unlinks the device from the account.
+func (c *Client) Logout() error {
+	return c.wac.Logout(context.Background())
+}
+
+// RecentChats method returns up to count synced chats, the most recent first.
+func (c *Client) RecentChats(count int) ([]domain.WhatsappChat, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	chats := make([]domain.WhatsappChat, 0, len(c.chats))
+	for jid, chat := range c.chats {
+		if len(chat.messages) == 0 {
+			continue
+		}
+		last := chat.messages[len(chat.messages)-1]
+		chats = append(chats, domain.WhatsappChat{
+			Jid:           jid,
+			Name:          chat.name,
+			LastMessageAt: last.Timestamp,
+			Unread:        len(chat.unread),
+			Preview:       last.Text,
+		})
+	}
+	sort.Slice(chats, func(i, j int) bool {
+		return chats[i].LastMessageAt.After(chats[j].LastMessageAt)
+	})
+	if len(chats) > count {
+		chats = chats[:count]
+	}
+
+	return chats, nil
+}
+
+// History method returns up to count last text messages of the synced chat.
+func (c *Client) History(remoteJid string, count int) ([]domain.WhatsappHistoryMessage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	chat, ok := c.chats[remoteJid]
+	if !ok {
+		return []domain.WhatsappHistoryMessage{}, nil
+	}
+
+	messages := chat.messages
+	if len(messages) > count {
+		messages = messages[len(messages)-count:]
+	}
+
+	return append([]domain.WhatsappHistoryMessage(nil), messages...), nil
+}
+
+// MarkRead method sends read receipts of the unread messages of the chat.
+func (c *Client) MarkRead(remoteJid string) error {
+	chatJid, err := types.ParseJID(remoteJid)
+	if err != nil {
+		return fmt.Errorf("failed to parse jid: %w", err)
+	}
+
+	c.mu.Lock()
+	var unread []unreadMessage
+	if chat, ok := c.chats[remoteJid]; ok {
+		unread, chat.unread = chat.unread, nil
+	}
+	c.mu.Unlock()
+
+	// Receipts are sent per sender, since messages of groups have different senders
+	bySender := make(map[types.JID][]unreadMessage)
+	for _, msg := range unread {
+		bySender[msg.sender] = append(bySender[msg.sender], msg)
+	}
+	for sender, messages := range bySender {
+		ids := make([]types.MessageID, 0, len(messages))
+		for _, msg := range messages {
+			ids = append(ids, msg.id)
+		}
+
+		err := c.wac.MarkRead(context.Background(), ids, messages[len(messages)-1].timestamp, chatJid, sender)
+		if err != nil {
+			return fmt.Errorf("failed to mark messages as read: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SubscribePresence method subscribes to presence updates of the contact,
+// they are handled by the events provider.
+func (c *Client) SubscribePresence(remoteJid string) error {
+	jid, err := types.ParseJID(remoteJid)
+	if err != nil {
+		return fmt.Errorf("failed to parse jid: %w", err)
+	}
+
+	// Whatsapp doesn't send presence updates to the device until it's available itself
+	c.mu.Lock()
+	available := c.available
+	c.available = true
+	c.mu.Unlock()
+
+	if !available {
+		if err := c.wac.SendPresence(context.Background(), types.PresenceAvailable); err != nil {
+			c.mu.Lock()
+			c.available = false
+			c.mu.Unlock()
+
+			return fmt.Errorf("failed to send presence: %w", err)
+		}
+	}
+
+	return c.wac.SubscribePresence(context.Background(), jid)
+}
+
+// SendPresence method lets the conversation know whether the user is composing a message.
+func (c *Client) SendPresence(remoteJid string, presence domain.WhatsappPresence) error {
+	jid, err := types.ParseJID(remoteJid)
+	if err != nil {
+		return fmt.Errorf("failed to parse jid: %w", err)
+	}
+
+	state := types.ChatPresencePaused
+	if presence == domain.WhatsappPresenceComposing {
+		state = types.ChatPresenceComposing
+	}
+
+	return c.wac.SendChatPresence(context.Background(), jid, state, types.ChatPresenceMediaText)
+}
+
+// HandleEvent method keeps the chats synced from the phone and the messages received since.
+func (c *Client) HandleEvent(rawEvent interface{}) {
+	switch event := rawEvent.(type) {
+	case *events.HistorySync:
+		for _, conv := range event.Data.GetConversations() {
+			chatJid, err := types.ParseJID(conv.GetID())
+			if err != nil {
+				continue
+			}
+
+			for _, historyMsg := range conv.GetMessages() {
+				msg, err := c.wac.ParseWebMessage(chatJid, historyMsg.GetMessage())
+				if err != nil {
+					continue
+				}
+				c.addHistory(conv.GetName(), msg)
+			}
+			c.trimUnread(chatJid.String(), int(conv.GetUnreadCount()))
+		}
+	case *events.Message:
+		c.addHistory("", event)
+	}
+}
+
+// addHistory adds the text message to its chat, messages are kept in chronological order.
+func (c *Client) addHistory(chatName string, msg *events.Message) {
+	text := messageText(msg.Message)
+	if text == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	jid := msg.Info.Chat.String()
+	chat, ok := c.chats[jid]
+	if !ok {
+		chat = &historyChat{}
+		c.chats[jid] = chat
+	}
+	if chatName != "" {
+		chat.name = chatName
+	}
+
+	for _, known := range chat.messages {
+		if known.ID == msg.Info.ID {
+			return
+		}
+	}
+
+	// Messages sent from the phone mean the chat has been read there
+	if msg.Info.IsFromMe {
+		chat.unread = nil
+	} else {
+		chat.unread = append(chat.unread, unreadMessage{
+			id:        msg.Info.ID,
+			sender:    msg.Info.Sender,
+			timestamp: msg.Info.Timestamp,
+		})
+	}
+
+	chat.messages = append(chat.messages, domain.WhatsappHistoryMessage{
+		ID:         msg.Info.ID,
+		RemoteJid:  jid,
+		SenderName: msg.Info.PushName,
+		FromMe:     msg.Info.IsFromMe,
+		Text:       text,
+		Timestamp:  msg.Info.Timestamp,
+	})
+	sort.SliceStable(chat.messages, func(i, j int) bool {
+		return chat.messages[i].Timestamp.Before(chat.messages[j].Timestamp)
+	})
+	if len(chat.messages) > maxHistoryMessages {
+		chat.messages = chat.messages[len(chat.messages)-maxHistoryMessages:]
+	}
+}
+
+// trimUnread keeps only the last count unread messages of the chat,
+// the phone reports how many messages of the synced chat are unread.
+func (c *Client) trimUnread(remoteJid string, count int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	chat, ok := c.chats[remoteJid]
+	if !ok || len(chat.unread) <= count {
+		return
+	}
+
+	chat.unread = chat.unread[len(chat.unread)-count:]
+}
@@ -0,0 +1,305 @@
+//go:build multidevice
+
+package multidevice
+
+import (
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"go.uber.org/zap"
+)
+
+// EventsProvider represents multi-device whatsapp events provider.
+type EventsProvider struct {
+	log            *zap.Logger
+	startAt        time.Time
+	chatID         int64
+	account        string
+	whatsappClient domain.WhatsappClient
+	outgoingEvents chan domain.Event
+	download       func(msg whatsmeow.DownloadableMessage) ([]byte, error)
+	reconnecting   bool
+}
+
+// EventsProviderOpts represents options to create new instance of EventsProvider.
+type EventsProviderOpts struct {
+	// ChatID is identifier of telegram chat.
+	ChatID int64
+
+	// Account is a name of the whatsapp account the events belong to.
+	Account string
+
+	// OutgoingEvents is a channel to send events to.
+	OutgoingEvents chan domain.Event
+
+	// WhatsappClient represents a client to work with whatsapp API.
+	WhatsappClient domain.WhatsappClient
+
+	// Download downloads media of the messages, e.g. documents.
+	Download func(msg whatsmeow.DownloadableMessage) ([]byte, error)
+}
+
+// NewEventsProvider creates new instance of EventsProvider.
+func NewEventsProvider(log *zap.Logger, opts *EventsProviderOpts) *EventsProvider {
+	return &EventsProvider{
+		log:            log,
+		chatID:         opts.ChatID,
+		account:        opts.Account,
+		startAt:        time.Now(),
+		outgoingEvents: opts.OutgoingEvents,
+		whatsappClient: opts.WhatsappClient,
+		download:       opts.Download,
+	}
+}
+
+// HandleEvent method translates whatsmeow events to domain events.
+func (ep *EventsProvider) HandleEvent(rawEvent interface{}) {
+	switch event := rawEvent.(type) {
+	case *events.Message:
+		ep.handleMessage(event)
+	case *events.Presence:
+		ep.handlePresence(event)
+	case *events.ChatPresence:
+		ep.handleChatPresence(event)
+	case *events.Disconnected:
+		// whatsmeow reconnects automatically, let the handler know once it's done
+		ep.reconnecting = true
+	case *events.Connected:
+		if ep.reconnecting {
+			ep.reconnecting = false
+			ep.outgoingEvents <- &domain.RestoreEvent{ChatID: ep.chatID, Account: ep.account}
+		}
+	case *events.LoggedOut, *events.StreamReplaced:
+		ep.log.Debug("session is invalidated", zap.Int64("chat_id", ep.chatID))
+		ep.outgoingEvents <- &domain.DisconnectEvent{ChatID: ep.chatID, Account: ep.account}
+	}
+}
+
+func (ep *EventsProvider) handleMessage(event *events.Message) {
+	// Skip own messages and the ones that were sent before the session is started
+	if event.Info.IsFromMe || event.Info.Timestamp.Before(ep.startAt) {
+		return
+	}
+
+	if protocolMsg := event.Message.GetProtocolMessage(); protocolMsg != nil {
+		ep.handleProtocolMessage(event, protocolMsg)
+
+		return
+	}
+
+	if reactionMsg := event.Message.GetReactionMessage(); reactionMsg != nil {
+		ep.handleReactionMessage(event, reactionMsg)
+
+		return
+	}
+
+	if location, live, ok := messageLocation(event.Message); ok {
+		locationEvent := &domain.LocationEvent{
+			ChatID:             ep.chatID,
+			Account:            ep.account,
+			WhatsappRemoteJid:  event.Info.Chat.String(),
+			WhatsappSenderName: ep.senderName(event),
+			WhatsappMessageID:  event.Info.ID,
+			Location:           location,
+			Live:               live,
+			LiveSequence:       event.Message.GetLiveLocationMessage().GetSequenceNumber(),
+		}
+		if event.Info.IsGroup {
+			locationEvent.WhatsappSenderJid = event.Info.Sender.ToNonAD().String()
+		}
+		ep.outgoingEvents <- locationEvent
+
+		return
+	}
+
+	if contact := event.Message.GetContactMessage(); contact != nil {
+		contactCardEvent := &domain.ContactCardEvent{
+			ChatID:             ep.chatID,
+			Account:            ep.account,
+			WhatsappRemoteJid:  event.Info.Chat.String(),
+			WhatsappSenderName: ep.senderName(event),
+			WhatsappMessageID:  event.Info.ID,
+			ContactCard: domain.WhatsappContactCard{
+				DisplayName: contact.GetDisplayName(),
+				Vcard:       contact.GetVcard(),
+			},
+		}
+		if event.Info.IsGroup {
+			contactCardEvent.WhatsappSenderJid = event.Info.Sender.ToNonAD().String()
+		}
+		ep.outgoingEvents <- contactCardEvent
+
+		return
+	}
+
+	if document := messageDocument(event.Message); document != nil {
+		ep.handleDocument(event, document)
+
+		return
+	}
+
+	text := messageText(event.Message)
+	if text == "" {
+		return
+	}
+
+	textEvent := &domain.TextMessageEvent{
+		ChatID:             ep.chatID,
+		Account:            ep.account,
+		WhatsappRemoteJid:  event.Info.Chat.String(),
+		WhatsappSenderName: ep.senderName(event),
+		Text:               text,
+		WhatsappMessageID:  event.Info.ID,
+	}
+	if event.Info.IsGroup {
+		textEvent.WhatsappSenderJid = event.Info.Sender.ToNonAD().String()
+	}
+	ep.outgoingEvents <- textEvent
+}
+
+// handleReactionMessage handles the reaction of the contact to a message.
+func (ep *EventsProvider) handleReactionMessage(event *events.Message, msg *waE2E.ReactionMessage) {
+	ep.outgoingEvents <- &domain.ReactionEvent{
+		ChatID:             ep.chatID,
+		Account:            ep.account,
+		WhatsappRemoteJid:  event.Info.Chat.String(),
+		WhatsappSenderName: ep.senderName(event),
+		WhatsappSenderJid:  event.Info.Sender.ToNonAD().String(),
+		WhatsappMessageID:  msg.GetKey().GetID(),
+		Emoji:              msg.GetText(),
+	}
+}
+
+// senderName returns the name of the contact that has sent the message.
+func (ep *EventsProvider) senderName(event *events.Message) string {
+	if contact, ok := ep.whatsappClient.GetContacts()[event.Info.Sender.ToNonAD().String()]; ok && contact.Name != "" {
+		return contact.Name
+	}
+
+	return event.Info.PushName
+}
+
+// handleProtocolMessage handles the message that changes another message, only revokes are handled.
+func (ep *EventsProvider) handleProtocolMessage(event *events.Message, msg *waE2E.ProtocolMessage) {
+	if msg.GetType() != waE2E.ProtocolMessage_REVOKE {
+		return
+	}
+
+	ep.outgoingEvents <- &domain.RevokeEvent{
+		ChatID:            ep.chatID,
+		Account:           ep.account,
+		WhatsappRemoteJid: event.Info.Chat.String(),
+		WhatsappMessageID: msg.GetKey().GetID(),
+	}
+}
+
+func (ep *EventsProvider) handlePresence(event *events.Presence) {
+	presence := domain.WhatsappPresenceAvailable
+	if event.Unavailable {
+		presence = domain.WhatsappPresenceUnavailable
+	}
+
+	ep.outgoingEvents <- &domain.PresenceEvent{
+		ChatID:            ep.chatID,
+		Account:           ep.account,
+		WhatsappRemoteJid: event.From.ToNonAD().String(),
+		Presence:          presence,
+		LastSeen:          event.LastSeen,
+	}
+}
+
+func (ep *EventsProvider) handleChatPresence(event *events.ChatPresence) {
+	if event.IsFromMe {
+		return
+	}
+
+	presence := domain.WhatsappPresencePaused
+	if event.State == types.ChatPresenceComposing {
+		presence = domain.WhatsappPresenceComposing
+	}
+
+	ep.outgoingEvents <- &domain.PresenceEvent{
+		ChatID:            ep.chatID,
+		Account:           ep.account,
+		WhatsappRemoteJid: event.Chat.ToNonAD().String(),
+		Presence:          presence,
+	}
+}
+
+// handleDocument downloads the document and passes it to the bridge, it's bridged
+// without the file if the download fails.
+func (ep *EventsProvider) handleDocument(event *events.Message, msg *waE2E.DocumentMessage) {
+	document := domain.WhatsappDocument{
+		FileName: msg.GetFileName(),
+		MimeType: msg.GetMimetype(),
+		Caption:  msg.GetCaption(),
+		Size:     int64(msg.GetFileLength()),
+	}
+	data, err := ep.download(msg)
+	if err != nil {
+		ep.log.Error("failed to download document", zap.Error(err))
+	} else {
+		document.Data = data
+		document.Size = int64(len(data))
+	}
+
+	documentEvent := &domain.DocumentEvent{
+		ChatID:             ep.chatID,
+		Account:            ep.account,
+		WhatsappRemoteJid:  event.Info.Chat.String(),
+		WhatsappSenderName: ep.senderName(event),
+		WhatsappMessageID:  event.Info.ID,
+		Document:           document,
+	}
+	if event.Info.IsGroup {
+		documentEvent.WhatsappSenderJid = event.Info.Sender.ToNonAD().String()
+	}
+	ep.outgoingEvents <- documentEvent
+}
+
+// messageDocument returns the document of the message, documents with captions are
+// wrapped by whatsapp. nil is returned if the message isn't a document.
+func messageDocument(msg *waE2E.Message) *waE2E.DocumentMessage {
+	if document := msg.GetDocumentMessage(); document != nil {
+		return document
+	}
+
+	return msg.GetDocumentWithCaptionMessage().GetMessage().GetDocumentMessage()
+}
+
+// messageLocation returns the location shared by the message and whether it's live,
+// false is returned if the message doesn't share a location.
+func messageLocation(msg *waE2E.Message) (domain.WhatsappLocation, bool, bool) {
+	if location := msg.GetLocationMessage(); location != nil {
+		return domain.WhatsappLocation{
+			Latitude:  location.GetDegreesLatitude(),
+			Longitude: location.GetDegreesLongitude(),
+			Name:      location.GetName(),
+			Address:   location.GetAddress(),
+		}, location.GetIsLive(), true
+	}
+
+	if location := msg.GetLiveLocationMessage(); location != nil {
+		return domain.WhatsappLocation{
+			Latitude:  location.GetDegreesLatitude(),
+			Longitude: location.GetDegreesLongitude(),
+		}, true, true
+	}
+
+	return domain.WhatsappLocation{}, false, false
+}
+
+func messageText(msg *waE2E.Message) string {
+	if msg == nil {
+		return ""
+	}
+	if text := msg.GetConversation(); text != "" {
+		return text
+	}
+
+	return msg.GetExtendedTextMessage().GetText()
+}
@@ -0,0 +1,28 @@
+//go:build multidevice
+
+package multidevice
+
+import (
+	"fmt"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+	"go.uber.org/zap"
+)
+
+// logger adapts zap logger to the whatsmeow logging interface.
+type logger struct {
+	log *zap.SugaredLogger
+}
+
+func newLogger(log *zap.Logger, module string) waLog.Logger {
+	return &logger{log: log.Sugar().Named(module)}
+}
+
+func (l *logger) Warnf(msg string, args ...interface{})  { l.log.Warnf(msg, args...) }
+func (l *logger) Errorf(msg string, args ...interface{}) { l.log.Errorf(msg, args...) }
+func (l *logger) Infof(msg string, args ...interface{})  { l.log.Infof(msg, args...) }
+func (l *logger) Debugf(msg string, args ...interface{}) { l.log.Debugf(msg, args...) }
+
+func (l *logger) Sub(module string) waLog.Logger {
+	return &logger{log: l.log.Named(fmt.Sprint(module))}
+}
@@ -0,0 +1,17 @@
+// Package multidevice implements whatsapp backend that speaks WhatsApp
+// multi-device protocol.
+//
+// The backend depends on go.mau.fi/whatsmeow and it's compiled only when
+// twbridge is built with "multidevice" build tag, otherwise NewBackend
+// returns ErrNotSupported.
+package multidevice
+
+// Opts represents options to create new instance of Backend.
+type Opts struct {
+	// Dialect is a name of the SQL dialect of the device store database,
+	// e.g. "sqlite3" or "postgres".
+	Dialect string
+
+	// Address is an address of the device store database.
+	Address string
+}
@@ -0,0 +1,728 @@
+package simulator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	whatsappsdk "github.com/Rhymen/go-whatsapp"
+	"github.com/Rhymen/go-whatsapp/binary/proto"
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/whatsapp"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrNotLoggedIn is returned when a logged out session is used.
+	ErrNotLoggedIn = errors.New("simulated session is logged out")
+
+	errConnectionLost = errors.New("simulated connection loss")
+)
+
+// Backend represents a simulated whatsapp backend.
+// It doesn't connect anywhere, instead its sessions are driven by the code
+// that uses it, so it's possible to exercise the whole bridge without a real phone.
+type Backend struct {
+	log           *zap.Logger
+	mu            sync.Mutex
+	contacts      map[string]domain.WhatsappContact
+	sessions      map[sessionKey]*Session
+	history       map[sessionKey][]domain.WhatsappHistoryMessage
+	unread        map[sessionKey]map[string]int
+	lastMessageID int
+	loginDelay    time.Duration
+	qrCodeTimeout time.Duration
+	loginErr      error
+	echo          bool
+	greeting      string
+}
+
+// sessionKey identifies a session of the whatsapp account of the chat.
+type sessionKey struct {
+	chatID  int64
+	account string
+}
+
+// Opts represents options to create new instance of Backend.
+type Opts struct {
+	// Contacts is a list of contacts of every simulated session.
+	Contacts []domain.WhatsappContact
+
+	// LoginDelay is a time it takes to "scan" the QR-code.
+	LoginDelay time.Duration
+
+	// QRCodeTimeout is a time a QR-code is valid for, a new one is sent
+	// once it passes. The QR-code is valid until the login completes if it's zero.
+	QRCodeTimeout time.Duration
+
+	// Echo makes contacts reply to every message with the same text.
+	Echo bool
+
+	// Greeting is a text every contact sends once the session is established,
+	// nothing is sent if it's empty.
+	Greeting string
+}
+
+// NewBackend returns new instance of Backend.
+func NewBackend(log *zap.Logger, opts *Opts) *Backend {
+	b := &Backend{
+		log:           log,
+		contacts:      make(map[string]domain.WhatsappContact, len(opts.Contacts)),
+		sessions:      make(map[sessionKey]*Session),
+		history:       make(map[sessionKey][]domain.WhatsappHistoryMessage),
+		unread:        make(map[sessionKey]map[string]int),
+		loginDelay:    opts.LoginDelay,
+		qrCodeTimeout: opts.QRCodeTimeout,
+		echo:          opts.Echo,
+		greeting:      opts.Greeting,
+	}
+	for _, contact := range opts.Contacts {
+		b.contacts[contact.Jid] = contact
+	}
+
+	return b
+}
+
+// AddContact method adds a contact to all the simulated sessions.
+func (b *Backend) AddContact(contact domain.WhatsappContact) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.contacts[contact.Jid] = contact
+}
+
+// FailNextLogin method makes the next login attempt fail with the provided error.
+func (b *Backend) FailNextLogin(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.loginErr = err
+}
+
+// addHistory method records the message to the history of the whatsapp account of the chat,
+// so it's available to the sessions the account logs in with later. Incoming messages are
+// unread until the conversation is read or answered.
+func (b *Backend) addHistory(key sessionKey, msg domain.WhatsappHistoryMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.history[key] = append(b.history[key], msg)
+
+	if b.unread[key] == nil {
+		b.unread[key] = make(map[string]int)
+	}
+	if msg.FromMe {
+		delete(b.unread[key], msg.RemoteJid)
+	} else {
+		b.unread[key][msg.RemoteJid]++
+	}
+}
+
+// updateHistory method applies the change to the message of the conversation in the history
+// of the whatsapp account of the chat, the message is removed if the change returns false.
+func (b *Backend) updateHistory(key sessionKey, remoteJid, messageID string,
+	change func(msg *domain.WhatsappHistoryMessage) bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	history := b.history[key]
+	for i := range history {
+		if history[i].RemoteJid != remoteJid || history[i].ID != messageID {
+			continue
+		}
+
+		if !change(&history[i]) {
+			b.history[key] = append(history[:i:i], history[i+1:]...)
+		}
+
+		return
+	}
+}
+
+// nextMessageID method returns a new identifier of a simulated message.
+func (b *Backend) nextMessageID() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastMessageID++
+
+	return fmt.Sprintf("simulated-%d", b.lastMessageID)
+}
+
+// Session method returns the last simulated session of the whatsapp account of the chat.
+func (b *Backend) Session(chatID int64, account string) (*Session, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	session, ok := b.sessions[sessionKey{chatID: chatID, account: account}]
+
+	return session, ok
+}
+
+// Login method sends fake QR-codes and completes login once the login delay passes.
+func (b *Backend) Login(ctx context.Context,
+	opts *domain.WhatsappSessionOpts,
+	qrCodes chan<- domain.WhatsappQRCode) (domain.WhatsappClient, error) {
+	if err := b.showQRCodes(ctx, opts.ChatID, qrCodes); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	loginErr := b.loginErr
+	b.loginErr = nil
+	b.mu.Unlock()
+
+	if loginErr != nil {
+		return nil, loginErr
+	}
+
+	session := &Session{
+		backend:       b,
+		chatID:        opts.ChatID,
+		account:       opts.Account,
+		events:        opts.Events,
+		loggedIn:      true,
+		connected:     true,
+		subscribed:    make(map[string]bool),
+		presences:     make(map[string]domain.WhatsappPresence),
+		liveSequences: make(map[string]int64),
+	}
+	session.eventsProvider = whatsapp.NewEventsProvider(b.log, &whatsapp.Opts{
+		ChatID:         opts.ChatID,
+		Account:        opts.Account,
+		OutgoingEvents: opts.Events,
+		WhatsappClient: session,
+	})
+
+	b.mu.Lock()
+	b.sessions[sessionKey{chatID: opts.ChatID, account: opts.Account}] = session
+	greeting := b.greeting
+	b.mu.Unlock()
+
+	if greeting != "" {
+		go func() {
+			for jid := range session.GetContacts() {
+				session.ReceiveText(jid, greeting)
+			}
+		}()
+	}
+
+	b.log.Debug("simulated login successful",
+		zap.Int64("chat_id", opts.ChatID),
+		zap.String("account", opts.Account))
+
+	return session, nil
+}
+
+// showQRCodes sends a new QR-code every time the previous one expires until
+// the login delay passes.
+func (b *Backend) showQRCodes(ctx context.Context, chatID int64, qrCodes chan<- domain.WhatsappQRCode) error {
+	refreshable := b.qrCodeTimeout > 0 && b.qrCodeTimeout < b.loginDelay
+	qrCodeTimeout := b.loginDelay
+	if refreshable {
+		qrCodeTimeout = b.qrCodeTimeout
+	}
+
+	scanned := time.After(b.loginDelay)
+	for {
+		qrCodes <- domain.WhatsappQRCode{
+			Content: fmt.Sprintf("simulator,%d,%d", chatID, time.Now().UnixNano()),
+			Timeout: qrCodeTimeout,
+		}
+
+		var expired <-chan time.Time
+		if refreshable {
+			expired = time.After(qrCodeTimeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-scanned:
+			return nil
+		case <-expired:
+		}
+	}
+}
+
+// Session represents a simulated whatsapp session.
+type Session struct {
+	backend        *Backend
+	chatID         int64
+	account        string
+	eventsProvider *whatsapp.EventsProvider
+	events         chan<- domain.Event
+	mu             sync.Mutex
+	loggedIn       bool
+	connected      bool
+	sendErr        error
+	restoreErr     error
+	sent           []domain.WhatsappMessage
+	subscribed     map[string]bool
+	presences      map[string]domain.WhatsappPresence
+	liveSequences  map[string]int64
+}
+
+// Restore method reconnects the session unless restoring is scripted to fail.
+func (s *Session) Restore() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.loggedIn {
+		return ErrNotLoggedIn
+	}
+	if s.restoreErr != nil {
+		return s.restoreErr
+	}
+	s.connected = true
+
+	return nil
+}
+
+// GetContacts method returns contacts of the simulated backend.
+func (s *Session) GetContacts() map[string]domain.WhatsappContact {
+	s.backend.mu.Lock()
+	defer s.backend.mu.Unlock()
+
+	contacts := make(map[string]domain.WhatsappContact, len(s.backend.contacts))
+	for jid, contact := range s.backend.contacts {
+		contacts[jid] = contact
+	}
+
+	return contacts
+}
+
+// Send method records the message, contacts reply to it if echo is enabled.
+func (s *Session) Send(msg domain.WhatsappMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case !s.loggedIn:
+		return ErrNotLoggedIn
+	case !s.connected:
+		return errConnectionLost
+	case s.sendErr != nil:
+		return s.sendErr
+	}
+	s.sent = append(s.sent, msg)
+
+	textMessage, ok := msg.(*domain.WhatsappTextMessage)
+	if !ok {
+		return nil
+	}
+
+	messageID := textMessage.ID
+	if messageID == "" {
+		messageID = s.backend.nextMessageID()
+	}
+	s.backend.addHistory(s.key(), domain.WhatsappHistoryMessage{
+		ID:        messageID,
+		RemoteJid: textMessage.RemoteJid,
+		FromMe:    true,
+		Text:      textMessage.Text,
+		Timestamp: time.Now(),
+	})
+
+	if s.backend.echo {
+		go s.ReceiveText(textMessage.RemoteJid, textMessage.Text)
+	}
+
+	return nil
+}
+
+// RecentChats method returns up to count conversations the session has messages of, the most recent first.
+func (s *Session) RecentChats(count int) ([]domain.WhatsappChat, error) {
+	contacts := s.GetContacts()
+
+	s.backend.mu.Lock()
+	defer s.backend.mu.Unlock()
+
+	chats := make([]domain.WhatsappChat, 0)
+	seen := make(map[string]bool)
+	history := s.backend.history[s.key()]
+	for i := len(history) - 1; i >= 0 && len(chats) < count; i-- {
+		msg := history[i]
+		if seen[msg.RemoteJid] {
+			continue
+		}
+		seen[msg.RemoteJid] = true
+		chats = append(chats, domain.WhatsappChat{
+			Jid:           msg.RemoteJid,
+			Name:          contacts[msg.RemoteJid].Name,
+			LastMessageAt: msg.Timestamp,
+			Unread:        s.backend.unread[s.key()][msg.RemoteJid],
+			Preview:       msg.Text,
+		})
+	}
+
+	return chats, nil
+}
+
+// History method returns up to count last messages of the conversation
+// that have been sent or received by the simulated sessions of the account.
+func (s *Session) History(remoteJid string, count int) ([]domain.WhatsappHistoryMessage, error) {
+	s.backend.mu.Lock()
+	defer s.backend.mu.Unlock()
+
+	messages := make([]domain.WhatsappHistoryMessage, 0)
+	history := s.backend.history[s.key()]
+	for i := len(history) - 1; i >= 0 && len(messages) < count; i-- {
+		if history[i].RemoteJid == remoteJid {
+			messages = append(messages, history[i])
+		}
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].Timestamp.Before(messages[j].Timestamp)
+	})
+
+	return messages, nil
+}
+
+// MarkRead method marks messages of the conversation as read.
+func (s *Session) MarkRead(remoteJid string) error {
+	s.backend.mu.Lock()
+	defer s.backend.mu.Unlock()
+
+	delete(s.backend.unread[s.key()], remoteJid)
+
+	return nil
+}
+
+// SubscribePresence method records the subscription to presence updates of the contact.
+func (s *Session) SubscribePresence(remoteJid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.loggedIn {
+		return ErrNotLoggedIn
+	}
+	s.subscribed[remoteJid] = true
+
+	return nil
+}
+
+// SendPresence method records the presence of the user in the conversation.
+func (s *Session) SendPresence(remoteJid string, presence domain.WhatsappPresence) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.loggedIn {
+		return ErrNotLoggedIn
+	}
+	s.presences[remoteJid] = presence
+
+	return nil
+}
+
+// Revoke method removes the message sent to the conversation from the history.
+func (s *Session) Revoke(remoteJid, messageID string) error {
+	s.mu.Lock()
+	loggedIn := s.loggedIn
+	s.mu.Unlock()
+
+	if !loggedIn {
+		return ErrNotLoggedIn
+	}
+
+	s.backend.updateHistory(s.key(), remoteJid, messageID, func(msg *domain.WhatsappHistoryMessage) bool {
+		return !msg.FromMe
+	})
+
+	return nil
+}
+
+// Edit method replaces the text of the message sent to the conversation in the history.
+func (s *Session) Edit(remoteJid, messageID, text string) error {
+	s.mu.Lock()
+	loggedIn := s.loggedIn
+	s.mu.Unlock()
+
+	if !loggedIn {
+		return ErrNotLoggedIn
+	}
+
+	s.backend.updateHistory(s.key(), remoteJid, messageID, func(msg *domain.WhatsappHistoryMessage) bool {
+		if msg.FromMe {
+			msg.Text = text
+		}
+
+		return true
+	})
+
+	return nil
+}
+
+// Logout method invalidates the session.
+func (s *Session) Logout() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.loggedIn = false
+	s.connected = false
+
+	return nil
+}
+
+// ReceiveText method emulates an incoming text message from the contact.
+// The call blocks until the message is passed to the bridge.
+func (s *Session) ReceiveText(remoteJid, text string) {
+	s.mu.Lock()
+	loggedIn := s.loggedIn
+	s.mu.Unlock()
+
+	if !loggedIn {
+		return
+	}
+
+	messageID := s.backend.nextMessageID()
+	now := time.Now()
+	s.backend.addHistory(s.key(), domain.WhatsappHistoryMessage{
+		ID:         messageID,
+		RemoteJid:  remoteJid,
+		SenderName: s.GetContacts()[remoteJid].Name,
+		Text:       text,
+		Timestamp:  now,
+	})
+
+	s.eventsProvider.HandleTextMessage(whatsappsdk.TextMessage{
+		Info: whatsappsdk.MessageInfo{
+			Id:        messageID,
+			RemoteJid: remoteJid,
+			Timestamp: uint64(now.Unix()),
+		},
+		Text: text,
+	})
+}
+
+// ReceiveLocation method emulates the contact sharing a location.
+// The call blocks until the location is passed to the bridge.
+func (s *Session) ReceiveLocation(remoteJid string, location domain.WhatsappLocation) {
+	s.mu.Lock()
+	loggedIn := s.loggedIn
+	s.mu.Unlock()
+
+	if !loggedIn {
+		return
+	}
+
+	s.eventsProvider.HandleLocationMessage(whatsappsdk.LocationMessage{
+		Info: whatsappsdk.MessageInfo{
+			Id:        s.backend.nextMessageID(),
+			RemoteJid: remoteJid,
+			Timestamp: uint64(time.Now().Unix()),
+		},
+		DegreesLatitude:  location.Latitude,
+		DegreesLongitude: location.Longitude,
+		Name:             location.Name,
+		Address:          location.Address,
+	})
+}
+
+// ReceiveLiveLocation method emulates the contact sharing its live location, it's called
+// again every time the contact moves, the updates have growing sequence numbers.
+// The call blocks until the location is passed to the bridge.
+func (s *Session) ReceiveLiveLocation(remoteJid string, latitude, longitude float64) {
+	s.mu.Lock()
+	loggedIn := s.loggedIn
+	s.liveSequences[remoteJid]++
+	sequence := s.liveSequences[remoteJid]
+	s.mu.Unlock()
+
+	if !loggedIn {
+		return
+	}
+
+	s.eventsProvider.HandleLiveLocationMessage(whatsappsdk.LiveLocationMessage{
+		Info: whatsappsdk.MessageInfo{
+			Id:        s.backend.nextMessageID(),
+			RemoteJid: remoteJid,
+			Timestamp: uint64(time.Now().Unix()),
+		},
+		DegreesLatitude:  latitude,
+		DegreesLongitude: longitude,
+		SequenceNumber:   sequence,
+	})
+}
+
+// ReceiveContactCard method emulates the contact sharing a contact card.
+// The call blocks until the card is passed to the bridge.
+func (s *Session) ReceiveContactCard(remoteJid string, contactCard domain.WhatsappContactCard) {
+	s.mu.Lock()
+	loggedIn := s.loggedIn
+	s.mu.Unlock()
+
+	if !loggedIn {
+		return
+	}
+
+	s.eventsProvider.HandleContactMessage(whatsappsdk.ContactMessage{
+		Info: whatsappsdk.MessageInfo{
+			Id:        s.backend.nextMessageID(),
+			RemoteJid: remoteJid,
+			Timestamp: uint64(time.Now().Unix()),
+		},
+		DisplayName: contactCard.DisplayName,
+		Vcard:       contactCard.Vcard,
+	})
+}
+
+// ReceiveRevoke method emulates the contact deleting its message for everyone, the message
+// is removed from the history. The call blocks until the revoke is passed to the bridge.
+func (s *Session) ReceiveRevoke(remoteJid, messageID string) {
+	s.mu.Lock()
+	loggedIn := s.loggedIn
+	s.mu.Unlock()
+
+	if !loggedIn {
+		return
+	}
+
+	s.backend.updateHistory(s.key(), remoteJid, messageID, func(msg *domain.WhatsappHistoryMessage) bool {
+		return msg.FromMe
+	})
+
+	revokeID := s.backend.nextMessageID()
+	revokeType := proto.ProtocolMessage_REVOKE
+	timestamp := uint64(time.Now().Unix())
+	s.eventsProvider.HandleRawMessage(&proto.WebMessageInfo{
+		Key:              &proto.MessageKey{RemoteJid: &remoteJid, Id: &revokeID},
+		MessageTimestamp: &timestamp,
+		Message: &proto.Message{
+			ProtocolMessage: &proto.ProtocolMessage{
+				Type: &revokeType,
+				Key:  &proto.MessageKey{RemoteJid: &remoteJid, Id: &messageID},
+			},
+		},
+	})
+}
+
+// ReceiveReaction method emulates the contact reacting to the message, the reaction is removed
+// if the emoji is empty. The web client protocol doesn't have reactions, so the event is passed
+// to the bridge directly, the call blocks until it's passed.
+func (s *Session) ReceiveReaction(remoteJid, messageID, emoji string) {
+	s.mu.Lock()
+	loggedIn := s.loggedIn
+	s.mu.Unlock()
+
+	if !loggedIn {
+		return
+	}
+
+	s.events <- &domain.ReactionEvent{
+		ChatID:             s.chatID,
+		WhatsappRemoteJid:  remoteJid,
+		WhatsappSenderName: s.GetContacts()[remoteJid].Name,
+		WhatsappSenderJid:  remoteJid,
+		WhatsappMessageID:  messageID,
+		Emoji:              emoji,
+		Account:            s.account,
+	}
+}
+
+// ReceiveDocument method emulates the contact sending a document, it's passed to the bridge
+// downloaded. The call blocks until the document is passed to the bridge.
+func (s *Session) ReceiveDocument(remoteJid string, document domain.WhatsappDocument) {
+	s.mu.Lock()
+	loggedIn := s.loggedIn
+	s.mu.Unlock()
+
+	if !loggedIn {
+		return
+	}
+
+	s.events <- &domain.DocumentEvent{
+		ChatID:             s.chatID,
+		WhatsappRemoteJid:  remoteJid,
+		WhatsappSenderName: s.GetContacts()[remoteJid].Name,
+		WhatsappMessageID:  s.backend.nextMessageID(),
+		Document:           document,
+		Account:            s.account,
+	}
+}
+
+// ReceivePresence method emulates a presence update of the contact, the last seen time
+// is sent only if it's not zero. The update is dropped unless the session is subscribed
+// to the contact, the call blocks until the update is passed to the bridge.
+func (s *Session) ReceivePresence(remoteJid string, presence domain.WhatsappPresence, lastSeen time.Time) {
+	s.mu.Lock()
+	subscribed := s.loggedIn && s.subscribed[remoteJid]
+	s.mu.Unlock()
+
+	if !subscribed {
+		return
+	}
+
+	update := map[string]interface{}{"id": remoteJid, "type": presence}
+	if !lastSeen.IsZero() {
+		update["t"] = lastSeen.Unix()
+	}
+	message, err := json.Marshal([]interface{}{"Presence", update})
+	if err != nil {
+		return
+	}
+
+	s.eventsProvider.HandleJsonMessage(string(message))
+}
+
+// Subscribed method returns true if the session is subscribed to presence updates of the contact.
+func (s *Session) Subscribed(remoteJid string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.subscribed[remoteJid]
+}
+
+// Presence method returns the last presence of the user sent to the conversation.
+func (s *Session) Presence(remoteJid string) domain.WhatsappPresence {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.presences[remoteJid]
+}
+
+// key returns a key of the session's account.
+func (s *Session) key() sessionKey {
+	return sessionKey{chatID: s.chatID, account: s.account}
+}
+
+// Disconnect method emulates a connection loss. The session will be restored
+// unless restoreErr is provided, in which case restoring fails with it.
+// The call blocks until the bridge has handled the connection loss.
+func (s *Session) Disconnect(restoreErr error) {
+	s.mu.Lock()
+	s.connected = false
+	s.restoreErr = restoreErr
+	s.mu.Unlock()
+
+	s.eventsProvider.HandleError(&whatsappsdk.ErrConnectionFailed{Err: errConnectionLost})
+}
+
+// SetSendError method makes all subsequent sends fail with the provided error.
+// Passing nil makes sending work normally again.
+func (s *Session) SetSendError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sendErr = err
+}
+
+// Sent method returns messages sent through the session so far.
+func (s *Session) Sent() []domain.WhatsappMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]domain.WhatsappMessage(nil), s.sent...)
+}
+
+// LoggedIn method returns true if the session hasn't been logged out.
+func (s *Session) LoggedIn() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.loggedIn
+}
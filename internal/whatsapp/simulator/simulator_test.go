@@ -0,0 +1,337 @@
+package simulator_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dstdfx/twbridge/internal/domain"
+	"github.com/dstdfx/twbridge/internal/whatsapp/simulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var _ domain.WhatsappBackend = &simulator.Backend{}
+
+var errTestLogin = errors.New("failed to login")
+
+func login(t *testing.T, backend *simulator.Backend, events chan domain.Event) *simulator.Session {
+	t.Helper()
+
+	qrCodes := make(chan domain.WhatsappQRCode, 1)
+	client, err := backend.Login(context.Background(), &domain.WhatsappSessionOpts{
+		ChatID:  42,
+		Account: domain.DefaultWhatsappAccount,
+		Events:  events,
+	}, qrCodes)
+	require.NoError(t, err)
+	assert.NotEmpty(t, (<-qrCodes).Content)
+
+	session, ok := backend.Session(42, domain.DefaultWhatsappAccount)
+	require.True(t, ok)
+	assert.Equal(t, client, session)
+
+	return session
+}
+
+func TestBackend(t *testing.T) {
+	testContact := domain.WhatsappContact{Jid: "alice@s.whatsapp.net", Name: "Alice"}
+
+	t.Run("login fails", func(t *testing.T) {
+		backend := simulator.NewBackend(zap.NewNop(), &simulator.Opts{})
+		backend.FailNextLogin(errTestLogin)
+
+		_, err := backend.Login(context.Background(),
+			&domain.WhatsappSessionOpts{ChatID: 42, Account: domain.DefaultWhatsappAccount},
+			make(chan domain.WhatsappQRCode, 1))
+		assert.ErrorIs(t, err, errTestLogin)
+
+		_, ok := backend.Session(42, domain.DefaultWhatsappAccount)
+		assert.False(t, ok)
+	})
+
+	t.Run("QR-code is refreshed until login completes", func(t *testing.T) {
+		backend := simulator.NewBackend(zap.NewNop(), &simulator.Opts{
+			LoginDelay:    250 * time.Millisecond,
+			QRCodeTimeout: 100 * time.Millisecond,
+		})
+
+		qrCodes := make(chan domain.WhatsappQRCode, 10)
+		_, err := backend.Login(context.Background(), &domain.WhatsappSessionOpts{ChatID: 42}, qrCodes)
+		require.NoError(t, err)
+		close(qrCodes)
+
+		var contents []string
+		for qrCode := range qrCodes {
+			assert.Equal(t, 100*time.Millisecond, qrCode.Timeout)
+			contents = append(contents, qrCode.Content)
+		}
+		require.Len(t, contents, 3)
+		assert.NotEqual(t, contents[0], contents[1])
+	})
+
+	t.Run("login is cancelled", func(t *testing.T) {
+		backend := simulator.NewBackend(zap.NewNop(), &simulator.Opts{
+			LoginDelay: time.Minute,
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		qrCodes := make(chan domain.WhatsappQRCode)
+		go func() {
+			<-qrCodes
+			cancel()
+		}()
+
+		_, err := backend.Login(ctx, &domain.WhatsappSessionOpts{ChatID: 42}, qrCodes)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, ok := backend.Session(42, domain.DefaultWhatsappAccount)
+		assert.False(t, ok)
+	})
+
+	t.Run("greeting and echo", func(t *testing.T) {
+		backend := simulator.NewBackend(zap.NewNop(), &simulator.Opts{
+			Contacts: []domain.WhatsappContact{testContact},
+			Echo:     true,
+			Greeting: "hello",
+		})
+		events := make(chan domain.Event)
+		session := login(t, backend, events)
+
+		greeting := (<-events).(*domain.TextMessageEvent)
+		assert.Equal(t, int64(42), greeting.ChatID)
+		assert.Equal(t, testContact.Jid, greeting.WhatsappRemoteJid)
+		assert.Equal(t, testContact.Name, greeting.WhatsappSenderName)
+		assert.Equal(t, "hello", greeting.Text)
+
+		msg := &domain.WhatsappTextMessage{RemoteJid: testContact.Jid, Text: "echo me"}
+		require.NoError(t, session.Send(msg))
+		assert.Equal(t, []domain.WhatsappMessage{msg}, session.Sent())
+
+		echo := (<-events).(*domain.TextMessageEvent)
+		assert.Equal(t, "echo me", echo.Text)
+	})
+
+	t.Run("disconnect and restore", func(t *testing.T) {
+		backend := simulator.NewBackend(zap.NewNop(), &simulator.Opts{})
+		events := make(chan domain.Event, 1)
+		session := login(t, backend, events)
+
+		session.Disconnect(nil)
+		assert.Equal(t, domain.RestoreEventType, (<-events).Type())
+		assert.NoError(t, session.Send(&domain.WhatsappTextMessage{}))
+	})
+
+	t.Run("history is kept between sessions", func(t *testing.T) {
+		bobContact := domain.WhatsappContact{Jid: "bob@s.whatsapp.net", Name: "Bob"}
+		backend := simulator.NewBackend(zap.NewNop(), &simulator.Opts{
+			Contacts: []domain.WhatsappContact{testContact, bobContact},
+		})
+		events := make(chan domain.Event, 2)
+		session := login(t, backend, events)
+
+		session.ReceiveText(testContact.Jid, "hi")
+		require.NoError(t, session.Send(&domain.WhatsappTextMessage{RemoteJid: testContact.Jid, Text: "hello"}))
+		session.ReceiveText(bobContact.Jid, "hey")
+		require.NoError(t, session.Logout())
+
+		session = login(t, backend, events)
+		chats, err := session.RecentChats(10)
+		require.NoError(t, err)
+		require.Len(t, chats, 2)
+		assert.Equal(t, bobContact.Jid, chats[0].Jid)
+		assert.Equal(t, 1, chats[0].Unread)
+		assert.Equal(t, "hey", chats[0].Preview)
+		assert.Equal(t, testContact.Name, chats[1].Name)
+		assert.Zero(t, chats[1].Unread)
+
+		require.NoError(t, session.MarkRead(bobContact.Jid))
+		chats, err = session.RecentChats(1)
+		require.NoError(t, err)
+		require.Len(t, chats, 1)
+		assert.Zero(t, chats[0].Unread)
+
+		history, err := session.History(testContact.Jid, 10)
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		assert.Equal(t, "Alice", history[0].SenderName)
+		assert.Equal(t, "hi", history[0].Text)
+		assert.True(t, history[1].FromMe)
+		assert.NotEqual(t, history[0].ID, history[1].ID)
+
+		history, err = session.History(testContact.Jid, 1)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		assert.Equal(t, "hello", history[0].Text)
+	})
+
+	t.Run("presence", func(t *testing.T) {
+		backend := simulator.NewBackend(zap.NewNop(), &simulator.Opts{
+			Contacts: []domain.WhatsappContact{testContact},
+		})
+		events := make(chan domain.Event, 1)
+		session := login(t, backend, events)
+
+		// Updates of the contacts the session isn't subscribed to are dropped
+		session.ReceivePresence(testContact.Jid, domain.WhatsappPresenceComposing, time.Time{})
+		assert.Empty(t, events)
+
+		require.NoError(t, session.SubscribePresence(testContact.Jid))
+		assert.True(t, session.Subscribed(testContact.Jid))
+
+		lastSeen := time.Date(2022, 3, 31, 10, 0, 0, 0, time.UTC)
+		session.ReceivePresence(testContact.Jid, domain.WhatsappPresenceUnavailable, lastSeen)
+		presenceEvent, ok := (<-events).(*domain.PresenceEvent)
+		require.True(t, ok)
+		assert.Equal(t, testContact.Jid, presenceEvent.WhatsappRemoteJid)
+		assert.Equal(t, domain.WhatsappPresenceUnavailable, presenceEvent.Presence)
+		assert.True(t, lastSeen.Equal(presenceEvent.LastSeen))
+
+		require.NoError(t, session.SendPresence(testContact.Jid, domain.WhatsappPresenceComposing))
+		assert.Equal(t, domain.WhatsappPresenceComposing, session.Presence(testContact.Jid))
+	})
+
+	t.Run("revoke and edit", func(t *testing.T) {
+		backend := simulator.NewBackend(zap.NewNop(), &simulator.Opts{
+			Contacts: []domain.WhatsappContact{testContact},
+		})
+		events := make(chan domain.Event, 1)
+		session := login(t, backend, events)
+
+		session.ReceiveText(testContact.Jid, "lunch?")
+		<-events
+		require.NoError(t, session.Send(&domain.WhatsappTextMessage{
+			ID:        "reply-id",
+			RemoteJid: testContact.Jid,
+			Text:      "see you at 5",
+		}))
+		history, err := session.History(testContact.Jid, 10)
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		assert.Equal(t, "reply-id", history[1].ID)
+
+		// The contact deletes its message
+		session.ReceiveRevoke(testContact.Jid, history[0].ID)
+		revokeEvent, ok := (<-events).(*domain.RevokeEvent)
+		require.True(t, ok)
+		assert.Equal(t, testContact.Jid, revokeEvent.WhatsappRemoteJid)
+		assert.Equal(t, history[0].ID, revokeEvent.WhatsappMessageID)
+
+		require.NoError(t, session.Edit(testContact.Jid, "reply-id", "see you at 6"))
+		history, err = session.History(testContact.Jid, 10)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		assert.Equal(t, "see you at 6", history[0].Text)
+
+		require.NoError(t, session.Revoke(testContact.Jid, "reply-id"))
+		history, err = session.History(testContact.Jid, 10)
+		require.NoError(t, err)
+		assert.Empty(t, history)
+	})
+
+	t.Run("reactions", func(t *testing.T) {
+		backend := simulator.NewBackend(zap.NewNop(), &simulator.Opts{
+			Contacts: []domain.WhatsappContact{testContact},
+		})
+		events := make(chan domain.Event, 1)
+		session := login(t, backend, events)
+
+		session.ReceiveReaction(testContact.Jid, "reply-id", "❤️")
+		assert.Equal(t, &domain.ReactionEvent{
+			ChatID:             42,
+			WhatsappRemoteJid:  testContact.Jid,
+			WhatsappSenderName: "Alice",
+			WhatsappSenderJid:  testContact.Jid,
+			WhatsappMessageID:  "reply-id",
+			Emoji:              "❤️",
+			Account:            domain.DefaultWhatsappAccount,
+		}, <-events)
+
+		reaction := &domain.WhatsappReactionMessage{
+			RemoteJid: testContact.Jid,
+			MessageID: "alice-message-id",
+			Emoji:     "👍",
+		}
+		require.NoError(t, session.Send(reaction))
+		assert.Equal(t, []domain.WhatsappMessage{reaction}, session.Sent())
+	})
+
+	t.Run("locations", func(t *testing.T) {
+		backend := simulator.NewBackend(zap.NewNop(), &simulator.Opts{
+			Contacts: []domain.WhatsappContact{testContact},
+		})
+		events := make(chan domain.Event, 1)
+		session := login(t, backend, events)
+
+		location := domain.WhatsappLocation{Latitude: 55.751244, Longitude: 37.618423, Name: "Cafe"}
+		session.ReceiveLocation(testContact.Jid, location)
+		locationEvent, ok := (<-events).(*domain.LocationEvent)
+		require.True(t, ok)
+		assert.Equal(t, "Alice", locationEvent.WhatsappSenderName)
+		assert.Equal(t, location, locationEvent.Location)
+		assert.False(t, locationEvent.Live)
+
+		session.ReceiveLiveLocation(testContact.Jid, 55.75, 37.62)
+		locationEvent, ok = (<-events).(*domain.LocationEvent)
+		require.True(t, ok)
+		assert.Equal(t, domain.WhatsappLocation{Latitude: 55.75, Longitude: 37.62}, locationEvent.Location)
+		assert.True(t, locationEvent.Live)
+		assert.Equal(t, int64(1), locationEvent.LiveSequence)
+
+		session.ReceiveLiveLocation(testContact.Jid, 55.76, 37.63)
+		locationEvent, ok = (<-events).(*domain.LocationEvent)
+		require.True(t, ok)
+		assert.Equal(t, int64(2), locationEvent.LiveSequence)
+
+		sent := &domain.WhatsappLocationMessage{RemoteJid: testContact.Jid, Location: location}
+		require.NoError(t, session.Send(sent))
+		assert.Equal(t, []domain.WhatsappMessage{sent}, session.Sent())
+	})
+
+	t.Run("contact cards", func(t *testing.T) {
+		backend := simulator.NewBackend(zap.NewNop(), &simulator.Opts{
+			Contacts: []domain.WhatsappContact{testContact},
+		})
+		events := make(chan domain.Event, 1)
+		session := login(t, backend, events)
+
+		contactCard := domain.WhatsappContactCard{DisplayName: "Bob", Vcard: "BEGIN:VCARD\nFN:Bob\nEND:VCARD"}
+		session.ReceiveContactCard(testContact.Jid, contactCard)
+		contactCardEvent, ok := (<-events).(*domain.ContactCardEvent)
+		require.True(t, ok)
+		assert.Equal(t, "Alice", contactCardEvent.WhatsappSenderName)
+		assert.Equal(t, contactCard, contactCardEvent.ContactCard)
+
+		sent := &domain.WhatsappContactCardMessage{RemoteJid: testContact.Jid, ContactCard: contactCard}
+		require.NoError(t, session.Send(sent))
+		assert.Equal(t, []domain.WhatsappMessage{sent}, session.Sent())
+	})
+
+	t.Run("documents", func(t *testing.T) {
+		backend := simulator.NewBackend(zap.NewNop(), &simulator.Opts{
+			Contacts: []domain.WhatsappContact{testContact},
+		})
+		events := make(chan domain.Event, 1)
+		session := login(t, backend, events)
+
+		document := domain.WhatsappDocument{FileName: "report.pdf", Size: 4, Data: []byte("%PDF")}
+		session.ReceiveDocument(testContact.Jid, document)
+		documentEvent, ok := (<-events).(*domain.DocumentEvent)
+		require.True(t, ok)
+		assert.Equal(t, "Alice", documentEvent.WhatsappSenderName)
+		assert.NotEmpty(t, documentEvent.WhatsappMessageID)
+		assert.Equal(t, document, documentEvent.Document)
+	})
+
+	t.Run("logout", func(t *testing.T) {
+		backend := simulator.NewBackend(zap.NewNop(), &simulator.Opts{})
+		session := login(t, backend, make(chan domain.Event))
+
+		require.NoError(t, session.Logout())
+		assert.False(t, session.LoggedIn())
+		assert.ErrorIs(t, session.Send(&domain.WhatsappTextMessage{}), simulator.ErrNotLoggedIn)
+		assert.ErrorIs(t, session.Restore(), simulator.ErrNotLoggedIn)
+	})
+}